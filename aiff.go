@@ -0,0 +1,217 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+func init() {
+	// Register AIFF and AIFF-C decoders, since azul3d/engine/audio does not
+	// provide them
+	audio.RegisterFormat("aiff", "FORM????AIFF", newAIFFDecoder)
+	audio.RegisterFormat("aifc", "FORM????AIFC", newAIFFDecoder)
+}
+
+// aiffDecoder is an audio.Decoder which decodes uncompressed PCM audio from
+// AIFF and AIFF-C streams.
+type aiffDecoder struct {
+	r      io.Reader
+	config audio.Config
+
+	// bytesPerSample is the size of each sample, and littleEndian is set for
+	// AIFF-C streams which store samples in little-endian byte order
+	bytesPerSample int
+	littleEndian   bool
+
+	// remaining is the number of samples which have not yet been read
+	remaining int64
+
+	// buf is reused to receive encoded samples from the stream
+	buf []byte
+}
+
+// newAIFFDecoder creates an audio.Decoder from an AIFF or AIFF-C stream.  The
+// COMM chunk, which describes the audio, must precede the SSND chunk, which
+// contains the audio samples.
+func newAIFFDecoder(r io.Reader) (audio.Decoder, error) {
+	br := bufio.NewReader(r)
+
+	// FORM chunk header, and the AIFF or AIFC form type
+	var form [12]byte
+	if _, err := io.ReadFull(br, form[:]); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+	aifc := string(form[8:12]) == "AIFC"
+
+	d := &aiffDecoder{r: br}
+	var haveComm bool
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[4:8]))
+
+		switch string(hdr[0:4]) {
+		case "COMM":
+			if err := d.readComm(br, size, aifc); err != nil {
+				return nil, err
+			}
+			haveComm = true
+		case "SSND":
+			if !haveComm {
+				return nil, audio.ErrInvalidData
+			}
+
+			// Skip any offset to the first sample frame
+			var ssnd [8]byte
+			if _, err := io.ReadFull(br, ssnd[:]); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+			offset := int64(binary.BigEndian.Uint32(ssnd[0:4]))
+			if _, err := io.CopyN(ioutil.Discard, br, offset); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			// Never read beyond the end of the chunk
+			chunk := (size - 8 - offset) / int64(d.bytesPerSample)
+			if chunk < 0 {
+				return nil, audio.ErrInvalidData
+			}
+			if chunk < d.remaining {
+				d.remaining = chunk
+			}
+
+			return d, nil
+		default:
+			// Skip unknown chunks, which are padded to an even length
+			if _, err := io.CopyN(ioutil.Discard, br, size+size%2); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// readComm reads the COMM chunk of an AIFF or AIFF-C stream, which describes
+// the format of its audio samples.
+func (d *aiffDecoder) readComm(r io.Reader, size int64, aifc bool) error {
+	// AIFF-C adds a compression type to the end of the chunk
+	min := int64(18)
+	if aifc {
+		min = 22
+	}
+	if size < min || size > 1024 {
+		return audio.ErrInvalidData
+	}
+
+	b := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	channels := int(binary.BigEndian.Uint16(b[0:2]))
+	frames := int64(binary.BigEndian.Uint32(b[2:6]))
+	bits := int(binary.BigEndian.Uint16(b[6:8]))
+	rate := extendedToFloat(b[8:18])
+
+	if aifc {
+		switch string(b[18:22]) {
+		case "NONE":
+		case "sowt":
+			d.littleEndian = true
+		default:
+			// Compressed audio is not supported
+			return audio.ErrFormat
+		}
+	}
+
+	if channels <= 0 || bits <= 0 || bits > 32 || rate < 1 || rate > math.MaxInt32 {
+		return audio.ErrInvalidData
+	}
+
+	d.config = audio.Config{
+		SampleRate: int(rate),
+		Channels:   channels,
+	}
+	d.bytesPerSample = (bits + 7) / 8
+	d.remaining = frames * int64(channels)
+
+	return nil
+}
+
+// Config returns the audio configuration of the AIFF stream.
+func (d *aiffDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes interleaved audio samples from the AIFF stream into b,
+// returning audio.EOS when the stream is exhausted.
+func (d *aiffDecoder) Read(b audio.Slice) (int, error) {
+	if d.remaining == 0 {
+		return 0, audio.EOS
+	}
+
+	n := b.Len()
+	if int64(n) > d.remaining {
+		n = int(d.remaining)
+	}
+
+	size := n * d.bytesPerSample
+	if cap(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+	buf := d.buf[:size]
+
+	// Decode any complete samples, even if the stream ends early
+	read, err := io.ReadFull(d.r, buf)
+	n = read / d.bytesPerSample
+	for i := 0; i < n; i++ {
+		b.Set(i, d.sample(buf[i*d.bytesPerSample:(i+1)*d.bytesPerSample]))
+	}
+	d.remaining -= int64(n)
+
+	if err != nil {
+		return n, audio.ErrUnexpectedEOS
+	}
+
+	return n, nil
+}
+
+// sample decodes a single signed, integer sample into the range [-1, 1).
+func (d *aiffDecoder) sample(b []byte) float64 {
+	// Assemble the sample in the most significant bits of a 32-bit integer,
+	// so that its sign is preserved
+	var v uint32
+	for i := range b {
+		j := i
+		if d.littleEndian {
+			j = len(b) - 1 - i
+		}
+
+		v |= uint32(b[j]) << uint(24-8*i)
+	}
+
+	return float64(int32(v)) / (1 << 31)
+}
+
+// extendedToFloat converts an 80-bit IEEE 754 extended precision number, as
+// used for the sample rate of an AIFF stream, to a float64.
+func extendedToFloat(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mant := binary.BigEndian.Uint64(b[2:10])
+	if exp == 0 && mant == 0 {
+		return 0
+	}
+
+	v := math.Ldexp(float64(mant), exp-16383-63)
+	if b[0]&0x80 != 0 {
+		v = -v
+	}
+
+	return v
+}
@@ -0,0 +1,189 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// TestWaveformComputeAIFFOK verifies that AIFF and AIFF-C streams produce the
+// same computed values as an equivalent WAV stream, at each supported bit
+// depth and byte order.  Values are compared approximately, because decoders
+// may scale integer samples by either 2^(bits-1) or 2^(bits-1)-1.
+func TestWaveformComputeAIFFOK(t *testing.T) {
+	// 2 seconds of a stereo square wave at 1/4 full scale
+	samples := make([]int16, 2*8000*2)
+	for i := range samples {
+		samples[i] = 8192
+		if (i/40)%2 == 0 {
+			samples[i] = -8192
+		}
+	}
+
+	want, err := New(bytes.NewReader(makeWAV(8000, 2, samples)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValues, err := want.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		form string
+		comp string
+		bits int
+	}{
+		{name: "AIFF 8-bit", form: "AIFF", bits: 8},
+		{name: "AIFF 16-bit", form: "AIFF", bits: 16},
+		{name: "AIFF 24-bit", form: "AIFF", bits: 24},
+		{name: "AIFF 32-bit", form: "AIFF", bits: 32},
+		{name: "AIFF-C NONE", form: "AIFC", comp: "NONE", bits: 16},
+		{name: "AIFF-C sowt", form: "AIFC", comp: "sowt", bits: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(bytes.NewReader(makeAIFF(tt.form, tt.comp, tt.bits, 8000, 2, samples)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := w.Compute()
+			if err != nil {
+				t.Fatalf("unexpected Compute error: %v", err)
+			}
+
+			if len(values) != len(wantValues) {
+				t.Fatalf("unexpected number of values: %v != %v", len(values), len(wantValues))
+			}
+			for i := range values {
+				if math.Abs(values[i]-wantValues[i]) > 1e-4 {
+					t.Fatalf("[%02d] unexpected value: %v != %v", i, values[i], wantValues[i])
+				}
+			}
+		})
+	}
+}
+
+// TestWaveformComputeAIFFErrors verifies that malformed and unsupported AIFF
+// streams produce appropriate errors.
+func TestWaveformComputeAIFFErrors(t *testing.T) {
+	samples := []int16{0, 1, 2, 3}
+
+	// Compressed AIFF-C audio is not supported
+	compressed := makeAIFF("AIFC", "ima4", 16, 8000, 1, samples)
+
+	// Streams which end before any audio
+	truncated := makeAIFF("AIFF", "", 16, 8000, 1, samples)[:30]
+
+	// SSND chunk which precedes the COMM chunk
+	reordered := []byte("FORM\x00\x00\x00\x14AIFFSSND\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00")
+
+	// COMM chunk with no channels
+	noChannels := makeAIFF("AIFF", "", 16, 8000, 0, nil)
+
+	var tests = []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{name: "compressed", b: compressed, err: ErrFormat},
+		{name: "truncated", b: truncated, err: ErrUnexpectedEOS},
+		{name: "reordered", b: reordered, err: ErrInvalidData},
+		{name: "no channels", b: noChannels, err: ErrInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testWaveformCompute(t, bytes.NewReader(tt.b), tt.err, nil, nil)
+		})
+	}
+}
+
+// TestExtendedToFloat verifies that extendedToFloat decodes common sample
+// rates stored as 80-bit extended precision numbers.
+func TestExtendedToFloat(t *testing.T) {
+	for _, rate := range []float64{0, 8000, 22050, 44100, 48000, 96000} {
+		if got := extendedToFloat(floatToExtended(rate)); got != rate {
+			t.Fatalf("unexpected rate: %v != %v", got, rate)
+		}
+	}
+}
+
+// makeAIFF is a test helper which encodes an AIFF or AIFF-C stream from a
+// slice of interleaved 16-bit audio samples, at the input bit depth.  If comp
+// is "sowt", samples are stored in little-endian byte order.
+func makeAIFF(form string, comp string, bits int, sampleRate int, channels int, samples []int16) []byte {
+	comm := bytes.NewBuffer(nil)
+	frames := 0
+	if channels > 0 {
+		frames = len(samples) / channels
+	}
+	_ = binary.Write(comm, binary.BigEndian, uint16(channels))
+	_ = binary.Write(comm, binary.BigEndian, uint32(frames))
+	_ = binary.Write(comm, binary.BigEndian, uint16(bits))
+	comm.Write(floatToExtended(float64(sampleRate)))
+	if form == "AIFC" {
+		comm.WriteString(comp)
+		comm.Write([]byte{0, 0})
+	}
+
+	// Scale each sample to the bit depth, storing the most significant
+	// byte first
+	ssnd := bytes.NewBuffer(make([]byte, 8))
+	for _, s := range samples {
+		v := uint32(int32(s) << 16)
+		b := make([]byte, bits/8)
+		for i := range b {
+			b[i] = byte(v >> uint(24-8*i))
+		}
+		if comp == "sowt" {
+			for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+				b[i], b[j] = b[j], b[i]
+			}
+		}
+		ssnd.Write(b)
+	}
+
+	body := bytes.NewBuffer(nil)
+	body.WriteString(form)
+	for _, c := range []struct {
+		id   string
+		data []byte
+	}{
+		{id: "COMM", data: comm.Bytes()},
+		{id: "SSND", data: ssnd.Bytes()},
+	} {
+		body.WriteString(c.id)
+		_ = binary.Write(body, binary.BigEndian, uint32(len(c.data)))
+		body.Write(c.data)
+		if len(c.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("FORM")
+	_ = binary.Write(buf, binary.BigEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+
+	return buf.Bytes()
+}
+
+// floatToExtended is a test helper which encodes a non-negative integer value
+// as an 80-bit extended precision number.
+func floatToExtended(v float64) []byte {
+	b := make([]byte, 10)
+	if v == 0 {
+		return b
+	}
+
+	frac, exp := math.Frexp(v)
+	binary.BigEndian.PutUint16(b[0:2], uint16(exp-1+16383))
+	binary.BigEndian.PutUint64(b[2:10], uint64(frac*(1<<64)))
+
+	return b
+}
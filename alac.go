@@ -0,0 +1,567 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"azul3d.org/engine/audio"
+)
+
+func init() {
+	// Register an MP4 decoder for Apple Lossless audio, since
+	// azul3d/engine/audio does not provide one
+	audio.RegisterFormat("mp4", "????ftyp", newALACDecoder)
+}
+
+const (
+	// maxALACFrameLength is the maximum number of sample frames in a single
+	// ALAC frame, which limits the memory allocated for malformed streams.
+	maxALACFrameLength = 1 << 16
+
+	// maxALACChannels is the maximum number of channels in an ALAC stream.
+	maxALACChannels = 8
+)
+
+// ALAC syntax elements which may appear in a frame.
+const (
+	alacElementSCE = 0
+	alacElementCPE = 1
+	alacElementLFE = 3
+	alacElementEnd = 7
+)
+
+// alacConfig is the ALAC specific configuration of a track, also known as
+// the magic cookie.
+type alacConfig struct {
+	frameLength int
+	bitDepth    int
+	pb, mb, kb  uint32
+	channels    int
+	sampleRate  int
+}
+
+// alacDecoder is an audio.Decoder which decodes Apple Lossless audio from the
+// first audio track of an MP4 stream, such as an M4A file.
+type alacDecoder struct {
+	mr     *mp4Reader
+	config alacConfig
+
+	// frame is reused to receive each encoded frame from the stream
+	frame []byte
+
+	// out holds decoded, interleaved samples which have not yet been read
+	out  []int32
+	outN int
+
+	// buffers used while decoding each channel of a frame
+	predicted [maxALACChannels][]int32
+	output    [maxALACChannels][]int32
+	extra     [maxALACChannels][]int32
+}
+
+// newALACDecoder creates an audio.Decoder from an MP4 stream containing Apple
+// Lossless audio.  MP4 streams which contain audio in other formats, such as
+// AAC, produce audio.ErrFormat, so that they may be decoded by the
+// FallbackDecoder option instead.
+func newALACDecoder(r io.Reader) (audio.Decoder, error) {
+	mr, err := newMP4Reader(r)
+	if err != nil {
+		return nil, err
+	}
+	if mr.track.format != "alac" {
+		return nil, audio.ErrFormat
+	}
+
+	config, err := parseALACConfig(mr.track.entry)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &alacDecoder{
+		mr:     mr,
+		config: config,
+	}
+	for ch := 0; ch < config.channels; ch++ {
+		d.predicted[ch] = make([]int32, config.frameLength)
+		d.output[ch] = make([]int32, config.frameLength)
+		d.extra[ch] = make([]int32, config.frameLength)
+	}
+
+	return d, nil
+}
+
+// parseALACConfig parses the ALAC specific configuration from the contents of
+// an ALAC sample entry, which contains it as a child box.
+func parseALACConfig(entry []byte) (alacConfig, error) {
+	// Skip the fields of the audio sample entry, which vary in size by version
+	if len(entry) < 28 {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	n := 28
+	switch binary.BigEndian.Uint16(entry[8:10]) {
+	case 1:
+		n += 16
+	case 2:
+		n += 36
+	}
+	if len(entry) < n {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+
+	box, err := mp4Child(entry[n:], "alac")
+	if err != nil {
+		return alacConfig{}, err
+	}
+
+	// Skip the version and flags of the box
+	if len(box) < 28 {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	b := box[4:]
+
+	c := alacConfig{
+		frameLength: int(binary.BigEndian.Uint32(b[0:4])),
+		bitDepth:    int(b[5]),
+		pb:          uint32(b[6]),
+		mb:          uint32(b[7]),
+		kb:          uint32(b[8]),
+		channels:    int(b[9]),
+		sampleRate:  int(binary.BigEndian.Uint32(b[20:24])),
+	}
+
+	if c.frameLength <= 0 || c.frameLength > maxALACFrameLength {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	if c.channels <= 0 || c.channels > maxALACChannels {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	switch c.bitDepth {
+	case 16, 20, 24, 32:
+	default:
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	if c.sampleRate <= 0 {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+
+	// The Rice parameter limit must allow at least 1 bit, and no more than a
+	// 32-bit value
+	if c.kb == 0 || c.kb > 31 {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+
+	return c, nil
+}
+
+// Config returns the audio configuration of the ALAC stream.
+func (d *alacDecoder) Config() audio.Config {
+	return audio.Config{
+		SampleRate: d.config.sampleRate,
+		Channels:   d.config.channels,
+	}
+}
+
+// Read decodes interleaved audio samples from the ALAC stream into b,
+// returning audio.EOS when the stream is exhausted.
+func (d *alacDecoder) Read(b audio.Slice) (int, error) {
+	scale := float64(uint64(1) << uint(d.config.bitDepth-1))
+
+	var n int
+	for n < b.Len() {
+		// Decode the next frame once all samples from the last are read
+		if d.outN == len(d.out) {
+			if err := d.decodeFrame(); err != nil {
+				if err == audio.EOS && n > 0 {
+					return n, nil
+				}
+
+				return n, err
+			}
+		}
+
+		for ; n < b.Len() && d.outN < len(d.out); n++ {
+			b.Set(n, float64(d.out[d.outN])/scale)
+			d.outN++
+		}
+	}
+
+	return n, nil
+}
+
+// decodeFrame reads and decodes the next frame of the stream.
+func (d *alacDecoder) decodeFrame() error {
+	frame, err := d.mr.readSample(d.frame)
+	if err != nil {
+		return err
+	}
+	d.frame = frame
+
+	br := &alacBitReader{b: frame}
+
+	// Each element of a frame contains one or two channels
+	var channels, samples int
+	for {
+		element := br.read(3)
+		if element == alacElementEnd {
+			break
+		}
+
+		var n int
+		switch element {
+		case alacElementSCE, alacElementLFE:
+			n = 1
+		case alacElementCPE:
+			n = 2
+		default:
+			return audio.ErrInvalidData
+		}
+		if channels+n > d.config.channels {
+			return audio.ErrInvalidData
+		}
+
+		s, err := d.decodeElement(br, channels, n)
+		if err != nil {
+			return err
+		}
+		if channels > 0 && s != samples {
+			return audio.ErrInvalidData
+		}
+
+		samples = s
+		channels += n
+	}
+	if br.err != nil || channels != d.config.channels {
+		return audio.ErrInvalidData
+	}
+
+	// Interleave samples from each channel
+	d.out = d.out[:0]
+	for i := 0; i < samples; i++ {
+		for ch := 0; ch < channels; ch++ {
+			d.out = append(d.out, d.output[ch][i])
+		}
+	}
+	d.outN = 0
+
+	return nil
+}
+
+// decodeElement decodes a single element of a frame, containing n channels,
+// into the output buffers beginning with channel first.  The number of
+// samples decoded for each channel is returned.
+func (d *alacDecoder) decodeElement(br *alacBitReader, first int, n int) (int, error) {
+	c := d.config
+
+	// Element instance tag and unused header bits
+	br.read(4)
+	br.read(12)
+
+	hasSize := br.read(1) == 1
+	extraBits := uint(br.read(2)) * 8
+	compressed := br.read(1) == 0
+
+	samples := c.frameLength
+	if hasSize {
+		s := br.read(32)
+		if s == 0 || s > uint32(c.frameLength) {
+			return 0, audio.ErrInvalidData
+		}
+
+		samples = int(s)
+	}
+
+	// Samples which are not compressed are stored directly
+	if !compressed {
+		for i := 0; i < samples; i++ {
+			for ch := first; ch < first+n; ch++ {
+				d.output[ch][i] = br.readSigned(uint(c.bitDepth))
+			}
+		}
+
+		return samples, br.err
+	}
+
+	// Sample size used for prediction, excluding any extra low-order bits
+	bps := uint(c.bitDepth) - extraBits + uint(n) - 1
+	if extraBits >= uint(c.bitDepth) || bps > 32 {
+		return 0, audio.ErrInvalidData
+	}
+
+	shift := uint(br.read(8))
+	weight := int32(br.read(8))
+
+	var (
+		prediction [2]uint32
+		quant      [2]uint
+		mult       [2]uint32
+		order      [2]int
+		coefs      [2][32]int32
+	)
+	for ch := 0; ch < n; ch++ {
+		prediction[ch] = br.read(4)
+		quant[ch] = uint(br.read(4))
+		mult[ch] = br.read(3)
+		order[ch] = int(br.read(5))
+		if quant[ch] == 0 {
+			return 0, audio.ErrInvalidData
+		}
+
+		// Coefficients are stored in reverse order
+		for i := order[ch] - 1; i >= 0; i-- {
+			coefs[ch][i] = br.readSigned(16)
+		}
+	}
+
+	// Extra low-order bits are stored uncompressed, before the residuals
+	if extraBits > 0 {
+		for i := 0; i < samples; i++ {
+			for ch := first; ch < first+n; ch++ {
+				d.extra[ch][i] = int32(br.read(extraBits))
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		ch := first + i
+
+		residuals := d.predicted[ch][:samples]
+		d.riceDecode(br, residuals, bps, mult[i]*c.pb/4)
+
+		// Prediction type 15 applies a first order prediction before the
+		// adaptive prediction
+		switch prediction[i] {
+		case 0:
+		case 15:
+			alacPredict(residuals, residuals, bps, nil, 31, 0)
+		default:
+			return 0, audio.ErrInvalidData
+		}
+
+		alacPredict(residuals, d.output[ch][:samples], bps, coefs[i][:order[i]], order[i], quant[i])
+	}
+	if br.err != nil {
+		return 0, audio.ErrInvalidData
+	}
+
+	if n == 2 && weight != 0 {
+		alacDecorrelate(d.output[first][:samples], d.output[first+1][:samples], shift, weight)
+	}
+
+	if extraBits > 0 {
+		for ch := first; ch < first+n; ch++ {
+			for i := 0; i < samples; i++ {
+				d.output[ch][i] = d.output[ch][i]<<extraBits | d.extra[ch][i]
+			}
+		}
+	}
+
+	return samples, nil
+}
+
+// riceDecode decodes adaptive Golomb-Rice coded prediction residuals into out.
+func (d *alacDecoder) riceDecode(br *alacBitReader, out []int32, bps uint, mult uint32) {
+	history := d.config.mb
+	var sign uint32
+
+	for i := 0; i < len(out); i++ {
+		k := alacLog2((history >> 9) + 3)
+		if k > d.config.kb {
+			k = d.config.kb
+		}
+
+		x := br.readScalar(k, bps) + sign
+		sign = 0
+		out[i] = int32(x>>1) ^ -int32(x&1)
+
+		if x > 0xffff {
+			history = 0xffff
+		} else {
+			history += x*mult - (history*mult)>>9
+		}
+
+		// A run of zero samples may follow when the history is small
+		if history < 128 && i+1 < len(out) {
+			k = 7 - alacLog2(history) + (history+16)>>6
+			if k > d.config.kb {
+				k = d.config.kb
+			}
+
+			run := int(br.readScalar(k, 16))
+			if run > 0 {
+				if run >= len(out)-i {
+					br.err = audio.ErrInvalidData
+					run = len(out) - i - 1
+				}
+
+				for j := 0; j < run; j++ {
+					out[i+1+j] = 0
+				}
+				i += run
+			}
+			if run <= 0xffff {
+				sign = 1
+			}
+
+			history = 0
+		}
+	}
+}
+
+// alacPredict reconstructs samples from prediction residuals using an adaptive
+// linear predictor, which adapts its coefficients as each sample is decoded.
+// An order of 31 indicates a simple first order prediction.  residuals and out
+// may be the same slice.
+func alacPredict(residuals []int32, out []int32, bps uint, coefs []int32, order int, quant uint) {
+	if len(residuals) == 0 {
+		return
+	}
+
+	// The first sample is always stored directly
+	out[0] = residuals[0]
+	if len(residuals) == 1 {
+		return
+	}
+
+	if order == 0 {
+		copy(out[1:], residuals[1:])
+		return
+	}
+
+	if order == 31 {
+		for i := 1; i < len(residuals); i++ {
+			out[i] = alacSignExtend(out[i-1]+residuals[i], bps)
+		}
+
+		return
+	}
+
+	// Warm up using first order prediction
+	i := 1
+	for ; i <= order && i < len(residuals); i++ {
+		out[i] = alacSignExtend(out[i-1]+residuals[i], bps)
+	}
+
+	for ; i < len(residuals); i++ {
+		base := out[i-order-1]
+		pred := out[i-order : i]
+
+		// Sums wrap on overflow, as in the reference decoder
+		var sum int32
+		for j, c := range coefs {
+			sum += (pred[j] - base) * c
+		}
+		v := int32((int64(sum) + 1<<(quant-1)) >> quant)
+
+		residual := residuals[i]
+		out[i] = alacSignExtend(v+base+residual, bps)
+
+		// Adapt coefficients toward reducing the residual
+		sign := alacSign(residual)
+		for j := 0; sign != 0 && j < order && residual*sign > 0; j++ {
+			diff := base - pred[j]
+			s := alacSign(diff) * sign
+			coefs[j] -= s
+			residual -= ((diff * s) >> quant) * int32(j+1)
+		}
+	}
+}
+
+// alacDecorrelate reconstructs the left and right channels of a stereo pair,
+// which the encoder decorrelated using the input shift and weight.
+func alacDecorrelate(left []int32, right []int32, shift uint, weight int32) {
+	for i := range left {
+		r := left[i] - (right[i]*weight)>>shift
+		left[i], right[i] = right[i]+r, r
+	}
+}
+
+// alacSignExtend sign extends the low bits of v.
+func alacSignExtend(v int32, bits uint) int32 {
+	shift := 32 - bits
+	return v << shift >> shift
+}
+
+// alacSign returns the sign of v as -1, 0, or 1.
+func alacSign(v int32) int32 {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// alacLog2 returns the integer base 2 logarithm of v, or 0 if v is 0.
+func alacLog2(v uint32) uint32 {
+	if v == 0 {
+		return 0
+	}
+
+	return uint32(bits.Len32(v) - 1)
+}
+
+// alacBitReader reads big-endian bit fields from an ALAC frame.  Reading
+// beyond the end of the frame produces zero bits and sets err.
+type alacBitReader struct {
+	b   []byte
+	pos uint
+	err error
+}
+
+// read reads an unsigned field of up to 32 bits.
+func (br *alacBitReader) read(n uint) uint32 {
+	var v uint64
+	for i := uint(0); i < n; i++ {
+		v = v<<1 | uint64(br.bit())
+	}
+
+	return uint32(v)
+}
+
+// readSigned reads a two's complement signed field of up to 32 bits.
+func (br *alacBitReader) readSigned(n uint) int32 {
+	return alacSignExtend(int32(br.read(n)), n)
+}
+
+// readScalar reads a single adaptive Golomb-Rice coded value with parameter
+// k.  Values with a large quotient are escaped, and stored using n bits.
+func (br *alacBitReader) readScalar(k uint32, n uint) uint32 {
+	// Unary coded quotient, of at most 9 bits
+	var x uint32
+	for x < 9 && br.bit() == 1 {
+		x++
+	}
+	if x == 9 {
+		return br.read(n)
+	}
+
+	if k == 1 {
+		return x
+	}
+
+	// Remainder, where the value 1 is never stored, so that a remainder of 0
+	// is stored using one fewer bits
+	x = x<<k - x
+	rem := br.read(uint(k) - 1)
+	if rem == 0 {
+		return x
+	}
+
+	return x + (rem<<1 | br.bit()) - 1
+}
+
+// bit reads a single bit.
+func (br *alacBitReader) bit() uint32 {
+	if br.pos >= uint(len(br.b))*8 {
+		br.err = audio.ErrInvalidData
+		return 0
+	}
+
+	v := br.b[br.pos/8] >> (7 - br.pos%8) & 1
+	br.pos++
+
+	return uint32(v)
+}
@@ -0,0 +1,421 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestALACDecoderOK verifies that ALAC streams are decoded losslessly, using
+// each coding feature of the format.
+func TestALACDecoderOK(t *testing.T) {
+	// A decaying tone which ends in silence, so that runs of zero residuals
+	// are coded
+	tone := func(n int, amplitude float64, period float64) []int32 {
+		s := make([]int32, n)
+		for i := 0; i < n*3/4; i++ {
+			s[i] = int32(amplitude * (1 - float64(i)/float64(n)) * math.Sin(2*math.Pi*float64(i)/period))
+		}
+
+		return s
+	}
+
+	var tests = []struct {
+		name     string
+		bitDepth int
+		channels [][]int32
+		enc      alacTestEncoder
+		mdat     bool
+	}{
+		{
+			name:     "mono residuals",
+			bitDepth: 16,
+			channels: [][]int32{tone(100, 20000, 16)},
+			enc:      alacTestEncoder{order: 0, quant: 9},
+		},
+		{
+			name:     "mono prediction",
+			bitDepth: 16,
+			channels: [][]int32{tone(100, 20000, 16)},
+			enc:      alacTestEncoder{order: 4, quant: 9, coefs: []int32{160, -190, 100, -30}},
+		},
+		{
+			name:     "mono first order",
+			bitDepth: 24,
+			channels: [][]int32{tone(100, 1<<22, 16)},
+			enc:      alacTestEncoder{order: 31, quant: 9},
+		},
+		{
+			name:     "stereo decorrelated",
+			bitDepth: 16,
+			channels: [][]int32{tone(100, 20000, 16), tone(100, 15000, 16)},
+			enc:      alacTestEncoder{order: 2, quant: 9, coefs: []int32{-512, 1024}, shift: 2, weight: 3},
+			mdat:     true,
+		},
+		{
+			name:     "stereo uncompressed",
+			bitDepth: 24,
+			channels: [][]int32{tone(100, 1<<22, 8), tone(100, -1<<22, 8)},
+			enc:      alacTestEncoder{uncompressed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := makeALAC(tt.bitDepth, 8000, 32, tt.channels, tt.enc, tt.mdat)
+
+			d, _, err := audio.NewDecoder(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			config := d.Config()
+			if config.SampleRate != 8000 || config.Channels != len(tt.channels) {
+				t.Fatalf("unexpected configuration: %+v", config)
+			}
+
+			got := make(audio.Float64, 1000)
+			n, err := readWindow(d, got)
+			if err != audio.EOS {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := len(tt.channels) * len(tt.channels[0])
+			if n != want {
+				t.Fatalf("unexpected number of samples: %d != %d", n, want)
+			}
+
+			scale := float64(int64(1) << uint(tt.bitDepth-1))
+			for i := 0; i < n; i++ {
+				ch, j := i%len(tt.channels), i/len(tt.channels)
+				if w := float64(tt.channels[ch][j]) / scale; got[i] != w {
+					t.Fatalf("[%03d] unexpected sample: %v != %v", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+// TestWaveformComputeALACErrors verifies that malformed and unsupported MP4
+// streams produce appropriate errors.
+func TestWaveformComputeALACErrors(t *testing.T) {
+	samples := [][]int32{make([]int32, 40)}
+	valid := makeALAC(16, 8000, 32, samples, alacTestEncoder{quant: 9}, false)
+
+	// AAC audio is not supported
+	aac := makeMP4("mp4a", nil, [][]byte{{0, 1, 2, 3}}, 1, false)
+
+	// Stream which ends within a frame
+	truncated := valid[:len(valid)-2]
+
+	// Frame with an unknown syntax element
+	unknown := makeMP4("alac", alacCookie(16, 1, 8000, 32), [][]byte{{0xc0}}, 1, false)
+
+	var tests = []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{name: "AAC", b: aac, err: ErrFormat},
+		{name: "truncated", b: truncated, err: ErrUnexpectedEOS},
+		{name: "unknown element", b: unknown, err: ErrInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testWaveformCompute(t, bytes.NewReader(tt.b), tt.err, nil, nil)
+		})
+	}
+}
+
+// TestALACBitReaderScalar verifies that alacBitReader.readScalar decodes
+// values coded by alacBitWriter.writeScalar.
+func TestALACBitReaderScalar(t *testing.T) {
+	var bw alacBitWriter
+	var want []uint32
+	for k := uint32(1); k <= 14; k++ {
+		for _, x := range []uint32{0, 1, 2, 3, 7, 100, 1000, 65535} {
+			bw.writeScalar(x, k, 16)
+			want = append(want, x)
+		}
+	}
+
+	br := &alacBitReader{b: bw.bytes()}
+	var i int
+	for k := uint32(1); k <= 14; k++ {
+		for j := 0; j < 8; j++ {
+			if got := br.readScalar(k, 16); got != want[i] {
+				t.Fatalf("[k=%d] unexpected value: %d != %d", k, got, want[i])
+			}
+			i++
+		}
+	}
+	if br.err != nil {
+		t.Fatalf("unexpected error: %v", br.err)
+	}
+}
+
+// Rice coding parameters used by the reference encoder.
+const (
+	alacTestPB = 40
+	alacTestMB = 10
+	alacTestKB = 14
+)
+
+// alacTestEncoder describes how a test helper encodes ALAC frames.
+type alacTestEncoder struct {
+	uncompressed  bool
+	order         int
+	quant         uint
+	coefs         []int32
+	shift, weight int32
+}
+
+// makeALAC is a test helper which encodes an MP4 stream containing ALAC audio
+// from a slice of samples for each channel, using frames of the input length.
+func makeALAC(bitDepth int, sampleRate int, frameLength int, channels [][]int32, enc alacTestEncoder, mdatFirst bool) []byte {
+	var frames [][]byte
+	for start := 0; start < len(channels[0]); start += frameLength {
+		end := start + frameLength
+		if end > len(channels[0]) {
+			end = len(channels[0])
+		}
+
+		frame := make([][]int32, len(channels))
+		for ch := range channels {
+			frame[ch] = channels[ch][start:end]
+		}
+
+		frames = append(frames, enc.encodeFrame(bitDepth, frameLength, frame))
+	}
+
+	return makeMP4("alac", alacCookie(bitDepth, len(channels), sampleRate, frameLength), frames, 2, mdatFirst)
+}
+
+// alacCookie is a test helper which creates an ALAC specific configuration
+// box.
+func alacCookie(bitDepth int, channels int, sampleRate int, frameLength int) []byte {
+	b := make([]byte, 28)
+	binary.BigEndian.PutUint32(b[4:8], uint32(frameLength))
+	b[9] = byte(bitDepth)
+	b[10] = alacTestPB
+	b[11] = alacTestMB
+	b[12] = alacTestKB
+	b[13] = byte(channels)
+	binary.BigEndian.PutUint32(b[24:28], uint32(sampleRate))
+
+	return mp4Box("alac", b)
+}
+
+// encodeFrame encodes a single frame containing one element, with one or two
+// channels.
+func (enc alacTestEncoder) encodeFrame(bitDepth int, frameLength int, channels [][]int32) []byte {
+	var bw alacBitWriter
+
+	n := len(channels)
+	samples := len(channels[0])
+	hasSize := samples < frameLength
+
+	bw.write(uint32(n-1), 3)
+	bw.write(0, 4+12)
+	bw.writeBool(hasSize)
+	bw.write(0, 2)
+	bw.writeBool(enc.uncompressed)
+	if hasSize {
+		bw.write(uint32(samples), 32)
+	}
+
+	if enc.uncompressed {
+		for i := 0; i < samples; i++ {
+			for ch := range channels {
+				bw.write(uint32(channels[ch][i]), uint(bitDepth))
+			}
+		}
+
+		return bw.finish()
+	}
+
+	// Decorrelate a stereo pair, which the decoder reverses
+	src := channels
+	if n == 2 && enc.weight != 0 {
+		src = [][]int32{make([]int32, samples), make([]int32, samples)}
+		for i := 0; i < samples; i++ {
+			l, r := channels[0][i], channels[1][i]
+			mid := l - r
+			src[0][i], src[1][i] = r+(mid*enc.weight)>>uint(enc.shift), mid
+		}
+	}
+
+	bps := uint(bitDepth + n - 1)
+	bw.write(uint32(enc.shift), 8)
+	bw.write(uint32(enc.weight), 8)
+	for range src {
+		bw.write(0, 4)
+		bw.write(uint32(enc.quant), 4)
+		bw.write(4, 3)
+		bw.write(uint32(enc.order), 5)
+		for i := enc.order - 1; i >= 0; i-- {
+			var c int32
+			if i < len(enc.coefs) {
+				c = enc.coefs[i]
+			}
+			bw.write(uint32(c), 16)
+		}
+	}
+
+	for _, s := range src {
+		coefs := append([]int32(nil), enc.coefs...)
+		bw.writeResiduals(alacTestResiduals(s, bps, coefs, enc.order, enc.quant), bps, 4*alacTestPB/4)
+	}
+
+	return bw.finish()
+}
+
+// alacTestResiduals is a test helper which computes the prediction residuals
+// of a channel, adapting coefficients in the same way as the decoder.
+func alacTestResiduals(s []int32, bps uint, coefs []int32, order int, quant uint) []int32 {
+	r := make([]int32, len(s))
+	if len(s) == 0 {
+		return r
+	}
+
+	r[0] = s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case order == 0:
+			r[i] = s[i]
+		case order == 31 || i <= order:
+			r[i] = s[i] - s[i-1]
+		default:
+			base := s[i-order-1]
+			pred := s[i-order : i]
+
+			var sum int32
+			for j, c := range coefs {
+				sum += (pred[j] - base) * c
+			}
+			v := int32((int64(sum) + 1<<(quant-1)) >> quant)
+
+			residual := s[i] - v - base
+			r[i] = residual
+
+			sign := alacSign(residual)
+			for j := 0; sign != 0 && j < order && residual*sign > 0; j++ {
+				diff := base - pred[j]
+				s := alacSign(diff) * sign
+				coefs[j] -= s
+				residual -= ((diff * s) >> quant) * int32(j+1)
+			}
+		}
+	}
+
+	return r
+}
+
+// alacBitWriter is a test helper which writes big-endian bit fields.
+type alacBitWriter struct {
+	b []byte
+	n uint
+}
+
+// write writes the low n bits of v.
+func (bw *alacBitWriter) write(v uint32, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		if bw.n%8 == 0 {
+			bw.b = append(bw.b, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			bw.b[len(bw.b)-1] |= 1 << (7 - bw.n%8)
+		}
+		bw.n++
+	}
+}
+
+// writeBool writes a single bit.
+func (bw *alacBitWriter) writeBool(v bool) {
+	if v {
+		bw.write(1, 1)
+		return
+	}
+
+	bw.write(0, 1)
+}
+
+// writeScalar writes a single adaptive Golomb-Rice coded value.
+func (bw *alacBitWriter) writeScalar(x uint32, k uint32, n uint) {
+	m := uint32(1)<<k - 1
+	q := x / m
+	if q >= 9 {
+		bw.write(0x1ff, 9)
+		bw.write(x, n)
+		return
+	}
+
+	bw.write(1<<q-1, uint(q))
+	bw.write(0, 1)
+	if k == 1 {
+		return
+	}
+
+	if r := x % m; r == 0 {
+		bw.write(0, uint(k)-1)
+	} else {
+		bw.write(r+1, uint(k))
+	}
+}
+
+// writeResiduals writes prediction residuals using adaptive Golomb-Rice
+// coding, in the same way as the reference encoder.
+func (bw *alacBitWriter) writeResiduals(r []int32, bps uint, mult uint32) {
+	history := uint32(alacTestMB)
+	var sign uint32
+
+	for i := 0; i < len(r); i++ {
+		k := alacLog2((history >> 9) + 3)
+		if k > alacTestKB {
+			k = alacTestKB
+		}
+
+		x := uint32(r[i]<<1) ^ uint32(r[i]>>31)
+		bw.writeScalar(x-sign, k, bps)
+		sign = 0
+
+		if x > 0xffff {
+			history = 0xffff
+		} else {
+			history += x*mult - (history*mult)>>9
+		}
+
+		// Code a run of zero residuals when the history is small
+		if history < 128 && i+1 < len(r) {
+			k = 7 - alacLog2(history) + (history+16)>>6
+			if k > alacTestKB {
+				k = alacTestKB
+			}
+
+			var run int
+			for i+1+run < len(r) && r[i+1+run] == 0 && run < 0xffff {
+				run++
+			}
+
+			bw.writeScalar(uint32(run), k, 16)
+			i += run
+			sign = 1
+			history = 0
+		}
+	}
+}
+
+// bytes returns the bits written so far.
+func (bw *alacBitWriter) bytes() []byte {
+	return bw.b
+}
+
+// finish writes the end of a frame, and returns the complete frame.
+func (bw *alacBitWriter) finish() []byte {
+	bw.write(alacElementEnd, 3)
+	return bw.b
+}
@@ -0,0 +1,73 @@
+package waveform
+
+import (
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// Chunk is a window of decoded audio samples, which is passed to each Analyzer
+// during computation.
+type Chunk struct {
+	// Window is the index of the window in the audio stream.
+	Window int
+
+	// Start is the time offset of the first sample in the window, relative
+	// to the beginning of the audio stream.
+	Start time.Duration
+
+	// Samples are the interleaved audio samples which were actually read for
+	// the window.  The final window of a stream is typically shorter than
+	// all others.  Samples is only valid for the duration of a call to an
+	// Analyzer, and must be copied if it is retained.
+	//
+	// Channels are not combined by the Downmix or DownmixFunction options
+	// before Samples are passed to an Analyzer.
+	Samples audio.Float64
+
+	// Config is the configuration of the audio stream, before any channels
+	// are combined.
+	Config audio.Config
+}
+
+// An Analyzer receives each window of decoded audio samples during
+// computation, so that additional analysis can be performed using the same
+// decode pass which is used to generate a waveform.
+//
+// If Analyze returns an error, computation stops and the error is returned.
+// Windows which are skipped when resuming from a Checkpoint are not passed to
+// an Analyzer.
+type Analyzer interface {
+	Analyze(c *Chunk) error
+}
+
+// AnalyzerFunc is an adapter which allows an ordinary function to be used as
+// an Analyzer.
+type AnalyzerFunc func(c *Chunk) error
+
+// Analyze calls fn(c).
+func (fn AnalyzerFunc) Analyze(c *Chunk) error {
+	return fn(c)
+}
+
+// analyze passes a window of audio samples to each of the input analyzers.
+func (w *Waveform) analyze(analyzers []Analyzer, config audio.Config, window int, samples audio.Float64) error {
+	if len(analyzers) == 0 {
+		return nil
+	}
+
+	c := &Chunk{
+		Window:  window,
+		Start:   windowStart(config, w.resolution, window),
+		Samples: samples,
+		Config:  config,
+	}
+
+	for _, a := range analyzers {
+		if err := a.Analyze(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
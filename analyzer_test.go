@@ -0,0 +1,73 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWaveformComputeAnalyzers verifies that each Analyzer receives every
+// window of audio samples read during computation.
+func TestWaveformComputeAnalyzers(t *testing.T) {
+	// One and a half seconds of mono, 8kHz audio, read 2 times per second
+	var chunks []Chunk
+	fn := AnalyzerFunc(func(c *Chunk) error {
+		chunks = append(chunks, *c)
+		return nil
+	})
+
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, make([]int16, 12000))),
+		Resolution(2),
+		Analyzers(fn),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		start   time.Duration
+		samples int
+	}{
+		{0, 4000},
+		{500 * time.Millisecond, 4000},
+		{time.Second, 4000},
+	}
+
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected number of chunks: %v != %v", len(chunks), len(want))
+	}
+	for i, c := range chunks {
+		if c.Window != i {
+			t.Fatalf("unexpected window at index %d: %v != %v", i, c.Window, i)
+		}
+		if c.Start != want[i].start {
+			t.Fatalf("unexpected start at index %d: %v != %v", i, c.Start, want[i].start)
+		}
+		if len(c.Samples) != want[i].samples {
+			t.Fatalf("unexpected samples at index %d: %v != %v", i, len(c.Samples), want[i].samples)
+		}
+	}
+}
+
+// TestWaveformComputeAnalyzerError verifies that an error returned by an
+// Analyzer stops computation.
+func TestWaveformComputeAnalyzerError(t *testing.T) {
+	errAnalyze := errors.New("analyze failed")
+	fn := AnalyzerFunc(func(c *Chunk) error {
+		return errAnalyze
+	})
+
+	w, err := New(bytes.NewReader(wavFile), Analyzers(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != errAnalyze {
+		t.Fatalf("unexpected Compute error: %v != %v", err, errAnalyze)
+	}
+}
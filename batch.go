@@ -0,0 +1,166 @@
+package waveform
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// NamedReader is an input audio stream, along with a name which is used to
+// identify it in the results of a batch operation.
+type NamedReader struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Result is the result of generating a waveform image from a single input
+// audio stream, as part of a batch operation.
+type Result struct {
+	// Name is the name of the input audio stream.
+	Name string
+
+	// Image is the generated waveform image, and Values are the values computed
+	// from the input audio stream to draw it.
+	Image  image.Image
+	Values []float64
+
+	// Timings reports how long each stage of generating the waveform image
+	// took.
+	Timings Timings
+
+	// Err is any error which occurred while generating a waveform image from
+	// the input audio stream.
+	Err error
+}
+
+// BatchError is an error which is returned when waveform image generation fails
+// for one or more inputs in a batch operation.  The Result for each failed input
+// contains its individual error.
+type BatchError struct {
+	Failed int
+	Total  int
+}
+
+// Error returns the string representation of a BatchError.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("waveform: generation failed for %d of %d inputs", e.Failed, e.Total)
+}
+
+// GenerateAll generates waveform images from a slice of input audio streams,
+// applying the same zero or more, variadic, OptionsFunc parameters to each.
+//
+// GenerateAll is equivalent to GenerateAllContext, using a background context.
+func GenerateAll(inputs []NamedReader, options ...OptionsFunc) ([]Result, error) {
+	return GenerateAllContext(context.Background(), inputs, options...)
+}
+
+// GenerateAllContext generates waveform images from a slice of input audio
+// streams, applying the same zero or more, variadic, OptionsFunc parameters
+// to each.  Inputs are processed concurrently by a bounded number of workers.
+//
+// One Result is returned for each input, in the same order as the inputs.  If
+// generation fails for any input, a *BatchError is returned, and each failed
+// Result contains its individual error.  If the context is canceled, inputs
+// which have not yet been processed are skipped, and the context's error is
+// returned.
+//
+// The number of workers is set by the Workers option, and is DefaultWorkers
+// otherwise.  Each worker holds the decoded windows, computed values, and
+// image of a single input at a time, so the MaxDuration option can be used to
+// bound the memory used by each worker.
+//
+// Because options are shared between all inputs, any ColorFunc or
+// SampleReduceFunc used must be safe for concurrent use.
+func GenerateAllContext(ctx context.Context, inputs []NamedReader, options ...OptionsFunc) ([]Result, error) {
+	results := make([]Result, len(inputs))
+
+	// Use the configured number of workers, if the options are valid.
+	// Otherwise, each input reports the invalid options in its Result.
+	workers := DefaultWorkers()
+	if w, err := New(nil, options...); err == nil && w.workers > 0 {
+		workers = int(w.workers)
+	}
+
+	// Never start more workers than there are inputs
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+
+	// Feed indices of inputs to workers, until all inputs are processed or the
+	// context is canceled
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+
+		for i := range inputs {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				results[i] = generateResult(inputs[i], options)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Mark any inputs skipped due to cancelation
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if results[i].Image == nil && results[i].Err == nil {
+				results[i] = Result{Name: inputs[i].Name, Err: err}
+			}
+		}
+
+		return results, err
+	}
+
+	// Report any individual failures
+	var failed int
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, &BatchError{
+			Failed: failed,
+			Total:  len(results),
+		}
+	}
+
+	return results, nil
+}
+
+// DefaultWorkers returns the default number of workers used for concurrent
+// waveform generation, which is three quarters of GOMAXPROCS, rounded up.
+// Leaving a share of the available CPUs idle allows an application to remain
+// responsive while generating waveforms, and because GOMAXPROCS is used rather
+// than the number of CPUs on the host, CPU limits which are applied to
+// GOMAXPROCS in containers are respected.
+func DefaultWorkers() int {
+	procs := runtime.GOMAXPROCS(0)
+	return procs - procs/4
+}
+
+// generateResult generates a waveform image from a single input, returning
+// a Result.
+func generateResult(input NamedReader, options []OptionsFunc) Result {
+	res := Result{Name: input.Name}
+	res.Image, res.Values, res.Timings, res.Err = generate(input.Reader, options)
+
+	return res
+}
@@ -0,0 +1,213 @@
+package waveform
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestGenerateAllOK verifies that GenerateAll generates an image for each
+// input, in order.
+func TestGenerateAllOK(t *testing.T) {
+	inputs := []NamedReader{
+		{Name: "a", Reader: bytes.NewReader(wavFile)},
+		{Name: "b", Reader: bytes.NewReader(wavFile)},
+		{Name: "c", Reader: bytes.NewReader(wavFile)},
+	}
+
+	results, err := GenerateAll(inputs, Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(inputs) {
+		t.Fatalf("unexpected results length: %v != %v", len(results), len(inputs))
+	}
+	for i, r := range results {
+		if r.Name != inputs[i].Name {
+			t.Fatalf("[%02d] unexpected name: %v != %v", i, r.Name, inputs[i].Name)
+		}
+		if r.Err != nil {
+			t.Fatalf("[%02d] unexpected error: %v", i, r.Err)
+		}
+		if x := r.Image.Bounds().Max.X; x != 2*len(r.Values) {
+			t.Fatalf("[%02d] unexpected image width: %v != %v", i, x, 2*len(r.Values))
+		}
+		if r.Timings.Compute <= 0 || r.Timings.Draw <= 0 {
+			t.Fatalf("[%02d] unexpected timings: %+v", i, r.Timings)
+		}
+	}
+}
+
+// TestGenerateAllBatchError verifies that GenerateAll reports individual
+// failures in each Result, and returns a BatchError.
+func TestGenerateAllBatchError(t *testing.T) {
+	inputs := []NamedReader{
+		{Name: "ok", Reader: bytes.NewReader(wavFile)},
+		{Name: "mp3", Reader: bytes.NewReader(mp3File)},
+	}
+
+	results, err := GenerateAll(inputs)
+	bErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if bErr.Failed != 1 || bErr.Total != 2 {
+		t.Fatalf("unexpected BatchError: %v", bErr)
+	}
+
+	if results[0].Err != nil {
+		t.Fatalf("unexpected error: %v", results[0].Err)
+	}
+	if results[1].Err != ErrFormat {
+		t.Fatalf("unexpected error: %v != %v", results[1].Err, ErrFormat)
+	}
+}
+
+// TestGenerateAllInvalidValues verifies that GenerateAll reports invalid
+// values in each Result, in the same way as Generate.
+func TestGenerateAllInvalidValues(t *testing.T) {
+	nan := SampleFunction(func(audio.Float64) float64 {
+		return math.NaN()
+	})
+
+	_, want := Generate(bytes.NewReader(wavFile), nan, InvalidValues(InvalidValueError))
+	if _, ok := want.(*ValueError); !ok {
+		t.Fatalf("unexpected error type: %T", want)
+	}
+
+	results, err := GenerateAll([]NamedReader{
+		{Name: "nan", Reader: bytes.NewReader(wavFile)},
+	}, nan, InvalidValues(InvalidValueError))
+	if _, ok := err.(*BatchError); !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+
+	if got := results[0].Err; got == nil || got.Error() != want.Error() {
+		t.Fatalf("unexpected error: %v != %v", got, want)
+	}
+}
+
+// TestGenerateAllProportionalTime verifies that GenerateAll draws columns
+// proportional to time, in the same way as Generate.
+func TestGenerateAllProportionalTime(t *testing.T) {
+	// 2.5 seconds of audio
+	wav := makeWAV(8000, 1, make([]int16, 20000))
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		width   int
+	}{
+		{name: "proportional", options: []OptionsFunc{Scale(10, 1), ProportionalTime()}, width: 25},
+		{name: "pixels per second", options: []OptionsFunc{PixelsPerSecond(4)}, width: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := GenerateAll([]NamedReader{
+				{Name: "wav", Reader: bytes.NewReader(wav)},
+			}, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := results[0].Image.Bounds().Dx(); got != tt.width {
+				t.Fatalf("unexpected image width: %v != %v", got, tt.width)
+			}
+			if len(results[0].Values) == 0 {
+				t.Fatal("no values in result")
+			}
+		})
+	}
+}
+
+// TestGenerateAllContextCanceled verifies that GenerateAllContext skips inputs
+// once its context is canceled.
+func TestGenerateAllContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	inputs := []NamedReader{
+		{Name: "a", Reader: bytes.NewReader(wavFile)},
+	}
+
+	results, err := GenerateAllContext(ctx, inputs)
+	if err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+
+	// Input may have started before cancelation was observed
+	if r := results[0]; r.Err != nil && r.Err != context.Canceled {
+		t.Fatalf("unexpected result error: %v", r.Err)
+	}
+}
+
+// TestGenerateAllWorkers verifies that GenerateAll processes no more inputs
+// concurrently than the number of workers set by the Workers option.
+func TestGenerateAllWorkers(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		active, max int
+	)
+
+	// Track the number of inputs being reduced at once
+	fn := func(samples audio.Float64) float64 {
+		mu.Lock()
+		active++
+		if active > max {
+			max = active
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		return RMSF64Samples(samples)
+	}
+
+	var inputs []NamedReader
+	for i := 0; i < 4; i++ {
+		inputs = append(inputs, NamedReader{Reader: bytes.NewReader(wavFile)})
+	}
+
+	if _, err := GenerateAll(inputs, SampleFunction(fn), Workers(1)); err != nil {
+		t.Fatal(err)
+	}
+	if max != 1 {
+		t.Fatalf("unexpected maximum concurrency: %d", max)
+	}
+
+	testWaveformOptionFunc(t, Workers(0), errWorkersZero)
+}
+
+// TestDefaultWorkers verifies that DefaultWorkers uses a share of GOMAXPROCS.
+func TestDefaultWorkers(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	var tests = []struct {
+		procs, workers int
+	}{
+		{1, 1},
+		{2, 2},
+		{4, 3},
+		{8, 6},
+		{16, 12},
+	}
+
+	for _, tt := range tests {
+		runtime.GOMAXPROCS(tt.procs)
+		if got := DefaultWorkers(); got != tt.workers {
+			t.Fatalf("unexpected workers for GOMAXPROCS %d: %d != %d", tt.procs, got, tt.workers)
+		}
+	}
+}
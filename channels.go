@@ -0,0 +1,169 @@
+package waveform
+
+import "azul3d.org/engine/audio"
+
+// errChannelsCheckpoint is returned when values are computed for each channel
+// while checkpointing is enabled, because a Checkpoint contains only a single
+// series of values.
+var errChannelsCheckpoint = &OptionsError{
+	Option: "checkpointEvery",
+	Reason: "checkpoints cannot be written while computing channels",
+	Code:   CodeConflict,
+}
+
+// ComputeChannels computes values in the same way as Compute, but computes a
+// separate series of values for each channel of the audio stream, rather than
+// reducing the samples of all channels to a single value.  The outer slice has
+// one element for each channel, in the order the channels are stored in the
+// stream, and every series has the same number of values.
+//
+// ComputeChannels allows applications to draw each channel of a stereo stream
+// separately, or to compare the levels of channels, such as to detect an
+// imbalance between the left and right channels.  If a Downmix or
+// DownmixFunction option is in use, channels are computed after downmixing,
+// so that, for example, DownmixFirst can select the channels which are
+// computed.  ComputeChannels cannot be used with CheckpointEvery.
+func (w *Waveform) ComputeChannels() ([][]float64, error) {
+	sets, err := w.ComputeChannelValueSets()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]float64, 0, len(sets))
+	for _, vs := range sets {
+		out = append(out, vs.Values)
+	}
+
+	return out, nil
+}
+
+// ComputeChannelValueSets creates a ValueSet for each channel of the audio
+// stream.
+//
+// ComputeChannelValueSets is equivalent to ComputeChannels, but also returns
+// information about how each value was computed.  Counts in each ValueSet are
+// the number of samples of a single channel which were reduced to produce
+// each value.
+func (w *Waveform) ComputeChannelValueSets() ([]*ValueSet, error) {
+	if w.checkpointW != nil {
+		return nil, errChannelsCheckpoint
+	}
+
+	cw := w.clone()
+	cw.perChannel = true
+
+	vs, err := cw.readAndComputeSamples(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return vs.channels, nil
+}
+
+// channelSplitter computes a separate series of values for each channel of
+// an audio stream, by reducing the samples of each channel of a window
+// independently.
+type channelSplitter struct {
+	w *Waveform
+
+	// config is the configuration of a single channel of the stream
+	config audio.Config
+
+	// sets holds the values computed for each channel, and bufs holds the
+	// samples of each channel of the current window
+	sets []*ValueSet
+	bufs []audio.Float64
+
+	// lasts holds the value of the last window of each channel which was
+	// reduced, for use with DownsampleAfter
+	lasts []float64
+
+	// wds, if set, compute values from overlapping windows of each channel
+	wds []*windower
+}
+
+// newChannelSplitter creates a channelSplitter for the receiving Waveform
+// struct, which reads audio with the input configuration.
+func (w *Waveform) newChannelSplitter(config audio.Config) *channelSplitter {
+	mono := config
+	mono.Channels = 1
+
+	cs := &channelSplitter{
+		w:      w,
+		config: mono,
+		sets:   make([]*ValueSet, config.Channels),
+		bufs:   make([]audio.Float64, config.Channels),
+		lasts:  make([]float64, config.Channels),
+	}
+	for c := range cs.sets {
+		cs.sets[c] = new(ValueSet)
+	}
+
+	if w.overlap > 1 || w.windowFn != nil {
+		cs.wds = make([]*windower, config.Channels)
+		for c := range cs.wds {
+			cs.wds[c] = w.newWindower(mono)
+		}
+	}
+
+	return cs
+}
+
+// push reduces each channel of the window with the input index, of which n
+// interleaved samples were read.
+func (cs *channelSplitter) push(window int, samples audio.Float64, n int) error {
+	channels := len(cs.sets)
+	frames, read := len(samples)/channels, n/channels
+
+	for c := range cs.bufs {
+		// Deinterleave the samples of this channel, including any stale
+		// samples past the end of a partial window, which are handled by the
+		// PartialWindowPolicy
+		buf := cs.bufs[c][:0]
+		for i := 0; i < frames; i++ {
+			buf = append(buf, samples[i*channels+c])
+		}
+		cs.bufs[c] = buf
+
+		if cs.wds != nil {
+			if read > 0 {
+				if err := cs.wds[c].push(window, buf, read, cs.emit(c)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if value, ok := cs.w.reduceOrHold(cs.config, window, buf, read, &cs.lasts[c]); ok {
+			cs.sets[c].append(cs.config, value, read)
+		}
+	}
+
+	return nil
+}
+
+// flush computes the values of any windows which remain once the end of the
+// stream is reached.
+func (cs *channelSplitter) flush() error {
+	for c, wd := range cs.wds {
+		if err := wd.flush(cs.emit(c)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emit returns a function which stores values computed from the channel with
+// the input index.
+func (cs *channelSplitter) emit(c int) func(value float64, n int) error {
+	return func(value float64, n int) error {
+		cs.sets[c].append(cs.config, value, n)
+		return nil
+	}
+}
+
+// empty reports whether no values were computed for any channel.
+func (cs *channelSplitter) empty() bool {
+	return len(cs.sets) == 0 || len(cs.sets[0].Values) == 0
+}
@@ -0,0 +1,106 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestWaveformComputeChannels verifies that ComputeChannels computes a
+// separate series of values for each channel of a stream.
+func TestWaveformComputeChannels(t *testing.T) {
+	// Stereo audio at 4Hz, read at 2 windows per second: the left channel is
+	// at full scale and the right at half scale, then the reverse, with a
+	// partial final window
+	wav := makeWAV(4, 2, []int16{
+		32767, 16384, 32767, 16384,
+		16384, 32767, 16384, 32767,
+		32767, 0,
+	})
+
+	w, err := New(bytes.NewReader(wav), Resolution(2), SampleFunction(AverageF64Samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets, err := w.ComputeChannelValueSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sets) != 2 {
+		t.Fatalf("unexpected number of channels: %d", len(sets))
+	}
+
+	half := 16384.0 / 32767
+	want := [][]float64{{1, half, 1}, {half, 1, 0}}
+	for c := range want {
+		if len(sets[c].Values) != len(want[c]) {
+			t.Fatalf("[%d] unexpected number of values: %d != %d", c, len(sets[c].Values), len(want[c]))
+		}
+		for i := range want[c] {
+			if math.Abs(sets[c].Values[i]-want[c][i]) > 1e-9 {
+				t.Fatalf("[%d] unexpected values: %v != %v", c, sets[c].Values, want[c])
+			}
+		}
+
+		if got, want := fmt.Sprint(sets[c].Counts), "[2 2 1]"; got != want {
+			t.Fatalf("[%d] unexpected counts: %v != %v", c, got, want)
+		}
+		if got, want := fmt.Sprint(sets[c].Durations), "[500ms 500ms 250ms]"; got != want {
+			t.Fatalf("[%d] unexpected durations: %v != %v", c, got, want)
+		}
+	}
+}
+
+// TestWaveformComputeChannelsMono verifies that ComputeChannels computes the
+// same values as Compute for a mono stream.
+func TestWaveformComputeChannelsMono(t *testing.T) {
+	options := []OptionsFunc{Resolution(4), Overlap(3), WindowFunction(HannWindow)}
+
+	w, err := New(bytes.NewReader(wavFile), append(options, Downmix(DownmixAverage))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = New(bytes.NewReader(wavFile), append(options, Downmix(DownmixAverage))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels, err := w.ComputeChannels()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(channels) != 1 {
+		t.Fatalf("unexpected number of channels: %d", len(channels))
+	}
+	if got, want := fmt.Sprint(channels[0]), fmt.Sprint(want); got != want {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// TestWaveformComputeChannelsErrors verifies that ComputeChannels returns
+// errors for streams which are too short, and for use with checkpoints.
+func TestWaveformComputeChannelsErrors(t *testing.T) {
+	w, err := New(bytes.NewReader(makeWAV(4, 2, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ComputeChannels(); err != ErrTooShort {
+		t.Fatalf("unexpected error: %v != %v", err, ErrTooShort)
+	}
+
+	w, err = New(bytes.NewReader(wavFile), CheckpointEvery(1, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ComputeChannels(); err != errChannelsCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errChannelsCheckpoint)
+	}
+}
@@ -0,0 +1,179 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"azul3d.org/engine/audio"
+)
+
+var (
+	// ErrNoCheckpoint is returned by ReadCheckpoint when no complete Checkpoint
+	// is found in the input stream.
+	ErrNoCheckpoint = errors.New("waveform: no checkpoint found")
+
+	// errCheckpointMismatch is returned when a Checkpoint does not match the
+	// audio stream or Waveform used to resume a computation.
+	errCheckpointMismatch = errors.New("waveform: checkpoint does not match input stream")
+)
+
+// Checkpoint is a snapshot of the state of a computation, which can be used
+// to resume it at a later time.
+type Checkpoint struct {
+	// Window is the number of windows of audio which were read from the
+	// stream when the Checkpoint was taken.
+	Window int `json:"window"`
+
+	// Values and Counts are the partial ValueSet computed from the stream when
+	// the Checkpoint was taken, beginning with the value at index Start.
+	//
+	// Each Checkpoint written by the CheckpointEvery option contains only the
+	// values computed since the previous Checkpoint, so that the size of the
+	// output grows linearly with the length of the stream.  ReadCheckpoint
+	// combines them, so the Checkpoint it returns always has a Start of 0.
+	Start  int       `json:"start"`
+	Values []float64 `json:"values"`
+	Counts []int     `json:"counts"`
+
+	// SampleRate, Channels, and Resolution are used to verify that a
+	// computation is resumed using the same stream and options.
+	SampleRate int  `json:"sample_rate"`
+	Channels   int  `json:"channels"`
+	Resolution uint `json:"resolution"`
+}
+
+// ReadCheckpoint reads the most recent complete Checkpoint from an input
+// stream of Checkpoints, written using the CheckpointEvery option, combining
+// the values of each Checkpoint with those of the Checkpoints before it.
+//
+// A trailing, incomplete Checkpoint, such as one which was being written when
+// a process was interrupted, is ignored.  If no complete Checkpoint is found,
+// ErrNoCheckpoint is returned.
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var last *Checkpoint
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	for s.Scan() {
+		cp := new(Checkpoint)
+		if err := json.Unmarshal(s.Bytes(), cp); err != nil {
+			// Incomplete checkpoints can only occur at end of stream
+			break
+		}
+
+		// Each Checkpoint begins a new computation, or continues the values
+		// of the previous Checkpoint.  Any other Checkpoint cannot be
+		// combined with the ones before it.
+		switch {
+		case cp.Start == 0:
+		case last != nil && cp.Start == len(last.Values):
+			cp.Values = append(last.Values, cp.Values...)
+			cp.Counts = append(last.Counts, cp.Counts...)
+			cp.Start = 0
+		default:
+			return last, checkNoCheckpoint(last)
+		}
+
+		last = cp
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return last, checkNoCheckpoint(last)
+}
+
+// checkNoCheckpoint returns ErrNoCheckpoint if no complete Checkpoint was
+// read by ReadCheckpoint.
+func checkNoCheckpoint(cp *Checkpoint) error {
+	if cp == nil {
+		return ErrNoCheckpoint
+	}
+
+	return nil
+}
+
+// Resume resumes a computation from a Checkpoint, returning the complete
+// ValueSet for the input audio stream.
+//
+// The receiving Waveform must be created using the same audio stream and
+// options as the computation which produced the Checkpoint.  If the input
+// stream implements io.Seeker, it is first rewound to its beginning.
+//
+// Uncompressed WAV and AIFF streams which implement io.Seeker are seeked
+// directly to the first window after the Checkpoint, so resuming near the end
+// of a long stream is fast.  Streams in all other formats must be decoded from
+// their beginning, so the windows which were read before the Checkpoint was
+// taken are decoded again, but are not reduced again.
+//
+// Computations which cannot be checkpointed, such as those which use the
+// Overlap option, cannot be resumed, and produce an *OptionsError.
+func (w *Waveform) Resume(cp *Checkpoint) (*ValueSet, error) {
+	if err := w.checkpointConflict(); err != nil {
+		return nil, err
+	}
+
+	if s, ok := w.r.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.readAndComputeSamples(cp)
+}
+
+// checkpointConflict returns an *OptionsError if the receiving Waveform struct
+// computes values in a way which cannot be recorded in a Checkpoint, and so
+// cannot be checkpointed or resumed.
+func (w *Waveform) checkpointConflict() error {
+	switch {
+	case w.overlap > 1:
+		// Overlapping windows depend on the audio before each window
+		return errOverlapConflictsCheckpoint
+	case w.perChannel:
+		return errChannelsCheckpoint
+	case w.valueFn != nil:
+		return errValueFuncCheckpoint
+	case len(w.levels) > 0:
+		return errPyramidCheckpoint
+	}
+
+	return nil
+}
+
+// validate verifies that a Checkpoint can be used to resume computation on an
+// audio stream with the input configuration, at the input resolution.
+func (cp *Checkpoint) validate(config audio.Config, resolution uint) error {
+	if cp.SampleRate != config.SampleRate || cp.Channels != config.Channels || cp.Resolution != resolution {
+		return errCheckpointMismatch
+	}
+	if cp.Window < 0 || cp.Start != 0 || len(cp.Values) != len(cp.Counts) {
+		return errCheckpointMismatch
+	}
+
+	return nil
+}
+
+// writeCheckpoint writes a Checkpoint containing the current state of
+// a computation to the checkpoint io.Writer of the receiving Waveform struct,
+// including only the values of vs from index start onward.  Each Checkpoint is
+// written as a single line of JSON.
+func (w *Waveform) writeCheckpoint(config audio.Config, window int, vs *ValueSet, start int) error {
+	b, err := json.Marshal(&Checkpoint{
+		Window:     window,
+		Start:      start,
+		Values:     vs.Values[start:],
+		Counts:     vs.Counts[start:],
+		SampleRate: config.SampleRate,
+		Channels:   config.Channels,
+		Resolution: w.resolution,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.checkpointW.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,284 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWaveformResumeCheckpoint verifies that a computation resumed from
+// a Checkpoint produces the same ValueSet as an uninterrupted computation.
+func TestWaveformResumeCheckpoint(t *testing.T) {
+	// Compute values while writing checkpoints
+	buf := bytes.NewBuffer(nil)
+	w, err := New(bytes.NewReader(wavFile), CheckpointEvery(2, buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an interruption partway through writing a checkpoint
+	buf.WriteString(`{"window":`)
+
+	cp, err := ReadCheckpoint(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Window != 4 || len(cp.Values) != 4 {
+		t.Fatalf("unexpected checkpoint: window %d, %d values", cp.Window, len(cp.Values))
+	}
+
+	// Resume from the same, seekable input
+	got, err := w.Resume(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Values) != len(want.Values) {
+		t.Fatalf("unexpected values length: %v != %v", len(got.Values), len(want.Values))
+	}
+	for i := range want.Values {
+		if got.Values[i] != want.Values[i] || got.Counts[i] != want.Counts[i] {
+			t.Fatalf("unexpected value at index %d: %v != %v", i, got.Values[i], want.Values[i])
+		}
+	}
+}
+
+// TestWaveformResumeCheckpointSeek verifies that a computation resumed from
+// a Checkpoint on an uncompressed, seekable stream does not read the audio
+// which precedes the Checkpoint.
+func TestWaveformResumeCheckpointSeek(t *testing.T) {
+	samples := make([]int16, 64)
+	for i := range samples {
+		samples[i] = int16(i * 256)
+	}
+
+	var tests = []struct {
+		description string
+		stream      []byte
+	}{
+		{
+			description: "WAV",
+			stream:      makeWAV(8, 1, samples),
+		},
+		{
+			description: "AIFF",
+			stream:      makeAIFF("AIFF", "", 16, 8, 1, samples),
+		},
+	}
+
+	for _, test := range tests {
+		want, err := New(bytes.NewReader(test.stream))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantVS, err := want.ComputeValueSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cp := &Checkpoint{
+			Window:     4,
+			Values:     wantVS.Values[:4],
+			Counts:     wantVS.Counts[:4],
+			SampleRate: 8,
+			Channels:   1,
+			Resolution: 1,
+		}
+
+		r := &countReader{Reader: bytes.NewReader(test.stream)}
+		w, err := New(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := w.Resume(cp)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", test.description, err)
+		}
+
+		if len(got.Values) != len(wantVS.Values) {
+			t.Fatalf("[%s] unexpected values length: %v != %v", test.description, len(got.Values), len(wantVS.Values))
+		}
+		for i := range wantVS.Values {
+			if got.Values[i] != wantVS.Values[i] || got.Counts[i] != wantVS.Counts[i] {
+				t.Fatalf("[%s] unexpected value at index %d: %v != %v", test.description, i, got.Values[i], wantVS.Values[i])
+			}
+		}
+
+		// The 32 sample frames before the Checkpoint should not be read
+		if max := int64(len(test.stream) - 32*2); r.n > max {
+			t.Fatalf("[%s] read too many bytes: %v > %v", test.description, r.n, max)
+		}
+	}
+}
+
+// TestWaveformCheckpointIncremental verifies that each Checkpoint written
+// during a computation contains only the values computed since the previous
+// Checkpoint.
+func TestWaveformCheckpointIncremental(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w, err := New(bytes.NewReader(makeWAV(8, 1, make([]int16, 64))), CheckpointEvery(2, buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ComputeValueSet(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	s := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for s.Scan() {
+		cp := new(Checkpoint)
+		if err := json.Unmarshal(s.Bytes(), cp); err != nil {
+			t.Fatal(err)
+		}
+
+		if cp.Start != n || len(cp.Values) != 2 {
+			t.Fatalf("unexpected checkpoint: start %d, %d values", cp.Start, len(cp.Values))
+		}
+		n += len(cp.Values)
+	}
+	if n != 8 {
+		t.Fatalf("unexpected number of checkpointed values: %v != %v", n, 8)
+	}
+
+	cp, err := ReadCheckpoint(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Window != 8 || cp.Start != 0 || len(cp.Values) != 8 || len(cp.Counts) != 8 {
+		t.Fatalf("unexpected checkpoint: window %d, start %d, %d values", cp.Window, cp.Start, len(cp.Values))
+	}
+}
+
+// TestReadCheckpointGap verifies that ReadCheckpoint returns the last
+// Checkpoint which can be combined with those before it.
+func TestReadCheckpointGap(t *testing.T) {
+	cp, err := ReadCheckpoint(strings.NewReader(strings.Join([]string{
+		`{"window":1,"start":0,"values":[1],"counts":[1]}`,
+		`{"window":2,"start":1,"values":[2],"counts":[1]}`,
+		`{"window":4,"start":3,"values":[4],"counts":[1]}`,
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cp.Window != 2 || len(cp.Values) != 2 || cp.Values[1] != 2 {
+		t.Fatalf("unexpected checkpoint: window %d, values %v", cp.Window, cp.Values)
+	}
+}
+
+// countReader is an io.ReadSeeker which counts the bytes read from it.
+type countReader struct {
+	*bytes.Reader
+	n int64
+}
+
+func (r *countReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// TestWaveformResumeCheckpointMismatch verifies that a Checkpoint cannot be
+// used to resume a computation with different options.
+func TestWaveformResumeCheckpointMismatch(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile), Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := &Checkpoint{
+		SampleRate: 44100,
+		Channels:   2,
+		Resolution: 1,
+	}
+	if _, err := w.Resume(cp); err != errCheckpointMismatch {
+		t.Fatalf("unexpected error: %v != %v", err, errCheckpointMismatch)
+	}
+}
+
+// TestWaveformCheckpointConflicts verifies that computations which cannot be
+// recorded in a Checkpoint can be neither checkpointed nor resumed, and
+// produce an *OptionsError.
+func TestWaveformCheckpointConflicts(t *testing.T) {
+	noop := func(int, float64, time.Duration) error { return nil }
+
+	var tests = []struct {
+		name   string
+		modify func(w *Waveform)
+		err    error
+	}{
+		{
+			name:   "overlap",
+			modify: func(w *Waveform) { w.overlap = 2 },
+			err:    errOverlapConflictsCheckpoint,
+		},
+		{
+			name:   "channels",
+			modify: func(w *Waveform) { w.perChannel = true },
+			err:    errChannelsCheckpoint,
+		},
+		{
+			name:   "value function",
+			modify: func(w *Waveform) { w.valueFn = noop },
+			err:    errValueFuncCheckpoint,
+		},
+		{
+			name:   "pyramid",
+			modify: func(w *Waveform) { w.levels = []Analyzer{&pyramidLevel{}} },
+			err:    errPyramidCheckpoint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.err.(*OptionsError); !ok {
+				t.Fatalf("unexpected error type: %T", tt.err)
+			}
+
+			w, err := New(bytes.NewReader(wavFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(w)
+
+			if _, err := w.Resume(&Checkpoint{}); err != tt.err {
+				t.Fatalf("unexpected resume error: %v != %v", err, tt.err)
+			}
+
+			w.checkpointW = ioutil.Discard
+			w.checkpointEvery = 1
+			if _, err := w.readAndComputeSamples(nil); err != tt.err {
+				t.Fatalf("unexpected checkpoint error: %v != %v", err, tt.err)
+			}
+		})
+	}
+
+	// Methods which cannot write checkpoints reject them before computing
+	// any values
+	w, err := New(bytes.NewReader(wavFile), CheckpointEvery(1, ioutil.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := w.ComputePeaks(MinMaxF64Samples); err != errPeakFuncCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errPeakFuncCheckpoint)
+	}
+}
+
+// TestReadCheckpointNone verifies that ReadCheckpoint returns an error when no
+// complete Checkpoint is present.
+func TestReadCheckpointNone(t *testing.T) {
+	if _, err := ReadCheckpoint(strings.NewReader(`{"win`)); err != ErrNoCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, ErrNoCheckpoint)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"log"
+	"os"
+)
+
+const (
+	// cmdCompare is the name of the compare subcommand
+	cmdCompare = "compare"
+
+	// Exit codes of the compare subcommand
+	exitSame  = 0
+	exitDiff  = 1
+	exitError = 2
+)
+
+// compareUsage is the usage string of the compare subcommand
+const compareUsage = "usage: " + app + " " + cmdCompare + " [flags] a.png b.png"
+
+// imageDiff describes the differences between two images.
+type imageDiff struct {
+	// pixels is the number of pixels compared, and differ is the number of
+	// pixels whose colors differ by more than the tolerance
+	pixels int
+	differ int
+
+	// maxDelta is the largest difference between any channel of any pair of
+	// pixels, in 8-bit units
+	maxDelta int
+}
+
+// fraction returns the fraction of pixels which differ.
+func (d imageDiff) fraction() float64 {
+	if d.pixels == 0 {
+		return 0
+	}
+
+	return float64(d.differ) / float64(d.pixels)
+}
+
+// compare implements the compare subcommand, which compares two waveform
+// images pixel by pixel, so that asset pipelines can detect unintended
+// rendering changes.  It exits with exitSame if the fraction of differing
+// pixels does not exceed the threshold, exitDiff if it does, and exitError
+// if the images cannot be compared.
+func compare(args []string) int {
+	fs := flag.NewFlagSet(cmdCompare, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), compareUsage)
+		fs.PrintDefaults()
+	}
+
+	threshold := fs.Float64("threshold", 0, "fraction of pixels which may differ before the images are reported as different")
+	tolerance := fs.Int("tolerance", 0, "difference in any 8-bit color channel which is ignored")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	a, err := readImage(fs.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+	b, err := readImage(fs.Arg(1))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
+	if a.Bounds().Size() != b.Bounds().Size() {
+		fmt.Printf("image sizes differ: %v != %v\n", a.Bounds().Size(), b.Bounds().Size())
+		return exitDiff
+	}
+
+	d := diffImages(a, b, *tolerance)
+	fmt.Printf("pixels: %d/%d differ (%.4f%%), max channel difference: %d\n",
+		d.differ, d.pixels, d.fraction()*100, d.maxDelta)
+
+	if d.fraction() > *threshold {
+		return exitDiff
+	}
+
+	return exitSame
+}
+
+// readImage reads and decodes the named image file.
+func readImage(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return img, nil
+}
+
+// diffImages compares two images of the same size pixel by pixel, ignoring
+// channel differences no greater than tolerance.
+func diffImages(a image.Image, b image.Image, tolerance int) imageDiff {
+	ab, bb := a.Bounds(), b.Bounds()
+
+	var d imageDiff
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			ca := color.NRGBAModel.Convert(a.At(ab.Min.X+x, ab.Min.Y+y)).(color.NRGBA)
+			cb := color.NRGBAModel.Convert(b.At(bb.Min.X+x, bb.Min.Y+y)).(color.NRGBA)
+
+			delta := 0
+			for _, c := range [][2]uint8{{ca.R, cb.R}, {ca.G, cb.G}, {ca.B, cb.B}, {ca.A, cb.A}} {
+				if v := absInt(int(c[0]) - int(c[1])); v > delta {
+					delta = v
+				}
+			}
+
+			d.pixels++
+			if delta > tolerance {
+				d.differ++
+			}
+			if delta > d.maxDelta {
+				d.maxDelta = delta
+			}
+		}
+	}
+
+	return d
+}
+
+// absInt returns the absolute value of v.
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -1,9 +1,13 @@
 // Command waveform is a simple utility which reads an audio file from stdin,
 // processes it into a waveform image using input flags, and writes a PNG image
 // of the generated waveform to stdout.
+//
+// The compare subcommand compares two waveform images, and reports their
+// differences.
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"image/color"
@@ -25,6 +29,12 @@ const (
 	fnGradient = "gradient"
 	fnSolid    = "solid"
 	fnStripe   = "stripe"
+
+	// Names of available PNG compression levels
+	compressionDefault = "default"
+	compressionNone    = "none"
+	compressionSpeed   = "speed"
+	compressionBest    = "best"
 )
 
 var (
@@ -53,20 +63,49 @@ var (
 
 	// strFn is an identifier which selects the ColorFunc used to color the waveform image
 	strFn = flag.String("fn", fnSolid, "function used to color output waveform image "+fnOptions)
+
+	// strCompression is an identifier which selects the compression level used to
+	// encode the output PNG image
+	strCompression = flag.String("compression", compressionDefault, "compression level of output PNG image "+compressionOptions)
+
+	// palette indicates if the output PNG image should be quantized to a color palette
+	palette = flag.Bool("palette", false, "quantize output PNG image to a color palette")
+
+	// depth is the bit depth of the output PNG image
+	depth = flag.Int("depth", 8, "bit depth of output PNG image [palette: 1, 2, 4, 8; otherwise: 8, 16]")
+
+	// offset is the time in the audio at which the waveform begins
+	offset = flag.Duration("offset", 0, "time in the audio at which the waveform begins")
+
+	// duration is the length of audio drawn in the waveform, or 0 to draw
+	// until the end of the audio
+	duration = flag.Duration("duration", 0, "length of audio drawn in the waveform [0: until end of audio]")
+
+	// deterministic indicates if output should be byte-identical across runs
+	// with the same input and flags
+	deterministic = flag.Bool("deterministic", false, "produce byte-identical output across runs with the same input and flags")
 )
 
 // fnOptions is the help string which lists available options
 var fnOptions = fmt.Sprintf("[options: %s, %s, %s, %s, %s]", fnChecker, fnFuzz, fnGradient, fnSolid, fnStripe)
 
-func main() {
-	// Parse flags
-	flag.Parse()
+// compressionOptions is the help string which lists available compression levels
+var compressionOptions = fmt.Sprintf("[options: %s, %s, %s, %s]", compressionDefault, compressionNone, compressionSpeed, compressionBest)
 
+func main() {
 	// Move all logging output to stderr, as output image will occupy
 	// the stdout stream
 	log.SetOutput(os.Stderr)
 	log.SetPrefix(app + ": ")
 
+	// Run a subcommand, if requested
+	if len(os.Args) > 1 && os.Args[1] == cmdCompare {
+		os.Exit(compare(os.Args[2:]))
+	}
+
+	// Parse flags
+	flag.Parse()
+
 	// Create image background color from input hex color string, or default
 	// to black if invalid
 	colorR, colorG, colorB := hexToRGB(*strBGColor)
@@ -85,10 +124,17 @@ func main() {
 		altColor = color.RGBA{colorR, colorG, colorB, 255}
 	}
 
+	// Fuzz colors are random unless deterministic output is requested, in
+	// which case a fixed seed is used
+	fuzzFn := waveform.FuzzColor(fgColor, altColor)
+	if *deterministic {
+		fuzzFn = waveform.FuzzColorSeed(0, fgColor, altColor)
+	}
+
 	// Set of available functions
 	fnSet := map[string]waveform.ColorFunc{
 		fnChecker:  waveform.CheckerColor(fgColor, altColor, 10),
-		fnFuzz:     waveform.FuzzColor(fgColor, altColor),
+		fnFuzz:     fuzzFn,
 		fnGradient: waveform.GradientColor(fgColor, altColor),
 		fnSolid:    waveform.SolidColor(fgColor),
 		fnStripe:   waveform.StripeColor(fgColor, altColor),
@@ -100,36 +146,66 @@ func main() {
 		log.Fatalf("unknown function: %q %s", *strFn, fnOptions)
 	}
 
-	// Generate a waveform image from stdin, using values passed from
-	// flags as options
-	img, err := waveform.Generate(os.Stdin,
+	// Set of available compression levels
+	compressionSet := map[string]png.CompressionLevel{
+		compressionDefault: png.DefaultCompression,
+		compressionNone:    png.NoCompression,
+		compressionSpeed:   png.BestSpeed,
+		compressionBest:    png.BestCompression,
+	}
+
+	// Validate user-selected compression level
+	compression, ok := compressionSet[*strCompression]
+	if !ok {
+		log.Fatalf("unknown compression level: %q %s", *strCompression, compressionOptions)
+	}
+
+	options := []waveform.OptionsFunc{
 		waveform.BGColorFunction(waveform.SolidColor(bgColor)),
 		waveform.FGColorFunction(colorFn),
 		waveform.Resolution(*resolution),
 		waveform.Scale(*scaleX, *scaleY),
 		waveform.ScaleClipping(),
 		waveform.Sharpness(*sharpness),
-	)
+		waveform.Offset(*offset),
+	}
+
+	// Draw only part of the audio, if requested
+	if *duration != 0 {
+		options = append(options, waveform.Duration(*duration))
+	}
+
+	// Generate a waveform image from stdin, using values passed from
+	// flags as options
+	img, err := waveform.Generate(os.Stdin, options...)
 	if err != nil {
-		// Set of known errors
-		knownErr := map[error]struct{}{
-			waveform.ErrFormat:        struct{}{},
-			waveform.ErrInvalidData:   struct{}{},
-			waveform.ErrUnexpectedEOS: struct{}{},
+		// Set of known errors, which may be wrapped with more detail
+		knownErr := []error{
+			waveform.ErrFormat,
+			waveform.ErrInvalidData,
+			waveform.ErrUnexpectedEOS,
 		}
 
 		// On known error, fatal log
-		if _, ok := knownErr[err]; ok {
-			log.Fatal(err)
+		for _, k := range knownErr {
+			if errors.Is(err, k) {
+				log.Fatal(err)
+			}
 		}
 
 		// Unknown errors, panic
 		panic(err)
 	}
 
-	// Encode results as PNG to stdout
-	if err := png.Encode(os.Stdout, img); err != nil {
-		panic(err)
+	// Encode results as PNG to stdout.  No timestamps or other metadata
+	// which varies between runs are written, so the output depends only on
+	// the image
+	if err := waveform.EncodePNG(os.Stdout, img, &waveform.PNGOptions{
+		CompressionLevel: compression,
+		Palette:          *palette,
+		BitDepth:         *depth,
+	}); err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/mdlayher/waveform"
+)
+
+const (
+	// cmdCompare is the name of the compare subcommand
+	cmdCompare = "compare"
+
+	// Exit codes of the compare subcommand
+	exitSame  = 0
+	exitDiff  = 1
+	exitError = 2
+)
+
+// compareUsage is the usage string of the compare subcommand
+const compareUsage = "usage: " + app + " " + cmdCompare + " [flags] a.dat b.dat"
+
+// compare implements the compare subcommand, which compares two peaks files
+// value by value, so that asset pipelines can detect unintended changes.  It
+// exits with exitSame if the fraction of differing values does not exceed the
+// threshold, exitDiff if it does, and exitError if the files cannot be
+// compared.
+func compare(args []string) int {
+	fs := flag.NewFlagSet(cmdCompare, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), compareUsage)
+		fs.PrintDefaults()
+	}
+
+	threshold := fs.Float64("threshold", 0, "fraction of values which may differ before the files are reported as different")
+	tolerance := fs.Int("tolerance", 0, "difference in any value which is ignored")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	a, err := readPeaksFile(fs.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+	b, err := readPeaksFile(fs.Arg(1))
+	if err != nil {
+		log.Print(err)
+		return exitError
+	}
+
+	// Files with different headers cannot be compared value by value
+	ha := [...]int{len(a.Min), a.SampleRate, a.SamplesPerPixel, int(a.Bits), a.Len()}
+	hb := [...]int{len(b.Min), b.SampleRate, b.SamplesPerPixel, int(b.Bits), b.Len()}
+	if ha != hb {
+		fmt.Printf("headers differ: channels, sample rate, samples per pixel, bits, length: %v != %v\n", ha, hb)
+		return exitDiff
+	}
+
+	// Values are compared as the integers stored in the files, so that the
+	// tolerance is independent of the number of bits
+	scale := float64(int(1)<<(a.Bits-1) - 1)
+
+	var total, differ, maxDelta int
+	for c := range a.Min {
+		for _, pair := range [][2][]float64{{a.Min[c], b.Min[c]}, {a.Max[c], b.Max[c]}} {
+			for i := range pair[0] {
+				delta := int(math.Round(math.Abs(pair[0][i]-pair[1][i]) * scale))
+
+				total++
+				if delta > *tolerance {
+					differ++
+				}
+				if delta > maxDelta {
+					maxDelta = delta
+				}
+			}
+		}
+	}
+
+	var fraction float64
+	if total > 0 {
+		fraction = float64(differ) / float64(total)
+	}
+
+	fmt.Printf("values: %d/%d differ (%.4f%%), max difference: %d\n",
+		differ, total, fraction*100, maxDelta)
+
+	if fraction > *threshold {
+		return exitDiff
+	}
+
+	return exitSame
+}
+
+// readPeaksFile reads the named peaks file, in either the .dat or JSON format.
+func readPeaksFile(name string) (*waveform.Peaks, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := waveform.ReadPeaks(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return p, nil
+}
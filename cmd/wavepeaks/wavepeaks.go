@@ -0,0 +1,335 @@
+// Command wavepeaks is a utility which reads an audio file, computes its
+// peaks: the minimum and maximum sample in each group of samples, and writes
+// them in the binary .dat or JSON formats used by BBC audiowaveform and
+// compatible waveform viewers, such as peaks.js.
+//
+// Its flags mirror those of audiowaveform, so that it may be used as a
+// drop-in replacement for peaks extraction in existing deployments.  The
+// compare subcommand compares two peaks files, and reports their differences.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mdlayher/waveform"
+)
+
+const (
+	// app is the name of this application
+	app = "wavepeaks"
+
+	// Names of available output formats
+	formatDat  = "dat"
+	formatJSON = "json"
+)
+
+var (
+	// input is the name of the input audio file, or "-" for stdin
+	input string
+
+	// output is the name of the output peaks file, or "-" for stdout
+	output string
+
+	// inputFormat is accepted for compatibility with audiowaveform, but the
+	// format of the input is always detected automatically
+	inputFormat string
+
+	// outputFormat selects the format of the output peaks file
+	outputFormat string
+
+	// zoom is the number of sample frames represented by each peak
+	zoom uint
+
+	// pixelsPerSecond, if set, is the number of peaks per second of audio,
+	// in place of zoom
+	pixelsPerSecond uint
+
+	// bits is the number of bits used to encode each peak
+	bits uint
+
+	// start and end select the range of time from which peaks are computed,
+	// in seconds; an end of 0 computes peaks until the end of the audio
+	start float64
+	end   float64
+
+	// levels is the number of zoom levels written, each of which halves the
+	// number of peaks of the level before it
+	levels uint
+)
+
+func init() {
+	// Register both the short and long names of flags used by audiowaveform
+	for _, name := range []string{"i", "input-filename"} {
+		flag.StringVar(&input, name, "-", "input audio file [-: stdin]")
+	}
+	for _, name := range []string{"o", "output-filename"} {
+		flag.StringVar(&output, name, "-", "output peaks file [-: stdout]")
+	}
+	for _, name := range []string{"z", "zoom"} {
+		flag.UintVar(&zoom, name, 256, "number of audio sample frames per peak")
+	}
+	for _, name := range []string{"b", "bits"} {
+		flag.UintVar(&bits, name, 16, "bits per peak value [options: 8, 16]")
+	}
+	for _, name := range []string{"s", "start"} {
+		flag.Float64Var(&start, name, 0, "time in seconds at which peaks begin")
+	}
+	for _, name := range []string{"e", "end"} {
+		flag.Float64Var(&end, name, 0, "time in seconds at which peaks end [0: end of audio]")
+	}
+
+	flag.StringVar(&inputFormat, "input-format", "", "ignored: the input format is detected automatically")
+	flag.StringVar(&outputFormat, "output-format", "", "format of output peaks file [options: dat, json; default: from output file extension, or dat]")
+	flag.UintVar(&pixelsPerSecond, "pixels-per-second", 0, "number of peaks per second of audio, in place of zoom")
+	flag.UintVar(&levels, "levels", 1, "number of zoom levels to write, each with twice the zoom of the last")
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	log.SetPrefix(app + ": ")
+
+	// Run a subcommand, if requested
+	if len(os.Args) > 1 && os.Args[1] == cmdCompare {
+		os.Exit(compare(os.Args[2:]))
+	}
+
+	// Parse flags
+	flag.Parse()
+
+	format, err := selectFormat(outputFormat, output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case bits != 8 && bits != 16:
+		log.Fatalf("unsupported bits: %d [options: 8, 16]", bits)
+	case zoom == 0:
+		log.Fatal("zoom must be at least 1")
+	case levels == 0:
+		log.Fatal("levels must be at least 1")
+	case levels > 1 && output == "-":
+		log.Fatal("an output file is required to write multiple levels")
+	case start < 0 || (end != 0 && end <= start):
+		log.Fatalf("invalid time range: %v-%v", start, end)
+	}
+
+	r := io.Reader(os.Stdin)
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	p, err := computePeaks(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for l := uint(0); l < levels; l++ {
+		name := levelName(output, p.samplesPerPixel, levels)
+		if err := writePeaks(name, format, p); err != nil {
+			log.Fatal(err)
+		}
+
+		p = p.halve()
+	}
+}
+
+// peaks are the minimum and maximum sample in each group of samplesPerPixel
+// sample frames of an audio stream.
+type peaks struct {
+	sampleRate      int
+	samplesPerPixel int
+
+	// min and max are the peaks of each group, and frames is the number of
+	// frames read for the current group
+	min    []float64
+	max    []float64
+	frames int
+}
+
+// computePeaks computes the peaks of the audio stream read from r, using the
+// values of flags.  Channels are averaged before peaks are computed, as with
+// audiowaveform.
+func computePeaks(r io.Reader) (*peaks, error) {
+	p := new(peaks)
+
+	options := []waveform.OptionsFunc{
+		waveform.Downmix(waveform.DownmixAverage),
+		waveform.Analyzers(waveform.AnalyzerFunc(p.analyze)),
+		waveform.Offset(time.Duration(start * float64(time.Second))),
+	}
+	if end != 0 {
+		options = append(options, waveform.Duration(time.Duration((end-start)*float64(time.Second))))
+	}
+
+	w, err := waveform.New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Peaks are collected by the Analyzer, so computed values are discarded
+	err = w.ComputeFunc(func(_ int, _ float64, _ time.Duration) error {
+		return nil
+	})
+	if err != nil && !errors.Is(err, waveform.ErrTooShort) {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// analyze is a waveform.AnalyzerFunc which collects the peaks of each window
+// of mono audio.
+func (p *peaks) analyze(c *waveform.Chunk) error {
+	if p.sampleRate == 0 {
+		p.sampleRate = c.Config.SampleRate
+
+		p.samplesPerPixel = int(zoom)
+		if pixelsPerSecond > 0 {
+			p.samplesPerPixel = p.sampleRate / int(pixelsPerSecond)
+			if p.samplesPerPixel == 0 {
+				return fmt.Errorf("pixels per second exceeds sample rate: %d", p.sampleRate)
+			}
+		}
+	}
+
+	for _, s := range c.Samples {
+		if p.frames == 0 {
+			p.min = append(p.min, s)
+			p.max = append(p.max, s)
+		}
+
+		i := len(p.min) - 1
+		p.min[i] = math.Min(p.min[i], s)
+		p.max[i] = math.Max(p.max[i], s)
+
+		if p.frames++; p.frames == p.samplesPerPixel {
+			p.frames = 0
+		}
+	}
+
+	return nil
+}
+
+// halve returns the peaks of the next zoom level, in which each peak covers
+// twice as many sample frames, while preserving the minimum and maximum of
+// each pair of peaks.
+func (p *peaks) halve() *peaks {
+	next := &peaks{
+		sampleRate:      p.sampleRate,
+		samplesPerPixel: p.samplesPerPixel * 2,
+		min:             make([]float64, (len(p.min)+1)/2),
+		max:             make([]float64, (len(p.max)+1)/2),
+	}
+
+	for i := range next.min {
+		next.min[i], next.max[i] = p.min[2*i], p.max[2*i]
+		if j := 2*i + 1; j < len(p.min) {
+			next.min[i] = math.Min(next.min[i], p.min[j])
+			next.max[i] = math.Max(next.max[i], p.max[j])
+		}
+	}
+
+	return next
+}
+
+// writePeaks writes peaks in the input format to the named file, or to
+// stdout if the name is "-".
+func writePeaks(name string, format string, p *peaks) error {
+	var w io.Writer = os.Stdout
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case formatJSON:
+		err = writeJSON(bw, p)
+	default:
+		err = writeDat(bw, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// writeDat writes peaks in the binary .dat format.
+func writeDat(w io.Writer, p *peaks) error {
+	b, err := p.file().MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// writeJSON writes peaks in the JSON format.
+func writeJSON(w io.Writer, p *peaks) error {
+	return json.NewEncoder(w).Encode(p.file())
+}
+
+// file returns the peaks as a waveform.Peaks, using the number of bits set
+// by flags.
+func (p *peaks) file() *waveform.Peaks {
+	return &waveform.Peaks{
+		SampleRate:      p.sampleRate,
+		SamplesPerPixel: p.samplesPerPixel,
+		Bits:            bits,
+		Min:             [][]float64{p.min},
+		Max:             [][]float64{p.max},
+	}
+}
+
+// selectFormat selects the output format using the format flag, or the
+// extension of the output file if the flag is empty.
+func selectFormat(format string, output string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(output), ".")
+		if format != formatJSON {
+			format = formatDat
+		}
+	}
+
+	if format != formatDat && format != formatJSON {
+		return "", fmt.Errorf("unknown output format: %q [options: %s, %s]", format, formatDat, formatJSON)
+	}
+
+	return format, nil
+}
+
+// levelName returns the name of the output file for a zoom level.  When
+// multiple levels are written, the number of sample frames per peak is
+// inserted before the extension of each file name.
+func levelName(output string, samplesPerPixel int, levels uint) string {
+	if levels == 1 {
+		return output
+	}
+
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(output, ext), samplesPerPixel, ext)
+}
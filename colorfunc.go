@@ -2,6 +2,7 @@ package waveform
 
 import (
 	"image/color"
+	"math"
 	"math/rand"
 	"time"
 )
@@ -45,6 +46,32 @@ func FuzzColor(colors ...color.Color) ColorFunc {
 	}
 }
 
+// FuzzColorSeed generates a ColorFunc which applies a pseudo-random color at
+// each coordinate, selected from an input, variadic slice of colors, in the
+// same way as FuzzColor.
+//
+// Unlike FuzzColor, the color at each coordinate depends only on the input
+// seed and the coordinate itself, so images are reproducible: drawing the same
+// waveform with the same seed always produces identical output, regardless of
+// the order in which coordinates are drawn.
+func FuzzColorSeed(seed int64, colors ...color.Color) ColorFunc {
+	// Filter any nil values
+	colors = filterNilColors(colors)
+
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		h := mix64(uint64(seed) ^ mix64(uint64(x)<<32|uint64(uint32(y))))
+		return colors[h%uint64(len(colors))]
+	}
+}
+
+// mix64 is the finalizer of the SplitMix64 pseudo-random number generator,
+// which scrambles the bits of an input value.
+func mix64(v uint64) uint64 {
+	v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+	v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+	return v ^ (v >> 31)
+}
+
 // GradientColor generates a ColorFunc which produces a color gradient between two
 // RGBA input colors.  The gradient attempts to gradually reduce the distance between
 // two colors, creating a sweeping color change effect in the resulting waveform
@@ -55,17 +82,16 @@ func GradientColor(start color.RGBA, end color.RGBA) ColorFunc {
 	startFG, endFG := float64(start.G), float64(end.G)
 	startFB, endFB := float64(start.B), float64(end.B)
 
-	// Values used for RGBA and percentage
-	var r, g, b, p float64
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
 		// Calculate percentage across waveform image
-		p = float64((float64(n) / float64(maxN)) * 100)
+		p := float64((float64(n) / float64(maxN)) * 100)
 
-		// Calculate new values for RGB using gradient algorithm
+		// Calculate new values for RGB using gradient algorithm.  Values are
+		// local to each call, so the ColorFunc is safe for concurrent use.
 		// Thanks: http://stackoverflow.com/questions/27532/generating-gradients-programmatically
-		r = (endFR * p) + (startFR * (1 - p))
-		g = (endFG * p) + (startFG * (1 - p))
-		b = (endFB * p) + (startFB * (1 - p))
+		r := (endFR * p) + (startFR * (1 - p))
+		g := (endFG * p) + (startFG * (1 - p))
+		b := (endFB * p) + (startFB * (1 - p))
 
 		// Correct overflow when moving from lighter to darker gradients
 		if start.R > end.R && r > -255.00 {
@@ -88,6 +114,81 @@ func GradientColor(start color.RGBA, end color.RGBA) ColorFunc {
 	}
 }
 
+// GradientDirection is the direction in which a gradient runs across a
+// waveform image, used by GradientColorDirection.
+type GradientDirection int
+
+const (
+	// GradientHorizontal runs a gradient along time, from the first computed
+	// value to the last.  This is the direction used by LinearGradientColor.
+	GradientHorizontal GradientDirection = iota
+
+	// GradientVertical runs a gradient from the top of the image to the
+	// bottom.
+	GradientVertical
+
+	// GradientDiagonal runs a gradient from the top left corner of the image
+	// to the bottom right corner.
+	GradientDiagonal
+
+	// GradientRadial runs a gradient outward from the center of the image to
+	// its corners.
+	GradientRadial
+)
+
+// LinearGradientColor generates a ColorFunc which produces a color gradient
+// between two input colors, interpolated in linear light.
+//
+// Unlike GradientColor, which interpolates sRGB encoded values directly, this
+// avoids the dark, muddy band which appears in the middle of gradients between
+// saturated colors.  The first computed value is drawn with the start color,
+// and the last with the end color.
+func LinearGradientColor(start color.Color, end color.Color) ColorFunc {
+	return GradientColorDirection(start, end, GradientHorizontal)
+}
+
+// GradientColorDirection generates a ColorFunc which produces a color gradient
+// between two input colors, interpolated in linear light as with
+// LinearGradientColor, which runs in the input GradientDirection.
+//
+// The start color is drawn at the beginning of the gradient: the first
+// computed value, the top or top left of the image, or its center, and the
+// end color is drawn at the end of the gradient.  Unknown directions are
+// treated as GradientHorizontal.
+func GradientColorDirection(start color.Color, end color.Color, direction GradientDirection) ColorFunc {
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		var t float64
+		switch direction {
+		case GradientVertical:
+			t = gradientFraction(y, maxY)
+		case GradientDiagonal:
+			t = (gradientFraction(x, maxX) + gradientFraction(y, maxY)) / 2
+		case GradientRadial:
+			// Distance from the center, relative to the distance from the
+			// center to each corner
+			cx, cy := float64(maxX-1)/2, float64(maxY-1)/2
+			if r := math.Hypot(cx, cy); r > 0 {
+				t = math.Min(math.Hypot(float64(x)-cx, float64(y)-cy)/r, 1)
+			}
+		default:
+			// Calculate fraction across waveform image
+			t = gradientFraction(n, maxN)
+		}
+
+		return lerpLinear(start, end, t)
+	}
+}
+
+// gradientFraction returns the fraction of the distance from 0 to max-1 at
+// which v lies.
+func gradientFraction(v int, max int) float64 {
+	if max <= 1 {
+		return 0
+	}
+
+	return float64(v) / float64(max-1)
+}
+
 // SolidColor generates a ColorFunc which simply returns the input color
 // as the color which should be drawn at all coordinates.
 //
@@ -106,14 +207,31 @@ func StripeColor(colors ...color.Color) ColorFunc {
 	// Filter any nil values
 	colors = filterNilColors(colors)
 
-	var lastN int
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
-		// For each new n value, use the next color in the slice
-		if n > lastN {
-			lastN = n
-		}
+		// For each n value, use the next color in the slice.  The color depends
+		// only on n, so coordinates may be drawn in any order.
+		return colors[n%len(colors)]
+	}
+}
+
+// StripeColorWidth generates a ColorFunc which applies one color from the
+// input, variadic slice to each stripe of width pixel columns.  Each color is
+// used in order, and the rotation will repeat until the image is complete.
+//
+// Unlike StripeColor, the width of each stripe depends only on the X
+// coordinate, so stripe density may be controlled independently of the
+// resolution and scale of the waveform image.  A width of 0 is treated as 1.
+func StripeColorWidth(width uint, colors ...color.Color) ColorFunc {
+	// Filter any nil values
+	colors = filterNilColors(colors)
 
-		return colors[lastN%len(colors)]
+	if width == 0 {
+		width = 1
+	}
+
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		// The color depends only on x, so coordinates may be drawn in any order
+		return colors[(uint(x)/width)%uint(len(colors))]
 	}
 }
 
@@ -85,6 +85,71 @@ func TestStripeColorMultipleColors(t *testing.T) {
 	})
 }
 
+// TestStripeColorWidth verifies that StripeColorWidth produces stripes of the
+// requested width, independent of the computed value at each column.
+func TestStripeColorWidth(t *testing.T) {
+	var tests = []struct {
+		width uint
+		out   []color.Color
+	}{
+		{
+			width: 0,
+			out:   []color.Color{black, white, red, black, white, red},
+		},
+		{
+			width: 1,
+			out:   []color.Color{black, white, red, black, white, red},
+		},
+		{
+			width: 3,
+			out: []color.Color{
+				black, black, black, white, white, white,
+				red, red, red, black, black, black,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		fn := StripeColorWidth(tt.width, black, nil, white, red)
+		for x := range tt.out {
+			// n is always 0, and must not affect the stripe
+			if c := fn(0, x, 0, 1, len(tt.out), 1); c != tt.out[x] {
+				t.Fatalf("[%d/%02d] unexpected output color: %v != %v", tt.width, x, c, tt.out[x])
+			}
+		}
+	}
+}
+
+// TestFuzzColorSeed verifies that FuzzColorSeed produces only colors from its
+// input, and produces the same colors for the same seed.
+func TestFuzzColorSeed(t *testing.T) {
+	in := []color.Color{black, white, red, green, blue}
+	a, b, c := FuzzColorSeed(1, in...), FuzzColorSeed(1, in...), FuzzColorSeed(2, in...)
+
+	seen := make(map[color.Color]bool)
+	var differ bool
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 100; y++ {
+			ca := a(0, x, y, 1, 100, 100)
+			if cb := b(0, x, y, 1, 100, 100); ca != cb {
+				t.Fatalf("(%d,%d): colors differ for the same seed: %v != %v", x, y, ca, cb)
+			}
+			if ca != c(0, x, y, 1, 100, 100) {
+				differ = true
+			}
+
+			seen[ca] = true
+		}
+	}
+
+	if !differ {
+		t.Fatal("colors are identical for different seeds")
+	}
+	if len(seen) != len(in) {
+		t.Fatalf("unexpected number of colors: %d != %d", len(seen), len(in))
+	}
+}
+
 // testCheckerColor is a test helper which aids in testing the CheckerColor function.
 func testCheckerColor(t *testing.T, colorA color.Color, colorB color.Color) {
 	// Predefined values for test
@@ -0,0 +1,334 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Column is a single column of a waveform image, which represents one computed
+// value.  Columns are produced by a ColumnIterator, and drawn using
+// RenderStyle.DrawColumn.
+type Column struct {
+	// N is the index of the computed value, and Value is the computed value
+	// after any sanitization and smoothing was applied.
+	N     int
+	Value float64
+
+	// X is the leftmost X coordinate of the column, and Width is the number
+	// of pixels it spans, as determined by the X-axis scaling factor.
+	X     int
+	Width int
+
+	// Height is the height of the waveform in the column, in pixels.  The
+	// waveform is centered vertically in the image.
+	Height int
+
+	// MaxN, MaxX, and MaxY are the number of computed values, and the width
+	// and height of the complete image, which are passed to each ColorFunc.
+	MaxN int
+	MaxX int
+	MaxY int
+}
+
+// ColumnFunc is a function which is called as each column of a waveform image
+// is drawn, with the index of its computed value, the leftmost X coordinate of
+// the column, and the value after any sanitization and smoothing was applied.
+// ColumnFuncs are applied using the OnColumn option.
+type ColumnFunc func(n int, x int, value float64)
+
+// ColumnIterator iterates over the columns of a waveform image, in order
+// from left to right.  A ColumnIterator allows custom drawing, such as
+// annotations or custom shapes, to be interleaved with the drawing of each
+// column, rather than post-processing a complete image.
+//
+// The columns produced by a ColumnIterator are always those of the raster
+// renderer, even if the style uses the vector renderer.
+type ColumnIterator struct {
+	values []float64
+	scale  float64
+	scaleX int
+	maxY   int
+	minY   int
+	limit  int
+	levels int
+
+	// edges are the X coordinates of the left edge of each column, followed
+	// by the right edge of the image, when columns vary in width
+	edges []int
+
+	n   int
+	col Column
+}
+
+// Columns creates a ColumnIterator over a slice of computed values, using the
+// receiving RenderStyle.  Values are sanitized and smoothed in the same way as
+// they would be by Draw.
+//
+// If the InvalidValueError policy is in use and an invalid value is encountered,
+// Columns returns a *ValueError which describes it.
+func (s RenderStyle) Columns(values []float64) (*ColumnIterator, error) {
+	values, err := s.sanitizeValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.columns(s.smoothValues(values), nil), nil
+}
+
+// columns creates a ColumnIterator over a slice of values which have already
+// been sanitized and smoothed.  If edges is not nil, it specifies the X
+// coordinates of the left edge of each column, followed by the right edge of
+// the image, as computed by timeEdges.  Otherwise, each column has the width
+// of the X-axis scaling factor.
+func (s *RenderStyle) columns(values []float64, edges []int) *ColumnIterator {
+	it := s.columnIterator(values, edges)
+	return &it
+}
+
+// columnIterator creates a ColumnIterator in the same way as columns, but
+// returns it by value, so that it need not be allocated on the heap.
+func (s *RenderStyle) columnIterator(values []float64, edges []int) ColumnIterator {
+	return ColumnIterator{
+		edges:  edges,
+		values: values,
+		scale:  s.scaleFactor(values) * (1 - s.headroom),
+		scaleX: int(s.scaleX),
+		maxY:   s.imageHeight(),
+		minY:   int(s.minHeight),
+		limit:  s.usableHeight(s.imageHeight()),
+		levels: int(s.levels),
+		n:      -1,
+	}
+}
+
+// quantizeHeight reduces a height to the highest of levels evenly spaced
+// heights, up to full, which it reaches.
+func quantizeHeight(height float64, full float64, levels int) float64 {
+	if full <= 0 {
+		return 0
+	}
+
+	step := full / float64(levels)
+	level := math.Min(math.Floor(height/step), float64(levels))
+
+	return level * step
+}
+
+// Bounds returns the bounds of the image which contains all columns.
+func (it *ColumnIterator) Bounds() image.Rectangle {
+	return image.Rect(0, 0, it.maxX(), it.maxY)
+}
+
+// maxX returns the width of the image which contains all columns.
+func (it *ColumnIterator) maxX() int {
+	if it.edges != nil {
+		return it.edges[len(it.edges)-1]
+	}
+
+	return len(it.values) * it.scaleX
+}
+
+// Next advances the ColumnIterator to the next column, returning false when no
+// columns remain.
+func (it *ColumnIterator) Next() bool {
+	it.n++
+	if it.n >= len(it.values) {
+		return false
+	}
+
+	// Scale computed value to an integer, using the height of the image
+	// and a constant scaling factor, but never draw below the minimum height
+	v := it.values[it.n]
+	height := int(math.Floor(v * float64(it.maxY) * it.scale))
+	if it.levels > 0 {
+		height = int(math.Round(quantizeHeight(float64(height), float64(it.limit), it.levels)))
+	}
+	if height < it.minY {
+		height = it.minY
+		if height > it.limit {
+			height = it.limit
+		}
+	}
+
+	// When headroom is reserved, never draw within the margins of the image
+	if it.limit < it.maxY && height > it.limit {
+		height = it.limit
+	}
+
+	// Columns vary in width when they are positioned by time
+	x, width := it.n*it.scaleX, it.scaleX
+	if it.edges != nil {
+		x, width = it.edges[it.n], it.edges[it.n+1]-it.edges[it.n]
+	}
+
+	it.col = Column{
+		N:      it.n,
+		Value:  v,
+		X:      x,
+		Width:  width,
+		Height: height,
+		MaxN:   len(it.values),
+		MaxX:   it.maxX(),
+		MaxY:   it.maxY,
+	}
+
+	return true
+}
+
+// Column returns the current column of the ColumnIterator.
+func (it *ColumnIterator) Column() Column {
+	return it.col
+}
+
+// DrawColumn draws a single column of a waveform image, using the background
+// and foreground color functions and sharpness of the receiving RenderStyle.
+// Pixels which fall outside of the bounds of img are not drawn.
+func (s RenderStyle) DrawColumn(img draw.Image, c Column) {
+	// Calculate halfway point of Y-axis for image
+	imgHalfY := c.MaxY / 2
+
+	// Calculate a peak value used for smoothing scaled X-axis images
+	peak := int(math.Ceil(float64(c.Width)) / 2)
+	intSharpness := int(s.sharpness)
+
+	// Calculate the rows spanned by the scaled computed value
+	top, bottom := s.columnSpan(c)
+
+	// Calculate the offsets which move the top and bottom halves of the
+	// waveform apart, and the margins reserved by headroom
+	up, down := s.mirrorOffsets(c.MaxY)
+	margin := (c.MaxY - s.usableHeight(c.MaxY)) / 2
+	centerRow := c.MaxY%2 == 1 && !s.legacyCenter
+
+	// Draw background color down the entire Y-axis
+	for y := 0; y < c.MaxY; y++ {
+		// If X-axis is being scaled, draw background over several X coordinates
+		for i := 0; i < c.Width; i++ {
+			setPixel(img, c.X+i, y, s.bgColorFn(c.N, c.X+i, y, c.MaxN, c.MaxX, c.MaxY))
+		}
+	}
+
+	// Iterate image coordinates on the Y-axis, generating a symmetrical waveform
+	// image above and below the center of the image
+	var adjust int
+	for y := top; y < bottom; y++ {
+		// If X-axis is being scaled, draw computed value over several X coordinates
+		for i := 0; i < c.Width; i++ {
+			// When scaled, adjust computed value to be lower on either side of the peak,
+			// so that the image appears more smooth and less "blocky"
+			if i < peak {
+				// Adjust downward
+				adjust = (i - peak) * intSharpness
+			} else if i == peak {
+				// No adjustment at peak
+				adjust = 0
+			} else {
+				// Adjust downward
+				adjust = (peak - i) * intSharpness
+			}
+
+			// On top half of the image, invert adjustment to create symmetry between
+			// top and bottom halves.  The center row of an odd height image
+			// belongs to neither half, and is never adjusted.
+			if y < imgHalfY {
+				adjust = -1*adjust - up
+			} else if y == imgHalfY && centerRow {
+				// The center row is omitted when a mirror gap is in use
+				if s.mirrorGap > 0 {
+					continue
+				}
+
+				adjust = 0
+			} else {
+				adjust += down
+			}
+
+			// Never draw within the headroom reserved at the edges of the image
+			ty := y + adjust
+			if ty < margin || ty >= c.MaxY-margin {
+				continue
+			}
+
+			// Retrieve and apply color function at specified computed value
+			// count, and X and Y coordinates.
+			// The output color is selected using the function, and is applied to
+			// the resulting image.
+			setPixel(img, c.X+i, ty, s.fgColorFn(c.N, c.X+i, ty, c.MaxN, c.MaxX, c.MaxY))
+		}
+	}
+}
+
+// drawColumn draws a single column of a waveform image, in the same way as
+// DrawColumn, and then calls the ColumnFunc of the receiving RenderStyle, if
+// one is set.
+func (s *RenderStyle) drawColumn(img draw.Image, c Column) {
+	s.DrawColumn(img, c)
+
+	if s.columnFn != nil {
+		s.columnFn(c.N, c.X, c.Value)
+	}
+}
+
+// setPixel sets the color of a single pixel of img.  *image.RGBA images are
+// set directly, because their Set method allocates to convert each color.
+func setPixel(img draw.Image, x int, y int, c color.Color) {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		img.Set(x, y, c)
+		return
+	}
+
+	r, g, b, a := c.RGBA()
+	rgba.SetRGBA(x, y, color.RGBA{
+		R: uint8(r >> 8),
+		G: uint8(g >> 8),
+		B: uint8(b >> 8),
+		A: uint8(a >> 8),
+	})
+}
+
+// columnSpan returns the first and last (exclusive) rows of the image which are
+// covered by the waveform in a column.
+//
+// The waveform is exactly symmetric about the center of the image.  In an even
+// height image, the center lies between two rows, and an even number of rows
+// are covered.  In an odd height image, the center row is covered by any
+// nonzero value, producing a 1 pixel center line, and an odd number of rows
+// are covered.  If legacy centering is enabled, the waveform is instead biased
+// downward by a pixel when its height is odd, as in earlier versions of this
+// package.
+func (s *RenderStyle) columnSpan(c Column) (int, int) {
+	half := c.MaxY / 2
+
+	switch {
+	case s.legacyCenter:
+		top := half - c.Height/2
+		return top, top + c.Height
+	case c.Height == 0:
+		return half, half
+	case c.MaxY%2 == 1:
+		n := c.Height / 2
+		return half - n, half + n + 1
+	default:
+		n := (c.Height + 1) / 2
+		return half - n, half + n
+	}
+}
+
+// mirrorOffsets returns the number of pixels by which the top half of a
+// waveform is moved up, and the bottom half is moved down, to leave a gap of
+// the configured size between them in an image of the input height.
+//
+// The center row of an odd height image forms part of the gap.  If the
+// remainder of the gap cannot be split evenly, the bottom half is moved by an
+// additional pixel.
+func (s *RenderStyle) mirrorOffsets(maxY int) (int, int) {
+	gap := int(s.mirrorGap)
+	if gap > 0 && maxY%2 == 1 && !s.legacyCenter {
+		gap--
+	}
+
+	return gap / 2, gap - gap/2
+}
@@ -0,0 +1,346 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestRenderStyleColumns verifies that drawing each column produced by a
+// ColumnIterator produces the same image as RenderStyle.Draw.
+func TestRenderStyleColumns(t *testing.T) {
+	s, err := NewStyle(Scale(3, 2), Sharpness(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.50, 0.30, 0.00}
+	it, err := s.Columns(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewRGBA(it.Bounds())
+	var n int
+	for it.Next() {
+		c := it.Column()
+		if c.N != n || c.X != n*3 || c.Width != 3 || c.Value != values[n] {
+			t.Fatalf("unexpected column: %+v", c)
+		}
+		if c.MaxN != 4 || c.MaxX != 12 || c.MaxY != 2*imgYDefault {
+			t.Fatalf("unexpected column maximums: %+v", c)
+		}
+
+		s.DrawColumn(img, c)
+		n++
+	}
+
+	if n != len(values) {
+		t.Fatalf("unexpected number of columns: %v != %v", n, len(values))
+	}
+	if !bytes.Equal(img.Pix, s.Draw(values).(*image.RGBA).Pix) {
+		t.Fatal("column image differs from Draw")
+	}
+}
+
+// TestRenderStyleColumnsInterleaved verifies that custom drawing may be
+// interleaved with drawing each column.
+func TestRenderStyleColumnsInterleaved(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := s.Columns([]float64{0.10, 0.10, 0.10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mark the top of each column after it is drawn
+	img := image.NewRGBA(it.Bounds())
+	for it.Next() {
+		c := it.Column()
+		s.DrawColumn(img, c)
+		img.Set(c.X, 0, red)
+	}
+
+	for x := 0; x < 3; x++ {
+		if !colorsEqual(img.At(x, 0), red) {
+			t.Fatalf("expected annotation at x=%d: %v", x, img.At(x, 0))
+		}
+	}
+}
+
+// TestRenderStyleColumnsInvalid verifies that RenderStyle.Columns applies
+// the InvalidValuePolicy of the style.
+func TestRenderStyleColumnsInvalid(t *testing.T) {
+	s, err := NewStyle(InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Columns([]float64{math.NaN()}); err == nil {
+		t.Fatal("expected an error for invalid value")
+	}
+}
+
+// TestRenderStyleDrawColumnSymmetric verifies that DrawColumn draws waveforms
+// which are exactly symmetric about the center of the image, unless legacy
+// centering is enabled.
+func TestRenderStyleDrawColumnSymmetric(t *testing.T) {
+	var tests = []struct {
+		name   string
+		legacy bool
+		height int
+		maxY   int
+		top    int
+		bottom int
+	}{
+		{name: "even image, even height", height: 4, maxY: 10, top: 3, bottom: 7},
+		{name: "even image, odd height", height: 5, maxY: 10, top: 2, bottom: 8},
+		{name: "odd image, zero height", height: 0, maxY: 11, top: 5, bottom: 5},
+		{name: "odd image, center line", height: 1, maxY: 11, top: 5, bottom: 6},
+		{name: "odd image, odd height", height: 5, maxY: 11, top: 3, bottom: 8},
+		{name: "legacy, odd height", legacy: true, height: 5, maxY: 10, top: 3, bottom: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []OptionsFunc{Height(uint(tt.maxY))}
+			if tt.legacy {
+				options = append(options, LegacyCentering())
+			}
+
+			s, err := NewStyle(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img := image.NewRGBA(image.Rect(0, 0, 1, tt.maxY))
+			s.DrawColumn(img, Column{
+				Width:  1,
+				Height: tt.height,
+				MaxN:   1,
+				MaxX:   1,
+				MaxY:   tt.maxY,
+			})
+
+			for y := 0; y < tt.maxY; y++ {
+				want := color.Color(color.White)
+				if y >= tt.top && y < tt.bottom {
+					want = color.Black
+				}
+
+				if got := img.At(0, y); !colorsEqual(got, want) {
+					t.Fatalf("unexpected color at y=%d: %v != %v", y, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRenderStyleHeight verifies that Height determines the height of images
+// drawn by both the raster and vector renderers.
+func TestRenderStyleHeight(t *testing.T) {
+	for _, vector := range []bool{false, true} {
+		options := []OptionsFunc{Height(101), Scale(2, 1)}
+		if vector {
+			options = append(options, VectorRenderer())
+		}
+
+		s, err := NewStyle(options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := image.Rect(0, 0, 6, 101)
+		if got := s.Draw([]float64{0.10, 0.20, 0.30}).Bounds(); got != want {
+			t.Fatalf("unexpected bounds, vector %v: %v != %v", vector, got, want)
+		}
+	}
+}
+
+// TestRenderStyleMinHeight verifies that MinHeight draws a baseline for silent
+// values, without affecting values which are already taller.
+func TestRenderStyleMinHeight(t *testing.T) {
+	s, err := NewStyle(MinHeight(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := s.Columns([]float64{0.00, 0.50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var heights []int
+	for it.Next() {
+		heights = append(heights, it.Column().Height)
+	}
+
+	if heights[0] != 2 {
+		t.Fatalf("unexpected silent column height: %v != %v", heights[0], 2)
+	}
+	if heights[1] <= 2 {
+		t.Fatalf("expected loud column to be taller than minimum: %v", heights[1])
+	}
+
+	img := s.Draw([]float64{0.00})
+	for _, y := range []int{imgYDefault/2 - 1, imgYDefault / 2} {
+		if c := img.At(0, y); !colorsEqual(c, color.Black) {
+			t.Fatalf("expected baseline at y=%d: %v", y, c)
+		}
+	}
+}
+
+// TestRenderStyleQuantize verifies that Quantize draws each column at the
+// highest level its value reaches, using both the raster and vector renderers.
+func TestRenderStyleQuantize(t *testing.T) {
+	s, err := NewStyle(Height(16), Quantize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := s.Columns([]float64{0.05, 0.10, 0.15, 0.20, 0.50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var heights []int
+	for it.Next() {
+		heights = append(heights, it.Column().Height)
+	}
+
+	if got, want := fmt.Sprint(heights), "[0 4 4 8 16]"; got != want {
+		t.Fatalf("unexpected column heights: %v != %v", got, want)
+	}
+
+	// Values within the same level draw identical vector images
+	s, err = NewStyle(Height(16), Quantize(4), VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := s.Draw([]float64{0.10, 0.10}), s.Draw([]float64{0.15, 0.15})
+	for y := 0; y < 16; y++ {
+		if !colorsEqual(a.At(1, y), b.At(1, y)) {
+			t.Fatalf("unexpected vector color at y=%d: %v != %v", y, b.At(1, y), a.At(1, y))
+		}
+	}
+}
+
+// TestRenderStyleHeadroom verifies that Headroom reserves equal margins at the
+// top and bottom of the image, even for full-scale values, using both the
+// raster and vector renderers.
+func TestRenderStyleHeadroom(t *testing.T) {
+	for _, vector := range []bool{false, true} {
+		options := []OptionsFunc{Headroom(0.25)}
+		if vector {
+			options = append(options, VectorRenderer())
+		}
+
+		s, err := NewStyle(options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 25% of 128 pixels, split between the top and bottom
+		const margin = 16
+
+		img := s.Draw([]float64{1.00, 1.00, 1.00})
+		for y := 0; y < imgYDefault; y++ {
+			want := color.Color(color.Black)
+			if y < margin || y >= imgYDefault-margin {
+				want = color.White
+			}
+
+			if got := img.At(1, y); !colorsEqual(got, want) {
+				t.Fatalf("unexpected color at y=%d, vector %v: %v != %v", y, vector, got, want)
+			}
+		}
+	}
+}
+
+// TestRenderStyleMirrorGap verifies that MirrorGap leaves a gap of the
+// requested size between the top and bottom halves of the waveform, using
+// both the raster and vector renderers.
+func TestRenderStyleMirrorGap(t *testing.T) {
+	var tests = []struct {
+		name   string
+		height uint
+		gap    uint
+		top    int
+		bottom int
+	}{
+		{name: "even image, even gap", height: 128, gap: 4, top: 62, bottom: 66},
+		{name: "even image, odd gap", height: 128, gap: 3, top: 63, bottom: 66},
+		{name: "odd image, odd gap", height: 127, gap: 3, top: 62, bottom: 65},
+	}
+
+	for _, tt := range tests {
+		for _, vector := range []bool{false, true} {
+			options := []OptionsFunc{Height(tt.height), MirrorGap(tt.gap)}
+			if vector {
+				options = append(options, VectorRenderer())
+			}
+
+			s, err := NewStyle(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img := s.Draw([]float64{0.10, 0.10, 0.10})
+			for y := tt.top - 2; y < tt.bottom+2; y++ {
+				want := color.Color(color.Black)
+				if y >= tt.top && y < tt.bottom {
+					want = color.White
+				}
+
+				if got := img.At(1, y); !colorsEqual(got, want) {
+					t.Fatalf("%s: unexpected color at y=%d, vector %v: %v != %v",
+						tt.name, y, vector, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestRenderStyleOnColumn verifies that a ColumnFunc is called for each
+// column, in order, by both the raster and vector renderers.
+func TestRenderStyleOnColumn(t *testing.T) {
+	values := []float64{0.1, 0.2, 0.3}
+
+	for _, options := range [][]OptionsFunc{
+		nil,
+		{VectorRenderer()},
+	} {
+		var calls []string
+		fn := func(n int, x int, value float64) {
+			calls = append(calls, fmt.Sprintf("%d/%d/%v", n, x, value))
+		}
+
+		s, err := NewStyle(append(options, Scale(4, 1), OnColumn(fn))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.DrawChecked(values); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := fmt.Sprint(calls), "[0/0/0.1 1/4/0.2 2/8/0.3]"; got != want {
+			t.Fatalf("unexpected calls:\n- want: %v\n-  got: %v", want, got)
+		}
+	}
+}
+
+// TestOptionOnColumnNil verifies that OnColumn does not accept a nil
+// ColumnFunc.
+func TestOptionOnColumnNil(t *testing.T) {
+	testWaveformOptionFunc(t, OnColumn(nil), errOnColumnNil)
+}
@@ -0,0 +1,137 @@
+package waveform
+
+import (
+	"image"
+	"time"
+)
+
+// AllChannels is the Channel of a ColumnInfo whose value was computed from the
+// samples of all audio channels, mixed together.
+const AllChannels = -1
+
+// ColumnInfo describes a single column of a drawn waveform image: the span of
+// time it represents, its value, and its position in the image.  ColumnInfo
+// allows hit-testing, tooltips, and accessibility descriptions to be generated
+// for an image, without duplicating the layout math used to draw it.
+type ColumnInfo struct {
+	// N is the index of the column, from left to right.  N is also the index
+	// of the computed value drawn in the column, unless the PixelsPerSecond
+	// option is in use, in which case several values may share a column.
+	N int
+
+	// Start and End are the span of audio, measured from the beginning of
+	// the stream, which is represented by the column.
+	Start time.Duration
+	End   time.Duration
+
+	// Value is the value drawn in the column, after any sanitization and
+	// smoothing was applied.
+	Value float64
+
+	// Channel is the index of the audio channel from which Value was
+	// computed, or AllChannels if all channels were mixed together.
+	Channel int
+
+	// X is the leftmost X coordinate of the column, Width is the number of
+	// pixels it spans, and Height is the height of the waveform in the column,
+	// in pixels.
+	X      int
+	Width  int
+	Height int
+}
+
+// DrawWithColumns creates a new image.Image from a slice of float64 values, in
+// the same way as DrawChecked, and also returns a ColumnInfo which describes
+// each column of the image.
+func (s RenderStyle) DrawWithColumns(values []float64) (image.Image, []ColumnInfo, error) {
+	return s.drawWithColumns(values, nil)
+}
+
+// DrawValueSetWithColumns creates a new image.Image from a ValueSet, in the
+// same way as DrawValueSet, and also returns a ColumnInfo which describes each
+// column of the image.  The durations of the ValueSet determine the span of
+// time represented by each column, so a short final window is described
+// accurately.
+func (s RenderStyle) DrawValueSetWithColumns(vs *ValueSet) (image.Image, []ColumnInfo, error) {
+	if len(vs.Durations) != len(vs.Values) {
+		return nil, nil, errValueSetMismatch
+	}
+
+	return s.drawWithColumns(vs.Values, vs.Durations)
+}
+
+// DrawWithColumns creates a new image.Image from a slice of float64 values, in
+// the same way as DrawChecked, and also returns a ColumnInfo which describes
+// each column of the image.  Zero or more OptionsFunc may be specified to
+// override options for a single call to DrawWithColumns.
+func (w *Waveform) DrawWithColumns(values []float64, options ...OptionsFunc) (image.Image, []ColumnInfo, error) {
+	ow, err := w.withOptions(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ow.Style().DrawWithColumns(values)
+}
+
+// drawWithColumns lays out and draws a slice of values, and describes each
+// column of the resulting image.  If durations is not nil, it is the duration
+// of audio used to compute each value.
+func (s *RenderStyle) drawWithColumns(values []float64, durations []time.Duration) (image.Image, []ColumnInfo, error) {
+	ls, lvalues, edges, err := s.layout(values, durations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	times := s.columnTimes(len(values), len(lvalues), durations)
+
+	infos := make([]ColumnInfo, 0, len(lvalues))
+	it := ls.columns(lvalues, edges)
+	for it.Next() {
+		c := it.Column()
+		infos = append(infos, ColumnInfo{
+			N:       c.N,
+			Start:   times[c.N],
+			End:     times[c.N+1],
+			Value:   c.Value,
+			Channel: AllChannels,
+			X:       c.X,
+			Width:   c.Width,
+			Height:  c.Height,
+		})
+	}
+
+	return ls.render(lvalues, edges), infos, nil
+}
+
+// columnTimes computes the time at which each of n columns begins, followed by
+// the time at which the final column ends, for a slice of count computed
+// values.  If durations is nil, every value represents a full window at the
+// current resolution.
+func (s *RenderStyle) columnTimes(count int, n int, durations []time.Duration) []time.Duration {
+	// Determine the time at which each computed value begins, and the time at
+	// which the audio ends
+	starts := make([]time.Duration, 0, count+1)
+	var t time.Duration
+	for i := 0; i <= count; i++ {
+		if durations == nil {
+			t = time.Duration(i) * time.Second / time.Duration(s.resolution)
+		} else if i > 0 {
+			t += durations[i-1]
+		}
+
+		starts = append(starts, t)
+	}
+
+	if s.pixelsPerSecond <= 0 {
+		return starts
+	}
+
+	// At a fixed density, each column spans a fixed duration, but the final
+	// column ends with the audio
+	times := make([]time.Duration, 0, n+1)
+	for x := 0; x < n; x++ {
+		times = append(times, time.Duration(float64(x)/s.pixelsPerSecond*float64(time.Second)))
+	}
+
+	return append(times, t)
+}
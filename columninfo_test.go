@@ -0,0 +1,167 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// TestRenderStyleDrawWithColumns verifies that RenderStyle.DrawWithColumns
+// produces the same image as DrawChecked, and describes each of its columns.
+func TestRenderStyleDrawWithColumns(t *testing.T) {
+	s, err := NewStyle(Resolution(4), Scale(3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.50, 0.30}
+	img, infos, err := s.DrawWithColumns(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.DrawChecked(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imagesEqual(t, img, want) {
+		t.Fatal("image does not match DrawChecked")
+	}
+
+	if len(infos) != len(values) {
+		t.Fatalf("unexpected number of columns: %v != %v", len(infos), len(values))
+	}
+
+	for i, c := range infos {
+		start := time.Duration(i) * 250 * time.Millisecond
+		if c.N != i || c.Start != start || c.End != start+250*time.Millisecond {
+			t.Fatalf("[%02d] unexpected column time: %+v", i, c)
+		}
+		if c.X != i*3 || c.Width != 3 || c.Value != values[i] || c.Channel != AllChannels {
+			t.Fatalf("[%02d] unexpected column: %+v", i, c)
+		}
+	}
+}
+
+// TestRenderStyleDrawValueSetWithColumns verifies that the durations of a
+// ValueSet determine the times and widths of columns.
+func TestRenderStyleDrawValueSetWithColumns(t *testing.T) {
+	vs := &ValueSet{
+		Values:    []float64{0.50, 0.50, 0.50},
+		Durations: []time.Duration{time.Second, time.Second, 500 * time.Millisecond},
+	}
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		want    []ColumnInfo
+	}{
+		{
+			name:    "proportional",
+			options: []OptionsFunc{Resolution(1), Scale(4, 1), ProportionalTime()},
+			want: []ColumnInfo{
+				{N: 0, Start: 0, End: time.Second, X: 0, Width: 4},
+				{N: 1, Start: time.Second, End: 2 * time.Second, X: 4, Width: 4},
+				{N: 2, Start: 2 * time.Second, End: 2500 * time.Millisecond, X: 8, Width: 2},
+			},
+		},
+		{
+			name:    "pixels per second",
+			options: []OptionsFunc{PixelsPerSecond(2)},
+			want: []ColumnInfo{
+				{N: 0, Start: 0, End: 500 * time.Millisecond, X: 0, Width: 1},
+				{N: 1, Start: 500 * time.Millisecond, End: time.Second, X: 1, Width: 1},
+				{N: 2, Start: time.Second, End: 1500 * time.Millisecond, X: 2, Width: 1},
+				{N: 3, Start: 1500 * time.Millisecond, End: 2 * time.Second, X: 3, Width: 1},
+				{N: 4, Start: 2 * time.Second, End: 2500 * time.Millisecond, X: 4, Width: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStyle(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, infos, err := s.DrawValueSetWithColumns(vs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := s.DrawValueSet(vs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !imagesEqual(t, img, want) {
+				t.Fatal("image does not match DrawValueSet")
+			}
+
+			if len(infos) != len(tt.want) {
+				t.Fatalf("unexpected number of columns: %v != %v", len(infos), len(tt.want))
+			}
+			for i, c := range infos {
+				w := tt.want[i]
+				if c.N != w.N || c.Start != w.Start || c.End != w.End || c.X != w.X || c.Width != w.Width {
+					t.Fatalf("[%02d] unexpected column:\n- want: %+v\n-  got: %+v", i, w, c)
+				}
+			}
+		})
+	}
+}
+
+// TestRenderStyleDrawValueSetWithColumnsMismatch verifies that a ValueSet with
+// differing numbers of values and durations returns an error.
+func TestRenderStyleDrawValueSetWithColumnsMismatch(t *testing.T) {
+	vs := &ValueSet{
+		Values: []float64{0.50},
+	}
+
+	if _, _, err := (RenderStyle{}).DrawValueSetWithColumns(vs); err != errValueSetMismatch {
+		t.Fatalf("unexpected error: %v != %v", err, errValueSetMismatch)
+	}
+}
+
+// TestWaveformDrawWithColumnsOverride verifies that Waveform.DrawWithColumns
+// applies override options without modifying the receiver.
+func TestWaveformDrawWithColumnsOverride(t *testing.T) {
+	w, err := New(nil, Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, infos, err := w.DrawWithColumns([]float64{0.5, 0.5}, Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end := infos[1].End; end != 500*time.Millisecond {
+		t.Fatalf("unexpected end time: %v", end)
+	}
+
+	_, infos, err = w.DrawWithColumns([]float64{0.5, 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end := infos[1].End; end != time.Second {
+		t.Fatalf("unexpected end time: %v", end)
+	}
+}
+
+// imagesEqual is a test helper which reports whether two images encode to
+// identical PNG images.
+func imagesEqual(t *testing.T, a image.Image, b image.Image) bool {
+	t.Helper()
+
+	var ab, bb bytes.Buffer
+	if err := png.Encode(&ab, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&bb, b); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Equal(ab.Bytes(), bb.Bytes())
+}
@@ -0,0 +1,51 @@
+package waveform
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// errValueFuncNil is returned when a nil ValueFunc is used to receive
+	// computed values.
+	errValueFuncNil = errors.New("waveform: value function cannot be nil")
+
+	// errValueFuncCheckpoint is returned when values are streamed to a
+	// ValueFunc while checkpointing is enabled, because a Checkpoint must
+	// contain every value computed so far.
+	errValueFuncCheckpoint = &OptionsError{
+		Option: "checkpointEvery",
+		Reason: "checkpoints cannot be written while streaming values",
+		Code:   CodeConflict,
+	}
+)
+
+// ValueFunc is a function which receives each value computed by
+// Waveform.ComputeFunc, in order.  n is the index of the value, beginning at 0,
+// and d is the duration of audio which was reduced to produce it.
+//
+// If a ValueFunc returns an error, computation stops and the error is
+// returned.
+type ValueFunc func(n int, value float64, d time.Duration) error
+
+// ComputeFunc computes values in the same way as Compute, but delivers each
+// value to fn as soon as it is computed, rather than returning a slice of all
+// values once the stream has been read.
+//
+// ComputeFunc allows applications such as servers which generate peaks for
+// very long streams to send values to clients progressively, without holding
+// every value in memory.  ComputeFunc cannot be used with CheckpointEvery.
+func (w *Waveform) ComputeFunc(fn ValueFunc) error {
+	if fn == nil {
+		return errValueFuncNil
+	}
+
+	cw := w.clone()
+	cw.valueFn = fn
+	if err := cw.Validate(); err != nil {
+		return err
+	}
+
+	_, err := cw.readAndComputeSamples(nil)
+	return err
+}
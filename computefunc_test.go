@@ -0,0 +1,110 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// TestComputeFunc verifies that ComputeFunc delivers the same values as
+// ComputeValueSet, in order.
+func TestComputeFunc(t *testing.T) {
+	pcm := []int16{0, 100, -100, 32767, -32768, 1000, 2000, -3000, 16384, 0}
+	wav := makeWAV(8, 1, pcm)
+
+	w, err := New(bytes.NewReader(wav), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ns        []int
+		values    []float64
+		durations []time.Duration
+	)
+	err = w.ComputeFunc(func(n int, value float64, d time.Duration) error {
+		ns = append(ns, n)
+		values = append(values, value)
+		durations = append(durations, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ww, err := New(bytes.NewReader(wav), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := ww.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(ns), fmt.Sprint([]int{0, 1, 2, 3, 4}); got != want {
+		t.Fatalf("unexpected indices: %v != %v", got, want)
+	}
+	if fmt.Sprint(values) != fmt.Sprint(vs.Values) {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", vs.Values, values)
+	}
+	if fmt.Sprint(durations) != fmt.Sprint(vs.Durations) {
+		t.Fatalf("unexpected durations:\n- want: %v\n-  got: %v", vs.Durations, durations)
+	}
+}
+
+// TestComputeFuncStop verifies that an error returned by a ValueFunc stops
+// computation and is returned.
+func TestComputeFuncStop(t *testing.T) {
+	w, err := New(bytes.NewReader(makeWAV(8, 1, make([]int16, 80))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := errors.New("stop")
+
+	var calls int
+	err = w.ComputeFunc(func(n int, _ float64, _ time.Duration) error {
+		calls++
+		if n == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("unexpected error: %v != %v", err, errStop)
+	}
+	if calls != 3 {
+		t.Fatalf("unexpected number of calls: %d", calls)
+	}
+}
+
+// TestComputeFuncErrors verifies that ComputeFunc returns appropriate errors
+// for invalid input.
+func TestComputeFuncErrors(t *testing.T) {
+	noop := func(int, float64, time.Duration) error { return nil }
+
+	w, err := New(bytes.NewReader(makeWAV(8, 1, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.ComputeFunc(nil); err != errValueFuncNil {
+		t.Fatalf("unexpected error: %v != %v", err, errValueFuncNil)
+	}
+	if err := w.ComputeFunc(noop); err != ErrTooShort {
+		t.Fatalf("unexpected error: %v != %v", err, ErrTooShort)
+	}
+
+	cw, err := New(nil, CheckpointEvery(1, ioutil.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.ComputeFunc(noop); err != errValueFuncCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errValueFuncCheckpoint)
+	}
+}
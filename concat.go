@@ -0,0 +1,228 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"io"
+
+	"azul3d.org/engine/audio"
+)
+
+var (
+	// errConcatNoInputs is returned when values are computed from an empty
+	// list of inputs.
+	errConcatNoInputs = errors.New("waveform: at least one input is required for concatenation")
+
+	// errConcatConfig is returned when concatenated inputs do not share the
+	// same sample rate and number of channels.
+	errConcatConfig = errors.New("waveform: concatenated inputs must have the same sample rate and channels")
+)
+
+// ComputeConcat computes values from multiple input audio streams, such as the
+// tracks of an album, as if they were a single continuous stream, applying the
+// same zero or more, variadic, OptionsFunc parameters to the computation.  Each
+// input is opened and decoded in order, only once the previous input has been
+// read to its end.  All inputs must have the same sample rate and number of
+// channels.
+//
+// Windows of audio span the boundaries between inputs, so the values are
+// identical to those computed from a single stream containing the audio of
+// every input.  The returned boundaries contain one index for each input: the
+// index of the first value computed from audio in that input, which is useful
+// for drawing separators between tracks.  The first boundary is always 0.
+// Because a window may contain audio from two adjacent inputs, the value at a
+// boundary may also include audio from the end of the previous input.  An
+// input which contains no audio has the same boundary as the input after it,
+// and an input which begins after the last computed value has a boundary equal
+// to the number of values.
+//
+// The Offset and Duration options apply to the concatenated stream, and
+// boundaries are reported relative to the first value computed from that
+// range.
+func ComputeConcat(inputs []io.Reader, options ...OptionsFunc) (*ValueSet, []int, error) {
+	if len(inputs) == 0 {
+		return nil, nil, errConcatNoInputs
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Inputs are opened using a copy of w, because w itself decodes the
+	// concatenated stream
+	d, err := newConcatDecoder(w.clone(), inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer d.Close()
+
+	w.decoder = d
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vs, d.boundaries(vs, timeSamples(d.config, w.offset)), nil
+}
+
+// GenerateConcat computes values from multiple input audio streams as if they
+// were a single continuous stream, in the same way as ComputeConcat, and
+// returns a waveform image which is customized by zero or more, variadic,
+// OptionsFunc parameters.  The returned boundaries contain the index of the
+// first value computed from each input.
+func GenerateConcat(inputs []io.Reader, options ...OptionsFunc) (image.Image, []int, error) {
+	vs, boundaries, err := ComputeConcat(inputs, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Draw columns proportional to time if requested, which requires the
+	// duration of each window
+	if w.style.proportional || w.style.pixelsPerSecond > 0 {
+		img, err := w.DrawValueSet(vs)
+		return img, boundaries, err
+	}
+
+	img, err := w.DrawChecked(vs.Values)
+	return img, boundaries, err
+}
+
+// concatDecoder is an audio.Decoder which decodes multiple input audio
+// streams in order, as a single continuous stream.
+type concatDecoder struct {
+	w      *Waveform
+	inputs []io.Reader
+	config audio.Config
+
+	// cur is the decoder for the current input, and next is the index of the
+	// next input to be opened
+	cur  *safeDecoder
+	next int
+
+	// read is the number of samples read from all inputs, and starts is the
+	// number of samples which were read before each opened input began
+	read   int64
+	starts []int64
+}
+
+// newConcatDecoder creates a concatDecoder which opens each input using w.
+// The first input is opened immediately, to determine the configuration of
+// the concatenated stream.
+func newConcatDecoder(w *Waveform, inputs []io.Reader) (*concatDecoder, error) {
+	d := &concatDecoder{
+		w:      w,
+		inputs: inputs,
+	}
+
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	d.config = d.cur.Config()
+
+	return d, nil
+}
+
+// open opens the next input, closing the current input if needed.
+func (d *concatDecoder) open() error {
+	if err := d.closeCurrent(); err != nil {
+		return err
+	}
+
+	cur, err := d.w.openDecoder(d.inputs[d.next])
+	if err != nil {
+		return err
+	}
+	d.cur = cur
+	d.next++
+	d.starts = append(d.starts, d.read)
+
+	if d.next > 1 && d.cur.Config() != d.config {
+		return errConcatConfig
+	}
+
+	return nil
+}
+
+// Config implements audio.Decoder.
+func (d *concatDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read implements audio.Decoder.  End-of-stream is only reported once the
+// last input has been read to its end.
+func (d *concatDecoder) Read(b audio.Slice) (int, error) {
+	for {
+		n, err := d.cur.Read(b)
+		d.read += int64(n)
+		if err != audio.EOS {
+			return n, err
+		}
+
+		if d.next == len(d.inputs) {
+			return n, audio.EOS
+		}
+		if err := d.open(); err != nil {
+			return n, err
+		}
+
+		// Report samples read from the end of the previous input immediately,
+		// but continue into the next input if none were read
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the decoder for the current input.
+func (d *concatDecoder) Close() error {
+	return d.closeCurrent()
+}
+
+// closeCurrent closes the decoder for the current input, if any.
+func (d *concatDecoder) closeCurrent() error {
+	if d.cur == nil {
+		return nil
+	}
+
+	err := d.cur.Close()
+	d.cur = nil
+	return err
+}
+
+// boundaries returns the index of the first value in vs which was computed
+// from each input, where skip samples were discarded before the first value.
+// Inputs which were never opened begin after the last value.
+func (d *concatDecoder) boundaries(vs *ValueSet, skip int64) []int {
+	out := make([]int, len(d.inputs))
+
+	var (
+		i   int
+		end int64
+	)
+	for j := range out {
+		if j >= len(d.starts) {
+			out[j] = len(vs.Values)
+			continue
+		}
+
+		// Advance to the first value whose window ends after the input begins
+		start := d.starts[j] - skip
+		for i < len(vs.Counts) && end+int64(vs.Counts[i]) <= start {
+			end += int64(vs.Counts[i])
+			i++
+		}
+		out[j] = i
+	}
+
+	// The first input always begins with the first value
+	out[0] = 0
+
+	return out
+}
@@ -0,0 +1,137 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestComputeConcat verifies that ComputeConcat computes the same values as a
+// single continuous stream, and reports the boundary of each input.
+func TestComputeConcat(t *testing.T) {
+	// Three tracks of mono audio at 8Hz, 1.5s, 1s, and 0.5s long, each louder
+	// than the last
+	tracks := [][]int16{
+		concatPCM(12, 1000),
+		concatPCM(8, 2000),
+		concatPCM(4, 3000),
+	}
+
+	var all []int16
+	for _, tr := range tracks {
+		all = append(all, tr...)
+	}
+	want := computeTimeRange(t, makeWAV(8, 1, all))
+
+	var tests = []struct {
+		name       string
+		options    []OptionsFunc
+		values     []float64
+		boundaries []int
+	}{
+		{
+			name:       "full",
+			values:     want,
+			boundaries: []int{0, 1, 2},
+		},
+		{
+			// Windows begin 1s into the first track
+			name:       "offset",
+			options:    []OptionsFunc{Offset(time.Second)},
+			values:     want[:2],
+			boundaries: []int{0, 0, 1},
+		},
+		{
+			// The last track is never read
+			name:       "duration",
+			options:    []OptionsFunc{Duration(2 * time.Second)},
+			values:     want[:2],
+			boundaries: []int{0, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inputs []io.Reader
+			for _, tr := range tracks {
+				inputs = append(inputs, bytes.NewReader(makeWAV(8, 1, tr)))
+			}
+
+			vs, boundaries, err := ComputeConcat(inputs, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.name == "offset" {
+				// Values are computed from a different range of samples, so
+				// only their number is compared
+				if len(vs.Values) != len(tt.values) {
+					t.Fatalf("unexpected number of values: %d", len(vs.Values))
+				}
+			} else if got, want := fmt.Sprint(vs.Values), fmt.Sprint(tt.values); got != want {
+				t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+			}
+
+			if got, want := fmt.Sprint(boundaries), fmt.Sprint(tt.boundaries); got != want {
+				t.Fatalf("unexpected boundaries: %v != %v", got, want)
+			}
+		})
+	}
+}
+
+// TestGenerateConcat verifies that GenerateConcat draws one column for each
+// value computed from the concatenated inputs.
+func TestGenerateConcat(t *testing.T) {
+	inputs := []io.Reader{
+		bytes.NewReader(makeWAV(8, 1, concatPCM(16, 1000))),
+		bytes.NewReader(makeWAV(8, 1, concatPCM(8, 2000))),
+	}
+
+	img, boundaries, err := GenerateConcat(inputs, Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := img.Bounds().Dx(); got != 6 {
+		t.Fatalf("unexpected image width: %d", got)
+	}
+	if got, want := fmt.Sprint(boundaries), fmt.Sprint([]int{0, 2}); got != want {
+		t.Fatalf("unexpected boundaries: %v != %v", got, want)
+	}
+}
+
+// TestComputeConcatErrors verifies that ComputeConcat returns appropriate
+// errors for invalid input.
+func TestComputeConcatErrors(t *testing.T) {
+	if _, _, err := ComputeConcat(nil); err != errConcatNoInputs {
+		t.Fatalf("unexpected error: %v != %v", err, errConcatNoInputs)
+	}
+
+	inputs := []io.Reader{
+		bytes.NewReader(makeWAV(8, 1, concatPCM(8, 1000))),
+		bytes.NewReader(makeWAV(16, 1, concatPCM(16, 1000))),
+	}
+	if _, _, err := ComputeConcat(inputs); err != errConcatConfig {
+		t.Fatalf("unexpected error: %v != %v", err, errConcatConfig)
+	}
+
+	inputs = []io.Reader{
+		bytes.NewReader(makeWAV(8, 1, concatPCM(8, 1000))),
+		bytes.NewReader([]byte("not audio at all")),
+	}
+	if _, _, err := ComputeConcat(inputs); err != ErrFormat {
+		t.Fatalf("unexpected error: %v != %v", err, ErrFormat)
+	}
+}
+
+// concatPCM returns n samples of 16-bit PCM audio with constant amplitude.
+func concatPCM(n int, amplitude int16) []int16 {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = amplitude
+	}
+
+	return pcm
+}
@@ -0,0 +1,44 @@
+package waveform
+
+import (
+	"errors"
+
+	"azul3d.org/engine/audio"
+)
+
+// errDecoderNil is returned when a nil audio.Decoder is used to create a
+// Waveform.
+var errDecoderNil = errors.New("waveform: audio decoder must not be nil")
+
+// NewFromDecoder generates a new Waveform struct which computes values by
+// reading audio samples from an existing audio.Decoder, applying any input
+// OptionsFunc on return.
+//
+// NewFromDecoder allows applications which have already opened an audio
+// stream, and perhaps inspected its configuration or metadata, to compute
+// values without handing over the raw input stream and decoding it a second
+// time.  Values are computed from the decoder's current position, so samples
+// which have already been read are not included.
+//
+// The caller retains ownership of the decoder: it is never closed by the
+// Waveform, even if it implements io.Closer.  As with New, values may only be
+// computed once, because the decoder is consumed while computing them.
+func NewFromDecoder(d audio.Decoder, options ...OptionsFunc) (*Waveform, error) {
+	if d == nil {
+		return nil, errDecoderNil
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, err
+	}
+	w.decoder = d
+
+	return w, nil
+}
+
+// callerDecoder wraps an audio.Decoder provided by the caller, hiding any
+// Close method so that the decoder is not closed once values are computed.
+type callerDecoder struct {
+	audio.Decoder
+}
@@ -0,0 +1,81 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestNewFromDecoder verifies that a Waveform created by NewFromDecoder
+// computes the same values as one created by New, and does not close the
+// caller's decoder.
+func TestNewFromDecoder(t *testing.T) {
+	pcm := []int16{0, 100, -100, 32767, -32768, 1000, 2000, -3000, 16384, 0}
+	wav := makeWAV(8, 1, pcm)
+
+	d, _, err := audio.NewDecoder(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller may inspect the decoder before handing it over
+	if config := d.Config(); config.SampleRate != 8 || config.Channels != 1 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	cd := &closeDecoder{Decoder: d}
+	w, err := NewFromDecoder(cd, Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ww, err := New(bytes.NewReader(wav), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ww.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if cd.closed {
+		t.Fatal("caller's decoder was closed")
+	}
+}
+
+// TestNewFromDecoderErrors verifies that NewFromDecoder returns appropriate
+// errors for a nil decoder or invalid options.
+func TestNewFromDecoderErrors(t *testing.T) {
+	if _, err := NewFromDecoder(nil); err != errDecoderNil {
+		t.Fatalf("unexpected error: %v != %v", err, errDecoderNil)
+	}
+
+	d := &sampleDecoder{config: audio.Config{SampleRate: 8000, Channels: 1}}
+	if _, err := NewFromDecoder(d, Resolution(0)); err != errResolutionZero {
+		t.Fatalf("unexpected error: %v != %v", err, errResolutionZero)
+	}
+}
+
+// closeDecoder is an audio.Decoder which records whether it was closed.
+type closeDecoder struct {
+	audio.Decoder
+	closed bool
+}
+
+// Close implements io.Closer.
+func (d *closeDecoder) Close() error {
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,199 @@
+package waveform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// describeLoud and describeQuiet are the fractions of the peak value at
+	// or above which a value is loud, and below which a value is quiet.
+	describeLoud  = 0.5
+	describeQuiet = 0.1
+
+	// describeMinSection is the fraction of the total duration which a loud
+	// or quiet section must span to be included in a Description, so that
+	// descriptions remain concise.
+	describeMinSection = 0.05
+)
+
+// Description is a summary of the computed values of an audio stream, which
+// can be rendered as a textual description of a waveform image, such as for
+// alt text.  A Description is created using Describe.
+type Description struct {
+	// Duration is the total duration of the audio stream.
+	Duration time.Duration
+
+	// Peak is the largest computed value, and PeakTime is the time at which
+	// the window of audio used to compute it began.
+	Peak     float64
+	PeakTime time.Duration
+
+	// Loud and Quiet are the sections of the stream whose values are at
+	// least half of the peak, and less than a tenth of the peak, in order.
+	// Sections which span less than 5% of the total duration are omitted.
+	Loud  []Range
+	Quiet []Range
+}
+
+// Describe creates a Description from a ValueSet, as returned by
+// ComputeValueSet.  The durations of the ValueSet position each value in time.
+//
+// Describe returns an error if the ValueSet has differing numbers of values
+// and durations.
+func Describe(vs *ValueSet) (*Description, error) {
+	if len(vs.Durations) != len(vs.Values) {
+		return nil, errValueSetMismatch
+	}
+
+	d := &Description{}
+
+	// Determine the start time of each value, the total duration, and the
+	// peak value
+	starts := make([]time.Duration, len(vs.Values))
+	for i, v := range vs.Values {
+		starts[i] = d.Duration
+		d.Duration += vs.Durations[i]
+
+		if v > d.Peak {
+			d.Peak = v
+			d.PeakTime = starts[i]
+		}
+	}
+
+	min := time.Duration(float64(d.Duration) * describeMinSection)
+	d.Loud = describeSections(vs, starts, min, func(v float64) bool {
+		return d.Peak > 0 && v >= d.Peak*describeLoud
+	})
+	d.Quiet = describeSections(vs, starts, min, func(v float64) bool {
+		return v < d.Peak*describeQuiet || d.Peak == 0
+	})
+
+	return d, nil
+}
+
+// describeSections returns the ranges of consecutive values in a ValueSet for
+// which fn returns true, and which last at least as long as the input minimum
+// duration.
+func describeSections(vs *ValueSet, starts []time.Duration, min time.Duration, fn func(v float64) bool) []Range {
+	var (
+		ranges []Range
+		in     bool
+		start  time.Duration
+	)
+
+	for i, v := range vs.Values {
+		switch match := fn(v); {
+		case match && !in:
+			start, in = starts[i], true
+		case !match && in:
+			ranges = appendSection(ranges, Range{Start: start, End: starts[i]}, min)
+			in = false
+		}
+	}
+
+	if in {
+		end := starts[len(starts)-1] + vs.Durations[len(vs.Durations)-1]
+		ranges = appendSection(ranges, Range{Start: start, End: end}, min)
+	}
+
+	return ranges
+}
+
+// appendSection appends r to ranges, if it lasts at least as long as the input
+// minimum duration.
+func appendSection(ranges []Range, r Range, min time.Duration) []Range {
+	if r.Duration() < min || r.Duration() == 0 {
+		return ranges
+	}
+
+	return append(ranges, r)
+}
+
+// String returns a textual description of the waveform, suitable for use as
+// alt text, such as:
+//
+//	Audio waveform, 3 minutes 25 seconds long. The loudest point is at 1:02.
+//	Loud sections: 0:10 to 0:45, 1:00 to 1:30. Quiet sections: 0:00 to 0:05.
+func (d *Description) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Audio waveform, %s long.", describeDuration(d.Duration))
+
+	if d.Peak == 0 {
+		b.WriteString(" The audio is silent.")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, " The loudest point is at %s.", describeTime(d.PeakTime))
+	writeSections(&b, "Loud", d.Loud)
+	writeSections(&b, "Quiet", d.Quiet)
+
+	return b.String()
+}
+
+// writeSections writes a sentence which lists a kind of section, if any exist.
+func writeSections(b *strings.Builder, kind string, ranges []Range) {
+	if len(ranges) == 0 {
+		return
+	}
+
+	noun := "sections"
+	if len(ranges) == 1 {
+		noun = "section"
+	}
+
+	times := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		times = append(times, fmt.Sprintf("%s to %s", describeTime(r.Start), describeTime(r.End)))
+	}
+
+	fmt.Fprintf(b, " %s %s: %s.", kind, noun, strings.Join(times, ", "))
+}
+
+// describeDuration formats a duration in words, such as "3 minutes 25 seconds".
+// Durations shorter than a minute are formatted to a tenth of a second.
+func describeDuration(d time.Duration) string {
+	if d < time.Minute {
+		s := d.Round(100 * time.Millisecond).Seconds()
+		if s == 1 {
+			return "1 second"
+		}
+
+		return fmt.Sprintf("%g seconds", s)
+	}
+
+	d = d.Round(time.Second)
+	parts := make([]string, 0, 3)
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{d: time.Hour, name: "hour"},
+		{d: time.Minute, name: "minute"},
+		{d: time.Second, name: "second"},
+	} {
+		n := d / u.d
+		d -= n * u.d
+
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+u.name)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, u.name))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// describeTime formats a timestamp as minutes and seconds, such as "1:02", or
+// hours, minutes, and seconds, such as "1:02:03".
+func describeTime(d time.Duration) string {
+	s := int64(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,120 @@
+package waveform
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestDescribe verifies that Describe summarizes the duration, peak, and loud
+// and quiet sections of a ValueSet.
+func TestDescribe(t *testing.T) {
+	values := []float64{
+		0.00, 0.00, 0.00, 0.20, 0.30,
+		0.80, 0.90, 1.00, 0.70, 0.20,
+		0.30, 0.60, 0.20, 0.30, 0.20,
+		0.01, 0.01, 0.01, 0.01, 0.01,
+		0.01, 0.01, 0.01, 0.01, 0.01,
+	}
+
+	d, err := Describe(testValueSet(values, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Duration != 25*time.Second || d.Peak != 1.00 || d.PeakTime != 7*time.Second {
+		t.Fatalf("unexpected description: %+v", d)
+	}
+
+	// The single loud value at 11 seconds is too short to be included
+	wantLoud := []Range{{Start: 5 * time.Second, End: 9 * time.Second}}
+	wantQuiet := []Range{
+		{Start: 0, End: 3 * time.Second},
+		{Start: 15 * time.Second, End: 25 * time.Second},
+	}
+
+	if got, want := fmt.Sprint(d.Loud), fmt.Sprint(wantLoud); got != want {
+		t.Fatalf("unexpected loud sections:\n- want: %v\n-  got: %v", want, got)
+	}
+	if got, want := fmt.Sprint(d.Quiet), fmt.Sprint(wantQuiet); got != want {
+		t.Fatalf("unexpected quiet sections:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	want := "Audio waveform, 25 seconds long. The loudest point is at 0:07. " +
+		"Loud section: 0:05 to 0:09. Quiet sections: 0:00 to 0:03, 0:15 to 0:25."
+	if got := d.String(); got != want {
+		t.Fatalf("unexpected string:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+// TestDescribeSilent verifies that a silent ValueSet is described as silent.
+func TestDescribeSilent(t *testing.T) {
+	d, err := Describe(testValueSet([]float64{0, 0, 0}, 500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Audio waveform, 1.5 seconds long. The audio is silent."
+	if got := d.String(); got != want {
+		t.Fatalf("unexpected string:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+// TestDescribeMismatch verifies that a ValueSet with differing numbers of
+// values and durations returns an error.
+func TestDescribeMismatch(t *testing.T) {
+	if _, err := Describe(&ValueSet{Values: []float64{1}}); err != errValueSetMismatch {
+		t.Fatalf("unexpected error: %v != %v", err, errValueSetMismatch)
+	}
+}
+
+// TestDescribeDuration verifies that durations are formatted in words.
+func TestDescribeDuration(t *testing.T) {
+	var tests = []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 250 * time.Millisecond, want: "0.3 seconds"},
+		{d: time.Second, want: "1 second"},
+		{d: 59 * time.Second, want: "59 seconds"},
+		{d: time.Minute, want: "1 minute"},
+		{d: 3*time.Minute + 25*time.Second, want: "3 minutes 25 seconds"},
+		{d: time.Hour + 2*time.Minute + time.Second, want: "1 hour 2 minutes 1 second"},
+	}
+
+	for _, tt := range tests {
+		if got := describeDuration(tt.d); got != tt.want {
+			t.Fatalf("unexpected duration for %v: %q != %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// TestDescribeTime verifies that timestamps are formatted as minutes and
+// seconds, or hours, minutes, and seconds.
+func TestDescribeTime(t *testing.T) {
+	var tests = []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0:00"},
+		{d: 62 * time.Second, want: "1:02"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second, want: "1:02:03"},
+	}
+
+	for _, tt := range tests {
+		if got := describeTime(tt.d); got != tt.want {
+			t.Fatalf("unexpected time for %v: %q != %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// testValueSet is a test helper which creates a ValueSet in which every value
+// was computed from the input duration of audio.
+func testValueSet(values []float64, d time.Duration) *ValueSet {
+	vs := &ValueSet{Values: values}
+	for range values {
+		vs.Durations = append(vs.Durations, d)
+	}
+
+	return vs
+}
@@ -0,0 +1,207 @@
+package waveform
+
+import (
+	"io"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+// DownmixPolicy is a policy which determines how the channels of an audio
+// stream are combined before each window of audio samples is reduced to a
+// computed value.
+type DownmixPolicy int
+
+const (
+	// DownmixNone reduces the interleaved samples of all channels together.
+	// This is the default behavior of the waveform package.
+	DownmixNone DownmixPolicy = iota
+
+	// DownmixAverage averages the samples of all channels in each sample
+	// frame, producing a single channel.
+	DownmixAverage
+
+	// DownmixITU downmixes 5.1 and 7.1 surround streams to stereo using the
+	// coefficients of ITU-R BS.775, in which the center and surround channels
+	// are attenuated by 3dB and the LFE channel is discarded.  Channels are
+	// expected in the standard WAV order: front left, front right, center,
+	// LFE, followed by the surround channels.  The result is normalized so
+	// that it never exceeds full scale.  Mono and stereo streams are left
+	// unchanged, and streams with any other number of channels are averaged,
+	// as with DownmixAverage.
+	DownmixITU
+
+	// DownmixFirst retains only the first channels of each sample frame, and
+	// discards the rest.  The number of channels retained is set using the
+	// DownmixChannels option, and is 2 by default.
+	DownmixFirst
+)
+
+// downmixChannelsDefault is the default number of channels retained by the
+// DownmixFirst policy.
+const downmixChannelsDefault = 2
+
+// downmixMatrix returns the coefficients used to downmix audio with the input
+// number of channels, using the input policy.  Each row of the matrix produces
+// one output channel from the channels of an input sample frame.  If the
+// audio does not need to be downmixed, nil is returned.
+func downmixMatrix(policy DownmixPolicy, channels int, first uint) [][]float64 {
+	switch policy {
+	case DownmixAverage:
+		if channels == 1 {
+			return nil
+		}
+
+		row := make([]float64, channels)
+		for i := range row {
+			row[i] = 1 / float64(channels)
+		}
+
+		return [][]float64{row}
+	case DownmixITU:
+		// Coefficients for front left, front right, center, LFE, and any
+		// surround channels, which alternate between left and right
+		const k = math.Sqrt2 / 2
+		switch channels {
+		case 1, 2:
+			return nil
+		case 6:
+			return normalizeMatrix([][]float64{
+				{1, 0, k, 0, k, 0},
+				{0, 1, k, 0, 0, k},
+			})
+		case 8:
+			return normalizeMatrix([][]float64{
+				{1, 0, k, 0, k, 0, k, 0},
+				{0, 1, k, 0, 0, k, 0, k},
+			})
+		default:
+			return downmixMatrix(DownmixAverage, channels, first)
+		}
+	case DownmixFirst:
+		if int(first) >= channels {
+			return nil
+		}
+
+		m := make([][]float64, first)
+		for i := range m {
+			m[i] = make([]float64, channels)
+			m[i][i] = 1
+		}
+
+		return m
+	default:
+		return nil
+	}
+}
+
+// normalizeMatrix scales each row of a downmix matrix so that its coefficients
+// sum to 1, so that the output never exceeds full scale.
+func normalizeMatrix(m [][]float64) [][]float64 {
+	for _, row := range m {
+		var sum float64
+		for _, k := range row {
+			sum += k
+		}
+		for i := range row {
+			row[i] /= sum
+		}
+	}
+
+	return m
+}
+
+// mixFunc downmixes a single sample frame of an input stream into a sample
+// frame of downmixed audio, stored in out.
+type mixFunc func(frame []float64, out []float64)
+
+// matrixMix creates a mixFunc which downmixes audio using the input downmix
+// matrix.
+func matrixMix(matrix [][]float64) mixFunc {
+	return func(frame []float64, out []float64) {
+		for c, row := range matrix {
+			var v float64
+			for i, k := range row {
+				v += k * frame[i]
+			}
+
+			out[c] = v
+		}
+	}
+}
+
+// downmixReader is an audio.Reader which downmixes the channels of audio read
+// from an underlying audio.Reader using a mixFunc.
+type downmixReader struct {
+	r   audio.Reader
+	mix mixFunc
+
+	// in and out are the number of channels before and after downmixing
+	in  int
+	out int
+
+	// buf holds samples read from r before they are downmixed, and frame
+	// holds a single downmixed sample frame
+	buf   audio.Float64
+	frame []float64
+
+	// source accumulates the samples read from r before they are downmixed,
+	// if retain is set, so that they can be passed to an Analyzer
+	retain bool
+	source audio.Float64
+}
+
+// newDownmixReader creates a downmixReader which reads audio with the input
+// configuration from r, and downmixes it to the input number of channels
+// using mix.  It returns the configuration of the downmixed audio.
+func newDownmixReader(r audio.Reader, config audio.Config, channels int, mix mixFunc) (*downmixReader, audio.Config) {
+	dr := &downmixReader{
+		r:     r,
+		mix:   mix,
+		in:    config.Channels,
+		out:   channels,
+		frame: make([]float64, channels),
+	}
+	config.Channels = channels
+
+	return dr, config
+}
+
+// Read implements audio.Reader.  Only whole sample frames are downmixed, so
+// b should have room for at least one downmixed sample frame.
+func (dr *downmixReader) Read(b audio.Slice) (int, error) {
+	frames := b.Len() / dr.out
+	if frames == 0 {
+		return 0, io.ErrShortBuffer
+	}
+
+	need := frames * dr.in
+	if cap(dr.buf) < need {
+		dr.buf = make(audio.Float64, need)
+	}
+	buf := dr.buf[:need]
+
+	// Read whole sample frames, so that no samples are carried between reads
+	n, err := dr.r.Read(buf)
+	for err == nil && n%dr.in != 0 {
+		var rn int
+		rn, err = dr.r.Read(buf[n:])
+		n += rn
+		if err == nil && rn == 0 {
+			err = io.ErrNoProgress
+		}
+	}
+
+	if dr.retain {
+		dr.source = append(dr.source, buf[:n/dr.in*dr.in]...)
+	}
+
+	for f := 0; f < n/dr.in; f++ {
+		dr.mix(buf[f*dr.in:(f+1)*dr.in], dr.frame)
+		for c, v := range dr.frame {
+			b.Set(f*dr.out+c, v)
+		}
+	}
+
+	return n / dr.in * dr.out, err
+}
@@ -0,0 +1,121 @@
+package waveform
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestDownmix verifies that each DownmixPolicy combines the channels of a
+// surround stream before values are computed.
+func TestDownmix(t *testing.T) {
+	// One second of 5.1 audio at 8Hz, with a constant half scale signal in the
+	// front left and right channels, and silence in all others
+	var pcm []int16
+	for i := 0; i < 8; i++ {
+		pcm = append(pcm, 16384, 16384, 0, 0, 0, 0)
+	}
+	wav := makeWAV(8, 6, pcm)
+
+	itu := 0.5 / (1 + math.Sqrt2)
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		value   float64
+	}{
+		{
+			name:  "none",
+			value: math.Sqrt(2 * 0.25 / 6),
+		},
+		{
+			name:    "average",
+			options: []OptionsFunc{Downmix(DownmixAverage)},
+			value:   1.0 / 6,
+		},
+		{
+			name:    "ITU",
+			options: []OptionsFunc{Downmix(DownmixITU)},
+			value:   itu,
+		},
+		{
+			name:    "first two",
+			options: []OptionsFunc{Downmix(DownmixFirst)},
+			value:   0.5,
+		},
+		{
+			name:    "first four",
+			options: []OptionsFunc{Downmix(DownmixFirst), DownmixChannels(4)},
+			value:   math.Sqrt(2 * 0.25 / 4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(bytes.NewReader(wav), tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			vs, err := w.ComputeValueSet()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(vs.Values) != 1 {
+				t.Fatalf("unexpected number of values: %d", len(vs.Values))
+			}
+			if math.Abs(vs.Values[0]-tt.value) > 1e-3 {
+				t.Fatalf("unexpected value: %v != %v", vs.Values[0], tt.value)
+			}
+
+			// Downmixing never changes the duration of audio
+			if vs.Durations[0] != time.Second {
+				t.Fatalf("unexpected duration: %v", vs.Durations[0])
+			}
+		})
+	}
+}
+
+// TestDownmixMatrix verifies the coefficients used for each DownmixPolicy.
+func TestDownmixMatrix(t *testing.T) {
+	// Mono and stereo streams are never downmixed by ITU, and no more channels
+	// can be retained than a stream has
+	for _, c := range []int{1, 2} {
+		if m := downmixMatrix(DownmixITU, c, 2); m != nil {
+			t.Fatalf("unexpected ITU matrix for %d channels: %v", c, m)
+		}
+	}
+	if m := downmixMatrix(DownmixFirst, 2, 4); m != nil {
+		t.Fatalf("unexpected first matrix: %v", m)
+	}
+	if m := downmixMatrix(DownmixNone, 6, 2); m != nil {
+		t.Fatalf("unexpected none matrix: %v", m)
+	}
+
+	// Other layouts fall back to averaging
+	m := downmixMatrix(DownmixITU, 4, 2)
+	if len(m) != 1 || m[0][3] != 0.25 {
+		t.Fatalf("unexpected ITU matrix for 4 channels: %v", m)
+	}
+
+	// Each output of the 7.1 ITU downmix never exceeds full scale
+	for _, row := range downmixMatrix(DownmixITU, 8, 2) {
+		var sum float64
+		for _, k := range row {
+			sum += k
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("unexpected sum of coefficients: %v", sum)
+		}
+	}
+}
+
+// TestDownmixOptions verifies that Downmix and DownmixChannels reject invalid
+// input.
+func TestDownmixOptions(t *testing.T) {
+	testWaveformOptionFunc(t, Downmix(DownmixITU), nil)
+	testWaveformOptionFunc(t, Downmix(DownmixPolicy(-1)), errDownmixInvalid)
+	testWaveformOptionFunc(t, DownmixChannels(0), errDownmixChannelsZero)
+}
@@ -0,0 +1,46 @@
+package waveform
+
+// DownmixFunc is a function which combines the samples of a single sample
+// frame, one for each channel of an audio stream, into a single sample.
+type DownmixFunc func(frame []float64) float64
+
+// DownmixLeft is a DownmixFunc which selects the left channel of a sample
+// frame, which is the first channel of a stream.
+func DownmixLeft(frame []float64) float64 {
+	return frame[0]
+}
+
+// DownmixRight is a DownmixFunc which selects the right channel of a sample
+// frame, which is the second channel of a stream.  Mono streams have no right
+// channel, so their only channel is selected.
+func DownmixRight(frame []float64) float64 {
+	if len(frame) < 2 {
+		return frame[0]
+	}
+
+	return frame[1]
+}
+
+// DownmixMid is a DownmixFunc which computes the mid signal of a sample frame,
+// (L+R)/2, which contains the audio common to the left and right channels.
+// Mono streams have no right channel, so their only channel is the mid
+// signal.
+func DownmixMid(frame []float64) float64 {
+	if len(frame) < 2 {
+		return frame[0]
+	}
+
+	return (frame[0] + frame[1]) / 2
+}
+
+// DownmixSide is a DownmixFunc which computes the side signal of a sample
+// frame, (L-R)/2, which contains the audio which differs between the left and
+// right channels, such as stereo width and reverb.  Mono streams have no side
+// signal, so silence is returned.
+func DownmixSide(frame []float64) float64 {
+	if len(frame) < 2 {
+		return 0
+	}
+
+	return (frame[0] - frame[1]) / 2
+}
@@ -0,0 +1,80 @@
+package waveform
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// TestDownmixFunction verifies that a DownmixFunc selects or combines the
+// channels of a stereo stream before values are computed.
+func TestDownmixFunction(t *testing.T) {
+	// One second of stereo audio at 8Hz, with a constant half scale signal in
+	// the left channel, and a quarter scale signal in the right channel
+	var pcm []int16
+	for i := 0; i < 8; i++ {
+		pcm = append(pcm, 16384, 8192)
+	}
+	wav := makeWAV(8, 2, pcm)
+
+	var tests = []struct {
+		name  string
+		fn    DownmixFunc
+		value float64
+	}{
+		{name: "left", fn: DownmixLeft, value: 0.5},
+		{name: "right", fn: DownmixRight, value: 0.25},
+		{name: "mid", fn: DownmixMid, value: 0.375},
+		{name: "side", fn: DownmixSide, value: 0.125},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(bytes.NewReader(wav), DownmixFunction(tt.fn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := w.Compute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(values) != 1 {
+				t.Fatalf("unexpected number of values: %d", len(values))
+			}
+			if math.Abs(values[0]-tt.value) > 1e-3 {
+				t.Fatalf("unexpected value: %v != %v", values[0], tt.value)
+			}
+		})
+	}
+}
+
+// TestDownmixFuncMono verifies that each DownmixFunc handles mono sample
+// frames.
+func TestDownmixFuncMono(t *testing.T) {
+	frame := []float64{0.5}
+
+	var tests = []struct {
+		fn   DownmixFunc
+		want float64
+	}{
+		{DownmixLeft, 0.5},
+		{DownmixRight, 0.5},
+		{DownmixMid, 0.5},
+		{DownmixSide, 0},
+	}
+
+	for i, tt := range tests {
+		if got := tt.fn(frame); got != tt.want {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, got, tt.want)
+		}
+	}
+}
+
+// TestDownmixFunctionOptions verifies that DownmixFunction rejects invalid
+// input.
+func TestDownmixFunctionOptions(t *testing.T) {
+	testWaveformOptionFunc(t, DownmixFunction(DownmixSide), nil)
+	testWaveformOptionFunc(t, DownmixFunction(nil), errDownmixFunctionNil)
+}
@@ -0,0 +1,93 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// errDrawIntoBounds is returned when DrawInto is called with a destination
+// image whose bounds do not match those of the waveform image.
+var errDrawIntoBounds = errors.New("waveform: destination image bounds do not match waveform image bounds")
+
+// DrawBounds returns the bounds of the image which Draw would create from a
+// slice of float64 values, using the receiving RenderStyle.  It is typically
+// used to allocate a destination image for DrawInto.
+func (s RenderStyle) DrawBounds(values []float64) image.Rectangle {
+	if s.pixelsPerSecond > 0 {
+		// The number of columns depends on the durations of values
+		_, values, _, err := s.layout(values, nil)
+		if err != nil {
+			return image.Rectangle{}
+		}
+
+		return image.Rect(0, 0, len(values), s.imageHeight())
+	}
+
+	return image.Rect(0, 0, len(values)*int(s.scaleX), s.imageHeight())
+}
+
+// DrawInto draws a waveform image from a slice of float64 values onto dst, in
+// the same way as DrawChecked, rather than creating a new image.  The bounds
+// of dst must be exactly those returned by DrawBounds; otherwise, an error is
+// returned.  Every pixel of dst is drawn, so it need not be cleared between
+// calls, and the ImageFormat of the receiving RenderStyle is ignored.
+//
+// DrawInto allows high-throughput applications, such as thumbnail farms, to
+// reuse a single image for many renders.  With the raster renderer, DrawInto
+// performs no heap allocations, provided that the values are valid, no
+// smoothing is applied, the PixelsPerSecond option is not in use, and the
+// ColorFuncs in use do not allocate.  The vector renderer always allocates.
+func (s RenderStyle) DrawInto(dst *image.RGBA, values []float64) error {
+	if s.pixelsPerSecond > 0 {
+		// Columns are positioned using the durations of values, which
+		// requires additional allocations
+		ls, values, edges, err := s.layout(values, nil)
+		if err != nil {
+			return err
+		}
+
+		return ls.drawInto(dst, values, edges)
+	}
+
+	values, err := s.sanitizeValues(values)
+	if err != nil {
+		return err
+	}
+
+	return s.drawInto(dst, s.smoothValues(values), nil)
+}
+
+// drawInto draws a slice of laid out values onto dst, using the renderer
+// selected by the receiving RenderStyle.
+func (s *RenderStyle) drawInto(dst *image.RGBA, values []float64, edges []int) error {
+	it := s.columnIterator(values, edges)
+	if dst.Bounds() != it.Bounds() {
+		return errDrawIntoBounds
+	}
+
+	if s.pixelSize > 1 {
+		img := s.renderPixelArt(values, edges)
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+		return nil
+	}
+
+	if s.vector {
+		s.drawVector(dst, values, edges)
+		return nil
+	}
+
+	s.drawRaster(dst, &it)
+	return nil
+}
+
+// DrawInto draws a waveform image from a slice of float64 values onto dst, in
+// the same way as RenderStyle.DrawInto, using the current options of the
+// receiving Waveform struct.
+func (w *Waveform) DrawInto(dst *image.RGBA, values []float64) error {
+	// Copy the style directly, rather than using Style, which allocates
+	s := w.style
+	s.resolution = w.resolution
+
+	return s.DrawInto(dst, values)
+}
@@ -0,0 +1,114 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestDrawInto verifies that DrawInto draws the same image as Draw.
+func TestDrawInto(t *testing.T) {
+	values := []float64{0.1, 0.5, 0.25, 0.8, 0, 0.3}
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:    "scaled",
+			options: []OptionsFunc{Scale(4, 2), Sharpness(1), Headroom(0.1)},
+		},
+		{
+			name:    "smooth",
+			options: []OptionsFunc{Smooth(3), MirrorGap(2)},
+		},
+		{
+			name:    "pixels per second",
+			options: []OptionsFunc{PixelsPerSecond(0.5)},
+		},
+		{
+			name:    "vector",
+			options: []OptionsFunc{VectorRenderer(), Scale(3, 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStyle(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := s.DrawChecked(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.DrawBounds(values); got != want.Bounds() {
+				t.Fatalf("unexpected bounds: %v != %v", got, want.Bounds())
+			}
+
+			// Fill the destination first, to verify that every pixel is drawn
+			dst := image.NewRGBA(s.DrawBounds(values))
+			for i := range dst.Pix {
+				dst.Pix[i] = 0x7f
+			}
+
+			if err := s.DrawInto(dst, values); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(dst.Pix, want.(*image.RGBA).Pix) {
+				t.Fatal("DrawInto image does not match Draw image")
+			}
+		})
+	}
+}
+
+// TestDrawIntoErrors verifies that DrawInto returns appropriate errors for an
+// incorrectly sized destination or invalid values.
+func TestDrawIntoErrors(t *testing.T) {
+	w, err := New(nil, InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 2, imgYDefault))
+	if err := w.DrawInto(dst, []float64{0.5}); err != errDrawIntoBounds {
+		t.Fatalf("unexpected error: %v != %v", err, errDrawIntoBounds)
+	}
+
+	err = w.DrawInto(dst, []float64{0.5, math.NaN()})
+	if _, ok := err.(*ValueError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestDrawIntoAllocs verifies that DrawInto performs no heap allocations with
+// the raster renderer.
+func TestDrawIntoAllocs(t *testing.T) {
+	w, err := New(nil, Scale(2, 1), FGColorFunction(SolidColor(color.Black)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make([]float64, 240)
+	for i := range values {
+		values[i] = float64(i%10) / 10
+	}
+
+	dst := image.NewRGBA(w.Style().DrawBounds(values))
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := w.DrawInto(dst, values); err != nil {
+			panic(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("unexpected allocations: %v", allocs)
+	}
+}
@@ -0,0 +1,166 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sort"
+)
+
+// errBitDepthInvalid is returned when an unsupported bit depth is used in
+// PNGOptions.
+var errBitDepthInvalid = errors.New("waveform: unsupported PNG bit depth")
+
+// PNGOptions specifies options which are used when encoding a waveform image
+// as PNG using EncodePNG.
+//
+// Waveform images typically contain only two or three colors, so quantizing them
+// to a small palette can produce files many times smaller than the default
+// RGBA encoding.
+type PNGOptions struct {
+	// CompressionLevel is the compression level used by the PNG encoder.
+	CompressionLevel png.CompressionLevel
+
+	// Palette indicates if an image should be quantized to a color palette
+	// before it is encoded.  If an image contains no more colors than the
+	// palette can hold, the palette is exact.  Otherwise, the most frequently
+	// used colors are selected and the image is dithered to fit.
+	Palette bool
+
+	// BitDepth is the number of bits used to encode each pixel's color.
+	//
+	// When Palette is true, valid values are 1, 2, 4, and 8, which limit the
+	// palette to 2, 4, 16, and 256 colors respectively.  Otherwise, valid values
+	// are 8 and 16 bits per channel.  If 0, 8 is used.
+	BitDepth int
+
+	// Text is metadata which is embedded in the image as PNG text chunks,
+	// keyed by PNG keyword, such as the metadata returned by RenderInfo.Text.
+	// Each key must be 1-79 printable ASCII characters.  Values which contain
+	// only ASCII characters are stored in tEXt chunks, and all others are
+	// stored in iTXt chunks.
+	Text map[string]string
+}
+
+// EncodePNG encodes an image as PNG to the input io.Writer, using the input
+// PNGOptions.  If options are nil, the image is encoded using the defaults of
+// the image/png package.
+func EncodePNG(w io.Writer, img image.Image, options *PNGOptions) error {
+	if options == nil {
+		options = &PNGOptions{}
+	}
+
+	depth := options.BitDepth
+	if depth == 0 {
+		depth = 8
+	}
+
+	// Convert image to the type which the PNG encoder uses to select the
+	// requested bit depth
+	switch {
+	case options.Palette:
+		if depth != 1 && depth != 2 && depth != 4 && depth != 8 {
+			return errBitDepthInvalid
+		}
+
+		img = quantize(img, 1<<uint(depth))
+	case depth == 16:
+		rgba := image.NewRGBA64(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = rgba
+	case depth != 8:
+		return errBitDepthInvalid
+	}
+
+	enc := &png.Encoder{
+		CompressionLevel: options.CompressionLevel,
+	}
+
+	if len(options.Text) == 0 {
+		return enc.Encode(w, img)
+	}
+
+	// Text chunks are inserted after encoding, because the PNG encoder
+	// cannot write them
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	b, err := addPNGText(buf.Bytes(), options.Text)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// quantize converts an image to an *image.Paletted, using at most maxColors
+// colors.  The PNG encoder automatically uses the smallest bit depth which can
+// hold the resulting palette.
+func quantize(img image.Image, maxColors int) *image.Paletted {
+	bounds := img.Bounds()
+
+	// Count the number of pixels which use each color
+	counts := make(map[color.RGBA64]int)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			counts[color.RGBA64Model.Convert(img.At(x, y)).(color.RGBA64)]++
+		}
+	}
+
+	// Select the most frequently used colors, ordered by frequency so that
+	// the palette is deterministic
+	colors := make([]color.RGBA64, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i int, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+
+		return rgba64Less(colors[i], colors[j])
+	})
+
+	exact := len(colors) <= maxColors
+	if !exact {
+		colors = colors[:maxColors]
+	}
+
+	palette := make(color.Palette, len(colors))
+	for i, c := range colors {
+		palette[i] = c
+	}
+
+	// Copy exact colors directly, or dither when some colors were discarded
+	out := image.NewPaletted(bounds, palette)
+	if exact {
+		draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+	} else {
+		draw.FloydSteinberg.Draw(out, bounds, img, bounds.Min)
+	}
+
+	return out
+}
+
+// rgba64Less reports whether color a sorts before color b, comparing each
+// channel in order.
+func rgba64Less(a color.RGBA64, b color.RGBA64) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	if a.B != b.B {
+		return a.B < b.B
+	}
+
+	return a.A < b.A
+}
@@ -0,0 +1,102 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// TestEncodePNGPalette verifies that EncodePNG quantizes a two color image
+// to an exact palette.
+func TestEncodePNGPalette(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(SolidColor(black)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw([]float64{0.10, 0.20, 0.30})
+
+	buf := bytes.NewBuffer(nil)
+	if err := EncodePNG(buf, img, &PNGOptions{Palette: true, BitDepth: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := out.(*image.Paletted)
+	if !ok {
+		t.Fatalf("unexpected image type: %T", out)
+	}
+	if len(p.Palette) != 2 {
+		t.Fatalf("unexpected palette length: %v != %v", len(p.Palette), 2)
+	}
+
+	// Every pixel must be preserved exactly
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			want := color.RGBA64Model.Convert(img.At(x, y))
+			if got := color.RGBA64Model.Convert(out.At(x, y)); got != want {
+				t.Fatalf("unexpected color at (%d,%d): %v != %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// TestEncodePNGPaletteDither verifies that EncodePNG limits the palette of an
+// image with more colors than the requested bit depth allows.
+func TestEncodePNGPaletteDither(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x, c := range []color.RGBA{black, white, red, green} {
+		img.Set(x, 0, c)
+	}
+
+	p := quantize(img, 2)
+	if len(p.Palette) != 2 {
+		t.Fatalf("unexpected palette length: %v != %v", len(p.Palette), 2)
+	}
+}
+
+// TestEncodePNGBitDepth16 verifies that EncodePNG encodes 16 bits per channel
+// when requested.
+func TestEncodePNGBitDepth16(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	if err := EncodePNG(buf, img, &PNGOptions{BitDepth: 16}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch out.(type) {
+	case *image.RGBA64, *image.NRGBA64:
+	default:
+		t.Fatalf("unexpected image type: %T", out)
+	}
+}
+
+// TestEncodePNGBitDepthInvalid verifies that EncodePNG does not accept an
+// unsupported bit depth.
+func TestEncodePNGBitDepthInvalid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+
+	var tests = []*PNGOptions{
+		{BitDepth: 3},
+		{BitDepth: 16, Palette: true},
+	}
+
+	for i, test := range tests {
+		if err := EncodePNG(bytes.NewBuffer(nil), img, test); err != errBitDepthInvalid {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, errBitDepthInvalid)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+)
+
+var (
+	// errWAVBitDepthInvalid is returned when an unsupported bit depth is used
+	// in WAVOptions.
+	errWAVBitDepthInvalid = errors.New("waveform: unsupported WAV bit depth")
+
+	// errWAVSampleRateInvalid is returned when a negative sample rate is used
+	// in WAVOptions.
+	errWAVSampleRateInvalid = errors.New("waveform: WAV sample rate cannot be negative")
+)
+
+// WAVOptions specifies options which are used when encoding computed values
+// as WAV audio using EncodeWAV.
+type WAVOptions struct {
+	// SampleRate is the sample rate of the WAV audio, which should be the
+	// resolution at which values were computed, so that the audio has the
+	// same duration as the audio stream from which they were computed.  If
+	// 0, 1 is used, which is the default resolution.
+	SampleRate int
+
+	// BitDepth is the number of bits used to encode each sample.  Valid
+	// values are 8, 16, 24, and 32.  If 0, 16 is used.
+	BitDepth int
+
+	// Dither indicates if triangular probability density function dither of
+	// one least significant bit is added to each sample before it is
+	// quantized.  Dither hides the steps between quantized values at low bit
+	// depths, at the cost of a small amount of noise.
+	Dither bool
+
+	// Seed is the seed of the random number generator used for dither, so
+	// that encoding is repeatable.
+	Seed int64
+
+	// Bipolar indicates if values are mapped to the full range of each
+	// sample, so that a value of 0 produces negative full scale and a value
+	// of 1 produces positive full scale, as expected by bipolar control
+	// voltage inputs.  Otherwise, values map to the positive half of the
+	// range only.
+	Bipolar bool
+}
+
+// EncodeWAV encodes a slice of computed values as mono, PCM WAV audio to the
+// input io.Writer, using the input WAVOptions.  If options are nil, the
+// defaults are used.
+//
+// Each value becomes a single sample, so the waveform's envelope is exported
+// as a low rate, audio-like signal, such as a control voltage for modular
+// synthesizers or an input for audio analysis tools.  Values are clamped to
+// the range [0, 1], and invalid values, such as NaN, are encoded as 0.
+func EncodeWAV(w io.Writer, values []float64, options *WAVOptions) error {
+	if options == nil {
+		options = &WAVOptions{}
+	}
+
+	rate := options.SampleRate
+	switch {
+	case rate < 0:
+		return errWAVSampleRateInvalid
+	case rate == 0:
+		rate = 1
+	}
+
+	depth := options.BitDepth
+	switch depth {
+	case 0:
+		depth = 16
+	case 8, 16, 24, 32:
+	default:
+		return errWAVBitDepthInvalid
+	}
+
+	bytesPerSample := depth / 8
+	dataSize := len(values) * bytesPerSample
+
+	header := make([]byte, 0, 44)
+	header = append(header, "RIFF"...)
+	header = appendUint32(header, uint32(36+dataSize))
+	header = append(header, "WAVEfmt "...)
+	header = appendUint32(header, 16)
+	header = appendUint16(header, 1)
+	header = appendUint16(header, 1)
+	header = appendUint32(header, uint32(rate))
+	header = appendUint32(header, uint32(rate*bytesPerSample))
+	header = appendUint16(header, uint16(bytesPerSample))
+	header = appendUint16(header, uint16(depth))
+	header = append(header, "data"...)
+	header = appendUint32(header, uint32(dataSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	var rng *rand.Rand
+	if options.Dither {
+		rng = rand.New(rand.NewSource(options.Seed))
+	}
+
+	// Scale each value to the largest positive sample of the bit depth
+	max := float64(int64(1)<<uint(depth-1) - 1)
+
+	data := make([]byte, 0, dataSize)
+	for _, v := range values {
+		if invalidValue(v) {
+			v = 0
+		}
+		v = math.Min(v, 1)
+
+		if options.Bipolar {
+			v = 2*v - 1
+		}
+
+		s := v * max
+		if rng != nil {
+			s += rng.Float64() - rng.Float64()
+		}
+		s = math.Max(math.Min(math.Round(s), max), -max-1)
+
+		data = appendSample(data, int32(s), depth)
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// appendSample appends a single PCM sample with the input bit depth to b.
+// 8-bit samples are unsigned, and all others are signed.
+func appendSample(b []byte, s int32, depth int) []byte {
+	switch depth {
+	case 8:
+		return append(b, byte(s+128))
+	case 16:
+		return appendUint16(b, uint16(s))
+	case 24:
+		return append(b, byte(s), byte(s>>8), byte(s>>16))
+	default:
+		return appendUint32(b, uint32(s))
+	}
+}
+
+// appendUint16 appends a little endian uint16 to b.
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appendUint32 appends a little endian uint32 to b.
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
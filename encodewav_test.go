@@ -0,0 +1,184 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// TestEncodeWAVHeader verifies that EncodeWAV writes a valid mono PCM WAV
+// header for each bit depth.
+func TestEncodeWAVHeader(t *testing.T) {
+	for _, depth := range []int{8, 16, 24, 32} {
+		var buf bytes.Buffer
+		err := EncodeWAV(&buf, []float64{0.5, 1}, &WAVOptions{
+			SampleRate: 4,
+			BitDepth:   depth,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := buf.Bytes()
+		size := 2 * depth / 8
+		if len(b) != 44+size {
+			t.Fatalf("[%d] unexpected length: %d", depth, len(b))
+		}
+		if string(b[0:4]) != "RIFF" || string(b[8:16]) != "WAVEfmt " || string(b[36:40]) != "data" {
+			t.Fatalf("[%d] unexpected chunk IDs: %q", depth, b[:40])
+		}
+
+		le := binary.LittleEndian
+		if got := le.Uint16(b[22:24]); got != 1 {
+			t.Fatalf("[%d] unexpected channels: %d", depth, got)
+		}
+		if got := le.Uint32(b[24:28]); got != 4 {
+			t.Fatalf("[%d] unexpected sample rate: %d", depth, got)
+		}
+		if got := le.Uint16(b[34:36]); int(got) != depth {
+			t.Fatalf("[%d] unexpected bit depth: %d", depth, got)
+		}
+		if got := le.Uint32(b[40:44]); int(got) != size {
+			t.Fatalf("[%d] unexpected data size: %d", depth, got)
+		}
+	}
+}
+
+// TestEncodeWAVSamples verifies that EncodeWAV quantizes values to samples
+// of each bit depth.
+func TestEncodeWAVSamples(t *testing.T) {
+	values := []float64{0, 0.5, 1, 2, math.NaN()}
+
+	var tests = []struct {
+		name    string
+		options *WAVOptions
+		data    []byte
+	}{
+		{
+			name: "8-bit",
+			options: &WAVOptions{
+				BitDepth: 8,
+			},
+			data: []byte{128, 192, 255, 255, 128},
+		},
+		{
+			name:    "16-bit",
+			options: nil,
+			data: []byte{
+				0x00, 0x00,
+				0x00, 0x40,
+				0xff, 0x7f,
+				0xff, 0x7f,
+				0x00, 0x00,
+			},
+		},
+		{
+			name: "24-bit bipolar",
+			options: &WAVOptions{
+				BitDepth: 24,
+				Bipolar:  true,
+			},
+			data: []byte{
+				0x01, 0x00, 0x80,
+				0x00, 0x00, 0x00,
+				0xff, 0xff, 0x7f,
+				0xff, 0xff, 0x7f,
+				0x01, 0x00, 0x80,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWAV(&buf, values, tt.options); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.Bytes()[44:]; !bytes.Equal(got, tt.data) {
+				t.Fatalf("unexpected data:\n- want: %v\n-  got: %v", tt.data, got)
+			}
+		})
+	}
+}
+
+// TestEncodeWAVDither verifies that dither changes each sample by at most one
+// least significant bit, and is repeatable.
+func TestEncodeWAVDither(t *testing.T) {
+	values := make([]float64, 256)
+	for i := range values {
+		values[i] = float64(i) / 256
+	}
+
+	encode := func(options *WAVOptions) []byte {
+		var buf bytes.Buffer
+		if err := EncodeWAV(&buf, values, options); err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()[44:]
+	}
+
+	plain := encode(&WAVOptions{BitDepth: 8})
+	dither := encode(&WAVOptions{BitDepth: 8, Dither: true, Seed: 1})
+
+	var changed int
+	for i := range plain {
+		d := int(dither[i]) - int(plain[i])
+		if d < -1 || d > 1 {
+			t.Fatalf("unexpected dither at %d: %d", i, d)
+		}
+		if d != 0 {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Fatal("dither did not change any samples")
+	}
+
+	if !bytes.Equal(dither, encode(&WAVOptions{BitDepth: 8, Dither: true, Seed: 1})) {
+		t.Fatal("dither is not repeatable with the same seed")
+	}
+}
+
+// TestEncodeWAVCompute verifies that values exported by EncodeWAV can be
+// computed again.
+func TestEncodeWAVCompute(t *testing.T) {
+	values := []float64{0.25, 0.5, 0.75, 1}
+
+	var buf bytes.Buffer
+	if err := EncodeWAV(&buf, values, &WAVOptions{SampleRate: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(&buf, Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(values) {
+		t.Fatalf("unexpected number of values: %d", len(got))
+	}
+	for i := range got {
+		if math.Abs(got[i]-values[i]) > 1e-4 {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, got[i], values[i])
+		}
+	}
+}
+
+// TestEncodeWAVErrors verifies that EncodeWAV rejects invalid options.
+func TestEncodeWAVErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWAV(&buf, nil, &WAVOptions{BitDepth: 12}); err != errWAVBitDepthInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errWAVBitDepthInvalid)
+	}
+	if err := EncodeWAV(&buf, nil, &WAVOptions{SampleRate: -1}); err != errWAVSampleRateInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errWAVSampleRateInvalid)
+	}
+}
@@ -0,0 +1,242 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"azul3d.org/engine/audio"
+)
+
+// ExternalDecoder describes an external command which decodes audio in formats
+// which are not supported by this package, such as ffmpeg.  An ExternalDecoder
+// is used by the FallbackDecoder option.
+//
+// The command reads the complete audio stream from its standard input, and must
+// write raw, interleaved, signed 16-bit little-endian PCM audio samples to its
+// standard output, at the sample rate and number of channels described by the
+// ExternalDecoder.
+type ExternalDecoder struct {
+	// Command is the name or path of the command, and Args are its arguments.
+	Command string
+	Args    []string
+
+	// SampleRate and Channels describe the PCM audio written by the command.
+	SampleRate int
+	Channels   int
+}
+
+// FFmpeg returns an ExternalDecoder which uses the ffmpeg command to decode any
+// format it supports, resampling and mixing audio to the input sample rate and
+// number of channels.  ffmpeg must be installed and available in PATH.
+func FFmpeg(sampleRate int, channels int) *ExternalDecoder {
+	return &ExternalDecoder{
+		Command: "ffmpeg",
+		Args: []string{
+			"-hide_banner",
+			"-loglevel", "error",
+			"-i", "pipe:0",
+			"-vn",
+			"-f", "s16le",
+			"-acodec", "pcm_s16le",
+			"-ar", strconv.Itoa(sampleRate),
+			"-ac", strconv.Itoa(channels),
+			"pipe:1",
+		},
+		SampleRate: sampleRate,
+		Channels:   channels,
+	}
+}
+
+// ExternalDecoderError is an error which is returned when the command of an
+// ExternalDecoder cannot be started, or exits unsuccessfully.
+type ExternalDecoderError struct {
+	// Command is the command which failed.
+	Command string
+
+	// Err is the error which occurred while running the command, and Stderr
+	// is any output the command wrote to its standard error.
+	Err    error
+	Stderr string
+}
+
+// Error returns the string representation of an ExternalDecoderError.
+func (e *ExternalDecoderError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("waveform: external decoder %q: %v", e.Command, e.Err)
+	}
+
+	return fmt.Sprintf("waveform: external decoder %q: %v: %s", e.Command, e.Err, e.Stderr)
+}
+
+// Unwrap returns the underlying error of an ExternalDecoderError, for use with
+// errors.Is and errors.As.
+func (e *ExternalDecoderError) Unwrap() error {
+	return e.Err
+}
+
+// start starts the command of an ExternalDecoder, using r as its standard
+// input, and returns an audio.Decoder which reads its standard output.
+func (e *ExternalDecoder) start(r io.Reader) (*externalDecoder, error) {
+	d := &externalDecoder{
+		cmd: exec.Command(e.Command, e.Args...),
+		config: audio.Config{
+			SampleRate: e.SampleRate,
+			Channels:   e.Channels,
+		},
+	}
+
+	d.cmd.Stdin = r
+
+	stdout, err := d.cmd.StdoutPipe()
+	if err != nil {
+		return nil, d.error(err)
+	}
+	stderr, err := d.cmd.StderrPipe()
+	if err != nil {
+		return nil, d.error(err)
+	}
+	if err := d.cmd.Start(); err != nil {
+		return nil, d.error(err)
+	}
+
+	// Collect standard error separately, so that a command which is stopped
+	// early can be waited for, even if a process it started holds standard
+	// error open
+	d.stderrDone = make(chan struct{})
+	go func() {
+		defer close(d.stderrDone)
+		_, _ = io.Copy(&d.stderr, stderr)
+	}()
+
+	d.r = bufio.NewReader(stdout)
+	return d, nil
+}
+
+// externalDecoder is an audio.Decoder which reads PCM audio samples from the
+// standard output of a running ExternalDecoder command.
+type externalDecoder struct {
+	cmd    *exec.Cmd
+	r      *bufio.Reader
+	config audio.Config
+
+	stderr     bytes.Buffer
+	stderrDone chan struct{}
+
+	buf  []byte
+	done bool
+}
+
+// Config returns the audio configuration of the ExternalDecoder.
+func (d *externalDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read reads audio samples from the command into b.  Once the command closes
+// its standard output, Read waits for it to exit, and returns an error if it
+// did not exit successfully.
+func (d *externalDecoder) Read(b audio.Slice) (int, error) {
+	if d.done {
+		return 0, audio.EOS
+	}
+
+	size := b.Len() * 2
+	if cap(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+	buf := d.buf[:size]
+
+	// Decode any complete samples, even if the output ends early
+	read, err := io.ReadFull(d.r, buf)
+	n := read / 2
+	for i := 0; i < n; i++ {
+		v := int16(uint16(buf[2*i]) | uint16(buf[2*i+1])<<8)
+		b.Set(i, float64(v)/(1<<15))
+	}
+
+	switch err {
+	case nil:
+		return n, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		d.done = true
+		<-d.stderrDone
+		if err := d.cmd.Wait(); err != nil {
+			return n, d.error(err)
+		}
+		if n == 0 {
+			return 0, audio.EOS
+		}
+
+		return n, nil
+	default:
+		return n, err
+	}
+}
+
+// Close stops the command if it is still running, such as when computation
+// stops before the end of the stream.
+func (d *externalDecoder) Close() error {
+	if d.done {
+		return nil
+	}
+
+	d.done = true
+	_ = d.cmd.Process.Kill()
+	_ = d.cmd.Wait()
+
+	return nil
+}
+
+// error wraps an error which occurred while running the command in an
+// *ExternalDecoderError.
+func (d *externalDecoder) error(err error) error {
+	return &ExternalDecoderError{
+		Command: d.cmd.Path,
+		Err:     err,
+		Stderr:  strings.TrimSpace(d.stderr.String()),
+	}
+}
+
+// rewindReader is an io.Reader which records the bytes read from an underlying
+// io.Reader while a format is detected, so that they can be replayed to an
+// ExternalDecoder if the format is not supported by this package.
+type rewindReader struct {
+	r      io.Reader
+	buf    bytes.Buffer
+	record bool
+}
+
+// newRewindReader creates a rewindReader which records bytes read from r.
+func newRewindReader(r io.Reader) *rewindReader {
+	return &rewindReader{
+		r:      r,
+		record: true,
+	}
+}
+
+// Read reads bytes from the underlying io.Reader, recording them if needed.
+func (r *rewindReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if r.record {
+		r.buf.Write(b[:n])
+	}
+
+	return n, err
+}
+
+// stop stops recording bytes, once a format has been detected.
+func (r *rewindReader) stop() {
+	r.record = false
+	r.buf = bytes.Buffer{}
+}
+
+// rewind stops recording bytes, and returns an io.Reader which replays all
+// recorded bytes, followed by the remainder of the underlying io.Reader.
+func (r *rewindReader) rewind() io.Reader {
+	r.record = false
+	return io.MultiReader(&r.buf, r.r)
+}
@@ -0,0 +1,174 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestFallbackDecoderOK verifies that a stream in an unknown format is decoded
+// by the command of a FallbackDecoder, including the bytes read while
+// detecting its format.
+func TestFallbackDecoderOK(t *testing.T) {
+	requireCommand(t, "cat")
+
+	// cat passes raw PCM through unmodified
+	w, err := New(bytes.NewReader(makePCM(-16384, 16384, 8)), FallbackDecoder(&ExternalDecoder{
+		Command:    "cat",
+		SampleRate: 4,
+		Channels:   1,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatalf("unexpected Compute error: %v", err)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("unexpected number of values: %d != %d", len(values), 2)
+	}
+	for i, v := range values {
+		if v != 0.5 {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, v, 0.5)
+		}
+	}
+}
+
+// TestFallbackDecoderKnownFormat verifies that streams in formats supported by
+// this package are never decoded by the command of a FallbackDecoder.
+func TestFallbackDecoderKnownFormat(t *testing.T) {
+	w, err := New(bytes.NewReader(makeWAV(4, 1, []int16{1, 2, 3, 4})), FallbackDecoder(&ExternalDecoder{
+		Command:    "waveform-command-does-not-exist",
+		SampleRate: 4,
+		Channels:   1,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatalf("unexpected Compute error: %v", err)
+	}
+}
+
+// TestFallbackDecoderCommandFails verifies that an *ExternalDecoderError which
+// includes the standard error of the command is returned when the command of
+// a FallbackDecoder exits unsuccessfully.
+func TestFallbackDecoderCommandFails(t *testing.T) {
+	requireCommand(t, "sh")
+
+	w, err := New(bytes.NewReader(makePCM(0, 0, 8)), FallbackDecoder(&ExternalDecoder{
+		Command:    "sh",
+		Args:       []string{"-c", "echo invalid data >&2; exit 1"},
+		SampleRate: 4,
+		Channels:   1,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Compute()
+
+	var eerr *ExternalDecoderError
+	if !errors.As(err, &eerr) {
+		t.Fatalf("unexpected error type: %T: %v", err, err)
+	}
+	if eerr.Stderr != "invalid data" {
+		t.Fatalf("unexpected standard error: %q", eerr.Stderr)
+	}
+
+	var xerr *exec.ExitError
+	if !errors.As(err, &xerr) {
+		t.Fatalf("error does not wrap *exec.ExitError: %v", err)
+	}
+}
+
+// TestFallbackDecoderCommandNotFound verifies that an error is returned when
+// the command of a FallbackDecoder does not exist.
+func TestFallbackDecoderCommandNotFound(t *testing.T) {
+	w, err := New(bytes.NewReader(makePCM(0, 0, 8)), FallbackDecoder(&ExternalDecoder{
+		Command:    "waveform-command-does-not-exist",
+		SampleRate: 4,
+		Channels:   1,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Compute()
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "waveform-command-does-not-exist") {
+		t.Fatalf("error does not name command: %v", err)
+	}
+}
+
+// TestFallbackDecoderStopped verifies that the command of a FallbackDecoder
+// is stopped when computation ends before the end of its output.
+func TestFallbackDecoderStopped(t *testing.T) {
+	requireCommand(t, "sh")
+
+	errStop := errors.New("stop")
+
+	// The command never stops writing unless it is stopped
+	w, err := New(bytes.NewReader(makePCM(0, 0, 8)), FallbackDecoder(&ExternalDecoder{
+		Command:    "sh",
+		Args:       []string{"-c", "cat /dev/zero"},
+		SampleRate: 4,
+		Channels:   1,
+	}), Analyzers(AnalyzerFunc(func(_ *Chunk) error {
+		return errStop
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != errStop {
+		t.Fatalf("unexpected error: %v != %v", err, errStop)
+	}
+}
+
+// TestFFmpeg verifies that FFmpeg describes output which matches its arguments.
+func TestFFmpeg(t *testing.T) {
+	d := FFmpeg(22050, 1)
+	if d.SampleRate != 22050 || d.Channels != 1 {
+		t.Fatalf("unexpected configuration: %d Hz, %d channels", d.SampleRate, d.Channels)
+	}
+
+	args := strings.Join(d.Args, " ")
+	for _, want := range []string{"-f s16le", "-ar 22050", "-ac 1", "pipe:0", "pipe:1"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("arguments %q do not contain %q", args, want)
+		}
+	}
+}
+
+// requireCommand skips a test if the named command is not available.
+func requireCommand(t *testing.T, name string) {
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("skipping, command %q not available", name)
+	}
+}
+
+// makePCM creates raw signed 16-bit little-endian PCM audio, alternating
+// between two sample values.
+func makePCM(a int16, b int16, n int) []byte {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < n; i++ {
+		v := a
+		if i%2 == 1 {
+			v = b
+		}
+
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,37 @@
+package waveform
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ImageFormat is a format which determines the type of image produced when a
+// waveform image is drawn.
+type ImageFormat int
+
+const (
+	// FormatRGBA produces an *image.RGBA, with 8 bits per channel.  This is
+	// the default image format of the waveform package.
+	FormatRGBA ImageFormat = iota
+
+	// FormatRGBA64 produces an *image.RGBA64, with 16 bits per channel and
+	// premultiplied alpha.
+	FormatRGBA64
+
+	// FormatNRGBA64 produces an *image.NRGBA64, with 16 bits per channel and
+	// non-premultiplied alpha.
+	FormatNRGBA64
+)
+
+// newImage creates an empty image with the input bounds, using the
+// ImageFormat of the receiving RenderStyle struct.
+func (s *RenderStyle) newImage(r image.Rectangle) draw.Image {
+	switch s.format {
+	case FormatRGBA64:
+		return image.NewRGBA64(r)
+	case FormatNRGBA64:
+		return image.NewNRGBA64(r)
+	default:
+		return image.NewRGBA(r)
+	}
+}
@@ -0,0 +1,60 @@
+package waveform
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestOutputFormatImageType verifies that each ImageFormat produces the
+// expected type of image, using both the raster and vector renderers.
+func TestOutputFormatImageType(t *testing.T) {
+	var tests = []struct {
+		format ImageFormat
+		img    image.Image
+	}{
+		{format: FormatRGBA, img: &image.RGBA{}},
+		{format: FormatRGBA64, img: &image.RGBA64{}},
+		{format: FormatNRGBA64, img: &image.NRGBA64{}},
+	}
+
+	for _, tt := range tests {
+		for _, vector := range []bool{false, true} {
+			options := []OptionsFunc{OutputFormat(tt.format)}
+			if vector {
+				options = append(options, VectorRenderer())
+			}
+
+			s, err := NewStyle(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img := s.Draw([]float64{0.10, 0.30, 0.20})
+			if want, got := fmt.Sprintf("%T", tt.img), fmt.Sprintf("%T", img); want != got {
+				t.Fatalf("unexpected image type for format %d, vector %v: %v != %v",
+					tt.format, vector, want, got)
+			}
+		}
+	}
+}
+
+// TestOutputFormatPrecision verifies that 16-bit image formats preserve the
+// full precision of colors returned by a ColorFunc.
+func TestOutputFormatPrecision(t *testing.T) {
+	fg := color.RGBA64{R: 0x1234, G: 0x5678, B: 0x9abc, A: 0xffff}
+
+	s, err := NewStyle(
+		OutputFormat(FormatRGBA64),
+		FGColorFunction(SolidColor(fg)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := s.Draw([]float64{0.50}).(*image.RGBA64)
+	if got := img.RGBA64At(0, imgYDefault/2); got != fg {
+		t.Fatalf("unexpected foreground color: %v != %v", got, fg)
+	}
+}
@@ -0,0 +1,214 @@
+package waveform
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// errFramesFPSZero is returned when an FPS of 0 is used in FrameOptions.
+	errFramesFPSZero = errors.New("waveform: frames per second cannot be 0")
+
+	// errFramesPlayedNil is returned when a nil played ColorFunc is used in
+	// FrameOptions.
+	errFramesPlayedNil = errors.New("waveform: played color function cannot be nil")
+
+	// errFrameFuncNil is returned when a nil FrameFunc is used to receive
+	// rendered frames.
+	errFrameFuncNil = errors.New("waveform: frame function cannot be nil")
+)
+
+// FrameFunc is a function which receives each frame rendered by a frame
+// renderer, such as Waveform.DrawFrames, in order.  n is the index of the
+// frame, beginning at 0.  Each frame is a newly allocated image, which may be
+// retained, for example by sending it on a channel.
+//
+// If a FrameFunc returns an error, rendering stops and the error is returned.
+type FrameFunc func(n int, img image.Image) error
+
+// FrameOptions specifies options which are used when rendering video frames
+// using Waveform.DrawFrames or Waveform.DrawCaptionFrames.
+type FrameOptions struct {
+	// FPS is the number of frames rendered for each second of audio.
+	FPS uint
+
+	// Duration is the duration of the audio which was used to compute values.
+	// If 0, it is estimated from the number of values and the resolution of
+	// the receiving Waveform struct.  Using the sum of ValueSet.Durations is
+	// more accurate, as the final window of a stream is typically partial.
+	Duration time.Duration
+
+	// Played is the ColorFunc used to draw the portion of the waveform which
+	// has already been played, or which is highlighted by an active caption.
+	Played ColorFunc
+
+	// Playhead is the color of a vertical line drawn at the current playback
+	// position.  If nil, no playhead is drawn.
+	Playhead color.Color
+}
+
+// DrawFrames renders a sequence of video frames from a slice of float64 values,
+// showing the progress of playback sweeping across the waveform.  Each frame is
+// passed to the input FrameFunc.
+//
+// In each frame, the portion of the waveform before the playback position is
+// drawn with the played color function, and the remainder is drawn with the
+// receiving Waveform's foreground color function.  The resulting frames can
+// be written as numbered images using PNGFrames, and muxed with the audio using
+// a tool such as ffmpeg to produce an "audiogram" video.
+func (w *Waveform) DrawFrames(values []float64, options *FrameOptions, fn FrameFunc) error {
+	return w.drawFrames(values, options, fn, func(t time.Duration, toX func(time.Duration) int) (int, int) {
+		return 0, toX(t)
+	})
+}
+
+// DrawCaptionFrames renders a sequence of video frames from a slice of float64
+// values, highlighting the region of the waveform which corresponds to the
+// currently active caption, such as a word or line of a subtitle track.  Each
+// frame is passed to the input FrameFunc.
+//
+// In each frame, the Range of the caption which contains the current playback
+// position is drawn with the played color function, and the remainder is drawn
+// with the receiving Waveform's foreground color function.  If captions overlap,
+// the first matching caption is highlighted.  If no caption is active, no region
+// is highlighted.
+func (w *Waveform) DrawCaptionFrames(values []float64, captions []Range, options *FrameOptions, fn FrameFunc) error {
+	return w.drawFrames(values, options, fn, func(t time.Duration, toX func(time.Duration) int) (int, int) {
+		for _, c := range captions {
+			if t >= c.Start && t < c.End {
+				return toX(c.Start), toX(c.End)
+			}
+		}
+
+		return 0, 0
+	})
+}
+
+// drawFrames renders a sequence of video frames from a slice of float64 values.
+// For each frame, highlight is called with the time offset of the playback
+// position and a function which converts a time offset to an X coordinate, and
+// returns the range of X coordinates which should be drawn with the played color
+// function.
+func (w *Waveform) drawFrames(values []float64, options *FrameOptions, fn FrameFunc, highlight func(t time.Duration, toX func(time.Duration) int) (int, int)) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	if fn == nil {
+		return errFrameFuncNil
+	}
+
+	// Draw played and unplayed variants once, and composite them per frame
+	us := w.Style()
+	ps := us
+	ps.fgColorFn = options.Played
+
+	unplayed, err := us.DrawChecked(values)
+	if err != nil {
+		return err
+	}
+	played, err := ps.DrawChecked(values)
+	if err != nil {
+		return err
+	}
+
+	duration := options.duration(len(values), w.resolution)
+	width := unplayed.Bounds().Dx()
+
+	toX := func(t time.Duration) int {
+		return int(int64(width) * int64(t) / int64(duration))
+	}
+
+	return renderFrames(duration, options.FPS, fn, func(t time.Duration) image.Image {
+		x := toX(t)
+
+		frame := image.NewRGBA(unplayed.Bounds())
+		draw.Draw(frame, frame.Bounds(), unplayed, image.Point{}, draw.Src)
+
+		x0, x1 := highlight(t, toX)
+		r := image.Rect(x0, 0, x1, frame.Bounds().Max.Y)
+		draw.Draw(frame, r, played, r.Min, draw.Src)
+
+		if options.Playhead != nil && x < width {
+			drawPlayhead(frame, x, options.Playhead)
+		}
+
+		return frame
+	})
+}
+
+// PNGFrames generates a FrameFunc which encodes each frame as a PNG file in the
+// input directory.  Each file is named using the input pattern and the index
+// of the frame, such as "frame-%06d.png", which is also the pattern expected
+// by ffmpeg's image sequence input.
+func PNGFrames(dir string, pattern string, options *PNGOptions) FrameFunc {
+	return func(n int, img image.Image) error {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf(pattern, n)))
+		if err != nil {
+			return err
+		}
+
+		if err := EncodePNG(f, img, options); err != nil {
+			_ = f.Close()
+			return err
+		}
+
+		return f.Close()
+	}
+}
+
+// validate verifies that FrameOptions are valid for rendering.
+func (o *FrameOptions) validate() error {
+	if o == nil || o.FPS == 0 {
+		return errFramesFPSZero
+	}
+	if o.Played == nil {
+		return errFramesPlayedNil
+	}
+
+	return nil
+}
+
+// duration returns the duration of the audio used to compute n values at the
+// input resolution, estimating it if no duration was specified.
+func (o *FrameOptions) duration(n int, resolution uint) time.Duration {
+	if o.Duration > 0 || resolution == 0 {
+		return o.Duration
+	}
+
+	return time.Duration(int64(n) * int64(time.Second) / int64(resolution))
+}
+
+// renderFrames calls render for the time offset of each frame in a video of the
+// input duration and frame rate, passing each resulting image to fn.
+func renderFrames(duration time.Duration, fps uint, fn FrameFunc, render func(t time.Duration) image.Image) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	// Render enough frames to cover the entire duration
+	interval := int64(time.Second) / int64(fps)
+	frames := int((int64(duration) + interval - 1) / interval)
+
+	for n := 0; n < frames; n++ {
+		t := time.Duration(int64(n) * int64(time.Second) / int64(fps))
+		if err := fn(n, render(t)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drawPlayhead draws a vertical line at the input X coordinate of an image.
+func drawPlayhead(img draw.Image, x int, c color.Color) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		img.Set(x, y, c)
+	}
+}
@@ -0,0 +1,188 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWaveformDrawFramesErrors verifies that Waveform.DrawFrames rejects
+// invalid input.
+func TestWaveformDrawFramesErrors(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(n int, img image.Image) error { return nil }
+	played := SolidColor(color.White)
+
+	var tests = []struct {
+		options *FrameOptions
+		fn      FrameFunc
+		err     error
+	}{
+		{nil, fn, errFramesFPSZero},
+		{&FrameOptions{Played: played}, fn, errFramesFPSZero},
+		{&FrameOptions{FPS: 30}, fn, errFramesPlayedNil},
+		{&FrameOptions{FPS: 30, Played: played}, nil, errFrameFuncNil},
+	}
+
+	for i, test := range tests {
+		if err := w.DrawFrames([]float64{0.5}, test.options, test.fn); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// TestWaveformDrawFramesSweep verifies that Waveform.DrawFrames renders the
+// expected number of frames, with the played region sweeping across them.
+func TestWaveformDrawFramesSweep(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	w, err := New(nil, FGColorFunction(SolidColor(blue)), Scale(10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 values at 1 per second, rendered at 2 frames per second
+	var frames []image.Image
+	err = w.DrawFrames([]float64{0.1, 0.1, 0.1, 0.1}, &FrameOptions{
+		FPS:    2,
+		Played: SolidColor(red),
+	}, func(n int, img image.Image) error {
+		if n != len(frames) {
+			t.Fatalf("unexpected frame index: %v != %v", n, len(frames))
+		}
+
+		frames = append(frames, img)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) != 8 {
+		t.Fatalf("unexpected number of frames: %v != %v", len(frames), 8)
+	}
+
+	// Each frame advances the played region by 5 pixels
+	y := imgYDefault / 2
+	for i, img := range frames {
+		x := i * 5
+		if x > 0 {
+			if !colorsEqual(img.At(x-1, y), red) {
+				t.Fatalf("[%02d] expected played color at x=%d: %v", i, x-1, img.At(x-1, y))
+			}
+		}
+		if !colorsEqual(img.At(x, y), blue) {
+			t.Fatalf("[%02d] expected unplayed color at x=%d: %v", i, x, img.At(x, y))
+		}
+	}
+}
+
+// TestWaveformDrawFramesPlayhead verifies that Waveform.DrawFrames draws a
+// playhead at the current playback position.
+func TestWaveformDrawFramesPlayhead(t *testing.T) {
+	green := color.RGBA{0, 255, 0, 255}
+
+	w, err := New(nil, Scale(10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last image.Image
+	err = w.DrawFrames([]float64{0.1, 0.1}, &FrameOptions{
+		FPS:      1,
+		Duration: 2 * time.Second,
+		Played:   SolidColor(color.White),
+		Playhead: green,
+	}, func(n int, img image.Image) error {
+		last = img
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Second frame is at one second, halfway across the image
+	if !colorsEqual(last.At(10, 0), green) {
+		t.Fatalf("expected playhead at x=10: %v", last.At(10, 0))
+	}
+}
+
+// TestWaveformDrawCaptionFrames verifies that Waveform.DrawCaptionFrames
+// highlights only the region of the currently active caption.
+func TestWaveformDrawCaptionFrames(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	w, err := New(nil, FGColorFunction(SolidColor(blue)), Scale(10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 values at 1 per second, rendered at 1 frame per second, with a
+	// caption active during the middle two seconds
+	captions := []Range{{Start: time.Second, End: 3 * time.Second}}
+
+	var frames []image.Image
+	err = w.DrawCaptionFrames([]float64{0.1, 0.1, 0.1, 0.1}, captions, &FrameOptions{
+		FPS:    1,
+		Played: SolidColor(red),
+	}, func(n int, img image.Image) error {
+		frames = append(frames, img)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) != 4 {
+		t.Fatalf("unexpected number of frames: %v != %v", len(frames), 4)
+	}
+
+	y := imgYDefault / 2
+	for i, img := range frames {
+		active := i == 1 || i == 2
+
+		for x := 0; x < 40; x++ {
+			want := color.Color(blue)
+			if active && x >= 10 && x < 30 {
+				want = red
+			}
+
+			if !colorsEqual(img.At(x, y), want) {
+				t.Fatalf("[%02d] unexpected color at x=%d: %v != %v", i, x, img.At(x, y), want)
+			}
+		}
+	}
+}
+
+// TestPNGFrames verifies that PNGFrames writes numbered PNG files.
+func TestPNGFrames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "waveform-frames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := PNGFrames(dir, "frame-%03d.png", nil)
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	for i := 0; i < 2; i++ {
+		if err := fn(i, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"frame-000.png", "frame-001.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
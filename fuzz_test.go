@@ -0,0 +1,61 @@
+//go:build go1.18
+// +build go1.18
+
+package waveform
+
+import (
+	"bytes"
+	"testing"
+)
+
+// addFuzzSeeds adds the test fixtures, and several small synthetic streams,
+// to the seed corpus of a fuzz target.
+func addFuzzSeeds(f *testing.F) {
+	for _, b := range [][]byte{
+		wavFile,
+		flacFile,
+		mp3File,
+		oggVorbisFile,
+		makeWAV(8000, 1, []int16{0, 100, -100, 32767, -32768}),
+		makeWAV(8000, 2, nil),
+		makeALAC(16, 8000, 16, [][]int32{{0, 100, -100, 32767, -32768}}, alacTestEncoder{order: 4, quant: 9}, false),
+		makeWavPack(8000, 2, [][]int32{{0, 100, -100, 32767, -32768}}, 4, wavpackTestEncoder{
+			terms: []wavpackTestTerm{{term: 17, delta: 2, weightA: 64}},
+		}),
+		{'f', 'L', 'a', 'C'},
+		{'O', 'g', 'g', 'S'},
+	} {
+		f.Add(b)
+	}
+}
+
+// FuzzCompute verifies that Waveform.Compute never panics or hangs on arbitrary
+// input, and always returns either values or an error.
+func FuzzCompute(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		w, err := New(bytes.NewReader(b), Resolution(10))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err == nil && len(values) == 0 {
+			t.Fatal("no values and no error")
+		}
+	})
+}
+
+// FuzzGenerate verifies that Generate never panics or hangs on arbitrary input,
+// and always returns either an image or an error.
+func FuzzGenerate(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		img, err := Generate(bytes.NewReader(b), Resolution(10))
+		if err == nil && img == nil {
+			t.Fatal("no image and no error")
+		}
+	})
+}
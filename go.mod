@@ -4,5 +4,8 @@ go 1.14
 
 require (
 	azul3d.org/engine v0.0.0-20180624221640-25c8eab2d474
+	github.com/jfreymuth/oggvorbis v1.0.5
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	github.com/mewkiz/flac v1.0.6 // indirect
+	golang.org/x/image v0.0.0-20200119044424-58c23975cae1
 )
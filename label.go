@@ -0,0 +1,292 @@
+package waveform
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultLabelSize is the height of text, in pixels, which is drawn using a
+// LabelStyle whose Size is 0.  It is the height of the embedded font, so text
+// of this size is drawn without scaling.
+const DefaultLabelSize = 13
+
+// LabelAnchor is the point of an image to which a label is anchored.
+type LabelAnchor int
+
+// Possible LabelAnchor values.
+const (
+	// AnchorTopLeft places a label in the top left corner of an image.  This
+	// is the default anchor.
+	AnchorTopLeft LabelAnchor = iota
+
+	// AnchorTop places a label at the center of the top edge of an image.
+	AnchorTop
+
+	// AnchorTopRight places a label in the top right corner of an image.
+	AnchorTopRight
+
+	// AnchorLeft places a label at the center of the left edge of an image.
+	AnchorLeft
+
+	// AnchorCenter places a label at the center of an image.
+	AnchorCenter
+
+	// AnchorRight places a label at the center of the right edge of an image.
+	AnchorRight
+
+	// AnchorBottomLeft places a label in the bottom left corner of an image.
+	AnchorBottomLeft
+
+	// AnchorBottom places a label at the center of the bottom edge of an
+	// image.
+	AnchorBottom
+
+	// AnchorBottomRight places a label in the bottom right corner of an
+	// image.
+	AnchorBottomRight
+)
+
+// LabelStyle determines how text is drawn by LabelLayer and RulerLayer.
+//
+// Text is drawn using a small fixed-width font which is embedded in this
+// package, so labels can be drawn without loading any font files.  The zero
+// value of LabelStyle draws black text of DefaultLabelSize in the top left
+// corner of an image.
+type LabelStyle struct {
+	// Size is the height of text, in pixels.  The embedded font is scaled to
+	// this height; sizes which are a multiple of DefaultLabelSize produce the
+	// sharpest text.  If 0, DefaultLabelSize is used.
+	Size int
+
+	// Color is the color of text.  If nil, black is used.
+	Color color.Color
+
+	// Background, if not nil, is the color of a box drawn behind text, so
+	// that it remains legible over a waveform.
+	Background color.Color
+
+	// Padding is the number of pixels between text and the edges of its
+	// background box.
+	Padding int
+
+	// Anchor is the point of the image to which the label is anchored, and
+	// Margin is the number of pixels between the label, including its
+	// background box, and the edges of the image at its anchor.
+	Anchor LabelAnchor
+	Margin int
+
+	// Offset moves the label from its anchored position.
+	Offset image.Point
+}
+
+// size returns the height of text drawn using the LabelStyle.
+func (s LabelStyle) size() int {
+	if s.Size <= 0 {
+		return DefaultLabelSize
+	}
+
+	return s.Size
+}
+
+// Measure returns the width and height, in pixels, of the input text when it
+// is drawn using the LabelStyle, including its padding.
+func (s LabelStyle) Measure(text string) image.Point {
+	face := basicfont.Face7x13
+	w := font.MeasureString(face, text).Ceil()
+	h := face.Metrics().Height.Ceil()
+
+	size := s.size()
+	return image.Pt(w*size/h+2*s.Padding, size+2*s.Padding)
+}
+
+// Place returns the bounds of the input text, including its padding, when it
+// is drawn using the LabelStyle within the input bounds.
+func (s LabelStyle) Place(text string, bounds image.Rectangle) image.Rectangle {
+	size := s.Measure(text)
+
+	// Determine the column and row of the anchor in a 3x3 grid
+	col, row := int(s.Anchor)%3, int(s.Anchor)/3
+	if s.Anchor < AnchorTopLeft || s.Anchor > AnchorBottomRight {
+		col, row = 0, 0
+	}
+
+	var at image.Point
+	switch col {
+	case 0:
+		at.X = bounds.Min.X + s.Margin
+	case 1:
+		at.X = bounds.Min.X + (bounds.Dx()-size.X)/2
+	case 2:
+		at.X = bounds.Max.X - s.Margin - size.X
+	}
+	switch row {
+	case 0:
+		at.Y = bounds.Min.Y + s.Margin
+	case 1:
+		at.Y = bounds.Min.Y + (bounds.Dy()-size.Y)/2
+	case 2:
+		at.Y = bounds.Max.Y - s.Margin - size.Y
+	}
+
+	at = at.Add(s.Offset)
+	return image.Rectangle{Min: at, Max: at.Add(size)}
+}
+
+// draw draws text using the LabelStyle, with the top left corner of its
+// padding at the input point.
+func (s LabelStyle) draw(dst draw.Image, text string, at image.Point) {
+	r := image.Rectangle{Min: at, Max: at.Add(s.Measure(text))}
+	if s.Background != nil {
+		draw.Draw(dst, r, image.NewUniform(s.Background), image.Point{}, draw.Over)
+	}
+
+	c := s.Color
+	if c == nil {
+		c = color.Black
+	}
+
+	mask := s.mask(text)
+	r = r.Inset(s.Padding)
+	draw.DrawMask(dst, r, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// mask draws text using the embedded font, and scales it to the size of the
+// LabelStyle, returning an alpha mask of the text.
+func (s LabelStyle) mask(text string) *image.Alpha {
+	face := basicfont.Face7x13
+	m := face.Metrics()
+
+	w, h := font.MeasureString(face, text).Ceil(), m.Height.Ceil()
+	src := image.NewAlpha(image.Rect(0, 0, w, h))
+	d := &font.Drawer{
+		Dst:  src,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.Point26_6{Y: m.Ascent},
+	}
+	d.DrawString(text)
+
+	size := s.size()
+	if size == h {
+		return src
+	}
+
+	// Scale the text using nearest neighbor sampling, which keeps the edges
+	// of the bitmap font sharp
+	sw := w * size / h
+	dst := image.NewAlpha(image.Rect(0, 0, sw, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < sw; x++ {
+			dst.SetAlpha(x, y, src.AlphaAt(x*w/sw, y*h/size))
+		}
+	}
+
+	return dst
+}
+
+// LabelLayer creates an opaque Layer which draws a single line of text using
+// the input LabelStyle, such as a title or the duration of a track.
+func LabelLayer(text string, style LabelStyle) Layer {
+	return Layer{
+		Name:    "label",
+		Opacity: 1,
+		Draw: func(dst draw.Image, c *Canvas) error {
+			style.draw(dst, text, style.Place(text, c.Bounds).Min)
+			return nil
+		},
+	}
+}
+
+// RulerLayer creates an opaque Layer which draws a time ruler along the top
+// or bottom edge of the image: a tick mark at each multiple of the input
+// interval, labeled with its time offset, such as "1:30".
+//
+// Ticks and labels are drawn using the Color of the input LabelStyle.  The
+// ruler is drawn along the bottom edge of the image if the Anchor of the style
+// is AnchorBottomLeft, AnchorBottom, or AnchorBottomRight, and along the top
+// edge otherwise.  Each label is drawn beside its tick, and labels which would
+// overlap the previous label or extend past the edge of the image are
+// omitted.
+func RulerLayer(interval time.Duration, style LabelStyle) Layer {
+	return Layer{
+		Name:    "ruler",
+		Opacity: 1,
+		Draw: func(dst draw.Image, cv *Canvas) error {
+			if interval <= 0 {
+				return nil
+			}
+
+			c := style.Color
+			if c == nil {
+				c = color.Black
+			}
+
+			b := cv.Bounds
+			bottom := style.Anchor >= AnchorBottomLeft && style.Anchor <= AnchorBottomRight
+
+			// Ticks are a quarter of the height of text
+			tick := style.size() / 4
+			if tick < 2 {
+				tick = 2
+			}
+
+			// lastX is the right edge of the previous label
+			lastX := b.Min.X - 1
+			for t := time.Duration(0); t < cv.Duration; t += interval {
+				x := cv.TimeX(t)
+
+				y0, y1 := b.Min.Y+style.Margin, b.Min.Y+style.Margin+tick
+				if bottom {
+					y0, y1 = b.Max.Y-style.Margin-tick, b.Max.Y-style.Margin
+				}
+				for y := y0; y < y1; y++ {
+					dst.Set(x, y, c)
+				}
+
+				text := rulerLabel(t, interval)
+				size := style.Measure(text)
+
+				at := image.Pt(x+2, y1)
+				if bottom {
+					at.Y = y0 - size.Y
+				}
+				if at.X <= lastX || at.X+size.X > b.Max.X {
+					continue
+				}
+
+				style.draw(dst, text, at)
+				lastX = at.X + size.X
+			}
+
+			return nil
+		},
+	}
+}
+
+// rulerLabel formats a time offset for a ruler with the input interval,
+// as minutes and seconds, with hours if needed, and with fractional seconds
+// only if the interval is shorter than a second.
+func rulerLabel(t time.Duration, interval time.Duration) string {
+	h, m := int(t/time.Hour), int(t/time.Minute)%60
+	sec := (t % time.Minute).Seconds()
+
+	var s string
+	if interval < time.Second {
+		s = fmt.Sprintf("%04.1f", sec)
+	} else {
+		s = fmt.Sprintf("%02d", int(sec))
+	}
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%s", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%s", m, s)
+}
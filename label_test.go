@@ -0,0 +1,172 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// TestLabelStyleMeasure verifies that LabelStyle.Measure scales text to the
+// size of the style, including its padding.
+func TestLabelStyleMeasure(t *testing.T) {
+	var tests = []struct {
+		style LabelStyle
+		want  image.Point
+	}{
+		{LabelStyle{}, image.Pt(14, 13)},
+		{LabelStyle{Size: 26}, image.Pt(28, 26)},
+		{LabelStyle{Size: 26, Padding: 3}, image.Pt(34, 32)},
+	}
+
+	for i, test := range tests {
+		if got := test.style.Measure("ab"); got != test.want {
+			t.Fatalf("[%02d] unexpected size: %v != %v", i, got, test.want)
+		}
+	}
+}
+
+// TestLabelStylePlace verifies that LabelStyle.Place positions text at each
+// anchor, less the margin and plus the offset.
+func TestLabelStylePlace(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+
+	var tests = []struct {
+		style LabelStyle
+		want  image.Point
+	}{
+		{LabelStyle{}, image.Pt(0, 0)},
+		{LabelStyle{Anchor: AnchorTop, Margin: 2}, image.Pt(43, 2)},
+		{LabelStyle{Anchor: AnchorTopRight, Margin: 2}, image.Pt(84, 2)},
+		{LabelStyle{Anchor: AnchorCenter}, image.Pt(43, 18)},
+		{LabelStyle{Anchor: AnchorBottomLeft, Margin: 2}, image.Pt(2, 35)},
+		{LabelStyle{Anchor: AnchorBottomRight, Offset: image.Pt(-1, -1)}, image.Pt(85, 36)},
+	}
+
+	for i, test := range tests {
+		r := test.style.Place("ab", bounds)
+		if r.Min != test.want || r.Size() != test.style.Measure("ab") {
+			t.Fatalf("[%02d] unexpected placement: %v, want min %v", i, r, test.want)
+		}
+	}
+}
+
+// TestCompositeLabel verifies that LabelLayer draws text and its background
+// within the placed bounds of the label.
+func TestCompositeLabel(t *testing.T) {
+	s, err := NewStyle(Scale(40, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	style := LabelStyle{
+		Size:       26,
+		Color:      red,
+		Background: white,
+		Padding:    2,
+		Anchor:     AnchorBottomRight,
+		Margin:     4,
+	}
+
+	img, err := Composite([]float64{0.00}, s, 0, LabelLayer("W", style))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := style.Place("W", img.Bounds())
+
+	var text bool
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			c := img.At(x, y)
+			_, _, _, a := c.RGBA()
+
+			in := image.Pt(x, y).In(r)
+			switch {
+			case !in && a != 0:
+				t.Fatalf("label drawn outside of its bounds at (%d, %d)", x, y)
+			case in && a == 0:
+				t.Fatalf("background not drawn at (%d, %d)", x, y)
+			case colorsEqual(c, red):
+				text = true
+			}
+		}
+	}
+	if !text {
+		t.Fatal("no text was drawn")
+	}
+}
+
+// TestCompositeRuler verifies that RulerLayer draws a tick at each interval,
+// along the selected edge of the image.
+func TestCompositeRuler(t *testing.T) {
+	s, err := NewStyle(Resolution(1), Scale(100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0, 0, 0}
+	for _, anchor := range []LabelAnchor{AnchorTopLeft, AnchorBottom} {
+		img, err := Composite(values, s, 0, RulerLayer(time.Second, LabelStyle{
+			Color:  blue,
+			Anchor: anchor,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		y := 0
+		if anchor == AnchorBottom {
+			y = img.Bounds().Max.Y - 1
+		}
+
+		for _, x := range []int{0, 100, 200} {
+			if c := img.At(x, y); !colorsEqual(c, blue) {
+				t.Fatalf("[%d] unexpected tick color at (%d, %d): %v", anchor, x, y, c)
+			}
+		}
+		if c := img.At(50, y); colorsEqual(c, blue) {
+			t.Fatalf("[%d] unexpected tick at (50, %d)", anchor, y)
+		}
+	}
+}
+
+// TestRulerLabel verifies the formatting of ruler labels.
+func TestRulerLabel(t *testing.T) {
+	var tests = []struct {
+		t, interval time.Duration
+		want        string
+	}{
+		{0, time.Second, "0:00"},
+		{90 * time.Second, 30 * time.Second, "1:30"},
+		{time.Hour + 2*time.Minute + 3*time.Second, time.Minute, "1:02:03"},
+		{1500 * time.Millisecond, 500 * time.Millisecond, "0:01.5"},
+	}
+
+	for i, test := range tests {
+		if got := rulerLabel(test.t, test.interval); got != test.want {
+			t.Fatalf("[%02d] unexpected label: %q != %q", i, got, test.want)
+		}
+	}
+}
+
+// TestLabelStyleDefaultColor verifies that a LabelStyle without a color draws
+// black text.
+func TestLabelStyleDefaultColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	LabelStyle{}.draw(img, "W", image.Pt(0, 0))
+
+	var drawn bool
+	for x := 0; x < 20 && !drawn; x++ {
+		for y := 0; y < 20; y++ {
+			if colorsEqual(img.At(x, y), color.Black) {
+				drawn = true
+				break
+			}
+		}
+	}
+	if !drawn {
+		t.Fatal("no black text was drawn")
+	}
+}
@@ -0,0 +1,348 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	// errLayerDrawNil is returned when a Layer with a nil LayerFunc is used
+	// in a call to Composite.
+	errLayerDrawNil = errors.New("waveform: layer draw function cannot be nil")
+
+	// errLayerOpacityInvalid is returned when a Layer with an opacity outside
+	// the range [0, 1] is used in a call to Composite.
+	errLayerOpacityInvalid = errors.New("waveform: layer opacity must be between 0 and 1")
+
+	// errLayerBlendInvalid is returned when a Layer with an unknown BlendMode
+	// is used in a call to Composite.
+	errLayerBlendInvalid = errors.New("waveform: unknown layer blend mode")
+)
+
+// BlendMode is a mode which determines how the colors of a Layer are combined
+// with the colors of the layers beneath it.
+type BlendMode int
+
+const (
+	// BlendNormal draws a layer over the layers beneath it.  This is the
+	// default blend mode.
+	BlendNormal BlendMode = iota
+
+	// BlendMultiply multiplies the colors of a layer with the colors beneath
+	// it, which always produces a darker color.
+	BlendMultiply
+
+	// BlendScreen inverts, multiplies, and inverts again the colors of a
+	// layer and the colors beneath it, which always produces a lighter color.
+	BlendScreen
+
+	// BlendOverlay multiplies dark colors beneath a layer and screens light
+	// colors beneath it, preserving highlights and shadows.
+	BlendOverlay
+)
+
+// Canvas describes the composite image which is being drawn, and is passed to
+// each Layer.
+type Canvas struct {
+	// Values are the computed values used to draw the waveform.
+	Values []float64
+
+	// Style is the RenderStyle which determines the size of the image, and
+	// which is used to draw the waveform.
+	Style RenderStyle
+
+	// Duration is the duration of the audio which was used to compute values,
+	// used to position time-based layers such as grids and markers.
+	Duration time.Duration
+
+	// Bounds are the bounds of the composite image.
+	Bounds image.Rectangle
+}
+
+// TimeX returns the X coordinate which corresponds to a time offset within the
+// audio represented by the Canvas.
+func (c *Canvas) TimeX(t time.Duration) int {
+	if c.Duration <= 0 {
+		return c.Bounds.Min.X
+	}
+
+	return c.Bounds.Min.X + int(int64(c.Bounds.Dx())*int64(t)/int64(c.Duration))
+}
+
+// LayerFunc is a function which draws a single layer onto dst.  dst has the
+// bounds of the Canvas, and is fully transparent when LayerFunc is called.
+type LayerFunc func(dst draw.Image, c *Canvas) error
+
+// Layer is a single layer of a composite image, drawn using Composite.
+//
+// Layers are drawn from lowest to highest Z, and layers with equal Z are drawn
+// in the order they were specified.  Each layer is drawn separately, and then
+// composited over the layers beneath it using its Opacity and BlendMode.
+type Layer struct {
+	// Name is a descriptive name for the layer.
+	Name string
+
+	// Z is the stacking order of the layer.
+	Z int
+
+	// Opacity is the opacity of the layer, from 0 (invisible) to 1 (opaque).
+	// Layers created by this package are opaque by default.
+	Opacity float64
+
+	// Blend is the BlendMode used to combine the layer with the layers
+	// beneath it.
+	Blend BlendMode
+
+	// Draw draws the layer.
+	Draw LayerFunc
+}
+
+// Composite builds an image from a slice of computed values by drawing a set of
+// layers, such as a background, the waveform itself, a time grid, markers, and
+// text, so that complex output can be built declaratively.
+//
+// The size of the image is determined by the input RenderStyle.  duration is the
+// duration of the audio used to compute values; if 0, it is estimated from the
+// number of values and the resolution of the style.
+func Composite(values []float64, style RenderStyle, duration time.Duration, layers ...Layer) (image.Image, error) {
+	for _, l := range layers {
+		if l.Draw == nil {
+			return nil, errLayerDrawNil
+		}
+		if l.Opacity < 0 || l.Opacity > 1 {
+			return nil, errLayerOpacityInvalid
+		}
+		if l.Blend < BlendNormal || l.Blend > BlendOverlay {
+			return nil, errLayerBlendInvalid
+		}
+	}
+
+	if duration <= 0 && style.resolution > 0 {
+		duration = time.Duration(int64(len(values)) * int64(time.Second) / int64(style.resolution))
+	}
+
+	c := &Canvas{
+		Values:   values,
+		Style:    style,
+		Duration: duration,
+		Bounds:   style.columns(values, nil).Bounds(),
+	}
+
+	// Draw layers in stacking order, without modifying the input slice
+	sorted := make([]Layer, len(layers))
+	copy(sorted, layers)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return sorted[i].Z < sorted[j].Z
+	})
+
+	dst := image.NewRGBA(c.Bounds)
+	for _, l := range sorted {
+		if l.Opacity == 0 {
+			continue
+		}
+
+		src := image.NewRGBA(c.Bounds)
+		if err := l.Draw(src, c); err != nil {
+			return nil, err
+		}
+
+		if l.Blend == BlendNormal {
+			mask := image.NewUniform(color.Alpha16{A: uint16(l.Opacity * 0xffff)})
+			draw.DrawMask(dst, c.Bounds, src, c.Bounds.Min, mask, image.Point{}, draw.Over)
+			continue
+		}
+
+		blendLayer(dst, src, l.Opacity, l.Blend)
+	}
+
+	return dst, nil
+}
+
+// blendLayer composites src over dst using the input opacity and BlendMode,
+// according to the separable blend modes of the W3C Compositing and Blending
+// specification.  dst and src must have the same bounds.
+func blendLayer(dst *image.RGBA, src *image.RGBA, opacity float64, mode BlendMode) {
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			di, si := dst.PixOffset(x, y), src.PixOffset(x, y)
+			d, s := dst.Pix[di:di+4:di+4], src.Pix[si:si+4:si+4]
+
+			as := float64(s[3]) / 0xff * opacity
+			if as == 0 {
+				continue
+			}
+			ab := float64(d[3]) / 0xff
+			ao := as + ab*(1-as)
+
+			for i := 0; i < 3; i++ {
+				// Unpremultiply both colors
+				var cs, cb float64
+				if s[3] > 0 {
+					cs = float64(s[i]) / float64(s[3])
+				}
+				if d[3] > 0 {
+					cb = float64(d[i]) / float64(d[3])
+				}
+
+				// Premultiplied result of blending the source over the backdrop
+				co := as*(1-ab)*cs + as*ab*blendChannel(mode, cb, cs) + (1-as)*ab*cb
+				d[i] = uint8(co*0xff + 0.5)
+			}
+
+			d[3] = uint8(ao*0xff + 0.5)
+		}
+	}
+}
+
+// blendChannel applies a BlendMode to a single unpremultiplied color channel
+// of a backdrop and source color, each in the range [0, 1].
+func blendChannel(mode BlendMode, cb float64, cs float64) float64 {
+	switch mode {
+	case BlendMultiply:
+		return cb * cs
+	case BlendScreen:
+		return cb + cs - cb*cs
+	case BlendOverlay:
+		// Overlay is hard light with the layers reversed
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+
+		return blendChannel(BlendScreen, 2*cb-1, cs)
+	default:
+		return cs
+	}
+}
+
+// BackgroundLayer creates an opaque Layer which fills the image using the
+// input ColorFunc.
+func BackgroundLayer(function ColorFunc) Layer {
+	return Layer{
+		Name:    "background",
+		Opacity: 1,
+		Draw: func(dst draw.Image, c *Canvas) error {
+			b := c.Bounds
+			maxN := len(c.Values)
+			for x := b.Min.X; x < b.Max.X; x++ {
+				for y := b.Min.Y; y < b.Max.Y; y++ {
+					// Determine which computed value this coordinate represents
+					var n int
+					if b.Dx() > 0 {
+						n = (x - b.Min.X) * maxN / b.Dx()
+					}
+
+					dst.Set(x, y, function(n, x, y, maxN, b.Max.X, b.Max.Y))
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// WaveformLayer creates an opaque Layer which draws the waveform using the
+// input RenderStyle.  Only the foreground of the waveform is drawn; the
+// background color function of the style is ignored, so that the waveform
+// may be drawn over other layers.
+func WaveformLayer(style RenderStyle) Layer {
+	style.bgColorFn = SolidColor(color.Transparent)
+
+	return Layer{
+		Name:    "waveform",
+		Opacity: 1,
+		Draw: func(dst draw.Image, c *Canvas) error {
+			img, err := style.DrawChecked(c.Values)
+			if err != nil {
+				return err
+			}
+
+			draw.Draw(dst, c.Bounds, img, img.Bounds().Min, draw.Over)
+			return nil
+		},
+	}
+}
+
+// GridLayer creates an opaque Layer which draws a vertical line at each
+// multiple of the input interval, and a horizontal line through the center of
+// the image, using the input color.
+func GridLayer(interval time.Duration, c color.Color) Layer {
+	return Layer{
+		Name:    "grid",
+		Opacity: 1,
+		Draw: func(dst draw.Image, cv *Canvas) error {
+			b := cv.Bounds
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for t := time.Duration(0); interval > 0 && t < cv.Duration; t += interval {
+					dst.Set(cv.TimeX(t), y, c)
+				}
+			}
+
+			cy := b.Min.Y + b.Dy()/2
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(x, cy, c)
+			}
+
+			return nil
+		},
+	}
+}
+
+// MarkersLayer creates an opaque Layer which draws a vertical line at each of
+// the input time offsets, such as cue points or chapter boundaries, using the
+// input color.
+func MarkersLayer(markers []time.Duration, c color.Color) Layer {
+	return Layer{
+		Name:    "markers",
+		Opacity: 1,
+		Draw: func(dst draw.Image, cv *Canvas) error {
+			for _, m := range markers {
+				drawPlayhead(dst, cv.TimeX(m), c)
+			}
+
+			return nil
+		},
+	}
+}
+
+// TextLayer creates an opaque Layer which draws a single line of text, using a
+// simple fixed-width font and the input color.  The input point is the top left
+// corner of the text.
+func TextLayer(text string, at image.Point, c color.Color) Layer {
+	return Layer{
+		Name:    "text",
+		Opacity: 1,
+		Draw: func(dst draw.Image, _ *Canvas) error {
+			face := basicfont.Face7x13
+			d := &font.Drawer{
+				Dst:  dst,
+				Src:  image.NewUniform(c),
+				Face: face,
+				Dot: fixed.Point26_6{
+					X: fixed.I(at.X),
+					Y: fixed.I(at.Y) + face.Metrics().Ascent,
+				},
+			}
+
+			d.DrawString(text)
+			return nil
+		},
+	}
+}
+
+// CustomLayer creates an opaque Layer which is drawn using the input LayerFunc.
+func CustomLayer(name string, fn LayerFunc) Layer {
+	return Layer{
+		Name:    name,
+		Opacity: 1,
+		Draw:    fn,
+	}
+}
@@ -0,0 +1,210 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+)
+
+// TestCompositeLayers verifies that Composite draws each built-in layer in
+// stacking order.
+func TestCompositeLayers(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	s, err := NewStyle(Scale(10, 1), FGColorFunction(SolidColor(blue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Layers are specified out of order, and sorted by Z
+	markers := MarkersLayer([]time.Duration{time.Second}, red)
+	markers.Z = 3
+	grid := GridLayer(2*time.Second, green)
+	grid.Z = 1
+	bg := BackgroundLayer(SolidColor(color.White))
+	bg.Z = -1
+
+	img, err := Composite([]float64{0.10, 0.10, 0.10, 0.10}, s, 0,
+		markers,
+		WaveformLayer(s),
+		grid,
+		bg,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		x, y int
+		c    color.Color
+	}{
+		// Background
+		{5, 0, color.White},
+		// Waveform is drawn above background
+		{5, imgYDefault/2 + 5, blue},
+		// Grid at 0s and 2s, and center line, drawn above waveform
+		{0, 0, green},
+		{20, 0, green},
+		{5, imgYDefault / 2, green},
+		// Marker at 1s, drawn above grid
+		{10, imgYDefault / 2, red},
+	}
+
+	for i, test := range tests {
+		if c := img.At(test.x, test.y); !colorsEqual(c, test.c) {
+			t.Fatalf("[%02d] unexpected color at (%d, %d): %v != %v", i, test.x, test.y, c, test.c)
+		}
+	}
+}
+
+// TestCompositeOpacity verifies that Composite applies the opacity of each
+// layer.
+func TestCompositeOpacity(t *testing.T) {
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill := CustomLayer("fill", func(dst draw.Image, c *Canvas) error {
+		draw.Draw(dst, c.Bounds, image.NewUniform(color.Black), image.Point{}, draw.Src)
+		return nil
+	})
+	fill.Opacity = 0.5
+
+	img, err := Composite([]float64{0.10}, s, 0, BackgroundLayer(SolidColor(color.White)), fill)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Half black over white is mid gray
+	r, _, _, a := img.At(0, 0).RGBA()
+	if a != 0xffff || r < 0x7f00 || r > 0x8100 {
+		t.Fatalf("unexpected blended color: %v", img.At(0, 0))
+	}
+}
+
+// TestCompositeBlendModes verifies that Composite applies the BlendMode of
+// each layer.
+func TestCompositeBlendModes(t *testing.T) {
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backdrop := color.RGBA{0x40, 0xc0, 0xff, 0xff}
+	source := color.RGBA{0x80, 0x80, 0x00, 0xff}
+
+	var tests = []struct {
+		mode BlendMode
+		want color.RGBA
+	}{
+		{BlendNormal, source},
+		{BlendMultiply, color.RGBA{0x20, 0x60, 0x00, 0xff}},
+		{BlendScreen, color.RGBA{0xa0, 0xe0, 0xff, 0xff}},
+		{BlendOverlay, color.RGBA{0x40, 0xc1, 0xff, 0xff}},
+	}
+
+	for i, test := range tests {
+		top := BackgroundLayer(SolidColor(source))
+		top.Blend = test.mode
+
+		img, err := Composite([]float64{0.00}, s, 0, BackgroundLayer(SolidColor(backdrop)), top)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA)
+		if !approxRGBA(got, test.want) {
+			t.Fatalf("[%02d] unexpected blended color: %v != %v", i, got, test.want)
+		}
+	}
+}
+
+// TestCompositeBlendTransparentBackdrop verifies that blend modes reduce to
+// normal compositing where nothing is beneath a layer.
+func TestCompositeBlendTransparentBackdrop(t *testing.T) {
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := color.RGBA{0x80, 0x80, 0x00, 0xff}
+	top := BackgroundLayer(SolidColor(source))
+	top.Blend = BlendMultiply
+
+	img, err := Composite([]float64{0.00}, s, 0, top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); got != source {
+		t.Fatalf("unexpected color: %v != %v", got, source)
+	}
+}
+
+// approxRGBA reports whether two colors are equal, within a tolerance of 1
+// in each channel to allow for rounding.
+func approxRGBA(a color.RGBA, b color.RGBA) bool {
+	near := func(x uint8, y uint8) bool {
+		return x-y <= 1 || y-x <= 1
+	}
+
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && near(a.A, b.A)
+}
+
+// TestCompositeText verifies that TextLayer draws text.
+func TestCompositeText(t *testing.T) {
+	s, err := NewStyle(Scale(20, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Composite([]float64{0.00}, s, 0, TextLayer("W", image.Pt(2, 2), color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var drawn bool
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X && !drawn; x++ {
+		for y := b.Min.Y; y < 20; y++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				drawn = true
+				break
+			}
+		}
+	}
+	if !drawn {
+		t.Fatal("no text was drawn")
+	}
+}
+
+// TestCompositeErrors verifies that Composite rejects invalid layers.
+func TestCompositeErrors(t *testing.T) {
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := BackgroundLayer(SolidColor(color.White))
+	bad.Opacity = 1.5
+
+	var tests = []struct {
+		layer Layer
+		err   error
+	}{
+		{Layer{Opacity: 1}, errLayerDrawNil},
+		{bad, errLayerOpacityInvalid},
+		{Layer{Opacity: 1, Blend: -1, Draw: bad.Draw}, errLayerBlendInvalid},
+	}
+
+	for i, test := range tests {
+		if _, err := Composite([]float64{0.10}, s, 0, test.layer); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+}
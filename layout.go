@@ -0,0 +1,114 @@
+package waveform
+
+import (
+	"errors"
+
+	"azul3d.org/engine/audio"
+)
+
+// errPlanarFrames is returned when a read from a decoder which delivers planar
+// audio does not contain whole sample frames, so that its samples cannot be
+// assigned to channels.
+var errPlanarFrames = errors.New("waveform: planar audio read does not contain whole sample frames")
+
+// SampleLayout is a layout which determines how the audio samples delivered by
+// each read from a decoder are arranged among channels.
+type SampleLayout int
+
+const (
+	// SampleLayoutAuto detects the layout of audio samples from the decoder.
+	// Decoders which deliver planar audio indicate so by implementing a
+	// Planar method which returns true:
+	//
+	//   Planar() bool
+	//
+	// Audio from all other decoders, including every decoder registered by
+	// this package, is treated as interleaved.  This is the default behavior
+	// of the waveform package.
+	SampleLayoutAuto SampleLayout = iota
+
+	// SampleLayoutInterleaved treats audio samples as interleaved sample
+	// frames, in which one sample for each channel is delivered in turn.
+	SampleLayoutInterleaved
+
+	// SampleLayoutPlanar treats audio samples as planar channels, in which
+	// each read delivers all of the samples of the first channel, followed
+	// by all of the samples of each following channel.  Each read must
+	// deliver whole sample frames.
+	SampleLayoutPlanar
+)
+
+// planarDecoder is implemented by decoders which may deliver planar audio.
+type planarDecoder interface {
+	Planar() bool
+}
+
+// isPlanar reports whether a decoder delivers planar audio, according to the
+// input SampleLayout.
+func isPlanar(layout SampleLayout, d audio.Decoder) bool {
+	switch layout {
+	case SampleLayoutInterleaved:
+		return false
+	case SampleLayoutPlanar:
+		return true
+	}
+
+	// Detect the layout from the decoder provided to NewFromDecoder, or
+	// registered for the stream's format
+	if sd, ok := d.(*safeDecoder); ok {
+		d = sd.d
+	}
+	if cd, ok := d.(callerDecoder); ok {
+		d = cd.Decoder
+	}
+
+	pd, ok := d.(planarDecoder)
+	return ok && pd.Planar()
+}
+
+// planarReader is an audio.Reader which rearranges the planar audio delivered
+// by each read from an underlying audio.Reader into interleaved sample frames.
+type planarReader struct {
+	r        audio.Reader
+	channels int
+
+	// buf holds a copy of the planar samples of a single read
+	buf []float64
+}
+
+// newPlanarReader creates a planarReader which reads audio with the input
+// configuration from r.
+func newPlanarReader(r audio.Reader, config audio.Config) *planarReader {
+	return &planarReader{
+		r:        r,
+		channels: config.Channels,
+	}
+}
+
+// Read implements audio.Reader.
+func (pr *planarReader) Read(b audio.Slice) (int, error) {
+	n, err := pr.r.Read(b)
+	if n == 0 || pr.channels == 1 {
+		return n, err
+	}
+	if n%pr.channels != 0 {
+		return 0, errPlanarFrames
+	}
+
+	if cap(pr.buf) < n {
+		pr.buf = make([]float64, n)
+	}
+	buf := pr.buf[:n]
+	for i := range buf {
+		buf[i] = float64(b.At(i))
+	}
+
+	frames := n / pr.channels
+	for c := 0; c < pr.channels; c++ {
+		for f := 0; f < frames; f++ {
+			b.Set(f*pr.channels+c, buf[c*frames+f])
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,130 @@
+package waveform
+
+import (
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestLayout verifies that planar audio is rearranged into interleaved sample
+// frames before values are computed.
+func TestLayout(t *testing.T) {
+	var tests = []struct {
+		name    string
+		planar  bool
+		options []OptionsFunc
+		value   float64
+	}{
+		{
+			name:   "auto planar",
+			planar: true,
+			value:  0.5,
+		},
+		{
+			name:    "forced planar",
+			options: []OptionsFunc{Layout(SampleLayoutPlanar)},
+			value:   0.5,
+		},
+		{
+			// Each read of 4 frames delivers samples for left, left, right,
+			// right frames, so half of the left channel is silent
+			name:    "forced interleaved",
+			planar:  true,
+			options: []OptionsFunc{Layout(SampleLayoutInterleaved)},
+			value:   math.Sqrt(0.25 / 2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two seconds of stereo audio with a signal in the left channel
+			d := &planarDecoderTest{frames: 16, planar: tt.planar}
+
+			options := append([]OptionsFunc{DownmixFunction(DownmixLeft)}, tt.options...)
+			w, err := NewFromDecoder(d, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := w.Compute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(values) != 2 {
+				t.Fatalf("unexpected number of values: %d", len(values))
+			}
+			for _, v := range values {
+				if math.Abs(v-tt.value) > 1e-9 {
+					t.Fatalf("unexpected value: %v != %v", v, tt.value)
+				}
+			}
+		})
+	}
+}
+
+// TestLayoutPartialFrame verifies that a planar read which does not contain
+// whole sample frames is rejected.
+func TestLayoutPartialFrame(t *testing.T) {
+	d := &sampleDecoder{
+		samples: make([]float64, 15),
+		config:  audio.Config{SampleRate: 8, Channels: 2},
+	}
+
+	w, err := NewFromDecoder(d, Layout(SampleLayoutPlanar), Resolution(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != errPlanarFrames {
+		t.Fatalf("unexpected error: %v != %v", err, errPlanarFrames)
+	}
+}
+
+// TestLayoutOptions verifies that Layout rejects unknown layouts.
+func TestLayoutOptions(t *testing.T) {
+	testWaveformOptionFunc(t, Layout(SampleLayoutPlanar), nil)
+	testWaveformOptionFunc(t, Layout(SampleLayout(10)), errLayoutInvalid)
+}
+
+// planarDecoderTest is an audio.Decoder which delivers stereo audio in planar
+// blocks of up to 4 sample frames, with a half scale signal in the left
+// channel and silence in the right channel.
+type planarDecoderTest struct {
+	frames int
+	planar bool
+}
+
+// Config implements audio.Decoder.
+func (d *planarDecoderTest) Config() audio.Config {
+	return audio.Config{SampleRate: 8, Channels: 2}
+}
+
+// Planar reports whether the decoder advertises planar audio.
+func (d *planarDecoderTest) Planar() bool {
+	return d.planar
+}
+
+// Read implements audio.Decoder.
+func (d *planarDecoderTest) Read(b audio.Slice) (int, error) {
+	if d.frames == 0 {
+		return 0, audio.EOS
+	}
+
+	frames := b.Len() / 2
+	if frames > 4 {
+		frames = 4
+	}
+	if frames > d.frames {
+		frames = d.frames
+	}
+	d.frames -= frames
+
+	for i := 0; i < frames; i++ {
+		b.Set(i, 0.5)
+		b.Set(frames+i, 0)
+	}
+
+	return 2 * frames, nil
+}
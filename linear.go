@@ -0,0 +1,129 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// srgbToLinear converts an sRGB encoded color channel value in the range
+// [0, 1] to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear light color channel value in the range
+// [0, 1] to sRGB encoding.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// linearColor is a color with unpremultiplied, linear light color channels,
+// and an alpha channel, each in the range [0, 1].
+type linearColor struct {
+	r, g, b, a float64
+}
+
+// toLinear converts a color.Color to a linearColor.
+func toLinear(c color.Color) linearColor {
+	n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	return linearColor{
+		r: srgbToLinear(float64(n.R) / 0xffff),
+		g: srgbToLinear(float64(n.G) / 0xffff),
+		b: srgbToLinear(float64(n.B) / 0xffff),
+		a: float64(n.A) / 0xffff,
+	}
+}
+
+// color converts a linearColor to an sRGB encoded color.NRGBA64.
+func (c linearColor) color() color.NRGBA64 {
+	enc := func(v float64) uint16 {
+		return uint16(math.Round(linearToSRGB(clamp01(v)) * 0xffff))
+	}
+
+	return color.NRGBA64{
+		R: enc(c.r),
+		G: enc(c.g),
+		B: enc(c.b),
+		A: uint16(math.Round(clamp01(c.a) * 0xffff)),
+	}
+}
+
+// clamp01 clamps a value to the range [0, 1].
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// lerpLinear interpolates between two colors in linear light, where t is in
+// the range [0, 1].  Color channels are weighted by alpha, so that a fully
+// transparent color does not darken the result.
+func lerpLinear(a color.Color, b color.Color, t float64) color.Color {
+	la, lb := toLinear(a), toLinear(b)
+
+	out := linearColor{
+		a: la.a*(1-t) + lb.a*t,
+	}
+	if out.a == 0 {
+		return color.NRGBA64{}
+	}
+
+	// Interpolate premultiplied channels, then unpremultiply
+	mix := func(x float64, y float64) float64 {
+		return (x*la.a*(1-t) + y*lb.a*t) / out.a
+	}
+	out.r = mix(la.r, lb.r)
+	out.g = mix(la.g, lb.g)
+	out.b = mix(la.b, lb.b)
+
+	return out.color()
+}
+
+// drawMaskLinear composites src over dst through a coverage mask, blending in
+// linear light rather than in sRGB encoded values.  This avoids the dark
+// fringes produced by naive blending of anti-aliased edges.
+func drawMaskLinear(dst draw.Image, src image.Image, mask *image.Alpha) {
+	b := dst.Bounds().Intersect(mask.Bounds())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			coverage := mask.AlphaAt(x, y).A
+			if coverage == 0 {
+				continue
+			}
+
+			s := src.At(x, y)
+			if coverage == 0xff {
+				// Fully covered pixels need no blending, except for alpha
+				if _, _, _, a := s.RGBA(); a == 0xffff {
+					dst.Set(x, y, s)
+					continue
+				}
+			}
+
+			// Source over destination, with source alpha scaled by coverage
+			ls, ld := toLinear(s), toLinear(dst.At(x, y))
+			sa := ls.a * float64(coverage) / 0xff
+			out := linearColor{
+				a: sa + ld.a*(1-sa),
+			}
+			if out.a > 0 {
+				over := func(cs float64, cd float64) float64 {
+					return (cs*sa + cd*ld.a*(1-sa)) / out.a
+				}
+				out.r = over(ls.r, ld.r)
+				out.g = over(ls.g, ld.g)
+				out.b = over(ls.b, ld.b)
+			}
+
+			dst.Set(x, y, out.color())
+		}
+	}
+}
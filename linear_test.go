@@ -0,0 +1,116 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestSRGBLinearRoundTrip verifies that converting sRGB values to linear light
+// and back produces the original values.
+func TestSRGBLinearRoundTrip(t *testing.T) {
+	for i := 0; i <= 0xff; i++ {
+		v := float64(i) / 0xff
+		if got := linearToSRGB(srgbToLinear(v)); math.Abs(got-v) > 1e-9 {
+			t.Fatalf("unexpected round trip for %d: %v != %v", i, got, v)
+		}
+	}
+}
+
+// TestLinearGradientColor verifies that LinearGradientColor interpolates in
+// linear light, and reaches both endpoints.
+func TestLinearGradientColor(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	fn := LinearGradientColor(red, green)
+
+	if c := fn(0, 0, 0, 3, 0, 0); !colorsEqual(c, red) {
+		t.Fatalf("unexpected start color: %v != %v", c, red)
+	}
+	if c := fn(2, 0, 0, 3, 0, 0); !colorsEqual(c, green) {
+		t.Fatalf("unexpected end color: %v != %v", c, green)
+	}
+
+	// Midpoint is much brighter than the naive sRGB midpoint of 127
+	mid := color.NRGBAModel.Convert(fn(1, 0, 0, 3, 0, 0)).(color.NRGBA)
+	if mid.R != 188 || mid.G != 188 || mid.B != 0 {
+		t.Fatalf("unexpected midpoint color: %v", mid)
+	}
+}
+
+// TestGradientColorDirection verifies that GradientColorDirection runs
+// gradients in each direction, from the start color to the end color.
+func TestGradientColorDirection(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	// Coordinates (n, x, y) of the start and end of each gradient, in a 5x5
+	// image with 5 computed values
+	var tests = []struct {
+		name       string
+		direction  GradientDirection
+		start, end [3]int
+	}{
+		{"horizontal", GradientHorizontal, [3]int{0, 4, 4}, [3]int{4, 0, 0}},
+		{"vertical", GradientVertical, [3]int{4, 4, 0}, [3]int{0, 0, 4}},
+		{"diagonal", GradientDiagonal, [3]int{4, 0, 0}, [3]int{0, 4, 4}},
+		{"radial", GradientRadial, [3]int{0, 2, 2}, [3]int{2, 4, 0}},
+		{"unknown", GradientDirection(10), [3]int{0, 4, 4}, [3]int{4, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := GradientColorDirection(black, white, tt.direction)
+			at := func(p [3]int) color.Color {
+				return fn(p[0], p[1], p[2], 5, 5, 5)
+			}
+
+			if c := at(tt.start); !colorsEqual(c, black) {
+				t.Fatalf("unexpected start color: %v != %v", c, black)
+			}
+			if c := at(tt.end); !colorsEqual(c, white) {
+				t.Fatalf("unexpected end color: %v != %v", c, white)
+			}
+		})
+	}
+
+	// A single pixel image is drawn with the start color
+	fn := GradientColorDirection(black, white, GradientRadial)
+	if c := fn(0, 0, 0, 1, 1, 1); !colorsEqual(c, black) {
+		t.Fatalf("unexpected single pixel color: %v != %v", c, black)
+	}
+}
+
+// TestLinearLightVectorEdges verifies that the LinearLight option blends the
+// anti-aliased edges of the vector renderer in linear light, which produces
+// lighter edges for a black waveform on a white background.
+func TestLinearLightVectorEdges(t *testing.T) {
+	values := []float64{0.10, 0.25, 0.15, 0.30}
+
+	srgb, err := NewStyle(VectorRenderer(), Scale(8, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linear, err := srgb.With(LinearLight(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := srgb.Draw(values).(*image.RGBA)
+	b := linear.Draw(values).(*image.RGBA)
+
+	var lighter int
+	for i := 0; i < len(a.Pix); i += 4 {
+		switch {
+		case b.Pix[i] < a.Pix[i]:
+			t.Fatalf("linear pixel darker than sRGB pixel at offset %d: %v < %v", i, b.Pix[i], a.Pix[i])
+		case b.Pix[i] > a.Pix[i]:
+			lighter++
+		}
+	}
+
+	if lighter == 0 {
+		t.Fatal("expected some anti-aliased edges to be lighter")
+	}
+}
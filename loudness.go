@@ -0,0 +1,500 @@
+package waveform
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// LoudnessStandard is a broadcast loudness standard, against which a
+// LoudnessReport is checked for compliance.
+type LoudnessStandard struct {
+	// Name is the name of the standard.
+	Name string
+
+	// Target is the target integrated loudness, in LUFS.
+	Target float64
+
+	// Tolerance is the permitted deviation from Target, in LU.
+	Tolerance float64
+
+	// MaxTruePeak is the maximum permitted true peak level, in dBTP.
+	MaxTruePeak float64
+
+	// MaxShortTerm is the short-term loudness, in LUFS, above which a range
+	// of audio is reported as over threshold.
+	MaxShortTerm float64
+}
+
+var (
+	// EBUR128 is the EBU R 128 loudness standard, with the short-term
+	// loudness limit recommended for short-form content by EBU R 128 s1.
+	EBUR128 = LoudnessStandard{
+		Name:         "EBU R 128",
+		Target:       -23,
+		Tolerance:    0.5,
+		MaxTruePeak:  -1,
+		MaxShortTerm: -18,
+	}
+
+	// ATSCA85 is the ATSC A/85 loudness standard.  A/85 specifies no
+	// short-term loudness limit, so the relative limit of EBU R 128 s1 is
+	// used.
+	ATSCA85 = LoudnessStandard{
+		Name:         "ATSC A/85",
+		Target:       -24,
+		Tolerance:    2,
+		MaxTruePeak:  -2,
+		MaxShortTerm: -19,
+	}
+)
+
+// Range is a range of time within an audio stream.
+type Range struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// Duration returns the duration of a Range.
+func (r Range) Duration() time.Duration {
+	return r.End - r.Start
+}
+
+// LoudnessReport is a loudness compliance report, produced by a
+// LoudnessAnalyzer.
+//
+// Loudness values are math.Inf(-1) for silent audio, and are encoded as null
+// in JSON.
+type LoudnessReport struct {
+	// Standard is the name of the standard the report was checked against.
+	Standard string
+
+	// Integrated is the gated integrated loudness, in LUFS.
+	Integrated float64
+
+	// Range is the loudness range, in LU.
+	Range float64
+
+	// TruePeak is the maximum true peak level of any channel, in dBTP.
+	TruePeak float64
+
+	// OverThreshold are the ranges of audio where short-term loudness
+	// exceeded the MaxShortTerm value of the standard.
+	OverThreshold []Range
+
+	// Compliant reports whether integrated loudness is within tolerance of
+	// the target, and true peak does not exceed the maximum.
+	Compliant bool
+}
+
+// MarshalJSON implements json.Marshaler, encoding infinite loudness values as
+// null.
+func (r *LoudnessReport) MarshalJSON() ([]byte, error) {
+	finite := func(v float64) *float64 {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+
+		return &v
+	}
+
+	over := r.OverThreshold
+	if over == nil {
+		over = []Range{}
+	}
+
+	return json.Marshal(struct {
+		Standard      string   `json:"standard"`
+		Integrated    *float64 `json:"integrated_lufs"`
+		Range         *float64 `json:"loudness_range_lu"`
+		TruePeak      *float64 `json:"true_peak_dbtp"`
+		OverThreshold []Range  `json:"over_threshold"`
+		Compliant     bool     `json:"compliant"`
+	}{
+		Standard:      r.Standard,
+		Integrated:    finite(r.Integrated),
+		Range:         finite(r.Range),
+		TruePeak:      finite(r.TruePeak),
+		OverThreshold: over,
+		Compliant:     r.Compliant,
+	})
+}
+
+// WriteJSON writes the JSON representation of a LoudnessReport to an
+// io.Writer, so it can be stored next to a waveform image.
+func (r *LoudnessReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(r)
+}
+
+const (
+	// Measurement block durations, as a number of 100ms sub-blocks
+	momentaryBlocks = 4
+	shortTermBlocks = 30
+
+	// Gating thresholds, as defined by ITU-R BS.1770 and EBU Tech 3342
+	absoluteGate      = -70.0
+	relativeGate      = -10.0
+	rangeRelativeGate = -20.0
+
+	// True peak oversampling factor and filter taps per phase
+	truePeakFactor = 4
+	truePeakTaps   = 12
+)
+
+// LoudnessAnalyzer is an Analyzer which measures loudness according to
+// ITU-R BS.1770 and EBU Tech 3342, and checks it against a LoudnessStandard.
+//
+// A LoudnessAnalyzer should be applied using the Analyzers option, and its
+// Report method called once computation is complete.  A LoudnessAnalyzer
+// measures a single audio stream, and is not safe for concurrent use.
+type LoudnessAnalyzer struct {
+	std LoudnessStandard
+
+	// Stream configuration, determined from the first Chunk
+	sampleRate int
+	channels   int
+	weights    []float64
+
+	// Per-channel K-weighting filters and true peak history
+	filters []kFilter
+	history [][]float64
+	peak    float64
+
+	// Mean square energy of each completed 100ms sub-block
+	subBlocks []float64
+	sum       float64
+	frames    int
+	perBlock  int
+}
+
+// NewLoudnessAnalyzer creates a LoudnessAnalyzer which checks compliance
+// against the input LoudnessStandard.
+func NewLoudnessAnalyzer(std LoudnessStandard) *LoudnessAnalyzer {
+	return &LoudnessAnalyzer{
+		std: std,
+	}
+}
+
+// Analyze implements Analyzer.
+func (a *LoudnessAnalyzer) Analyze(c *Chunk) error {
+	if a.filters == nil {
+		a.init(c.Config.SampleRate, c.Config.Channels)
+	}
+
+	for i := 0; i+a.channels <= len(c.Samples); i += a.channels {
+		var energy float64
+		for ch := 0; ch < a.channels; ch++ {
+			s := c.Samples.At(i + ch)
+			a.truePeak(ch, s)
+
+			k := a.filters[ch].process(s)
+			energy += a.weights[ch] * k * k
+		}
+
+		a.sum += energy
+		a.frames++
+		if a.frames == a.perBlock {
+			a.subBlocks = append(a.subBlocks, a.sum/float64(a.perBlock))
+			a.sum = 0
+			a.frames = 0
+		}
+	}
+
+	return nil
+}
+
+// Report produces a LoudnessReport from all audio analyzed so far.
+func (a *LoudnessAnalyzer) Report() *LoudnessReport {
+	r := &LoudnessReport{
+		Standard:   a.std.Name,
+		Integrated: math.Inf(-1),
+		TruePeak:   math.Inf(-1),
+	}
+
+	if a.peak > 0 {
+		r.TruePeak = 20 * math.Log10(a.peak)
+	}
+
+	momentary := blockEnergies(a.subBlocks, momentaryBlocks)
+	r.Integrated = gatedLoudness(momentary, relativeGate)
+	r.Range = loudnessRange(blockEnergies(a.subBlocks, shortTermBlocks))
+	r.OverThreshold = a.overThreshold()
+
+	r.Compliant = math.Abs(r.Integrated-a.std.Target) <= a.std.Tolerance &&
+		r.TruePeak <= a.std.MaxTruePeak
+
+	return r
+}
+
+// init prepares a LoudnessAnalyzer for a stream with the input configuration.
+func (a *LoudnessAnalyzer) init(sampleRate int, channels int) {
+	a.sampleRate = sampleRate
+	a.channels = channels
+	a.perBlock = sampleRate / 10
+	if a.perBlock == 0 {
+		a.perBlock = 1
+	}
+
+	// Channel weights for 5.1 audio exclude LFE and boost surrounds
+	a.weights = make([]float64, channels)
+	for i := range a.weights {
+		a.weights[i] = 1
+	}
+	if channels == 6 {
+		a.weights[3] = 0
+		a.weights[4] = 1.41
+		a.weights[5] = 1.41
+	}
+
+	a.filters = make([]kFilter, channels)
+	for i := range a.filters {
+		a.filters[i] = newKFilter(float64(sampleRate))
+	}
+
+	a.history = make([][]float64, channels)
+	for i := range a.history {
+		a.history[i] = make([]float64, truePeakTaps)
+	}
+}
+
+// truePeak updates the true peak level using an oversampled sample value for
+// the input channel.
+func (a *LoudnessAnalyzer) truePeak(ch int, s float64) {
+	h := a.history[ch]
+	copy(h, h[1:])
+	h[len(h)-1] = s
+
+	for _, phase := range truePeakFilter {
+		var v float64
+		for i, c := range phase {
+			v += c * h[len(h)-1-i]
+		}
+
+		if v = math.Abs(v); v > a.peak {
+			a.peak = v
+		}
+	}
+
+	if s = math.Abs(s); s > a.peak {
+		a.peak = s
+	}
+}
+
+// overThreshold returns the merged ranges of audio in which short-term
+// loudness exceeds the MaxShortTerm value of the standard.
+func (a *LoudnessAnalyzer) overThreshold() []Range {
+	const step = 100 * time.Millisecond
+
+	var ranges []Range
+	for i, e := range blockEnergies(a.subBlocks, shortTermBlocks) {
+		if energyLoudness(e) <= a.std.MaxShortTerm {
+			continue
+		}
+
+		r := Range{
+			Start: time.Duration(i) * step,
+			End:   time.Duration(i+shortTermBlocks) * step,
+		}
+
+		// Merge overlapping ranges
+		if n := len(ranges); n > 0 && r.Start <= ranges[n-1].End {
+			ranges[n-1].End = r.End
+			continue
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+// blockEnergies computes the mean energy of each block of n consecutive
+// sub-blocks, with a step of one sub-block.
+func blockEnergies(subBlocks []float64, n int) []float64 {
+	if len(subBlocks) < n {
+		return nil
+	}
+
+	out := make([]float64, 0, len(subBlocks)-n+1)
+	var sum float64
+	for i, e := range subBlocks {
+		sum += e
+		if i >= n {
+			sum -= subBlocks[i-n]
+		}
+		if i >= n-1 {
+			out = append(out, sum/float64(n))
+		}
+	}
+
+	return out
+}
+
+// gatedLoudness computes the loudness of a set of block energies, applying
+// the absolute gate and a relative gate.
+func gatedLoudness(energies []float64, relative float64) float64 {
+	gated := gate(energies, relative)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyLoudness(mean(gated))
+}
+
+// loudnessRange computes the loudness range of a set of short-term block
+// energies, according to EBU Tech 3342.
+func loudnessRange(energies []float64) float64 {
+	gated := gate(energies, rangeRelativeGate)
+	if len(gated) == 0 {
+		return 0
+	}
+
+	loudness := make([]float64, 0, len(gated))
+	for _, e := range gated {
+		loudness = append(loudness, energyLoudness(e))
+	}
+	sort.Float64s(loudness)
+
+	percentile := func(p float64) float64 {
+		i := int(math.Round(p * float64(len(loudness)-1)))
+		return loudness[i]
+	}
+
+	return percentile(0.95) - percentile(0.10)
+}
+
+// gate returns the block energies which pass the absolute gate, and a gate
+// relative to the loudness of those blocks.
+func gate(energies []float64, relative float64) []float64 {
+	var abs []float64
+	for _, e := range energies {
+		if energyLoudness(e) > absoluteGate {
+			abs = append(abs, e)
+		}
+	}
+	if len(abs) == 0 {
+		return nil
+	}
+
+	threshold := energyLoudness(mean(abs)) + relative
+
+	var rel []float64
+	for _, e := range abs {
+		if energyLoudness(e) > threshold {
+			rel = append(rel, e)
+		}
+	}
+
+	return rel
+}
+
+// energyLoudness converts a mean square energy to loudness in LUFS.
+func energyLoudness(e float64) float64 {
+	if e <= 0 {
+		return math.Inf(-1)
+	}
+
+	return -0.691 + 10*math.Log10(e)
+}
+
+// mean computes the arithmetic mean of a slice of values.
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+// biquad is a second order IIR filter section.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	z1, z2     float64
+}
+
+// process filters a single sample.
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+
+	return y
+}
+
+// kFilter is the two stage K-weighting filter defined by ITU-R BS.1770.
+type kFilter struct {
+	shelf    biquad
+	highpass biquad
+}
+
+// newKFilter computes K-weighting filter coefficients for the input sample
+// rate, so that streams at any sample rate are weighted correctly.
+func newKFilter(rate float64) kFilter {
+	// High shelf, modeling the acoustic effects of the head
+	f0, g, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// High pass, the revised low-frequency B-weighting curve
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+
+	highpass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return kFilter{
+		shelf:    shelf,
+		highpass: highpass,
+	}
+}
+
+// process filters a single sample.
+func (f *kFilter) process(x float64) float64 {
+	return f.highpass.process(f.shelf.process(x))
+}
+
+// truePeakFilter is a polyphase, windowed sinc interpolation filter used to
+// estimate inter-sample peaks by oversampling.
+var truePeakFilter = func() [truePeakFactor][truePeakTaps]float64 {
+	var phases [truePeakFactor][truePeakTaps]float64
+
+	const n = truePeakFactor * truePeakTaps
+	for i := 0; i < n; i++ {
+		// Position relative to the filter center, in input samples
+		t := (float64(i) - float64(n-1)/2) / truePeakFactor
+
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+
+		// Hann window
+		win := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+
+		phases[i%truePeakFactor][i/truePeakFactor] = sinc * win
+	}
+
+	return phases
+}()
@@ -0,0 +1,180 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestLoudnessAnalyzerSine verifies that LoudnessAnalyzer measures a stereo,
+// 1kHz sine wave at -23dBFS as -23LUFS, as specified by EBU Tech 3341.
+func TestLoudnessAnalyzerSine(t *testing.T) {
+	a := NewLoudnessAnalyzer(EBUR128)
+	analyzeSine(t, a, 48000, 2, []sineSegment{{-23, 20 * time.Second}})
+
+	r := a.Report()
+	if math.Abs(r.Integrated-(-23)) > 0.1 {
+		t.Fatalf("unexpected integrated loudness: %v", r.Integrated)
+	}
+	if r.Range > 0.1 {
+		t.Fatalf("unexpected loudness range: %v", r.Range)
+	}
+	if math.Abs(r.TruePeak-(-23)) > 0.1 {
+		t.Fatalf("unexpected true peak: %v", r.TruePeak)
+	}
+	if len(r.OverThreshold) != 0 {
+		t.Fatalf("unexpected over threshold ranges: %v", r.OverThreshold)
+	}
+	if !r.Compliant {
+		t.Fatal("report should be compliant")
+	}
+}
+
+// TestLoudnessAnalyzerOverThreshold verifies that LoudnessAnalyzer reports
+// ranges of audio which are louder than the standard permits.
+func TestLoudnessAnalyzerOverThreshold(t *testing.T) {
+	a := NewLoudnessAnalyzer(EBUR128)
+	analyzeSine(t, a, 48000, 2, []sineSegment{
+		{-30, 10 * time.Second},
+		{-10, 5 * time.Second},
+		{-30, 10 * time.Second},
+	})
+
+	r := a.Report()
+	if len(r.OverThreshold) != 1 {
+		t.Fatalf("unexpected over threshold ranges: %v", r.OverThreshold)
+	}
+
+	// The loud segment must be contained by the reported range
+	over := r.OverThreshold[0]
+	if over.Start > 10*time.Second || over.End < 15*time.Second {
+		t.Fatalf("unexpected over threshold range: %v", over)
+	}
+	if r.Range < 10 {
+		t.Fatalf("unexpected loudness range: %v", r.Range)
+	}
+	if r.Compliant {
+		t.Fatal("report should not be compliant")
+	}
+}
+
+// TestLoudnessReportJSONSilence verifies that a LoudnessReport for silent
+// audio encodes infinite loudness values as null.
+func TestLoudnessReportJSONSilence(t *testing.T) {
+	a := NewLoudnessAnalyzer(ATSCA85)
+	if err := a.Analyze(&Chunk{
+		Samples: make(audio.Float64, 48000),
+		Config:  audio.Config{SampleRate: 48000, Channels: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := a.Report().WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v["integrated_lufs"] != nil || v["true_peak_dbtp"] != nil {
+		t.Fatalf("unexpected loudness values: %v", v)
+	}
+	if v["standard"] != ATSCA85.Name {
+		t.Fatalf("unexpected standard: %v", v["standard"])
+	}
+}
+
+// TestLoudnessAnalyzerWAV verifies that LoudnessAnalyzer shares the compute
+// pass used to generate a waveform.
+func TestLoudnessAnalyzerWAV(t *testing.T) {
+	a := NewLoudnessAnalyzer(EBUR128)
+	w, err := New(bytes.NewReader(wavFile), Analyzers(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := a.Report()
+	if math.IsInf(r.Integrated, 0) || math.IsInf(r.TruePeak, 0) {
+		t.Fatalf("unexpected report for non-silent audio: %+v", r)
+	}
+}
+
+// TestLoudnessAnalyzerDownmix verifies that LoudnessAnalyzer measures the
+// channels of a stream before they are combined by Downmix.
+func TestLoudnessAnalyzerDownmix(t *testing.T) {
+	// A stereo stream with a 1kHz sine wave in only the left channel
+	const rate = 48000
+	samples := make([]int16, 0, rate*2)
+	for i := 0; i < rate; i++ {
+		v := 0.1 * math.Sin(2*math.Pi*1000*float64(i)/rate)
+		samples = append(samples, int16(v*32767), 0)
+	}
+	stream := makeWAV(rate, 2, samples)
+
+	report := func(options ...OptionsFunc) *LoudnessReport {
+		a := NewLoudnessAnalyzer(EBUR128)
+		w, err := New(bytes.NewReader(stream), append(options, Analyzers(a))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Compute(); err != nil {
+			t.Fatal(err)
+		}
+
+		return a.Report()
+	}
+
+	want := report()
+	got := report(Downmix(DownmixAverage))
+	if got.Integrated != want.Integrated || got.TruePeak != want.TruePeak {
+		t.Fatalf("unexpected report for downmixed audio: %+v != %+v", got, want)
+	}
+}
+
+// sineSegment is a segment of a 1kHz sine wave at a level in dBFS.
+type sineSegment struct {
+	level    float64
+	duration time.Duration
+}
+
+// analyzeSine is a test helper which passes segments of a 1kHz sine wave to
+// an Analyzer, one second at a time, with identical samples in each channel.
+func analyzeSine(t *testing.T, a Analyzer, rate int, channels int, segments []sineSegment) {
+	var n, window int
+	for _, s := range segments {
+		amp := math.Pow(10, s.level/20)
+		frames := int(s.duration.Seconds() * float64(rate))
+
+		for f := 0; f < frames; f += rate {
+			samples := make(audio.Float64, 0, rate*channels)
+			for i := 0; i < rate && f+i < frames; i++ {
+				v := amp * math.Sin(2*math.Pi*1000*float64(n)/float64(rate))
+				for c := 0; c < channels; c++ {
+					samples = append(samples, v)
+				}
+				n++
+			}
+
+			if err := a.Analyze(&Chunk{
+				Window:  window,
+				Samples: samples,
+				Config:  audio.Config{SampleRate: rate, Channels: channels},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			window++
+		}
+	}
+}
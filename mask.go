@@ -0,0 +1,126 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+var (
+	// errMaskNil is returned when a nil mask is used in a call to DrawMasked.
+	errMaskNil = errors.New("waveform: mask cannot be nil")
+
+	// errMaskEmpty is returned when a mask or text with no area is used in a
+	// call to DrawMasked or DrawText.
+	errMaskEmpty = errors.New("waveform: mask cannot be empty")
+)
+
+// DrawMasked creates a new image.Image from a slice of float64 values, using
+// the options of the receiving Waveform struct, in which the waveform is
+// visible only where the input mask is opaque.
+func (w *Waveform) DrawMasked(values []float64, mask image.Image) (image.Image, error) {
+	return w.Style().DrawMasked(values, mask)
+}
+
+// DrawText creates a new image.Image from a slice of float64 values, using
+// the options of the receiving Waveform struct, in which the waveform fills
+// the glyphs of the input text.
+func (w *Waveform) DrawText(values []float64, text string) (image.Image, error) {
+	return w.Style().DrawText(values, text)
+}
+
+// DrawMasked creates a new image.Image from a slice of float64 values, using
+// the receiving RenderStyle, in which the waveform is visible only where the
+// input mask is opaque, such as to fill a logo with a waveform.
+//
+// The waveform image, including its background, is drawn as with DrawChecked,
+// and then the alpha channel of the mask is applied to it: pixels where the
+// mask is transparent are transparent, and pixels where the mask is partially
+// transparent are blended.  The mask is stretched to the bounds of the
+// waveform image using nearest neighbor sampling, so masks which have the
+// same size as the image are applied exactly.
+func (s RenderStyle) DrawMasked(values []float64, mask image.Image) (image.Image, error) {
+	if mask == nil {
+		return nil, errMaskNil
+	}
+	if mask.Bounds().Empty() {
+		return nil, errMaskEmpty
+	}
+
+	img, err := s.DrawChecked(values)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	return applyMask(img, scaleMask(mask, b, b)), nil
+}
+
+// DrawText creates a new image.Image from a slice of float64 values, using
+// the receiving RenderStyle, in which the waveform fills the glyphs of the
+// input text, and all other pixels are transparent, such as for a title which
+// is filled with the waveform of a track.
+//
+// Text is drawn in a single line, using the fixed-width font which is
+// embedded in this package, as with LabelLayer.  It is scaled to fill as much
+// of the image as possible without changing its aspect ratio, and centered.
+// Wide images, which are typical of waveforms, suit short text best.
+func (s RenderStyle) DrawText(values []float64, text string) (image.Image, error) {
+	glyphs := LabelStyle{}.mask(text)
+	gb := glyphs.Bounds()
+	if gb.Empty() {
+		return nil, errMaskEmpty
+	}
+
+	img, err := s.DrawChecked(values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scale text to fit within the image, preserving its aspect ratio
+	b := img.Bounds()
+	w, h := b.Dx(), gb.Dy()*b.Dx()/gb.Dx()
+	if h > b.Dy() {
+		w, h = gb.Dx()*b.Dy()/gb.Dy(), b.Dy()
+	}
+
+	at := b.Min.Add(image.Pt((b.Dx()-w)/2, (b.Dy()-h)/2))
+	r := image.Rectangle{Min: at, Max: at.Add(image.Pt(w, h))}
+
+	return applyMask(img, scaleMask(glyphs, b, r)), nil
+}
+
+// applyMask returns a copy of img which is visible only where mask is opaque.
+func applyMask(img image.Image, mask *image.Alpha) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.DrawMask(out, b, img, b.Min, mask, b.Min, draw.Over)
+
+	return out
+}
+
+// scaleMask creates an alpha mask with the input bounds, which is transparent
+// except within r, where the alpha channel of src is drawn, stretched to fill
+// r using nearest neighbor sampling.
+func scaleMask(src image.Image, bounds image.Rectangle, r image.Rectangle) *image.Alpha {
+	dst := image.NewAlpha(bounds)
+
+	// Only pixels of r within bounds are drawn, but src is always stretched
+	// to fill all of r
+	sb, clip := src.Bounds(), r.Intersect(bounds)
+	if clip.Empty() {
+		return dst
+	}
+
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		sy := sb.Min.Y + (y-r.Min.Y)*sb.Dy()/r.Dy()
+		for x := clip.Min.X; x < clip.Max.X; x++ {
+			sx := sb.Min.X + (x-r.Min.X)*sb.Dx()/r.Dx()
+
+			_, _, _, a := src.At(sx, sy).RGBA()
+			dst.Pix[dst.PixOffset(x, y)] = uint8(a >> 8)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,110 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// TestRenderStyleDrawMasked verifies that DrawMasked draws the waveform only
+// where the mask is opaque, stretching the mask to the bounds of the image.
+func TestRenderStyleDrawMasked(t *testing.T) {
+	s, err := NewStyle(Scale(4, 1), Height(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.30, 0.20, 0.05}
+	want := s.Draw(values)
+
+	// A mask whose left half is opaque, at half the size of the image
+	mask := image.NewAlpha(image.Rect(0, 0, 8, 8))
+	draw.Draw(mask, image.Rect(0, 0, 4, 8), image.Opaque, image.Point{}, draw.Src)
+
+	img, err := s.DrawMasked(values, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := img.Bounds()
+	if b != want.Bounds() {
+		t.Fatalf("unexpected bounds: %v != %v", b, want.Bounds())
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.Color(color.Transparent)
+			if x < b.Dx()/2 {
+				c = want.At(x, y)
+			}
+
+			if got := img.At(x, y); !colorsEqual(got, c) {
+				t.Fatalf("unexpected color at (%d, %d): %v != %v", x, y, got, c)
+			}
+		}
+	}
+}
+
+// TestRenderStyleDrawText verifies that DrawText draws the waveform only
+// within the glyphs of text, which is centered within the image.
+func TestRenderStyleDrawText(t *testing.T) {
+	s, err := NewStyle(Scale(8, 1), Height(26), FGColorFunction(SolidColor(red)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	want := s.Draw(values)
+
+	img, err := s.DrawText(values, "HI")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Text is 14x13 pixels, so it is scaled to 28x26 and centered
+	const left, right = 18, 46
+
+	var drawn bool
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			got := img.At(x, y)
+			if _, _, _, a := got.RGBA(); a == 0 {
+				continue
+			}
+
+			if x < left || x >= right {
+				t.Fatalf("unexpected pixel outside of text at (%d, %d): %v", x, y, got)
+			}
+			if !colorsEqual(got, want.At(x, y)) {
+				t.Fatalf("unexpected color at (%d, %d): %v != %v", x, y, got, want.At(x, y))
+			}
+
+			drawn = true
+		}
+	}
+	if !drawn {
+		t.Fatal("no text was drawn")
+	}
+}
+
+// TestRenderStyleDrawMaskedErrors verifies that DrawMasked and DrawText
+// reject empty masks.
+func TestRenderStyleDrawMaskedErrors(t *testing.T) {
+	s, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.5}
+	if _, err := s.DrawMasked(values, nil); err != errMaskNil {
+		t.Fatalf("unexpected error: %v != %v", err, errMaskNil)
+	}
+	if _, err := s.DrawMasked(values, image.NewAlpha(image.Rectangle{})); err != errMaskEmpty {
+		t.Fatalf("unexpected error: %v != %v", err, errMaskEmpty)
+	}
+	if _, err := s.DrawText(values, ""); err != errMaskEmpty {
+		t.Fatalf("unexpected error: %v != %v", err, errMaskEmpty)
+	}
+}
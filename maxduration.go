@@ -0,0 +1,51 @@
+package waveform
+
+import (
+	"fmt"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TooLongError is returned when an input audio stream contains more audio
+// than permitted by the MaxDuration option.
+type TooLongError struct {
+	// Max is the maximum duration of audio which may be decoded.
+	Max time.Duration
+}
+
+// Error returns the string representation of a TooLongError.
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("waveform: audio stream exceeds maximum duration of %v", e.Max)
+}
+
+// maxReader is an audio.Reader which returns a *TooLongError once more than
+// a maximum number of samples have been read from an underlying audio.Reader.
+type maxReader struct {
+	r   audio.Reader
+	max time.Duration
+
+	// remain is the number of samples which may still be read
+	remain int64
+}
+
+// newMaxReader creates a maxReader which reads audio with the input
+// configuration from r, for at most max.
+func newMaxReader(r audio.Reader, config audio.Config, max time.Duration) *maxReader {
+	return &maxReader{
+		r:      r,
+		max:    max,
+		remain: timeSamples(config, max),
+	}
+}
+
+// Read implements audio.Reader.
+func (mr *maxReader) Read(b audio.Slice) (int, error) {
+	n, err := mr.r.Read(b)
+	mr.remain -= int64(n)
+	if mr.remain < 0 {
+		return 0, &TooLongError{Max: mr.max}
+	}
+
+	return n, err
+}
@@ -0,0 +1,80 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestMaxDuration verifies that computation stops with a *TooLongError once
+// more audio than the maximum duration has been read.
+func TestMaxDuration(t *testing.T) {
+	// Three seconds of mono audio at 8Hz
+	wav := makeWAV(8, 1, make([]int16, 24))
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		ok      bool
+	}{
+		{
+			name:    "under",
+			options: []OptionsFunc{MaxDuration(4 * time.Second)},
+			ok:      true,
+		},
+		{
+			name:    "exact",
+			options: []OptionsFunc{MaxDuration(3 * time.Second)},
+			ok:      true,
+		},
+		{
+			name:    "over",
+			options: []OptionsFunc{MaxDuration(2 * time.Second)},
+		},
+		{
+			// Audio skipped by Offset still counts towards the limit
+			name:    "offset",
+			options: []OptionsFunc{MaxDuration(2 * time.Second), Offset(2 * time.Second)},
+		},
+		{
+			// Decoding stops before the limit is reached
+			name:    "duration",
+			options: []OptionsFunc{MaxDuration(2 * time.Second), Duration(time.Second)},
+			ok:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(bytes.NewReader(wav), tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = w.Compute()
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var tErr *TooLongError
+			if !errors.As(err, &tErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tErr.Max != 2*time.Second {
+				t.Fatalf("unexpected maximum duration: %v", tErr.Max)
+			}
+		})
+	}
+}
+
+// TestMaxDurationOptions verifies that MaxDuration rejects durations which are
+// not positive.
+func TestMaxDurationOptions(t *testing.T) {
+	testWaveformOptionFunc(t, MaxDuration(0), errMaxDurationZero)
+	testWaveformOptionFunc(t, MaxDuration(-time.Second), errMaxDurationNegative)
+	testWaveformOptionFunc(t, MaxDuration(time.Second), nil)
+}
@@ -0,0 +1,205 @@
+package waveform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// errPNGTextKeyword is returned when a text metadata key in PNGOptions is not
+// a valid PNG keyword.
+var errPNGTextKeyword = errors.New("waveform: PNG text keys must be 1-79 printable ASCII characters without leading, trailing, or consecutive spaces")
+
+// modulePath is the import path of the waveform module, used to find its
+// version in build information.
+const modulePath = "github.com/mdlayher/waveform"
+
+// Keys used by RenderInfo.Text for each item of render metadata.
+const (
+	TextKeyDuration    = "waveform:duration"
+	TextKeyOptionsHash = "waveform:options-hash"
+	TextKeySourceHash  = "waveform:source-hash"
+	TextKeyVersion     = "waveform:version"
+)
+
+// RenderInfo describes how a waveform image was produced, so that it may be
+// embedded in the image as metadata using the Text field of PNGOptions.
+// Downstream systems can use it to trace the origin of an asset, and to
+// invalidate cached assets precisely when their source audio, options, or the
+// waveform package itself change.
+type RenderInfo struct {
+	// Duration is the duration of the audio from which the waveform was
+	// computed, such as the Duration of a ValueSet.
+	Duration time.Duration
+
+	// OptionsHash identifies the options used to produce the image.  Options
+	// may contain functions, which cannot be hashed, so the hash is supplied
+	// by the application: typically the render key or cache key which it
+	// already uses to identify an image.
+	OptionsHash string
+
+	// SourceHash identifies the content of the source audio, such as a hash
+	// computed by HashSource.
+	SourceHash string
+
+	// Version is the version of the waveform package which produced the
+	// image, such as the version reported by PackageVersion.
+	Version string
+}
+
+// Text returns the text metadata for the receiving RenderInfo, suitable for
+// use as the Text field of PNGOptions.  Empty fields are omitted, and the
+// duration is formatted as by time.Duration.String, so that it may be parsed
+// using time.ParseDuration.
+func (ri RenderInfo) Text() map[string]string {
+	text := make(map[string]string, 4)
+	if ri.Duration != 0 {
+		text[TextKeyDuration] = ri.Duration.String()
+	}
+
+	for k, v := range map[string]string{
+		TextKeyOptionsHash: ri.OptionsHash,
+		TextKeySourceHash:  ri.SourceHash,
+		TextKeyVersion:     ri.Version,
+	} {
+		if v != "" {
+			text[k] = v
+		}
+	}
+
+	return text
+}
+
+// HashSource reads an input audio stream to its end, and returns the
+// hex-encoded SHA-256 hash of its content, for use as the SourceHash of a
+// RenderInfo.
+func HashSource(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// PackageVersion returns the module version of the waveform package which is
+// built into the running binary, for use as the Version of a RenderInfo.  If
+// the version is unknown, such as when the package is built from a local
+// checkout, "(devel)" is returned.
+func PackageVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	for _, m := range info.Deps {
+		if m.Path != modulePath {
+			continue
+		}
+
+		// Report the version of a replacement module, if any
+		if m.Replace != nil && m.Replace.Version != "" {
+			return m.Replace.Version
+		}
+
+		return m.Version
+	}
+
+	return "(devel)"
+}
+
+// pngIHDREnd is the offset of the end of the IHDR chunk in an encoded PNG
+// image, which always immediately follows the 8 byte signature and is 25
+// bytes long.
+const pngIHDREnd = 8 + 25
+
+// addPNGText inserts text metadata chunks into an encoded PNG image, directly
+// after its IHDR chunk, in order of their keys.  Values which contain only
+// ASCII characters are stored in tEXt chunks, and all others are stored as
+// UTF-8 in iTXt chunks.
+func addPNGText(img []byte, text map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(text))
+	for k := range text {
+		if !validPNGKeyword(k) {
+			return nil, errPNGTextKeyword
+		}
+
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var chunks bytes.Buffer
+	for _, k := range keys {
+		v := text[k]
+
+		if isASCII(v) {
+			writePNGChunk(&chunks, "tEXt", k+"\x00"+v)
+			continue
+		}
+
+		// Uncompressed, with empty language and translated keyword fields
+		writePNGChunk(&chunks, "iTXt", k+"\x00\x00\x00\x00\x00"+v)
+	}
+
+	out := make([]byte, 0, len(img)+chunks.Len())
+	out = append(out, img[:pngIHDREnd]...)
+	out = append(out, chunks.Bytes()...)
+
+	return append(out, img[pngIHDREnd:]...), nil
+}
+
+// writePNGChunk writes a single PNG chunk with the input type and data to b.
+func writePNGChunk(b *bytes.Buffer, typ string, data string) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	b.Write(n[:])
+
+	crc := crc32.NewIEEE()
+	_, _ = io.WriteString(crc, typ)
+	_, _ = io.WriteString(crc, data)
+
+	b.WriteString(typ)
+	b.WriteString(data)
+
+	binary.BigEndian.PutUint32(n[:], crc.Sum32())
+	b.Write(n[:])
+}
+
+// validPNGKeyword reports whether k is a valid keyword for a PNG text chunk.
+func validPNGKeyword(k string) bool {
+	if len(k) == 0 || len(k) > 79 || k[0] == ' ' || k[len(k)-1] == ' ' {
+		return false
+	}
+
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if c < 0x20 || c > 0x7e || (c == ' ' && k[i-1] == ' ') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isASCII reports whether s contains only printable ASCII characters and
+// newlines.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '\n' && (c < 0x20 || c > 0x7e) {
+			return false
+		}
+	}
+
+	return true
+}
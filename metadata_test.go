@@ -0,0 +1,127 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEncodePNGText verifies that EncodePNG embeds text metadata in valid PNG
+// text chunks, which do not prevent the image from being decoded.
+func TestEncodePNGText(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw([]float64{0.10, 0.20, 0.30})
+
+	text := RenderInfo{
+		Duration:    1500 * time.Millisecond,
+		OptionsHash: "abc123",
+		SourceHash:  "def456",
+		Version:     "v1.2.3",
+	}.Text()
+	text["Title"] = "Café"
+
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img, &PNGOptions{Text: text}); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := readPNGChunks(t, buf.Bytes())
+
+	// Text chunks follow the header, in order of their keys
+	want := []string{
+		"IHDR",
+		"iTXt Title\x00\x00\x00\x00\x00Café",
+		"tEXt waveform:duration\x001.5s",
+		"tEXt waveform:options-hash\x00abc123",
+		"tEXt waveform:source-hash\x00def456",
+		"tEXt waveform:version\x00v1.2.3",
+	}
+	for i, w := range want {
+		if chunks[i] != w {
+			t.Fatalf("[%d] unexpected chunk: %q != %q", i, chunks[i], w)
+		}
+	}
+
+	if _, err := png.Decode(&buf); err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+}
+
+// TestEncodePNGTextInvalidKeyword verifies that EncodePNG rejects text
+// metadata keys which are not valid PNG keywords.
+func TestEncodePNGTextInvalidKeyword(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw([]float64{0.10})
+
+	for _, k := range []string{"", " lead", "trail ", "two  spaces", "nul\x00", "Café", strings.Repeat("k", 80)} {
+		err := EncodePNG(&bytes.Buffer{}, img, &PNGOptions{Text: map[string]string{k: "v"}})
+		if err != errPNGTextKeyword {
+			t.Fatalf("%q: unexpected error: %v", k, err)
+		}
+	}
+}
+
+// TestRenderInfoTextEmpty verifies that RenderInfo.Text omits empty fields.
+func TestRenderInfoTextEmpty(t *testing.T) {
+	text := RenderInfo{SourceHash: "def456"}.Text()
+	if len(text) != 1 || text[TextKeySourceHash] != "def456" {
+		t.Fatalf("unexpected text: %v", text)
+	}
+}
+
+// TestHashSource verifies that HashSource produces the SHA-256 hash of its
+// input.
+func TestHashSource(t *testing.T) {
+	h, err := HashSource(strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if h != want {
+		t.Fatalf("unexpected hash: %v != %v", h, want)
+	}
+}
+
+// TestPackageVersion verifies that PackageVersion always reports a version.
+func TestPackageVersion(t *testing.T) {
+	if v := PackageVersion(); v == "" {
+		t.Fatal("empty package version")
+	}
+}
+
+// readPNGChunks returns the type of each chunk in an encoded PNG image, and
+// the data of each text chunk, verifying the checksum of every chunk.
+func readPNGChunks(t *testing.T, b []byte) []string {
+	t.Helper()
+
+	var chunks []string
+	for b = b[8:]; len(b) > 0; {
+		n := binary.BigEndian.Uint32(b[:4])
+		typ, data := string(b[4:8]), b[8:8+n]
+
+		sum := crc32.ChecksumIEEE(b[4 : 8+n])
+		if got := binary.BigEndian.Uint32(b[8+n : 12+n]); got != sum {
+			t.Fatalf("%s: unexpected checksum: %08x != %08x", typ, got, sum)
+		}
+
+		if typ == "tEXt" || typ == "iTXt" {
+			typ += " " + string(data)
+		}
+		chunks = append(chunks, typ)
+
+		b = b[12+n:]
+	}
+
+	return chunks
+}
@@ -0,0 +1,510 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"azul3d.org/engine/audio"
+)
+
+const (
+	// maxMP4MoovSize is the maximum size of the moov box of an MP4 stream, which
+	// limits the memory allocated for malformed streams.
+	maxMP4MoovSize = 1 << 26
+
+	// maxMP4SampleSize is the maximum size of a single sample of an MP4 stream.
+	maxMP4SampleSize = 1 << 24
+
+	// maxMP4MdatSize is the maximum size of an mdat box which precedes the moov
+	// box of an MP4 stream, and must be kept in memory until the moov box is
+	// read.  This limits the memory allocated for large or malformed streams.
+	maxMP4MdatSize = 1 << 27
+)
+
+// mp4Track is the information needed to read the samples of an audio track
+// from an MP4 stream.
+type mp4Track struct {
+	// format is the type of the sample entry which describes the track, such
+	// as "alac" or "mp4a", and entry is its contents
+	format string
+	entry  []byte
+
+	// offsets and sizes are the position within the stream and size of each
+	// sample, in the order they are decoded
+	offsets []int64
+	sizes   []int64
+}
+
+// mp4Reader reads the samples of an audio track from an MP4 stream, such as an
+// M4A file.
+//
+// The moov box, which describes the track, is read first.  If the mdat box,
+// which contains the samples, precedes it in the stream, the mdat box is kept
+// in memory, and streams whose mdat box is larger than maxMP4MdatSize are
+// rejected; otherwise, samples are read from the stream as they are needed.
+type mp4Reader struct {
+	r     *bufio.Reader
+	pos   int64
+	track *mp4Track
+
+	// mdat and mdatPos are the contents and position of an mdat box which
+	// preceded the moov box
+	mdat    []byte
+	mdatPos int64
+
+	// next is the index of the next sample to be read
+	next int
+}
+
+// newMP4Reader reads the boxes of an MP4 stream until the first audio track is
+// found.  If the stream contains no audio track, audio.ErrFormat is returned.
+func newMP4Reader(r io.Reader) (*mp4Reader, error) {
+	mr := &mp4Reader{r: bufio.NewReader(r)}
+
+	for {
+		typ, size, err := mr.readBoxHeader()
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case "moov":
+			if size < 0 || size > maxMP4MoovSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			moov := make([]byte, size)
+			if err := mr.readFull(moov); err != nil {
+				return nil, err
+			}
+
+			track, err := parseMP4Moov(moov)
+			if err != nil {
+				return nil, err
+			}
+
+			mr.track = track
+			return mr, nil
+		case "mdat":
+			if mr.mdat != nil {
+				return nil, audio.ErrInvalidData
+			}
+
+			// The samples cannot be located until the moov box is read, so
+			// they are kept in memory, up to a limit
+			if size > maxMP4MdatSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			mr.mdatPos = mr.pos
+			if size < 0 {
+				mr.mdat, err = ioutil.ReadAll(io.LimitReader(mr.r, maxMP4MdatSize+1))
+				if err == nil && len(mr.mdat) > maxMP4MdatSize {
+					err = audio.ErrInvalidData
+				}
+			} else {
+				mr.mdat, err = ioutil.ReadAll(io.LimitReader(mr.r, size))
+				if err == nil && int64(len(mr.mdat)) != size {
+					err = audio.ErrUnexpectedEOS
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			mr.pos += int64(len(mr.mdat))
+		default:
+			// The moov box must appear before the end of the stream
+			if size < 0 {
+				return nil, audio.ErrInvalidData
+			}
+			if err := mr.skip(size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readSample reads the next sample of the track into a buffer, returning
+// audio.EOS when no samples remain.
+func (mr *mp4Reader) readSample(buf []byte) ([]byte, error) {
+	if mr.next >= len(mr.track.offsets) {
+		return nil, audio.EOS
+	}
+
+	offset, size := mr.track.offsets[mr.next], mr.track.sizes[mr.next]
+	mr.next++
+
+	if cap(buf) < int(size) {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+
+	// Samples in an mdat box which preceded the moov box are in memory
+	if mr.mdat != nil {
+		start := offset - mr.mdatPos
+		if start < 0 || start+size > int64(len(mr.mdat)) {
+			return nil, audio.ErrInvalidData
+		}
+
+		copy(buf, mr.mdat[start:start+size])
+		return buf, nil
+	}
+
+	// Otherwise, samples must appear in order in the remainder of the stream
+	if offset < mr.pos {
+		return nil, audio.ErrInvalidData
+	}
+	if err := mr.skip(offset - mr.pos); err != nil {
+		return nil, err
+	}
+	if err := mr.readFull(buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// readBoxHeader reads the header of a box, returning its type and the size of
+// its contents.  A size of -1 indicates that the box extends to the end of the
+// stream.
+func (mr *mp4Reader) readBoxHeader() (string, int64, error) {
+	var hdr [8]byte
+	if err := mr.readFull(hdr[:]); err != nil {
+		return "", 0, err
+	}
+
+	typ := string(hdr[4:8])
+	size := int64(binary.BigEndian.Uint32(hdr[0:4]))
+	switch size {
+	case 0:
+		return typ, -1, nil
+	case 1:
+		// 64-bit size follows the box type
+		var large [8]byte
+		if err := mr.readFull(large[:]); err != nil {
+			return "", 0, err
+		}
+
+		size = int64(binary.BigEndian.Uint64(large[:]))
+		if size < 16 {
+			return "", 0, audio.ErrInvalidData
+		}
+
+		return typ, size - 16, nil
+	default:
+		if size < 8 {
+			return "", 0, audio.ErrInvalidData
+		}
+
+		return typ, size - 8, nil
+	}
+}
+
+// readFull reads exactly len(b) bytes from the stream.
+func (mr *mp4Reader) readFull(b []byte) error {
+	n, err := io.ReadFull(mr.r, b)
+	mr.pos += int64(n)
+	if err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	return nil
+}
+
+// skip discards n bytes from the stream.
+func (mr *mp4Reader) skip(n int64) error {
+	m, err := io.CopyN(ioutil.Discard, mr.r, n)
+	mr.pos += m
+	if err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	return nil
+}
+
+// mp4Boxes iterates over the boxes contained in b, calling fn with the type and
+// contents of each.  Iteration stops if fn returns false.
+func mp4Boxes(b []byte, fn func(typ string, box []byte) bool) error {
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return audio.ErrInvalidData
+		}
+
+		size := uint64(binary.BigEndian.Uint32(b[0:4]))
+		typ := string(b[4:8])
+		hdr := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return audio.ErrInvalidData
+			}
+
+			size = binary.BigEndian.Uint64(b[8:16])
+			hdr = 16
+		}
+		if size < hdr || size > uint64(len(b)) {
+			return audio.ErrInvalidData
+		}
+
+		if !fn(typ, b[hdr:size]) {
+			return nil
+		}
+
+		b = b[size:]
+	}
+
+	return nil
+}
+
+// mp4Child returns the contents of the first box of the input type contained
+// in b, or nil if none exists.
+func mp4Child(b []byte, typ string) ([]byte, error) {
+	var child []byte
+	err := mp4Boxes(b, func(t string, box []byte) bool {
+		if t != typ {
+			return true
+		}
+
+		child = box
+		return false
+	})
+
+	return child, err
+}
+
+// mp4Path returns the contents of the box found by following the input path of
+// box types from b, or nil if none exists.
+func mp4Path(b []byte, path ...string) ([]byte, error) {
+	for _, typ := range path {
+		var err error
+		b, err = mp4Child(b, typ)
+		if err != nil || b == nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// parseMP4Moov parses the contents of a moov box, returning the first audio
+// track it describes.
+func parseMP4Moov(moov []byte) (*mp4Track, error) {
+	var (
+		track *mp4Track
+		terr  error
+	)
+
+	err := mp4Boxes(moov, func(typ string, trak []byte) bool {
+		if typ != "trak" {
+			return true
+		}
+
+		// Only audio tracks are considered
+		hdlr, err := mp4Path(trak, "mdia", "hdlr")
+		if err != nil {
+			terr = err
+			return false
+		}
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			return true
+		}
+
+		stbl, err := mp4Path(trak, "mdia", "minf", "stbl")
+		if err != nil || stbl == nil {
+			terr = audio.ErrInvalidData
+			return false
+		}
+
+		track, terr = parseMP4Stbl(stbl)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if terr != nil {
+		return nil, terr
+	}
+	if track == nil {
+		return nil, audio.ErrFormat
+	}
+
+	return track, nil
+}
+
+// parseMP4Stbl parses the contents of an stbl box, which describes the format
+// and location of the samples of a track.
+func parseMP4Stbl(stbl []byte) (*mp4Track, error) {
+	boxes := make(map[string][]byte)
+	err := mp4Boxes(stbl, func(typ string, box []byte) bool {
+		if _, ok := boxes[typ]; !ok {
+			boxes[typ] = box
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The first sample entry describes the format of the track
+	stsd := boxes["stsd"]
+	if len(stsd) < 16 {
+		return nil, audio.ErrInvalidData
+	}
+	entries := stsd[8:]
+	size := binary.BigEndian.Uint32(entries[0:4])
+	if size < 8 || uint64(size) > uint64(len(entries)) {
+		return nil, audio.ErrInvalidData
+	}
+
+	t := &mp4Track{
+		format: string(entries[4:8]),
+		entry:  entries[8:size],
+	}
+
+	sizes, err := parseMP4Stsz(boxes["stsz"])
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := parseMP4Chunks(boxes["stco"], boxes["co64"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Each sample follows the previous sample in its chunk
+	t.offsets = make([]int64, 0, len(sizes))
+	t.sizes = sizes
+	err = mp4SamplesPerChunk(boxes["stsc"], len(chunks), func(chunk int, samples int) error {
+		offset := chunks[chunk]
+		for i := 0; i < samples; i++ {
+			n := len(t.offsets)
+			if n >= len(sizes) {
+				return audio.ErrInvalidData
+			}
+
+			t.offsets = append(t.offsets, offset)
+			offset += sizes[n]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(t.offsets) != len(sizes) {
+		return nil, audio.ErrInvalidData
+	}
+
+	return t, nil
+}
+
+// parseMP4Stsz parses the contents of an stsz box, which contains the size of
+// each sample of a track.
+func parseMP4Stsz(stsz []byte) ([]int64, error) {
+	if len(stsz) < 12 {
+		return nil, audio.ErrInvalidData
+	}
+
+	size := int64(binary.BigEndian.Uint32(stsz[4:8]))
+	count := int64(binary.BigEndian.Uint32(stsz[8:12]))
+	if size > maxMP4SampleSize {
+		return nil, audio.ErrInvalidData
+	}
+
+	// All samples may have the same size, or each sample size is listed
+	if size == 0 && count > int64(len(stsz)-12)/4 {
+		return nil, audio.ErrInvalidData
+	}
+	if size != 0 && count > maxMP4MoovSize {
+		return nil, audio.ErrInvalidData
+	}
+
+	sizes := make([]int64, count)
+	for i := range sizes {
+		if size != 0 {
+			sizes[i] = size
+			continue
+		}
+
+		sizes[i] = int64(binary.BigEndian.Uint32(stsz[12+4*i:]))
+		if sizes[i] > maxMP4SampleSize {
+			return nil, audio.ErrInvalidData
+		}
+	}
+
+	return sizes, nil
+}
+
+// parseMP4Chunks parses the contents of an stco or co64 box, which contain the
+// position within the stream of each chunk of samples of a track.
+func parseMP4Chunks(stco []byte, co64 []byte) ([]int64, error) {
+	b, width := stco, 4
+	if b == nil {
+		b, width = co64, 8
+	}
+	if len(b) < 8 {
+		return nil, audio.ErrInvalidData
+	}
+
+	count := int64(binary.BigEndian.Uint32(b[4:8]))
+	if count > int64(len(b)-8)/int64(width) {
+		return nil, audio.ErrInvalidData
+	}
+
+	offsets := make([]int64, count)
+	for i := range offsets {
+		p := b[8+width*i:]
+		if width == 4 {
+			offsets[i] = int64(binary.BigEndian.Uint32(p))
+			continue
+		}
+
+		offsets[i] = int64(binary.BigEndian.Uint64(p))
+		if offsets[i] < 0 {
+			return nil, audio.ErrInvalidData
+		}
+	}
+
+	return offsets, nil
+}
+
+// mp4SamplesPerChunk parses the contents of an stsc box, which describes the
+// number of samples in each chunk of a track, calling fn with the index of
+// each chunk and its number of samples.
+func mp4SamplesPerChunk(stsc []byte, chunks int, fn func(chunk int, samples int) error) error {
+	if len(stsc) < 8 {
+		return audio.ErrInvalidData
+	}
+
+	count := int(binary.BigEndian.Uint32(stsc[4:8]))
+	if count > (len(stsc)-8)/12 {
+		return audio.ErrInvalidData
+	}
+
+	for i := 0; i < count; i++ {
+		e := stsc[8+12*i:]
+		first := int64(binary.BigEndian.Uint32(e[0:4])) - 1
+		samples := int(binary.BigEndian.Uint32(e[4:8]))
+
+		// Each entry applies until the first chunk of the next entry
+		last := int64(chunks)
+		if i+1 < count {
+			last = int64(binary.BigEndian.Uint32(stsc[8+12*(i+1):])) - 1
+		}
+		if first < 0 || last > int64(chunks) || samples > maxMP4MoovSize {
+			return audio.ErrInvalidData
+		}
+
+		for c := first; c < last; c++ {
+			if err := fn(int(c), samples); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestMP4ReaderSamples verifies that mp4Reader reads each sample of a track,
+// whether the mdat box precedes or follows the moov box.
+func TestMP4ReaderSamples(t *testing.T) {
+	samples := [][]byte{
+		[]byte("first"),
+		[]byte("second sample"),
+		[]byte("3"),
+	}
+
+	for _, mdatFirst := range []bool{false, true} {
+		mr, err := newMP4Reader(bytes.NewReader(makeMP4("test", nil, samples, 2, mdatFirst)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mr.track.format != "test" {
+			t.Fatalf("unexpected format: %q", mr.track.format)
+		}
+
+		for i, want := range samples {
+			got, err := mr.readSample(nil)
+			if err != nil {
+				t.Fatalf("[%02d] unexpected error: %v", i, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("[%02d] unexpected sample: %q != %q", i, got, want)
+			}
+		}
+
+		if _, err := mr.readSample(nil); err != audio.EOS {
+			t.Fatalf("unexpected error: %v != %v", err, audio.EOS)
+		}
+	}
+}
+
+// TestMP4ReaderErrors verifies that malformed MP4 streams produce appropriate
+// errors.
+func TestMP4ReaderErrors(t *testing.T) {
+	valid := makeMP4("test", nil, [][]byte{[]byte("sample")}, 1, false)
+
+	// Box whose size is smaller than its header
+	short := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(short[0:4], 4)
+
+	// Stream which ends before the moov box
+	noMoov := mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+
+	// moov box with no audio track
+	noAudio := append(mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4Box("moov")...)
+
+	// mdat box preceding the moov box, with a huge declared 64-bit size
+	hugeMdat := append(mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")), 0, 0, 0, 1)
+	hugeMdat = append(hugeMdat, "mdat"...)
+	hugeMdat = append(hugeMdat, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00)
+	hugeMdat = append(hugeMdat, "sample"...)
+
+	var tests = []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{name: "short box", b: short, err: audio.ErrInvalidData},
+		{name: "truncated", b: valid[:20], err: audio.ErrUnexpectedEOS},
+		{name: "no moov", b: noMoov, err: audio.ErrUnexpectedEOS},
+		{name: "no audio", b: noAudio, err: audio.ErrFormat},
+		{name: "huge mdat", b: hugeMdat, err: audio.ErrInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMP4Reader(bytes.NewReader(tt.b)); err != tt.err {
+				t.Fatalf("unexpected error: %v != %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// makeMP4 is a test helper which creates an MP4 stream containing a single
+// audio track.  The sample entry of the track has the input format, and
+// contains the input child boxes.  Samples are stored in chunks of the input
+// number of samples, in an mdat box which precedes or follows the moov box.
+func makeMP4(format string, children []byte, samples [][]byte, perChunk int, mdatFirst bool) []byte {
+	ftyp := mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00M4A mp42"))
+
+	var mdat []byte
+	for _, s := range samples {
+		mdat = append(mdat, s...)
+	}
+
+	// Audio sample entry, version 0: reserved, data reference index, version,
+	// revision, vendor, channels, sample size, compression ID, packet size,
+	// and sample rate
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[6:8], 1)
+	entry = append(entry, children...)
+
+	stsd := append(mp4Uint32(0, 1), mp4Box(format, entry)...)
+	stsz := mp4Uint32(0, 0, uint32(len(samples)))
+	for _, s := range samples {
+		stsz = append(stsz, mp4Uint32(uint32(len(s)))...)
+	}
+
+	// The final chunk may contain fewer samples than all others
+	full, rem := len(samples)/perChunk, len(samples)%perChunk
+	stsc := mp4Uint32(0, 1, 1, uint32(perChunk), 1)
+	if rem > 0 {
+		stsc = mp4Uint32(0, 2, 1, uint32(perChunk), 1, uint32(full+1), uint32(rem), 1)
+	}
+
+	moov := func(offset int) []byte {
+		// Chunks are stored contiguously, beginning at offset
+		var chunks []uint32
+		for i := 0; i < len(samples); i += perChunk {
+			chunks = append(chunks, uint32(offset))
+			for j := i; j < i+perChunk && j < len(samples); j++ {
+				offset += len(samples[j])
+			}
+		}
+		stco := append(mp4Uint32(0, uint32(len(chunks))), mp4Uint32(chunks...)...)
+
+		hdlr := append(mp4Uint32(0, 0), []byte("soun\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")...)
+		stbl := mp4Box("stbl",
+			mp4Box("stsd", stsd),
+			mp4Box("stsz", stsz),
+			mp4Box("stsc", stsc),
+			mp4Box("stco", stco),
+		)
+
+		return mp4Box("moov", mp4Box("trak", mp4Box("mdia",
+			mp4Box("hdlr", hdlr),
+			mp4Box("minf", stbl),
+		)))
+	}
+
+	if mdatFirst {
+		return bytes.Join([][]byte{ftyp, mp4Box("mdat", mdat), moov(len(ftyp) + 8)}, nil)
+	}
+
+	size := len(moov(0))
+	return bytes.Join([][]byte{ftyp, moov(len(ftyp) + size + 8), mp4Box("mdat", mdat)}, nil)
+}
+
+// mp4Box is a test helper which creates an MP4 box from its type and contents.
+func mp4Box(typ string, contents ...[]byte) []byte {
+	b := bytes.Join(contents, nil)
+	return append(append(mp4Uint32(uint32(8+len(b))), typ...), b...)
+}
+
+// mp4Uint32 is a test helper which encodes big-endian 32-bit integers.
+func mp4Uint32(vs ...uint32) []byte {
+	b := make([]byte, 4*len(vs))
+	for i, v := range vs {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+
+	return b
+}
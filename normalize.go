@@ -0,0 +1,57 @@
+package waveform
+
+import "math"
+
+// DefaultNormalizeTarget is the value to which the maximum of a set of values
+// is scaled by Normalize, when a target of 0 is used.
+const DefaultNormalizeTarget = 1.0
+
+// Normalize returns a copy of a slice of computed values, rescaled so that
+// the maximum value is equal to target, such as for comparing the shapes of
+// waveforms computed from quiet and loud audio.  If target is 0,
+// DefaultNormalizeTarget is used.
+//
+// NaN values are copied unchanged.  If no value is greater than 0, the values
+// cannot be rescaled, and an unchanged copy is returned.
+func Normalize(values []float64, target float64) []float64 {
+	out := make([]float64, len(values))
+	copy(out, values)
+
+	f := normalizeFactor(values, target)
+	if f == 0 {
+		return out
+	}
+
+	for i := range out {
+		out[i] *= f
+	}
+
+	return out
+}
+
+// Normalize rescales the values of the ValueSet in place, so that the maximum
+// value is equal to target, in the same way as the Normalize function.  Counts
+// and durations are unchanged.
+func (vs *ValueSet) Normalize(target float64) {
+	vs.Values = Normalize(vs.Values, target)
+}
+
+// normalizeFactor returns the factor by which values are multiplied so that
+// their maximum is equal to target, or 0 if no value is greater than 0.
+func normalizeFactor(values []float64, target float64) float64 {
+	if target == 0 {
+		target = DefaultNormalizeTarget
+	}
+
+	var max float64
+	for _, v := range values {
+		if !math.IsInf(v, 1) && v > max {
+			max = v
+		}
+	}
+	if max == 0 {
+		return 0
+	}
+
+	return target / max
+}
@@ -0,0 +1,87 @@
+package waveform
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestNormalize verifies that Normalize rescales values so that their maximum
+// is equal to the target.
+func TestNormalize(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		target float64
+		want   []float64
+	}{
+		{nil, 0, []float64{}},
+		{[]float64{0, 0}, 1, []float64{0, 0}},
+		{[]float64{0.25, 0.5}, 0, []float64{0.5, 1}},
+		{[]float64{0.25, 0.5}, 0.5, []float64{0.25, 0.5}},
+		{[]float64{0.1, 0.4, 0.2}, 0.8, []float64{0.2, 0.8, 0.4}},
+		{[]float64{math.NaN(), 0.5}, 1, []float64{math.NaN(), 1}},
+	}
+
+	for i, test := range tests {
+		got := Normalize(test.values, test.target)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Fatalf("[%02d] unexpected values:\n- want: %v\n-  got: %v", i, test.want, got)
+		}
+	}
+}
+
+// TestNormalizeCopies verifies that Normalize does not modify its input.
+func TestNormalizeCopies(t *testing.T) {
+	values := []float64{0.25, 0.5}
+	Normalize(values, 1)
+
+	if got := fmt.Sprint(values); got != "[0.25 0.5]" {
+		t.Fatalf("input values were modified: %v", got)
+	}
+}
+
+// TestValueSetNormalize verifies that ValueSet.Normalize rescales values in
+// place.
+func TestValueSetNormalize(t *testing.T) {
+	vs := &ValueSet{Values: []float64{0.2, 0.1}}
+	vs.Normalize(0)
+
+	if got := fmt.Sprint(vs.Values); got != "[1 0.5]" {
+		t.Fatalf("unexpected values: %v", got)
+	}
+}
+
+// TestNormalizedDraw verifies that the Normalized option draws the maximum
+// value at the target fraction of the height of the image, regardless of its
+// magnitude.
+func TestNormalizedDraw(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		target float64
+		height []int
+	}{
+		{[]float64{0.05, 0.1}, 1, []int{8, 16}},
+		{[]float64{0.5, 1.0}, 1, []int{8, 16}},
+		{[]float64{0.05, 0.1}, 0.5, []int{4, 8}},
+		// Values which cannot be normalized use the default scale
+		{[]float64{0, 0}, 1, []int{0, 0}},
+	}
+
+	for i, test := range tests {
+		s, err := NewStyle(Height(16), Normalized(test.target))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := s.Columns(test.values)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for n := 0; it.Next(); n++ {
+			if h := it.Column().Height; h != test.height[n] {
+				t.Fatalf("[%02d] unexpected height of column %d: %d != %d", i, n, h, test.height[n])
+			}
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package waveform
 
-import "fmt"
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"time"
+)
 
 var (
 	// errBGColorFunctionNil is returned when a nil ColorFunc is used in
@@ -8,6 +14,15 @@ var (
 	errBGColorFunctionNil = &OptionsError{
 		Option: "bgColorFunction",
 		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errOnColumnNil is returned when a nil ColumnFunc is used in a call to
+	// OnColumn.
+	errOnColumnNil = &OptionsError{
+		Option: "onColumn",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
 	}
 
 	// errFGColorFunctionNil is returned when a nil ColorFunc is used in
@@ -15,6 +30,7 @@ var (
 	errFGColorFunctionNil = &OptionsError{
 		Option: "fgColorFunction",
 		Reason: "function cannot be nil",
+		Code:   CodeNil,
 	}
 
 	// errSampleFunctionNil is returned when a nil SampleReduceFunc is used in
@@ -22,6 +38,23 @@ var (
 	errSampleFunctionNil = &OptionsError{
 		Option: "sampleFunction",
 		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errChunkFunctionNil is returned when a nil ChunkReduceFunc is used in
+	// a call to ChunkFunction.
+	errChunkFunctionNil = &OptionsError{
+		Option: "chunkFunction",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errChunkFunctionConflictsSampleFunction is returned when ChunkFunction
+	// is used with SampleFunction, because only one may reduce each window.
+	errChunkFunctionConflictsSampleFunction = &OptionsError{
+		Option: "chunkFunction",
+		Reason: "chunkFunction cannot be used with sampleFunction",
+		Code:   CodeConflict,
 	}
 
 	// errResolutionZero is returned when integer 0 is used in a call
@@ -29,6 +62,8 @@ var (
 	errResolutionZero = &OptionsError{
 		Option: "resolution",
 		Reason: "resolution cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
 	}
 
 	// errScaleXZero is returned when integer 0 is used as the X value
@@ -36,6 +71,8 @@ var (
 	errScaleXZero = &OptionsError{
 		Option: "scale",
 		Reason: "X scale cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
 	}
 
 	// errScaleYZero is returned when integer 0 is used as the Y value
@@ -43,14 +80,426 @@ var (
 	errScaleYZero = &OptionsError{
 		Option: "scale",
 		Reason: "Y scale cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errHeightZero is returned when integer 0 is used in a call to Height.
+	errHeightZero = &OptionsError{
+		Option: "height",
+		Reason: "height cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errHeadroomInvalid is returned when a value outside the range [0, 1)
+	// is used in a call to Headroom.
+	errHeadroomInvalid = &OptionsError{
+		Option: "headroom",
+		Reason: "headroom must be at least 0 and less than 1",
+		Code:   CodeRange,
+	}
+
+	// errPixelsPerSecondInvalid is returned when a value which is not positive
+	// and finite is used in a call to PixelsPerSecond.
+	errPixelsPerSecondInvalid = &OptionsError{
+		Option: "pixelsPerSecond",
+		Reason: "pixels per second must be positive and finite",
+		Code:   CodeRange,
+	}
+
+	// errSmoothNegative is returned when a negative integer is used in a call
+	// to Smooth.
+	errSmoothNegative = &OptionsError{
+		Option: "smooth",
+		Reason: "window cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errSmoothARNegative is returned when a negative duration is used as the
+	// attack or release value in a call to SmoothAR.
+	errSmoothARNegative = &OptionsError{
+		Option: "smoothAR",
+		Reason: "attack and release cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errSmoothEMAInvalid is returned when a value outside the range (0, 1]
+	// is used in a call to SmoothEMA.
+	errSmoothEMAInvalid = &OptionsError{
+		Option: "smoothEMA",
+		Reason: "alpha must be greater than 0, and no greater than 1",
+		Code:   CodeRange,
+	}
+
+	// errNormalizedInvalid is returned when a value outside the range (0, 1]
+	// is used in a call to Normalized.
+	errNormalizedInvalid = &OptionsError{
+		Option: "normalized",
+		Reason: "target must be greater than 0, and no greater than 1",
+		Code:   CodeRange,
+	}
+
+	// errNormalizedConflictsScaleClipping is returned when Normalized is used
+	// with ScaleClipping, because both determine the scale of values.
+	errNormalizedConflictsScaleClipping = &OptionsError{
+		Option: "normalized",
+		Reason: "normalized cannot be used with scaleClipping",
+		Code:   CodeConflict,
+	}
+
+	// errPartialWindowInvalid is returned when an unknown PartialWindowPolicy
+	// is used in a call to PartialWindow.
+	errPartialWindowInvalid = &OptionsError{
+		Option: "partialWindow",
+		Reason: "unknown partial window policy",
+		Code:   CodeUnknown,
+	}
+
+	// errOutlineFunctionNil is returned when a nil ColorFunc is used in
+	// a call to Outline.
+	errOutlineFunctionNil = &OptionsError{
+		Option: "outline",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errCheckpointWriterNil is returned when a nil io.Writer is used in a
+	// call to CheckpointEvery.
+	errCheckpointWriterNil = &OptionsError{
+		Option: "checkpointEvery",
+		Reason: "writer cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errCheckpointEveryZero is returned when integer 0 is used as the number
+	// of windows in a call to CheckpointEvery.
+	errCheckpointEveryZero = &OptionsError{
+		Option: "checkpointEvery",
+		Reason: "windows cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errAnalyzerNil is returned when a nil Analyzer is used in a call to
+	// Analyzers.
+	errAnalyzerNil = &OptionsError{
+		Option: "analyzers",
+		Reason: "analyzer cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errOffsetNegative is returned when a negative duration is used in a
+	// call to Offset.
+	errOffsetNegative = &OptionsError{
+		Option: "offset",
+		Reason: "offset cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errDurationZero is returned when a zero duration is used in a call to
+	// Duration.
+	errDurationZero = &OptionsError{
+		Option: "duration",
+		Reason: "duration cannot be 0",
+		Value:  time.Duration(0),
+		Code:   CodeZero,
+	}
+
+	// errDurationNegative is returned when a negative duration is used in a
+	// call to Duration.
+	errDurationNegative = &OptionsError{
+		Option: "duration",
+		Reason: "duration cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errDownmixInvalid is returned when an unknown DownmixPolicy is used in a
+	// call to Downmix.
+	errDownmixInvalid = &OptionsError{
+		Option: "downmix",
+		Reason: "unknown downmix policy",
+		Code:   CodeUnknown,
+	}
+
+	// errDownmixChannelsZero is returned when integer 0 is used in a call to
+	// DownmixChannels.
+	errDownmixChannelsZero = &OptionsError{
+		Option: "downmixChannels",
+		Reason: "number of channels cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errDownmixChannelsRequiresDownmix is returned when DownmixChannels is
+	// used without Downmix, because channels are only selected by the
+	// DownmixFirst policy.
+	errDownmixChannelsRequiresDownmix = &OptionsError{
+		Option: "downmixChannels",
+		Reason: "downmixChannels requires downmix",
+		Code:   CodeRequires,
+	}
+
+	// errDownmixFunctionNil is returned when a nil DownmixFunc is used in a
+	// call to DownmixFunction.
+	errDownmixFunctionNil = &OptionsError{
+		Option: "downmixFunction",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errDownmixFunctionConflictsDownmix is returned when DownmixFunction is
+	// used with Downmix, because only one may combine channels.
+	errDownmixFunctionConflictsDownmix = &OptionsError{
+		Option: "downmixFunction",
+		Reason: "downmixFunction cannot be used with downmix",
+		Code:   CodeConflict,
+	}
+
+	// errLayoutInvalid is returned when an unknown SampleLayout is used in a
+	// call to Layout.
+	errLayoutInvalid = &OptionsError{
+		Option: "layout",
+		Reason: "unknown sample layout",
+		Code:   CodeUnknown,
+	}
+
+	// errWorkersZero is returned when integer 0 is used in a call to Workers.
+	errWorkersZero = &OptionsError{
+		Option: "workers",
+		Reason: "number of workers cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errMaxDurationZero is returned when a zero duration is used in a call to
+	// MaxDuration.
+	errMaxDurationZero = &OptionsError{
+		Option: "maxDuration",
+		Reason: "maximum duration cannot be 0",
+		Value:  time.Duration(0),
+		Code:   CodeZero,
+	}
+
+	// errMaxDurationNegative is returned when a negative duration is used in
+	// a call to MaxDuration.
+	errMaxDurationNegative = &OptionsError{
+		Option: "maxDuration",
+		Reason: "maximum duration cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errOverlapZero is returned when integer 0 is used in a call to Overlap.
+	errOverlapZero = &OptionsError{
+		Option: "overlap",
+		Reason: "overlap cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errOverlapConflictsCheckpoint is returned when Overlap is used with
+	// CheckpointEvery, because a checkpoint cannot record the audio which
+	// overlaps the windows after it.
+	errOverlapConflictsCheckpoint = &OptionsError{
+		Option: "overlap",
+		Reason: "overlap cannot be used with checkpointEvery",
+		Code:   CodeConflict,
+	}
+
+	// errDownsampleAfterNegative is returned when a negative duration is
+	// used in a call to DownsampleAfter.
+	errDownsampleAfterNegative = &OptionsError{
+		Option: "downsampleAfter",
+		Reason: "duration cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errDownsampleStrideZero is returned when integer 0 is used as the
+	// stride in a call to DownsampleAfter.
+	errDownsampleStrideZero = &OptionsError{
+		Option: "downsampleAfter",
+		Reason: "stride cannot be 0",
+		Value:  uint(0),
+		Code:   CodeZero,
+	}
+
+	// errWindowFunctionNil is returned when a nil WindowFunc is used in a
+	// call to WindowFunction.
+	errWindowFunctionNil = &OptionsError{
+		Option: "windowFunction",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errAutoContrastColorNil is returned when a nil color is used in a call
+	// to AutoContrast.
+	errAutoContrastColorNil = &OptionsError{
+		Option: "autoContrast",
+		Reason: "background color cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errAutoContrastRatioInvalid is returned when a contrast ratio outside
+	// the range [1, 21] is used in a call to AutoContrast.
+	errAutoContrastRatioInvalid = &OptionsError{
+		Option: "autoContrast",
+		Reason: "contrast ratio must be between 1 and 21",
+		Code:   CodeRange,
+	}
+
+	// errProgressFunctionNil is returned when a nil ProgressFunc is used in a
+	// call to ProgressFunction.
+	errProgressFunctionNil = &OptionsError{
+		Option: "progressFunction",
+		Reason: "function cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errInvalidValuesInvalid is returned when an unknown InvalidValuePolicy
+	// is used in a call to InvalidValues.
+	errInvalidValuesInvalid = &OptionsError{
+		Option: "invalidValues",
+		Reason: "unknown invalid value policy",
+		Code:   CodeUnknown,
+	}
+
+	// errClampNegative is returned when a negative or NaN minimum is used in
+	// a call to Clamp.
+	errClampNegative = &OptionsError{
+		Option: "clamp",
+		Reason: "minimum cannot be negative",
+		Code:   CodeNegative,
+	}
+
+	// errClampRange is returned when a maximum which is not greater than the
+	// minimum is used in a call to Clamp.
+	errClampRange = &OptionsError{
+		Option: "clamp",
+		Reason: "maximum must be greater than minimum",
+		Code:   CodeRange,
+	}
+
+	// errOutputFormatInvalid is returned when an unknown ImageFormat is used
+	// in a call to OutputFormat.
+	errOutputFormatInvalid = &OptionsError{
+		Option: "outputFormat",
+		Reason: "unknown image format",
+		Code:   CodeUnknown,
+	}
+
+	// errOutlineRequiresVector is returned when Outline is used without
+	// VectorRenderer, because outlines are only drawn by the vector renderer.
+	errOutlineRequiresVector = &OptionsError{
+		Option: "outline",
+		Reason: "outline requires vectorRenderer",
+		Code:   CodeRequires,
+	}
+
+	// errSharpnessConflictsVector is returned when Sharpness is used with
+	// VectorRenderer, because the vector renderer does not apply sharpness.
+	errSharpnessConflictsVector = &OptionsError{
+		Option: "sharpness",
+		Reason: "sharpness cannot be used with vectorRenderer",
+		Code:   CodeConflict,
+	}
+
+	// errHeightConflictsScale is returned when Height is used with a Y-axis
+	// scaling factor other than 1, because Height determines the height of
+	// images in place of the Y-axis scaling factor.
+	errHeightConflictsScale = &OptionsError{
+		Option: "height",
+		Reason: "height cannot be used with a Y-axis scale other than 1",
+		Code:   CodeConflict,
+	}
+
+	// errPixelsPerSecondConflictsResolution is returned when PixelsPerSecond
+	// is used with Resolution, because PixelsPerSecond determines the
+	// resolution at which values are computed.
+	errPixelsPerSecondConflictsResolution = &OptionsError{
+		Option: "pixelsPerSecond",
+		Reason: "pixelsPerSecond cannot be used with resolution",
+		Code:   CodeConflict,
+	}
+
+	// errLegacyCenteringConflictsVector is returned when LegacyCentering is
+	// used with VectorRenderer, because the vector renderer has always drawn
+	// waveforms exactly centered.
+	errLegacyCenteringConflictsVector = &OptionsError{
+		Option: "legacyCentering",
+		Reason: "legacyCentering cannot be used with vectorRenderer",
+		Code:   CodeConflict,
+	}
+
+	// errFallbackDecoderNil is returned when a nil ExternalDecoder is used in
+	// a call to FallbackDecoder.
+	errFallbackDecoderNil = &OptionsError{
+		Option: "fallbackDecoder",
+		Reason: "decoder cannot be nil",
+		Code:   CodeNil,
+	}
+
+	// errFallbackDecoderCommandEmpty is returned when an ExternalDecoder with
+	// an empty command is used in a call to FallbackDecoder.
+	errFallbackDecoderCommandEmpty = &OptionsError{
+		Option: "fallbackDecoder",
+		Reason: "command cannot be empty",
+		Code:   CodeZero,
+	}
+
+	// errFallbackDecoderConfigInvalid is returned when an ExternalDecoder with
+	// a sample rate or number of channels which is not positive is used in a
+	// call to FallbackDecoder.
+	errFallbackDecoderConfigInvalid = &OptionsError{
+		Option: "fallbackDecoder",
+		Reason: "sample rate and channels must be positive",
+		Code:   CodeRange,
 	}
 )
 
+// OptionsErrorCode is a machine-readable code which describes the reason an
+// OptionsError occurred.  Codes are stable, so that applications may translate
+// them into their own messages.
+type OptionsErrorCode string
+
+// OptionsErrorCode values which may be set on an OptionsError.
+const (
+	// CodeNil indicates that a required value was nil.
+	CodeNil OptionsErrorCode = "nil"
+
+	// CodeZero indicates that a value which must be non-zero was zero.
+	CodeZero OptionsErrorCode = "zero"
+
+	// CodeNegative indicates that a value which must not be negative was
+	// negative.
+	CodeNegative OptionsErrorCode = "negative"
+
+	// CodeUnknown indicates that a value was not one of a known set of values.
+	CodeUnknown OptionsErrorCode = "unknown"
+
+	// CodeRange indicates that a value was outside of its accepted range.
+	CodeRange OptionsErrorCode = "range"
+
+	// CodeConflict indicates that an option cannot be used together with
+	// another option.
+	CodeConflict OptionsErrorCode = "conflict"
+
+	// CodeRequires indicates that an option has no effect unless another
+	// option is also used.
+	CodeRequires OptionsErrorCode = "requires"
+)
+
 // OptionsError is an error which is returned when invalid input
 // options are set on a Waveform struct.
 type OptionsError struct {
 	Option string
 	Reason string
+
+	// Value is the offending value which caused the error, if any.
+	Value interface{}
+
+	// Code is a machine-readable code which describes the reason for
+	// the error.
+	Code OptionsErrorCode
 }
 
 // Error returns the string representation of an OptionsError.
@@ -58,24 +507,58 @@ func (e *OptionsError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Option, e.Reason)
 }
 
+// Is reports whether an OptionsError matches a target error, for use with
+// errors.Is.  Two OptionsError values match if their options, reasons, and
+// codes are equal, regardless of their offending values.
+func (e *OptionsError) Is(target error) bool {
+	t, ok := target.(*OptionsError)
+	if !ok {
+		return false
+	}
+
+	return e.Option == t.Option && e.Reason == t.Reason && e.Code == t.Code
+}
+
+// withValue returns a copy of an OptionsError, with its offending value set
+// to the input value.
+func (e *OptionsError) withValue(value interface{}) *OptionsError {
+	out := *e
+	out.Value = value
+	return &out
+}
+
 // OptionsFunc is a function which is applied to an input Waveform
 // struct, and can manipulate its properties.
 type OptionsFunc func(*Waveform) error
 
 // SetOptions applies zero or more OptionsFunc to the receiving Waveform
 // struct, manipulating its properties.
+//
+// Once all options are applied, the Waveform is checked for incompatible
+// combinations of options using Validate.  Because this check occurs after each
+// call, options which depend on one another should be applied together.
+//
+// Options are applied to a copy of the Waveform, which replaces the receiver
+// only if every option is applied and validated successfully.  If an error is
+// returned, the receiving Waveform is left unchanged.
 func (w *Waveform) SetOptions(options ...OptionsFunc) error {
+	cw := w.clone()
 	for _, o := range options {
 		// Do not apply nil function arguments
 		if o == nil {
 			continue
 		}
 
-		if err := o(w); err != nil {
+		if err := o(cw); err != nil {
 			return err
 		}
 	}
 
+	if err := cw.Validate(); err != nil {
+		return err
+	}
+
+	*w = *cw
 	return nil
 }
 
@@ -105,7 +588,7 @@ func (w *Waveform) setBGColorFunction(function ColorFunc) error {
 		return errBGColorFunctionNil
 	}
 
-	w.bgColorFn = function
+	w.style.bgColorFn = function
 
 	return nil
 }
@@ -136,7 +619,40 @@ func (w *Waveform) setFGColorFunction(function ColorFunc) error {
 		return errFGColorFunctionNil
 	}
 
-	w.fgColorFn = function
+	w.style.fgColorFn = function
+
+	return nil
+}
+
+// OnColumn generates an OptionsFunc which applies the input ColumnFunc to an
+// input Waveform struct.
+//
+// This function is called as each column of a waveform image is drawn, in
+// order from left to right, so that custom adornments, such as glyphs which
+// mark detected events, can be positioned in sync with the drawing of the
+// waveform itself.  When the vector renderer is in use, the waveform is drawn
+// as a whole, and the function is called for each column once the waveform is
+// complete.  Redraw calls the function only for the columns it repaints.
+func OnColumn(function ColumnFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOnColumn(function)
+	}
+}
+
+// SetOnColumn applies the input ColumnFunc to the receiving Waveform struct.
+func (w *Waveform) SetOnColumn(function ColumnFunc) error {
+	return w.SetOptions(OnColumn(function))
+}
+
+// setOnColumn directly sets the ColumnFunc member of the receiving Waveform
+// struct.
+func (w *Waveform) setOnColumn(function ColumnFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errOnColumnNil
+	}
+
+	w.style.columnFn = function
 
 	return nil
 }
@@ -166,6 +682,7 @@ func (w *Waveform) setResolution(resolution uint) error {
 	}
 
 	w.resolution = resolution
+	w.markSet("resolution", resolution != 1)
 
 	return nil
 }
@@ -197,6 +714,40 @@ func (w *Waveform) setSampleFunction(function SampleReduceFunc) error {
 	}
 
 	w.sampleFn = function
+	w.markSet("sampleFunction", !sameFunc(function, RMSF64Samples))
+
+	return nil
+}
+
+// ChunkFunction generates an OptionsFunc which applies the input
+// ChunkReduceFunc to an input Waveform struct.
+//
+// This function is used to compute values from audio samples in place of a
+// SampleReduceFunc, when the reduction also depends on the index or start
+// time of each window, or on the sample rate or number of channels of the
+// audio stream.  ChunkFunction cannot be used with SampleFunction.
+func ChunkFunction(function ChunkReduceFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setChunkFunction(function)
+	}
+}
+
+// SetChunkFunction applies the input ChunkReduceFunc to the receiving Waveform
+// struct.
+func (w *Waveform) SetChunkFunction(function ChunkReduceFunc) error {
+	return w.SetOptions(ChunkFunction(function))
+}
+
+// setChunkFunction directly sets the chunkFn member of the receiving Waveform
+// struct.
+func (w *Waveform) setChunkFunction(function ChunkReduceFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errChunkFunctionNil
+	}
+
+	w.chunkFn = function
+	w.markSet("chunkFunction", true)
 
 	return nil
 }
@@ -232,8 +783,11 @@ func (w *Waveform) setScale(x uint, y uint) error {
 
 	}
 
-	w.scaleX = x
-	w.scaleY = y
+	w.style.scaleX = x
+	w.style.scaleY = y
+
+	// Only a Y-axis scaling factor other than 1 conflicts with Height
+	w.markSet("scaleY", y != 1)
 
 	return nil
 }
@@ -244,6 +798,9 @@ func (w *Waveform) setScale(x uint, y uint) error {
 // This value indicates if the waveform image should be scaled down on its Y-axis
 // when clipping thresholds are reached.  This can be used to show a more accurate
 // waveform when the input audio stream exhibits signs of clipping.
+//
+// The scale is reduced using a rough estimate; Normalized scales values
+// exactly, and cannot be used with ScaleClipping.
 func ScaleClipping() OptionsFunc {
 	return func(w *Waveform) error {
 		return w.setScaleClipping(true)
@@ -259,7 +816,47 @@ func (w *Waveform) SetScaleClipping() error {
 // setScaleClipping directly sets the scaleClipping member of the receiving Waveform
 // struct.
 func (w *Waveform) setScaleClipping(scaleClipping bool) error {
-	w.scaleClipping = scaleClipping
+	w.style.scaleClipping = scaleClipping
+	w.markSet("scaleClipping", scaleClipping)
+
+	return nil
+}
+
+// Normalized generates an OptionsFunc which applies the input normalization
+// target to an input Waveform struct.
+//
+// This value indicates that values should be scaled when a waveform image is
+// drawn, so that the maximum value reaches the fraction target of the height
+// of the image, less any headroom, and all other values are scaled in
+// proportion to it.  A target of 1 fills the image, regardless of the loudness
+// of the input audio stream.  By default, values are not normalized, and are
+// drawn using a fixed scale, so that the waveforms of quiet and loud audio can
+// be compared.  Target must be greater than 0, and no greater than 1.
+//
+// Normalized cannot be used with ScaleClipping.  To normalize computed values
+// themselves, use the Normalize function or ValueSet.Normalize.
+func Normalized(target float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setNormalized(target)
+	}
+}
+
+// SetNormalized applies the input normalization target to the receiving
+// Waveform struct.
+func (w *Waveform) SetNormalized(target float64) error {
+	return w.SetOptions(Normalized(target))
+}
+
+// setNormalized directly sets the normalize member of the receiving Waveform
+// struct.
+func (w *Waveform) setNormalized(target float64) error {
+	// Target must be in the range (0, 1], and NaN is rejected
+	if !(target > 0 && target <= 1) {
+		return errNormalizedInvalid.withValue(target)
+	}
+
+	w.style.normalize = target
+	w.markSet("normalized", true)
 
 	return nil
 }
@@ -284,7 +881,1209 @@ func (w *Waveform) SetSharpness(sharpness uint) error {
 // setSharpness directly sets the sharpness member of the receiving Waveform
 // struct.
 func (w *Waveform) setSharpness(sharpness uint) error {
-	w.sharpness = sharpness
+	w.style.sharpness = sharpness
+	w.markSet("sharpness", sharpness != 1)
+
+	return nil
+}
+
+// Smooth generates an OptionsFunc which applies the input moving average
+// window to an input Waveform struct.
+//
+// This value indicates the number of computed values which are averaged
+// together, centered on each value, before a waveform image is drawn.  Larger
+// windows remove more flicker from noisy audio, such as vinyl rips or field
+// recordings.  A value of 0 or 1 disables smoothing.
+func Smooth(windows int) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSmooth(windows)
+	}
+}
+
+// SetSmooth applies the input moving average window to the receiving Waveform
+// struct.
+func (w *Waveform) SetSmooth(windows int) error {
+	return w.SetOptions(Smooth(windows))
+}
+
+// setSmooth directly sets the smooth member of the receiving Waveform
+// struct.
+func (w *Waveform) setSmooth(windows int) error {
+	// Window cannot be negative
+	if windows < 0 {
+		return errSmoothNegative.withValue(windows)
+	}
+
+	w.style.smooth = windows
+
+	return nil
+}
+
+// SmoothAR generates an OptionsFunc which applies the input attack and release
+// times to an input Waveform struct.
+//
+// These values are used to apply asymmetric, exponential smoothing to computed
+// values before a waveform image is drawn.  The attack time controls how
+// quickly the rendered envelope rises to meet a louder value, and the release
+// time controls how slowly it decays afterward.  A short attack and long release
+// produces an envelope similar to that of a broadcast meter.  Times of 0 disable
+// smoothing in the respective direction.
+func SmoothAR(attack time.Duration, release time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSmoothAR(attack, release)
+	}
+}
+
+// SetSmoothAR applies the input attack and release times to the receiving
+// Waveform struct.
+func (w *Waveform) SetSmoothAR(attack time.Duration, release time.Duration) error {
+	return w.SetOptions(SmoothAR(attack, release))
+}
+
+// setSmoothAR directly sets the attack and release members of the receiving
+// Waveform struct.
+func (w *Waveform) setSmoothAR(attack time.Duration, release time.Duration) error {
+	// Attack and release cannot be negative
+	if attack < 0 {
+		return errSmoothARNegative.withValue(attack)
+	}
+	if release < 0 {
+		return errSmoothARNegative.withValue(release)
+	}
+
+	w.style.attack = attack
+	w.style.release = release
+
+	return nil
+}
+
+// SmoothEMA generates an OptionsFunc which applies the input exponential
+// smoothing factor to an input Waveform struct.
+//
+// This value is used to apply an exponential moving average to computed values
+// before a waveform image is drawn, so that waveforms computed at a high
+// resolution do not appear jagged.  Each smoothed value moves toward its
+// computed value by the fraction alpha, so smaller values produce smoother
+// waveforms.  The average is applied both forward and backward over the
+// values, so that, unlike SmoothAR, peaks are not shifted later in time.
+// Alpha must be greater than 0, and no greater than 1; an alpha of 1 disables
+// smoothing, and is the default.
+func SmoothEMA(alpha float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSmoothEMA(alpha)
+	}
+}
+
+// SetSmoothEMA applies the input exponential smoothing factor to the receiving
+// Waveform struct.
+func (w *Waveform) SetSmoothEMA(alpha float64) error {
+	return w.SetOptions(SmoothEMA(alpha))
+}
+
+// setSmoothEMA directly sets the alpha member of the receiving Waveform
+// struct.
+func (w *Waveform) setSmoothEMA(alpha float64) error {
+	// Alpha must be in the range (0, 1], and NaN is rejected
+	if !(alpha > 0 && alpha <= 1) {
+		return errSmoothEMAInvalid.withValue(alpha)
+	}
+
+	w.style.alpha = alpha
+
+	return nil
+}
+
+// PartialWindow generates an OptionsFunc which applies the input
+// PartialWindowPolicy to an input Waveform struct.
+//
+// This value indicates how a window of audio which is shorter than the window
+// size determined by resolution, typically the final window of a stream,
+// is reduced to a computed value.
+func PartialWindow(policy PartialWindowPolicy) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPartialWindow(policy)
+	}
+}
+
+// SetPartialWindow applies the input PartialWindowPolicy to the receiving
+// Waveform struct.
+func (w *Waveform) SetPartialWindow(policy PartialWindowPolicy) error {
+	return w.SetOptions(PartialWindow(policy))
+}
+
+// setPartialWindow directly sets the partialWindow member of the receiving
+// Waveform struct.
+func (w *Waveform) setPartialWindow(policy PartialWindowPolicy) error {
+	// Policy must be known
+	switch policy {
+	case PartialWindowTrim, PartialWindowDrop, PartialWindowPad:
+	default:
+		return errPartialWindowInvalid.withValue(policy)
+	}
+
+	w.partialWindow = policy
+
+	return nil
+}
+
+// Layout generates an OptionsFunc which applies the input SampleLayout to an
+// input Waveform struct.
+//
+// This value indicates whether the audio samples delivered by the decoder
+// are interleaved sample frames or planar channels.  Treating planar audio as
+// interleaved mixes samples from different points in time within each sample
+// frame, which subtly distorts the waveform envelope, and confuses any
+// downmixing of channels.  By default, the layout is detected from the
+// decoder.
+func Layout(layout SampleLayout) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setLayout(layout)
+	}
+}
+
+// SetLayout applies the input SampleLayout to the receiving Waveform struct.
+func (w *Waveform) SetLayout(layout SampleLayout) error {
+	return w.SetOptions(Layout(layout))
+}
+
+// setLayout directly sets the layout member of the receiving Waveform struct.
+func (w *Waveform) setLayout(layout SampleLayout) error {
+	// Layout must be known
+	switch layout {
+	case SampleLayoutAuto, SampleLayoutInterleaved, SampleLayoutPlanar:
+	default:
+		return errLayoutInvalid.withValue(layout)
+	}
+
+	w.layout = layout
+
+	return nil
+}
+
+// VectorRenderer generates an OptionsFunc which sets the vector member to true
+// on an input Waveform struct.
+//
+// This value indicates if waveform images should be drawn using a 2D vector
+// rasterizer, rather than by setting individual pixels.  The vector renderer
+// traces the waveform envelope using smooth curves, and anti-aliases its edges.
+// Sharpness does not apply when the vector renderer is in use.
+func VectorRenderer() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setVector(true)
+	}
+}
+
+// SetVectorRenderer sets the vector member true for the receiving Waveform
+// struct.
+func (w *Waveform) SetVectorRenderer() error {
+	return w.SetOptions(VectorRenderer())
+}
+
+// setVector directly sets the vector member of the receiving Waveform struct.
+func (w *Waveform) setVector(vector bool) error {
+	w.style.vector = vector
+	w.markSet("vectorRenderer", vector)
+
+	return nil
+}
+
+// Outline generates an OptionsFunc which applies the input outline ColorFunc
+// and line width to an input Waveform struct.
+//
+// These values are used to stroke the outline of the waveform envelope, and
+// only apply when the vector renderer is in use.  A width of 0 disables the
+// outline.
+func Outline(function ColorFunc, width uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOutline(function, width)
+	}
+}
+
+// SetOutline applies the input outline ColorFunc and line width to the
+// receiving Waveform struct.
+func (w *Waveform) SetOutline(function ColorFunc, width uint) error {
+	return w.SetOptions(Outline(function, width))
+}
+
+// setOutline directly sets the outline members of the receiving Waveform
+// struct.
+func (w *Waveform) setOutline(function ColorFunc, width uint) error {
+	// Function cannot be nil
+	if function == nil {
+		return errOutlineFunctionNil
+	}
+
+	w.style.outlineFn = function
+	w.style.outlineWidth = width
+	w.markSet("outline", true)
+
+	return nil
+}
+
+// CheckpointEvery generates an OptionsFunc which applies the input checkpoint
+// interval and io.Writer to an input Waveform struct.
+//
+// These values indicate that, during computation, a Checkpoint should be written
+// to the io.Writer after every specified number of windows of audio are read.
+// Checkpoints are appended to the io.Writer, and the most recent one can be
+// retrieved using ReadCheckpoint, so that a long-running computation can later
+// be resumed using Waveform.Resume.  Each Checkpoint contains only the values
+// computed since the previous one, so the io.Writer should not be truncated
+// between them.
+//
+// CheckpointEvery cannot be used with Waveform.ComputeFunc, which does not
+// retain the values it computes.
+func CheckpointEvery(windows uint, w io.Writer) OptionsFunc {
+	return func(wf *Waveform) error {
+		return wf.setCheckpointEvery(windows, w)
+	}
+}
+
+// SetCheckpointEvery applies the input checkpoint interval and io.Writer to
+// the receiving Waveform struct.
+func (w *Waveform) SetCheckpointEvery(windows uint, cw io.Writer) error {
+	return w.SetOptions(CheckpointEvery(windows, cw))
+}
+
+// setCheckpointEvery directly sets the checkpoint members of the receiving
+// Waveform struct.
+func (w *Waveform) setCheckpointEvery(windows uint, cw io.Writer) error {
+	// Writer cannot be nil
+	if cw == nil {
+		return errCheckpointWriterNil
+	}
+
+	// Windows cannot be zero
+	if windows == 0 {
+		return errCheckpointEveryZero
+	}
+
+	w.checkpointW = cw
+	w.checkpointEvery = windows
+	w.markSet("checkpointEvery", true)
+
+	return nil
+}
+
+// Offset generates an OptionsFunc which applies the input offset to an input
+// Waveform struct.
+//
+// This value indicates the time at which computation begins.  Audio before the
+// offset is not analyzed or reduced, and the durations of computed values are
+// relative to the offset.  Combined with Duration, this allows a preview of
+// a short clip to be generated from a long recording.
+//
+// Uncompressed WAV and AIFF streams which implement io.Seeker are seeked
+// directly to the offset.  Streams in all other formats must still decode and
+// discard the audio before the offset, so the cost of a large offset grows
+// with its length.
+func Offset(d time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOffset(d)
+	}
+}
+
+// SetOffset applies the input offset to the receiving Waveform struct.
+func (w *Waveform) SetOffset(d time.Duration) error {
+	return w.SetOptions(Offset(d))
+}
+
+// setOffset directly sets the offset member of the receiving Waveform struct.
+func (w *Waveform) setOffset(d time.Duration) error {
+	// Offset cannot be negative
+	if d < 0 {
+		return errOffsetNegative.withValue(d)
+	}
+
+	w.offset = d
+
+	return nil
+}
+
+// Duration generates an OptionsFunc which applies the input duration to an
+// input Waveform struct.
+//
+// This value indicates the length of audio which is analyzed, beginning at
+// the time specified by Offset.  Decoding stops once the duration has been
+// read, so the remainder of a long stream is never decoded.  If the stream
+// ends sooner, values are computed until its end.
+func Duration(d time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDuration(d)
+	}
+}
+
+// SetDuration applies the input duration to the receiving Waveform struct.
+func (w *Waveform) SetDuration(d time.Duration) error {
+	return w.SetOptions(Duration(d))
+}
+
+// setDuration directly sets the duration member of the receiving Waveform
+// struct.
+func (w *Waveform) setDuration(d time.Duration) error {
+	// Duration must be positive
+	if d == 0 {
+		return errDurationZero
+	}
+	if d < 0 {
+		return errDurationNegative.withValue(d)
+	}
+
+	w.duration = d
+
+	return nil
+}
+
+// Downmix generates an OptionsFunc which applies the input DownmixPolicy to
+// an input Waveform struct.
+//
+// This value indicates how the channels of an audio stream are combined
+// before each window is passed to any Analyzers and reduced by the
+// SampleReduceFunc.  Without downmixing, the samples of every channel are
+// reduced together, so that the silent or quiet channels of surround audio,
+// such as LFE and surround channels, reduce the amplitude of its waveform.
+// Downmix cannot be used with DownmixFunction.
+func Downmix(policy DownmixPolicy) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDownmix(policy)
+	}
+}
+
+// SetDownmix applies the input DownmixPolicy to the receiving Waveform struct.
+func (w *Waveform) SetDownmix(policy DownmixPolicy) error {
+	return w.SetOptions(Downmix(policy))
+}
+
+// setDownmix directly sets the downmix member of the receiving Waveform
+// struct.
+func (w *Waveform) setDownmix(policy DownmixPolicy) error {
+	// Policy must be known
+	switch policy {
+	case DownmixNone, DownmixAverage, DownmixITU, DownmixFirst:
+	default:
+		return errDownmixInvalid.withValue(policy)
+	}
+
+	w.downmix = policy
+	w.markSet("downmix", policy != DownmixNone)
+
+	return nil
+}
+
+// DownmixChannels generates an OptionsFunc which applies the input number of
+// channels to an input Waveform struct.
+//
+// This value indicates the number of channels which are retained by the
+// DownmixFirst policy, and has no effect with any other DownmixPolicy.
+// DownmixChannels requires Downmix.
+func DownmixChannels(n uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDownmixChannels(n)
+	}
+}
+
+// SetDownmixChannels applies the input number of channels to the receiving
+// Waveform struct.
+func (w *Waveform) SetDownmixChannels(n uint) error {
+	return w.SetOptions(DownmixChannels(n))
+}
+
+// setDownmixChannels directly sets the downmixChannels member of the
+// receiving Waveform struct.
+func (w *Waveform) setDownmixChannels(n uint) error {
+	// At least one channel must be retained
+	if n == 0 {
+		return errDownmixChannelsZero
+	}
+
+	w.downmixChannels = n
+	w.markSet("downmixChannels", n != downmixChannelsDefault)
+
+	return nil
+}
+
+// DownmixFunction generates an OptionsFunc which applies the input
+// DownmixFunc to an input Waveform struct.
+//
+// This function combines the channels of each sample frame into a single
+// sample before each window is passed to any Analyzers and reduced by the
+// SampleReduceFunc, so that a single channel or a combination of channels
+// can be analyzed.  For example, DownmixSide allows the energy of the side
+// signal of a stereo master to be visualized separately.  DownmixFunction
+// cannot be used with Downmix.
+func DownmixFunction(function DownmixFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDownmixFunction(function)
+	}
+}
+
+// SetDownmixFunction applies the input DownmixFunc to the receiving Waveform
+// struct.
+func (w *Waveform) SetDownmixFunction(function DownmixFunc) error {
+	return w.SetOptions(DownmixFunction(function))
+}
+
+// setDownmixFunction directly sets the downmixFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setDownmixFunction(function DownmixFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errDownmixFunctionNil
+	}
+
+	w.downmixFn = function
+	w.markSet("downmixFunction", true)
+
+	return nil
+}
+
+// Workers generates an OptionsFunc which applies the input number of workers
+// to an input Waveform struct.
+//
+// This value indicates the number of inputs which are processed concurrently
+// by GenerateAll and GenerateAllContext, overriding DefaultWorkers.  It has
+// no effect on a single Waveform.
+func Workers(n uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setWorkers(n)
+	}
+}
+
+// SetWorkers applies the input number of workers to the receiving Waveform
+// struct.
+func (w *Waveform) SetWorkers(n uint) error {
+	return w.SetOptions(Workers(n))
+}
+
+// setWorkers directly sets the workers member of the receiving Waveform
+// struct.
+func (w *Waveform) setWorkers(n uint) error {
+	// At least one worker is required
+	if n == 0 {
+		return errWorkersZero
+	}
+
+	w.workers = n
+
+	return nil
+}
+
+// MaxDuration generates an OptionsFunc which applies the input maximum
+// duration to an input Waveform struct.
+//
+// This value limits the length of audio which may be decoded from the input
+// stream.  If the stream contains more audio than the maximum duration,
+// computation stops with a *TooLongError, so that services which accept
+// uploads are protected against maliciously long or unbounded streams.  All
+// decoded audio counts towards the limit, including audio before the time
+// specified by Offset.
+func MaxDuration(d time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMaxDuration(d)
+	}
+}
+
+// SetMaxDuration applies the input maximum duration to the receiving Waveform
+// struct.
+func (w *Waveform) SetMaxDuration(d time.Duration) error {
+	return w.SetOptions(MaxDuration(d))
+}
+
+// setMaxDuration directly sets the maxDuration member of the receiving
+// Waveform struct.
+func (w *Waveform) setMaxDuration(d time.Duration) error {
+	// Maximum duration must be positive
+	if d == 0 {
+		return errMaxDurationZero
+	}
+	if d < 0 {
+		return errMaxDurationNegative.withValue(d)
+	}
+
+	w.maxDuration = d
+
+	return nil
+}
+
+// Overlap generates an OptionsFunc which applies the input overlap to an
+// input Waveform struct.
+//
+// This value indicates the number of consecutive windows of audio, at the
+// current resolution, from which each value is computed.  Resolution
+// continues to determine the hop size between values, so the number of
+// values is unchanged, but each value is computed from a longer window which
+// is centered on its own window and overlaps those of its neighbors.  For
+// example, an overlap of 2 computes each value from a window which overlaps
+// each neighboring window by half.  Combined with a WindowFunction such as
+// HannWindow, this produces much smoother waveforms, particularly at low
+// resolutions.  An overlap of 1 is the default, and disables overlapping.
+//
+// Windows at the beginning and end of the stream contain only the audio which
+// is available, and the PartialWindowPolicy applies only to the window's own
+// hop: under PartialWindowPad, no padding is applied.  Overlaps greater than
+// 1 cannot be used with CheckpointEvery.
+func Overlap(windows uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOverlap(windows)
+	}
+}
+
+// SetOverlap applies the input overlap to the receiving Waveform struct.
+func (w *Waveform) SetOverlap(windows uint) error {
+	return w.SetOptions(Overlap(windows))
+}
+
+// setOverlap directly sets the overlap member of the receiving Waveform
+// struct.
+func (w *Waveform) setOverlap(windows uint) error {
+	// Each value must be computed from at least one window
+	if windows == 0 {
+		return errOverlapZero
+	}
+
+	w.overlap = windows
+
+	// An overlap of 1 disables overlapping, and is compatible with all
+	// other options
+	w.markSet("overlap", windows > 1)
+
+	return nil
+}
+
+// WindowFunction generates an OptionsFunc which applies the input WindowFunc
+// to an input Waveform struct.
+//
+// This function weights each sample frame of a window of audio before the
+// window is reduced to a computed value, typically tapering the edges of the
+// window, as with HannWindow and HammingWindow.  Weights are normalized so
+// that the root mean square of constant audio is unchanged.  WindowFunction
+// is most useful when combined with Overlap.
+func WindowFunction(function WindowFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setWindowFunction(function)
+	}
+}
+
+// SetWindowFunction applies the input WindowFunc to the receiving Waveform
+// struct.
+func (w *Waveform) SetWindowFunction(function WindowFunc) error {
+	return w.SetOptions(WindowFunction(function))
+}
+
+// setWindowFunction directly sets the windowFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setWindowFunction(function WindowFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errWindowFunctionNil
+	}
+
+	w.windowFn = function
+
+	return nil
+}
+
+// AutoContrast generates an OptionsFunc which applies the input background
+// color to an input Waveform struct, along with a foreground color derived
+// from it, as computed by ContrastColor.
+//
+// This option simplifies styling waveforms from user-chosen background
+// colors: the foreground always meets the input WCAG contrast ratio against
+// the background, where possible.  WCAG 2.1 recommends a ratio of at least 3
+// for graphical objects, and 4.5 for text.  Options applied after
+// AutoContrast, such as FGColorFunction, replace the colors it applies.
+func AutoContrast(bg color.Color, ratio float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setAutoContrast(bg, ratio)
+	}
+}
+
+// SetAutoContrast applies the input background color and a contrasting
+// foreground color to the receiving Waveform struct.
+func (w *Waveform) SetAutoContrast(bg color.Color, ratio float64) error {
+	return w.SetOptions(AutoContrast(bg, ratio))
+}
+
+// setAutoContrast directly sets the background and foreground ColorFunc
+// members of the receiving Waveform struct.
+func (w *Waveform) setAutoContrast(bg color.Color, ratio float64) error {
+	if bg == nil {
+		return errAutoContrastColorNil
+	}
+
+	// Ratio must be a valid WCAG contrast ratio, and NaN is rejected
+	if !(ratio >= 1 && ratio <= 21) {
+		return errAutoContrastRatioInvalid.withValue(ratio)
+	}
+
+	w.style.bgColorFn = SolidColor(bg)
+	w.style.fgColorFn = SolidColor(ContrastColor(bg, ratio))
+
+	return nil
+}
+
+// ProgressFunction generates an OptionsFunc which applies the input
+// ProgressFunc to an input Waveform struct.
+//
+// This function is called after each window of audio is read during
+// computation, and once more when computation is complete.  When the input
+// stream implements io.Seeker, its size is determined before computation
+// begins, so that the Progress reports an accurate percentage and an estimate
+// of the total number of windows.
+func ProgressFunction(function ProgressFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setProgressFunction(function)
+	}
+}
+
+// SetProgressFunction applies the input ProgressFunc to the receiving
+// Waveform struct.
+func (w *Waveform) SetProgressFunction(function ProgressFunc) error {
+	return w.SetOptions(ProgressFunction(function))
+}
+
+// setProgressFunction directly sets the progressFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setProgressFunction(function ProgressFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errProgressFunctionNil
+	}
+
+	w.progressFn = function
+
+	return nil
+}
+
+// InvalidValues generates an OptionsFunc which applies the input
+// InvalidValuePolicy to an input Waveform struct.
+//
+// This value indicates how computed values which are NaN, infinite, or
+// negative are handled when a waveform image is drawn.
+func InvalidValues(policy InvalidValuePolicy) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setInvalidValues(policy)
+	}
+}
+
+// SetInvalidValues applies the input InvalidValuePolicy to the receiving
+// Waveform struct.
+func (w *Waveform) SetInvalidValues(policy InvalidValuePolicy) error {
+	return w.SetOptions(InvalidValues(policy))
+}
+
+// setInvalidValues directly sets the invalidValues member of the receiving
+// Waveform struct.
+func (w *Waveform) setInvalidValues(policy InvalidValuePolicy) error {
+	// Policy must be known
+	switch policy {
+	case InvalidValueClamp, InvalidValueSkip, InvalidValueError:
+	default:
+		return errInvalidValuesInvalid.withValue(policy)
+	}
+
+	w.style.invalidValues = policy
+
+	return nil
+}
+
+// Clamp generates an OptionsFunc which applies the input minimum and maximum
+// values to an input Waveform struct.
+//
+// These values are used to clamp computed values before a waveform image is
+// drawn, after invalid values are handled by the InvalidValuePolicy, and
+// before values are smoothed or scaled.  Values above max are drawn as max, so
+// that outliers, such as digital clicks, do not reduce the scale of the rest
+// of a waveform when ScaleClipping or Normalized is in use, and values below
+// min are drawn as min, so that quiet passages remain visible.
+//
+// Min cannot be negative, and max must be greater than min.  A max of positive
+// infinity clamps only the minimum.  By default, values are not clamped.
+func Clamp(min float64, max float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setClamp(min, max)
+	}
+}
+
+// SetClamp applies the input minimum and maximum values to the receiving
+// Waveform struct.
+func (w *Waveform) SetClamp(min float64, max float64) error {
+	return w.SetOptions(Clamp(min, max))
+}
+
+// setClamp directly sets the clamp members of the receiving Waveform struct.
+func (w *Waveform) setClamp(min float64, max float64) error {
+	// Minimum must not be negative, and NaN is rejected
+	if !(min >= 0) {
+		return errClampNegative.withValue(min)
+	}
+	if !(max > min) {
+		return errClampRange.withValue(max)
+	}
+
+	w.style.clamp = true
+	w.style.clampMin = min
+	w.style.clampMax = max
+
+	return nil
+}
+
+// Analyzers generates an OptionsFunc which applies the input Analyzers to an
+// input Waveform struct, replacing any which were previously set.
+//
+// Each Analyzer receives every window of audio samples read during
+// computation, so that analysis such as loudness measurement can share the
+// decode pass used to generate a waveform.
+func Analyzers(analyzers ...Analyzer) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setAnalyzers(analyzers)
+	}
+}
+
+// SetAnalyzers applies the input Analyzers to the receiving Waveform struct.
+func (w *Waveform) SetAnalyzers(analyzers ...Analyzer) error {
+	return w.SetOptions(Analyzers(analyzers...))
+}
+
+// setAnalyzers directly sets the analyzers member of the receiving Waveform
+// struct.
+func (w *Waveform) setAnalyzers(analyzers []Analyzer) error {
+	// Analyzers cannot be nil
+	for _, a := range analyzers {
+		if a == nil {
+			return errAnalyzerNil
+		}
+	}
+
+	w.analyzers = analyzers
+
+	return nil
+}
+
+// LinearLight generates an OptionsFunc which applies the input linear light
+// blending setting to an input Waveform struct.
+//
+// This value indicates if the anti-aliased edges produced by the vector
+// renderer should be blended in linear light, rather than by interpolating
+// sRGB encoded values directly.  Linear light blending avoids dark fringes
+// around edges, especially between saturated colors.  To also interpolate
+// gradients in linear light, use LinearGradientColor.
+func LinearLight(enabled bool) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setLinearLight(enabled)
+	}
+}
+
+// SetLinearLight applies the input linear light blending setting to the
+// receiving Waveform struct.
+func (w *Waveform) SetLinearLight(enabled bool) error {
+	return w.SetOptions(LinearLight(enabled))
+}
+
+// setLinearLight directly sets the linear member of the receiving Waveform
+// struct.
+func (w *Waveform) setLinearLight(enabled bool) error {
+	w.style.linear = enabled
+
+	return nil
+}
+
+// OutputFormat generates an OptionsFunc which applies the input ImageFormat to
+// an input Waveform struct.
+//
+// This value indicates the type of image produced when a waveform image is
+// drawn.  16-bit formats, such as FormatRGBA64, preserve the precision of
+// colors and anti-aliased edges for pipelines which post-process the image,
+// such as by blurring or compositing it, before it is quantized for output.
+func OutputFormat(format ImageFormat) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOutputFormat(format)
+	}
+}
+
+// SetOutputFormat applies the input ImageFormat to the receiving Waveform
+// struct.
+func (w *Waveform) SetOutputFormat(format ImageFormat) error {
+	return w.SetOptions(OutputFormat(format))
+}
+
+// setOutputFormat directly sets the format member of the receiving Waveform
+// struct.
+func (w *Waveform) setOutputFormat(format ImageFormat) error {
+	// Format must be known
+	switch format {
+	case FormatRGBA, FormatRGBA64, FormatNRGBA64:
+	default:
+		return errOutputFormatInvalid.withValue(format)
+	}
+
+	w.style.format = format
+
+	return nil
+}
+
+// Height generates an OptionsFunc which applies the input image height, in
+// pixels, to an input Waveform struct.
+//
+// This value replaces the Y-axis scaling factor applied by Scale, and allows
+// images of any height to be drawn.  Height cannot be used with a Y-axis
+// scaling factor other than 1, but the X-axis scaling factor still applies.
+// Odd heights have a center row, so a waveform drawn at an odd height has a
+// crisp, 1 pixel center line.
+func Height(pixels uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setHeight(pixels)
+	}
+}
+
+// SetHeight applies the input image height to the receiving Waveform struct.
+func (w *Waveform) SetHeight(pixels uint) error {
+	return w.SetOptions(Height(pixels))
+}
+
+// setHeight directly sets the height member of the receiving Waveform struct.
+func (w *Waveform) setHeight(pixels uint) error {
+	// Height cannot be zero
+	if pixels == 0 {
+		return errHeightZero
+	}
+
+	w.style.height = pixels
+	w.markSet("height", true)
+
+	return nil
+}
+
+// LegacyCentering generates an OptionsFunc which sets the legacyCenter member
+// to true on an input Waveform struct.
+//
+// Waveforms are drawn exactly symmetric about the center of the image.  Earlier
+// versions of this package biased waveforms with an odd pixel height downward
+// by a pixel.  This option restores that behavior, for applications which
+// compare output against previously generated images.  It only applies to the
+// raster renderer.
+func LegacyCentering() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setLegacyCentering(true)
+	}
+}
+
+// SetLegacyCentering sets the legacyCenter member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetLegacyCentering() error {
+	return w.SetOptions(LegacyCentering())
+}
+
+// setLegacyCentering directly sets the legacyCenter member of the receiving
+// Waveform struct.
+func (w *Waveform) setLegacyCentering(legacy bool) error {
+	w.style.legacyCenter = legacy
+	w.markSet("legacyCentering", legacy)
+
+	return nil
+}
+
+// MinHeight generates an OptionsFunc which applies the input minimum waveform
+// height, in pixels, to an input Waveform struct.
+//
+// This value indicates the smallest height at which any computed value will be
+// drawn, so that silent or near-silent windows of audio still produce a thin
+// baseline, rather than disappearing from the image.  A height of 0 disables
+// the minimum.
+func MinHeight(pixels uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMinHeight(pixels)
+	}
+}
+
+// SetMinHeight applies the input minimum waveform height to the receiving
+// Waveform struct.
+func (w *Waveform) SetMinHeight(pixels uint) error {
+	return w.SetOptions(MinHeight(pixels))
+}
+
+// setMinHeight directly sets the minHeight member of the receiving Waveform
+// struct.
+func (w *Waveform) setMinHeight(pixels uint) error {
+	w.style.minHeight = pixels
+
+	return nil
+}
+
+// Quantize generates an OptionsFunc which applies the input number of levels
+// to an input Waveform struct.
+//
+// This value indicates the number of discrete heights at which values are
+// drawn, evenly spaced from the center to the edges of the image, less any
+// headroom.  Each column is drawn at the highest level its value reaches,
+// producing a stepped waveform in the style of an LED level meter.  Values
+// which reach no level are drawn at the minimum height.  Fewer levels produce
+// coarser steps, and 0 disables quantization, which is the default.
+func Quantize(levels uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setQuantize(levels)
+	}
+}
+
+// SetQuantize applies the input number of levels to the receiving Waveform
+// struct.
+func (w *Waveform) SetQuantize(levels uint) error {
+	return w.SetOptions(Quantize(levels))
+}
+
+// setQuantize directly sets the levels member of the receiving Waveform
+// struct.
+func (w *Waveform) setQuantize(levels uint) error {
+	w.style.levels = levels
+
+	return nil
+}
+
+// PixelArt generates an OptionsFunc which applies the input block size, in
+// pixels, to an input Waveform struct.
+//
+// This value indicates that waveform images should be drawn at a resolution
+// reduced by the block size, and then scaled up to their full size using
+// nearest neighbor sampling, so that the image is made up of square blocks of
+// pixels, in the style of pixel art.  Each block covers the loudest value of
+// the columns it spans, and blocks at the right and bottom edges are cropped,
+// so images have the same bounds as they would without this option.  Other
+// measurements in pixels, such as MinHeight and MirrorGap, are reduced in
+// proportion to the block size, and ColorFuncs and ColumnFuncs receive the
+// coordinates of the reduced image.
+//
+// Combined with Quantize, this produces chunky waveforms in the style of video
+// game and chiptune visualizers.  A size of 0 or 1 draws images at their full
+// resolution, which is the default.
+func PixelArt(size uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPixelArt(size)
+	}
+}
+
+// SetPixelArt applies the input block size to the receiving Waveform struct.
+func (w *Waveform) SetPixelArt(size uint) error {
+	return w.SetOptions(PixelArt(size))
+}
+
+// setPixelArt directly sets the pixelSize member of the receiving Waveform
+// struct.
+func (w *Waveform) setPixelArt(size uint) error {
+	w.style.pixelSize = size
+
+	return nil
+}
+
+// Headroom generates an OptionsFunc which applies the input headroom fraction
+// to an input Waveform struct.
+//
+// This value indicates the fraction of the image height which is reserved as a
+// margin, split evenly between the top and bottom of the image.  Waveforms are
+// scaled to fit within the remaining height, and are never drawn within the
+// margin, so that even full-scale audio does not touch the edges of the image.
+// This improves readability when borders or labels are drawn near the edges.
+// The fraction must be at least 0 and less than 1.
+func Headroom(fraction float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setHeadroom(fraction)
+	}
+}
+
+// SetHeadroom applies the input headroom fraction to the receiving Waveform
+// struct.
+func (w *Waveform) SetHeadroom(fraction float64) error {
+	return w.SetOptions(Headroom(fraction))
+}
+
+// setHeadroom directly sets the headroom member of the receiving Waveform
+// struct.
+func (w *Waveform) setHeadroom(fraction float64) error {
+	// Fraction must leave some room for the waveform, and NaN is rejected
+	if !(fraction >= 0 && fraction < 1) {
+		return errHeadroomInvalid.withValue(fraction)
+	}
+
+	w.style.headroom = fraction
+
+	return nil
+}
+
+// MirrorGap generates an OptionsFunc which applies the input mirror gap, in
+// pixels, to an input Waveform struct.
+//
+// This value indicates the size of the vertical gap between the top and bottom
+// halves of the mirrored waveform.  Each half retains its height, and is moved
+// away from the center of the image, but never into any reserved Headroom.  A
+// gap of 0 disables the gap.
+func MirrorGap(pixels uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMirrorGap(pixels)
+	}
+}
+
+// SetMirrorGap applies the input mirror gap to the receiving Waveform struct.
+func (w *Waveform) SetMirrorGap(pixels uint) error {
+	return w.SetOptions(MirrorGap(pixels))
+}
+
+// setMirrorGap directly sets the mirrorGap member of the receiving Waveform
+// struct.
+func (w *Waveform) setMirrorGap(pixels uint) error {
+	w.style.mirrorGap = pixels
+
+	return nil
+}
+
+// ProportionalTime generates an OptionsFunc which sets the proportional member
+// to true on an input Waveform struct.
+//
+// This value indicates if the width of each column of a waveform image should
+// be proportional to the duration of audio used to compute its value, so that
+// the X-axis maps linearly to time.  It applies when values are drawn from a
+// ValueSet, using Generate or DrawValueSet.  Values drawn using Draw have no
+// duration information, and are always drawn at equal widths.
+func ProportionalTime() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setProportionalTime(true)
+	}
+}
+
+// SetProportionalTime sets the proportional member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetProportionalTime() error {
+	return w.SetOptions(ProportionalTime())
+}
+
+// setProportionalTime directly sets the proportional member of the receiving
+// Waveform struct.
+func (w *Waveform) setProportionalTime(proportional bool) error {
+	w.style.proportional = proportional
+
+	return nil
+}
+
+// PixelsPerSecond generates an OptionsFunc which applies the input time to
+// pixel density to an input Waveform struct.
+//
+// This value is an alternative to the interplay of Resolution and the X-axis
+// scaling factor of Scale, and states the zoom level of a waveform directly, as
+// editors and similar applications do.  Values are computed at a resolution
+// which is derived from the density, and are reduced to columns which are
+// exactly 1 pixel wide, so that the X-axis maps linearly to time at any
+// density, including fractional densities and densities below 1.  Where a
+// column represents more than one value, the largest value is drawn.
+//
+// PixelsPerSecond cannot be used with Resolution, and the X-axis scaling factor
+// of Scale is ignored when it is in use.
+func PixelsPerSecond(f float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPixelsPerSecond(f)
+	}
+}
+
+// SetPixelsPerSecond applies the input time to pixel density to the receiving
+// Waveform struct.
+func (w *Waveform) SetPixelsPerSecond(f float64) error {
+	return w.SetOptions(PixelsPerSecond(f))
+}
+
+// setPixelsPerSecond directly sets the pixelsPerSecond member of the receiving
+// Waveform struct, along with the resolution derived from it.
+func (w *Waveform) setPixelsPerSecond(f float64) error {
+	// Density must be positive and finite, and NaN is rejected
+	if !(f > 0 && f <= math.MaxUint32) {
+		return errPixelsPerSecondInvalid.withValue(f)
+	}
+
+	// Compute at least one value for each column
+	w.resolution = uint(math.Ceil(f))
+	w.style.pixelsPerSecond = f
+	w.markSet("pixelsPerSecond", true)
+
+	return nil
+}
+
+// FallbackDecoder generates an OptionsFunc which applies the input
+// ExternalDecoder to an input Waveform struct.
+//
+// When this option is in use, audio streams in formats which are not supported
+// by this package are decoded by the command of the ExternalDecoder, such as
+// ffmpeg, rather than causing ErrFormat to be returned.  This gives coverage of
+// the long tail of audio formats, at the cost of running an external process.
+// Errors which occur while running the command are returned as an
+// *ExternalDecoderError.
+func FallbackDecoder(d *ExternalDecoder) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setFallbackDecoder(d)
+	}
+}
+
+// SetFallbackDecoder applies the input ExternalDecoder to the receiving
+// Waveform struct.
+func (w *Waveform) SetFallbackDecoder(d *ExternalDecoder) error {
+	return w.SetOptions(FallbackDecoder(d))
+}
+
+// setFallbackDecoder directly sets the fallback member of the receiving
+// Waveform struct.
+func (w *Waveform) setFallbackDecoder(d *ExternalDecoder) error {
+	// Decoder cannot be nil, and must describe a command and its output
+	if d == nil {
+		return errFallbackDecoderNil
+	}
+	if d.Command == "" {
+		return errFallbackDecoderCommandEmpty
+	}
+	if d.SampleRate <= 0 || d.Channels <= 0 {
+		return errFallbackDecoderConfigInvalid.withValue(d)
+	}
+
+	w.fallback = d
+
+	return nil
+}
+
+// DownsampleAfter generates an OptionsFunc which applies the input duration
+// and stride to an input Waveform struct.
+//
+// Once the input duration of audio has been read, only every stride'th
+// window is reduced to a computed value, and each window which is skipped
+// repeats the value of the last window which was reduced.  This bounds the
+// work done by the SampleReduceFunc for very long streams, trading accuracy
+// for speed, such as to quickly generate a preview of a multi-hour recording
+// before a full-accuracy waveform is computed.  A duration of 0 downsamples
+// the entire stream.
+//
+// Windows are skipped according to their position in the stream, so the same
+// stream always produces the same values, whether or not its length is known
+// in advance.  The number of values, and their Counts and Durations, are
+// unchanged, so that downsampled values remain aligned with time.  Skipped
+// windows are still decoded and passed to any Analyzers.  A stride of 1 is
+// the default, and disables downsampling.
+func DownsampleAfter(after time.Duration, stride uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDownsampleAfter(after, stride)
+	}
+}
+
+// SetDownsampleAfter applies the input duration and stride to the receiving
+// Waveform struct.
+func (w *Waveform) SetDownsampleAfter(after time.Duration, stride uint) error {
+	return w.SetOptions(DownsampleAfter(after, stride))
+}
+
+// setDownsampleAfter directly sets the downsampleAfter and downsampleStride
+// members of the receiving Waveform struct.
+func (w *Waveform) setDownsampleAfter(after time.Duration, stride uint) error {
+	if after < 0 {
+		return errDownsampleAfterNegative.withValue(after)
+	}
+	if stride == 0 {
+		return errDownsampleStrideZero
+	}
+
+	w.downsampleAfter = after
+	w.downsampleStride = stride
 
 	return nil
 }
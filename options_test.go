@@ -1,9 +1,13 @@
 package waveform
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"image/color"
+	"math"
 	"testing"
+	"time"
 )
 
 // TestOptionsError verifies that the format of OptionsError.Error does
@@ -32,6 +36,43 @@ func TestOptionsError(t *testing.T) {
 	}
 }
 
+// TestOptionsErrorValue verifies that OptionsError values carry the offending
+// value and a machine-readable code, and match their sentinel errors.
+func TestOptionsErrorValue(t *testing.T) {
+	var tests = []struct {
+		fn    OptionsFunc
+		err   error
+		value interface{}
+		code  OptionsErrorCode
+	}{
+		{BGColorFunction(nil), errBGColorFunctionNil, nil, CodeNil},
+		{Resolution(0), errResolutionZero, uint(0), CodeZero},
+		{Smooth(-2), errSmoothNegative, -2, CodeNegative},
+		{SmoothAR(0, -time.Second), errSmoothARNegative, -time.Second, CodeNegative},
+		{PartialWindow(10), errPartialWindowInvalid, PartialWindowPolicy(10), CodeUnknown},
+		{Downmix(10), errDownmixInvalid, DownmixPolicy(10), CodeUnknown},
+		{Outline(SolidColor(color.Black), 1), errOutlineRequiresVector, nil, CodeRequires},
+	}
+
+	for i, test := range tests {
+		_, err := New(nil, test.fn)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+
+		var opErr *OptionsError
+		if !errors.As(err, &opErr) {
+			t.Fatalf("[%02d] unexpected error type: %T", i, err)
+		}
+		if opErr.Value != test.value {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, opErr.Value, test.value)
+		}
+		if opErr.Code != test.code {
+			t.Fatalf("[%02d] unexpected code: %v != %v", i, opErr.Code, test.code)
+		}
+	}
+}
+
 // TestOptionBGColorFunctionOK verifies that BGColorFunction returns no error
 // with acceptable input.
 func TestOptionBGColorFunctionOK(t *testing.T) {
@@ -68,6 +109,18 @@ func TestOptionSampleFunctionNil(t *testing.T) {
 	testWaveformOptionFunc(t, SampleFunction(nil), errSampleFunctionNil)
 }
 
+// TestOptionChunkFunctionOK verifies that ChunkFunction returns no error
+// with acceptable input.
+func TestOptionChunkFunctionOK(t *testing.T) {
+	testWaveformOptionFunc(t, ChunkFunction(chunkRMS), nil)
+}
+
+// TestOptionChunkFunctionNil verifies that ChunkFunction does not accept
+// a nil ChunkReduceFunc.
+func TestOptionChunkFunctionNil(t *testing.T) {
+	testWaveformOptionFunc(t, ChunkFunction(nil), errChunkFunctionNil)
+}
+
 // TestOptionResolutionOK verifies that Resolution returns no error with acceptable input.
 func TestOptionResolutionOK(t *testing.T) {
 	testWaveformOptionFunc(t, Resolution(1), nil)
@@ -103,6 +156,233 @@ func TestOptionSharpnessOK(t *testing.T) {
 	testWaveformOptionFunc(t, Sharpness(0), nil)
 }
 
+// TestOptionSmoothOK verifies that Smooth returns no error with acceptable input.
+func TestOptionSmoothOK(t *testing.T) {
+	testWaveformOptionFunc(t, Smooth(3), nil)
+}
+
+// TestOptionSmoothNegative verifies that Smooth does not accept a negative integer.
+func TestOptionSmoothNegative(t *testing.T) {
+	testWaveformOptionFunc(t, Smooth(-1), errSmoothNegative)
+}
+
+// TestOptionSmoothAROK verifies that SmoothAR returns no error with acceptable input.
+func TestOptionSmoothAROK(t *testing.T) {
+	testWaveformOptionFunc(t, SmoothAR(10*time.Millisecond, time.Second), nil)
+}
+
+// TestOptionSmoothARNegative verifies that SmoothAR does not accept negative durations.
+func TestOptionSmoothARNegative(t *testing.T) {
+	testWaveformOptionFunc(t, SmoothAR(-1, 0), errSmoothARNegative)
+	testWaveformOptionFunc(t, SmoothAR(0, -1), errSmoothARNegative)
+}
+
+// TestOptionSmoothEMAOK verifies that SmoothEMA returns no error with acceptable input.
+func TestOptionSmoothEMAOK(t *testing.T) {
+	testWaveformOptionFunc(t, SmoothEMA(0.2), nil)
+	testWaveformOptionFunc(t, SmoothEMA(1), nil)
+}
+
+// TestOptionSmoothEMAInvalid verifies that SmoothEMA does not accept values
+// outside the range (0, 1].
+func TestOptionSmoothEMAInvalid(t *testing.T) {
+	for _, alpha := range []float64{0, -0.5, 1.5, math.NaN()} {
+		testWaveformOptionFunc(t, SmoothEMA(alpha), errSmoothEMAInvalid)
+	}
+}
+
+// TestOptionNormalizedOK verifies that Normalized returns no error with
+// acceptable input.
+func TestOptionNormalizedOK(t *testing.T) {
+	testWaveformOptionFunc(t, Normalized(0.5), nil)
+	testWaveformOptionFunc(t, Normalized(1), nil)
+}
+
+// TestOptionNormalizedInvalid verifies that Normalized does not accept values
+// outside the range (0, 1].
+func TestOptionNormalizedInvalid(t *testing.T) {
+	for _, target := range []float64{0, -0.5, 1.5, math.NaN()} {
+		testWaveformOptionFunc(t, Normalized(target), errNormalizedInvalid)
+	}
+}
+
+// TestOptionClampOK verifies that Clamp returns no error with acceptable
+// input.
+func TestOptionClampOK(t *testing.T) {
+	testWaveformOptionFunc(t, Clamp(0, 0.5), nil)
+	testWaveformOptionFunc(t, Clamp(0.1, math.Inf(1)), nil)
+}
+
+// TestOptionClampInvalid verifies that Clamp does not accept a negative
+// minimum, or a maximum which is not greater than the minimum.
+func TestOptionClampInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, Clamp(-0.1, 0.5), errClampNegative)
+	testWaveformOptionFunc(t, Clamp(math.NaN(), 0.5), errClampNegative)
+	testWaveformOptionFunc(t, Clamp(0.5, 0.5), errClampRange)
+	testWaveformOptionFunc(t, Clamp(0.5, math.NaN()), errClampRange)
+}
+
+// TestOptionPartialWindowOK verifies that PartialWindow returns no error with
+// acceptable input.
+func TestOptionPartialWindowOK(t *testing.T) {
+	testWaveformOptionFunc(t, PartialWindow(PartialWindowPad), nil)
+}
+
+// TestOptionPartialWindowInvalid verifies that PartialWindow does not accept
+// an unknown PartialWindowPolicy.
+func TestOptionPartialWindowInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, PartialWindow(-1), errPartialWindowInvalid)
+}
+
+// TestOptionVectorRendererOK verifies that VectorRenderer returns no error.
+func TestOptionVectorRendererOK(t *testing.T) {
+	testWaveformOptionFunc(t, VectorRenderer(), nil)
+}
+
+// TestOptionOutlineOK verifies that Outline returns no error with acceptable input.
+func TestOptionOutlineOK(t *testing.T) {
+	if _, err := New(nil, VectorRenderer(), Outline(SolidColor(color.Black), 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestOptionOutlineNil verifies that Outline does not accept a nil ColorFunc.
+func TestOptionOutlineNil(t *testing.T) {
+	testWaveformOptionFunc(t, Outline(nil, 2), errOutlineFunctionNil)
+}
+
+// TestOptionCheckpointEveryOK verifies that CheckpointEvery returns no error
+// with acceptable input.
+func TestOptionCheckpointEveryOK(t *testing.T) {
+	testWaveformOptionFunc(t, CheckpointEvery(10, bytes.NewBuffer(nil)), nil)
+}
+
+// TestOptionCheckpointEveryWriterNil verifies that CheckpointEvery does not
+// accept a nil io.Writer.
+func TestOptionCheckpointEveryWriterNil(t *testing.T) {
+	testWaveformOptionFunc(t, CheckpointEvery(10, nil), errCheckpointWriterNil)
+}
+
+// TestOptionCheckpointEveryZero verifies that CheckpointEvery does not accept
+// integer 0.
+func TestOptionCheckpointEveryZero(t *testing.T) {
+	testWaveformOptionFunc(t, CheckpointEvery(0, bytes.NewBuffer(nil)), errCheckpointEveryZero)
+}
+
+// TestOptionInvalidValuesOK verifies that InvalidValues returns no error with
+// acceptable input.
+func TestOptionInvalidValuesOK(t *testing.T) {
+	testWaveformOptionFunc(t, InvalidValues(InvalidValueSkip), nil)
+}
+
+// TestOptionInvalidValuesInvalid verifies that InvalidValues does not accept
+// an unknown InvalidValuePolicy.
+func TestOptionInvalidValuesInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, InvalidValues(-1), errInvalidValuesInvalid)
+}
+
+// TestOptionAnalyzersOK verifies that Analyzers returns no error with
+// acceptable input.
+func TestOptionAnalyzersOK(t *testing.T) {
+	testWaveformOptionFunc(t, Analyzers(NewLoudnessAnalyzer(EBUR128)), nil)
+}
+
+// TestOptionAnalyzersNil verifies that Analyzers does not accept a nil
+// Analyzer.
+func TestOptionAnalyzersNil(t *testing.T) {
+	testWaveformOptionFunc(t, Analyzers(nil), errAnalyzerNil)
+}
+
+// TestOptionLinearLightOK verifies that LinearLight returns no error with
+// acceptable input.
+func TestOptionLinearLightOK(t *testing.T) {
+	testWaveformOptionFunc(t, LinearLight(true), nil)
+}
+
+// TestOptionHeightOK verifies that Height returns no error with acceptable
+// input.
+func TestOptionHeightOK(t *testing.T) {
+	testWaveformOptionFunc(t, Height(101), nil)
+}
+
+// TestOptionHeightZero verifies that Height does not accept a height of 0.
+func TestOptionHeightZero(t *testing.T) {
+	testWaveformOptionFunc(t, Height(0), errHeightZero)
+}
+
+// TestOptionLegacyCenteringOK verifies that LegacyCentering returns no error.
+func TestOptionLegacyCenteringOK(t *testing.T) {
+	testWaveformOptionFunc(t, LegacyCentering(), nil)
+}
+
+// TestOptionMinHeightOK verifies that MinHeight returns no error with
+// acceptable input.
+func TestOptionMinHeightOK(t *testing.T) {
+	testWaveformOptionFunc(t, MinHeight(2), nil)
+}
+
+// TestOptionQuantizeOK verifies that Quantize returns no error with acceptable
+// input.
+func TestOptionQuantizeOK(t *testing.T) {
+	testWaveformOptionFunc(t, Quantize(0), nil)
+	testWaveformOptionFunc(t, Quantize(8), nil)
+}
+
+// TestOptionPixelArtOK verifies that PixelArt returns no error with acceptable
+// input.
+func TestOptionPixelArtOK(t *testing.T) {
+	testWaveformOptionFunc(t, PixelArt(0), nil)
+	testWaveformOptionFunc(t, PixelArt(4), nil)
+}
+
+// TestOptionHeadroomOK verifies that Headroom returns no error with acceptable
+// input.
+func TestOptionHeadroomOK(t *testing.T) {
+	testWaveformOptionFunc(t, Headroom(0.10), nil)
+}
+
+// TestOptionHeadroomInvalid verifies that Headroom does not accept fractions
+// outside the range [0, 1).
+func TestOptionHeadroomInvalid(t *testing.T) {
+	for _, f := range []float64{-0.10, 1, math.NaN()} {
+		testWaveformOptionFunc(t, Headroom(f), errHeadroomInvalid)
+	}
+}
+
+// TestOptionMirrorGapOK verifies that MirrorGap returns no error with
+// acceptable input.
+func TestOptionMirrorGapOK(t *testing.T) {
+	testWaveformOptionFunc(t, MirrorGap(4), nil)
+}
+
+// TestOptionPixelsPerSecondOK verifies that PixelsPerSecond returns no error
+// with acceptable input.
+func TestOptionPixelsPerSecondOK(t *testing.T) {
+	for _, f := range []float64{0.25, 1, 37.5} {
+		testWaveformOptionFunc(t, PixelsPerSecond(f), nil)
+	}
+}
+
+// TestOptionPixelsPerSecondInvalid verifies that PixelsPerSecond does not
+// accept densities which are not positive and finite.
+func TestOptionPixelsPerSecondInvalid(t *testing.T) {
+	for _, f := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		testWaveformOptionFunc(t, PixelsPerSecond(f), errPixelsPerSecondInvalid)
+	}
+}
+
+// TestOptionOutputFormatOK verifies that OutputFormat returns no error with
+// acceptable input.
+func TestOptionOutputFormatOK(t *testing.T) {
+	testWaveformOptionFunc(t, OutputFormat(FormatRGBA64), nil)
+}
+
+// TestOptionOutputFormatInvalid verifies that OutputFormat does not accept
+// an unknown ImageFormat.
+func TestOptionOutputFormatInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, OutputFormat(-1), errOutputFormatInvalid)
+}
+
 // TestWaveformSetOptionsNil verifies that Waveform.SetOptions ignores any
 // nil OptionsFunc arguments.
 func TestWaveformSetOptionsNil(t *testing.T) {
@@ -119,7 +399,7 @@ func TestWaveformSetBGColorFunction(t *testing.T) {
 	}
 
 	// Validate that struct members are set properly
-	if w.bgColorFn == nil {
+	if w.style.bgColorFn == nil {
 		t.Fatalf("SetBGColorFunction failed, nil function member")
 	}
 }
@@ -134,7 +414,7 @@ func TestWaveformSetFGColorFunction(t *testing.T) {
 	}
 
 	// Validate that struct members are set properly
-	if w.fgColorFn == nil {
+	if w.style.fgColorFn == nil {
 		t.Fatalf("SetFGColorFunction failed, nil function member")
 	}
 }
@@ -186,11 +466,11 @@ func TestWaveformSetScale(t *testing.T) {
 	}
 
 	// Validate that struct members are set properly
-	if w.scaleX != x {
-		t.Fatalf("unexpected scale X: %v != %v", w.scaleX, x)
+	if w.style.scaleX != x {
+		t.Fatalf("unexpected scale X: %v != %v", w.style.scaleX, x)
 	}
-	if w.scaleY != y {
-		t.Fatalf("unexpected scale Y: %v != %v", w.scaleY, y)
+	if w.style.scaleY != y {
+		t.Fatalf("unexpected scale Y: %v != %v", w.style.scaleY, y)
 	}
 }
 
@@ -204,7 +484,7 @@ func TestWaveformSetScaleClipping(t *testing.T) {
 	}
 
 	// Validate that struct members are set properly
-	if !w.scaleClipping {
+	if !w.style.scaleClipping {
 		t.Fatalf("SetScaleClipping failed, false scaleClipping member")
 	}
 }
@@ -222,16 +502,169 @@ func TestWaveformSetSharpness(t *testing.T) {
 	}
 
 	// Validate that struct members are set properly
-	if w.sharpness != sharpness {
-		t.Fatalf("unexpected sharpness: %v != %v", w.sharpness, sharpness)
+	if w.style.sharpness != sharpness {
+		t.Fatalf("unexpected sharpness: %v != %v", w.style.sharpness, sharpness)
+	}
+}
+
+// TestWaveformSetSmooth verifies that the Waveform.SetSmooth method properly
+// modifies struct members.
+func TestWaveformSetSmooth(t *testing.T) {
+	// Predefined test values
+	smooth := 5
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSmooth(smooth); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.style.smooth != smooth {
+		t.Fatalf("unexpected smooth: %v != %v", w.style.smooth, smooth)
+	}
+}
+
+// TestWaveformSetSmoothAR verifies that the Waveform.SetSmoothAR method properly
+// modifies struct members.
+func TestWaveformSetSmoothAR(t *testing.T) {
+	// Predefined test values
+	attack := 10 * time.Millisecond
+	release := time.Second
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSmoothAR(attack, release); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.style.attack != attack {
+		t.Fatalf("unexpected attack: %v != %v", w.style.attack, attack)
+	}
+	if w.style.release != release {
+		t.Fatalf("unexpected release: %v != %v", w.style.release, release)
+	}
+}
+
+// TestWaveformSetPartialWindow verifies that the Waveform.SetPartialWindow method
+// properly modifies struct members.
+func TestWaveformSetPartialWindow(t *testing.T) {
+	// Predefined test values
+	policy := PartialWindowDrop
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetPartialWindow(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.partialWindow != policy {
+		t.Fatalf("unexpected partial window policy: %v != %v", w.partialWindow, policy)
+	}
+}
+
+// TestWaveformSetVectorRenderer verifies that the Waveform.SetVectorRenderer method
+// properly modifies struct members.
+func TestWaveformSetVectorRenderer(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetVectorRenderer(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.style.vector {
+		t.Fatalf("SetVectorRenderer failed, false vector member")
+	}
+}
+
+// TestWaveformSetOutline verifies that the Waveform.SetOutline method properly
+// modifies struct members.
+func TestWaveformSetOutline(t *testing.T) {
+	// Predefined test values
+	width := uint(2)
+
+	// Generate empty Waveform with vector renderer, apply parameters
+	w := &Waveform{}
+	if err := w.SetVectorRenderer(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetOutline(SolidColor(color.Black), width); err != nil {
+		t.Fatal(err)
 	}
+
+	// Validate that struct members are set properly
+	if w.style.outlineFn == nil {
+		t.Fatalf("SetOutline failed, nil function member")
+	}
+	if w.style.outlineWidth != width {
+		t.Fatalf("unexpected outline width: %v != %v", w.style.outlineWidth, width)
+	}
+}
+
+// TestOptionFallbackDecoderOK verifies that FallbackDecoder returns no error
+// with acceptable input.
+func TestOptionFallbackDecoderOK(t *testing.T) {
+	testWaveformOptionFunc(t, FallbackDecoder(FFmpeg(44100, 2)), nil)
+}
+
+// TestOptionFallbackDecoderInvalid verifies that FallbackDecoder does not
+// accept a nil or incomplete ExternalDecoder.
+func TestOptionFallbackDecoderInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, FallbackDecoder(nil), errFallbackDecoderNil)
+	testWaveformOptionFunc(t, FallbackDecoder(&ExternalDecoder{
+		SampleRate: 44100,
+		Channels:   2,
+	}), errFallbackDecoderCommandEmpty)
+	testWaveformOptionFunc(t, FallbackDecoder(FFmpeg(44100, 0)), errFallbackDecoderConfigInvalid)
+	testWaveformOptionFunc(t, FallbackDecoder(FFmpeg(-1, 2)), errFallbackDecoderConfigInvalid)
+}
+
+// TestOptionOffsetOK verifies that Offset returns no error with acceptable
+// input.
+func TestOptionOffsetOK(t *testing.T) {
+	testWaveformOptionFunc(t, Offset(0), nil)
+	testWaveformOptionFunc(t, Offset(time.Minute), nil)
+}
+
+// TestOptionOffsetNegative verifies that Offset does not accept a negative
+// duration.
+func TestOptionOffsetNegative(t *testing.T) {
+	testWaveformOptionFunc(t, Offset(-time.Second), errOffsetNegative)
+}
+
+// TestOptionDurationOK verifies that Duration returns no error with
+// acceptable input.
+func TestOptionDurationOK(t *testing.T) {
+	testWaveformOptionFunc(t, Duration(30*time.Second), nil)
+}
+
+// TestOptionDurationInvalid verifies that Duration does not accept a zero or
+// negative duration.
+func TestOptionDurationInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, Duration(0), errDurationZero)
+	testWaveformOptionFunc(t, Duration(-time.Second), errDurationNegative)
+}
+
+// TestOptionProgressFunctionOK verifies that ProgressFunction returns no
+// error with acceptable input.
+func TestOptionProgressFunctionOK(t *testing.T) {
+	testWaveformOptionFunc(t, ProgressFunction(func(Progress) {}), nil)
+}
+
+// TestOptionProgressFunctionNil verifies that ProgressFunction does not accept
+// a nil ProgressFunc.
+func TestOptionProgressFunctionNil(t *testing.T) {
+	testWaveformOptionFunc(t, ProgressFunction(nil), errProgressFunctionNil)
 }
 
 // testWaveformOptionFunc is a test helper which verifies that applying the
 // input OptionsFunc to a new Waveform struct generates the appropriate
 // error output.
 func testWaveformOptionFunc(t *testing.T, fn OptionsFunc, err error) {
-	if _, wErr := New(nil, fn); wErr != err {
+	if _, wErr := New(nil, fn); !errors.Is(wErr, err) {
 		t.Fatalf("unexpected error: %v != %v", wErr, err)
 	}
 }
@@ -0,0 +1,212 @@
+package waveform
+
+import (
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+// WindowFunc is a function which returns the weight applied to the sample
+// frame at index i of a window of n sample frames, before the window is
+// reduced to a computed value.  WindowFuncs are applied using the
+// WindowFunction option.
+type WindowFunc func(i int, n int) float64
+
+// HannWindow is a WindowFunc which applies a Hann window: a raised cosine
+// which tapers to zero at both edges of the window.  Combined with the Overlap
+// option, it produces a smooth waveform in which each value blends gradually
+// into its neighbors.
+func HannWindow(i int, n int) float64 {
+	if n <= 1 {
+		return 1
+	}
+
+	return 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+}
+
+// HammingWindow is a WindowFunc which applies a Hamming window: a raised
+// cosine which, unlike HannWindow, does not taper completely to zero at the
+// edges of the window.
+func HammingWindow(i int, n int) float64 {
+	if n <= 1 {
+		return 1
+	}
+
+	return 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+}
+
+// windowHop is a single hop of audio: the samples of one window at the
+// current resolution.
+type windowHop struct {
+	window  int
+	samples audio.Float64
+	full    bool
+}
+
+// windower computes values from overlapping windows of audio, each of which
+// spans several hops and is centered on a single hop.  Because each window
+// extends past the end of its hop, the value for a hop is computed only once
+// the following hops have been read, or the stream has ended.
+type windower struct {
+	// reduceFn reduces the samples of the window centered on the hop with the
+	// input index
+	reduceFn func(window int, samples audio.Float64) float64
+
+	// skipFn reports whether the window centered on the hop with the input
+	// index is skipped by DownsampleAfter, in which case last, the value of
+	// the last window which was reduced, is repeated
+	skipFn func(window int) bool
+	last   float64
+
+	weightFn WindowFunc
+	partial  PartialWindowPolicy
+	channels int
+
+	// before and after are the number of hops in each window before and
+	// after its center hop
+	before int
+	after  int
+
+	// hops are the hops which may still be part of a window, and next is the
+	// index in hops of the next center hop to be reduced
+	hops []windowHop
+	next int
+
+	// buf holds the samples of a single window, and weights holds the
+	// normalized weights of each sample frame in a window of len(weights)
+	// frames
+	buf     audio.Float64
+	weights []float64
+}
+
+// newWindower creates a windower for the input Waveform, which reads audio
+// with the input configuration.  Values are reduced by the Waveform's
+// ChunkReduceFunc or SampleReduceFunc; a Chunk passed to a ChunkReduceFunc
+// has the index and start time of its center hop, and the samples of its
+// entire window.
+func (w *Waveform) newWindower(config audio.Config) *windower {
+	overlap := int(w.overlap)
+	if overlap == 0 {
+		overlap = 1
+	}
+
+	return &windower{
+		reduceFn: func(window int, samples audio.Float64) float64 {
+			return w.reduce(config, window, samples)
+		},
+		skipFn: func(window int) bool {
+			return w.skipWindow(config, window)
+		},
+		weightFn: w.windowFn,
+		partial:  w.partialWindow,
+		channels: config.Channels,
+		before:   (overlap - 1) / 2,
+		after:    overlap - 1 - (overlap-1)/2,
+	}
+}
+
+// push adds the hop of audio with the input index to the windower, of which n
+// samples were read, and calls emit with the value and sample count of each center hop whose window
+// is now complete.
+func (wd *windower) push(window int, samples audio.Float64, n int, emit func(value float64, n int) error) error {
+	hop := windowHop{
+		window:  window,
+		samples: append(audio.Float64(nil), samples[:n]...),
+		full:    n == len(samples),
+	}
+	wd.hops = append(wd.hops, hop)
+
+	for wd.next < len(wd.hops) && len(wd.hops)-1-wd.next >= wd.after {
+		if err := wd.reduce(emit); err != nil {
+			return err
+		}
+	}
+
+	// Discard hops which can no longer be part of a window
+	if drop := wd.next - wd.before; drop > 0 {
+		wd.hops = append(wd.hops[:0], wd.hops[drop:]...)
+		wd.next -= drop
+	}
+
+	return nil
+}
+
+// flush calls emit with the value and sample count of each remaining center
+// hop, once the end of the stream is reached.  Windows at the end of the
+// stream contain only the hops which were read.
+func (wd *windower) flush(emit func(value float64, n int) error) error {
+	for wd.next < len(wd.hops) {
+		if err := wd.reduce(emit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reduce reduces the window centered on the next center hop, and calls emit
+// with its value.  Empty hops never produce a value, and partial hops do not
+// under the PartialWindowDrop policy.
+func (wd *windower) reduce(emit func(value float64, n int) error) error {
+	i := wd.next
+	wd.next++
+
+	center := wd.hops[i]
+	if len(center.samples) == 0 || (!center.full && wd.partial == PartialWindowDrop) {
+		return nil
+	}
+
+	if wd.skipFn(center.window) {
+		return emit(wd.last, len(center.samples))
+	}
+
+	start, end := i-wd.before, i+wd.after+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(wd.hops) {
+		end = len(wd.hops)
+	}
+
+	wd.buf = wd.buf[:0]
+	for _, h := range wd.hops[start:end] {
+		wd.buf = append(wd.buf, h.samples...)
+	}
+	wd.applyWeights(wd.buf)
+
+	wd.last = wd.reduceFn(center.window, wd.buf)
+	return emit(wd.last, len(center.samples))
+}
+
+// applyWeights multiplies each sample frame of a window by its weight, if a
+// WindowFunc is in use.  Weights are normalized so that their root mean square
+// is 1, so that applying a window does not change the root mean square of
+// constant audio.
+func (wd *windower) applyWeights(samples audio.Float64) {
+	if wd.weightFn == nil {
+		return
+	}
+
+	frames := (len(samples) + wd.channels - 1) / wd.channels
+	if len(wd.weights) != frames {
+		wd.weights = make([]float64, frames)
+
+		var sumSquare float64
+		for i := range wd.weights {
+			v := wd.weightFn(i, frames)
+			wd.weights[i] = v
+			sumSquare += v * v
+		}
+
+		if sumSquare > 0 {
+			scale := 1 / math.Sqrt(sumSquare/float64(frames))
+			for i := range wd.weights {
+				wd.weights[i] *= scale
+			}
+		}
+	}
+
+	for i := range samples {
+		samples[i] *= wd.weights[i/wd.channels]
+	}
+}
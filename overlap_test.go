@@ -0,0 +1,245 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestWaveformOverlap verifies that Overlap computes each value from a window
+// spanning several hops, centered on its own hop.
+func TestWaveformOverlap(t *testing.T) {
+	// Mono audio at 4Hz, read one sample frame per hop
+	pcm := []int16{0, 32767, 0, 0, 32767, 32767}
+	wav := makeWAV(4, 1, pcm)
+
+	// RMS of each window of up to three hops, truncated at the edges of the
+	// stream
+	var want []float64
+	for i := range pcm {
+		var sumSquare float64
+		var n int
+		for j := i - 1; j <= i+1; j++ {
+			if j < 0 || j >= len(pcm) {
+				continue
+			}
+
+			v := float64(pcm[j]) / 32767
+			sumSquare += v * v
+			n++
+		}
+
+		want = append(want, math.Sqrt(sumSquare/float64(n)))
+	}
+
+	w, err := New(bytes.NewReader(wav), Resolution(4), Overlap(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vs.Values) != len(want) {
+		t.Fatalf("unexpected number of values: %d != %d", len(vs.Values), len(want))
+	}
+	for i := range want {
+		if math.Abs(vs.Values[i]-want[i]) > 1e-9 {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, vs.Values[i], want[i])
+		}
+	}
+
+	// Each value still represents a single hop of audio
+	for i, c := range vs.Counts {
+		if c != 1 {
+			t.Fatalf("[%02d] unexpected sample count: %d", i, c)
+		}
+	}
+}
+
+// TestWaveformOverlapHann verifies that a Hann window over overlapping windows
+// preserves the level of constant audio, and smooths transitions.
+func TestWaveformOverlapHann(t *testing.T) {
+	// Mono audio at 16Hz: one second of silence, followed by one second at
+	// half scale
+	pcm := make([]int16, 32)
+	for i := 16; i < len(pcm); i++ {
+		pcm[i] = 16384
+	}
+	wav := makeWAV(16, 1, pcm)
+
+	compute := func(options ...OptionsFunc) []float64 {
+		w, err := New(bytes.NewReader(wav), append([]OptionsFunc{Resolution(8)}, options...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return values
+	}
+
+	plain := compute()
+	smooth := compute(Overlap(4), WindowFunction(HannWindow))
+
+	if len(plain) != 16 || len(smooth) != len(plain) {
+		t.Fatalf("unexpected number of values: %d, %d", len(plain), len(smooth))
+	}
+
+	// Constant audio, far from the transition, is unchanged
+	for _, i := range []int{0, 15} {
+		if math.Abs(smooth[i]-plain[i]) > 1e-3 {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, smooth[i], plain[i])
+		}
+	}
+
+	// Without overlap, the transition is a single step, but with overlap it
+	// rises gradually over several values
+	var steps int
+	for i := 1; i < len(smooth); i++ {
+		if smooth[i] < smooth[i-1]-1e-9 {
+			t.Fatalf("values are not monotonic: %v", smooth)
+		}
+		if smooth[i]-smooth[i-1] > 1e-3 {
+			steps++
+		}
+	}
+	if steps < 3 {
+		t.Fatalf("transition is not smoothed: %v", smooth)
+	}
+}
+
+// TestWaveformOverlapStreaming verifies that values computed from overlapping
+// windows are delivered to a ValueFunc in order, with the durations of their
+// hops.
+func TestWaveformOverlapStreaming(t *testing.T) {
+	options := []OptionsFunc{Resolution(4), Overlap(2), WindowFunction(HammingWindow)}
+
+	w, err := New(bytes.NewReader(wavFile), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = New(bytes.NewReader(wavFile), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		values    []float64
+		durations []time.Duration
+	)
+	err = w.ComputeFunc(func(n int, value float64, d time.Duration) error {
+		if n != len(values) {
+			t.Fatalf("unexpected value index: %d != %d", n, len(values))
+		}
+
+		values = append(values, value)
+		durations = append(durations, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(values), fmt.Sprint(want.Values); got != want {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+	if got, want := fmt.Sprint(durations), fmt.Sprint(want.Durations); got != want {
+		t.Fatalf("unexpected durations:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// TestWaveformOverlapPartialDrop verifies that PartialWindowDrop discards the
+// value for a partial hop, even when its window overlaps full hops.
+func TestWaveformOverlapPartialDrop(t *testing.T) {
+	// 2.5 hops of mono audio at 4Hz, read at 2 hops per second
+	wav := makeWAV(4, 1, []int16{16384, 16384, 16384, 16384, 16384})
+
+	for _, tt := range []struct {
+		policy PartialWindowPolicy
+		n      int
+	}{
+		{PartialWindowTrim, 3},
+		{PartialWindowDrop, 2},
+	} {
+		w, err := New(bytes.NewReader(wav), Resolution(2), Overlap(3), PartialWindow(tt.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(values) != tt.n {
+			t.Fatalf("[%d] unexpected number of values: %d != %d", tt.policy, len(values), tt.n)
+		}
+	}
+}
+
+// TestWaveformOverlapResume verifies that computations which use Overlap
+// cannot be resumed from a Checkpoint.
+func TestWaveformOverlapResume(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile), Overlap(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Resume(&Checkpoint{}); err != errOverlapConflictsCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errOverlapConflictsCheckpoint)
+	}
+}
+
+// TestWindowFuncs verifies the weights produced by each WindowFunc at the
+// edges and center of a window.
+func TestWindowFuncs(t *testing.T) {
+	var tests = []struct {
+		name   string
+		fn     WindowFunc
+		edge   float64
+		center float64
+	}{
+		{name: "Hann", fn: HannWindow, edge: 0, center: 1},
+		{name: "Hamming", fn: HammingWindow, edge: 0.08, center: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, i := range []int{0, 8} {
+				if v := tt.fn(i, 9); math.Abs(v-tt.edge) > 1e-9 {
+					t.Fatalf("[%d] unexpected edge weight: %v != %v", i, v, tt.edge)
+				}
+			}
+			if v := tt.fn(4, 9); math.Abs(v-tt.center) > 1e-9 {
+				t.Fatalf("unexpected center weight: %v != %v", v, tt.center)
+			}
+			if v := tt.fn(0, 1); v != 1 {
+				t.Fatalf("unexpected weight for single frame: %v", v)
+			}
+		})
+	}
+}
+
+// TestOptionOverlapZero verifies that Overlap does not accept integer 0.
+func TestOptionOverlapZero(t *testing.T) {
+	testWaveformOptionFunc(t, Overlap(0), errOverlapZero)
+}
+
+// TestOptionWindowFunctionNil verifies that WindowFunction does not accept a
+// nil WindowFunc.
+func TestOptionWindowFunctionNil(t *testing.T) {
+	testWaveformOptionFunc(t, WindowFunction(nil), errWindowFunctionNil)
+}
@@ -0,0 +1,142 @@
+package waveform
+
+import (
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EnvelopePath returns the outline of the waveform envelope which the vector
+// renderer fills for a slice of float64 values, as the data of an SVG path
+// element, suitable for use as its "d" attribute.  Coordinates are in pixels,
+// within the bounds returned by DrawBounds.
+//
+// The path is made up of straight lines and quadratic Bézier curves, and is
+// identical to the path rasterized by the VectorRenderer option, so that
+// applications may draw a waveform using their own canvas, SVG, or WebGL
+// renderers.  Colors, outlines, and the renderer in use are ignored, but all
+// options which affect the shape of the waveform, such as Scale, Sharpness,
+// Headroom, and MirrorGap, are applied.  When a mirror gap is in use, the path
+// contains two closed subpaths: one for each half of the waveform.  If no
+// values are given, the path is empty.
+func (s RenderStyle) EnvelopePath(values []float64) (string, error) {
+	var p svgPath
+	if err := s.traceValues(&p, values); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(p.b.String()), nil
+}
+
+// EnvelopePolygons returns the outline of the waveform envelope which the
+// vector renderer fills for a slice of float64 values, in the same way as
+// EnvelopePath, as a series of closed polygons.  Each curve of the envelope is
+// approximated by straight lines, and each point is rounded to the nearest
+// pixel.  When a mirror gap is in use, one polygon is returned for each half
+// of the waveform; otherwise, a single polygon is returned.  If no values are
+// given, no polygons are returned.
+func (s RenderStyle) EnvelopePolygons(values []float64) ([][]image.Point, error) {
+	var p polygonPath
+	if err := s.traceValues(&p, values); err != nil {
+		return nil, err
+	}
+
+	return p.polygons, nil
+}
+
+// EnvelopePath returns the outline of the waveform envelope for a slice of
+// float64 values as SVG path data, in the same way as
+// RenderStyle.EnvelopePath, using the current options of the receiving
+// Waveform struct.
+func (w *Waveform) EnvelopePath(values []float64) (string, error) {
+	return w.Style().EnvelopePath(values)
+}
+
+// EnvelopePolygons returns the outline of the waveform envelope for a slice
+// of float64 values as a series of closed polygons, in the same way as
+// RenderStyle.EnvelopePolygons, using the current options of the receiving
+// Waveform struct.
+func (w *Waveform) EnvelopePolygons(values []float64) ([][]image.Point, error) {
+	return w.Style().EnvelopePolygons(values)
+}
+
+// traceValues lays out a slice of values as the vector renderer would, and
+// adds the path of their envelope to z.
+func (s *RenderStyle) traceValues(z pather, values []float64) error {
+	ls, values, _, err := s.layout(values, nil)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to trace for an empty waveform
+	if len(values) == 0 {
+		return nil
+	}
+
+	maxX, maxY := len(values)*int(ls.scaleX), ls.imageHeight()
+	top, base := ls.envelope(values, nil, maxX, maxY)
+	ls.tracePath(z, top, ls.mirrorAxis(maxY), base)
+
+	return nil
+}
+
+// svgPath is a pather which produces SVG path data.
+type svgPath struct {
+	b strings.Builder
+}
+
+func (p *svgPath) MoveTo(x float32, y float32) { p.command("M", x, y) }
+func (p *svgPath) LineTo(x float32, y float32) { p.command("L", x, y) }
+func (p *svgPath) ClosePath()                  { p.command("Z") }
+
+func (p *svgPath) QuadTo(bx float32, by float32, cx float32, cy float32) {
+	p.command("Q", bx, by, cx, cy)
+}
+
+// command writes a single path command and its coordinates, using the
+// shortest representation of each coordinate.
+func (p *svgPath) command(c string, coords ...float32) {
+	p.b.WriteString(c)
+	for i, v := range coords {
+		if i > 0 {
+			p.b.WriteByte(' ')
+		}
+		p.b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	p.b.WriteByte(' ')
+}
+
+// polygonPath is a pather which produces polygons, approximating each curve
+// using straight lines.
+type polygonPath struct {
+	polygons [][]image.Point
+	points   []point
+}
+
+func (p *polygonPath) MoveTo(x float32, y float32) { p.points = []point{{x, y}} }
+func (p *polygonPath) LineTo(x float32, y float32) { p.points = append(p.points, point{x, y}) }
+
+func (p *polygonPath) QuadTo(bx float32, by float32, cx float32, cy float32) {
+	p.points = appendQuad(p.points, p.points[len(p.points)-1], point{bx, by}, point{cx, cy})
+}
+
+// ClosePath completes the current polygon, rounding each of its points to the
+// nearest pixel and discarding consecutive duplicate points.
+func (p *polygonPath) ClosePath() {
+	poly := make([]image.Point, 0, len(p.points))
+	for _, pt := range p.points {
+		ip := image.Pt(
+			int(math.Round(float64(pt.X))),
+			int(math.Round(float64(pt.Y))),
+		)
+		if len(poly) > 0 && poly[len(poly)-1] == ip {
+			continue
+		}
+
+		poly = append(poly, ip)
+	}
+
+	p.polygons = append(p.polygons, poly)
+	p.points = nil
+}
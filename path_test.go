@@ -0,0 +1,144 @@
+package waveform
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestWaveformEnvelopePath verifies that EnvelopePath produces SVG path data
+// for the envelope drawn by the vector renderer.
+func TestWaveformEnvelopePath(t *testing.T) {
+	var tests = []struct {
+		name     string
+		options  []OptionsFunc
+		subpaths int
+	}{
+		{
+			name:     "default",
+			subpaths: 1,
+		},
+		{
+			name:     "mirror gap",
+			options:  []OptionsFunc{MirrorGap(4)},
+			subpaths: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(nil, append([]OptionsFunc{Scale(4, 1)}, tt.options...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := w.EnvelopePath([]float64{0.10, 0.20, 0.10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(d, "M0 ") || !strings.HasSuffix(d, "Z") {
+				t.Fatalf("unexpected path: %q", d)
+			}
+			if n := strings.Count(d, "M"); n != tt.subpaths {
+				t.Fatalf("unexpected number of subpaths: %d != %d", n, tt.subpaths)
+			}
+			if n := strings.Count(d, "Z"); n != tt.subpaths {
+				t.Fatalf("unexpected number of closed subpaths: %d != %d", n, tt.subpaths)
+			}
+			if !strings.Contains(d, "Q") {
+				t.Fatalf("path contains no curves: %q", d)
+			}
+		})
+	}
+}
+
+// TestWaveformEnvelopePolygons verifies that EnvelopePolygons produces
+// polygons which span the bounds of the waveform image, and are symmetric
+// about its center.
+func TestWaveformEnvelopePolygons(t *testing.T) {
+	w, err := New(nil, Scale(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.10, 0.10}
+	polygons, err := w.EnvelopePolygons(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polygons) != 1 {
+		t.Fatalf("unexpected number of polygons: %d", len(polygons))
+	}
+
+	bounds := w.Style().DrawBounds(values)
+	min, max := polygons[0][0], polygons[0][0]
+	for _, p := range polygons[0] {
+		if !p.In(bounds.Inset(-1)) {
+			t.Fatalf("point outside of image bounds: %v", p)
+		}
+
+		if p.X < min.X {
+			min.X = p.X
+		}
+		if p.Y < min.Y {
+			min.Y = p.Y
+		}
+		if p.X > max.X {
+			max.X = p.X
+		}
+		if p.Y > max.Y {
+			max.Y = p.Y
+		}
+	}
+
+	// A value of 0.10 is scaled to a half-height of 19.2 pixels
+	halfY := bounds.Max.Y / 2
+	if min.X != 0 || max.X != bounds.Max.X {
+		t.Fatalf("unexpected horizontal extent: %d-%d", min.X, max.X)
+	}
+	if min.Y != halfY-19 || max.Y != halfY+19 {
+		t.Fatalf("unexpected vertical extent: %d-%d", min.Y, max.Y)
+	}
+
+	// A mirror gap splits the envelope in two
+	if err := w.SetOptions(MirrorGap(4)); err != nil {
+		t.Fatal(err)
+	}
+	if polygons, err = w.EnvelopePolygons(values); err != nil {
+		t.Fatal(err)
+	}
+	if len(polygons) != 2 {
+		t.Fatalf("unexpected number of polygons: %d", len(polygons))
+	}
+}
+
+// TestWaveformEnvelopePathEmpty verifies that no path is produced when no
+// values are given.
+func TestWaveformEnvelopePathEmpty(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err := w.EnvelopePath(nil); err != nil || d != "" {
+		t.Fatalf("unexpected path: %q, %v", d, err)
+	}
+	if p, err := w.EnvelopePolygons(nil); err != nil || p != nil {
+		t.Fatalf("unexpected polygons: %v, %v", p, err)
+	}
+}
+
+// TestWaveformEnvelopePathInvalidValue verifies that EnvelopePath applies the
+// InvalidValues policy.
+func TestWaveformEnvelopePathInvalidValue(t *testing.T) {
+	w, err := New(nil, InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.EnvelopePath([]float64{0.1, math.NaN()})
+	if _, ok := err.(*ValueError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+// maxPCMHeaderSize is the maximum number of bytes which precede the first
+// sample frame of a stream which is read by seeking, which limits the memory
+// allocated for streams with large metadata chunks.
+const maxPCMHeaderSize = 1 << 20
+
+// errNotPCM is returned when a stream is not an uncompressed WAV or AIFF stream,
+// and must be decoded from its beginning.
+var errNotPCM = errors.New("waveform: stream is not uncompressed PCM")
+
+// pcmLayout describes the layout of an uncompressed WAV or AIFF stream, so
+// that the stream can be read beginning at any sample frame by seeking, rather
+// than by decoding and discarding the audio before it.
+type pcmLayout struct {
+	// header is a copy of the stream up to its first sample frame, which is
+	// at position start
+	header []byte
+	start  int64
+
+	// sampleRate is the sample rate of the stream, frameSize is the number of
+	// bytes in each sample frame, and frames is the number of sample frames
+	sampleRate int
+	frameSize  int64
+	frames     int64
+
+	// sizes rewrites the sizes in a copy of the header, for a stream which
+	// begins the input number of sample frames later
+	sizes func(header []byte, frames int64)
+}
+
+// readPCMLayout reads the header of an uncompressed WAV or AIFF stream from
+// its current position.  If the stream is in any other format, it is returned
+// to its original position, and errNotPCM is returned.
+func readPCMLayout(rs io.ReadSeeker) (*pcmLayout, error) {
+	base, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	r := io.TeeReader(io.LimitReader(rs, maxPCMHeaderSize), &header)
+
+	var l *pcmLayout
+	var form [12]byte
+	if _, err := io.ReadFull(r, form[:]); err == nil {
+		switch {
+		case string(form[0:4]) == "RIFF" && string(form[8:12]) == "WAVE":
+			l, err = readWAVLayout(r, &header)
+		case string(form[0:4]) == "FORM" && (string(form[8:12]) == "AIFF" || string(form[8:12]) == "AIFC"):
+			l, err = readAIFFLayout(r, &header, string(form[8:12]) == "AIFC")
+		}
+		if err != nil {
+			l = nil
+		}
+	}
+
+	if l == nil {
+		if _, err := rs.Seek(base, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		return nil, errNotPCM
+	}
+
+	l.header = header.Bytes()
+	l.start = base + int64(len(l.header))
+
+	// Streams may be shorter than their headers indicate
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if frames := (end - l.start) / l.frameSize; frames < l.frames {
+		l.frames = frames
+	}
+
+	return l, nil
+}
+
+// readWAVLayout reads the chunks of a WAV stream which follow its RIFF header,
+// up to the first sample frame.
+func readWAVLayout(r io.Reader, header *bytes.Buffer) (*pcmLayout, error) {
+	l := new(pcmLayout)
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+
+		switch string(hdr[0:4]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, errNotPCM
+			}
+
+			b := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, err
+			}
+
+			// Only integer and floating point PCM have a fixed frame size
+			format := binary.LittleEndian.Uint16(b[0:2])
+			if format == 0xfffe && size >= 26 {
+				format = binary.LittleEndian.Uint16(b[24:26])
+			}
+			if format != 1 && format != 3 {
+				return nil, errNotPCM
+			}
+
+			l.sampleRate = int(binary.LittleEndian.Uint32(b[4:8]))
+			l.frameSize = int64(binary.LittleEndian.Uint16(b[12:14]))
+		case "data":
+			if l.frameSize == 0 || l.sampleRate == 0 {
+				return nil, errNotPCM
+			}
+
+			// The data chunk size and RIFF chunk size both include the
+			// sample frames which are skipped
+			at := header.Len() - 4
+			l.frames = size / l.frameSize
+			l.sizes = func(h []byte, frames int64) {
+				skip := uint32(frames * l.frameSize)
+				binary.LittleEndian.PutUint32(h[at:], binary.LittleEndian.Uint32(h[at:])-skip)
+				binary.LittleEndian.PutUint32(h[4:], binary.LittleEndian.Uint32(h[4:])-skip)
+			}
+
+			return l, nil
+		default:
+			// Skip unknown chunks, which are padded to an even length
+			if _, err := io.CopyN(ioutil.Discard, r, size+size%2); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readAIFFLayout reads the chunks of an AIFF or AIFF-C stream which follow its
+// FORM header, up to the first sample frame.
+func readAIFFLayout(r io.Reader, header *bytes.Buffer, aifc bool) (*pcmLayout, error) {
+	l := new(pcmLayout)
+	var comm int
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[4:8]))
+
+		switch string(hdr[0:4]) {
+		case "COMM":
+			if size < 18 || (aifc && size < 22) {
+				return nil, errNotPCM
+			}
+
+			comm = header.Len()
+			b := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, err
+			}
+
+			// Only uncompressed AIFF-C streams have a fixed frame size
+			if aifc && string(b[18:22]) != "NONE" && string(b[18:22]) != "sowt" {
+				return nil, errNotPCM
+			}
+
+			channels := int64(binary.BigEndian.Uint16(b[0:2]))
+			bits := int64(binary.BigEndian.Uint16(b[6:8]))
+			rate := extendedToFloat(b[8:18])
+			if rate < 1 || rate > math.MaxInt32 {
+				return nil, errNotPCM
+			}
+
+			l.frames = int64(binary.BigEndian.Uint32(b[2:6]))
+			l.sampleRate = int(rate)
+			l.frameSize = channels * ((bits + 7) / 8)
+		case "SSND":
+			if comm == 0 || l.frameSize == 0 || size < 8 {
+				return nil, errNotPCM
+			}
+
+			// Skip any offset to the first sample frame
+			at := header.Len() - 4
+			var ssnd [8]byte
+			if _, err := io.ReadFull(r, ssnd[:]); err != nil {
+				return nil, err
+			}
+			offset := int64(binary.BigEndian.Uint32(ssnd[0:4]))
+			if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+				return nil, err
+			}
+
+			if chunk := (size - 8 - offset) / l.frameSize; chunk < l.frames {
+				l.frames = chunk
+			}
+
+			// The SSND chunk size, FORM chunk size, and COMM frame count all
+			// include the sample frames which are skipped
+			l.sizes = func(h []byte, frames int64) {
+				skip := uint32(frames * l.frameSize)
+				binary.BigEndian.PutUint32(h[at:], binary.BigEndian.Uint32(h[at:])-skip)
+				binary.BigEndian.PutUint32(h[4:], binary.BigEndian.Uint32(h[4:])-skip)
+				binary.BigEndian.PutUint32(h[comm+2:], binary.BigEndian.Uint32(h[comm+2:])-uint32(frames))
+			}
+
+			return l, nil
+		default:
+			// Skip unknown chunks, which are padded to an even length
+			if _, err := io.CopyN(ioutil.Discard, r, size+size%2); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// seek seeks rs to the input sample frame, and returns an io.Reader which
+// reads a copy of the stream's header, adjusted to describe only the
+// remaining sample frames, followed by the remainder of the stream.  If the
+// stream has fewer sample frames, it is read from its end.
+func (l *pcmLayout) seek(rs io.ReadSeeker, frame int64) (io.Reader, error) {
+	if frame > l.frames {
+		frame = l.frames
+	}
+
+	if _, err := rs.Seek(l.start+frame*l.frameSize, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(nil), l.header...)
+	l.sizes(header, frame)
+
+	return io.MultiReader(bytes.NewReader(header), rs), nil
+}
+
+// seekStream returns an io.Reader which reads the input stream beginning at
+// the first sample frame needed by a computation, and reports whether the
+// stream was read this way.  A computation which begins at an offset, or which
+// resumes from a Checkpoint, does not need the audio before it.
+//
+// Only seekable, uncompressed WAV and AIFF streams can be read this way, so
+// all other streams are read from their beginning, and the audio which is not
+// needed is decoded and discarded.
+func (w *Waveform) seekStream(cp *Checkpoint) (io.Reader, bool, error) {
+	rs, ok := w.r.(io.ReadSeeker)
+	if !ok || w.samples != nil || w.decoder != nil || (w.offset == 0 && cp == nil) {
+		return w.r, false, nil
+	}
+
+	l, err := readPCMLayout(rs)
+	if err != nil {
+		if err == errNotPCM {
+			return w.r, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	// A Checkpoint cannot have been taken beyond the end of the stream
+	frame := w.startFrame(l.sampleRate, cp)
+	if cp != nil && frame > l.frames {
+		return nil, false, errCheckpointMismatch
+	}
+
+	r, err := l.seek(rs, frame)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return r, true, nil
+}
+
+// startFrame returns the number of sample frames before the first sample frame
+// needed by a computation on a stream with the input sample rate, which begins
+// at an offset, or resumes from a Checkpoint.
+func (w *Waveform) startFrame(sampleRate int, cp *Checkpoint) int64 {
+	frame := timeSamples(audio.Config{SampleRate: sampleRate, Channels: 1}, w.offset)
+	if cp != nil {
+		frame += int64(cp.Window) * int64(sampleRate) / int64(w.resolution)
+	}
+
+	return frame
+}
@@ -0,0 +1,217 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+var (
+	// errPeakFuncNil is returned when a nil PeakReduceFunc is used to compute
+	// peaks.
+	errPeakFuncNil = errors.New("waveform: peak function cannot be nil")
+
+	// errPeakFuncCheckpoint is returned when peaks are computed while
+	// checkpointing is enabled, because a Checkpoint contains only a single
+	// value for each window.
+	errPeakFuncCheckpoint = &OptionsError{
+		Option: "checkpointEvery",
+		Reason: "checkpoints cannot be written while computing peaks",
+		Code:   CodeConflict,
+	}
+
+	// errPeaksLength is returned when the minimum and maximum values passed
+	// to DrawPeaks have different lengths.
+	errPeaksLength = errors.New("waveform: minimum and maximum peaks must have the same length")
+)
+
+// PeakReduceFunc is a function which reduces a set of float64 audio samples
+// into a pair of values: the lowest and highest points of the waveform in a
+// window, such as the minimum and maximum sample.  Values are typically in the
+// range [-1, 1], and min should be no greater than max.
+//
+// Unlike a SampleReduceFunc, which produces a single magnitude which is drawn
+// symmetrically about the center of an image, a PeakReduceFunc preserves the
+// asymmetry of audio whose positive and negative excursions differ, as drawn
+// by editors such as Audacity.  PeakReduceFuncs are applied using
+// Waveform.ComputePeaks.
+type PeakReduceFunc func(samples audio.Float64) (min float64, max float64)
+
+// MinMaxF64Samples is a PeakReduceFunc which finds the minimum and maximum of
+// a slice of float64 audio samples.  Samples of all channels are considered,
+// so the peaks of a window are the extremes of its loudest channel.
+func MinMaxF64Samples(samples audio.Float64) (float64, float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	min, max := samples[0], samples[0]
+	for _, s := range samples[1:] {
+		min = math.Min(min, s)
+		max = math.Max(max, s)
+	}
+
+	return min, max
+}
+
+// ComputePeaks computes a pair of values for each window of the audio stream,
+// using the input PeakReduceFunc, in place of the single value computed by
+// Compute.  The returned slices have one element for each window, containing
+// the lower and upper values computed for the window.
+//
+// All other options which determine how windows are read apply, so, for
+// example, channels are downmixed and windows overlap in the same way as with
+// Compute, and DownsampleAfter repeats both values of the last reduced window.
+// The SampleReduceFunc or ChunkReduceFunc of the receiving Waveform struct is
+// not used.  ComputePeaks cannot be used with CheckpointEvery.
+//
+// The output is typically drawn using DrawPeaks.
+func (w *Waveform) ComputePeaks(fn PeakReduceFunc) (min []float64, max []float64, err error) {
+	if fn == nil {
+		return nil, nil, errPeakFuncNil
+	}
+	if w.checkpointW != nil {
+		return nil, nil, errPeakFuncCheckpoint
+	}
+
+	// The maximum of each window is computed as a value, and its minimum is
+	// recorded by window index, because windows skipped by DownsampleAfter
+	// are not reduced
+	var (
+		mins []float64
+		set  []bool
+	)
+
+	cw := w.clone()
+	cw.chunkFn = func(c *Chunk) float64 {
+		lo, hi := fn(c.Samples)
+
+		for len(mins) <= c.Window {
+			mins = append(mins, 0)
+			set = append(set, false)
+		}
+		mins[c.Window], set[c.Window] = lo, true
+
+		return hi
+	}
+
+	vs, err := cw.readAndComputeSamples(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each value is computed from the window with the same index, so the
+	// minimum of each value is that of its window, or of the last window
+	// which was reduced
+	min = make([]float64, len(vs.Values))
+	var last float64
+	for i := range min {
+		if i < len(set) && set[i] {
+			last = mins[i]
+		}
+
+		min[i] = last
+	}
+
+	return min, vs.Values, nil
+}
+
+// DrawPeaks creates a new image.Image from pairs of minimum and maximum
+// values, such as those computed by ComputePeaks, using the options of the
+// receiving Waveform struct.
+func (w *Waveform) DrawPeaks(min []float64, max []float64) (image.Image, error) {
+	return w.Style().DrawPeaks(min, max)
+}
+
+// DrawPeaks creates a new image.Image from pairs of minimum and maximum
+// values, such as those computed by Waveform.ComputePeaks, using the
+// receiving RenderStyle.
+//
+// Each pair of values is drawn as a single column, which extends above the
+// center of the image in proportion to its maximum, and below the center in
+// proportion to its minimum, so that asymmetric waveforms are drawn as they
+// are.  Values of 1 and -1 reach the top and bottom edges of the image, less
+// any headroom, and values which do not cross the center of the image are
+// drawn from the center.  Colors, scaling, and headroom are applied as with
+// Draw; sharpness, smoothing, and the vector renderer are not.
+//
+// If min and max have different lengths, or if the InvalidValueError policy is
+// in use and an invalid value is encountered, an error is returned.
+func (s RenderStyle) DrawPeaks(min []float64, max []float64) (image.Image, error) {
+	if len(min) != len(max) {
+		return nil, errPeaksLength
+	}
+
+	min, err := s.sanitizePeaks(min)
+	if err != nil {
+		return nil, err
+	}
+	max, err = s.sanitizePeaks(max)
+	if err != nil {
+		return nil, err
+	}
+
+	scaleX := int(s.scaleX)
+	maxN, maxX, maxY := len(max), len(max)*scaleX, s.imageHeight()
+	img := s.newImage(image.Rect(0, 0, maxX, maxY))
+
+	// Each half of the image, less any headroom, spans a value of 1
+	half := float64(s.usableHeight(maxY)) / 2
+	center := maxY / 2
+
+	for n := range max {
+		lo, hi := math.Min(min[n], max[n]), math.Max(min[n], max[n])
+
+		// Columns which do not cross the center are drawn from the center
+		top := center - int(math.Floor(math.Max(0, hi)*half))
+		bottom := center + int(math.Floor(math.Max(0, -lo)*half))
+		if maxY%2 == 1 && (hi != 0 || lo != 0) {
+			// The center row of an odd height image is covered by any nonzero
+			// column
+			bottom++
+		}
+
+		for i := 0; i < scaleX; i++ {
+			x := n*scaleX + i
+			for y := 0; y < maxY; y++ {
+				c := s.bgColorFn(n, x, y, maxN, maxX, maxY)
+				if y >= top && y < bottom {
+					c = s.fgColorFn(n, x, y, maxN, maxX, maxY)
+				}
+
+				setPixel(img, x, y, c)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// sanitizePeaks applies the InvalidValuePolicy of the receiving RenderStyle
+// struct to a slice of peak values, and clamps them to the range [-1, 1].
+// Unlike computed values, negative peaks are valid, so only NaN and infinite
+// values are invalid; under InvalidValueClamp, infinite values are clamped to
+// -1 or 1.  A new slice is always returned.
+func (s *RenderStyle) sanitizePeaks(values []float64) ([]float64, error) {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			if s.invalidValues == InvalidValueError {
+				return nil, &ValueError{
+					Index: i,
+					Value: v,
+				}
+			}
+
+			if s.invalidValues == InvalidValueSkip || math.IsNaN(v) {
+				continue
+			}
+		}
+
+		out[i] = math.Max(-1, math.Min(1, v))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,187 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestMinMaxF64Samples verifies that MinMaxF64Samples computes correct results.
+func TestMinMaxF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples  audio.Float64
+		min, max float64
+	}{
+		{audio.Float64{}, 0, 0},
+		{audio.Float64{0.5}, 0.5, 0.5},
+		{audio.Float64{0.1, -0.4, 0.3, -0.2}, -0.4, 0.3},
+		{audio.Float64{-0.1, -0.2}, -0.2, -0.1},
+	}
+
+	for i, test := range tests {
+		min, max := MinMaxF64Samples(test.samples)
+		if min != test.min || max != test.max {
+			t.Fatalf("[%02d] unexpected peaks: (%v, %v) != (%v, %v)", i, min, max, test.min, test.max)
+		}
+	}
+}
+
+// TestWaveformComputePeaks verifies that ComputePeaks computes the minimum and
+// maximum of each window, including windows skipped by DownsampleAfter.
+func TestWaveformComputePeaks(t *testing.T) {
+	// Mono audio at 4Hz, read at 2 windows per second
+	wav := makeWAV(4, 1, []int16{
+		16384, -32767,
+		32767, -16384,
+		0, 0,
+		8192,
+	})
+
+	half, quarter := 16384.0/32767, 8192.0/32767
+
+	var tests = []struct {
+		options  []OptionsFunc
+		min, max []float64
+	}{
+		{
+			min: []float64{-1, -half, 0, quarter},
+			max: []float64{half, 1, 0, quarter},
+		},
+		{
+			options: []OptionsFunc{PartialWindow(PartialWindowDrop)},
+			min:     []float64{-1, -half, 0},
+			max:     []float64{half, 1, 0},
+		},
+		{
+			options: []OptionsFunc{DownsampleAfter(500*time.Millisecond, 2)},
+			min:     []float64{-1, -half, -half, quarter},
+			max:     []float64{half, 1, 1, quarter},
+		},
+	}
+
+	for i, test := range tests {
+		w, err := New(bytes.NewReader(wav), append(test.options, Resolution(2))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		min, max, err := w.ComputePeaks(MinMaxF64Samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := fmt.Sprint(min), fmt.Sprint(test.min); got != want {
+			t.Fatalf("[%02d] unexpected minimums:\n- want: %v\n-  got: %v", i, want, got)
+		}
+		if got, want := fmt.Sprint(max), fmt.Sprint(test.max); got != want {
+			t.Fatalf("[%02d] unexpected maximums:\n- want: %v\n-  got: %v", i, want, got)
+		}
+	}
+}
+
+// TestWaveformComputePeaksErrors verifies that ComputePeaks rejects a nil
+// PeakReduceFunc, and use with checkpoints.
+func TestWaveformComputePeaksErrors(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.ComputePeaks(nil); err != errPeakFuncNil {
+		t.Fatalf("unexpected error: %v != %v", err, errPeakFuncNil)
+	}
+
+	w, err = New(bytes.NewReader(wavFile), CheckpointEvery(1, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.ComputePeaks(MinMaxF64Samples); err != errPeakFuncCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errPeakFuncCheckpoint)
+	}
+}
+
+// TestRenderStyleDrawPeaks verifies that DrawPeaks draws each column above and
+// below the center of the image in proportion to its maximum and minimum.
+func TestRenderStyleDrawPeaks(t *testing.T) {
+	var tests = []struct {
+		height   uint
+		min, max float64
+		top, end int
+	}{
+		// Full scale in both directions
+		{height: 8, min: -1, max: 1, top: 0, end: 8},
+		// Asymmetric
+		{height: 8, min: -0.5, max: 1, top: 0, end: 6},
+		{height: 8, min: -1, max: 0.25, top: 3, end: 8},
+		// Entirely above the center
+		{height: 8, min: 0.5, max: 1, top: 0, end: 4},
+		// Silence
+		{height: 8, min: 0, max: 0, top: 4, end: 4},
+		// Odd height images cover the center row
+		{height: 9, min: -1, max: 1, top: 0, end: 9},
+		{height: 9, min: 0, max: 0.5, top: 2, end: 5},
+		// Out of range and invalid values are clamped
+		{height: 8, min: math.Inf(-1), max: 2, top: 0, end: 8},
+		{height: 8, min: math.NaN(), max: 0.5, top: 2, end: 4},
+	}
+
+	for i, test := range tests {
+		s, err := NewStyle(
+			BGColorFunction(SolidColor(black)),
+			FGColorFunction(SolidColor(white)),
+			Height(test.height),
+			Scale(2, 1),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := s.DrawPeaks([]float64{test.min}, []float64{test.max})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b := img.Bounds(); b.Dx() != 2 || b.Dy() != int(test.height) {
+			t.Fatalf("[%02d] unexpected bounds: %v", i, b)
+		}
+
+		for x := 0; x < 2; x++ {
+			for y := 0; y < int(test.height); y++ {
+				want := black
+				if y >= test.top && y < test.end {
+					want = white
+				}
+
+				if !colorsEqual(img.At(x, y), want) {
+					t.Fatalf("[%02d] unexpected color at (%d, %d): %v", i, x, y, img.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+// TestRenderStyleDrawPeaksErrors verifies that DrawPeaks rejects slices of
+// different lengths, and invalid values under InvalidValueError.
+func TestRenderStyleDrawPeaksErrors(t *testing.T) {
+	s, err := NewStyle(InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.DrawPeaks([]float64{0}, nil); err != errPeaksLength {
+		t.Fatalf("unexpected error: %v != %v", err, errPeaksLength)
+	}
+
+	// Negative values are valid peaks
+	if _, err := s.DrawPeaks([]float64{-0.5}, []float64{0.5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = s.DrawPeaks([]float64{-0.5, math.NaN()}, []float64{0.5, 0.5})
+	if verr, ok := err.(*ValueError); !ok || verr.Index != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// errPeaksDatShort is returned when a .dat peaks file ends before its header
+// or all of the values it declares.
+var errPeaksDatShort = errors.New("waveform: .dat peaks file is truncated")
+
+// datFlag8Bit is the flag of a .dat peaks file which indicates 8-bit values,
+// rather than 16-bit values.
+const datFlag8Bit = 1
+
+// datHeader is the header of version 1 of the .dat format.  Version 2 adds
+// the number of channels as a signed 32-bit integer.
+type datHeader struct {
+	Version         int32
+	Flags           uint32
+	SampleRate      int32
+	SamplesPerPixel int32
+	Length          uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding Peaks in
+// version 2 of the binary .dat format of BBC audiowaveform: a header, followed
+// by the minimum and maximum of each channel of each peak, as little endian,
+// signed integers.
+func (p *Peaks) MarshalBinary() ([]byte, error) {
+	data, err := p.data()
+	if err != nil {
+		return nil, err
+	}
+
+	bits := p.bits()
+
+	var flags uint32
+	if bits == 8 {
+		flags = datFlag8Bit
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(24 + len(data)*int(bits/8))
+
+	// Writes to a bytes.Buffer cannot fail
+	_ = binary.Write(&buf, binary.LittleEndian, datHeader{
+		Version:         PeaksVersion,
+		Flags:           flags,
+		SampleRate:      int32(p.SampleRate),
+		SamplesPerPixel: int32(p.SamplesPerPixel),
+		Length:          uint32(p.Len()),
+	})
+	_ = binary.Write(&buf, binary.LittleEndian, int32(len(p.Min)))
+
+	for _, v := range data {
+		if bits == 8 {
+			buf.WriteByte(byte(int8(v)))
+			continue
+		}
+
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(int16(v)))
+		buf.Write(b[:])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding Peaks from
+// version 1 or 2 of the binary .dat format of BBC audiowaveform.
+func (p *Peaks) UnmarshalBinary(b []byte) error {
+	r := bytes.NewReader(b)
+
+	var h datHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return errPeaksDatShort
+	}
+
+	channels := int32(1)
+	switch h.Version {
+	case 1:
+	case 2:
+		if err := binary.Read(r, binary.LittleEndian, &channels); err != nil {
+			return errPeaksDatShort
+		}
+	default:
+		return fmt.Errorf("waveform: unsupported peaks version: %d", h.Version)
+	}
+
+	bits := uint(16)
+	if h.Flags&datFlag8Bit != 0 {
+		bits = 8
+	}
+
+	if channels < 1 {
+		return errPeaksChannels
+	}
+
+	// Guard against headers which declare more values than are present,
+	// before any values are allocated
+	n := 2 * uint64(h.Length) * uint64(channels)
+	if uint64(r.Len()) < n*uint64(bits/8) {
+		return errPeaksDatShort
+	}
+
+	data := make([]int, n)
+	for i := range data {
+		if bits == 8 {
+			v, _ := r.ReadByte()
+			data[i] = int(int8(v))
+			continue
+		}
+
+		var v [2]byte
+		_, _ = r.Read(v[:])
+		data[i] = int(int16(binary.LittleEndian.Uint16(v[:])))
+	}
+
+	return p.setData(int(h.SampleRate), int(h.SamplesPerPixel), bits, int(channels), int(h.Length), data)
+}
+
+// ReadPeaks reads Peaks from r, in either the JSON or binary .dat format of
+// BBC audiowaveform.  Input which begins with a JSON object is read as JSON,
+// and all other input is read as a .dat file.
+func ReadPeaks(r io.Reader) (*Peaks, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(Peaks)
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		err = p.UnmarshalJSON(t)
+	} else {
+		err = p.UnmarshalBinary(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
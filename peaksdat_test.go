@@ -0,0 +1,164 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// TestPeaksMarshalBinary verifies that Peaks are encoded in version 2 of the
+// audiowaveform .dat format.
+func TestPeaksMarshalBinary(t *testing.T) {
+	p := &Peaks{
+		SampleRate:      44100,
+		SamplesPerPixel: 256,
+		Bits:            8,
+		Min:             [][]float64{{-1, 0}},
+		Max:             [][]float64{{1, 0.5}},
+	}
+
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		// Version, flags, sample rate, samples per pixel, length, channels
+		2, 0, 0, 0,
+		1, 0, 0, 0,
+		0x44, 0xac, 0, 0,
+		0, 1, 0, 0,
+		2, 0, 0, 0,
+		1, 0, 0, 0,
+		// Minimum and maximum of each peak
+		0x81, 0x7f, 0x00, 0x40,
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("unexpected .dat:\n- want: % x\n-  got: % x", want, b)
+	}
+
+	// 16-bit values are little endian
+	p.Bits = 16
+	b, err = p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b[24:], []byte{0x01, 0x80, 0xff, 0x7f, 0, 0, 0x00, 0x40}; !bytes.Equal(got, want) {
+		t.Fatalf("unexpected 16-bit values:\n- want: % x\n-  got: % x", want, got)
+	}
+}
+
+// TestPeaksBinaryRoundTrip verifies that Peaks with multiple channels which are
+// encoded and decoded again are equal to the originals.
+func TestPeaksBinaryRoundTrip(t *testing.T) {
+	in := &Peaks{
+		SampleRate:      8000,
+		SamplesPerPixel: 80,
+		Bits:            8,
+		Min:             [][]float64{{-1, 0, -127.0 / 127}, {0, -64.0 / 127, 0}},
+		Max:             [][]float64{{1, 32.0 / 127, 0}, {1, 0, 64.0 / 127}},
+	}
+
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(Peaks)
+	if err := out.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprintf("%+v", out), fmt.Sprintf("%+v", in); got != want {
+		t.Fatalf("unexpected peaks:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// TestPeaksUnmarshalBinaryVersion1 verifies that version 1 files, which have
+// no channels field, are decoded as a single channel of 16-bit values.
+func TestPeaksUnmarshalBinaryVersion1(t *testing.T) {
+	b := []byte{
+		1, 0, 0, 0,
+		0, 0, 0, 0,
+		0x80, 0xbb, 0, 0,
+		0, 2, 0, 0,
+		1, 0, 0, 0,
+		0x01, 0x80, 0xff, 0x7f,
+	}
+
+	p := new(Peaks)
+	if err := p.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.SampleRate != 48000 || p.SamplesPerPixel != 512 || p.Bits != 16 {
+		t.Fatalf("unexpected header: %+v", p)
+	}
+	if got, want := fmt.Sprint(p.Min, p.Max), "[[-1]] [[1]]"; got != want {
+		t.Fatalf("unexpected peaks: %v != %v", got, want)
+	}
+}
+
+// TestPeaksUnmarshalBinaryErrors verifies that truncated and unsupported
+// .dat files cannot be decoded.
+func TestPeaksUnmarshalBinaryErrors(t *testing.T) {
+	p := &Peaks{Min: [][]float64{{0, 0}}, Max: [][]float64{{0, 0}}}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		b   []byte
+		err error
+	}{
+		// Truncated header and values
+		{b[:10], errPeaksDatShort},
+		{b[:22], errPeaksDatShort},
+		{b[:len(b)-1], errPeaksDatShort},
+		// No channels
+		{append(append([]byte(nil), b[:20]...), 0, 0, 0, 0), errPeaksChannels},
+	}
+
+	for i, test := range tests {
+		if err := new(Peaks).UnmarshalBinary(test.b); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+
+	// Unsupported version
+	v3 := append([]byte{3}, b[1:]...)
+	if err := new(Peaks).UnmarshalBinary(v3); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+}
+
+// TestReadPeaks verifies that ReadPeaks reads peaks in both the JSON and .dat
+// formats.
+func TestReadPeaks(t *testing.T) {
+	in, err := NewPeaks([]float64{-1, 0}, []float64{1, 0}, 8000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range [][]byte{dat, append([]byte("  "), js...)} {
+		out, err := ReadPeaks(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := fmt.Sprint(out.Min, out.Max, out.SamplesPerPixel), "[[-1 0]] [[1 0]] 800"; got != want {
+			t.Fatalf("unexpected peaks: %v != %v", got, want)
+		}
+	}
+}
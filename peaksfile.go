@@ -0,0 +1,242 @@
+package waveform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// PeaksVersion is the version of the peaks formats which are written by
+	// Peaks.MarshalJSON and Peaks.MarshalBinary.  Version 2 adds the number of
+	// channels to version 1.
+	PeaksVersion = 2
+
+	// DefaultPeaksBits is the number of bits used to encode each peak when
+	// the Bits field of Peaks is 0.
+	DefaultPeaksBits = 16
+)
+
+var (
+	// errPeaksBits is returned when peaks are encoded or decoded with a number
+	// of bits other than 8 or 16.
+	errPeaksBits = errors.New("waveform: peaks must use 8 or 16 bits")
+
+	// errPeaksChannels is returned when peaks are encoded with no channels, or
+	// with channels of different lengths.
+	errPeaksChannels = errors.New("waveform: peaks must have at least one channel, each with the same number of minimums and maximums")
+
+	// errPeaksResolution is returned by NewPeaks when the input sample rate
+	// and resolution do not produce at least one sample frame per peak.
+	errPeaksResolution = errors.New("waveform: peaks resolution must be positive, and no greater than the sample rate")
+)
+
+// Peaks is a series of peaks computed from an audio stream: the minimum and
+// maximum of each group of sample frames, such as those computed by
+// Waveform.ComputePeaks, along with the information needed to position them
+// in time.
+//
+// Peaks can be serialized using the JSON and binary .dat formats of BBC
+// audiowaveform, which are read by waveform viewers such as peaks.js and
+// wavesurfer.js, so that peaks can be computed once on a server and drawn by a
+// web player.  Peaks written by audiowaveform can be read using ReadPeaks.
+// Values are stored as signed integers of Bits bits, so serialized peaks are
+// quantized: a value read back may differ from the original by up to half of
+// 1 divided by the largest integer of that size.
+type Peaks struct {
+	// SampleRate is the sample rate of the audio stream, and SamplesPerPixel
+	// is the number of sample frames represented by each peak.
+	SampleRate      int
+	SamplesPerPixel int
+
+	// Bits is the number of bits used to encode each value: 8 or 16.  If 0,
+	// DefaultPeaksBits is used.
+	Bits uint
+
+	// Min and Max hold the minimum and maximum of each peak, for each channel,
+	// in the range [-1, 1].  Values outside of that range are clamped when
+	// peaks are encoded.
+	Min [][]float64
+	Max [][]float64
+}
+
+// NewPeaks creates Peaks for a single channel from the minimums and maximums
+// of each window of an audio stream with the input sample rate, computed at
+// the input resolution, such as those returned by Waveform.ComputePeaks.
+//
+// The number of sample frames per peak is the sample rate divided by the
+// resolution, rounded down, so it is exact only when the sample rate is evenly
+// divisible by the resolution.
+func NewPeaks(min []float64, max []float64, sampleRate int, resolution uint) (*Peaks, error) {
+	if len(min) != len(max) {
+		return nil, errPeaksLength
+	}
+	if resolution == 0 || sampleRate < int(resolution) {
+		return nil, errPeaksResolution
+	}
+
+	return &Peaks{
+		SampleRate:      sampleRate,
+		SamplesPerPixel: sampleRate / int(resolution),
+		Min:             [][]float64{min},
+		Max:             [][]float64{max},
+	}, nil
+}
+
+// Resolution returns the number of peaks per second of audio.
+func (p *Peaks) Resolution() float64 {
+	if p.SamplesPerPixel <= 0 {
+		return 0
+	}
+
+	return float64(p.SampleRate) / float64(p.SamplesPerPixel)
+}
+
+// Len returns the number of peaks of each channel.
+func (p *Peaks) Len() int {
+	if len(p.Min) == 0 {
+		return 0
+	}
+
+	return len(p.Min[0])
+}
+
+// peaksJSON is the JSON format of Peaks.
+type peaksJSON struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            uint  `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding Peaks in version 2 of the
+// audiowaveform JSON format.
+func (p *Peaks) MarshalJSON() ([]byte, error) {
+	data, err := p.data()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(peaksJSON{
+		Version:         PeaksVersion,
+		Channels:        len(p.Min),
+		SampleRate:      p.SampleRate,
+		SamplesPerPixel: p.SamplesPerPixel,
+		Bits:            p.bits(),
+		Length:          p.Len(),
+		Data:            data,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Peaks from version 1
+// or 2 of the audiowaveform JSON format.
+func (p *Peaks) UnmarshalJSON(b []byte) error {
+	var pj peaksJSON
+	if err := json.Unmarshal(b, &pj); err != nil {
+		return err
+	}
+
+	switch pj.Version {
+	case 1:
+		// Version 1 has no channels field, and always has a single channel
+		pj.Channels = 1
+	case 2:
+	default:
+		return fmt.Errorf("waveform: unsupported peaks version: %d", pj.Version)
+	}
+
+	return p.setData(pj.SampleRate, pj.SamplesPerPixel, pj.Bits, pj.Channels, pj.Length, pj.Data)
+}
+
+// bits returns the number of bits used to encode each value of the Peaks.
+func (p *Peaks) bits() uint {
+	if p.Bits == 0 {
+		return DefaultPeaksBits
+	}
+
+	return p.Bits
+}
+
+// peaksScale returns the largest signed integer of the input number of bits,
+// to which a value of 1 is scaled.
+func peaksScale(bits uint) (float64, error) {
+	if bits != 8 && bits != 16 {
+		return 0, errPeaksBits
+	}
+
+	return float64(int(1)<<(bits-1) - 1), nil
+}
+
+// data returns the values of the Peaks as signed integers, with the minimum
+// and maximum of each channel of each peak interleaved, as they are stored in
+// a peaks file.
+func (p *Peaks) data() ([]int, error) {
+	scale, err := peaksScale(p.bits())
+	if err != nil {
+		return nil, err
+	}
+
+	n := p.Len()
+	if len(p.Min) == 0 || len(p.Max) != len(p.Min) {
+		return nil, errPeaksChannels
+	}
+	for c := range p.Min {
+		if len(p.Min[c]) != n || len(p.Max[c]) != n {
+			return nil, errPeaksChannels
+		}
+	}
+
+	quantize := func(v float64) int {
+		if math.IsNaN(v) {
+			return 0
+		}
+
+		return int(math.Round(math.Max(-1, math.Min(1, v)) * scale))
+	}
+
+	out := make([]int, 0, 2*n*len(p.Min))
+	for i := 0; i < n; i++ {
+		for c := range p.Min {
+			out = append(out, quantize(p.Min[c][i]), quantize(p.Max[c][i]))
+		}
+	}
+
+	return out, nil
+}
+
+// setData sets the fields of the Peaks from the header and interleaved
+// integer values of a peaks file.
+func (p *Peaks) setData(sampleRate int, samplesPerPixel int, bits uint, channels int, length int, data []int) error {
+	scale, err := peaksScale(bits)
+	if err != nil {
+		return err
+	}
+	if channels < 1 || length < 0 || len(data) != 2*length*channels {
+		return fmt.Errorf("waveform: peaks with %d channels and length %d must have %d values, but found %d",
+			channels, length, 2*length*channels, len(data))
+	}
+
+	p.SampleRate = sampleRate
+	p.SamplesPerPixel = samplesPerPixel
+	p.Bits = bits
+	p.Min = make([][]float64, channels)
+	p.Max = make([][]float64, channels)
+	for c := 0; c < channels; c++ {
+		p.Min[c] = make([]float64, length)
+		p.Max[c] = make([]float64, length)
+	}
+
+	for i := 0; i < length; i++ {
+		for c := 0; c < channels; c++ {
+			j := 2 * (i*channels + c)
+			p.Min[c][i] = float64(data[j]) / scale
+			p.Max[c][i] = float64(data[j+1]) / scale
+		}
+	}
+
+	return nil
+}
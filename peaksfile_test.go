@@ -0,0 +1,123 @@
+package waveform
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestPeaksMarshalJSON verifies that Peaks are encoded in the audiowaveform
+// JSON format, with the peaks of each channel interleaved.
+func TestPeaksMarshalJSON(t *testing.T) {
+	p := &Peaks{
+		SampleRate:      44100,
+		SamplesPerPixel: 512,
+		Bits:            8,
+		Min:             [][]float64{{-1, -0.5}, {0, -2}},
+		Max:             [][]float64{{1, 0.5}, {0.25, 2}},
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"version":2,"channels":2,"sample_rate":44100,"samples_per_pixel":512,"bits":8,"length":2,"data":[-127,127,0,32,-64,64,-127,127]}`
+	if got := string(b); got != want {
+		t.Fatalf("unexpected JSON:\n- want: %s\n-  got: %s", want, got)
+	}
+}
+
+// TestPeaksJSONRoundTrip verifies that Peaks which are encoded and decoded
+// again are equal to the originals, within the precision of their bits.
+func TestPeaksJSONRoundTrip(t *testing.T) {
+	in, err := NewPeaks([]float64{-1, -0.25, 0}, []float64{1, 0.5, 0}, 8000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(Peaks)
+	if err := json.Unmarshal(b, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.SampleRate != 8000 || out.SamplesPerPixel != 80 || out.Bits != 16 || out.Resolution() != 100 {
+		t.Fatalf("unexpected header: %+v", out)
+	}
+
+	for c := range in.Min {
+		for i := range in.Min[c] {
+			if math.Abs(in.Min[c][i]-out.Min[c][i]) > 1.0/32767 || math.Abs(in.Max[c][i]-out.Max[c][i]) > 1.0/32767 {
+				t.Fatalf("unexpected peak %d: (%v, %v) != (%v, %v)",
+					i, out.Min[c][i], out.Max[c][i], in.Min[c][i], in.Max[c][i])
+			}
+		}
+	}
+}
+
+// TestPeaksUnmarshalJSONVersion1 verifies that version 1 files, which have no
+// channels field, are decoded as a single channel.
+func TestPeaksUnmarshalJSONVersion1(t *testing.T) {
+	const in = `{"version":1,"sample_rate":48000,"samples_per_pixel":256,"bits":8,"length":2,"data":[0,127,-127,0]}`
+
+	p := new(Peaks)
+	if err := json.Unmarshal([]byte(in), p); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(p.Min, p.Max), "[[0 -1]] [[1 0]]"; got != want {
+		t.Fatalf("unexpected peaks: %v != %v", got, want)
+	}
+}
+
+// TestPeaksJSONErrors verifies that invalid Peaks cannot be encoded, and that
+// invalid peaks files cannot be decoded.
+func TestPeaksJSONErrors(t *testing.T) {
+	var encode = []struct {
+		p   *Peaks
+		err error
+	}{
+		{&Peaks{Bits: 12, Min: [][]float64{{0}}, Max: [][]float64{{0}}}, errPeaksBits},
+		{&Peaks{}, errPeaksChannels},
+		{&Peaks{Min: [][]float64{{0}}, Max: [][]float64{{0, 1}}}, errPeaksChannels},
+		{&Peaks{Min: [][]float64{{0}, {}}, Max: [][]float64{{0}, {}}}, errPeaksChannels},
+	}
+
+	for i, test := range encode {
+		if _, err := test.p.MarshalJSON(); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+
+	for i, in := range []string{
+		`{"version":3,"channels":1,"bits":8,"length":0,"data":[]}`,
+		`{"version":2,"channels":1,"bits":12,"length":0,"data":[]}`,
+		`{"version":2,"channels":1,"bits":8,"length":2,"data":[0,0]}`,
+		`{"version":2,"channels":0,"bits":8,"length":0,"data":[]}`,
+		`[]`,
+	} {
+		if err := json.Unmarshal([]byte(in), new(Peaks)); err == nil {
+			t.Fatalf("[%02d] expected an error for %s", i, in)
+		}
+	}
+}
+
+// TestNewPeaksErrors verifies that NewPeaks rejects mismatched slices and
+// invalid resolutions.
+func TestNewPeaksErrors(t *testing.T) {
+	if _, err := NewPeaks([]float64{0}, nil, 8000, 10); err != errPeaksLength {
+		t.Fatalf("unexpected error: %v != %v", err, errPeaksLength)
+	}
+	if _, err := NewPeaks(nil, nil, 8000, 0); err != errPeaksResolution {
+		t.Fatalf("unexpected error: %v != %v", err, errPeaksResolution)
+	}
+	if _, err := NewPeaks(nil, nil, 8000, 8001); err != errPeaksResolution {
+		t.Fatalf("unexpected error: %v != %v", err, errPeaksResolution)
+	}
+}
@@ -0,0 +1,72 @@
+package waveform
+
+import (
+	"image"
+	"image/draw"
+)
+
+// renderPixelArt draws a slice of laid out values in the same way as render,
+// but at a resolution reduced by the PixelArt block size of the receiving
+// RenderStyle, and then scales the image up to its full size using nearest
+// neighbor sampling, so that each pixel of the reduced image becomes a square
+// block of pixels.
+func (s *RenderStyle) renderPixelArt(values []float64, edges []int) image.Image {
+	size := int(s.pixelSize)
+
+	// Determine the bounds of the full size image
+	it := s.columnIterator(values, edges)
+	full := it.Bounds()
+
+	// Draw the reduced image using one column for each block, with pixel
+	// measurements reduced to match, so that a nonzero measurement is never
+	// reduced to 0
+	ls := *s
+	ls.pixelSize = 0
+	ls.scaleX = 1
+	ls.height = uint(ceilDiv(full.Dy(), size))
+	ls.minHeight = uint(ceilDiv(int(s.minHeight), size))
+	ls.mirrorGap = uint(ceilDiv(int(s.mirrorGap), size))
+	ls.outlineWidth = uint(ceilDiv(int(s.outlineWidth), size))
+
+	small := ls.render(blockValues(values, edges, int(s.scaleX), full.Dx(), size), nil)
+
+	// Scale each pixel of the reduced image to a block, cropping blocks at the
+	// right and bottom edges to the bounds of the full size image
+	img := s.newImage(full)
+	sb := small.Bounds()
+	for y := sb.Min.Y; y < sb.Max.Y; y++ {
+		for x := sb.Min.X; x < sb.Max.X; x++ {
+			r := image.Rect(x*size, y*size, (x+1)*size, (y+1)*size).Intersect(full)
+			draw.Draw(img, r, image.NewUniform(small.At(x, y)), image.Point{}, draw.Src)
+		}
+	}
+
+	return img
+}
+
+// blockValues reduces a slice of laid out values, whose columns span a total
+// of width pixels, to one value for each block of size pixels, so that peaks
+// are preserved.  Columns span scaleX pixels each, or are positioned using
+// edges if they are not nil.
+func blockValues(values []float64, edges []int, scaleX int, width int, size int) []float64 {
+	out := make([]float64, ceilDiv(width, size))
+	for n, v := range values {
+		x0, x1 := n*scaleX, (n+1)*scaleX
+		if edges != nil {
+			x0, x1 = edges[n], edges[n+1]
+		}
+
+		for b := x0 / size; b < len(out) && b*size < x1; b++ {
+			if v > out[b] {
+				out[b] = v
+			}
+		}
+	}
+
+	return out
+}
+
+// ceilDiv returns a divided by b, rounded up.
+func ceilDiv(a int, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,84 @@
+package waveform
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+// TestRenderStylePixelArt verifies that PixelArt draws images made up of
+// square blocks of pixels, with the same bounds as images drawn at full
+// resolution, using both the raster and vector renderers.
+func TestRenderStylePixelArt(t *testing.T) {
+	values := []float64{0.05, 0.10, 0.30, 0.20, 0.15, 0.00, 0.25, 0.10, 0.30}
+
+	for _, vector := range []bool{false, true} {
+		options := []OptionsFunc{Scale(3, 1), Height(18), PixelArt(4)}
+		if vector {
+			options = append(options, VectorRenderer())
+		}
+
+		s, err := NewStyle(options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := s.Draw(values)
+		if b, want := img.Bounds(), image.Rect(0, 0, 27, 18); b != want {
+			t.Fatalf("unexpected bounds, vector %v: %v != %v", vector, b, want)
+		}
+
+		// Every pixel matches the top left pixel of its block
+		var drawn bool
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				want := img.At(x/4*4, y/4*4)
+				if got := img.At(x, y); !colorsEqual(got, want) {
+					t.Fatalf("unexpected color at (%d, %d), vector %v: %v != %v", x, y, vector, got, want)
+				}
+
+				drawn = drawn || colorsEqual(img.At(x, y), black)
+			}
+		}
+		if !drawn {
+			t.Fatalf("no waveform was drawn, vector %v", vector)
+		}
+
+		// DrawInto produces the same image
+		dst := image.NewRGBA(s.DrawBounds(values))
+		if err := s.DrawInto(dst, values); err != nil {
+			t.Fatal(err)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if got, want := dst.At(x, y), img.At(x, y); !colorsEqual(got, want) {
+					t.Fatalf("unexpected DrawInto color at (%d, %d), vector %v: %v != %v", x, y, vector, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestBlockValues verifies that blockValues reduces columns to blocks while
+// preserving peaks.
+func TestBlockValues(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		edges  []int
+		scaleX int
+		width  int
+		want   []float64
+	}{
+		{[]float64{0.1, 0.5, 0.2}, nil, 2, 6, []float64{0.5, 0.2}},
+		{[]float64{0.1, 0.2, 0.3, 0.4}, nil, 4, 16, []float64{0.1, 0.2, 0.3, 0.4}},
+		{[]float64{0.1, 0.5, 0.2}, []int{0, 1, 5, 6}, 0, 6, []float64{0.5, 0.5}},
+	}
+
+	for i, test := range tests {
+		got := blockValues(test.values, test.edges, test.scaleX, test.width, 4)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Fatalf("[%02d] unexpected values:\n- want: %v\n-  got: %v", i, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package waveform
+
+import (
+	"io"
+	"math"
+)
+
+// maxReserveValues is the maximum number of values for which space is
+// reserved in advance, which limits the memory allocated when the length of a
+// stream is badly overestimated.
+const maxReserveValues = 1 << 24
+
+// Progress describes the progress of a computation over an input audio
+// stream.
+type Progress struct {
+	// Windows is the number of windows of audio which have been read.
+	Windows int
+
+	// EstimatedWindows is an estimate of the total number of windows in the
+	// stream, or 0 if no estimate is available yet.  The estimate is refined
+	// as the stream is read.
+	EstimatedWindows int
+
+	// Read is the number of bytes which have been read from the input stream.
+	Read int64
+
+	// Size is the number of bytes in the input stream, from its position when
+	// computation began, or 0 if the stream does not implement io.Seeker.
+	Size int64
+
+	// Done reports whether computation is complete.  Decoders may stop
+	// before reading trailing metadata, so Read may be less than Size even
+	// when computation is complete.
+	Done bool
+}
+
+// Percent returns the percentage of the input stream which has been read, in
+// the range [0, 100], or -1 if the size of the stream is unknown.  Percent
+// always returns 100 once computation is complete.
+func (p Progress) Percent() float64 {
+	if p.Done {
+		return 100
+	}
+	if p.Size <= 0 {
+		return -1
+	}
+
+	return math.Min(100, 100*float64(p.Read)/float64(p.Size))
+}
+
+// ProgressFunc is a function which receives the Progress of a computation.
+type ProgressFunc func(p Progress)
+
+// streamProbe tracks the number of bytes read from an input audio stream, and
+// its size, if the stream implements io.Seeker.
+type streamProbe struct {
+	r    io.Reader
+	read int64
+	size int64
+
+	// base is the number of bytes read when the decoder was opened, such as
+	// a header, which do not contain audio for any window
+	base int64
+}
+
+// newStreamProbe creates a streamProbe which reads from r.  If r implements
+// io.Seeker, its size is determined by seeking to its end and back to its
+// current position.  If seeking fails, the size remains unknown.
+func newStreamProbe(r io.Reader) *streamProbe {
+	p := &streamProbe{r: r}
+
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return p
+	}
+
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return p
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return p
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		// The stream is now at an unknown position, but decoding will fail
+		// on its own, so no error is reported here
+		return p
+	}
+
+	if end > cur {
+		p.size = end - cur
+	}
+
+	return p
+}
+
+// Read implements io.Reader, counting the bytes read.
+func (p *streamProbe) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	return n, err
+}
+
+// opened records that the decoder has been opened, so that bytes read while
+// opening it are not used to estimate the length of a window.
+func (p *streamProbe) opened() {
+	p.base = p.read
+}
+
+// estimate estimates the total number of windows in the stream, from the
+// number of bytes used by the input number of windows.  It returns 0 if no
+// estimate is available.
+func (p *streamProbe) estimate(windows int) int {
+	used := p.read - p.base
+	if p.size <= 0 || windows <= 0 || used <= 0 {
+		return 0
+	}
+
+	// Decoders read ahead of the window being decoded, so the estimate tends
+	// to be low early in the stream, and improves as more windows are read
+	est := float64(windows) * float64(p.size-p.base) / float64(used)
+	if est > maxReserveValues {
+		return maxReserveValues
+	}
+	if est < float64(windows) {
+		return windows
+	}
+
+	return int(math.Ceil(est))
+}
+
+// progress returns the Progress of a computation after the input number of
+// windows have been read.
+func (p *streamProbe) progress(windows int) Progress {
+	return Progress{
+		Windows:          windows,
+		EstimatedWindows: p.estimate(windows),
+		Read:             p.read,
+		Size:             p.size,
+	}
+}
@@ -0,0 +1,149 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestProgressFunctionSeeker verifies that a ProgressFunc receives accurate
+// progress and estimates for an input stream which implements io.Seeker.
+func TestProgressFunctionSeeker(t *testing.T) {
+	// 60 seconds of mono audio at 800Hz
+	wav := makeWAV(800, 1, make([]int16, 60*800))
+
+	var ps []Progress
+	w, err := New(bytes.NewReader(wav), ProgressFunction(func(p Progress) {
+		ps = append(ps, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ps) < 2 {
+		t.Fatalf("too few progress reports: %d", len(ps))
+	}
+
+	var last float64
+	for i, p := range ps[:len(ps)-1] {
+		if p.Size != int64(len(wav)) {
+			t.Fatalf("[%02d] unexpected size: %d != %d", i, p.Size, len(wav))
+		}
+		if p.Windows != i+1 || p.Done {
+			t.Fatalf("[%02d] unexpected progress: %+v", i, p)
+		}
+
+		pct := p.Percent()
+		if pct < last || pct <= 0 || pct > 100 {
+			t.Fatalf("[%02d] unexpected percentage: %v, previous %v", i, pct, last)
+		}
+		last = pct
+	}
+
+	// Once half of the stream is read, the estimate should be close
+	mid := ps[len(ps)/2]
+	if d := mid.EstimatedWindows - len(values); d < -2 || d > 2 {
+		t.Fatalf("inaccurate estimate: %d windows, actually %d", mid.EstimatedWindows, len(values))
+	}
+
+	done := ps[len(ps)-1]
+	if !done.Done || done.Percent() != 100 || done.Windows != len(values) || done.EstimatedWindows != len(values) {
+		t.Fatalf("unexpected final progress: %+v", done)
+	}
+}
+
+// TestProgressFunctionReader verifies that a ProgressFunc receives progress
+// with an unknown size for an input stream which does not implement
+// io.Seeker.
+func TestProgressFunctionReader(t *testing.T) {
+	wav := makeWAV(8, 1, make([]int16, 32))
+
+	var ps []Progress
+	w, err := New(struct{ io.Reader }{bytes.NewReader(wav)}, ProgressFunction(func(p Progress) {
+		ps = append(ps, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range ps[:len(ps)-1] {
+		if p.Size != 0 || p.EstimatedWindows != 0 || p.Percent() != -1 {
+			t.Fatalf("[%02d] unexpected progress: %+v", i, p)
+		}
+	}
+
+	if done := ps[len(ps)-1]; !done.Done || done.Percent() != 100 {
+		t.Fatalf("unexpected final progress: %+v", done)
+	}
+}
+
+// TestStreamProbeOffset verifies that a streamProbe measures the size of a
+// stream from its current position, and leaves the position unchanged.
+func TestStreamProbeOffset(t *testing.T) {
+	r := bytes.NewReader(make([]byte, 100))
+	if _, err := r.Seek(40, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newStreamProbe(r)
+	if p.size != 60 {
+		t.Fatalf("unexpected size: %d != %d", p.size, 60)
+	}
+
+	if off, _ := r.Seek(0, io.SeekCurrent); off != 40 {
+		t.Fatalf("unexpected offset: %d != %d", off, 40)
+	}
+}
+
+// TestStreamProbeEstimate verifies that a streamProbe estimates the number of
+// windows in a stream from the bytes used by the windows read so far.
+func TestStreamProbeEstimate(t *testing.T) {
+	var tests = []struct {
+		name    string
+		p       streamProbe
+		windows int
+		want    int
+	}{
+		{name: "unknown size", p: streamProbe{read: 100}, windows: 1, want: 0},
+		{name: "no windows", p: streamProbe{read: 100, size: 1000}, windows: 0, want: 0},
+		{name: "only header", p: streamProbe{read: 44, base: 44, size: 1044}, windows: 1, want: 0},
+		{name: "OK", p: streamProbe{read: 244, base: 44, size: 1044}, windows: 2, want: 10},
+		{name: "at least windows", p: streamProbe{read: 2000, size: 1000}, windows: 4, want: 4},
+		{name: "maximum", p: streamProbe{read: 1, size: 1 << 40}, windows: 1, want: maxReserveValues},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.estimate(tt.windows); got != tt.want {
+				t.Fatalf("unexpected estimate: %d != %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValueSetReserve verifies that reserving space in a ValueSet retains
+// its existing values.
+func TestValueSetReserve(t *testing.T) {
+	vs := testValueSet([]float64{1, 2, 3}, 1)
+	vs.Counts = []int{4, 5, 6}
+
+	want := fmt.Sprint(vs)
+	vs.reserve(10)
+
+	if got := fmt.Sprint(vs); got != want {
+		t.Fatalf("unexpected ValueSet:\n- want: %v\n-  got: %v", want, got)
+	}
+	if cap(vs.Values) != 10 || cap(vs.Counts) != 10 || cap(vs.Durations) != 10 {
+		t.Fatal("capacity was not reserved")
+	}
+}
@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"time"
+)
+
+// A Notification describes a job which has finished or failed.
+type Notification struct {
+	// ID and Key are the ID returned when the job was submitted, and the key
+	// of its output in the Store.
+	ID  string
+	Key string
+
+	// State is the final state of the job: Done or Failed.
+	State State
+
+	// Err is the error which caused the job to fail, if its State is Failed.
+	Err error
+
+	// Location is the location of the job's output, as reported by a Store
+	// which implements Locator.  It is empty if the job failed, or if the
+	// Store does not implement Locator.
+	Location string
+
+	// Metadata describing the job's output, which are zero if it failed.
+	// Width and Height are the dimensions of the rendered image, Values is
+	// the number of computed values, Peak is the largest computed value, and
+	// Duration is the duration of audio from which values were computed.
+	Width    int
+	Height   int
+	Values   int
+	Peak     float64
+	Duration time.Duration
+}
+
+// newNotification creates a Notification for a job with the input final
+// Status and output, which is stored in store.
+func newNotification(s Status, out *Output, store Store) Notification {
+	n := Notification{
+		ID:    s.ID,
+		Key:   s.Key,
+		State: s.State,
+		Err:   s.Err,
+	}
+	if s.State != Done || out == nil {
+		return n
+	}
+
+	if l, ok := store.(Locator); ok {
+		n.Location = l.Location(s.Key)
+	}
+
+	if out.Image != nil {
+		b := out.Image.Bounds()
+		n.Width, n.Height = b.Dx(), b.Dy()
+	}
+
+	n.Values = len(out.Values)
+	for _, v := range out.Values {
+		n.Peak = math.Max(n.Peak, v)
+	}
+	n.Duration = out.Duration
+
+	return n
+}
+
+// webhookBody is the JSON request body sent by a Webhook.
+type webhookBody struct {
+	ID       string  `json:"id"`
+	Key      string  `json:"key"`
+	State    string  `json:"state"`
+	Error    string  `json:"error,omitempty"`
+	Location string  `json:"location,omitempty"`
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Values   int     `json:"values,omitempty"`
+	Peak     float64 `json:"peak,omitempty"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
+// Webhook creates a Notify function which sends each Notification to url
+// as a JSON object in the body of an HTTP POST request, using the input HTTP
+// client.  If client is nil, http.DefaultClient is used.  Each request is
+// bound to the context passed to the Notify function, so it is canceled once
+// the Queue's NotifyTimeout elapses.
+//
+// The JSON object contains the fields "id", "key", and "state", and when they
+// are set, "error", "location", "width", "height", "values", "peak", and
+// "duration_seconds".  Any response status other than 2xx is reported as an
+// error.
+func Webhook(client *http.Client, url string) func(ctx context.Context, n Notification) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, n Notification) error {
+		body := webhookBody{
+			ID:       n.ID,
+			Key:      n.Key,
+			State:    n.State.String(),
+			Location: n.Location,
+			Width:    n.Width,
+			Height:   n.Height,
+			Values:   n.Values,
+			Peak:     n.Peak,
+			Duration: n.Duration.Seconds(),
+		}
+		if n.Err != nil {
+			body.Error = n.Err.Error()
+		}
+
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		// Drain the body so that the connection may be reused
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("queue: unexpected webhook HTTP status: %s", res.Status)
+		}
+
+		return nil
+	}
+}
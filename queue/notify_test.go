@@ -0,0 +1,230 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/waveform"
+	"github.com/mdlayher/waveform/synth"
+)
+
+// TestQueueNotify verifies that a Queue sends a Notification with the output
+// location and metadata when a job finishes or fails.
+func TestQueueNotify(t *testing.T) {
+	notes := make(chan Notification, 2)
+	q := testQueue(t, &locatorStore{MemoryStore: NewMemoryStore()}, &Config{
+		Workers: 1,
+		Notify: func(_ context.Context, n Notification) error {
+			notes <- n
+			return nil
+		},
+	})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), 3*time.Second, &synth.Options{SampleRate: 8000})
+
+	id, err := q.Submit(Job{
+		Key:     "tone",
+		Open:    openBytes(wav),
+		Options: []waveform.OptionsFunc{waveform.Resolution(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := <-notes
+	if n.ID != id || n.Key != "tone" || n.State != Done || n.Err != nil {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+	if n.Location != "https://example.com/tone" {
+		t.Fatalf("unexpected location: %q", n.Location)
+	}
+	if n.Values != 6 || n.Width != 6 || n.Height == 0 || n.Peak <= 0 || n.Duration != 3*time.Second {
+		t.Fatalf("unexpected metadata: %+v", n)
+	}
+
+	// The notification is sent before the job is marked as finished
+	if s := waitState(t, q, id, Done); s.NotifyErr != nil {
+		t.Fatalf("unexpected notify error: %v", s.NotifyErr)
+	}
+
+	id, err = q.Submit(Job{Key: "bad", Open: openBytes([]byte("not audio at all"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n = <-notes
+	if n.ID != id || n.State != Failed || !errors.Is(n.Err, waveform.ErrFormat) {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+	if n.Location != "" || n.Values != 0 {
+		t.Fatalf("unexpected metadata for failed job: %+v", n)
+	}
+}
+
+// TestQueueNotifyError verifies that an error returned by a Notify function
+// is recorded in the job's Status.
+func TestQueueNotifyError(t *testing.T) {
+	errNotify := errors.New("unreachable")
+
+	q := testQueue(t, NewMemoryStore(), &Config{
+		Workers: 1,
+		Notify: func(_ context.Context, _ Notification) error {
+			return errNotify
+		},
+	})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), time.Second, &synth.Options{SampleRate: 8000})
+
+	id, err := q.Submit(Job{Key: "tone", Open: openBytes(wav)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := waitState(t, q, id, Done)
+	if s.Err != nil || s.NotifyErr != errNotify {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
+
+// TestQueueNotifyTimeout verifies that the context passed to a Notify
+// function is canceled once the NotifyTimeout elapses.
+func TestQueueNotifyTimeout(t *testing.T) {
+	q := testQueue(t, NewMemoryStore(), &Config{
+		Workers:       1,
+		NotifyTimeout: 10 * time.Millisecond,
+		Notify: func(ctx context.Context, _ Notification) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), time.Second, &synth.Options{SampleRate: 8000})
+
+	id, err := q.Submit(Job{Key: "tone", Open: openBytes(wav)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := waitState(t, q, id, Done)
+	if s.Err != nil || s.NotifyErr != context.DeadlineExceeded {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
+
+// TestWebhook verifies that a Webhook sends a Notification as JSON, and
+// reports unsuccessful HTTP responses as errors.
+func TestWebhook(t *testing.T) {
+	var tests = []struct {
+		name   string
+		n      Notification
+		status int
+		want   map[string]interface{}
+		ok     bool
+	}{
+		{
+			name: "done",
+			n: Notification{
+				ID:       "1",
+				Key:      "tone",
+				State:    Done,
+				Location: "https://example.com/tone",
+				Width:    6,
+				Height:   128,
+				Values:   6,
+				Peak:     0.5,
+				Duration: 1500 * time.Millisecond,
+			},
+			status: http.StatusNoContent,
+			want: map[string]interface{}{
+				"id":               "1",
+				"key":              "tone",
+				"state":            "done",
+				"location":         "https://example.com/tone",
+				"width":            6.0,
+				"height":           128.0,
+				"values":           6.0,
+				"peak":             0.5,
+				"duration_seconds": 1.5,
+			},
+			ok: true,
+		},
+		{
+			name: "failed",
+			n: Notification{
+				ID:    "2",
+				Key:   "bad",
+				State: Failed,
+				Err:   waveform.ErrFormat,
+			},
+			status: http.StatusOK,
+			want: map[string]interface{}{
+				"id":    "2",
+				"key":   "bad",
+				"state": "failed",
+				"error": waveform.ErrFormat.Error(),
+			},
+			ok: true,
+		},
+		{
+			name:   "HTTP error",
+			n:      Notification{ID: "3", Key: "tone", State: Done},
+			status: http.StatusInternalServerError,
+			want: map[string]interface{}{
+				"id":    "3",
+				"key":   "tone",
+				"state": "done",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected request: %s %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := Webhook(srv.Client(), srv.URL)(context.Background(), tt.n)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("unexpected body: %v", got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Fatalf("unexpected %q: %v != %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// locatorStore is a Store which implements Locator.
+type locatorStore struct {
+	*MemoryStore
+}
+
+func (s *locatorStore) Location(key string) string {
+	return "https://example.com/" + key
+}
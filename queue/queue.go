@@ -0,0 +1,600 @@
+// Package queue provides an asynchronous rendering queue for package
+// waveform.
+//
+// Long audio streams may take longer to render than an HTTP request timeout
+// permits.  A Queue accepts rendering jobs, renders them in the background
+// using a bounded number of workers while tracking their progress, and writes
+// each result to a Store, from which it can later be served.
+//
+// Jobs are rendered in order of their Priority, and running jobs yield their
+// workers to jobs of a higher Priority, so that interactive requests are not
+// starved by long renders which share the same Queue.
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mdlayher/waveform"
+)
+
+const (
+	// backlogDefault is the default number of jobs which may wait for a
+	// worker.
+	backlogDefault = 64
+
+	// retentionDefault is the default amount of time for which the status of
+	// a finished job is retained.
+	retentionDefault = time.Hour
+
+	// notifyTimeoutDefault is the default amount of time for which a worker
+	// waits for a Notify function to return.
+	notifyTimeoutDefault = 30 * time.Second
+)
+
+var (
+	// ErrClosed is returned when a job is submitted to a closed Queue, and is
+	// the error of any job which did not finish before its Queue was closed.
+	ErrClosed = errors.New("queue: queue closed")
+
+	// ErrFull is returned when a job is submitted to a Queue whose backlog of
+	// waiting jobs is full.
+	ErrFull = errors.New("queue: backlog full")
+
+	// ErrNotFound is returned when the status of an unknown job is requested.
+	// The status of a finished job is only retained for a limited time.
+	ErrNotFound = errors.New("queue: job not found")
+
+	// errStoreNil is returned when a Queue is created without a Store.
+	errStoreNil = errors.New("queue: store must not be nil")
+
+	// errJobInvalid is returned when a job without a key or input is
+	// submitted.
+	errJobInvalid = errors.New("queue: job must have a key and an input")
+)
+
+// A Job is a request to render a waveform image in the background.
+type Job struct {
+	// Key identifies the rendered output in the Store, such as a
+	// content-addressed render key.  It must not be empty.
+	Key string
+
+	// Open opens the input audio stream.  It is called by a worker once the
+	// job begins, so that no resources are held while the job waits.  The
+	// returned io.ReadCloser is closed once rendering is complete.  Open
+	// must not be nil.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+
+	// Options are applied to the Waveform used to render the input stream.
+	// Any ColorFunc or SampleReduceFunc used must be safe for concurrent use
+	// if it is shared between jobs.
+	//
+	// The Queue tracks the progress of each job using its own
+	// ProgressFunction, which replaces any ProgressFunction in Options.  Use
+	// Progress to receive progress updates instead.
+	Options []waveform.OptionsFunc
+
+	// Progress, if not nil, is called by a worker each time the progress of
+	// the job is reported, after it is recorded in the job's Status.
+	Progress waveform.ProgressFunc
+
+	// Priority determines the order in which jobs are rendered.  Waiting jobs
+	// with a higher Priority are started before those with a lower Priority,
+	// and jobs with the same Priority are started in the order they were
+	// submitted.
+	//
+	// Running jobs are also preempted by jobs with a higher Priority: between
+	// each window of audio, a running job checks whether a job with a higher
+	// Priority is waiting, and if so, pauses and yields its worker until it
+	// is once more the highest priority job which is waiting.  This allows,
+	// for example, interactive thumbnail requests to be rendered promptly
+	// while long archive renders share the same Queue.  The default Priority
+	// is 0.
+	Priority int
+}
+
+// State is the state of a job in a Queue.
+type State int
+
+// Possible State values.
+const (
+	// Queued indicates that a job is waiting for a worker.
+	Queued State = iota
+
+	// Running indicates that a job is being rendered.
+	Running
+
+	// Done indicates that a job was rendered, and its output was written to
+	// the Store.
+	Done
+
+	// Failed indicates that a job could not be rendered or stored.
+	Failed
+
+	// Paused indicates that a running job was preempted by a job with a higher
+	// Priority, and is waiting for a worker so that it can resume.
+	Paused
+)
+
+// String returns the string representation of a State.
+func (s State) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	case Paused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is the status of a job in a Queue.
+type Status struct {
+	// ID and Key are the ID returned when the job was submitted, and the key
+	// of its output in the Store.
+	ID  string
+	Key string
+
+	// State is the current state of the job.
+	State State
+
+	// Progress is the progress of the computation of the job's values.  It
+	// is only updated while the job is Running.
+	Progress waveform.Progress
+
+	// Err is the error which caused the job to fail, if its State is Failed.
+	Err error
+
+	// NotifyErr is the error returned by the Config.Notify function when the
+	// job finished, if any.
+	NotifyErr error
+}
+
+// Config specifies optional configuration for a Queue.
+type Config struct {
+	// Workers is the number of jobs which are rendered concurrently.  If 0,
+	// waveform.DefaultWorkers is used.  Each worker renders a single job at a
+	// time, so the waveform.MaxDuration option can be applied to jobs to
+	// bound the memory used by each worker.  Jobs which are Paused do not
+	// occupy a worker, but hold the memory they have used so far.
+	Workers int
+
+	// Backlog is the number of jobs which may wait for a worker before
+	// Submit returns ErrFull.  If 0, a default of 64 is used.  Jobs which
+	// are Paused do not count toward the backlog.
+	Backlog int
+
+	// Retention is the amount of time for which the status of a finished job
+	// is retained.  If 0, a default of one hour is used.
+	Retention time.Duration
+
+	// Notify, if not nil, is called by a worker each time a job finishes or
+	// fails, so that applications can learn of completed renders without
+	// polling.  Webhook creates a Notify function which sends each
+	// Notification to a URL.  Any error returned is recorded in the job's
+	// Status.
+	Notify func(ctx context.Context, n Notification) error
+
+	// NotifyTimeout is the amount of time after which the context passed to
+	// Notify is canceled, so that a slow or unresponsive webhook cannot stall
+	// a worker indefinitely.  If 0, a default of 30 seconds is used.
+	NotifyTimeout time.Duration
+}
+
+// A Queue renders waveform images in the background.  Queue is safe for
+// concurrent use.
+type Queue struct {
+	store     Store
+	retention time.Duration
+	notify    func(ctx context.Context, n Notification) error
+	timeout   time.Duration
+	now       func() time.Time
+
+	ctx     context.Context
+	cancel  func()
+	workers int
+	backlog int
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	status map[string]*job
+
+	// waiting holds jobs which are queued or paused, in priority order, and
+	// running is the number of jobs which occupy a worker.  queued is the
+	// number of jobs in waiting which have not yet started, and seq orders
+	// jobs of the same priority.
+	waiting jobHeap
+	running int
+	queued  int
+	seq     uint64
+}
+
+// job is a Job which has been submitted to a Queue.
+type job struct {
+	Job
+	status   Status
+	finished time.Time
+
+	// seq is the order in which the job was submitted, and resume, if set,
+	// is closed when a paused job may resume
+	seq    uint64
+	resume chan struct{}
+}
+
+// New creates a Queue which writes rendered output to store, and starts its
+// workers.  If cfg is nil, a default configuration is used.  Close must be
+// called to stop the workers once the Queue is no longer needed.
+func New(store Store, cfg *Config) (*Queue, error) {
+	if store == nil {
+		return nil, errStoreNil
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = waveform.DefaultWorkers()
+	}
+	backlog := cfg.Backlog
+	if backlog <= 0 {
+		backlog = backlogDefault
+	}
+	retention := cfg.Retention
+	if retention <= 0 {
+		retention = retentionDefault
+	}
+	timeout := cfg.NotifyTimeout
+	if timeout <= 0 {
+		timeout = notifyTimeoutDefault
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		store:     store,
+		retention: retention,
+		notify:    cfg.Notify,
+		timeout:   timeout,
+		now:       time.Now,
+
+		ctx:     ctx,
+		cancel:  cancel,
+		workers: workers,
+		backlog: backlog,
+
+		status: make(map[string]*job),
+	}
+
+	return q, nil
+}
+
+// Submit submits a job to be rendered in the background, returning an ID
+// which can be used to retrieve its Status.  If the Queue's backlog is full,
+// ErrFull is returned, and the job may be submitted again later.
+func (q *Queue) Submit(j Job) (string, error) {
+	if j.Key == "" || j.Open == nil {
+		return "", errJobInvalid
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	jj := &job{
+		Job: j,
+		status: Status{
+			ID:    id,
+			Key:   j.Key,
+			State: Queued,
+		},
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return "", ErrClosed
+	}
+
+	if q.queued >= q.backlog {
+		return "", ErrFull
+	}
+
+	q.seq++
+	jj.seq = q.seq
+	heap.Push(&q.waiting, jj)
+	q.queued++
+
+	q.prune()
+	q.status[id] = jj
+	q.dispatch()
+
+	return id, nil
+}
+
+// Status returns the Status of the job with the input ID, or ErrNotFound if
+// no such job exists.
+func (q *Queue) Status(id string) (Status, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.status[id]
+	if !ok {
+		return Status{}, ErrNotFound
+	}
+
+	return j.status, nil
+}
+
+// Close stops accepting jobs, cancels any jobs which are queued or running,
+// and waits for all workers to stop.  Jobs which did not finish fail with
+// ErrClosed.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+
+	// Jobs which have not started are failed here, and paused jobs stop
+	// once the Queue's context is canceled
+	var queued []*job
+	for _, j := range q.waiting {
+		if j.resume == nil {
+			queued = append(queued, j)
+		}
+	}
+	q.waiting = nil
+	q.queued = 0
+	q.mu.Unlock()
+
+	q.cancel()
+	for _, j := range queued {
+		q.finish(j, nil, ErrClosed)
+	}
+	q.wg.Wait()
+
+	return nil
+}
+
+// dispatch starts or resumes the highest priority waiting jobs while workers
+// are available.  q.mu must be held.
+func (q *Queue) dispatch() {
+	for q.running < q.workers && q.waiting.Len() > 0 {
+		j := heap.Pop(&q.waiting).(*job)
+		q.running++
+
+		if j.resume != nil {
+			close(j.resume)
+			j.resume = nil
+			continue
+		}
+
+		q.queued--
+		j.status.State = Running
+
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(j)
+		}()
+	}
+}
+
+// work renders a job, and releases its worker once it is finished.
+func (q *Queue) work(j *job) {
+	out, err := q.render(j)
+	if err != nil && q.ctx.Err() != nil {
+		err = ErrClosed
+	}
+
+	q.finish(j, out, err)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.running--
+	q.dispatch()
+}
+
+// preempt is called by a running job between each window of audio.  If a job
+// with a higher priority is waiting, the running job yields its worker, and
+// preempt blocks until the job may resume, or the Queue is closed.
+func (q *Queue) preempt(j *job) {
+	q.mu.Lock()
+	if q.closed || q.waiting.Len() == 0 || q.waiting[0].Priority <= j.Priority {
+		q.mu.Unlock()
+		return
+	}
+
+	// The job keeps its original place among jobs of the same priority
+	resume := make(chan struct{})
+	j.resume = resume
+	j.status.State = Paused
+	heap.Push(&q.waiting, j)
+
+	q.running--
+	q.dispatch()
+	q.mu.Unlock()
+
+	select {
+	case <-resume:
+		q.update(j, func(s *Status) { s.State = Running })
+	case <-q.ctx.Done():
+		// The job's next read fails, stopping its computation, after which
+		// it releases its worker as usual
+		q.mu.Lock()
+		q.running++
+		q.mu.Unlock()
+	}
+}
+
+// render renders a job and writes its output to the Store.
+func (q *Queue) render(j *job) (*Output, error) {
+	rc, err := j.Open(q.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	options := append(j.Options[:len(j.Options):len(j.Options)],
+		waveform.ProgressFunction(func(p waveform.Progress) {
+			q.update(j, func(s *Status) { s.Progress = p })
+			if j.Progress != nil {
+				j.Progress(p)
+			}
+			if !p.Done {
+				q.preempt(j)
+			}
+		}),
+	)
+
+	w, err := waveform.New(&ctxReader{ctx: q.ctx, r: rc}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := w.DrawValueSet(vs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Output{
+		Image:  img,
+		Values: vs.Values,
+	}
+	for _, d := range vs.Durations {
+		out.Duration += d
+	}
+
+	if err := q.store.Put(q.ctx, j.Key, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// update applies fn to the Status of a job.
+func (q *Queue) update(j *job, fn func(s *Status)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	fn(&j.status)
+}
+
+// finish marks a job as finished, with the input output or error.  If
+// configured, a Notification is sent before the job's State changes, so that
+// its Status reflects the outcome of the notification once it is finished.
+func (q *Queue) finish(j *job, out *Output, err error) {
+	q.mu.Lock()
+	s := j.status
+	q.mu.Unlock()
+
+	s.State = Done
+	if err != nil {
+		s.State = Failed
+		s.Err = err
+	}
+
+	// The Queue's context may already be canceled if the job was stopped by
+	// Close, so notifications are not bound to it, but they must still finish
+	// before the worker can continue
+	if q.notify != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		s.NotifyErr = q.notify(ctx, newNotification(s, out, q.store))
+		cancel()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j.status = s
+	j.finished = q.now()
+}
+
+// prune removes the status of jobs which finished longer ago than the
+// retention period.  q.mu must be held.
+func (q *Queue) prune() {
+	now := q.now()
+	for id, j := range q.status {
+		if !j.finished.IsZero() && now.Sub(j.finished) >= q.retention {
+			delete(q.status, id)
+		}
+	}
+}
+
+// newID generates a random job ID.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// ctxReader is an io.Reader which fails once its context is canceled, so that
+// a computation can be stopped while it reads an input stream.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read implements io.Reader.
+func (r *ctxReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(b)
+}
+
+// jobHeap is a heap of jobs, ordered by descending priority, and then by the
+// order in which they were submitted.
+type jobHeap []*job
+
+// Len implements heap.Interface.
+func (h jobHeap) Len() int { return len(h) }
+
+// Less implements heap.Interface.
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+
+	return h[i].seq < h[j].seq
+}
+
+// Swap implements heap.Interface.
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Push implements heap.Interface.
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*job)) }
+
+// Pop implements heap.Interface.
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	j := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return j
+}
@@ -0,0 +1,373 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/waveform"
+	"github.com/mdlayher/waveform/synth"
+)
+
+// TestQueueRender verifies that a Queue renders a job in the background,
+// tracks its progress, and writes its output to the Store.
+func TestQueueRender(t *testing.T) {
+	store := NewMemoryStore()
+	q := testQueue(t, store, &Config{Workers: 1})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), 3*time.Second, &synth.Options{SampleRate: 8000})
+
+	id, err := q.Submit(Job{
+		Key:     "tone",
+		Open:    openBytes(wav),
+		Options: []waveform.OptionsFunc{waveform.Resolution(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := waitState(t, q, id, Done)
+	if s.ID != id || s.Key != "tone" || s.Err != nil {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	if !s.Progress.Done || s.Progress.Percent() != 100 || s.Progress.Windows != 6 {
+		t.Fatalf("unexpected progress: %+v", s.Progress)
+	}
+
+	out, ok := store.Get("tone")
+	if !ok {
+		t.Fatal("output was not stored")
+	}
+	if len(out.Values) != 6 || out.Image == nil {
+		t.Fatalf("unexpected output: %d values, image %v", len(out.Values), out.Image != nil)
+	}
+}
+
+// TestQueueProgress verifies that a job's Progress function receives progress
+// updates, even if its Options contain a ProgressFunction which the Queue
+// replaces.
+func TestQueueProgress(t *testing.T) {
+	q := testQueue(t, NewMemoryStore(), &Config{Workers: 1})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), 3*time.Second, &synth.Options{SampleRate: 8000})
+
+	var (
+		mu   sync.Mutex
+		last waveform.Progress
+	)
+
+	id, err := q.Submit(Job{
+		Key:  "tone",
+		Open: openBytes(wav),
+		Options: []waveform.OptionsFunc{
+			waveform.Resolution(2),
+			waveform.ProgressFunction(func(waveform.Progress) {}),
+		},
+		Progress: func(p waveform.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			last = p
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := waitState(t, q, id, Done)
+	if !s.Progress.Done || s.Progress.Windows != 6 {
+		t.Fatalf("unexpected status progress: %+v", s.Progress)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !last.Done || last.Windows != 6 {
+		t.Fatalf("unexpected job progress: %+v", last)
+	}
+}
+
+// TestQueueFailed verifies that a job which cannot be rendered fails with the
+// error which occurred.
+func TestQueueFailed(t *testing.T) {
+	store := NewMemoryStore()
+	q := testQueue(t, store, &Config{Workers: 1})
+	defer q.Close()
+
+	errOpen := errors.New("no such object")
+
+	var tests = []struct {
+		name string
+		open func(ctx context.Context) (io.ReadCloser, error)
+		err  error
+	}{
+		{
+			name: "open",
+			open: func(_ context.Context) (io.ReadCloser, error) { return nil, errOpen },
+			err:  errOpen,
+		},
+		{
+			name: "format",
+			open: openBytes([]byte("not audio at all")),
+			err:  waveform.ErrFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := q.Submit(Job{Key: tt.name, Open: tt.open})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := waitState(t, q, id, Failed); !errors.Is(s.Err, tt.err) {
+				t.Fatalf("unexpected error: %v != %v", s.Err, tt.err)
+			}
+			if _, ok := store.Get(tt.name); ok {
+				t.Fatal("output was stored for failed job")
+			}
+		})
+	}
+}
+
+// TestQueuePriority verifies that waiting jobs are started in priority order,
+// and that a running job is preempted by a job with a higher priority.
+func TestQueuePriority(t *testing.T) {
+	store := &orderStore{Store: NewMemoryStore()}
+	q := testQueue(t, store, &Config{Workers: 1})
+	defer q.Close()
+
+	wav := synth.WAV(synth.Tone(440), 3*time.Second, &synth.Options{SampleRate: 8000})
+	options := []waveform.OptionsFunc{waveform.Resolution(2)}
+
+	// gate returns a Job.Open function which opens wav once c is closed
+	gate := func(c chan struct{}) func(ctx context.Context) (io.ReadCloser, error) {
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			<-c
+			return openBytes(wav)(ctx)
+		}
+	}
+
+	archiveC, thumbC := make(chan struct{}), make(chan struct{})
+
+	archive, err := q.Submit(Job{Key: "archive", Open: gate(archiveC), Options: options})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, q, archive, Running)
+
+	// Both jobs wait for the only worker, and the later job has a higher
+	// priority
+	low, err := q.Submit(Job{Key: "low", Open: openBytes(wav), Options: options})
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := q.Submit(Job{Key: "thumb", Open: gate(thumbC), Options: options, Priority: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the archive job computes a window, it yields to the thumbnail job
+	close(archiveC)
+	waitState(t, q, archive, Paused)
+	waitState(t, q, thumb, Running)
+	if s, _ := q.Status(low); s.State != Queued {
+		t.Fatalf("unexpected state for low priority job: %v", s.State)
+	}
+
+	// The archive job resumes before the job of the same priority which was
+	// submitted after it
+	close(thumbC)
+	for _, id := range []string{thumb, archive, low} {
+		waitState(t, q, id, Done)
+	}
+
+	if got, want := fmt.Sprint(store.keys()), "[thumb archive low]"; got != want {
+		t.Fatalf("unexpected order: %v != %v", got, want)
+	}
+	if s, _ := q.Status(archive); s.Progress.Windows != 6 {
+		t.Fatalf("unexpected progress: %+v", s.Progress)
+	}
+}
+
+// TestQueueFullClose verifies that a Queue with a full backlog rejects jobs,
+// and that closing a Queue fails all jobs which have not finished.
+func TestQueueFullClose(t *testing.T) {
+	q := testQueue(t, NewMemoryStore(), &Config{Workers: 1, Backlog: 1})
+
+	// The first job blocks its only worker until the Queue is closed
+	block := func(ctx context.Context) (io.ReadCloser, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	running, err := q.Submit(Job{Key: "running", Open: block})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, q, running, Running)
+
+	queued, err := q.Submit(Job{Key: "queued", Open: block})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.Submit(Job{Key: "full", Open: block}); err != ErrFull {
+		t.Fatalf("unexpected error: %v != %v", err, ErrFull)
+	}
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{running, queued} {
+		s, err := q.Status(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.State != Failed || s.Err != ErrClosed {
+			t.Fatalf("unexpected status: %+v", s)
+		}
+	}
+
+	if _, err := q.Submit(Job{Key: "closed", Open: block}); err != ErrClosed {
+		t.Fatalf("unexpected error: %v != %v", err, ErrClosed)
+	}
+}
+
+// TestQueueRetention verifies that the status of a finished job is only
+// retained for the configured retention period.
+func TestQueueRetention(t *testing.T) {
+	q := testQueue(t, NewMemoryStore(), &Config{Workers: 1, Retention: time.Minute})
+	defer q.Close()
+
+	now := time.Unix(0, 0)
+	q.mu.Lock()
+	q.now = func() time.Time { return now }
+	q.mu.Unlock()
+
+	failOpen := func(_ context.Context) (io.ReadCloser, error) {
+		return nil, errors.New("failed")
+	}
+
+	id, err := q.Submit(Job{Key: "a", Open: failOpen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, q, id, Failed)
+
+	// Submitting another job after the retention period prunes the first
+	q.mu.Lock()
+	now = now.Add(time.Minute)
+	q.mu.Unlock()
+
+	if _, err := q.Submit(Job{Key: "b", Open: failOpen}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.Status(id); err != ErrNotFound {
+		t.Fatalf("unexpected error: %v != %v", err, ErrNotFound)
+	}
+}
+
+// TestQueueInvalid verifies that a Queue requires a Store, and that invalid
+// jobs are rejected.
+func TestQueueInvalid(t *testing.T) {
+	if _, err := New(nil, nil); err != errStoreNil {
+		t.Fatalf("unexpected error: %v != %v", err, errStoreNil)
+	}
+
+	q := testQueue(t, NewMemoryStore(), nil)
+	defer q.Close()
+
+	for _, j := range []Job{{Key: "a"}, {Open: openBytes(nil)}} {
+		if _, err := q.Submit(j); err != errJobInvalid {
+			t.Fatalf("unexpected error: %v != %v", err, errJobInvalid)
+		}
+	}
+
+	if _, err := q.Status("unknown"); err != ErrNotFound {
+		t.Fatalf("unexpected error: %v != %v", err, ErrNotFound)
+	}
+}
+
+// TestStateString verifies the string representation of each State.
+func TestStateString(t *testing.T) {
+	want := "queued running done failed paused unknown"
+	if got := Queued.String() + " " + Running.String() + " " + Done.String() + " " +
+		Failed.String() + " " + Paused.String() + " " + State(99).String(); got != want {
+		t.Fatalf("unexpected strings: %q != %q", got, want)
+	}
+}
+
+// testQueue creates a Queue, failing the test on error.
+func testQueue(t *testing.T, store Store, cfg *Config) *Queue {
+	t.Helper()
+
+	q, err := New(store, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+// orderStore is a Store which records the order in which outputs are stored.
+type orderStore struct {
+	Store
+
+	mu    sync.Mutex
+	order []string
+}
+
+// Put implements Store.
+func (s *orderStore) Put(ctx context.Context, key string, out *Output) error {
+	s.mu.Lock()
+	s.order = append(s.order, key)
+	s.mu.Unlock()
+
+	return s.Store.Put(ctx, key, out)
+}
+
+// keys returns the keys of outputs in the order they were stored.
+func (s *orderStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.order...)
+}
+
+// openBytes returns a Job.Open function which opens a copy of b.
+func openBytes(b []byte) func(ctx context.Context) (io.ReadCloser, error) {
+	return func(_ context.Context) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+// waitState waits for the job with the input ID to reach the input State,
+// and returns its Status.
+func waitState(t *testing.T, q *Queue, id string, state State) Status {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s, err := q.Status(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.State == state {
+			return s
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not reach state %s", id, state)
+	return Status{}
+}
@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+)
+
+// Output is the output of a rendering job.
+type Output struct {
+	// Image is the rendered waveform image, and Values are the values
+	// computed from the input audio stream to draw it.
+	Image  image.Image
+	Values []float64
+
+	// Duration is the duration of audio from which values were computed.
+	Duration time.Duration
+}
+
+// A Store stores the output of rendering jobs, such as a cache or object
+// storage bucket.
+//
+// Put stores the output of the job with the input key, replacing any existing
+// output with the same key.  Implementations must be safe for concurrent use.
+type Store interface {
+	Put(ctx context.Context, key string, out *Output) error
+}
+
+// A Locator is a Store which can report the location of stored output, such
+// as a URL, which is included in each Notification.
+type Locator interface {
+	Store
+	Location(key string) string
+}
+
+// A MemoryStore is a Store which holds output in memory.  It is suitable for
+// testing, and for small deployments which do not need to retain output
+// across restarts.
+type MemoryStore struct {
+	mu  sync.RWMutex
+	out map[string]*Output
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{out: make(map[string]*Output)}
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(_ context.Context, key string, out *Output) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.out[key] = out
+	return nil
+}
+
+// Get returns the output stored with the input key, and reports whether it
+// was found.
+func (s *MemoryStore) Get(key string) (*Output, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out, ok := s.out[key]
+	return out, ok
+}
@@ -0,0 +1,104 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// errRedrawBoundsMismatch is returned when an image with bounds which differ
+// from those of the waveform is used in a call to Redraw.
+var errRedrawBoundsMismatch = errors.New("waveform: redraw image bounds do not match waveform")
+
+// Redraw repaints the columns of an existing image, previously drawn from a
+// slice of computed values by Draw, which fall within the changed Range of
+// audio.  This enables efficient live-updating user interfaces, such as
+// recoloring a waveform as playback progresses, or drawing a waveform as its
+// values are computed, without drawing a complete image each time.
+//
+// values must contain all computed values for the image, including those which
+// have not changed, and the image must have the same bounds as an image drawn
+// from values.  If the end of the changed Range is 0 or past the end of the
+// audio, all columns from its start to the end of the image are repainted.
+//
+// Columns whose smoothed values depend on changed values are also repainted.
+// If the ScaleClipping or Normalized option is in use and a change alters the
+// maximum of values, the scale of every column changes, and the complete range
+// of audio should be repainted.
+//
+// Columns of images drawn by the vector renderer or in blocks using the
+// PixelArt option, or positioned by time using the ProportionalTime or
+// PixelsPerSecond options, are not independent of one another, so the complete
+// image is repainted when those options are in use.
+func (w *Waveform) Redraw(img *image.RGBA, values []float64, changed Range) error {
+	s := w.Style()
+
+	// Repaint the complete image when columns cannot be repainted individually
+	if s.vector || s.proportional || s.pixelsPerSecond > 0 || s.pixelSize > 1 {
+		out, err := s.DrawChecked(values)
+		if err != nil {
+			return err
+		}
+		if out.Bounds() != img.Bounds() {
+			return errRedrawBoundsMismatch
+		}
+
+		draw.Draw(img, img.Bounds(), out, out.Bounds().Min, draw.Src)
+		return nil
+	}
+
+	it, err := s.Columns(values)
+	if err != nil {
+		return err
+	}
+	if it.Bounds() != img.Bounds() {
+		return errRedrawBoundsMismatch
+	}
+
+	start, end := s.redrawSpan(len(values), changed)
+	for it.Next() {
+		if c := it.Column(); c.N >= start && c.N < end {
+			s.drawColumn(img, c)
+		}
+	}
+
+	return nil
+}
+
+// redrawSpan returns the first and last (exclusive) indices of n computed values
+// whose columns must be repainted when values within the input Range change.
+func (s *RenderStyle) redrawSpan(n int, changed Range) (int, int) {
+	start := 0
+	if changed.Start > 0 {
+		start = int(changed.Start.Seconds() * float64(s.resolution))
+	}
+
+	end := n
+	if changed.End > 0 {
+		end = int(math.Ceil(changed.End.Seconds() * float64(s.resolution)))
+	}
+
+	// A moving average spreads each change over the values around it,
+	// attack and release spread each change over all following values, and
+	// an exponential average spreads each change over all values
+	if s.smooth > 1 {
+		start -= s.smooth - s.smooth/2 - 1
+		end += s.smooth / 2
+	}
+	if s.attack > 0 || s.release > 0 {
+		end = n
+	}
+	if s.alpha > 0 && s.alpha < 1 {
+		start, end = 0, n
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > n {
+		end = n
+	}
+
+	return start, end
+}
@@ -0,0 +1,114 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+)
+
+// TestWaveformRedraw verifies that Waveform.Redraw produces the same image as
+// drawing the changed values from scratch, while only repainting the columns
+// within the changed range.
+func TestWaveformRedraw(t *testing.T) {
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		changed Range
+	}{
+		{
+			name:    "default",
+			changed: Range{Start: 2 * time.Second, End: 4 * time.Second},
+		},
+		{
+			name:    "to end",
+			options: []OptionsFunc{Scale(3, 1)},
+			changed: Range{Start: 2 * time.Second},
+		},
+		{
+			name:    "smooth",
+			options: []OptionsFunc{Smooth(4)},
+			changed: Range{Start: 2 * time.Second, End: 4 * time.Second},
+		},
+		{
+			name:    "attack release",
+			options: []OptionsFunc{SmoothAR(time.Second, 2*time.Second)},
+			changed: Range{Start: 2 * time.Second, End: 4 * time.Second},
+		},
+		{
+			name:    "exponential",
+			options: []OptionsFunc{SmoothEMA(0.5)},
+			changed: Range{Start: 2 * time.Second, End: 4 * time.Second},
+		},
+		{
+			name:    "vector",
+			options: []OptionsFunc{VectorRenderer()},
+			changed: Range{Start: 2 * time.Second, End: 4 * time.Second},
+		},
+	}
+
+	before := []float64{0.1, 0.2, 0.3, 0.2, 0.1, 0.2, 0.3, 0.2}
+	after := []float64{0.1, 0.2, 0.5, 0.0, 0.1, 0.2, 0.3, 0.2}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(nil, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img := w.Draw(before).(*image.RGBA)
+			if err := w.Redraw(img, after, tt.changed); err != nil {
+				t.Fatalf("unexpected Redraw error: %v", err)
+			}
+
+			want := w.Draw(after).(*image.RGBA)
+			if !bytes.Equal(img.Pix, want.Pix) {
+				t.Fatal("redrawn image does not match image drawn from scratch")
+			}
+		})
+	}
+}
+
+// TestWaveformRedrawProgress verifies that Waveform.Redraw only repaints the
+// columns within the changed range, such as when recoloring for progress.
+func TestWaveformRedrawProgress(t *testing.T) {
+	values := []float64{0.2, 0.2, 0.2, 0.2}
+
+	w, err := New(nil, FGColorFunction(SolidColor(black)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw(values).(*image.RGBA)
+
+	// Recolor the second value only
+	if err := w.SetFGColorFunction(SolidColor(red)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Redraw(img, values, Range{Start: time.Second, End: 2 * time.Second}); err != nil {
+		t.Fatalf("unexpected Redraw error: %v", err)
+	}
+
+	half := imgYDefault / 2
+	for x, want := range []interface{}{black, red, black, black} {
+		if c := img.At(x, half); c != want {
+			t.Fatalf("unexpected color at column %d: %v != %v", x, c, want)
+		}
+	}
+}
+
+// TestWaveformRedrawBoundsMismatch verifies that Waveform.Redraw returns an
+// error when the image does not match the values.
+func TestWaveformRedrawBoundsMismatch(t *testing.T) {
+	for _, options := range [][]OptionsFunc{nil, {VectorRenderer()}} {
+		w, err := New(nil, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, 3, imgYDefault))
+		if err := w.Redraw(img, make([]float64, 4), Range{}); err != errRedrawBoundsMismatch {
+			t.Fatalf("unexpected error: %v != %v", err, errRedrawBoundsMismatch)
+		}
+	}
+}
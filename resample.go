@@ -0,0 +1,119 @@
+package waveform
+
+import "errors"
+
+var (
+	// errResampleCountZero is returned when a count less than 1 is used in a
+	// call to Resample.
+	errResampleCountZero = errors.New("waveform: resample count must be at least 1")
+
+	// errResampleMethodInvalid is returned when an unknown ResampleMethod is
+	// used in a call to Resample.
+	errResampleMethodInvalid = errors.New("waveform: unknown resample method")
+)
+
+// ResampleMethod is a method which determines how values are computed when a
+// slice of values is resampled to a different length by Resample.
+type ResampleMethod int
+
+const (
+	// ResampleMax computes each output value as the maximum of the input
+	// values it covers when reducing the number of values, so that peaks are
+	// preserved, and repeats input values when increasing the number of
+	// values.  This is the method used to resample sprites by DrawSprite.
+	ResampleMax ResampleMethod = iota
+
+	// ResampleLinear computes each output value by linear interpolation
+	// between the two nearest input values, with the first and last output
+	// values equal to the first and last input values.  Linear interpolation
+	// produces smooth waveforms when increasing the number of values, but may
+	// omit short peaks when reducing it.
+	ResampleLinear
+)
+
+// Resample resamples a slice of computed values, such as those returned by
+// Compute, to exactly n values, using the input ResampleMethod.  Resample
+// allows a waveform to be drawn at a fixed width, such as one value for each
+// pixel available in a user interface, regardless of the duration of the
+// audio and the resolution at which values were computed.
+//
+// If values is empty, n zero values are returned.  The input slice is never
+// modified.  If n is less than 1, or method is unknown, an error is returned.
+func Resample(values []float64, n int, method ResampleMethod) ([]float64, error) {
+	if n < 1 {
+		return nil, errResampleCountZero
+	}
+
+	switch method {
+	case ResampleMax:
+		return resampleMax(values, n), nil
+	case ResampleLinear:
+		return resampleLinear(values, n), nil
+	default:
+		return nil, errResampleMethodInvalid
+	}
+}
+
+// resampleMax resamples a slice of values to exactly n values.  When reducing
+// the number of values, each output value is the maximum of the input values
+// it covers, so that peaks are preserved.  When increasing the number of values,
+// input values are repeated.
+func resampleMax(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	if len(values) == 0 {
+		return out
+	}
+
+	for i := range out {
+		// Determine the range of input values covered by this output value,
+		// always covering at least one value
+		start := i * len(values) / n
+		end := (i + 1) * len(values) / n
+		if end <= start {
+			end = start + 1
+		}
+
+		max := values[start]
+		for _, v := range values[start+1 : end] {
+			if v > max {
+				max = v
+			}
+		}
+
+		out[i] = max
+	}
+
+	return out
+}
+
+// resampleLinear resamples a slice of values to exactly n values, using linear
+// interpolation between the input values.
+func resampleLinear(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	if len(values) == 0 {
+		return out
+	}
+	if len(values) == 1 || n == 1 {
+		for i := range out {
+			out[i] = values[0]
+		}
+		return out
+	}
+
+	// Align the first and last output values with the first and last input
+	// values, and interpolate between the input values nearest each position
+	step := float64(len(values)-1) / float64(n-1)
+	for i := range out {
+		x := float64(i) * step
+		j := int(x)
+		if j >= len(values)-1 {
+			out[i] = values[len(values)-1]
+			continue
+		}
+
+		f := x - float64(j)
+		out[i] = values[j] + (values[j+1]-values[j])*f
+	}
+
+	return out
+}
@@ -0,0 +1,90 @@
+package waveform
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestResampleMax verifies that resampleMax computes correct results.
+func TestResampleMax(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		n      int
+		result []float64
+	}{
+		// Empty values
+		{nil, 2, []float64{0, 0}},
+		// Same length
+		{[]float64{0.10, 0.20}, 2, []float64{0.10, 0.20}},
+		// Reduce, preserving peaks
+		{[]float64{0.10, 0.40, 0.30, 0.20}, 2, []float64{0.40, 0.30}},
+		// Increase, repeating values
+		{[]float64{0.10, 0.20}, 4, []float64{0.10, 0.10, 0.20, 0.20}},
+	}
+
+	for i, test := range tests {
+		out := resampleMax(test.values, test.n)
+		for j := range out {
+			if out[j] != test.result[j] {
+				t.Fatalf("[%02d] unexpected result at index %d: %v != %v", i, j, out[j], test.result[j])
+			}
+		}
+	}
+}
+
+// TestResampleLinear verifies that resampleLinear computes correct results.
+func TestResampleLinear(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		n      int
+		result []float64
+	}{
+		// Empty values
+		{nil, 2, []float64{0, 0}},
+		// Single value or output
+		{[]float64{0.5}, 3, []float64{0.5, 0.5, 0.5}},
+		{[]float64{0.25, 0.5}, 1, []float64{0.25}},
+		// Same length
+		{[]float64{0.10, 0.20}, 2, []float64{0.10, 0.20}},
+		// Increase, interpolating between values
+		{[]float64{0, 1}, 5, []float64{0, 0.25, 0.5, 0.75, 1}},
+		// Reduce
+		{[]float64{0, 0.5, 1, 0.5, 0}, 3, []float64{0, 1, 0}},
+	}
+
+	for i, test := range tests {
+		out := resampleLinear(test.values, test.n)
+		if got, want := fmt.Sprint(out), fmt.Sprint(test.result); got != want {
+			t.Fatalf("[%02d] unexpected result:\n- want: %v\n-  got: %v", i, want, got)
+		}
+	}
+}
+
+// TestResample verifies that Resample applies each ResampleMethod, and
+// rejects invalid counts and methods.
+func TestResample(t *testing.T) {
+	values := []float64{0.1, 0.4, 0.3, 0.2}
+
+	out, err := Resample(values, 2, ResampleMax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(out); got != "[0.4 0.3]" {
+		t.Fatalf("unexpected max result: %v", got)
+	}
+
+	out, err = Resample(values, 7, ResampleLinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 7 || out[0] != 0.1 || out[6] != 0.2 {
+		t.Fatalf("unexpected linear result: %v", out)
+	}
+
+	if _, err := Resample(values, 0, ResampleMax); err != errResampleCountZero {
+		t.Fatalf("unexpected error: %v != %v", err, errResampleCountZero)
+	}
+	if _, err := Resample(values, 2, ResampleMethod(99)); err != errResampleMethodInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errResampleMethodInvalid)
+	}
+}
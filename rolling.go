@@ -0,0 +1,128 @@
+package waveform
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// errRollingWindowInvalid is returned when a Rolling waveform is created with
+// a window which holds no values.
+var errRollingWindowInvalid = errors.New("waveform: rolling window must hold at least one value")
+
+// Rolling computes values from an unbounded input audio stream, such as live
+// capture or an Internet radio stream, and retains only the values computed
+// from the most recent window of time.  Draw may be called repeatedly, even
+// while values are being computed, to draw the moving window.
+//
+// Rolling is safe for concurrent use.
+type Rolling struct {
+	w *Waveform
+
+	mu     sync.Mutex
+	values []float64
+	start  int
+	n      int
+	total  int
+}
+
+// NewRolling creates a Rolling waveform which reads audio from r, and retains
+// the values computed from the most recent window of audio, applying any input
+// OptionsFunc on return.  The number of values retained is the length of the
+// window multiplied by the resolution.
+//
+// NewRolling does not read from r; call Run to begin computing values.
+func NewRolling(r io.Reader, window time.Duration, options ...OptionsFunc) (*Rolling, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	if w.checkpointW != nil {
+		return nil, errValueFuncCheckpoint
+	}
+
+	n := math.Ceil(window.Seconds() * float64(w.resolution))
+	if n < 1 {
+		return nil, errRollingWindowInvalid
+	}
+
+	return &Rolling{
+		w:      w,
+		values: make([]float64, int(n)),
+	}, nil
+}
+
+// Run computes values from the input stream until it ends, an error occurs, or
+// ctx is canceled.  Live streams may never end, so Run typically returns only
+// once ctx is canceled, in which case the context's error is returned.
+//
+// The context is checked as each value is computed.  A read which blocks
+// indefinitely is not interrupted by ctx; close the input stream to unblock
+// it.
+func (r *Rolling) Run(ctx context.Context) error {
+	return r.w.ComputeFunc(func(_ int, value float64, _ time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.push(value)
+		return nil
+	})
+}
+
+// push adds a value to the window, discarding the oldest value if the window
+// is full.
+func (r *Rolling) push(value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.n < len(r.values) {
+		r.values[(r.start+r.n)%len(r.values)] = value
+		r.n++
+	} else {
+		r.values[r.start] = value
+		r.start = (r.start + 1) % len(r.values)
+	}
+	r.total++
+}
+
+// Values returns a copy of the values in the current window, from oldest to
+// newest.
+func (r *Rolling) Values() []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]float64, r.n)
+	for i := range out {
+		out[i] = r.values[(r.start+i)%len(r.values)]
+	}
+
+	return out
+}
+
+// Total returns the total number of values computed from the input stream,
+// including those which are no longer in the window.  The first value in the
+// window is the value with index Total minus the length of Values.
+func (r *Rolling) Total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.total
+}
+
+// Draw creates a new image.Image from the values in the current window, in
+// the same way as Waveform.Draw.  Until the window is full, the image only
+// contains the values computed so far.
+func (r *Rolling) Draw(options ...OptionsFunc) image.Image {
+	return r.w.Draw(r.Values(), options...)
+}
+
+// DrawChecked creates a new image.Image from the values in the current
+// window, in the same way as Waveform.DrawChecked.
+func (r *Rolling) DrawChecked(options ...OptionsFunc) (image.Image, error) {
+	return r.w.DrawChecked(r.Values(), options...)
+}
@@ -0,0 +1,108 @@
+package waveform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// TestRolling verifies that a Rolling waveform retains only the values
+// computed from the most recent window of audio.
+func TestRolling(t *testing.T) {
+	// Ten seconds of mono audio at 8Hz, in which each second is louder than
+	// the last
+	var pcm []int16
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 8; j++ {
+			pcm = append(pcm, int16(i*1000))
+		}
+	}
+	wav := makeWAV(8, 1, pcm)
+
+	full := computeTimeRange(t, wav)
+
+	r, err := NewRolling(bytes.NewReader(wav), 3*time.Second, Resolution(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is drawn until values are computed
+	if got := r.Values(); len(got) != 0 {
+		t.Fatalf("unexpected values before Run: %v", got)
+	}
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(r.Values()), fmt.Sprint(full[7:]); got != want {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+	if r.Total() != 10 {
+		t.Fatalf("unexpected total: %d", r.Total())
+	}
+
+	img, err := r.DrawChecked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Dx(); got != 3 {
+		t.Fatalf("unexpected image width: %d", got)
+	}
+}
+
+// TestRollingPartial verifies that a Rolling waveform whose window is not yet
+// full returns all values computed so far.
+func TestRollingPartial(t *testing.T) {
+	r, err := NewRolling(nil, time.Minute, Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.values) != 120 {
+		t.Fatalf("unexpected window length: %d", len(r.values))
+	}
+
+	for i := 0; i < 3; i++ {
+		r.push(float64(i))
+	}
+
+	if got, want := fmt.Sprint(r.Values()), fmt.Sprint([]float64{0, 1, 2}); got != want {
+		t.Fatalf("unexpected values: %v != %v", got, want)
+	}
+}
+
+// TestRollingCancel verifies that canceling the context stops a Rolling
+// waveform.
+func TestRollingCancel(t *testing.T) {
+	r, err := NewRolling(bytes.NewReader(makeWAV(8, 1, make([]int16, 80))), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := r.Run(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+	if r.Total() != 0 {
+		t.Fatalf("unexpected total: %d", r.Total())
+	}
+}
+
+// TestNewRollingErrors verifies that NewRolling returns appropriate errors
+// for invalid input.
+func TestNewRollingErrors(t *testing.T) {
+	if _, err := NewRolling(nil, 0); err != errRollingWindowInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errRollingWindowInvalid)
+	}
+	if _, err := NewRolling(nil, time.Second, CheckpointEvery(1, ioutil.Discard)); err != errValueFuncCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errValueFuncCheckpoint)
+	}
+	if _, err := NewRolling(nil, time.Second, Resolution(0)); err != errResolutionZero {
+		t.Fatalf("unexpected error: %v != %v", err, errResolutionZero)
+	}
+}
@@ -25,3 +25,67 @@ func RMSF64Samples(samples audio.Float64) float64 {
 	// Multiply squared sum by length of samples slice, return square root
 	return math.Sqrt(sumSquare / float64(samples.Len()))
 }
+
+// AverageF64Samples is a SampleReduceFunc which calculates the mean absolute
+// value of a slice of float64 audio samples.
+//
+// AverageF64Samples is cheaper to compute than RMSF64Samples, and produces a
+// smoother waveform with lower peaks, which makes it well suited to long audio
+// streams where throughput matters more than the precision of each value.
+func AverageF64Samples(samples audio.Float64) float64 {
+	// Sum the magnitude of all input samples
+	var sum float64
+	for i := range samples {
+		sum += math.Abs(samples.At(i))
+	}
+
+	// Divide sum by length of samples slice
+	return sum / float64(samples.Len())
+}
+
+// DefaultDBFSFloor is the floor, in decibels full scale, used by DBFS when an
+// invalid floor is given.
+const DefaultDBFSFloor = -60.0
+
+// DBFS generates a SampleReduceFunc which wraps an input SampleReduceFunc,
+// such as RMSF64Samples, converting each linear amplitude value it computes
+// to decibels full scale (dBFS).
+//
+// Decibel values are mapped linearly from the range [floor, 0] to [0, 1], so
+// that quiet passages, which are barely visible using linear amplitude, are
+// drawn with a height proportional to their perceived loudness.  Values at or
+// below the floor, including silence, produce 0, and values at or above full
+// scale produce 1.  The floor must be negative, such as -60; otherwise,
+// DefaultDBFSFloor is used.
+func DBFS(function SampleReduceFunc, floor float64) SampleReduceFunc {
+	// Floor must be negative, and NaN is rejected
+	if !(floor < 0) {
+		floor = DefaultDBFSFloor
+	}
+
+	return func(samples audio.Float64) float64 {
+		v := function(samples)
+		if math.IsNaN(v) {
+			return v
+		}
+
+		// Convert amplitude to decibels and map the range [floor, 0] to [0, 1]
+		db := 20 * math.Log10(math.Abs(v))
+		return math.Max(0, math.Min(1, 1-db/floor))
+	}
+}
+
+// ChunkReduceFunc is a function which reduces a window of audio samples into a
+// single float64 value, like a SampleReduceFunc, but which also receives the
+// metadata of the window: its index and start time, and the sample rate and
+// number of channels of the audio stream.
+//
+// This metadata allows reductions which cannot be expressed as a
+// SampleReduceFunc, such as filters which depend on the sample rate, loudness
+// measurements which weight each channel differently, or reductions which
+// carry state from one window to the next.  ChunkReduceFuncs are applied
+// using the ChunkFunction option.
+//
+// The Samples of the input Chunk are only valid for the duration of a call,
+// and must be copied if they are retained.
+type ChunkReduceFunc func(c *Chunk) float64
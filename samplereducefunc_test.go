@@ -1,6 +1,8 @@
 package waveform
 
 import (
+	"bytes"
+	"fmt"
 	"math"
 	"testing"
 
@@ -41,3 +43,173 @@ func TestRMSF64Samples(t *testing.T) {
 		}
 	}
 }
+
+// TestAverageF64Samples verifies that AverageF64Samples computes correct
+// results
+func TestAverageF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		result  float64
+		isNaN   bool
+	}{
+		// Empty samples - NaN
+		{audio.Float64{}, 0.00, true},
+		// Negative samples
+		{audio.Float64{-0.10}, 0.10, false},
+		{audio.Float64{-0.25, -0.75}, 0.50, false},
+		// Positive samples
+		{audio.Float64{0.10}, 0.10, false},
+		{audio.Float64{0.25, 0.75}, 0.50, false},
+		// Mixed samples
+		{audio.Float64{0.25, -0.75}, 0.50, false},
+		{audio.Float64{0.50, -0.50, 0.50, -0.50}, 0.50, false},
+	}
+
+	for i, test := range tests {
+		if avg := AverageF64Samples(test.samples); avg != test.result {
+			// If expected result is NaN, continue
+			if math.IsNaN(avg) && test.isNaN {
+				continue
+			}
+
+			t.Fatalf("[%02d] unexpected result: %v != %v", i, avg, test.result)
+		}
+	}
+}
+
+// TestDBFS verifies that DBFS converts linear amplitude values to decibels
+// full scale, mapped to the range [0, 1] using its floor.
+func TestDBFS(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		floor   float64
+		result  float64
+		isNaN   bool
+	}{
+		// Empty samples - NaN
+		{audio.Float64{}, -60, 0.00, true},
+		// Silence and values below the floor
+		{audio.Float64{0}, -60, 0.00, false},
+		{audio.Float64{0.0001}, -60, 0.00, false},
+		// Full scale and above
+		{audio.Float64{1}, -60, 1.00, false},
+		{audio.Float64{-2}, -60, 1.00, false},
+		// -20dB and -40dB
+		{audio.Float64{0.10}, -60, 2.0 / 3.0, false},
+		{audio.Float64{-0.01}, -60, 1.0 / 3.0, false},
+		{audio.Float64{0.10}, -40, 0.50, false},
+		// Invalid floors use the default
+		{audio.Float64{0.10}, 0, 2.0 / 3.0, false},
+		{audio.Float64{0.10}, math.NaN(), 2.0 / 3.0, false},
+	}
+
+	for i, test := range tests {
+		db := DBFS(RMSF64Samples, test.floor)(test.samples)
+		if math.IsNaN(db) && test.isNaN {
+			continue
+		}
+
+		if math.Abs(db-test.result) > 1e-9 {
+			t.Fatalf("[%02d] unexpected result: %v != %v", i, db, test.result)
+		}
+	}
+}
+
+// TestDBFSCompute verifies that DBFS can be used to compute values from an
+// audio stream.
+func TestDBFSCompute(t *testing.T) {
+	// Mono audio at 4Hz: a second at -40dB, followed by a second at full scale
+	wav := makeWAV(4, 1, []int16{328, -328, 328, -328, 32767, -32767, 32767, -32767})
+
+	w, err := New(bytes.NewReader(wav), SampleFunction(DBFS(RMSF64Samples, -60)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("unexpected number of values: %d", len(values))
+	}
+	if math.Abs(values[0]-1.0/3.0) > 1e-3 || values[1] != 1 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// TestChunkFunction verifies that a ChunkReduceFunc receives the metadata of
+// each window, and that its values are used in place of a SampleReduceFunc.
+func TestChunkFunction(t *testing.T) {
+	// Stereo audio at 4Hz, read at 2 windows per second, with a partial final
+	// window
+	wav := makeWAV(4, 2, []int16{
+		16384, 0, 16384, 0,
+		32767, 0, 32767, 0,
+		16384, 0,
+	})
+
+	var chunks []string
+	fn := func(c *Chunk) float64 {
+		chunks = append(chunks, fmt.Sprintf("%d/%v/%d/%d/%d",
+			c.Window, c.Start, c.Config.SampleRate, c.Config.Channels, len(c.Samples)))
+
+		// Use only the left channel
+		var peak float64
+		for i := 0; i < len(c.Samples); i += c.Config.Channels {
+			peak = math.Max(peak, c.Samples[i])
+		}
+
+		return peak
+	}
+
+	w, err := New(bytes.NewReader(wav), Resolution(2), ChunkFunction(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(chunks), "[0/0s/4/2/4 1/500ms/4/2/4 2/1s/4/2/2]"; got != want {
+		t.Fatalf("unexpected chunks:\n- want: %v\n-  got: %v", want, got)
+	}
+	if got, want := fmt.Sprint(values), fmt.Sprint([]float64{16384.0 / 32767, 1, 16384.0 / 32767}); got != want {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// TestChunkFunctionOverlap verifies that a ChunkReduceFunc used with Overlap
+// receives the index of each window's center hop, and the samples of its
+// entire window.
+func TestChunkFunctionOverlap(t *testing.T) {
+	wav := makeWAV(4, 1, []int16{0, 0, 0, 0})
+
+	var chunks []string
+	fn := func(c *Chunk) float64 {
+		chunks = append(chunks, fmt.Sprintf("%d/%d", c.Window, len(c.Samples)))
+		return 0
+	}
+
+	w, err := New(bytes.NewReader(wav), Resolution(4), Overlap(3), ChunkFunction(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(chunks), "[0/2 1/3 2/3 3/2]"; got != want {
+		t.Fatalf("unexpected chunks:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// chunkRMS is a ChunkReduceFunc which computes the root mean square of the
+// samples of a Chunk.
+func chunkRMS(c *Chunk) float64 {
+	return RMSF64Samples(c.Samples)
+}
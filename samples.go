@@ -0,0 +1,136 @@
+package waveform
+
+import (
+	"errors"
+
+	"azul3d.org/engine/audio"
+)
+
+var (
+	// errSamplesConfigInvalid is returned when in-memory audio samples are
+	// provided with a sample rate or number of channels which is not positive.
+	errSamplesConfigInvalid = errors.New("waveform: sample rate and number of channels must be greater than zero")
+
+	// errSamplesPartialFrame is returned when the number of in-memory audio
+	// samples is not a multiple of the number of channels.
+	errSamplesPartialFrame = errors.New("waveform: number of samples must be a multiple of the number of channels")
+)
+
+// sampleSource is a slice of interleaved audio samples which are held in
+// memory, and used in place of an input audio stream.
+type sampleSource struct {
+	samples []float64
+	config  audio.Config
+}
+
+// newSampleSource creates a sampleSource, verifying that its configuration
+// is valid.
+func newSampleSource(samples []float64, sampleRate int, channels int) (*sampleSource, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, errSamplesConfigInvalid
+	}
+	if len(samples)%channels != 0 {
+		return nil, errSamplesPartialFrame
+	}
+
+	return &sampleSource{
+		samples: samples,
+		config: audio.Config{
+			SampleRate: sampleRate,
+			Channels:   channels,
+		},
+	}, nil
+}
+
+// NewFromSamples generates a new Waveform struct which computes values from
+// a slice of interleaved audio samples held in memory, applying any input
+// OptionsFunc on return.  Samples should be in the range [-1.0, 1.0], and are
+// interpreted using the input sample rate and number of channels.
+//
+// NewFromSamples allows applications which have already decoded audio, or
+// which produce audio using their own processing pipeline, to compute and draw
+// waveforms without encoding audio to a format such as WAV.
+//
+// Unlike a Waveform created by New, a Waveform created by NewFromSamples may
+// compute values any number of times.  The slice of samples must not be
+// modified while values are computed.
+func NewFromSamples(samples []float64, sampleRate int, channels int, options ...OptionsFunc) (*Waveform, error) {
+	src, err := newSampleSource(samples, sampleRate, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, err
+	}
+	w.samples = src
+
+	return w, nil
+}
+
+// ComputeFromSamples creates a slice of float64 values from a slice of
+// interleaved audio samples held in memory, in the same way as Compute, using
+// the options of the receiving Waveform struct.  Samples should be in the
+// range [-1.0, 1.0], and are interpreted using the input sample rate and number
+// of channels.
+//
+// ComputeFromSamples does not read the input stream of the receiving Waveform,
+// so a single Waveform may be used to compute values for many slices of
+// samples.
+func (w *Waveform) ComputeFromSamples(samples []float64, sampleRate int, channels int) ([]float64, error) {
+	src, err := newSampleSource(samples, sampleRate, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	cw := w.clone()
+	cw.samples = src
+
+	return cw.Compute()
+}
+
+// decoder returns an audio.Decoder which reads the samples of a sampleSource
+// from the beginning.
+func (s *sampleSource) decoder() *sampleDecoder {
+	return &sampleDecoder{
+		samples: s.samples,
+		config:  s.config,
+	}
+}
+
+// sampleDecoder is an audio.Decoder which reads audio samples from a slice in
+// memory.
+type sampleDecoder struct {
+	samples []float64
+	config  audio.Config
+}
+
+// Config returns the audio configuration of the samples.
+func (d *sampleDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read reads audio samples into b, returning audio.EOS once all samples have
+// been read.
+func (d *sampleDecoder) Read(b audio.Slice) (int, error) {
+	if len(d.samples) == 0 {
+		return 0, audio.EOS
+	}
+
+	n := b.Len()
+	if n > len(d.samples) {
+		n = len(d.samples)
+	}
+
+	if f, ok := b.(audio.Float64); ok {
+		copy(f, d.samples[:n])
+	} else {
+		for i := 0; i < n; i++ {
+			b.Set(i, d.samples[i])
+		}
+	}
+	d.samples = d.samples[n:]
+
+	return n, nil
+}
@@ -0,0 +1,107 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestNewFromSamples verifies that a Waveform created by NewFromSamples
+// computes values from in-memory samples, and may compute them repeatedly.
+func TestNewFromSamples(t *testing.T) {
+	// Two seconds of stereo audio at 4Hz, in two windows
+	samples := []float64{
+		0.5, -0.5, -0.5, 0.5,
+		0.5, -0.5, -0.5, 0.5,
+		0.25, 0.25, 0.25, 0.25,
+		-0.25, -0.25, -0.25, -0.25,
+	}
+
+	w, err := NewFromSamples(samples, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprint([]float64{0.5, 0.25})
+	for i := 0; i < 2; i++ {
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := fmt.Sprint(values); got != want {
+			t.Fatalf("[%d] unexpected values: %v != %v", i, got, want)
+		}
+	}
+}
+
+// TestWaveformComputeFromSamples verifies that ComputeFromSamples computes the
+// same values as a WAV stream containing the same samples.
+func TestWaveformComputeFromSamples(t *testing.T) {
+	pcm := []int16{0, 100, -100, 32767, -32768, 1000, 2000, -3000, 16384, 0}
+
+	// Scale samples in the same way as the WAV decoder
+	samples := make([]float64, len(pcm))
+	for i, v := range pcm {
+		samples[i] = float64(v) / 32767
+	}
+
+	w, err := New(nil, Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := w.ComputeFromSamples(samples, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ww, err := New(bytes.NewReader(makeWAV(8, 1, pcm)), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ww.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// TestNewFromSamplesErrors verifies that invalid in-memory samples produce
+// appropriate errors.
+func TestNewFromSamplesErrors(t *testing.T) {
+	var tests = []struct {
+		name       string
+		samples    []float64
+		sampleRate int
+		channels   int
+		options    []OptionsFunc
+		err        error
+	}{
+		{name: "sample rate", samples: []float64{0}, sampleRate: 0, channels: 1, err: errSamplesConfigInvalid},
+		{name: "channels", samples: []float64{0}, sampleRate: 8000, channels: 0, err: errSamplesConfigInvalid},
+		{name: "partial frame", samples: []float64{0, 0, 0}, sampleRate: 8000, channels: 2, err: errSamplesPartialFrame},
+		{name: "option", samples: []float64{0}, sampleRate: 8000, channels: 1, options: []OptionsFunc{Resolution(0)}, err: errResolutionZero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFromSamples(tt.samples, tt.sampleRate, tt.channels, tt.options...); err != tt.err {
+				t.Fatalf("unexpected error: %v != %v", err, tt.err)
+			}
+		})
+	}
+
+	// No samples at all cannot produce a value
+	w, err := NewFromSamples(nil, 8000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Compute(); err != ErrTooShort {
+		t.Fatalf("unexpected error: %v != %v", err, ErrTooShort)
+	}
+}
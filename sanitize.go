@@ -0,0 +1,105 @@
+package waveform
+
+import (
+	"fmt"
+	"math"
+)
+
+// InvalidValuePolicy is a policy which determines how computed values which
+// are NaN, infinite, or negative are handled when a waveform image is drawn.
+// Such values may be produced by a custom SampleReduceFunc, such as when it
+// is applied to an empty window of audio samples.
+type InvalidValuePolicy int
+
+const (
+	// InvalidValueClamp replaces NaN and negative values with 0, and positive
+	// infinity with 1.  This is the default behavior of the waveform package.
+	InvalidValueClamp InvalidValuePolicy = iota
+
+	// InvalidValueSkip replaces all invalid values with 0, so that only the
+	// background is drawn for their columns.
+	InvalidValueSkip
+
+	// InvalidValueError causes DrawChecked to return a *ValueError when an
+	// invalid value is encountered.
+	InvalidValueError
+)
+
+// ValueError is an error which is returned by DrawChecked when an invalid
+// computed value is encountered, and the InvalidValueError policy is in use.
+type ValueError struct {
+	// Index is the index of the invalid value in the input slice.
+	Index int
+
+	// Value is the invalid value.
+	Value float64
+}
+
+// Error returns the string representation of a ValueError.
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("waveform: invalid value at index %d: %v", e.Index, e.Value)
+}
+
+// sanitizeValues applies the InvalidValuePolicy of the receiving RenderStyle
+// struct to a slice of computed values, and then clamps them if the Clamp
+// option is in use.  If no values are changed, the input slice is returned.
+// Otherwise, a new slice is returned, so that the input slice is never
+// modified.
+func (s *RenderStyle) sanitizeValues(values []float64) ([]float64, error) {
+	var out []float64
+	for i, v := range values {
+		if !invalidValue(v) {
+			continue
+		}
+
+		if s.invalidValues == InvalidValueError {
+			return nil, &ValueError{
+				Index: i,
+				Value: v,
+			}
+		}
+
+		// Copy values on first invalid value
+		if out == nil {
+			out = make([]float64, len(values))
+			copy(out, values)
+		}
+
+		out[i] = 0
+		if s.invalidValues == InvalidValueClamp && math.IsInf(v, 1) {
+			out[i] = 1
+		}
+	}
+
+	if s.clamp {
+		for i, v := range values {
+			if out != nil {
+				v = out[i]
+			}
+
+			c := math.Max(s.clampMin, math.Min(s.clampMax, v))
+			if c == v {
+				continue
+			}
+
+			// Copy values on first clamped value
+			if out == nil {
+				out = make([]float64, len(values))
+				copy(out, values)
+			}
+
+			out[i] = c
+		}
+	}
+
+	if out == nil {
+		return values, nil
+	}
+
+	return out, nil
+}
+
+// invalidValue reports whether a computed value is NaN, infinite, or negative.
+func invalidValue(v float64) bool {
+	return math.IsNaN(v) || math.IsInf(v, 0) || v < 0
+}
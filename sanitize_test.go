@@ -0,0 +1,116 @@
+package waveform
+
+import (
+	"math"
+	"testing"
+)
+
+// TestWaveformSanitizeValues verifies that Waveform.sanitizeValues applies
+// each InvalidValuePolicy correctly.
+func TestWaveformSanitizeValues(t *testing.T) {
+	in := []float64{0.10, math.NaN(), -0.20, math.Inf(1), math.Inf(-1)}
+
+	var tests = []struct {
+		policy InvalidValuePolicy
+		result []float64
+	}{
+		{InvalidValueClamp, []float64{0.10, 0, 0, 1, 0}},
+		{InvalidValueSkip, []float64{0.10, 0, 0, 0, 0}},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, InvalidValues(test.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := w.style.sanitizeValues(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j := range out {
+			if out[j] != test.result[j] {
+				t.Fatalf("[%02d] unexpected result at index %d: %v != %v", i, j, out[j], test.result[j])
+			}
+		}
+	}
+
+	// Input must not be modified
+	if !math.IsNaN(in[1]) {
+		t.Fatalf("input values modified: %v", in)
+	}
+}
+
+// TestWaveformSanitizeValuesClamp verifies that Waveform.sanitizeValues
+// clamps values after applying the InvalidValuePolicy, without modifying its
+// input.
+func TestWaveformSanitizeValuesClamp(t *testing.T) {
+	in := []float64{0.01, 0.20, 0.90, math.Inf(1), math.NaN()}
+
+	w, err := New(nil, Clamp(0.05, 0.50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := w.style.sanitizeValues(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{0.05, 0.20, 0.50, 0.50, 0.05}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Fatalf("unexpected result at index %d: %v != %v", i, out[i], want[i])
+		}
+	}
+
+	if in[0] != 0.01 || in[2] != 0.90 {
+		t.Fatalf("input values were modified: %v", in)
+	}
+
+	// Values within the range are returned unchanged
+	in = []float64{0.10, 0.20}
+	if out, _ := w.style.sanitizeValues(in); &out[0] != &in[0] {
+		t.Fatal("values within range were copied")
+	}
+}
+
+// TestWaveformDrawCheckedError verifies that Waveform.DrawChecked returns a
+// ValueError for invalid values when the InvalidValueError policy is in use.
+func TestWaveformDrawCheckedError(t *testing.T) {
+	w, err := New(nil, InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := w.DrawChecked([]float64{0.10, 0.20, -0.30})
+	if img != nil {
+		t.Fatal("unexpected non-nil image")
+	}
+
+	vErr, ok := err.(*ValueError)
+	if !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if vErr.Index != 2 || vErr.Value != -0.30 {
+		t.Fatalf("unexpected ValueError: %v", vErr)
+	}
+}
+
+// TestWaveformDrawCheckedOK verifies that Waveform.DrawChecked draws valid
+// values without error under any policy.
+func TestWaveformDrawCheckedOK(t *testing.T) {
+	w, err := New(nil, InvalidValues(InvalidValueError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := w.DrawChecked([]float64{0.10, 0.20, 0.30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := img.Bounds().Max.X; x != 3 {
+		t.Fatalf("unexpected image width: %v != %v", x, 3)
+	}
+}
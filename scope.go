@@ -0,0 +1,193 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"time"
+)
+
+// errScopeSizeZero is returned when a width or height of 0 is used in
+// ScopeOptions.
+var errScopeSizeZero = errors.New("waveform: scope width and height cannot be 0")
+
+// ScopeOptions specifies options which are used when rendering oscilloscope
+// frames using a ScopeRenderer.
+type ScopeOptions struct {
+	// FPS is the number of frames rendered for each second of audio.
+	FPS uint
+
+	// Width and Height are the dimensions of each frame, in pixels.
+	Width  int
+	Height int
+
+	// Span is the duration of audio displayed in each frame.  If 0, the
+	// duration of a single frame is used, so that consecutive frames display
+	// consecutive audio.
+	Span time.Duration
+
+	// Background and Foreground are the ColorFuncs used to draw each frame.
+	// The value of n passed to each ColorFunc is the index of the frame.  If
+	// nil, white and black are used, respectively.
+	Background ColorFunc
+	Foreground ColorFunc
+}
+
+// ScopeRenderer is an Analyzer which renders a scrolling oscilloscope view of
+// raw audio samples as a sequence of video frames, complementing the envelope
+// drawn by a Waveform for music visualizers.
+//
+// Frame n is rendered as soon as audio up to the end of that frame, at time
+// (n+1)/FPS, has been decoded, and displays the most recent Span of audio,
+// downmixed to a single channel.  A trailing partial frame is not rendered.
+//
+// A ScopeRenderer should be applied using the Analyzers option, so that frames
+// are rendered during computation.  A ScopeRenderer renders a single audio
+// stream, and is not safe for concurrent use.
+type ScopeRenderer struct {
+	options ScopeOptions
+	fn      FrameFunc
+
+	// Ring buffer of the most recent Span of samples
+	ring []float64
+	pos  int
+
+	rate   int
+	frames int64
+	n      int
+}
+
+// NewScopeRenderer creates a ScopeRenderer which passes each rendered frame
+// to the input FrameFunc.
+func NewScopeRenderer(options *ScopeOptions, fn FrameFunc) (*ScopeRenderer, error) {
+	if options == nil || options.FPS == 0 {
+		return nil, errFramesFPSZero
+	}
+	if options.Width <= 0 || options.Height <= 0 {
+		return nil, errScopeSizeZero
+	}
+	if fn == nil {
+		return nil, errFrameFuncNil
+	}
+
+	o := *options
+	if o.Span <= 0 {
+		o.Span = time.Second / time.Duration(o.FPS)
+	}
+	if o.Background == nil {
+		o.Background = SolidColor(color.White)
+	}
+	if o.Foreground == nil {
+		o.Foreground = SolidColor(color.Black)
+	}
+
+	return &ScopeRenderer{
+		options: o,
+		fn:      fn,
+	}, nil
+}
+
+// Analyze implements Analyzer.
+func (s *ScopeRenderer) Analyze(c *Chunk) error {
+	channels := c.Config.Channels
+	if channels <= 0 || c.Config.SampleRate <= 0 {
+		return nil
+	}
+
+	if s.ring == nil {
+		s.rate = c.Config.SampleRate
+		size := int(int64(s.rate) * int64(s.options.Span) / int64(time.Second))
+		if size < 1 {
+			size = 1
+		}
+		s.ring = make([]float64, size)
+	}
+
+	for i := 0; i+channels <= len(c.Samples); i += channels {
+		var sum float64
+		for ch := 0; ch < channels; ch++ {
+			sum += c.Samples.At(i + ch)
+		}
+
+		s.ring[s.pos] = sum / float64(channels)
+		s.pos = (s.pos + 1) % len(s.ring)
+		s.frames++
+
+		// Render once all audio for the current frame has been received
+		end := int64(s.n+1) * int64(s.rate) / int64(s.options.FPS)
+		if s.frames < end {
+			continue
+		}
+
+		if err := s.fn(s.n, s.render()); err != nil {
+			return err
+		}
+		s.n++
+	}
+
+	return nil
+}
+
+// render draws the current contents of the ring buffer as a frame.  Each
+// column displays the range of sample values which it covers, so that no
+// peaks are lost when many samples are displayed in a single column.
+func (s *ScopeRenderer) render() image.Image {
+	width, height := s.options.Width, s.options.Height
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, s.options.Background(s.n, x, y, s.n, width, height))
+		}
+	}
+
+	// Map samples in [-1, 1] to Y coordinates, with positive values at the top
+	toY := func(v float64) int {
+		if v > 1 {
+			v = 1
+		}
+		if v < -1 {
+			v = -1
+		}
+
+		return int((1 - v) / 2 * float64(height-1))
+	}
+
+	prev := -1
+	for x := 0; x < width; x++ {
+		start := x * len(s.ring) / width
+		end := (x + 1) * len(s.ring) / width
+		if end <= start {
+			end = start + 1
+		}
+
+		// Oldest sample is at the current ring position
+		min, max := height, -1
+		for i := start; i < end; i++ {
+			y := toY(s.ring[(s.pos+i)%len(s.ring)])
+			if y < min {
+				min = y
+			}
+			if y > max {
+				max = y
+			}
+		}
+
+		// Connect to the previous column to produce a continuous trace
+		if prev >= 0 {
+			if prev < min {
+				min = prev
+			}
+			if prev > max {
+				max = prev
+			}
+		}
+		prev = toY(s.ring[(s.pos+end-1)%len(s.ring)])
+
+		for y := min; y <= max; y++ {
+			img.Set(x, y, s.options.Foreground(s.n, x, y, s.n, width, height))
+		}
+	}
+
+	return img
+}
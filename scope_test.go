@@ -0,0 +1,79 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestNewScopeRendererErrors verifies that NewScopeRenderer rejects invalid
+// input.
+func TestNewScopeRendererErrors(t *testing.T) {
+	fn := func(n int, img image.Image) error { return nil }
+
+	var tests = []struct {
+		options *ScopeOptions
+		fn      FrameFunc
+		err     error
+	}{
+		{nil, fn, errFramesFPSZero},
+		{&ScopeOptions{Width: 10, Height: 10}, fn, errFramesFPSZero},
+		{&ScopeOptions{FPS: 30, Height: 10}, fn, errScopeSizeZero},
+		{&ScopeOptions{FPS: 30, Width: 10, Height: 10}, nil, errFrameFuncNil},
+	}
+
+	for i, test := range tests {
+		if _, err := NewScopeRenderer(test.options, test.fn); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// TestScopeRendererFrames verifies that ScopeRenderer renders one frame for
+// each complete frame of audio, displaying the most recent samples.
+func TestScopeRendererFrames(t *testing.T) {
+	// One and a quarter seconds of mono, 8kHz audio: silence for one second,
+	// followed by a full scale positive signal
+	samples := make([]int16, 10000)
+	for i := 8000; i < len(samples); i++ {
+		samples[i] = 32767
+	}
+
+	var frames []image.Image
+	s, err := NewScopeRenderer(&ScopeOptions{
+		FPS:    10,
+		Width:  20,
+		Height: 11,
+	}, func(n int, img image.Image) error {
+		frames = append(frames, img)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, samples)), Analyzers(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) != 12 {
+		t.Fatalf("unexpected number of frames: %v != %v", len(frames), 12)
+	}
+
+	// Silence is drawn through the center of the frame, and the full scale
+	// signal at the top
+	if c := frames[0].At(10, 5); !colorsEqual(c, color.Black) {
+		t.Fatalf("expected trace at center of silent frame: %v", c)
+	}
+	if c := frames[11].At(10, 0); !colorsEqual(c, color.Black) {
+		t.Fatalf("expected trace at top of loud frame: %v", c)
+	}
+	if c := frames[11].At(10, 5); !colorsEqual(c, color.White) {
+		t.Fatalf("expected background at center of loud frame: %v", c)
+	}
+}
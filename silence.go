@@ -0,0 +1,149 @@
+package waveform
+
+import (
+	"math"
+	"time"
+)
+
+// silenceBlock is the duration of each block of audio which is checked for
+// silence by a SilenceAnalyzer.
+const silenceBlock = 10 * time.Millisecond
+
+// SilenceAnalyzer is an Analyzer which detects ranges of silence in an audio
+// stream, such as for identifying ad insertion points or editing cues.
+//
+// Audio is considered silent when the peak level of every channel remains below
+// a threshold.  Silence is detected in blocks of 10ms, so range boundaries are
+// accurate to 10ms.
+//
+// A SilenceAnalyzer should be applied using the Analyzers option, and its
+// Ranges method called once computation is complete.  A SilenceAnalyzer
+// analyzes a single audio stream, and is not safe for concurrent use.
+type SilenceAnalyzer struct {
+	threshold float64
+	min       time.Duration
+
+	// Stream configuration, determined from the first Chunk
+	sampleRate int
+	channels   int
+	perBlock   int
+
+	// Current block state
+	frames int
+	peak   float64
+
+	// Completed blocks, sample frame offset of the current silent run,
+	// and detected ranges
+	blocks int
+	start  int
+	silent bool
+	ranges []Range
+}
+
+// NewSilenceAnalyzer creates a SilenceAnalyzer which detects ranges of audio
+// with a peak level below threshold, in dBFS, which last at least as long as
+// the input minimum duration.
+func NewSilenceAnalyzer(threshold float64, min time.Duration) *SilenceAnalyzer {
+	return &SilenceAnalyzer{
+		threshold: math.Pow(10, threshold/20),
+		min:       min,
+	}
+}
+
+// Analyze implements Analyzer.
+func (a *SilenceAnalyzer) Analyze(c *Chunk) error {
+	if a.perBlock == 0 {
+		a.sampleRate = c.Config.SampleRate
+		a.channels = c.Config.Channels
+		a.perBlock = int(int64(a.sampleRate) * int64(silenceBlock) / int64(time.Second))
+		if a.perBlock == 0 {
+			a.perBlock = 1
+		}
+	}
+
+	for i := 0; i+a.channels <= len(c.Samples); i += a.channels {
+		for ch := 0; ch < a.channels; ch++ {
+			if s := math.Abs(c.Samples.At(i + ch)); s > a.peak {
+				a.peak = s
+			}
+		}
+
+		a.frames++
+		if a.frames == a.perBlock {
+			a.endBlock()
+		}
+	}
+
+	return nil
+}
+
+// Ranges returns the ranges of silence detected in all audio analyzed so far.
+// A range of silence which continues until the end of the analyzed audio is
+// included.
+func (a *SilenceAnalyzer) Ranges() []Range {
+	start, silent := a.start, a.silent
+	end := a.blocks * a.perBlock
+
+	// Account for a final, partial block
+	if a.frames > 0 {
+		switch {
+		case a.peak < a.threshold && !silent:
+			start, silent = end, true
+			fallthrough
+		case a.peak < a.threshold:
+			end += a.frames
+		}
+	}
+
+	if !silent {
+		return a.ranges
+	}
+
+	return a.appendRange(a.ranges, start, end)
+}
+
+// endBlock completes the current block of audio, and updates the current
+// run of silence.
+func (a *SilenceAnalyzer) endBlock() {
+	silent := a.peak < a.threshold
+	offset := a.blocks * a.perBlock
+
+	switch {
+	case silent && !a.silent:
+		a.start = offset
+	case !silent && a.silent:
+		a.ranges = a.appendRange(a.ranges, a.start, offset)
+	}
+
+	a.silent = silent
+	a.blocks++
+	a.frames = 0
+	a.peak = 0
+}
+
+// appendRange appends a range of silent sample frames to a slice of Ranges,
+// if it lasts at least as long as the minimum duration.  The input slice is
+// never modified.
+func (a *SilenceAnalyzer) appendRange(ranges []Range, start int, end int) []Range {
+	r := Range{
+		Start: a.frameTime(start),
+		End:   a.frameTime(end),
+	}
+
+	if r.Duration() < a.min {
+		return ranges
+	}
+
+	out := make([]Range, len(ranges), len(ranges)+1)
+	copy(out, ranges)
+	return append(out, r)
+}
+
+// frameTime returns the duration of a number of sample frames.
+func (a *SilenceAnalyzer) frameTime(frames int) time.Duration {
+	if a.sampleRate == 0 {
+		return 0
+	}
+
+	return time.Duration(int64(frames) * int64(time.Second) / int64(a.sampleRate))
+}
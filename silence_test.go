@@ -0,0 +1,73 @@
+package waveform
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestSilenceAnalyzerRanges verifies that SilenceAnalyzer detects ranges of
+// silence which last at least the minimum duration.
+func TestSilenceAnalyzerRanges(t *testing.T) {
+	// Mono, 8kHz audio: 1s tone, 2s silence, 1s tone, 0.05s silence, 1s tone,
+	// 1.5s silence until the end of the stream
+	var samples []int16
+	add := func(v int16, d time.Duration) {
+		for i := 0; i < int(d.Seconds()*8000); i++ {
+			samples = append(samples, v)
+		}
+	}
+	add(16384, time.Second)
+	add(0, 2*time.Second)
+	add(16384, time.Second)
+	add(0, 50*time.Millisecond)
+	add(16384, time.Second)
+	add(0, 1500*time.Millisecond)
+
+	a := NewSilenceAnalyzer(-60, 500*time.Millisecond)
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, samples)), Analyzers(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Range{
+		{Start: time.Second, End: 3 * time.Second},
+		{Start: 5050 * time.Millisecond, End: 6550 * time.Millisecond},
+	}
+
+	got := a.Ranges()
+	if len(got) != len(want) {
+		t.Fatalf("unexpected number of ranges: %v != %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected range at index %d: %v != %v", i, got[i], want[i])
+		}
+	}
+
+	if d := got[0].Duration(); d != 2*time.Second {
+		t.Fatalf("unexpected duration: %v != %v", d, 2*time.Second)
+	}
+}
+
+// TestSilenceAnalyzerNoSilence verifies that SilenceAnalyzer reports no
+// ranges for audio which is never silent.
+func TestSilenceAnalyzerNoSilence(t *testing.T) {
+	a := NewSilenceAnalyzer(-60, 0)
+	w, err := New(bytes.NewReader(wavFile), Analyzers(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := a.Ranges(); len(r) != 0 {
+		t.Fatalf("unexpected ranges: %v", r)
+	}
+}
@@ -0,0 +1,122 @@
+package waveform
+
+import (
+	"math"
+	"time"
+)
+
+// smoothValues applies any smoothing options set on the receiving RenderStyle
+// struct to a slice of computed values, returning a new slice.  The input
+// slice is never modified, so that it may be reused by subsequent calls
+// to Draw.
+func (s *RenderStyle) smoothValues(values []float64) []float64 {
+	if s.smooth > 1 {
+		values = movingAverage(values, s.smooth)
+	}
+	if s.alpha > 0 && s.alpha < 1 {
+		values = exponentialAverage(values, s.alpha)
+	}
+
+	// Each computed value spans one window, which is determined by resolution
+	if (s.attack > 0 || s.release > 0) && s.resolution > 0 {
+		step := time.Second / time.Duration(s.resolution)
+		values = attackRelease(values, step, s.attack, s.release)
+	}
+
+	return values
+}
+
+// movingAverage computes a centered moving average over a slice of values,
+// using the input window size.  Near the edges of the slice, only the values
+// which fall within the window are averaged.
+func movingAverage(values []float64, window int) []float64 {
+	// Offset of the first value in the window from its center
+	half := window / 2
+
+	out := make([]float64, len(values))
+	for i := range values {
+		// Determine window bounds, clamping to the edges of the slice
+		start := i - half
+		if start < 0 {
+			start = 0
+		}
+		end := i - half + window
+		if end > len(values) {
+			end = len(values)
+		}
+
+		var sum float64
+		for _, v := range values[start:end] {
+			sum += v
+		}
+
+		out[i] = sum / float64(end-start)
+	}
+
+	return out
+}
+
+// exponentialAverage applies an exponential moving average with the input
+// smoothing factor to a slice of values, first forward and then backward, so
+// that the smoothed values are not delayed relative to the input values.
+func exponentialAverage(values []float64, alpha float64) []float64 {
+	out := make([]float64, len(values))
+	if len(values) == 0 {
+		return out
+	}
+
+	// Begin each pass at its first value, so it does not ramp up from silence
+	env := values[0]
+	for i, v := range values {
+		env += alpha * (v - env)
+		out[i] = env
+	}
+
+	env = out[len(out)-1]
+	for i := len(out) - 1; i >= 0; i-- {
+		env += alpha * (out[i] - env)
+		out[i] = env
+	}
+
+	return out
+}
+
+// attackRelease applies asymmetric exponential smoothing to a slice of values,
+// where step is the duration spanned by each value.  When a value is greater
+// than the current envelope, the envelope rises using the attack time
+// constant; otherwise it decays using the release time constant.
+func attackRelease(values []float64, step time.Duration, attack time.Duration, release time.Duration) []float64 {
+	// Precompute coefficients for both directions
+	attackCoef := smoothingCoefficient(step, attack)
+	releaseCoef := smoothingCoefficient(step, release)
+
+	out := make([]float64, len(values))
+	var env float64
+	for i, v := range values {
+		// Begin envelope at first value, so it does not ramp up from silence
+		if i == 0 {
+			env = v
+		}
+
+		if v > env {
+			env += attackCoef * (v - env)
+		} else {
+			env += releaseCoef * (v - env)
+		}
+
+		out[i] = env
+	}
+
+	return out
+}
+
+// smoothingCoefficient computes the coefficient used to move an exponential
+// envelope toward a new value, given the duration of a single step and a time
+// constant.  A time constant of 0 causes the envelope to follow values exactly.
+func smoothingCoefficient(step time.Duration, constant time.Duration) float64 {
+	if constant <= 0 {
+		return 1
+	}
+
+	return 1 - math.Exp(-float64(step)/float64(constant))
+}
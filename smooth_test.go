@@ -0,0 +1,126 @@
+package waveform
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestMovingAverage verifies that movingAverage computes correct results.
+func TestMovingAverage(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		window int
+		result []float64
+	}{
+		// Empty values
+		{nil, 3, []float64{}},
+		// Window of 1, no change
+		{[]float64{0.10, 0.20, 0.30}, 1, []float64{0.10, 0.20, 0.30}},
+		// Odd window, edges averaged over fewer values
+		{[]float64{0.00, 0.30, 0.00, 0.30}, 3, []float64{0.15, 0.10, 0.20, 0.15}},
+		// Even window, centered with extra value before
+		{[]float64{0.00, 0.40, 0.00, 0.40}, 2, []float64{0.00, 0.20, 0.20, 0.20}},
+		// Window larger than values
+		{[]float64{0.10, 0.30}, 10, []float64{0.20, 0.20}},
+	}
+
+	for i, test := range tests {
+		out := movingAverage(test.values, test.window)
+		if len(out) != len(test.result) {
+			t.Fatalf("[%02d] unexpected length: %v != %v", i, len(out), len(test.result))
+		}
+
+		for j := range out {
+			if !floatEqual(out[j], test.result[j]) {
+				t.Fatalf("[%02d] unexpected result at index %d: %v != %v", i, j, out[j], test.result[j])
+			}
+		}
+	}
+}
+
+// TestExponentialAverage verifies that exponentialAverage computes correct
+// results.
+func TestExponentialAverage(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		alpha  float64
+		result []float64
+	}{
+		// Empty values
+		{nil, 0.5, []float64{}},
+		// Alpha of 1, no change
+		{[]float64{0.10, 0.50, 0.10}, 1, []float64{0.10, 0.50, 0.10}},
+		// Constant values are unchanged
+		{[]float64{0.30, 0.30, 0.30}, 0.25, []float64{0.30, 0.30, 0.30}},
+		// Forward pass: 0, 0.5, 0.25; backward pass from 0.25
+		{[]float64{0.00, 1.00, 0.00}, 0.5, []float64{0.1875, 0.375, 0.25}},
+	}
+
+	for i, test := range tests {
+		out := exponentialAverage(test.values, test.alpha)
+		if len(out) != len(test.result) {
+			t.Fatalf("[%02d] unexpected length: %v != %v", i, len(out), len(test.result))
+		}
+
+		for j := range out {
+			if !floatEqual(out[j], test.result[j]) {
+				t.Fatalf("[%02d] unexpected result at index %d: %v != %v", i, j, out[j], test.result[j])
+			}
+		}
+	}
+}
+
+// TestAttackRelease verifies that attackRelease computes correct results.
+func TestAttackRelease(t *testing.T) {
+	// Coefficient for a time constant equal to the step duration
+	c := 1 - math.Exp(-1)
+
+	var tests = []struct {
+		values  []float64
+		attack  time.Duration
+		release time.Duration
+		result  []float64
+	}{
+		// No smoothing in either direction
+		{[]float64{0.10, 0.50, 0.10}, 0, 0, []float64{0.10, 0.50, 0.10}},
+		// Instant attack, slow release
+		{[]float64{0.00, 1.00, 0.00}, 0, time.Second, []float64{0.00, 1.00, 1.00 - c}},
+		// Slow attack, instant release
+		{[]float64{0.00, 1.00, 0.00}, time.Second, 0, []float64{0.00, c, 0.00}},
+	}
+
+	for i, test := range tests {
+		out := attackRelease(test.values, time.Second, test.attack, test.release)
+		for j := range out {
+			if !floatEqual(out[j], test.result[j]) {
+				t.Fatalf("[%02d] unexpected result at index %d: %v != %v", i, j, out[j], test.result[j])
+			}
+		}
+	}
+}
+
+// TestWaveformSmoothValuesUnmodified verifies that smoothing values does not
+// modify the input slice.
+func TestWaveformSmoothValuesUnmodified(t *testing.T) {
+	w, err := New(nil, Smooth(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.00, 0.30, 0.00}
+	w.style.smoothValues(values)
+
+	if values[1] != 0.30 {
+		t.Fatalf("input values modified: %v", values)
+	}
+}
+
+// floatEqual reports whether two float64 values are equal, within a small
+// tolerance.
+func floatEqual(a float64, b float64) bool {
+	const epsilon = 1e-9
+
+	d := a - b
+	return d < epsilon && d > -epsilon
+}
@@ -0,0 +1,302 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// blockSizeDefault is the default size of each block read by an HTTPReader.
+	blockSizeDefault = 64 * 1024
+
+	// cacheBlocksDefault is the default number of blocks cached by an HTTPReader.
+	cacheBlocksDefault = 16
+)
+
+var (
+	// ErrRangeUnsupported is returned when a remote server does not support
+	// HTTP Range requests.
+	ErrRangeUnsupported = errors.New("source: server does not support range requests")
+
+	// errNegativeOffset is returned when a read or seek is attempted at a
+	// negative offset.
+	errNegativeOffset = errors.New("source: negative offset")
+)
+
+// HTTPConfig specifies optional configuration for an HTTPReader.
+type HTTPConfig struct {
+	// BlockSize is the number of bytes requested from the remote server in
+	// each Range request.  If 0, a default of 64KiB is used.
+	BlockSize int64
+
+	// CacheBlocks is the maximum number of blocks which are cached in memory.
+	// The least recently used block is evicted when the cache is full.  If 0,
+	// a default of 16 blocks is used.
+	CacheBlocks int
+}
+
+// HTTPReader is an io.ReaderAt and io.ReadSeeker which reads a remote file
+// using HTTP Range requests.  Only the blocks of the file which are actually
+// read are downloaded, and a small number of recently used blocks are cached.
+//
+// HTTPReader is safe for concurrent use, although its Read and Seek methods
+// share a single offset.
+type HTTPReader struct {
+	ctx       context.Context
+	client    *http.Client
+	url       string
+	size      int64
+	blockSize int64
+
+	mu          sync.Mutex
+	off         int64
+	cache       map[int64][]byte
+	lru         []int64
+	cacheBlocks int
+}
+
+// NewHTTPReader creates an HTTPReader which reads the file at the input URL
+// using the input HTTP client.  If client is nil, http.DefaultClient is used.
+// If cfg is nil, a default configuration is used.
+//
+// The context is used for all requests made by the HTTPReader.  The remote
+// server must support Range requests, or ErrRangeUnsupported is returned.
+func NewHTTPReader(ctx context.Context, client *http.Client, url string, cfg *HTTPConfig) (*HTTPReader, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg == nil {
+		cfg = &HTTPConfig{}
+	}
+
+	r := &HTTPReader{
+		ctx:         ctx,
+		client:      client,
+		url:         url,
+		blockSize:   cfg.BlockSize,
+		cache:       make(map[int64][]byte),
+		cacheBlocks: cfg.CacheBlocks,
+	}
+	if r.blockSize <= 0 {
+		r.blockSize = blockSizeDefault
+	}
+	if r.cacheBlocks <= 0 {
+		r.cacheBlocks = cacheBlocksDefault
+	}
+
+	// Fetch the first block, which also reveals the size of the file
+	b, size, err := r.fetch(0)
+	if err != nil {
+		return nil, err
+	}
+	r.size = size
+	r.store(0, b)
+
+	return r, nil
+}
+
+// Size returns the size of the remote file, in bytes.
+func (r *HTTPReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *HTTPReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+
+		// Retrieve the block containing the current position
+		block := pos / r.blockSize
+		b, err := r.block(block)
+		if err != nil {
+			return n, err
+		}
+
+		// A block which ends before the current position means the remote
+		// file is shorter than it was when the HTTPReader was created
+		i := pos - block*r.blockSize
+		if i >= int64(len(b)) {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		n += copy(p[n:], b[i:])
+	}
+
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (r *HTTPReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	off := r.off
+	r.mu.Unlock()
+
+	n, err := r.ReadAt(p, off)
+
+	r.mu.Lock()
+	r.off = off + int64(n)
+	r.mu.Unlock()
+
+	// A short read which fills the buffer is not an error
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *HTTPReader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("source: invalid whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	r.off = offset
+	return offset, nil
+}
+
+// block retrieves a block from the cache, or fetches it from the remote server
+// if it is not cached.
+func (r *HTTPReader) block(block int64) ([]byte, error) {
+	r.mu.Lock()
+	b, ok := r.cache[block]
+	if ok {
+		r.touch(block)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		return b, nil
+	}
+
+	b, _, err := r.fetch(block)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(block, b)
+	return b, nil
+}
+
+// store stores a block in the cache, evicting the least recently used block
+// if the cache is full.
+func (r *HTTPReader) store(block int64, b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.cache[block]; ok {
+		r.touch(block)
+		return
+	}
+
+	if len(r.lru) >= r.cacheBlocks {
+		delete(r.cache, r.lru[0])
+		r.lru = r.lru[1:]
+	}
+
+	r.cache[block] = b
+	r.lru = append(r.lru, block)
+}
+
+// touch marks a cached block as the most recently used.  The caller must hold
+// r.mu.
+func (r *HTTPReader) touch(block int64) {
+	for i, l := range r.lru {
+		if l == block {
+			r.lru = append(append(r.lru[:i:i], r.lru[i+1:]...), block)
+			return
+		}
+	}
+}
+
+// fetch retrieves a block from the remote server using a Range request,
+// returning the block and the total size of the remote file.
+func (r *HTTPReader) fetch(block int64) ([]byte, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(r.ctx)
+
+	start := block * r.blockSize
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+r.blockSize-1))
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return nil, 0, ErrRangeUnsupported
+	default:
+		return nil, 0, fmt.Errorf("source: unexpected HTTP status: %s", res.Status)
+	}
+
+	size, err := parseContentRange(res.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, r.blockSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Every block is full length, except for the final block of the file
+	want := size - start
+	if want > r.blockSize {
+		want = r.blockSize
+	}
+	if int64(len(b)) < want {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	return b, size, nil
+}
+
+// parseContentRange parses the total size of a remote file from the value of
+// a Content-Range header, such as "bytes 0-1023/4096".
+func parseContentRange(s string) (int64, error) {
+	i := strings.LastIndexByte(s, '/')
+	if !strings.HasPrefix(s, "bytes ") || i == -1 {
+		return 0, fmt.Errorf("source: invalid Content-Range: %q", s)
+	}
+
+	size, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("source: invalid Content-Range: %q", s)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,169 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHTTPReaderReadAll verifies that an HTTPReader reads the entire contents
+// of a remote file.
+func TestHTTPReaderReadAll(t *testing.T) {
+	data, _, done := testHTTPServer(true)
+	defer done()
+
+	r, err := NewHTTPReader(context.Background(), nil, data.url, &HTTPConfig{BlockSize: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Size() != int64(len(data.b)) {
+		t.Fatalf("unexpected size: %v != %v", r.Size(), len(data.b))
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data.b) {
+		t.Fatalf("unexpected contents:\n- want: %q\n-  got: %q", data.b, b)
+	}
+}
+
+// TestHTTPReaderPartial verifies that an HTTPReader only downloads the blocks
+// which are read, and caches them.
+func TestHTTPReaderPartial(t *testing.T) {
+	data, requests, done := testHTTPServer(true)
+	defer done()
+
+	r, err := NewHTTPReader(context.Background(), nil, data.url, &HTTPConfig{BlockSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read a range which spans two blocks, twice
+	for i := 0; i < 2; i++ {
+		b := make([]byte, 4)
+		if _, err := r.ReadAt(b, 22); err != nil {
+			t.Fatal(err)
+		}
+		if want := data.b[22:26]; !bytes.Equal(b, want) {
+			t.Fatalf("unexpected contents: %q != %q", b, want)
+		}
+	}
+
+	// First block on open, plus the two blocks read
+	if n := atomic.LoadInt64(requests); n != 3 {
+		t.Fatalf("unexpected number of requests: %v != %v", n, 3)
+	}
+}
+
+// TestHTTPReaderSeek verifies that an HTTPReader can seek within a remote file.
+func TestHTTPReaderSeek(t *testing.T) {
+	data, _, done := testHTTPServer(true)
+	defer done()
+
+	r, err := NewHTTPReader(context.Background(), nil, data.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Seek(-3, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := data.b[len(data.b)-3:]; !bytes.Equal(b, want) {
+		t.Fatalf("unexpected contents: %q != %q", b, want)
+	}
+}
+
+// TestHTTPReaderRangeUnsupported verifies that an HTTPReader returns an error
+// when a server does not support Range requests.
+func TestHTTPReaderRangeUnsupported(t *testing.T) {
+	data, _, done := testHTTPServer(false)
+	defer done()
+
+	if _, err := NewHTTPReader(context.Background(), nil, data.url, nil); err != ErrRangeUnsupported {
+		t.Fatalf("unexpected error: %v != %v", err, ErrRangeUnsupported)
+	}
+}
+
+// TestHTTPReaderShortBlock verifies that an HTTPReader returns an error when a
+// server returns less of a block than was requested, and does not cache the
+// short block.
+func TestHTTPReaderShortBlock(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	var requests int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the first request, made when the HTTPReader is created, returns
+		// a complete block
+		if atomic.AddInt64(&requests, 1) == 1 {
+			http.ServeContent(w, r, "test", time.Time{}, bytes.NewReader(data))
+			return
+		}
+
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 8-15/%d", len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(data[8:10])
+	}))
+	defer s.Close()
+
+	r, err := NewHTTPReader(context.Background(), nil, s.URL, &HTTPConfig{BlockSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		b := make([]byte, 4)
+		if _, err := r.ReadAt(b, 12); err != io.ErrUnexpectedEOF {
+			t.Fatalf("unexpected error: %v != %v", err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	// First block on open, plus one request for each read of the short block
+	if n := atomic.LoadInt64(&requests); n != 3 {
+		t.Fatalf("unexpected number of requests: %v != %v", n, 3)
+	}
+}
+
+// testData is the contents and URL of a file served by a test HTTP server.
+type testData struct {
+	b   []byte
+	url string
+}
+
+// testHTTPServer starts an HTTP server which serves a test file, optionally
+// with support for Range requests.  It returns the file, a counter of requests
+// made to the server, and a function to close the server.
+func testHTTPServer(ranges bool) (*testData, *int64, func()) {
+	data := &testData{
+		b: []byte("the quick brown fox jumps over the lazy dog"),
+	}
+
+	var requests int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		if !ranges {
+			_, _ = w.Write(data.b)
+			return
+		}
+
+		http.ServeContent(w, r, "test", time.Time{}, bytes.NewReader(data.b))
+	}))
+	data.url = s.URL
+
+	return data, &requests, s.Close
+}
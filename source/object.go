@@ -0,0 +1,386 @@
+package source
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// s3RegionDefault is the default region used by S3.
+	s3RegionDefault = "us-east-1"
+
+	// gcsEndpointDefault is the default endpoint used by GCS.
+	gcsEndpointDefault = "https://storage.googleapis.com"
+
+	// emptySHA256 is the hex-encoded SHA-256 hash of an empty request body.
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// errBucketEmpty is returned when an object storage adapter is created
+// without a bucket.
+var errBucketEmpty = errors.New("source: bucket must not be empty")
+
+// An Opener opens the object with the input key for reading.  The returned
+// io.ReadCloser must be closed by the caller when reading is complete.
+type Opener func(ctx context.Context, key string) (io.ReadCloser, error)
+
+// S3Config specifies the configuration for an S3 Opener.
+type S3Config struct {
+	// Bucket is the name of the bucket which contains objects.  It must not be
+	// empty.
+	Bucket string
+
+	// Region is the region in which the bucket resides.  If empty, a default
+	// of "us-east-1" is used.
+	Region string
+
+	// Endpoint is the base URL of an S3-compatible service, such as MinIO,
+	// Cloudflare R2, or the GCS interoperability API.  If empty, the AWS
+	// endpoint for Region is used.
+	Endpoint string
+
+	// PathStyle specifies that the bucket is part of the URL path, rather
+	// than the host name.  Most S3-compatible services require path style.
+	PathStyle bool
+
+	// AccessKeyID, SecretAccessKey, and SessionToken are the credentials used
+	// to sign requests with AWS Signature Version 4.  If AccessKeyID is empty,
+	// requests are not signed, which permits reading public objects.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Client is the HTTP client used to make requests.  If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// HTTP specifies optional configuration for the HTTPReader used to read
+	// each object.
+	HTTP *HTTPConfig
+}
+
+// S3 creates an Opener which reads objects from an S3 or S3-compatible
+// bucket using HTTP Range requests, so that only the parts of an object which
+// are actually read are downloaded.
+//
+// The io.ReadCloser returned by the Opener also implements io.ReaderAt and
+// io.Seeker.
+func S3(cfg S3Config) (Opener, error) {
+	if cfg.Bucket == "" {
+		return nil, errBucketEmpty
+	}
+	if cfg.Region == "" {
+		cfg.Region = s3RegionDefault
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the bucket as part of the host name unless path style is requested
+	prefix := "/" + cfg.Bucket
+	if !cfg.PathStyle {
+		base.Host = cfg.Bucket + "." + base.Host
+		prefix = ""
+	}
+
+	client := cfg.Client
+	if cfg.AccessKeyID != "" {
+		client = withTransport(client, &s3Transport{
+			signer: &s3Signer{
+				region:       cfg.Region,
+				accessKeyID:  cfg.AccessKeyID,
+				secretKey:    cfg.SecretAccessKey,
+				sessionToken: cfg.SessionToken,
+				now:          time.Now,
+			},
+		})
+	}
+
+	return newObjectOpener(client, base, prefix, cfg.HTTP), nil
+}
+
+// GCSConfig specifies the configuration for a GCS Opener.
+type GCSConfig struct {
+	// Bucket is the name of the bucket which contains objects.  It must not be
+	// empty.
+	Bucket string
+
+	// Endpoint is the base URL of the GCS XML API.  If empty, a default of
+	// "https://storage.googleapis.com" is used.
+	Endpoint string
+
+	// Token returns an OAuth 2.0 access token which is used to authorize each
+	// request.  If nil, requests are not authorized, which permits reading
+	// public objects.  Token is typically backed by a token source from
+	// golang.org/x/oauth2.
+	Token func(ctx context.Context) (string, error)
+
+	// Client is the HTTP client used to make requests.  If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// HTTP specifies optional configuration for the HTTPReader used to read
+	// each object.
+	HTTP *HTTPConfig
+}
+
+// GCS creates an Opener which reads objects from a Google Cloud Storage
+// bucket using HTTP Range requests, so that only the parts of an object which
+// are actually read are downloaded.
+//
+// GCS may also be accessed using HMAC keys by passing its interoperability
+// endpoint to S3.
+//
+// The io.ReadCloser returned by the Opener also implements io.ReaderAt and
+// io.Seeker.
+func GCS(cfg GCSConfig) (Opener, error) {
+	if cfg.Bucket == "" {
+		return nil, errBucketEmpty
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = gcsEndpointDefault
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client := cfg.Client
+	if cfg.Token != nil {
+		client = withTransport(client, &bearerTransport{token: cfg.Token})
+	}
+
+	return newObjectOpener(client, base, "/"+cfg.Bucket, cfg.HTTP), nil
+}
+
+// newObjectOpener creates an Opener which reads objects beneath the input
+// base URL and path prefix using an HTTPReader.
+func newObjectOpener(client *http.Client, base *url.URL, prefix string, cfg *HTTPConfig) Opener {
+	return func(ctx context.Context, key string) (io.ReadCloser, error) {
+		u := *base
+		u.Path = strings.TrimSuffix(base.Path, "/") + prefix + "/" + key
+		u.RawPath = escapeObjectPath(u.Path)
+
+		r, err := NewHTTPReader(ctx, client, u.String(), cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &objectReader{HTTPReader: r}, nil
+	}
+}
+
+// objectReader is the io.ReadCloser returned by an Opener.
+type objectReader struct {
+	*HTTPReader
+}
+
+// Close implements io.Closer.  An HTTPReader holds no open connections
+// between reads, so Close does nothing.
+func (r *objectReader) Close() error {
+	return nil
+}
+
+// withTransport returns a copy of client which uses the input transport.  The
+// transport's base is set to the client's original transport.
+func withTransport(client *http.Client, t baseTransport) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	c := *client
+	t.setBase(c.Transport)
+	c.Transport = t
+
+	return &c
+}
+
+// A baseTransport is an http.RoundTripper which wraps another.
+type baseTransport interface {
+	http.RoundTripper
+	setBase(rt http.RoundTripper)
+}
+
+// s3Transport is an http.RoundTripper which signs requests using AWS
+// Signature Version 4.
+type s3Transport struct {
+	base   http.RoundTripper
+	signer *s3Signer
+}
+
+func (t *s3Transport) setBase(rt http.RoundTripper) { t.base = rt }
+
+// RoundTrip implements http.RoundTripper.
+func (t *s3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify its input request
+	req = req.Clone(req.Context())
+	t.signer.sign(req)
+
+	return roundTrip(t.base, req)
+}
+
+// bearerTransport is an http.RoundTripper which authorizes requests using an
+// OAuth 2.0 access token.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token func(ctx context.Context) (string, error)
+}
+
+func (t *bearerTransport) setBase(rt http.RoundTripper) { t.base = rt }
+
+// RoundTrip implements http.RoundTripper.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return roundTrip(t.base, req)
+}
+
+// roundTrip performs a request using rt, or http.DefaultTransport if rt is
+// nil.
+func roundTrip(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	return rt.RoundTrip(req)
+}
+
+// s3Signer signs requests for S3 using AWS Signature Version 4.
+type s3Signer struct {
+	region       string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+// sign adds the headers required by AWS Signature Version 4 to req, which
+// must have no body.
+func (s *s3Signer) sign(req *http.Request) {
+	now := s.now().UTC()
+	date := now.Format("20060102")
+	stamp := now.Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	// Sign the host, any Range header, and all Amazon headers
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "range" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		escapeObjectPath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signed,
+		emptySHA256,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signed+
+		", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of data using key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters as required by AWS Signature
+// Version 4, sorted by key and value.
+func canonicalQuery(q url.Values) string {
+	var pairs []string
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, escapeObject(k, false)+"="+escapeObject(v, false))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// escapeObjectPath escapes an object path as required by S3 and GCS, leaving
+// only unreserved characters and slashes unescaped.
+func escapeObjectPath(p string) string {
+	return escapeObject(p, true)
+}
+
+// escapeObject percent-encodes all bytes of s except unreserved characters,
+// and slashes if slash is true.
+func escapeObject(s string, slash bool) string {
+	const hexUpper = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && slash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexUpper[c>>4])
+			b.WriteByte(hexUpper[c&15])
+		}
+	}
+
+	return b.String()
+}
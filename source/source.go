@@ -0,0 +1,4 @@
+// Package source provides input audio stream sources for package waveform,
+// such as files which are stored on remote servers, or in object storage
+// services such as S3 and Google Cloud Storage.
+package source
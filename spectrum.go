@@ -0,0 +1,197 @@
+package waveform
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+var (
+	// errSpectrumSizeInvalid is returned when a SpectrumAnalyzer frame size
+	// is not a power of two.
+	errSpectrumSizeInvalid = errors.New("waveform: spectrum frame size must be a power of two")
+
+	// errSpectrumHopInvalid is returned when a SpectrumAnalyzer hop size is
+	// not positive.
+	errSpectrumHopInvalid = errors.New("waveform: spectrum hop size must be positive")
+
+	// errSpectrumFuncNil is returned when a SpectrumAnalyzer is created with
+	// a nil SpectrumFunc.
+	errSpectrumFuncNil = errors.New("waveform: spectrum function cannot be nil")
+)
+
+// SpectrumFrame is a windowed FFT frame of audio, produced by a
+// SpectrumAnalyzer.
+type SpectrumFrame struct {
+	// Index is the index of the frame in the audio stream.
+	Index int
+
+	// Start is the time offset of the first sample in the frame, relative to
+	// the beginning of the audio stream.
+	Start time.Duration
+
+	// SampleRate is the sample rate of the audio stream, which can be used to
+	// determine the frequency of each bin: i * SampleRate / (2 * (len(Magnitudes) - 1)).
+	SampleRate int
+
+	// Magnitudes are the magnitudes of each frequency bin, from 0Hz up to and
+	// including the Nyquist frequency.  Magnitudes is only valid for the
+	// duration of a call to a SpectrumFunc, and must be copied if it is
+	// retained.
+	Magnitudes []float64
+}
+
+// SpectrumFunc is a function which receives each SpectrumFrame produced by a
+// SpectrumAnalyzer.  If a SpectrumFunc returns an error, computation stops and
+// the error is returned.
+type SpectrumFunc func(f *SpectrumFrame) error
+
+// SpectrumAnalyzer is an Analyzer which produces windowed FFT frames from
+// audio samples, which are suitable for feeding an audio fingerprinter,
+// so that fingerprinting can share the decode pass used to generate a
+// waveform.
+//
+// Audio is downmixed to a single channel, and divided into overlapping frames
+// of a fixed size, each of which is multiplied by a Hann window before the FFT
+// is applied.  A trailing partial frame is discarded.
+//
+// A SpectrumAnalyzer analyzes a single audio stream, and is not safe for
+// concurrent use.
+type SpectrumAnalyzer struct {
+	size int
+	hop  int
+	fn   SpectrumFunc
+
+	window []float64
+	buf    []float64
+	fft    []complex128
+	frame  SpectrumFrame
+}
+
+// NewSpectrumAnalyzer creates a SpectrumAnalyzer which produces frames of the
+// input size, which must be a power of two, beginning every hop samples.
+// Each frame is passed to the input SpectrumFunc.
+//
+// For example, a chromaprint-style fingerprinter typically uses frames of 4096
+// samples with a hop of 4096/3 samples.
+func NewSpectrumAnalyzer(size int, hop int, fn SpectrumFunc) (*SpectrumAnalyzer, error) {
+	if size < 2 || size&(size-1) != 0 {
+		return nil, errSpectrumSizeInvalid
+	}
+	if hop <= 0 {
+		return nil, errSpectrumHopInvalid
+	}
+	if fn == nil {
+		return nil, errSpectrumFuncNil
+	}
+
+	// Precompute Hann window
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+	}
+
+	return &SpectrumAnalyzer{
+		size:   size,
+		hop:    hop,
+		fn:     fn,
+		window: window,
+		fft:    make([]complex128, size),
+		frame: SpectrumFrame{
+			Magnitudes: make([]float64, size/2+1),
+		},
+	}, nil
+}
+
+// Analyze implements Analyzer.
+func (a *SpectrumAnalyzer) Analyze(c *Chunk) error {
+	channels := c.Config.Channels
+	if channels <= 0 {
+		return nil
+	}
+	a.frame.SampleRate = c.Config.SampleRate
+
+	// Downmix to a single channel
+	for i := 0; i+channels <= len(c.Samples); i += channels {
+		var sum float64
+		for ch := 0; ch < channels; ch++ {
+			sum += c.Samples.At(i + ch)
+		}
+
+		a.buf = append(a.buf, sum/float64(channels))
+	}
+
+	// Produce as many complete frames as possible
+	var off int
+	for ; off+a.size <= len(a.buf); off += a.hop {
+		if err := a.emit(a.buf[off : off+a.size]); err != nil {
+			return err
+		}
+	}
+
+	// Retain samples needed by the next frame
+	if off > len(a.buf) {
+		off = len(a.buf)
+	}
+	a.buf = append(a.buf[:0], a.buf[off:]...)
+
+	return nil
+}
+
+// emit applies a window and FFT to a frame of samples, and passes the result
+// to the SpectrumFunc.
+func (a *SpectrumAnalyzer) emit(samples []float64) error {
+	for i, s := range samples {
+		a.fft[i] = complex(s*a.window[i], 0)
+	}
+	fft(a.fft)
+
+	for i := range a.frame.Magnitudes {
+		a.frame.Magnitudes[i] = cmplx.Abs(a.fft[i])
+	}
+
+	if a.frame.SampleRate > 0 {
+		start := a.frame.Index * a.hop
+		a.frame.Start = time.Duration(int64(start) * int64(time.Second) / int64(a.frame.SampleRate))
+	}
+
+	if err := a.fn(&a.frame); err != nil {
+		return err
+	}
+	a.frame.Index++
+
+	return nil
+}
+
+// fft performs an in-place, iterative radix-2 fast Fourier transform.  The
+// length of x must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// Butterflies
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+)
+
+// TestNewSpectrumAnalyzerErrors verifies that NewSpectrumAnalyzer rejects
+// invalid parameters.
+func TestNewSpectrumAnalyzerErrors(t *testing.T) {
+	fn := func(f *SpectrumFrame) error { return nil }
+
+	var tests = []struct {
+		size int
+		hop  int
+		fn   SpectrumFunc
+		err  error
+	}{
+		{1000, 100, fn, errSpectrumSizeInvalid},
+		{0, 100, fn, errSpectrumSizeInvalid},
+		{1024, 0, fn, errSpectrumHopInvalid},
+		{1024, 512, nil, errSpectrumFuncNil},
+	}
+
+	for i, test := range tests {
+		if _, err := NewSpectrumAnalyzer(test.size, test.hop, test.fn); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// TestSpectrumAnalyzerFrames verifies that SpectrumAnalyzer produces
+// overlapping frames, with peak magnitude at the frequency of a sine wave.
+func TestSpectrumAnalyzerFrames(t *testing.T) {
+	// One second of mono, 8kHz audio with a 1kHz tone, which falls exactly
+	// on bin 128 of a 1024 sample FFT
+	samples := make([]int16, 8000)
+	for i := range samples {
+		samples[i] = int16(16384 * math.Sin(2*math.Pi*1000*float64(i)/8000))
+	}
+
+	var starts []time.Duration
+	a, err := NewSpectrumAnalyzer(1024, 512, func(f *SpectrumFrame) error {
+		starts = append(starts, f.Start)
+
+		if len(f.Magnitudes) != 513 {
+			t.Fatalf("unexpected number of bins: %v != %v", len(f.Magnitudes), 513)
+		}
+
+		var peak int
+		for i, m := range f.Magnitudes {
+			if m > f.Magnitudes[peak] {
+				peak = i
+			}
+		}
+		if peak != 128 {
+			t.Fatalf("unexpected peak bin: %v != %v", peak, 128)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, samples)), Resolution(3), Analyzers(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames begin every 512 samples, and only complete frames are produced
+	if want := (8000-1024)/512 + 1; len(starts) != want {
+		t.Fatalf("unexpected number of frames: %v != %v", len(starts), want)
+	}
+	if want := 64 * time.Millisecond; starts[1] != want {
+		t.Fatalf("unexpected start of second frame: %v != %v", starts[1], want)
+	}
+}
+
+// TestSpectrumAnalyzerError verifies that an error returned by a SpectrumFunc
+// stops computation.
+func TestSpectrumAnalyzerError(t *testing.T) {
+	errSpectrum := errors.New("spectrum failed")
+	a, err := NewSpectrumAnalyzer(256, 256, func(f *SpectrumFrame) error {
+		return errSpectrum
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(bytes.NewReader(wavFile), Analyzers(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Compute(); err != errSpectrum {
+		t.Fatalf("unexpected Compute error: %v != %v", err, errSpectrum)
+	}
+}
+
+// TestFFT verifies fft against a direct computation of the discrete Fourier
+// transform.
+func TestFFT(t *testing.T) {
+	x := []complex128{1, 2, 3, 4, -1, -2, 0.5, 0}
+
+	want := make([]complex128, len(x))
+	for k := range want {
+		for n, v := range x {
+			want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(x))))
+		}
+	}
+
+	fft(x)
+	for i := range x {
+		if cmplx.Abs(x[i]-want[i]) > 1e-9 {
+			t.Fatalf("unexpected value at index %d: %v != %v", i, x[i], want[i])
+		}
+	}
+}
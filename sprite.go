@@ -0,0 +1,80 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+var (
+	// errSpritePlayedNil is returned when a nil ColorFunc is used as the played
+	// color function in a call to DrawSprite.
+	errSpritePlayedNil = errors.New("waveform: played color function cannot be nil")
+
+	// errSpriteWidthZero is returned when a width of 0 is used in a call to
+	// DrawSprite.
+	errSpriteWidthZero = errors.New("waveform: sprite width cannot be 0")
+)
+
+// DrawSprite creates a new image.Image sprite sheet from a slice of float64 values.
+//
+// The sprite contains the waveform drawn with the receiving Waveform's foreground
+// color function (the "unplayed" variant), stacked above the same waveform drawn
+// with the played color function (the "played" variant).  Web players typically
+// implement progress display by cropping between the two variants.
+//
+// If one or more widths are specified, a pair of variants is drawn for each width,
+// in order, by resampling values to fit.  Each width is rounded down to a multiple
+// of the X-axis scaling factor.  If no widths are specified, the waveform is drawn
+// at its natural width.
+func (w *Waveform) DrawSprite(values []float64, played ColorFunc, widths ...uint) (image.Image, error) {
+	if played == nil {
+		return nil, errSpritePlayedNil
+	}
+
+	// Draw played variant using a copy of the style, so that the receiver
+	// is never modified
+	us := w.Style()
+	ps := us
+	ps.fgColorFn = played
+
+	// Draw at natural width if none specified
+	sets := [][]float64{values}
+	if len(widths) > 0 {
+		sets = sets[:0]
+		for _, width := range widths {
+			n := int(width / us.scaleX)
+			if n == 0 {
+				return nil, errSpriteWidthZero
+			}
+
+			sets = append(sets, resampleMax(values, n))
+		}
+	}
+
+	// Draw each variant, tracking the size of the resulting sprite
+	var images []image.Image
+	var maxX, maxY int
+	for _, set := range sets {
+		for _, img := range []image.Image{us.Draw(set), ps.Draw(set)} {
+			images = append(images, img)
+
+			b := img.Bounds()
+			if b.Max.X > maxX {
+				maxX = b.Max.X
+			}
+			maxY += b.Max.Y
+		}
+	}
+
+	// Stack each variant vertically in the sprite
+	sprite := image.NewRGBA(image.Rect(0, 0, maxX, maxY))
+	var y int
+	for _, img := range images {
+		b := img.Bounds()
+		draw.Draw(sprite, b.Add(image.Pt(0, y)), img, image.Point{}, draw.Src)
+		y += b.Max.Y
+	}
+
+	return sprite, nil
+}
@@ -0,0 +1,78 @@
+package waveform
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestWaveformDrawSpriteNaturalWidth verifies that Waveform.DrawSprite stacks
+// unplayed and played variants at the natural width of a waveform.
+func TestWaveformDrawSpriteNaturalWidth(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(SolidColor(black)),
+		Scale(2, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := w.DrawSprite([]float64{0.10, 0.20, 0.30}, SolidColor(red))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := img.Bounds()
+	if b.Max.X != 6 || b.Max.Y != 2*imgYDefault {
+		t.Fatalf("unexpected sprite size: %dx%d", b.Max.X, b.Max.Y)
+	}
+
+	// Check center of each variant
+	half := imgYDefault / 2
+	if c := color.RGBAModel.Convert(img.At(0, half)); c != black {
+		t.Fatalf("unexpected unplayed color: %v != %v", c, black)
+	}
+	if c := color.RGBAModel.Convert(img.At(0, imgYDefault+half)); c != red {
+		t.Fatalf("unexpected played color: %v != %v", c, red)
+	}
+
+	// Receiver must not be modified
+	if c := w.style.fgColorFn(0, 0, 0, 0, 0, 0); c != black {
+		t.Fatalf("receiver foreground modified: %v != %v", c, black)
+	}
+}
+
+// TestWaveformDrawSpriteWidths verifies that Waveform.DrawSprite draws a pair
+// of variants for each requested width.
+func TestWaveformDrawSpriteWidths(t *testing.T) {
+	w, err := New(nil, Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := w.DrawSprite(make([]float64, 100), SolidColor(red), 20, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := img.Bounds()
+	if b.Max.X != 20 || b.Max.Y != 4*imgYDefault {
+		t.Fatalf("unexpected sprite size: %dx%d", b.Max.X, b.Max.Y)
+	}
+}
+
+// TestWaveformDrawSpriteErrors verifies that Waveform.DrawSprite returns errors
+// for invalid input.
+func TestWaveformDrawSpriteErrors(t *testing.T) {
+	w, err := New(nil, Scale(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.DrawSprite(nil, nil); err != errSpritePlayedNil {
+		t.Fatalf("unexpected error: %v != %v", err, errSpritePlayedNil)
+	}
+	if _, err := w.DrawSprite(nil, SolidColor(red), 3); err != errSpriteWidthZero {
+		t.Fatalf("unexpected error: %v != %v", err, errSpriteWidthZero)
+	}
+}
@@ -0,0 +1,311 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+)
+
+// RenderStyle is an immutable set of options which determine how computed
+// values are drawn as a waveform image, such as colors, scale, and smoothing.
+//
+// A RenderStyle separates "how to render" from "how to compute": values
+// computed by a Waveform may be cached and drawn with any number of styles,
+// and a RenderStyle may be shared and used concurrently.
+//
+// A RenderStyle is created using NewStyle, or retrieved from an existing
+// Waveform using Waveform.Style.  The zero value is not a usable RenderStyle.
+type RenderStyle struct {
+	bgColorFn ColorFunc
+	fgColorFn ColorFunc
+
+	// columnFn, if set, is called as each column is drawn
+	columnFn ColumnFunc
+
+	scaleX uint
+	scaleY uint
+
+	sharpness uint
+
+	scaleClipping bool
+
+	// normalize, if nonzero, is the fraction of the height of the image
+	// reached by the maximum value
+	normalize float64
+
+	smooth  int
+	attack  time.Duration
+	release time.Duration
+
+	// alpha is the factor of the exponential moving average applied to
+	// values; 0 and 1 disable it
+	alpha float64
+
+	// resolution is the resolution at which values were computed, used to
+	// determine the duration of each value for attack/release smoothing
+	resolution uint
+
+	vector       bool
+	outlineFn    ColorFunc
+	outlineWidth uint
+
+	invalidValues InvalidValuePolicy
+
+	// clamp indicates that values are clamped to the range [clampMin,
+	// clampMax] before they are drawn
+	clamp    bool
+	clampMin float64
+	clampMax float64
+
+	linear bool
+
+	format ImageFormat
+
+	// height is the height of the image in pixels, which overrides scaleY
+	// when nonzero
+	height       uint
+	legacyCenter bool
+
+	minHeight uint
+	levels    uint
+
+	// pixelSize, if greater than 1, is the size of the square blocks of
+	// pixels in which the image is drawn
+	pixelSize uint
+	headroom  float64
+	mirrorGap uint
+
+	proportional    bool
+	pixelsPerSecond float64
+
+	// explicit is the set of options which were explicitly applied, so that
+	// derived styles detect incompatible combinations of options
+	explicit map[string]bool
+}
+
+// defaultStyle returns a RenderStyle with the default options of the waveform
+// package.
+func defaultStyle() RenderStyle {
+	return RenderStyle{
+		// Generate solid, black background color with solid, white
+		// foreground color waveform using ColorFunc
+		bgColorFn: SolidColor(color.White),
+		fgColorFn: SolidColor(color.Black),
+
+		// No scaling
+		scaleX: 1,
+		scaleY: 1,
+
+		// Normal sharpness
+		sharpness: 1,
+
+		// Do not scale clipping values
+		scaleClipping: false,
+		normalize:     0,
+
+		// No smoothing
+		smooth:  0,
+		attack:  0,
+		release: 0,
+		alpha:   1,
+
+		// Values computed once per second of audio
+		resolution: 1,
+
+		// Draw by setting individual pixels, with no outline
+		vector:       false,
+		outlineFn:    nil,
+		outlineWidth: 0,
+
+		// Clamp invalid values while drawing
+		invalidValues: InvalidValueClamp,
+
+		// Do not clamp values
+		clamp: false,
+
+		// Blend anti-aliased edges in sRGB, for compatibility
+		linear: false,
+
+		// Draw into 8-bit RGBA images
+		format: FormatRGBA,
+
+		// Height determined by Y-axis scaling, with the waveform exactly
+		// centered vertically
+		height:       0,
+		legacyCenter: false,
+
+		// Silent windows are not drawn, and waveforms may fill the image
+		minHeight: 0,
+		headroom:  0,
+
+		// Values are drawn at any height, rather than in discrete steps
+		levels: 0,
+
+		// Draw at the full resolution of the image
+		pixelSize: 0,
+
+		// Top and bottom halves of the waveform meet at the center
+		mirrorGap: 0,
+
+		// Each value is drawn at the width of the X-axis scaling factor,
+		// regardless of the duration of audio used to compute it
+		proportional: false,
+
+		// Density determined by resolution and X-axis scaling
+		pixelsPerSecond: 0,
+	}
+}
+
+// usableHeight returns the height of the region of an image with the input
+// height in which a waveform may be drawn, after reserving headroom at the top
+// and bottom of the image.  The margins are equal, so that the waveform
+// remains exactly centered.
+func (s *RenderStyle) usableHeight(maxY int) int {
+	margin := int(math.Ceil(float64(maxY) * s.headroom / 2))
+	if usable := maxY - 2*margin; usable > 0 {
+		return usable
+	}
+
+	return 0
+}
+
+// imageHeight returns the height of the images drawn using the receiving
+// RenderStyle struct.
+func (s *RenderStyle) imageHeight() int {
+	if s.height > 0 {
+		return int(s.height)
+	}
+
+	return imgYDefault * int(s.scaleY)
+}
+
+// NewStyle creates a new RenderStyle, applying zero or more OptionsFunc to
+// the default style.  Options which do not affect drawing, such as
+// SampleFunction, are ignored, with the exception of Resolution, which
+// determines the duration of each value for attack/release smoothing.
+func NewStyle(options ...OptionsFunc) (RenderStyle, error) {
+	w, err := New(nil, options...)
+	if err != nil {
+		return RenderStyle{}, err
+	}
+
+	return w.Style(), nil
+}
+
+// Style returns the RenderStyle used by the receiving Waveform struct to draw
+// computed values.  The returned RenderStyle is not affected by options which
+// are later applied to the Waveform.
+func (w *Waveform) Style() RenderStyle {
+	s := w.style
+	s.resolution = w.resolution
+	s.explicit = copyExplicit(w.explicit)
+
+	return s
+}
+
+// With returns a new RenderStyle derived from the receiving RenderStyle, with
+// zero or more OptionsFunc applied.  The receiving RenderStyle is not modified.
+//
+// With allows an application to define a base style, and derive variants of it
+// for each context in which a waveform is displayed, without repeating every
+// option:
+//
+//	base, _ := waveform.NewStyle(waveform.FGColorFunction(brand))
+//	thumb, _ := base.With(waveform.Scale(1, 1))
+//	hero, _ := base.With(waveform.Scale(4, 3), waveform.Smooth(5))
+//
+// Options are validated against those of the base style, so an option which
+// conflicts with an option of the base style returns an error.
+func (s RenderStyle) With(options ...OptionsFunc) (RenderStyle, error) {
+	w := &Waveform{
+		resolution: s.resolution,
+		sampleFn:   RMSF64Samples,
+		style:      s,
+		explicit:   copyExplicit(s.explicit),
+	}
+
+	if err := w.SetOptions(options...); err != nil {
+		return RenderStyle{}, err
+	}
+
+	return w.Style(), nil
+}
+
+// Draw creates a new image.Image from a slice of float64 values, using the
+// receiving RenderStyle.
+//
+// Invalid values, such as NaN, are handled according to the InvalidValuePolicy
+// of the style.  If the InvalidValueError policy is in use and an invalid value
+// is encountered, Draw returns nil; use DrawChecked to retrieve the error.
+func (s RenderStyle) Draw(values []float64) image.Image {
+	img, _ := s.DrawChecked(values)
+	return img
+}
+
+// DrawChecked creates a new image.Image from a slice of float64 values, in the
+// same way as Draw.
+//
+// If the InvalidValueError policy is in use and an invalid value is encountered,
+// DrawChecked returns a *ValueError which describes it.
+func (s RenderStyle) DrawChecked(values []float64) (image.Image, error) {
+	return s.draw(values, nil)
+}
+
+// draw sanitizes and smooths a slice of values, and draws them using the
+// renderer selected by the receiving RenderStyle.  If durations is not nil, it
+// is the duration of audio used to compute each value, which positions values
+// in time when the ProportionalTime or PixelsPerSecond options are in use.
+func (s *RenderStyle) draw(values []float64, durations []time.Duration) (image.Image, error) {
+	s, values, edges, err := s.layout(values, durations)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(values, edges), nil
+}
+
+// layout sanitizes and smooths a slice of values, and determines the columns
+// in which they are drawn.  It returns the style used to draw the columns, the
+// value of each column, and the X coordinates of the edges of each column if
+// they are positioned by time, as computed by timeEdges.
+func (s *RenderStyle) layout(values []float64, durations []time.Duration) (*RenderStyle, []float64, []int, error) {
+	values, err := s.sanitizeValues(values)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	values = s.smoothValues(values)
+
+	// Reduce values to 1 pixel wide columns at a fixed density, or position
+	// columns by time, if requested
+	var edges []int
+	switch {
+	case s.pixelsPerSecond > 0:
+		ps := *s
+		ps.scaleX = 1
+		s = &ps
+
+		values = s.pixelColumns(values, durations)
+	case s.proportional && durations != nil:
+		edges = s.timeEdges(durations)
+	}
+
+	return s, values, edges, nil
+}
+
+// render draws a slice of laid out values using the renderer selected by the
+// receiving RenderStyle.
+func (s *RenderStyle) render(values []float64, edges []int) image.Image {
+	// Draw at a reduced resolution and scale up, if requested
+	if s.pixelSize > 1 {
+		return s.renderPixelArt(values, edges)
+	}
+
+	// Use vector rasterizer if requested
+	if s.vector {
+		return s.generateVectorImage(values, edges)
+	}
+
+	return s.generateImage(values, edges)
+}
@@ -0,0 +1,113 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestNewStyleDraw verifies that a RenderStyle created using NewStyle draws
+// the same image as a Waveform with the same options.
+func TestNewStyleDraw(t *testing.T) {
+	options := []OptionsFunc{
+		FGColorFunction(SolidColor(color.RGBA{255, 0, 0, 255})),
+		Scale(3, 2),
+		Sharpness(2),
+	}
+
+	s, err := NewStyle(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.30, 0.20}
+	if !bytes.Equal(s.Draw(values).(*image.RGBA).Pix, w.Draw(values).(*image.RGBA).Pix) {
+		t.Fatal("RenderStyle and Waveform images differ")
+	}
+}
+
+// TestNewStyleInvalid verifies that NewStyle returns errors from invalid
+// options.
+func TestNewStyleInvalid(t *testing.T) {
+	if _, err := NewStyle(Scale(0, 1)); err != errScaleXZero {
+		t.Fatalf("unexpected error: %v != %v", err, errScaleXZero)
+	}
+}
+
+// TestWaveformStyleImmutable verifies that a RenderStyle retrieved from a
+// Waveform is not affected by options later applied to the Waveform.
+func TestWaveformStyleImmutable(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := w.Style()
+	if err := w.SetScale(5, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if x := s.Draw([]float64{0.10}).Bounds().Max.X; x != 1 {
+		t.Fatalf("unexpected image width: %v != %v", x, 1)
+	}
+	if x := w.Draw([]float64{0.10}).Bounds().Max.X; x != 5 {
+		t.Fatalf("unexpected image width: %v != %v", x, 5)
+	}
+}
+
+// TestRenderStyleWith verifies that RenderStyle.With derives a new style
+// without modifying the base style.
+func TestRenderStyleWith(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	base, err := NewStyle(FGColorFunction(SolidColor(red)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hero, err := base.With(Scale(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10}
+
+	// Derived style inherits foreground color, and applies its own scale
+	img := hero.Draw(values)
+	if x := img.Bounds().Max.X; x != 4 {
+		t.Fatalf("unexpected image width: %v != %v", x, 4)
+	}
+	if c := img.At(0, imgYDefault/2); !colorsEqual(c, red) {
+		t.Fatalf("unexpected foreground: %v != %v", c, red)
+	}
+
+	// Base style is unchanged
+	if x := base.Draw(values).Bounds().Max.X; x != 1 {
+		t.Fatalf("unexpected base image width: %v != %v", x, 1)
+	}
+}
+
+// TestRenderStyleWithConflict verifies that RenderStyle.With detects options
+// which conflict with those of the base style.
+func TestRenderStyleWithConflict(t *testing.T) {
+	base, err := NewStyle(VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := base.With(Sharpness(2)); !errors.Is(err, errSharpnessConflictsVector) {
+		t.Fatalf("unexpected error: %v != %v", err, errSharpnessConflictsVector)
+	}
+
+	// Failed derivation does not affect the base style
+	if base.explicit["sharpness"] {
+		t.Fatal("With modified base style")
+	}
+}
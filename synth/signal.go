@@ -0,0 +1,178 @@
+package synth
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Tone generates a Signal which is a sine wave with the input frequency, in Hz.
+func Tone(freq float64) Signal {
+	return func(sampleRate int, _ *rand.Rand) func() float64 {
+		step := 2 * math.Pi * freq / float64(sampleRate)
+
+		var phase float64
+		return func() float64 {
+			v := math.Sin(phase)
+			phase = math.Mod(phase+step, 2*math.Pi)
+			return v
+		}
+	}
+}
+
+// Sweep generates a Signal which is a sine wave whose frequency rises or falls
+// exponentially from start to end, in Hz, over the input period.  The sweep
+// repeats after each period.
+func Sweep(start float64, end float64, period time.Duration) Signal {
+	return func(sampleRate int, _ *rand.Rand) func() float64 {
+		n := int(int64(period) * int64(sampleRate) / int64(time.Second))
+		ratio := math.Log(end / start)
+
+		var i int
+		var phase float64
+		return func() float64 {
+			// Determine the instantaneous frequency at this point in the sweep
+			freq := start
+			if n > 0 {
+				freq = start * math.Exp(ratio*float64(i%n)/float64(n))
+			}
+			i++
+
+			v := math.Sin(phase)
+			phase = math.Mod(phase+2*math.Pi*freq/float64(sampleRate), 2*math.Pi)
+			return v
+		}
+	}
+}
+
+// WhiteNoise generates a Signal which is white noise, with equal energy at
+// all frequencies.
+func WhiteNoise() Signal {
+	return func(_ int, rng *rand.Rand) func() float64 {
+		return func() float64 {
+			return rng.Float64()*2 - 1
+		}
+	}
+}
+
+// PinkNoise generates a Signal which is pink noise, with equal energy in each
+// octave, which sounds more natural than white noise.
+func PinkNoise() Signal {
+	return func(_ int, rng *rand.Rand) func() float64 {
+		// Filter white noise using Paul Kellet's refined method, which
+		// approximates a -3dB/octave slope across the audible range
+		var b [7]float64
+		return func() float64 {
+			w := rng.Float64()*2 - 1
+
+			b[0] = 0.99886*b[0] + w*0.0555179
+			b[1] = 0.99332*b[1] + w*0.0750759
+			b[2] = 0.96900*b[2] + w*0.1538520
+			b[3] = 0.86650*b[3] + w*0.3104856
+			b[4] = 0.55000*b[4] + w*0.5329522
+			b[5] = -0.7616*b[5] - w*0.0168980
+			v := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + w*0.5362
+			b[6] = w * 0.115926
+
+			return v * 0.11
+		}
+	}
+}
+
+// Speech generates a Signal which resembles the loudness envelope of speech:
+// bursts of syllables of varying length and loudness, grouped into words which
+// are separated by pauses.  Each syllable is a buzzing tone with a randomized
+// pitch, mixed with a small amount of noise.
+func Speech() Signal {
+	return func(sampleRate int, rng *rand.Rand) func() float64 {
+		samples := func(min, max time.Duration) int {
+			d := min + time.Duration(rng.Int63n(int64(max-min)))
+			return int(int64(d) * int64(sampleRate) / int64(time.Second))
+		}
+
+		var (
+			// Current segment, and position within it
+			n, length int
+			voiced    bool
+
+			// Syllables remaining in the current word
+			syllables int
+
+			// Loudness and pitch of the current syllable
+			gain, pitch, phase float64
+		)
+
+		// next advances to the next segment, which is a syllable, a short gap
+		// between syllables, or a pause between words
+		next := func() {
+			n = 0
+			switch {
+			case voiced && syllables > 0:
+				voiced = false
+				length = samples(20*time.Millisecond, 60*time.Millisecond)
+			case voiced:
+				voiced = false
+				length = samples(150*time.Millisecond, 600*time.Millisecond)
+			default:
+				if syllables == 0 {
+					syllables = 1 + rng.Intn(5)
+				}
+				syllables--
+
+				voiced = true
+				length = samples(80*time.Millisecond, 280*time.Millisecond)
+				gain = 0.4 + 0.6*rng.Float64()
+				pitch = 90 + 130*rng.Float64()
+			}
+		}
+
+		// Begin with a pause, as a speaker would
+		voiced = true
+		next()
+
+		return func() float64 {
+			if n >= length {
+				next()
+			}
+			n++
+
+			if !voiced {
+				return 0
+			}
+
+			// Raised cosine envelope over the syllable
+			env := gain * 0.5 * (1 - math.Cos(2*math.Pi*float64(n)/float64(length)))
+
+			// Buzzing tone with a falling pitch and several harmonics, plus noise
+			freq := pitch * (1 - 0.2*float64(n)/float64(length))
+			phase = math.Mod(phase+2*math.Pi*freq/float64(sampleRate), 2*math.Pi)
+			v := 0.6*math.Sin(phase) + 0.25*math.Sin(2*phase) + 0.1*math.Sin(3*phase)
+			v += 0.05 * (rng.Float64()*2 - 1)
+
+			return env * v
+		}
+	}
+}
+
+// Mix generates a Signal which is the average of the input signals.
+func Mix(signals ...Signal) Signal {
+	return func(sampleRate int, rng *rand.Rand) func() float64 {
+		gens := make([]func() float64, 0, len(signals))
+		for _, s := range signals {
+			gens = append(gens, s(sampleRate, rng))
+		}
+
+		return func() float64 {
+			if len(gens) == 0 {
+				return 0
+			}
+
+			var v float64
+			for _, g := range gens {
+				v += g()
+			}
+
+			return v / float64(len(gens))
+		}
+	}
+}
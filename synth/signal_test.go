@@ -0,0 +1,103 @@
+package synth
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestSweepFrequency verifies that Sweep rises in frequency over its period,
+// by counting zero crossings in the first and last tenths of the sweep.
+func TestSweepFrequency(t *testing.T) {
+	samples := Samples(Sweep(100, 1000, time.Second), time.Second, nil)
+
+	tenth := len(samples) / 10
+	low := zeroCrossings(samples[:tenth])
+	high := zeroCrossings(samples[len(samples)-tenth:])
+
+	// Approximately 100Hz to 126Hz, and 794Hz to 1000Hz, over 100ms
+	if low < 20 || low > 26 {
+		t.Fatalf("unexpected zero crossings at start of sweep: %d", low)
+	}
+	if high < 158 || high > 200 {
+		t.Fatalf("unexpected zero crossings at end of sweep: %d", high)
+	}
+}
+
+// TestPinkNoiseSpectrum verifies that PinkNoise has less high frequency energy
+// than WhiteNoise, relative to its total energy.
+func TestPinkNoiseSpectrum(t *testing.T) {
+	ratio := func(s Signal) float64 {
+		samples := Samples(s, time.Second, nil)
+
+		// Differences between adjacent samples emphasize high frequencies
+		diff := make([]float64, len(samples)-1)
+		for i := range diff {
+			diff[i] = samples[i+1] - samples[i]
+		}
+
+		return rootMeanSquare(diff) / rootMeanSquare(samples)
+	}
+
+	if white, pink := ratio(WhiteNoise()), ratio(PinkNoise()); pink >= white/2 {
+		t.Fatalf("pink noise is not darker than white noise: %v >= %v/2", pink, white)
+	}
+}
+
+// TestSpeechEnvelope verifies that Speech produces both silent pauses and
+// loud syllables, and never exceeds full scale.
+func TestSpeechEnvelope(t *testing.T) {
+	samples := Samples(Speech(), 10*time.Second, nil)
+
+	// Measure loudness in 10ms blocks
+	const block = sampleRateDefault / 100
+	var silent, loud int
+	for i := 0; i+block <= len(samples); i += block {
+		switch rms := rootMeanSquare(samples[i : i+block]); {
+		case rms == 0:
+			silent++
+		case rms > 0.1:
+			loud++
+		}
+	}
+
+	if silent == 0 || loud == 0 {
+		t.Fatalf("unexpected envelope: %d silent blocks, %d loud blocks", silent, loud)
+	}
+
+	for i, s := range samples {
+		if math.Abs(s) > 1 {
+			t.Fatalf("sample %d exceeds full scale: %v", i, s)
+		}
+	}
+}
+
+// TestMix verifies that Mix averages its input signals, and produces silence
+// when no signals are provided.
+func TestMix(t *testing.T) {
+	samples := Samples(Mix(Tone(440), Tone(440)), 10*time.Millisecond, nil)
+	tone := Samples(Tone(440), 10*time.Millisecond, nil)
+	for i := range samples {
+		if math.Abs(samples[i]-tone[i]) > 1e-12 {
+			t.Fatalf("unexpected mixed sample at index %d: %v != %v", i, samples[i], tone[i])
+		}
+	}
+
+	for i, s := range Samples(Mix(), 10*time.Millisecond, nil) {
+		if s != 0 {
+			t.Fatalf("unexpected sample at index %d: %v", i, s)
+		}
+	}
+}
+
+// zeroCrossings counts the number of times a signal crosses zero.
+func zeroCrossings(samples []float64) int {
+	var n int
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,122 @@
+// Package synth generates synthetic audio for package waveform, such as test
+// tones, sweeps, noise, and speech-like signals, encoded as WAV in memory.
+//
+// Synthetic audio is useful for demonstrating rendering styles, and for
+// examples and benchmarks, without the need for audio files.  All randomized
+// signals are generated using a seeded source, so that the same input options
+// always produce the same audio.
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// sampleRateDefault is the default sample rate of generated audio.
+	sampleRateDefault = 44100
+
+	// bitDepth is the bit depth of generated WAV audio.
+	bitDepth = 16
+)
+
+// Options specifies optional configuration for generated audio.
+type Options struct {
+	// SampleRate is the sample rate of the generated audio, in Hz.  If 0,
+	// a default of 44100Hz is used.
+	SampleRate int
+
+	// Channels is the number of channels of the generated audio.  Each channel
+	// contains the same signal.  If 0, a default of 1 channel is used.
+	Channels int
+
+	// Amplitude is the peak amplitude of the generated audio, from 0 to 1,
+	// where 1 is full scale.  If 0, a default of 1 is used.
+	Amplitude float64
+
+	// Seed is the seed used to generate randomized signals, such as noise.
+	Seed int64
+}
+
+// A Signal is a source of mono audio.  Each call to a Signal returns a new
+// function which generates consecutive samples at the input sample rate, in
+// the range [-1, 1], using the input source of randomness.
+type Signal func(sampleRate int, rng *rand.Rand) func() float64
+
+// Samples generates mono audio samples from a Signal with the input duration,
+// scaled by the amplitude from the input Options.  If options are nil, the
+// defaults are used.
+func Samples(s Signal, d time.Duration, options *Options) []float64 {
+	o := options.withDefaults()
+
+	next := s(o.SampleRate, rand.New(rand.NewSource(o.Seed)))
+	out := make([]float64, int64(d)*int64(o.SampleRate)/int64(time.Second))
+	for i := range out {
+		out[i] = math.Max(-1, math.Min(1, next())) * o.Amplitude
+	}
+
+	return out
+}
+
+// WAV generates 16-bit PCM WAV audio from a Signal with the input duration.
+// If options are nil, the defaults are used.  The result may be passed to
+// package waveform using bytes.NewReader.
+func WAV(s Signal, d time.Duration, options *Options) []byte {
+	o := options.withDefaults()
+	samples := Samples(s, d, &o)
+
+	dataSize := len(samples) * o.Channels * bitDepth / 8
+
+	buf := bytes.NewBuffer(make([]byte, 0, 44+dataSize))
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{
+		uint32(16),
+		uint16(1),
+		uint16(o.Channels),
+		uint32(o.SampleRate),
+		uint32(o.SampleRate * o.Channels * bitDepth / 8),
+		uint16(o.Channels * bitDepth / 8),
+		uint16(bitDepth),
+	} {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+
+	// Interleave the same sample into each channel
+	var b [2]byte
+	for _, s := range samples {
+		binary.LittleEndian.PutUint16(b[:], uint16(int16(math.Round(s*math.MaxInt16))))
+		for c := 0; c < o.Channels; c++ {
+			buf.Write(b[:])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// withDefaults returns a copy of the receiving Options, with defaults applied
+// to any unset fields.  A nil receiver produces the default Options.
+func (o *Options) withDefaults() Options {
+	var out Options
+	if o != nil {
+		out = *o
+	}
+
+	if out.SampleRate == 0 {
+		out.SampleRate = sampleRateDefault
+	}
+	if out.Channels == 0 {
+		out.Channels = 1
+	}
+	if out.Amplitude == 0 {
+		out.Amplitude = 1
+	}
+
+	return out
+}
@@ -0,0 +1,106 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestWAVHeader verifies that WAV produces a valid WAV header and the expected
+// amount of interleaved sample data.
+func TestWAVHeader(t *testing.T) {
+	b := WAV(Tone(440), 500*time.Millisecond, &Options{
+		SampleRate: 8000,
+		Channels:   2,
+	})
+
+	if !bytes.HasPrefix(b, []byte("RIFF")) || string(b[8:16]) != "WAVEfmt " {
+		t.Fatalf("unexpected WAV header: %q", b[:16])
+	}
+
+	if channels := binary.LittleEndian.Uint16(b[22:24]); channels != 2 {
+		t.Fatalf("unexpected channels: %v != %v", channels, 2)
+	}
+	if rate := binary.LittleEndian.Uint32(b[24:28]); rate != 8000 {
+		t.Fatalf("unexpected sample rate: %v != %v", rate, 8000)
+	}
+
+	// 4000 samples, 2 channels, 2 bytes each
+	const dataSize = 4000 * 2 * 2
+	if size := binary.LittleEndian.Uint32(b[40:44]); size != dataSize {
+		t.Fatalf("unexpected data size: %v != %v", size, dataSize)
+	}
+	if len(b) != 44+dataSize {
+		t.Fatalf("unexpected WAV length: %v != %v", len(b), 44+dataSize)
+	}
+
+	// Each channel contains the same sample
+	for i := 44; i < len(b); i += 4 {
+		if !bytes.Equal(b[i:i+2], b[i+2:i+4]) {
+			t.Fatalf("channels differ at offset %d", i)
+		}
+	}
+}
+
+// TestSamplesDefaults verifies that Samples applies default options when
+// options are nil.
+func TestSamplesDefaults(t *testing.T) {
+	samples := Samples(Tone(440), time.Second, nil)
+	if len(samples) != sampleRateDefault {
+		t.Fatalf("unexpected number of samples: %v != %v", len(samples), sampleRateDefault)
+	}
+
+	// A full scale sine wave has an RMS of 1/sqrt(2)
+	if rms := rootMeanSquare(samples); math.Abs(rms-1/math.Sqrt2) > 0.001 {
+		t.Fatalf("unexpected RMS: %v", rms)
+	}
+}
+
+// TestSamplesAmplitude verifies that Samples scales signals by the amplitude
+// from the input options.
+func TestSamplesAmplitude(t *testing.T) {
+	samples := Samples(Tone(440), time.Second, &Options{Amplitude: 0.5})
+
+	var peak float64
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+
+	if math.Abs(peak-0.5) > 0.001 {
+		t.Fatalf("unexpected peak: %v", peak)
+	}
+}
+
+// TestSamplesSeed verifies that randomized signals are reproducible using the
+// same seed, and differ using a different seed.
+func TestSamplesSeed(t *testing.T) {
+	gen := func(seed int64) []float64 {
+		return Samples(WhiteNoise(), 10*time.Millisecond, &Options{Seed: seed})
+	}
+
+	a, b, c := gen(1), gen(1), gen(2)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("samples differ at index %d with the same seed", i)
+		}
+	}
+
+	for i := range a {
+		if a[i] != c[i] {
+			return
+		}
+	}
+	t.Fatal("samples are identical with different seeds")
+}
+
+// rootMeanSquare returns the RMS of a slice of samples.
+func rootMeanSquare(samples []float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+
+	return math.Sqrt(sum / float64(len(samples)))
+}
@@ -0,0 +1,195 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+var (
+	// DarkBackground is the background color used for the dark variant of a
+	// ThemePair.
+	DarkBackground = color.RGBA{0x12, 0x12, 0x12, 0xff}
+
+	// LightBackground is the background color used for the light variant of a
+	// ThemePair.
+	LightBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// minThemeContrast is the minimum contrast ratio between a foreground color and
+// the background color of a theme.  This is the WCAG 2.1 recommendation for
+// graphical objects.
+const minThemeContrast = 3.0
+
+// ThemePair is a pair of waveform images, drawn from the same computed values
+// and options, with one variant for a dark background and one variant for a
+// light background.
+type ThemePair struct {
+	Dark  image.Image
+	Light image.Image
+}
+
+// GenerateThemes immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and returns a ThemePair which
+// is customized by zero or more, variadic, OptionsFunc parameters.
+//
+// GenerateThemes is equivalent to calling New, followed by the Compute and
+// DrawThemes methods of a Waveform struct.
+func GenerateThemes(r io.Reader, options ...OptionsFunc) (*ThemePair, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		return nil, err
+	}
+
+	return w.DrawThemes(values)
+}
+
+// DrawThemes creates a ThemePair from a slice of float64 values, so that both
+// dark and light variants of a waveform may be produced from a single Compute
+// pass.
+//
+// The background color of each variant is replaced by DarkBackground or
+// LightBackground.  The foreground and outline colors of the receiving
+// Waveform struct are used as-is where they contrast sufficiently with the
+// background, and are otherwise lightened or darkened until they do.
+func (w *Waveform) DrawThemes(values []float64) (*ThemePair, error) {
+	s := w.Style()
+
+	dark, err := s.themed(DarkBackground).DrawChecked(values)
+	if err != nil {
+		return nil, err
+	}
+
+	light, err := s.themed(LightBackground).DrawChecked(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ThemePair{
+		Dark:  dark,
+		Light: light,
+	}, nil
+}
+
+// themed returns a copy of the receiving RenderStyle which draws on the input
+// background color, with foreground colors adjusted for contrast.
+func (s RenderStyle) themed(bg color.Color) RenderStyle {
+	s.bgColorFn = SolidColor(bg)
+	s.fgColorFn = contrastColor(s.fgColorFn, bg)
+	if s.outlineFn != nil {
+		s.outlineFn = contrastColor(s.outlineFn, bg)
+	}
+
+	return s
+}
+
+// contrastColor generates a ColorFunc which wraps an input ColorFunc, adjusting
+// each color it produces so that it contrasts sufficiently with an input
+// background color.
+func contrastColor(function ColorFunc, bg color.Color) ColorFunc {
+	if function == nil {
+		return nil
+	}
+
+	// Blend towards white on dark backgrounds, and black on light ones
+	bgL := luminance(bg)
+	target := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	if bgL > 0.5 {
+		target = color.NRGBA{0x00, 0x00, 0x00, 0xff}
+	}
+
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		c := function(n, x, y, maxN, maxX, maxY)
+		if contrastRatio(luminance(c), bgL) >= minThemeContrast {
+			return c
+		}
+
+		// Blend in small steps until contrast is acceptable, preserving
+		// the alpha channel of the original color
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		for t := 0.1; t < 1; t += 0.1 {
+			b := color.NRGBA{
+				R: blend(nc.R, target.R, t),
+				G: blend(nc.G, target.G, t),
+				B: blend(nc.B, target.B, t),
+				A: nc.A,
+			}
+
+			if contrastRatio(luminance(b), bgL) >= minThemeContrast {
+				return b
+			}
+		}
+
+		return color.NRGBA{target.R, target.G, target.B, nc.A}
+	}
+}
+
+// contrastSteps is the number of shades between a background color and white
+// or black which are considered by ContrastColor.
+const contrastSteps = 100
+
+// ContrastColor derives a foreground color from an input background color,
+// which meets the input WCAG contrast ratio against the background.
+//
+// The foreground is a shade of the background: it is blended towards white
+// or black, whichever contrasts more with the background, and the shade
+// closest to the background which meets the ratio is returned, so that the
+// foreground retains some of the background's hue.  If no shade meets the
+// ratio, which is only possible for ratios greater than 4.5, white or black
+// is returned.  The returned color is always opaque.
+func ContrastColor(bg color.Color, ratio float64) color.Color {
+	bgL := luminance(bg)
+	target := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	if contrastRatio(0, bgL) > contrastRatio(1, bgL) {
+		target = color.NRGBA{0x00, 0x00, 0x00, 0xff}
+	}
+
+	nc := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	for i := 1; i < contrastSteps; i++ {
+		t := float64(i) / contrastSteps
+		c := color.NRGBA{
+			R: blend(nc.R, target.R, t),
+			G: blend(nc.G, target.G, t),
+			B: blend(nc.B, target.B, t),
+			A: 0xff,
+		}
+
+		if contrastRatio(luminance(c), bgL) >= ratio {
+			return c
+		}
+	}
+
+	return target
+}
+
+// blend linearly interpolates between two color channel values.
+func blend(from uint8, to uint8, t float64) uint8 {
+	return uint8(math.Round(float64(from) + (float64(to)-float64(from))*t))
+}
+
+// luminance computes the WCAG relative luminance of a color, ignoring alpha.
+func luminance(c color.Color) float64 {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	linear := func(v uint8) float64 {
+		return srgbToLinear(float64(v) / 0xff)
+	}
+
+	return 0.2126*linear(nc.R) + 0.7152*linear(nc.G) + 0.0722*linear(nc.B)
+}
+
+// contrastRatio computes the WCAG contrast ratio between two relative
+// luminance values.
+func contrastRatio(a float64, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+
+	return (a + 0.05) / (b + 0.05)
+}
@@ -0,0 +1,149 @@
+package waveform
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestWaveformDrawThemes verifies that Waveform.DrawThemes draws both theme
+// variants, with the expected backgrounds and a contrasting foreground.
+func TestWaveformDrawThemes(t *testing.T) {
+	// Black foreground must be lightened on the dark background
+	w, err := New(nil, FGColorFunction(SolidColor(color.Black)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair, err := w.DrawThemes([]float64{0.10, 0.10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		description string
+		bg          color.Color
+		img         interface {
+			At(x, y int) color.Color
+		}
+	}{
+		{"dark", DarkBackground, pair.Dark},
+		{"light", LightBackground, pair.Light},
+	}
+
+	for _, test := range tests {
+		if !colorsEqual(test.img.At(0, 0), test.bg) {
+			t.Fatalf("%s: unexpected background: %v != %v", test.description, test.img.At(0, 0), test.bg)
+		}
+
+		// Center of the image is always foreground for non-zero values
+		fg := test.img.At(0, imgYDefault/2)
+		if c := contrastRatio(luminance(fg), luminance(test.bg)); c < minThemeContrast {
+			t.Fatalf("%s: insufficient foreground contrast: %v", test.description, c)
+		}
+	}
+
+	// Black already contrasts with the light background, and is unchanged
+	if fg := pair.Light.At(0, imgYDefault/2); !colorsEqual(fg, color.Black) {
+		t.Fatalf("unexpected light foreground: %v != %v", fg, color.Black)
+	}
+}
+
+// TestGenerateThemesWAV verifies that GenerateThemes produces a ThemePair
+// from an input WAV stream.
+func TestGenerateThemesWAV(t *testing.T) {
+	pair, err := GenerateThemes(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pair.Dark.Bounds() != pair.Light.Bounds() {
+		t.Fatalf("mismatched bounds: %v != %v", pair.Dark.Bounds(), pair.Light.Bounds())
+	}
+}
+
+// TestContrastColorPreservesAlpha verifies that contrastColor preserves the
+// alpha channel of adjusted colors.
+func TestContrastColorPreservesAlpha(t *testing.T) {
+	fn := contrastColor(SolidColor(color.NRGBA{0x20, 0x20, 0x20, 0x80}), DarkBackground)
+
+	c := color.NRGBAModel.Convert(fn(0, 0, 0, 0, 0, 0)).(color.NRGBA)
+	if c.A != 0x80 {
+		t.Fatalf("unexpected alpha: %v != %v", c.A, 0x80)
+	}
+}
+
+// TestContrastColor verifies that ContrastColor derives foreground colors which
+// meet the requested contrast ratio against a variety of backgrounds.
+func TestContrastColor(t *testing.T) {
+	backgrounds := []color.Color{
+		color.Black,
+		color.White,
+		DarkBackground,
+		color.RGBA{0x33, 0x66, 0x99, 0xff},
+		color.RGBA{0xff, 0xcc, 0x00, 0xff},
+		color.RGBA{0x77, 0x77, 0x77, 0xff},
+	}
+
+	for _, bg := range backgrounds {
+		for _, ratio := range []float64{1, 3, 4.5} {
+			fg := ContrastColor(bg, ratio)
+			if c := contrastRatio(luminance(fg), luminance(bg)); c < ratio {
+				t.Fatalf("%v: insufficient contrast for ratio %v: %v", bg, ratio, c)
+			}
+			if _, _, _, a := fg.RGBA(); a != 0xffff {
+				t.Fatalf("%v: foreground is not opaque: %v", bg, fg)
+			}
+		}
+	}
+
+	// Shades closest to the background are preferred
+	if fg := ContrastColor(color.White, 1); colorsEqual(fg, color.Black) {
+		t.Fatalf("unexpected foreground for ratio 1: %v", fg)
+	}
+
+	// Unreachable ratios produce white or black
+	grey := color.RGBA{0x77, 0x77, 0x77, 0xff}
+	if fg := ContrastColor(grey, 21); !colorsEqual(fg, color.Black) && !colorsEqual(fg, color.White) {
+		t.Fatalf("unexpected foreground for unreachable ratio: %v", fg)
+	}
+}
+
+// TestOptionAutoContrast verifies that AutoContrast applies a background color
+// and a contrasting foreground color.
+func TestOptionAutoContrast(t *testing.T) {
+	bg := color.RGBA{0x33, 0x66, 0x99, 0xff}
+	w, err := New(nil, AutoContrast(bg, 4.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.10, 0.10})
+	if c := img.At(0, 0); !colorsEqual(c, bg) {
+		t.Fatalf("unexpected background: %v != %v", c, bg)
+	}
+
+	fg := img.At(0, imgYDefault/2)
+	if c := contrastRatio(luminance(fg), luminance(bg)); c < 4.5 {
+		t.Fatalf("insufficient foreground contrast: %v", c)
+	}
+}
+
+// TestOptionAutoContrastInvalid verifies that AutoContrast does not accept
+// nil colors or invalid contrast ratios.
+func TestOptionAutoContrastInvalid(t *testing.T) {
+	testWaveformOptionFunc(t, AutoContrast(nil, 3), errAutoContrastColorNil)
+
+	for _, r := range []float64{0, 0.5, 22, math.NaN()} {
+		testWaveformOptionFunc(t, AutoContrast(color.White, r), errAutoContrastRatioInvalid)
+	}
+}
+
+// colorsEqual reports whether two colors are equal in RGBA space.
+func colorsEqual(a color.Color, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
@@ -0,0 +1,121 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"math"
+	"time"
+)
+
+// errValueSetMismatch is returned when a ValueSet with differing numbers of
+// values and durations is drawn.
+var errValueSetMismatch = errors.New("waveform: value set values and durations differ in length")
+
+// DrawValueSet creates a new image.Image from a ValueSet, in the same way as
+// DrawChecked.
+//
+// If the ProportionalTime option is in use, the duration of audio used to
+// compute each value determines the width of its column, so that the X-axis
+// of the image maps linearly to time at exactly resolution * scaleX pixels per
+// second.  A short final window produces a proportionally narrower column.
+// This allows renders of different audio streams to be overlaid accurately.
+// Otherwise, every column has the same width.
+//
+// If the PixelsPerSecond option is in use, the duration of audio used to
+// compute each value positions it in time, so that a short final window is
+// also drawn accurately.
+func (s RenderStyle) DrawValueSet(vs *ValueSet) (image.Image, error) {
+	if len(vs.Durations) != len(vs.Values) {
+		return nil, errValueSetMismatch
+	}
+
+	return s.draw(vs.Values, vs.Durations)
+}
+
+// DrawValueSet creates a new image.Image from a ValueSet, as returned by
+// ComputeValueSet, in the same way as DrawChecked.  Zero or more OptionsFunc
+// may be specified to override options for a single call to DrawValueSet.
+//
+// See RenderStyle.DrawValueSet for details on how the ProportionalTime option
+// affects the output image.
+func (w *Waveform) DrawValueSet(vs *ValueSet, options ...OptionsFunc) (image.Image, error) {
+	ow, err := w.withOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ow.Style().DrawValueSet(vs)
+}
+
+// timeEdges computes the X coordinate of the left edge of each column, followed
+// by the right edge of the image, so that each column begins at the X
+// coordinate which corresponds to the time at which its window of audio began.
+func (s *RenderStyle) timeEdges(durations []time.Duration) []int {
+	pixelsPerSecond := float64(s.resolution) * float64(s.scaleX)
+
+	edges := make([]int, 0, len(durations)+1)
+	var t time.Duration
+	for _, d := range durations {
+		edges = append(edges, int(math.Round(t.Seconds()*pixelsPerSecond)))
+		t += d
+	}
+
+	return append(edges, int(math.Round(t.Seconds()*pixelsPerSecond)))
+}
+
+// pixelColumns reduces a slice of values to one value for each column of an
+// image at the density specified by the PixelsPerSecond option.  Each value is
+// placed in the column which contains the midpoint of its window of audio, and
+// columns which contain more than one value use the largest.  If durations is
+// nil, every value represents a full window at the current resolution.
+func (s *RenderStyle) pixelColumns(values []float64, durations []time.Duration) []float64 {
+	window := 1 / float64(s.resolution)
+
+	// Determine the total duration, and the number of columns required
+	var total float64
+	for i := range values {
+		if durations != nil {
+			total += durations[i].Seconds()
+		} else {
+			total += window
+		}
+	}
+
+	// Tolerate small errors in durations, which are truncated to nanoseconds
+	n := int(math.Ceil(total*s.pixelsPerSecond - 1e-6))
+	if n == 0 && len(values) > 0 {
+		n = 1
+	}
+
+	out := make([]float64, n)
+	filled := make([]bool, n)
+
+	var t float64
+	for i, v := range values {
+		d := window
+		if durations != nil {
+			d = durations[i].Seconds()
+		}
+
+		p := int((t + d/2) * s.pixelsPerSecond)
+		if p >= n {
+			p = n - 1
+		}
+		if !filled[p] || v > out[p] {
+			out[p] = v
+		}
+		filled[p] = true
+
+		t += d
+	}
+
+	// Columns which contain no midpoint, such as a final column which is only
+	// partially covered, repeat the previous column
+	for p := 1; p < n; p++ {
+		if !filled[p] {
+			out[p] = out[p-1]
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,129 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestGenerateProportionalTime verifies that ProportionalTime draws a short
+// final window as a proportionally narrower column, so that the width of the
+// image is proportional to the duration of the audio stream.
+func TestGenerateProportionalTime(t *testing.T) {
+	// 2.5 seconds of audio
+	wav := makeWAV(8000, 1, make([]int16, 20000))
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		width   int
+	}{
+		{name: "equal widths", options: []OptionsFunc{Scale(10, 1)}, width: 30},
+		{name: "proportional", options: []OptionsFunc{Scale(10, 1), ProportionalTime()}, width: 25},
+		{name: "proportional vector", options: []OptionsFunc{Scale(10, 1), ProportionalTime(), VectorRenderer()}, width: 25},
+		{name: "proportional resolution", options: []OptionsFunc{Scale(3, 1), Resolution(4), ProportionalTime()}, width: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Generate(bytes.NewReader(wav), tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := img.Bounds().Dx(); got != tt.width {
+				t.Fatalf("unexpected image width: %v != %v", got, tt.width)
+			}
+		})
+	}
+}
+
+// TestRenderStyleTimeEdges verifies that timeEdges positions each column at
+// the X coordinate which corresponds to the time at which it began.
+func TestRenderStyleTimeEdges(t *testing.T) {
+	s, err := NewStyle(Resolution(2), Scale(3, 1), ProportionalTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edges := s.timeEdges([]time.Duration{
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+		100 * time.Millisecond,
+	})
+
+	// 6 pixels per second
+	want := []int{0, 3, 6, 7}
+	if fmt.Sprint(want) != fmt.Sprint(edges) {
+		t.Fatalf("unexpected edges: %v != %v", edges, want)
+	}
+}
+
+// TestRenderStyleDrawValueSetMismatch verifies that DrawValueSet rejects a
+// ValueSet with differing numbers of values and durations.
+func TestRenderStyleDrawValueSetMismatch(t *testing.T) {
+	s, err := NewStyle(ProportionalTime())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.DrawValueSet(&ValueSet{Values: []float64{0.10}})
+	if err != errValueSetMismatch {
+		t.Fatalf("unexpected error: %v != %v", err, errValueSetMismatch)
+	}
+}
+
+// TestGeneratePixelsPerSecond verifies that PixelsPerSecond produces an image
+// whose width is proportional to the duration of the audio stream, at any
+// density.
+func TestGeneratePixelsPerSecond(t *testing.T) {
+	// 2.5 seconds of audio
+	wav := makeWAV(8000, 1, make([]int16, 20000))
+
+	var tests = []struct {
+		f     float64
+		width int
+	}{
+		{f: 10, width: 25},
+		{f: 2.5, width: 7},
+		{f: 0.5, width: 2},
+	}
+
+	for _, tt := range tests {
+		for _, vector := range []bool{false, true} {
+			options := []OptionsFunc{PixelsPerSecond(tt.f), Scale(5, 1)}
+			if vector {
+				options = append(options, VectorRenderer())
+			}
+
+			img, err := Generate(bytes.NewReader(wav), options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := img.Bounds().Dx(); got != tt.width {
+				t.Fatalf("unexpected image width at %v pixels per second, vector %v: %v != %v",
+					tt.f, vector, got, tt.width)
+			}
+		}
+	}
+}
+
+// TestRenderStylePixelColumns verifies that pixelColumns reduces values which
+// share a column to the largest value.
+func TestRenderStylePixelColumns(t *testing.T) {
+	s, err := NewStyle(PixelsPerSecond(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Draw values as if they were computed at 4 values per second
+	s.resolution = 4
+
+	got := s.pixelColumns([]float64{0.10, 0.50, 0.20, 0.30, 0.40}, nil)
+	want := []float64{0.50, 0.30, 0.40}
+	if fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Fatalf("unexpected columns: %v != %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package waveform
+
+import (
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// rangeReader is an audio.Reader which reads only a range of time from an
+// underlying audio.Reader.  Samples before the range are decoded and
+// discarded, unless the input stream was seeked past them, and end-of-stream
+// is reported once the range has been read.
+type rangeReader struct {
+	r audio.Reader
+
+	// skip is the number of samples which remain to be discarded
+	skip int64
+
+	// remain is the number of samples which remain in the range, or -1 if the
+	// range extends to the end of the stream
+	remain int64
+}
+
+// newRangeReader creates a rangeReader which reads audio with the input
+// configuration from r, beginning at offset and continuing for duration.  If
+// duration is 0, the range extends to the end of the stream.
+func newRangeReader(r audio.Reader, config audio.Config, offset time.Duration, duration time.Duration) *rangeReader {
+	rr := &rangeReader{
+		r:      r,
+		skip:   timeSamples(config, offset),
+		remain: -1,
+	}
+	if duration > 0 {
+		rr.remain = timeSamples(config, duration)
+	}
+
+	return rr
+}
+
+// advance accounts for the input number of samples which were skipped by
+// seeking the input stream, rather than by reading them.
+func (rr *rangeReader) advance(n int64) {
+	skip := n
+	if skip > rr.skip {
+		skip = rr.skip
+	}
+	rr.skip -= skip
+
+	if rr.remain > 0 {
+		rr.remain -= n - skip
+		if rr.remain < 0 {
+			rr.remain = 0
+		}
+	}
+}
+
+// timeSamples returns the number of interleaved audio samples in the input
+// duration of audio with the input configuration.
+func timeSamples(config audio.Config, d time.Duration) int64 {
+	// Split the duration to avoid overflow for long durations at high sample
+	// rates
+	rate := int64(config.SampleRate)
+	secs, frac := int64(d/time.Second), int64(d%time.Second)
+	frames := secs*rate + frac*rate/int64(time.Second)
+
+	return frames * int64(config.Channels)
+}
+
+// Read implements audio.Reader.
+func (rr *rangeReader) Read(b audio.Slice) (int, error) {
+	// Decode and discard samples before the range, using b as scratch space
+	for rr.skip > 0 {
+		s := b
+		if int64(s.Len()) > rr.skip {
+			s = s.Slice(0, int(rr.skip))
+		}
+
+		n, err := rr.r.Read(s)
+		rr.skip -= int64(n)
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, nil
+		}
+	}
+
+	if rr.remain == 0 {
+		return 0, audio.EOS
+	}
+	if rr.remain > 0 && int64(b.Len()) > rr.remain {
+		b = b.Slice(0, int(rr.remain))
+	}
+
+	n, err := rr.r.Read(b)
+	if rr.remain > 0 {
+		rr.remain -= int64(n)
+	}
+
+	return n, err
+}
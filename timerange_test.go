@@ -0,0 +1,198 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestOffsetDuration verifies that Offset and Duration select the range of
+// time from which values are computed.
+func TestOffsetDuration(t *testing.T) {
+	// Ten seconds of mono audio at 8Hz, in which each second is louder than
+	// the last
+	var pcm []int16
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 8; j++ {
+			pcm = append(pcm, int16(i*1000))
+		}
+	}
+	wav := makeWAV(8, 1, pcm)
+
+	full := computeTimeRange(t, wav)
+
+	var tests = []struct {
+		name    string
+		options []OptionsFunc
+		want    []float64
+		err     error
+	}{
+		{
+			name:    "offset",
+			options: []OptionsFunc{Offset(7 * time.Second)},
+			want:    full[7:],
+		},
+		{
+			name:    "duration",
+			options: []OptionsFunc{Duration(2 * time.Second)},
+			want:    full[:2],
+		},
+		{
+			name:    "offset and duration",
+			options: []OptionsFunc{Offset(2 * time.Second), Duration(3 * time.Second)},
+			want:    full[2:5],
+		},
+		{
+			name:    "duration past end",
+			options: []OptionsFunc{Offset(8 * time.Second), Duration(time.Minute)},
+			want:    full[8:],
+		},
+		{
+			name:    "offset past end",
+			options: []OptionsFunc{Offset(time.Minute)},
+			err:     ErrTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(bytes.NewReader(wav), tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := w.Compute()
+			if err != tt.err {
+				t.Fatalf("unexpected error: %v != %v", err, tt.err)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestOffsetSeek verifies that an Offset on an uncompressed, seekable stream
+// is applied by seeking past the audio before it, producing the same values as
+// a stream which must be decoded from its beginning.
+func TestOffsetSeek(t *testing.T) {
+	var pcm []int16
+	for i := 0; i < 10*8; i++ {
+		pcm = append(pcm, int16(i*100))
+	}
+	wav := makeWAV(8, 1, pcm)
+
+	compute := func(r io.Reader) []float64 {
+		w, err := New(r, Offset(7*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return values
+	}
+
+	r := &countReader{Reader: bytes.NewReader(wav)}
+	got := compute(r)
+	want := compute(io.MultiReader(bytes.NewReader(wav)))
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected values:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	// The 56 sample frames before the offset should not be read
+	if max := int64(len(wav) - 56*2); r.n > max {
+		t.Fatalf("read too many bytes: %v > %v", r.n, max)
+	}
+}
+
+// TestOffsetDurationValueSet verifies that the durations of values computed
+// from a range of time reflect only that range.
+func TestOffsetDurationValueSet(t *testing.T) {
+	w, err := New(
+		bytes.NewReader(makeWAV(8, 2, make([]int16, 2*8*4))),
+		Offset(1500*time.Millisecond),
+		Duration(time.Second+750*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprint([]time.Duration{time.Second, 750 * time.Millisecond})
+	if got := fmt.Sprint(vs.Durations); got != want {
+		t.Fatalf("unexpected durations: %v != %v", got, want)
+	}
+}
+
+// TestRangeReaderShortReads verifies that a rangeReader skips and limits
+// samples correctly when the underlying reader returns few samples per read.
+func TestRangeReaderShortReads(t *testing.T) {
+	samples := make([]float64, 20)
+	for i := range samples {
+		samples[i] = float64(i)
+	}
+
+	config := audio.Config{SampleRate: 4, Channels: 1}
+	r := &shortReader{samples: samples, max: 3}
+	rr := newRangeReader(r, config, 1250*time.Millisecond, 2*time.Second)
+
+	var got []float64
+	buf := make(audio.Float64, 4)
+	for {
+		n, err := rr.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == audio.EOS {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := fmt.Sprint(samples[5:13]); fmt.Sprint(got) != want {
+		t.Fatalf("unexpected samples: %v != %v", got, want)
+	}
+}
+
+// TestTimeSamples verifies that durations are converted to a number of
+// interleaved samples without overflow.
+func TestTimeSamples(t *testing.T) {
+	config := audio.Config{SampleRate: 192000, Channels: 8}
+
+	if got, want := timeSamples(config, 1000*time.Hour), int64(1000*3600*192000*8); got != want {
+		t.Fatalf("unexpected samples: %d != %d", got, want)
+	}
+	if got, want := timeSamples(config, 500*time.Millisecond), int64(96000*8); got != want {
+		t.Fatalf("unexpected samples: %d != %d", got, want)
+	}
+}
+
+// computeTimeRange computes all values from a WAV stream.
+func computeTimeRange(t *testing.T, wav []byte) []float64 {
+	t.Helper()
+
+	w, err := New(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
@@ -0,0 +1,73 @@
+package waveform
+
+import (
+	"image"
+	"io"
+	"time"
+)
+
+// Timings reports how long each stage of generating a waveform image took,
+// so that applications can track rendering performance and detect
+// regressions without profiling.
+type Timings struct {
+	// Compute is the time spent reading and decoding the input audio stream,
+	// and computing values from it.
+	Compute time.Duration
+
+	// Draw is the time spent drawing the computed values.
+	Draw time.Duration
+}
+
+// Total returns the total time spent generating a waveform image.
+func (t Timings) Total() time.Duration {
+	return t.Compute + t.Draw
+}
+
+// GenerateTimed generates a waveform image in the same way as Generate, and
+// also reports how long each stage of generation took.  If an error occurs
+// while computing values, only the Compute time is set.
+func GenerateTimed(r io.Reader, options ...OptionsFunc) (image.Image, Timings, error) {
+	img, _, t, err := generate(r, options)
+	return img, t, err
+}
+
+// generate implements Generate, GenerateTimed, and GenerateAll, returning the
+// values which were computed to draw the image.
+func generate(r io.Reader, options []OptionsFunc) (image.Image, []float64, Timings, error) {
+	var t Timings
+
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, nil, t, err
+	}
+
+	// Draw columns proportional to time if requested, which requires the
+	// duration of each window
+	if w.style.proportional || w.style.pixelsPerSecond > 0 {
+		start := time.Now()
+		vs, err := w.ComputeValueSet()
+		t.Compute = time.Since(start)
+		if err != nil {
+			return w.Draw(nil), nil, t, err
+		}
+
+		start = time.Now()
+		img, err := w.DrawValueSet(vs)
+		t.Draw = time.Since(start)
+
+		return img, vs.Values, t, err
+	}
+
+	start := time.Now()
+	values, err := w.Compute()
+	t.Compute = time.Since(start)
+	if err != nil {
+		return w.Draw(values), values, t, err
+	}
+
+	start = time.Now()
+	img, err := w.DrawChecked(values)
+	t.Draw = time.Since(start)
+
+	return img, values, t, err
+}
@@ -0,0 +1,47 @@
+package waveform
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestGenerateTimed verifies that GenerateTimed measures each stage of
+// generating a waveform image.
+func TestGenerateTimed(t *testing.T) {
+	img, timings, err := GenerateTimed(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img == nil {
+		t.Fatal("no image was generated")
+	}
+
+	if timings.Compute <= 0 || timings.Draw <= 0 {
+		t.Fatalf("unexpected timings: %+v", timings)
+	}
+	if timings.Total() != timings.Compute+timings.Draw {
+		t.Fatalf("unexpected total: %v", timings.Total())
+	}
+}
+
+// TestGenerateTimedError verifies that GenerateTimed measures only the time
+// spent computing values if an error occurs.
+func TestGenerateTimedError(t *testing.T) {
+	_, timings, err := GenerateTimed(bytes.NewReader(mp3File))
+	if err != ErrFormat {
+		t.Fatalf("unexpected error: %v != %v", err, ErrFormat)
+	}
+
+	if timings.Draw != 0 {
+		t.Fatalf("unexpected timings: %+v", timings)
+	}
+}
+
+// TestTimingsTotal verifies that Timings.Total sums each stage.
+func TestTimingsTotal(t *testing.T) {
+	timings := Timings{Compute: 3 * time.Second, Draw: time.Second}
+	if got := timings.Total(); got != 4*time.Second {
+		t.Fatalf("unexpected total: %v", got)
+	}
+}
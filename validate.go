@@ -0,0 +1,98 @@
+package waveform
+
+import "fmt"
+
+// optionConflicts is a list of options which cannot be used together, and
+// the error which is returned when they are.
+var optionConflicts = []struct {
+	a   string
+	b   string
+	err error
+}{
+	{"sharpness", "vectorRenderer", errSharpnessConflictsVector},
+	{"legacyCentering", "vectorRenderer", errLegacyCenteringConflictsVector},
+	{"height", "scaleY", errHeightConflictsScale},
+	{"pixelsPerSecond", "resolution", errPixelsPerSecondConflictsResolution},
+	{"downmixFunction", "downmix", errDownmixFunctionConflictsDownmix},
+	{"overlap", "checkpointEvery", errOverlapConflictsCheckpoint},
+	{"chunkFunction", "sampleFunction", errChunkFunctionConflictsSampleFunction},
+	{"normalized", "scaleClipping", errNormalizedConflictsScaleClipping},
+}
+
+// optionRequirements is a list of options which have no effect unless another
+// option is also used, and the error which is returned when it is not.
+var optionRequirements = []struct {
+	option   string
+	requires string
+	err      error
+}{
+	{"outline", "vectorRenderer", errOutlineRequiresVector},
+	{"downmixChannels", "downmix", errDownmixChannelsRequiresDownmix},
+}
+
+// Validate checks the receiving Waveform struct for incompatible combinations
+// of explicitly applied options, which would otherwise produce surprising
+// output.  If any are found, a descriptive *OptionsError is returned.  Options
+// which are applied with their default values never conflict, so a conflict
+// may be resolved by reverting either option to its default value.
+//
+// Validate is called automatically by SetOptions, and by extension, New.
+func (w *Waveform) Validate() error {
+	for _, c := range optionConflicts {
+		if w.explicit[c.a] && w.explicit[c.b] {
+			return c.err
+		}
+	}
+
+	for _, r := range optionRequirements {
+		if w.explicit[r.option] && !w.explicit[r.requires] {
+			return r.err
+		}
+	}
+
+	// Values streamed by ComputeFunc are not retained, so they cannot be
+	// written to a Checkpoint
+	if w.valueFn != nil && w.checkpointW != nil {
+		return errValueFuncCheckpoint
+	}
+
+	return nil
+}
+
+// markSet records whether an option was explicitly applied to the receiving
+// Waveform struct.  An option which is applied with its default value is not
+// considered set, so that reverting an option to its default value also
+// reverts any conflict it caused.
+func (w *Waveform) markSet(option string, set bool) {
+	if !set {
+		delete(w.explicit, option)
+		return
+	}
+
+	if w.explicit == nil {
+		w.explicit = make(map[string]bool)
+	}
+
+	w.explicit[option] = true
+}
+
+// sameFunc reports whether a and b are the same function, such as a
+// SampleReduceFunc and its default value.  Functions cannot be compared
+// directly, so the addresses of their code are compared instead.
+func sameFunc(a, b interface{}) bool {
+	return fmt.Sprintf("%p", a) == fmt.Sprintf("%p", b)
+}
+
+// copyExplicit returns a copy of a set of explicitly applied options.
+func copyExplicit(explicit map[string]bool) map[string]bool {
+	if explicit == nil {
+		return nil
+	}
+
+	out := make(map[string]bool, len(explicit))
+	for k, v := range explicit {
+		out[k] = v
+	}
+
+	return out
+}
@@ -0,0 +1,126 @@
+package waveform
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWaveformValidate verifies that Waveform.Validate detects incompatible
+// combinations of options.
+func TestWaveformValidate(t *testing.T) {
+	var tests = []struct {
+		options []OptionsFunc
+		err     error
+	}{
+		// No options
+		{nil, nil},
+		// Compatible options
+		{[]OptionsFunc{VectorRenderer(), Outline(SolidColor(black), 2)}, nil},
+		{[]OptionsFunc{Sharpness(2), Scale(4, 1)}, nil},
+		{[]OptionsFunc{Scale(4, 1), Height(20)}, nil},
+		{[]OptionsFunc{Downmix(DownmixFirst), DownmixChannels(4)}, nil},
+		{[]OptionsFunc{CheckpointEvery(2, &bytes.Buffer{}), Overlap(1)}, nil},
+		// Default values never conflict
+		{[]OptionsFunc{Sharpness(1), VectorRenderer()}, nil},
+		{[]OptionsFunc{SampleFunction(RMSF64Samples), ChunkFunction(chunkRMS)}, nil},
+		{[]OptionsFunc{Downmix(DownmixNone), DownmixFunction(DownmixMid)}, nil},
+		// Conflicting options, in either order
+		{[]OptionsFunc{Sharpness(2), VectorRenderer()}, errSharpnessConflictsVector},
+		{[]OptionsFunc{VectorRenderer(), Sharpness(2)}, errSharpnessConflictsVector},
+		{[]OptionsFunc{LegacyCentering(), VectorRenderer()}, errLegacyCenteringConflictsVector},
+		{[]OptionsFunc{Height(20), Scale(1, 2)}, errHeightConflictsScale},
+		{[]OptionsFunc{Scale(4, 3), Height(20)}, errHeightConflictsScale},
+		{[]OptionsFunc{Resolution(4), PixelsPerSecond(10)}, errPixelsPerSecondConflictsResolution},
+		{[]OptionsFunc{Downmix(DownmixAverage), DownmixFunction(DownmixMid)}, errDownmixFunctionConflictsDownmix},
+		{[]OptionsFunc{CheckpointEvery(2, &bytes.Buffer{}), Overlap(2)}, errOverlapConflictsCheckpoint},
+		{[]OptionsFunc{SampleFunction(AverageF64Samples), ChunkFunction(chunkRMS)}, errChunkFunctionConflictsSampleFunction},
+		{[]OptionsFunc{ScaleClipping(), Normalized(1)}, errNormalizedConflictsScaleClipping},
+		// Missing required option
+		{[]OptionsFunc{Outline(SolidColor(black), 2)}, errOutlineRequiresVector},
+		{[]OptionsFunc{DownmixChannels(4)}, errDownmixChannelsRequiresDownmix},
+	}
+
+	for i, test := range tests {
+		if _, err := New(nil, test.options...); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// TestWaveformValidateIncremental verifies that options applied in separate
+// calls to SetOptions are validated together.
+func TestWaveformValidateIncremental(t *testing.T) {
+	w, err := New(nil, VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.SetSharpness(2); err != errSharpnessConflictsVector {
+		t.Fatalf("unexpected error: %v != %v", err, errSharpnessConflictsVector)
+	}
+}
+
+// TestWaveformValidateRevert verifies that a conflict between options applied
+// in separate calls to SetOptions is resolved by reverting one of them to its
+// default value.
+func TestWaveformValidateRevert(t *testing.T) {
+	w, err := New(nil, Scale(1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.SetHeight(20); err != errHeightConflictsScale {
+		t.Fatalf("unexpected error: %v != %v", err, errHeightConflictsScale)
+	}
+	if err := w.SetScale(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetHeight(20); err != nil {
+		t.Fatalf("unexpected error after reverting scale: %v", err)
+	}
+
+	if err := w.SetSampleFunction(AverageF64Samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetChunkFunction(chunkRMS); err != errChunkFunctionConflictsSampleFunction {
+		t.Fatalf("unexpected error: %v != %v", err, errChunkFunctionConflictsSampleFunction)
+	}
+	if err := w.SetOptions(SampleFunction(RMSF64Samples), ChunkFunction(chunkRMS)); err != nil {
+		t.Fatalf("unexpected error after reverting sample function: %v", err)
+	}
+}
+
+// TestWaveformSetOptionsUnchangedOnError verifies that a call to SetOptions
+// which returns an error leaves the receiving Waveform unchanged, so that it
+// can continue to be used.
+func TestWaveformSetOptionsUnchangedOnError(t *testing.T) {
+	w, err := New(nil, VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options before the failing option are not applied either
+	if err := w.SetOptions(Scale(4, 1), Sharpness(2)); err != errSharpnessConflictsVector {
+		t.Fatalf("unexpected error: %v != %v", err, errSharpnessConflictsVector)
+	}
+	if w.style.sharpness != 1 || w.style.scaleX != 1 || w.explicit["sharpness"] {
+		t.Fatalf("failed SetOptions modified Waveform: sharpness %d, scale %d, explicit %v",
+			w.style.sharpness, w.style.scaleX, w.explicit)
+	}
+
+	// Later, compatible options are applied as usual
+	if err := w.SetScale(2, 1); err != nil {
+		t.Fatalf("unexpected error after failed SetOptions: %v", err)
+	}
+	if w.style.scaleX != 2 {
+		t.Fatalf("unexpected X scale: %v != %v", w.style.scaleX, 2)
+	}
+
+	// Options which fail before validation are not applied either
+	if err := w.SetOptions(Scale(3, 1), Scale(0, 1)); err != errScaleXZero {
+		t.Fatalf("unexpected error: %v != %v", err, errScaleXZero)
+	}
+	if w.style.scaleX != 2 {
+		t.Fatalf("failed SetOptions modified X scale: %v != %v", w.style.scaleX, 2)
+	}
+}
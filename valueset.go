@@ -0,0 +1,120 @@
+package waveform
+
+import (
+	"math"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// ValueSet is a set of values computed from an input audio stream, along with
+// information about how each value was computed, and about the stream itself.
+type ValueSet struct {
+	// Values is the slice of values computed by a SampleReduceFunc, one for
+	// each window of audio read from the stream.
+	Values []float64
+
+	// Counts is the number of audio samples which were actually read and
+	// reduced to produce each value in Values.  The final window of a stream
+	// is typically shorter than all others.
+	Counts []int
+
+	// Durations is the exact duration of audio which was read and reduced to
+	// produce each value in Values.  When the sample rate of a stream is not
+	// evenly divisible by the resolution, window lengths vary by one sample
+	// frame so that windows remain aligned with time, and Durations reflects
+	// that variation.
+	//
+	// When the Overlap option is in use, each value is also reduced from the
+	// audio of neighboring windows, but Counts and Durations describe only
+	// the value's own window, so that values remain aligned with time.
+	Durations []time.Duration
+
+	// SampleRate and Channels are the sample rate and number of channels of
+	// the input audio stream, before any channels were downmixed.
+	SampleRate int
+	Channels   int
+
+	// Resolution is the number of values computed for each second of audio.
+	Resolution uint
+
+	// channels, if set, are the values computed for each channel of the
+	// stream by ComputeChannelValueSets
+	channels []*ValueSet
+}
+
+// Duration returns the total duration of audio which was read and reduced to
+// produce the values in the ValueSet.
+func (vs *ValueSet) Duration() time.Duration {
+	var d time.Duration
+	for _, v := range vs.Durations {
+		d += v
+	}
+
+	return d
+}
+
+// Max returns the largest value in the ValueSet, such as for normalizing
+// values before they are drawn.  NaN values are ignored, and 0 is returned if
+// the ValueSet contains no other values.
+func (vs *ValueSet) Max() float64 {
+	max := math.Inf(-1)
+	for _, v := range vs.Values {
+		if v > max {
+			max = v
+		}
+	}
+
+	if math.IsInf(max, -1) {
+		return 0
+	}
+
+	return max
+}
+
+// setStream records information about the input audio stream, with the input
+// configuration, from which the ValueSet was computed at the input resolution.
+func (vs *ValueSet) setStream(config audio.Config, resolution uint) {
+	vs.SampleRate = config.SampleRate
+	vs.Channels = config.Channels
+	vs.Resolution = resolution
+}
+
+// append adds a computed value to the ValueSet, along with the number of audio
+// samples used to compute it.
+func (vs *ValueSet) append(config audio.Config, value float64, count int) {
+	vs.Values = append(vs.Values, value)
+	vs.Counts = append(vs.Counts, count)
+	vs.Durations = append(vs.Durations, sampleDuration(config, count))
+}
+
+// reserve grows the capacity of the ValueSet so that it can hold at least n
+// values without further allocation.
+func (vs *ValueSet) reserve(n int) {
+	if n <= cap(vs.Values) {
+		return
+	}
+
+	values := make([]float64, len(vs.Values), n)
+	copy(values, vs.Values)
+	vs.Values = values
+
+	counts := make([]int, len(vs.Counts), n)
+	copy(counts, vs.Counts)
+	vs.Counts = counts
+
+	durations := make([]time.Duration, len(vs.Durations), n)
+	copy(durations, vs.Durations)
+	vs.Durations = durations
+}
+
+// sampleDuration returns the duration of a number of interleaved audio samples
+// with the input audio configuration.
+func sampleDuration(config audio.Config, count int) time.Duration {
+	if config.SampleRate <= 0 || config.Channels <= 0 {
+		return 0
+	}
+
+	frames := int64(count / config.Channels)
+	return time.Duration(frames * int64(time.Second) / int64(config.SampleRate))
+}
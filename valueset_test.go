@@ -0,0 +1,76 @@
+package waveform
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestWaveformComputeValueSetStream verifies that ComputeValueSet records
+// information about the input audio stream, before channels are downmixed.
+func TestWaveformComputeValueSetStream(t *testing.T) {
+	// Stereo audio at 4Hz, read at 2 windows per second
+	wav := makeWAV(4, 2, []int16{
+		16384, 0, 0, 0,
+		32767, 0, 0, 0,
+		0, 0,
+	})
+
+	w, err := New(bytes.NewReader(wav), Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vs.SampleRate != 4 || vs.Channels != 2 || vs.Resolution != 2 {
+		t.Fatalf("unexpected stream information: %d Hz, %d channels, resolution %d",
+			vs.SampleRate, vs.Channels, vs.Resolution)
+	}
+
+	if want := 1250 * time.Millisecond; vs.Duration() != want {
+		t.Fatalf("unexpected duration: %v != %v", vs.Duration(), want)
+	}
+
+	w, err = New(bytes.NewReader(wav), Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets, err := w.ComputeChannelValueSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cvs := range sets {
+		if cvs.SampleRate != 4 || cvs.Channels != 2 || cvs.Resolution != 2 {
+			t.Fatalf("[%d] unexpected channel stream information: %d Hz, %d channels, resolution %d",
+				i, cvs.SampleRate, cvs.Channels, cvs.Resolution)
+		}
+	}
+}
+
+// TestValueSetMax verifies that ValueSet.Max finds the largest value, ignoring
+// NaN values.
+func TestValueSetMax(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{math.NaN()}, 0},
+		{[]float64{0.25, 0.75, 0.5}, 0.75},
+		{[]float64{math.NaN(), 0.5}, 0.5},
+		{[]float64{-2, -1}, -1},
+	}
+
+	for i, test := range tests {
+		vs := &ValueSet{Values: test.values}
+		if got := vs.Max(); got != test.want {
+			t.Fatalf("[%02d] unexpected maximum: %v != %v", i, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/vector"
+)
+
+// generateVectorImage takes a slice of computed values and generates a waveform
+// image from the input, using a 2D vector rasterizer.
+//
+// Rather than setting individual pixels, the waveform envelope is described as
+// a path of smooth curves which is filled and, optionally, stroked.  The
+// rasterizer anti-aliases the edges of the resulting shapes.
+//
+// If edges is not nil, values are positioned using the input edges, as
+// computed by timeEdges.
+func (s *RenderStyle) generateVectorImage(computed []float64, edges []int) image.Image {
+	// Calculate maximum n, x, y, as in generateImage
+	maxN := len(computed)
+	maxX := maxN * int(s.scaleX)
+	if edges != nil {
+		maxX = edges[len(edges)-1]
+	}
+	maxY := s.imageHeight()
+
+	// Create output, rectangular image
+	img := s.newImage(image.Rect(0, 0, maxX, maxY))
+	s.drawVector(img, computed, edges)
+
+	return img
+}
+
+// drawVector draws a waveform image from a slice of computed values onto img,
+// which must have the bounds of the complete image, using a 2D vector
+// rasterizer.  If edges is not nil, values are positioned using the input
+// edges, as computed by timeEdges.
+func (s *RenderStyle) drawVector(img draw.Image, computed []float64, edges []int) {
+	bounds := img.Bounds()
+	maxN, maxX, maxY := len(computed), bounds.Dx(), bounds.Dy()
+
+	// Draw background color over the entire image
+	draw.Draw(img, bounds, s.colorFuncImage(s.bgColorFn, maxN, maxX, maxY), image.Point{}, draw.Src)
+
+	// Nothing to draw for an empty waveform
+	if maxN == 0 {
+		return
+	}
+
+	// The envelope is drawn as a whole, so each column is reported once it is
+	// complete
+	if s.columnFn != nil {
+		defer func() {
+			for n, v := range computed {
+				x := n * int(s.scaleX)
+				if edges != nil {
+					x = edges[n]
+				}
+
+				s.columnFn(n, x, v)
+			}
+		}()
+	}
+
+	// Trace and fill the envelope of the waveform
+	top, base := s.envelope(computed, edges, maxX, maxY)
+	axis := s.mirrorAxis(maxY)
+	z := vector.NewRasterizer(maxX, maxY)
+	s.tracePath(z, top, axis, base)
+	s.rasterize(img, z, s.colorFuncImage(s.fgColorFn, maxN, maxX, maxY))
+
+	// Stroke the outline of the envelope, if requested
+	if s.outlineFn != nil && s.outlineWidth > 0 {
+		z.Reset(maxX, maxY)
+		strokeEnvelope(z, top, axis, float32(s.outlineWidth))
+		s.rasterize(img, z, s.colorFuncImage(s.outlineFn, maxN, maxX, maxY))
+	}
+}
+
+// rasterize draws the path of a vector rasterizer onto img using the colors of
+// src.  If linear light blending is enabled, anti-aliased edges are blended in
+// linear light.
+func (s *RenderStyle) rasterize(img draw.Image, z *vector.Rasterizer, src image.Image) {
+	bounds := img.Bounds()
+	if !s.linear {
+		z.Draw(img, bounds, src, image.Point{})
+		return
+	}
+
+	mask := image.NewAlpha(bounds)
+	z.Draw(mask, bounds, image.Opaque, image.Point{})
+	drawMaskLinear(img, src, mask)
+}
+
+// envelope computes the points which make up the top half of a waveform's
+// envelope, from the left edge of the image to the right edge, and the Y
+// coordinate of the baseline from which they are measured.  Each computed
+// value is placed at the center of its column on the X-axis, using edges if
+// they are not nil.  The bottom half of the envelope is a reflection of the top
+// half about the axis returned by mirrorAxis.
+func (s *RenderStyle) envelope(computed []float64, edges []int, maxX int, maxY int) ([]point, float32) {
+	// Calculate scaling factor, based upon maximum value computed by a SampleReduceFunc
+	imgScale := s.scaleFactor(computed) * (1 - s.headroom)
+
+	// The top half begins at the center of the image, or above the center
+	// using the same offset as the raster renderer when a mirror gap is in use
+	base := float64(maxY) / 2
+	if s.mirrorGap > 0 {
+		up, _ := s.mirrorOffsets(maxY)
+		base = float64(maxY/2 - up)
+	}
+	limit := math.Max(base-float64(maxY-s.usableHeight(maxY))/2, 0)
+	colX := float64(s.scaleX)
+
+	// Begin and end envelope at the edges of the image, using the heights of
+	// the first and last values
+	points := make([]point, 0, len(computed)+2)
+	for n, c := range computed {
+		// Scale computed value using the height of the image and scaling factor,
+		// but never draw below the minimum height or within the headroom reserved
+		// at the edges of the image
+		h := c * float64(maxY) * imgScale
+		if s.levels > 0 {
+			h = quantizeHeight(h, float64(s.usableHeight(maxY)), int(s.levels))
+		}
+		h = math.Min(math.Max(h, float64(s.minHeight))/2, limit)
+		y := float32(base - h)
+
+		if n == 0 {
+			points = append(points, point{0, y})
+		}
+
+		x := (float64(n) + 0.5) * colX
+		if edges != nil {
+			x = float64(edges[n]+edges[n+1]) / 2
+		}
+		points = append(points, point{float32(x), y})
+
+		if n == len(computed)-1 {
+			points = append(points, point{float32(maxX), y})
+		}
+	}
+
+	return points, float32(base)
+}
+
+// mirrorAxis returns the Y coordinate about which the top half of a waveform's
+// envelope is reflected to produce the bottom half.  This is twice the center
+// of the image, shifted to produce the same mirror gap as the raster renderer.
+func (s *RenderStyle) mirrorAxis(maxY int) float32 {
+	up, down := s.mirrorOffsets(maxY)
+	return float32(maxY + down - up)
+}
+
+// pather is a destination for the path of a waveform's envelope.  It is
+// implemented by vector.Rasterizer, and by the types which produce the
+// envelope in other forms, such as SVG path data.
+type pather interface {
+	MoveTo(x float32, y float32)
+	LineTo(x float32, y float32)
+	QuadTo(bx float32, by float32, cx float32, cy float32)
+	ClosePath()
+}
+
+// tracePath adds the closed paths which make up a waveform's envelope to z,
+// using the top half of the envelope, its axis of reflection, and its
+// baseline, as computed by envelope and mirrorAxis.
+func (s *RenderStyle) tracePath(z pather, top []point, axis float32, base float32) {
+	if s.mirrorGap == 0 {
+		traceEnvelope(z, top, axis)
+		return
+	}
+
+	traceHalves(z, top, axis, base)
+}
+
+// traceEnvelope adds a closed path to a pather, which traces the top half of
+// a waveform's envelope from left to right, and its reflection across the
+// bottom half of the image from right to left.
+func traceEnvelope(z pather, top []point, maxY float32) {
+	bottom := reflect(top, maxY)
+
+	z.MoveTo(top[0].X, top[0].Y)
+	curveThrough(z, top)
+	z.LineTo(bottom[len(bottom)-1].X, bottom[len(bottom)-1].Y)
+	curveThrough(z, reverse(bottom))
+	z.ClosePath()
+}
+
+// traceHalves adds two closed paths to a pather, which trace the top half of
+// a waveform's envelope down to the input baseline, and its reflection about
+// the input axis up to the reflected baseline, leaving a gap between the two
+// halves.
+func traceHalves(z pather, top []point, axis float32, base float32) {
+	for _, half := range []struct {
+		points []point
+		base   float32
+	}{
+		{points: top, base: base},
+		{points: reflect(top, axis), base: axis - base},
+	} {
+		first, last := half.points[0], half.points[len(half.points)-1]
+
+		z.MoveTo(first.X, half.base)
+		z.LineTo(first.X, first.Y)
+		curveThrough(z, half.points)
+		z.LineTo(last.X, half.base)
+		z.ClosePath()
+	}
+}
+
+// strokeEnvelope adds shapes to a vector.Rasterizer, which stroke the top
+// and bottom halves of a waveform's envelope using a line of the input width.
+func strokeEnvelope(z *vector.Rasterizer, top []point, maxY float32, width float32) {
+	for _, line := range [][]point{flatten(top), flatten(reflect(top, maxY))} {
+		for i := 1; i < len(line); i++ {
+			strokeSegment(z, line[i-1], line[i], width)
+		}
+
+		// Round each join between segments
+		for _, p := range line {
+			strokeJoin(z, p, width)
+		}
+	}
+}
+
+// point is a point in a vector path.
+type point struct {
+	X float32
+	Y float32
+}
+
+// midpoint returns the point halfway between points a and b.
+func midpoint(a point, b point) point {
+	return point{(a.X + b.X) / 2, (a.Y + b.Y) / 2}
+}
+
+// curveThrough adds a series of quadratic curves to a pather, beginning at
+// the first input point.  Each intermediate point is used as a
+// control point, and the curves pass through the midpoints between them,
+// producing a smooth path without sharp corners.
+func curveThrough(z pather, points []point) {
+	for i := 1; i < len(points)-1; i++ {
+		m := midpoint(points[i], points[i+1])
+		z.QuadTo(points[i].X, points[i].Y, m.X, m.Y)
+	}
+
+	last := points[len(points)-1]
+	z.LineTo(last.X, last.Y)
+}
+
+// curveSteps is the number of line segments used to approximate each
+// quadratic curve when a path is flattened.
+const curveSteps = 8
+
+// flatten approximates the path produced by curveThrough as a series of
+// points joined by straight lines.
+func flatten(points []point) []point {
+	out := []point{points[0]}
+	pen := points[0]
+	for i := 1; i < len(points)-1; i++ {
+		b := points[i]
+		c := midpoint(points[i], points[i+1])
+
+		out = appendQuad(out, pen, b, c)
+		pen = c
+	}
+
+	return append(out, points[len(points)-1])
+}
+
+// appendQuad approximates the quadratic curve from point a to point c, with
+// control point b, as curveSteps line segments, and appends the end point of
+// each segment to out.
+func appendQuad(out []point, a point, b point, c point) []point {
+	for s := 1; s <= curveSteps; s++ {
+		t := float32(s) / curveSteps
+		u := 1 - t
+		out = append(out, point{
+			X: u*u*a.X + 2*u*t*b.X + t*t*c.X,
+			Y: u*u*a.Y + 2*u*t*b.Y + t*t*c.Y,
+		})
+	}
+
+	return out
+}
+
+// strokeSegment adds a rectangle to a vector.Rasterizer which covers the line
+// segment between points a and b, using a line of the input width.
+func strokeSegment(z *vector.Rasterizer, a point, b point, width float32) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return
+	}
+
+	// Normal vector to the segment, half the width of the line
+	nx, ny := -dy/length*width/2, dx/length*width/2
+
+	z.MoveTo(a.X+nx, a.Y+ny)
+	z.LineTo(b.X+nx, b.Y+ny)
+	z.LineTo(b.X-nx, b.Y-ny)
+	z.LineTo(a.X-nx, a.Y-ny)
+	z.ClosePath()
+}
+
+// strokeJoin adds an octagon to a vector.Rasterizer, centered on point p and
+// approximating a circle with a diameter of the input width.
+//
+// The octagon is wound in the same direction as the rectangles added by
+// strokeSegment, so that overlapping shapes do not cancel each other out.
+func strokeJoin(z *vector.Rasterizer, p point, width float32) {
+	const sides = 8
+
+	r := float64(width) / 2
+	for i := 0; i < sides; i++ {
+		theta := -2 * math.Pi * float64(i) / sides
+		x := p.X + float32(r*math.Cos(theta))
+		y := p.Y + float32(r*math.Sin(theta))
+
+		if i == 0 {
+			z.MoveTo(x, y)
+			continue
+		}
+
+		z.LineTo(x, y)
+	}
+	z.ClosePath()
+}
+
+// reflect reflects a series of points about the horizontal center of an image
+// with height maxY.
+func reflect(points []point, maxY float32) []point {
+	out := make([]point, len(points))
+	for i, p := range points {
+		out[i] = point{p.X, maxY - p.Y}
+	}
+
+	return out
+}
+
+// reverse returns a reversed copy of a series of points.
+func reverse(points []point) []point {
+	out := make([]point, len(points))
+	for i, p := range points {
+		out[len(points)-1-i] = p
+	}
+
+	return out
+}
+
+// colorFuncImage generates an image.Image which produces the color of each pixel
+// using the input ColorFunc, so that a ColorFunc may be used as the source image
+// when drawing with a vector rasterizer.
+func (s *RenderStyle) colorFuncImage(fn ColorFunc, maxN int, maxX int, maxY int) image.Image {
+	return &colorFuncImage{
+		fn:     fn,
+		scaleX: int(s.scaleX),
+		maxN:   maxN,
+		maxX:   maxX,
+		maxY:   maxY,
+	}
+}
+
+// colorFuncImage is an image.Image which produces the color at each coordinate
+// using a ColorFunc.
+type colorFuncImage struct {
+	fn     ColorFunc
+	scaleX int
+	maxN   int
+	maxX   int
+	maxY   int
+}
+
+// ColorModel implements image.Image.
+func (c *colorFuncImage) ColorModel() color.Model { return color.RGBAModel }
+
+// Bounds implements image.Image.
+func (c *colorFuncImage) Bounds() image.Rectangle { return image.Rect(0, 0, c.maxX, c.maxY) }
+
+// At implements image.Image.
+func (c *colorFuncImage) At(x int, y int) color.Color {
+	return c.fn(x/c.scaleX, x, y, c.maxN, c.maxX, c.maxY)
+}
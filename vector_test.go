@@ -0,0 +1,93 @@
+package waveform
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestWaveformDrawVectorRenderer verifies that the vector renderer draws
+// foreground color at the center of a waveform, and background color at
+// its edges.
+func TestWaveformDrawVectorRenderer(t *testing.T) {
+	w, err := New(nil,
+		VectorRenderer(),
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(SolidColor(black)),
+		Scale(4, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.10, 0.10, 0.10})
+	bounds := img.Bounds()
+	if x, y := bounds.Max.X, bounds.Max.Y; x != 12 || y != imgYDefault {
+		t.Fatalf("unexpected image size: %dx%d", x, y)
+	}
+
+	if c := color.RGBAModel.Convert(img.At(6, bounds.Max.Y/2)); c != black {
+		t.Fatalf("unexpected center color: %v != %v", c, black)
+	}
+	if c := color.RGBAModel.Convert(img.At(6, 0)); c != white {
+		t.Fatalf("unexpected edge color: %v != %v", c, white)
+	}
+}
+
+// TestWaveformDrawVectorRendererOutline verifies that the vector renderer
+// strokes the outline of a waveform's envelope.
+func TestWaveformDrawVectorRendererOutline(t *testing.T) {
+	w, err := New(nil,
+		VectorRenderer(),
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(SolidColor(black)),
+		Outline(SolidColor(red), 4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Envelope is flat, so the outline lies on a known row: a value of 0.10
+	// is scaled to a half-height of 19.2 pixels
+	img := w.Draw([]float64{0.10, 0.10, 0.10, 0.10})
+	halfY := img.Bounds().Max.Y / 2
+	edge := halfY - 19
+
+	if c := color.RGBAModel.Convert(img.At(2, edge)); c != red {
+		t.Fatalf("unexpected outline color: %v != %v", c, red)
+	}
+	if c := color.RGBAModel.Convert(img.At(2, halfY)); c != black {
+		t.Fatalf("unexpected center color: %v != %v", c, black)
+	}
+}
+
+// TestWaveformDrawVectorRendererEmpty verifies that the vector renderer does
+// not panic when no values are drawn.
+func TestWaveformDrawVectorRendererEmpty(t *testing.T) {
+	w, err := New(nil, VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x := w.Draw(nil).Bounds().Max.X; x != 0 {
+		t.Fatalf("unexpected image width: %v != %v", x, 0)
+	}
+}
+
+// TestFlatten verifies that flatten begins and ends at the same points as
+// its input path.
+func TestFlatten(t *testing.T) {
+	points := []point{{0, 10}, {5, 2}, {10, 8}, {15, 4}}
+
+	out := flatten(points)
+	if out[0] != points[0] {
+		t.Fatalf("unexpected first point: %v != %v", out[0], points[0])
+	}
+	if last := out[len(out)-1]; last != points[len(points)-1] {
+		t.Fatalf("unexpected last point: %v != %v", last, points[len(points)-1])
+	}
+
+	// Two curves, plus first and last points
+	if l := 2*curveSteps + 2; len(out) != l {
+		t.Fatalf("unexpected flattened length: %v != %v", len(out), l)
+	}
+}
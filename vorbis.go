@@ -0,0 +1,92 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"azul3d.org/engine/audio"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+func init() {
+	// Register Ogg Vorbis decoder, since azul3d/engine/audio does not provide one
+	audio.RegisterFormat("ogg", "OggS", newVorbisDecoder)
+}
+
+// vorbisDecoder is an audio.Decoder which decodes Ogg Vorbis streams.
+type vorbisDecoder struct {
+	r      *oggvorbis.Reader
+	config audio.Config
+
+	// buf is reused to receive float32 samples from the Vorbis decoder
+	buf []float32
+}
+
+// oggPeekSize is the number of bytes inspected at the beginning of an Ogg
+// stream to determine which codec it contains.
+const oggPeekSize = 64
+
+// newVorbisDecoder creates an audio.Decoder from an Ogg Vorbis stream.
+//
+// Ogg is a container which may hold other codecs.  Ogg Opus streams cannot be
+// decoded, and produce audio.ErrFormat, as would any other unsupported format,
+// so that they may be decoded by the FallbackDecoder option instead.
+func newVorbisDecoder(r io.Reader) (audio.Decoder, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(oggPeekSize); bytes.Contains(head, []byte("OpusHead")) {
+		return nil, audio.ErrFormat
+	}
+
+	vr, err := oggvorbis.NewReader(br)
+	if err != nil {
+		return nil, vorbisError(err)
+	}
+
+	return &vorbisDecoder{
+		r: vr,
+		config: audio.Config{
+			SampleRate: vr.SampleRate(),
+			Channels:   vr.Channels(),
+		},
+	}, nil
+}
+
+// Config returns the audio configuration of the Ogg Vorbis stream.
+func (d *vorbisDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes interleaved audio samples from the Ogg Vorbis stream into b,
+// returning audio.EOS when the stream is exhausted.
+func (d *vorbisDecoder) Read(b audio.Slice) (int, error) {
+	if cap(d.buf) < b.Len() {
+		d.buf = make([]float32, b.Len())
+	}
+	buf := d.buf[:b.Len()]
+
+	n, err := d.r.Read(buf)
+	for i := 0; i < n; i++ {
+		b.Set(i, float64(buf[i]))
+	}
+
+	if err != nil {
+		return n, vorbisError(err)
+	}
+
+	return n, nil
+}
+
+// vorbisError converts errors from the Vorbis decoder into errors from the
+// audio package, so that they are handled in the same way as errors from
+// other decoders.
+func vorbisError(err error) error {
+	switch err {
+	case io.EOF:
+		return audio.EOS
+	case io.ErrUnexpectedEOF:
+		return audio.ErrUnexpectedEOS
+	default:
+		return audio.ErrInvalidData
+	}
+}
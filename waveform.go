@@ -2,10 +2,12 @@
 package waveform
 
 import (
+	"errors"
+	"fmt"
 	"image"
-	"image/color"
+	"image/draw"
 	"io"
-	"math"
+	"time"
 
 	"azul3d.org/engine/audio"
 
@@ -39,6 +41,15 @@ var (
 	ErrUnexpectedEOS = audio.ErrUnexpectedEOS
 )
 
+// ErrTooShort is returned when an input audio stream does not contain enough
+// audio samples to compute a single value.  This occurs when the stream
+// contains no audio samples at all, or when it is shorter than one window and
+// the PartialWindowDrop policy is in use.
+//
+// Streams which are shorter than one window, but not empty, produce a single
+// computed value under the PartialWindowTrim and PartialWindowPad policies.
+var ErrTooShort = errors.New("waveform: audio stream too short to compute a value")
+
 // Waveform is a struct which can be manipulated and used to generate
 // audio waveform images from an input audio stream.
 type Waveform struct {
@@ -47,15 +58,83 @@ type Waveform struct {
 	resolution uint
 	sampleFn   SampleReduceFunc
 
-	bgColorFn ColorFunc
-	fgColorFn ColorFunc
+	// chunkFn, if set, reduces each window along with its metadata, in place
+	// of sampleFn
+	chunkFn ChunkReduceFunc
+
+	// style contains all options which determine how values are drawn
+	style RenderStyle
+
+	partialWindow PartialWindowPolicy
+
+	// layout determines how samples delivered by the decoder are arranged
+	// among channels
+	layout SampleLayout
+
+	// offset and duration select the range of time which is read from the
+	// stream; a duration of 0 reads until the end of the stream
+	offset   time.Duration
+	duration time.Duration
+
+	// maxDuration, if set, is the maximum length of audio which may be
+	// decoded from the stream
+	maxDuration time.Duration
+
+	// downmix determines how channels are combined before values are
+	// computed, and downmixChannels is the number of channels retained by
+	// DownmixFirst
+	downmix         DownmixPolicy
+	downmixChannels uint
+
+	// downmixFn, if set, combines each sample frame into a single sample,
+	// in place of downmix
+	downmixFn DownmixFunc
+
+	// overlap is the number of hops spanned by each window, and windowFn, if
+	// set, weights the samples of each window before it is reduced
+	overlap  uint
+	windowFn WindowFunc
+
+	// downsampleAfter and downsampleStride, if set, skip all but every
+	// downsampleStride'th window once downsampleAfter of audio is read
+	downsampleAfter  time.Duration
+	downsampleStride uint
+
+	// workers, if set, is the number of inputs processed concurrently by
+	// GenerateAll
+	workers uint
+
+	checkpointW     io.Writer
+	checkpointEvery uint
 
-	scaleX uint
-	scaleY uint
+	progressFn ProgressFunc
 
-	sharpness uint
+	// valueFn, if set, receives values as they are computed, in place of
+	// storing them
+	valueFn ValueFunc
 
-	scaleClipping bool
+	// perChannel, if set, computes a separate series of values for each
+	// channel, in place of a single series
+	perChannel bool
+
+	analyzers []Analyzer
+
+	// levels receive each window of audio samples after any channels are
+	// combined, so that ComputePyramid can reduce them at other resolutions
+	levels []Analyzer
+
+	// fallback decodes formats which are not supported by this package
+	fallback *ExternalDecoder
+
+	// samples, if set, are read in place of the input stream
+	samples *sampleSource
+
+	// decoder, if set, is read in place of the input stream
+	decoder audio.Decoder
+
+	// explicit is the set of options which were explicitly applied, used to
+	// detect incompatible combinations of options
+	explicit map[string]bool
 }
 
 // Generate immediately opens and reads an input audio stream, computes
@@ -64,15 +143,11 @@ type Waveform struct {
 //
 // Generate is equivalent to calling New, followed by the Compute and Draw
 // methods of a Waveform struct.  In general, Generate should only be used
-// for one-time waveform image generation.
+// for one-time waveform image generation.  Use GenerateTimed to also measure
+// how long generation took.
 func Generate(r io.Reader, options ...OptionsFunc) (image.Image, error) {
-	w, err := New(r, options...)
-	if err != nil {
-		return nil, err
-	}
-
-	values, err := w.Compute()
-	return w.Draw(values), err
+	img, _, _, err := generate(r, options)
+	return img, err
 }
 
 // New generates a new Waveform struct, applying any input OptionsFunc
@@ -89,20 +164,25 @@ func New(r io.Reader, options ...OptionsFunc) (*Waveform, error) {
 		// Use RMSF64Samples as a SampleReduceFunc
 		sampleFn: RMSF64Samples,
 
-		// Generate solid, black background color with solid, white
-		// foreground color waveform using ColorFunc
-		bgColorFn: SolidColor(color.White),
-		fgColorFn: SolidColor(color.Black),
+		// Use default drawing options
+		style: defaultStyle(),
 
-		// No scaling
-		scaleX: 1,
-		scaleY: 1,
+		// Reduce only samples read in partial windows
+		partialWindow: PartialWindowTrim,
 
-		// Normal sharpness
-		sharpness: 1,
+		// Detect the layout of samples from the decoder
+		layout: SampleLayoutAuto,
 
-		// Do not scale clipping values
-		scaleClipping: false,
+		// Reduce the samples of all channels together
+		downmix:         DownmixNone,
+		downmixChannels: downmixChannelsDefault,
+
+		// Compute each value from a single window of audio
+		overlap: 1,
+
+		// Do not snapshot computation state
+		checkpointW:     nil,
+		checkpointEvery: 0,
 	}
 
 	// Apply any input OptionsFunc on return
@@ -115,7 +195,23 @@ func New(r io.Reader, options ...OptionsFunc) (*Waveform, error) {
 // used for subsequent waveform generations.  Its return value can be used with Draw to
 // generate and customize multiple waveform images from a single stream.
 func (w *Waveform) Compute() ([]float64, error) {
-	return w.readAndComputeSamples()
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return vs.Values, nil
+}
+
+// ComputeValueSet creates a ValueSet, computed using an input function.
+//
+// ComputeValueSet is equivalent to Compute, but also returns information about
+// how each value was computed, such as the number of audio samples which were
+// read to produce it, and about the input audio stream, such as its sample
+// rate and number of channels, so that it need not be read again to determine
+// them.
+func (w *Waveform) ComputeValueSet() (*ValueSet, error) {
+	return w.readAndComputeSamples(nil)
 }
 
 // Draw creates a new image.Image from a slice of float64 values.
@@ -123,14 +219,68 @@ func (w *Waveform) Compute() ([]float64, error) {
 // Draw is typically used after a waveform has been computed one time, and a slice
 // of computed values was returned from the first computation.  Subsequent calls to
 // Draw may be used to customize a waveform using the same input values.
-func (w *Waveform) Draw(values []float64) image.Image {
-	return w.generateImage(values)
+//
+// Zero or more OptionsFunc may be specified to override options for a single
+// call to Draw, such as colors or scale, without modifying the receiving
+// Waveform struct.  This allows a single set of computed values to be drawn in
+// many styles, even concurrently.
+//
+// Invalid values, such as NaN, are handled according to the InvalidValuePolicy
+// set by the InvalidValues option.  If the InvalidValueError policy is in use and
+// an invalid value is encountered, or an override is invalid, Draw returns nil;
+// use DrawChecked to retrieve the error.
+func (w *Waveform) Draw(values []float64, options ...OptionsFunc) image.Image {
+	img, _ := w.DrawChecked(values, options...)
+	return img
+}
+
+// DrawChecked creates a new image.Image from a slice of float64 values, in the
+// same way as Draw.
+//
+// If an override OptionsFunc is invalid, its error is returned.  If the
+// InvalidValueError policy is in use and an invalid value is encountered,
+// DrawChecked returns a *ValueError which describes it.
+func (w *Waveform) DrawChecked(values []float64, options ...OptionsFunc) (image.Image, error) {
+	ow, err := w.withOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ow.Style().DrawChecked(values)
+}
+
+// clone returns a copy of the receiving Waveform struct, which may have options
+// applied without affecting the original.
+func (w *Waveform) clone() *Waveform {
+	cw := *w
+	cw.explicit = copyExplicit(w.explicit)
+
+	return &cw
+}
+
+// withOptions returns the receiving Waveform struct if no options are
+// specified, or a copy of it with the options applied, so that per-call
+// overrides never modify the receiver.
+func (w *Waveform) withOptions(options ...OptionsFunc) (*Waveform, error) {
+	if len(options) == 0 {
+		return w, nil
+	}
+
+	ow := w.clone()
+	if err := ow.SetOptions(options...); err != nil {
+		return nil, err
+	}
+
+	return ow, nil
 }
 
 // readAndComputeSamples opens the input audio stream, computes samples according
-// to an input function, and returns a slice of computed values and any errors
+// to an input function, and returns a set of computed values and any errors
 // which occurred during the computation.
-func (w *Waveform) readAndComputeSamples() ([]float64, error) {
+//
+// If a Checkpoint is specified, computation resumes from the window at which the
+// Checkpoint was taken.
+func (w *Waveform) readAndComputeSamples(cp *Checkpoint) (*ValueSet, error) {
 	// Validate struct members
 	// These checks are also done when applying options, but verifying them here
 	// will prevent a runtime panic if called on an empty Waveform instance.
@@ -141,167 +291,484 @@ func (w *Waveform) readAndComputeSamples() ([]float64, error) {
 		return nil, errResolutionZero
 	}
 
+	// Checkpoints record only a single series of values, computed from
+	// independent windows
+	if w.checkpointW != nil || cp != nil {
+		if err := w.checkpointConflict(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Seek past the audio which precedes an offset or Checkpoint, if possible
+	r, seeked, err := w.seekStream(cp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track the bytes read from the input stream, so that progress can be
+	// reported and the number of values can be estimated
+	probe := newStreamProbe(r)
+
 	// Open audio decoder on input stream
-	decoder, _, err := audio.NewDecoder(w.r)
+	decoder, err := w.openDecoder(probe)
 	if err != nil {
-		// Unknown format
-		if err == audio.ErrFormat {
-			return nil, ErrFormat
+		return nil, err
+	}
+	defer decoder.Close()
+	probe.opened()
+
+	// vs stores values computed by a SampleReduceFunc from each slice of audio
+	// samples, along with the number of samples used to compute each value
+	vs := new(ValueSet)
+
+	// Reject malformed streams which could never produce a value, or which
+	// would require an unreasonably large window buffer
+	config := decoder.Config()
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+	vs.setStream(config, w.resolution)
+
+	// Every window must contain at least one sample frame
+	if uint(config.SampleRate) < w.resolution {
+		return nil, errResolutionTooHigh
+	}
+
+	// skipped is the number of samples which were skipped by seeking the
+	// input stream, rather than by decoding them
+	var skipped int64
+	if seeked {
+		skipped = w.startFrame(config.SampleRate, cp) * int64(config.Channels)
+	}
+
+	// Rearrange planar audio into interleaved sample frames, stop decoding
+	// streams which are too long, and read only the requested range of time
+	// from the stream, if any
+	var reader audio.Reader = decoder
+	if isPlanar(w.layout, decoder) {
+		reader = newPlanarReader(reader, config)
+	}
+	if w.maxDuration > 0 {
+		mr := newMaxReader(reader, config, w.maxDuration)
+		mr.remain -= skipped
+		reader = mr
+	}
+	if w.offset > 0 || w.duration > 0 {
+		rr := newRangeReader(reader, config, w.offset, w.duration)
+		rr.advance(skipped)
+		reader = rr
+	}
+
+	// Combine channels before windows are read, if requested.  All further
+	// computation uses the configuration of the downmixed audio, except for
+	// analyzers, which receive the audio before it was downmixed.
+	var dr *downmixReader
+	sourceConfig := config
+	switch {
+	case w.downmixFn != nil:
+		fn := w.downmixFn
+		dr, config = newDownmixReader(reader, config, 1, func(frame []float64, out []float64) {
+			out[0] = fn(frame)
+		})
+	default:
+		if m := downmixMatrix(w.downmix, config.Channels, w.downmixChannels); m != nil {
+			dr, config = newDownmixReader(reader, config, len(m), matrixMix(m))
 		}
+	}
+	if dr != nil {
+		dr.retain = len(w.analyzers) > 0
+		reader = dr
+	}
 
-		// Invalid data
-		if err == audio.ErrInvalidData {
-			return nil, ErrInvalidData
+	// buf is a slice of float64 audio samples, used to store decoded values.
+	// It is large enough to hold the longest window at the current resolution.
+	frames := (uint(config.SampleRate) + w.resolution - 1) / w.resolution
+	buf := make(audio.Float64, frames*uint(config.Channels))
+
+	// window is the number of windows read from the stream
+	var window int
+
+	// streamed is the number of values delivered to a ValueFunc, rather than
+	// stored in vs
+	var streamed int
+
+	// Skip any windows which were computed before a checkpoint was taken, by
+	// decoding them if the input stream could not be seeked past them
+	if cp != nil {
+		if err := cp.validate(config, w.resolution); err != nil {
+			return nil, err
 		}
 
-		// Unexpected end-of-stream
-		if err == audio.ErrUnexpectedEOS {
-			return nil, ErrUnexpectedEOS
+		if seeked {
+			window = cp.Window
 		}
+		for ; window < cp.Window; window++ {
+			samples := buf[:windowSize(config, w.resolution, window)]
+			if _, err := readWindow(reader, samples); err != nil {
+				if err == audio.EOS {
+					return nil, errCheckpointMismatch
+				}
 
-		// All other errors
-		return nil, err
+				return nil, err
+			}
+		}
+
+		for i := range cp.Values {
+			vs.append(config, cp.Values[i], cp.Counts[i])
+		}
 	}
 
-	// computed is a slice of computed values by a SampleReduceFunc, from each
-	// slice of audio samples
-	var computed []float64
+	// checkpointed is the number of values which were written to previous
+	// checkpoints.  The first checkpoint written by a resumed computation
+	// includes the values read from the Checkpoint, so that it can be read
+	// without the checkpoints which preceded it.
+	var checkpointed int
+
+	// emit delivers a value computed from n samples to a ValueFunc, or stores
+	// it in vs
+	emit := func(value float64, n int) error {
+		if w.valueFn != nil {
+			// Deliver values as they are computed, rather than storing them
+			if err := w.valueFn(streamed, value, sampleDuration(config, n)); err != nil {
+				return err
+			}
+			streamed++
+			return nil
+		}
 
-	// Track the current computed value
-	var value float64
+		// Reserve space for the estimated number of values, rather than
+		// growing the ValueSet repeatedly for long streams
+		if len(vs.Values) == cap(vs.Values) {
+			vs.reserve(probe.estimate(window))
+		}
+
+		vs.append(config, value, n)
+		return nil
+	}
+
+	// last is the value of the last window which was reduced, which is
+	// repeated for windows which are skipped by DownsampleAfter
+	var last float64
+	if len(vs.Values) > 0 {
+		last = vs.Values[len(vs.Values)-1]
+	}
+
+	// Compute values from overlapping or weighted windows, or for each
+	// channel, if requested
+	var (
+		wd    *windower
+		split *channelSplitter
+	)
+	switch {
+	case w.perChannel:
+		split = w.newChannelSplitter(config)
+	case w.overlap > 1 || w.windowFn != nil:
+		wd = w.newWindower(config)
+	}
 
-	// samples is a slice of float64 audio samples, used to store decoded values
-	config := decoder.Config()
-	samples := make(audio.Float64, uint(config.SampleRate*config.Channels)/w.resolution)
 	for {
-		// Decode at specified resolution from options
+		// Decode a full window at specified resolution from options
 		// On any error other than end-of-stream, return
-		_, err := decoder.Read(samples)
+		samples := buf[:windowSize(config, w.resolution, window)]
+		if dr != nil {
+			dr.source = dr.source[:0]
+		}
+		n, err := readWindow(reader, samples)
 		if err != nil && err != audio.EOS {
 			return nil, err
 		}
 
-		// Apply SampleReduceFunc over float64 audio samples
-		value = w.sampleFn(samples)
+		// Pass samples to any analyzers before they are reduced, and before
+		// they were downmixed
+		index := window
+		if n > 0 {
+			sourceSamples := samples[:n]
+			if dr != nil {
+				sourceSamples = dr.source
+			}
 
-		// Store computed value
-		computed = append(computed, value)
+			if err := w.analyze(w.analyzers, sourceConfig, index, sourceSamples); err != nil {
+				return nil, err
+			}
+			if err := w.analyze(w.levels, config, index, samples[:n]); err != nil {
+				return nil, err
+			}
+		}
+		window++
+
+		// Apply SampleReduceFunc over float64 audio samples.  Only the samples
+		// which were actually read are considered, so a partial window is never
+		// skewed by stale samples from the previous window.  Overlapping
+		// windows are reduced once the audio after each window's center has
+		// been read.
+		if split != nil {
+			if n > 0 {
+				if err := split.push(index, samples, n); err != nil {
+					return nil, err
+				}
+			}
+		} else if wd != nil {
+			if n > 0 {
+				if err := wd.push(index, samples, n, emit); err != nil {
+					return nil, err
+				}
+			}
+		} else if value, ok := w.reduceOrHold(config, index, samples, n, &last); ok {
+			if err := emit(value, n); err != nil {
+				return nil, err
+			}
+		}
 
-		// On end of stream, stop reading values
+		// On end of stream, stop reading values.  An empty read at the end of
+		// the stream does not count as a window.
 		if err == audio.EOS {
+			if n == 0 {
+				window--
+			}
+			if wd != nil {
+				if err := wd.flush(emit); err != nil {
+					return nil, err
+				}
+			}
+			if split != nil {
+				if err := split.flush(); err != nil {
+					return nil, err
+				}
+			}
 			break
 		}
+
+		if w.progressFn != nil {
+			w.progressFn(probe.progress(window))
+		}
+
+		// Snapshot computation state periodically, if requested
+		if w.checkpointW != nil && window%int(w.checkpointEvery) == 0 {
+			if err := w.writeCheckpoint(config, window, vs, checkpointed); err != nil {
+				return nil, err
+			}
+			checkpointed = len(vs.Values)
+		}
 	}
 
-	// Return slice of computed values
-	return computed, nil
+	// No values could be computed from the stream
+	if split != nil {
+		if split.empty() {
+			return nil, ErrTooShort
+		}
+
+		for _, cvs := range split.sets {
+			cvs.setStream(config, w.resolution)
+		}
+		vs.channels = split.sets
+	} else if len(vs.Values) == 0 && streamed == 0 {
+		return nil, ErrTooShort
+	}
+
+	if w.progressFn != nil {
+		p := probe.progress(window)
+		p.EstimatedWindows = window
+		p.Done = true
+		w.progressFn(p)
+	}
+
+	// Return set of computed values
+	return vs, nil
 }
 
-// generateImage takes a slice of computed values and generates
-// a waveform image from the input.
-func (w *Waveform) generateImage(computed []float64) image.Image {
-	// Store integer scale values
-	intScaleX := int(w.scaleX)
-	intScaleY := int(w.scaleY)
-
-	// Calculate maximum n, x, y, where:
-	//  - n: number of computed values
-	//  - x: number of pixels on X-axis
-	//  - y: number of pixels on Y-axis
-	maxN := len(computed)
-	maxX := maxN * intScaleX
-	maxY := imgYDefault * intScaleY
+// openDecoder opens an audio decoder on r, which reads the input audio stream,
+// translating any errors from the audio package into errors exported by this
+// package.
+//
+// If a fallback decoder is set, streams in unknown formats are decoded by its
+// command instead.  If in-memory samples or a caller's decoder are set, they
+// are read instead of the input stream.
+//
+// Malformed streams may cause some decoders to panic, so the decoder is
+// wrapped to recover from any panics and report them as a *DecoderPanicError.
+func (w *Waveform) openDecoder(r io.Reader) (decoder *safeDecoder, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			decoder, err = nil, &DecoderPanicError{Value: r}
+		}
+	}()
 
-	// Create output, rectangular image
-	img := image.NewRGBA(image.Rect(0, 0, maxX, maxY))
-	bounds := img.Bounds()
+	if w.samples != nil {
+		return &safeDecoder{d: w.samples.decoder()}, nil
+	}
+	if w.decoder != nil {
+		return &safeDecoder{d: callerDecoder{w.decoder}}, nil
+	}
 
-	// Calculate halfway point of Y-axis for image
-	imgHalfY := bounds.Max.Y / 2
+	// Record the bytes read while detecting the format, so that they can be
+	// replayed to the fallback decoder
+	var rr *rewindReader
+	if w.fallback != nil {
+		rr = newRewindReader(r)
+		r = rr
+	}
 
-	// Calculate a peak value used for smoothing scaled X-axis images
-	peak := int(math.Ceil(float64(w.scaleX)) / 2)
+	d, _, err := audio.NewDecoder(r)
+	if err != nil {
+		// Unknown format
+		if err == audio.ErrFormat {
+			if rr == nil {
+				return nil, ErrFormat
+			}
 
-	// Calculate scaling factor, based upon maximum value computed by a SampleReduceFunc.
-	// If option ScaleClipping is true, when maximum value is above certain thresholds
-	// the scaling factor is reduced to show an accurate waveform with less clipping.
-	imgScale := scaleDefault
-	if w.scaleClipping {
-		// Find maximum value from input slice
-		var maxValue float64
-		for _, c := range computed {
-			if c > maxValue {
-				maxValue = c
+			ed, err := w.fallback.start(rr.rewind())
+			if err != nil {
+				return nil, err
 			}
+
+			return &safeDecoder{d: ed}, nil
+		}
+
+		// Invalid data
+		if err == audio.ErrInvalidData {
+			return nil, ErrInvalidData
 		}
 
-		// For each 0.05 maximum increment at 0.30 and above, reduce the scaling
-		// factor by 0.25.  This is a rough estimate and may be tweaked in the future.
-		for i := 0.30; i < maxValue; i += 0.05 {
-			imgScale -= 0.25
+		// Unexpected end-of-stream
+		if err == audio.ErrUnexpectedEOS {
+			return nil, ErrUnexpectedEOS
 		}
+
+		// All other errors
+		return nil, err
+	}
+
+	if rr != nil {
+		rr.stop()
 	}
 
-	// Values to be used for repeated computations
-	var scaleComputed, halfScaleComputed, adjust int
-	intBoundY := int(bounds.Max.Y)
-	f64BoundY := float64(bounds.Max.Y)
-	intSharpness := int(w.sharpness)
+	return &safeDecoder{d: d}, nil
+}
 
-	// Begin iterating all computed values
-	x := 0
-	for n := range computed {
-		// Scale computed value to an integer, using the height of the image and a constant
-		// scaling factor
-		scaleComputed = int(math.Floor(computed[n] * f64BoundY * imgScale))
+// maxWindowSamples is the maximum number of samples in a single window of
+// audio, which limits the memory allocated for streams with an unreasonably
+// large sample rate or number of channels.
+const maxWindowSamples = 1 << 22
 
-		// Calculate the halfway point for the scaled computed value
-		halfScaleComputed = scaleComputed / 2
+// checkConfig verifies that the audio configuration reported by a decoder is
+// one which can be used to compute values.
+func checkConfig(config audio.Config) error {
+	if config.SampleRate <= 0 || config.Channels <= 0 {
+		return ErrInvalidData
+	}
 
-		// Draw background color down the entire Y-axis
-		for y := 0; y < intBoundY; y++ {
-			// If X-axis is being scaled, draw background over several X coordinates
-			for i := 0; i < intScaleX; i++ {
-				img.Set(x+i, y, w.bgColorFn(n, x+i, y, maxN, maxX, maxY))
-			}
+	if int64(config.SampleRate)*int64(config.Channels) > maxWindowSamples {
+		return ErrInvalidData
+	}
+
+	return nil
+}
+
+// DecoderPanicError is an error which is returned when an audio decoder panics
+// while decoding a malformed stream, so that a single malformed stream cannot
+// crash an entire application.  A DecoderPanicError matches ErrInvalidData
+// when used with errors.Is.
+type DecoderPanicError struct {
+	// Value is the value which was passed to panic by the decoder.
+	Value interface{}
+}
+
+// Error returns the string representation of a DecoderPanicError.
+func (e *DecoderPanicError) Error() string {
+	return fmt.Sprintf("%v: decoder panic: %v", ErrInvalidData, e.Value)
+}
+
+// Is reports whether a DecoderPanicError matches a target error, for use with
+// errors.Is.  A DecoderPanicError matches ErrInvalidData.
+func (e *DecoderPanicError) Is(target error) bool {
+	return target == ErrInvalidData
+}
+
+// safeDecoder is an audio.Decoder which recovers from any panics which occur
+// while decoding a malformed stream, and returns a *DecoderPanicError instead.
+type safeDecoder struct {
+	d audio.Decoder
+}
+
+// Config returns the audio configuration of the underlying decoder.
+func (d *safeDecoder) Config() audio.Config {
+	return d.d.Config()
+}
+
+// Read reads audio samples from the underlying decoder into b.
+func (d *safeDecoder) Read(b audio.Slice) (n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, &DecoderPanicError{Value: r}
 		}
+	}()
 
-		// Iterate image coordinates on the Y-axis, generating a symmetrical waveform
-		// image above and below the center of the image
-		for y := imgHalfY - halfScaleComputed; y < scaleComputed+(imgHalfY-halfScaleComputed); y++ {
-			// If X-axis is being scaled, draw computed value over several X coordinates
-			for i := 0; i < intScaleX; i++ {
-				// When scaled, adjust computed value to be lower on either side of the peak,
-				// so that the image appears more smooth and less "blocky"
-				if i < peak {
-					// Adjust downward
-					adjust = (i - peak) * intSharpness
-				} else if i == peak {
-					// No adjustment at peak
-					adjust = 0
-				} else {
-					// Adjust downward
-					adjust = (peak - i) * intSharpness
-				}
+	return d.d.Read(b)
+}
 
-				// On top half of the image, invert adjustment to create symmetry between
-				// top and bottom halves
-				if y < imgHalfY {
-					adjust = -1 * adjust
-				}
+// Close closes the underlying decoder, if it must be closed.
+func (d *safeDecoder) Close() error {
+	if c, ok := d.d.(io.Closer); ok {
+		return c.Close()
+	}
 
-				// Retrieve and apply color function at specified computed value
-				// count, and X and Y coordinates.
-				// The output color is selected using the function, and is applied to
-				// the resulting image.
-				img.Set(x+i, y+adjust, w.fgColorFn(n, x+i, y+adjust, maxN, maxX, maxY))
-			}
+	return nil
+}
+
+// scaleFactor calculates the factor used to scale computed values by the height
+// of the output image.
+//
+// If option ScaleClipping is true, when the maximum computed value is above certain
+// thresholds, the scaling factor is reduced to show an accurate waveform with less
+// clipping.  If option Normalized is in use, the scaling factor is chosen so that
+// the maximum computed value reaches its target.
+func (s *RenderStyle) scaleFactor(computed []float64) float64 {
+	imgScale := scaleDefault
+	if s.normalize > 0 {
+		if f := normalizeFactor(computed, s.normalize); f > 0 {
+			return f
 		}
 
-		// Increase X by scaling factor, to continue drawing at next loop
-		x += intScaleX
+		return imgScale
 	}
 
+	if !s.scaleClipping {
+		return imgScale
+	}
+
+	// Find maximum value from input slice
+	var maxValue float64
+	for _, c := range computed {
+		if c > maxValue {
+			maxValue = c
+		}
+	}
+
+	// For each 0.05 maximum increment at 0.30 and above, reduce the scaling
+	// factor by 0.25.  This is a rough estimate and may be tweaked in the future.
+	for i := 0.30; i < maxValue; i += 0.05 {
+		imgScale -= 0.25
+	}
+
+	return imgScale
+}
+
+// generateImage takes a slice of computed values and generates
+// a waveform image from the input.  If edges is not nil, columns are
+// positioned using the input edges, as computed by timeEdges.
+func (s *RenderStyle) generateImage(computed []float64, edges []int) image.Image {
+	// Create output, rectangular image
+	it := s.columns(computed, edges)
+	img := s.newImage(it.Bounds())
+	s.drawRaster(img, it)
+
 	// Return generated image
 	return img
 }
+
+// drawRaster draws each column produced by a ColumnIterator onto img.
+func (s *RenderStyle) drawRaster(img draw.Image, it *ColumnIterator) {
+	for it.Next() {
+		s.drawColumn(img, it.Column())
+	}
+}
@@ -2,13 +2,24 @@ package waveform
 
 import (
 	"bytes"
+	"image"
+	"image/color"
 	"math/rand"
 	"testing"
 	"time"
 
 	"azul3d.org/engine/audio"
+	"github.com/mdlayher/waveform/synth"
 )
 
+// Benchmarks report allocations, and may be profiled using the standard
+// testing flags, such as:
+//
+//   go test -run NONE -bench Generate -cpuprofile cpu.out -memprofile mem.out
+
+// synthSpeechFile is 60 seconds of synthetic, speech-like audio, encoded as WAV
+var synthSpeechFile = synth.WAV(synth.Speech(), 60*time.Second, nil)
+
 // BenchmarkGenerateWAV checks the performance of the Generate() function with a WAV file
 func BenchmarkGenerateWAV(b *testing.B) {
 	benchmarkGenerate(b, wavFile)
@@ -19,6 +30,40 @@ func BenchmarkGenerateFLAC(b *testing.B) {
 	benchmarkGenerate(b, flacFile)
 }
 
+// BenchmarkGenerateSynthSpeech checks the performance of the Generate() function
+// with 60 seconds of synthetic speech
+func BenchmarkGenerateSynthSpeech(b *testing.B) {
+	benchmarkGenerate(b, synthSpeechFile)
+}
+
+// BenchmarkGenerateSynthSpeechVector checks the performance of the Generate()
+// function with 60 seconds of synthetic speech, using the anti-aliased vector
+// renderer
+func BenchmarkGenerateSynthSpeechVector(b *testing.B) {
+	benchmarkGenerate(b, synthSpeechFile, VectorRenderer())
+}
+
+// BenchmarkGenerateSynthSpeechOutline checks the performance of the Generate()
+// function with 60 seconds of synthetic speech, using the vector renderer to
+// fill and stroke the waveform envelope
+func BenchmarkGenerateSynthSpeechOutline(b *testing.B) {
+	benchmarkGenerate(b, synthSpeechFile, VectorRenderer(), Outline(SolidColor(color.Black), 2))
+}
+
+// BenchmarkGenerateSynthSpeechProportional checks the performance of the
+// Generate() function with 60 seconds of synthetic speech, drawing columns
+// proportional to time
+func BenchmarkGenerateSynthSpeechProportional(b *testing.B) {
+	benchmarkGenerate(b, synthSpeechFile, PixelsPerSecond(2.5))
+}
+
+// BenchmarkGenerateSynthSpeechRGBA64 checks the performance of the Generate()
+// function with 60 seconds of synthetic speech, drawing a 16-bit image in
+// linear light
+func BenchmarkGenerateSynthSpeechRGBA64(b *testing.B) {
+	benchmarkGenerate(b, synthSpeechFile, OutputFormat(FormatRGBA64), LinearLight(true))
+}
+
 // BenchmarkWaveformComputeWAV checks the performance of the WaveformCompute() function with a WAV file
 func BenchmarkWaveformComputeWAV(b *testing.B) {
 	benchmarkWaveformCompute(b, wavFile)
@@ -59,6 +104,35 @@ func BenchmarkWaveformDraw960(b *testing.B) {
 	benchmarkWaveformDraw(b, 960)
 }
 
+// BenchmarkWaveformDrawInto960 checks the performance of the
+// WaveformDrawInto() function with approximately 960 seconds of computed
+// values, drawing into a reused image
+func BenchmarkWaveformDrawInto960(b *testing.B) {
+	benchmarkWaveformDrawInto(b, 960)
+}
+
+// BenchmarkRenderStyleDrawColumn checks the performance of the
+// RenderStyle.DrawColumn() function for a single column
+func BenchmarkRenderStyleDrawColumn(b *testing.B) {
+	s, err := NewStyle(Scale(4, 2))
+	if err != nil {
+		panic(err)
+	}
+
+	it, err := s.Columns([]float64{0.50})
+	if err != nil {
+		panic(err)
+	}
+	img := image.NewRGBA(it.Bounds())
+	it.Next()
+	c := it.Column()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.DrawColumn(img, c)
+	}
+}
+
 // BenchmarkRMSF64Samples22050 checks the performance of the RMSF64Samples() function
 // with 22050 samples
 func BenchmarkRMSF64Samples22050(b *testing.B) {
@@ -83,15 +157,39 @@ func BenchmarkRMSF64Samples176400(b *testing.B) {
 	benchmarkRMSF64Samples(b, 176400)
 }
 
-// benchmarkGenerate contains common logic for benchmarking Generate
-func benchmarkGenerate(b *testing.B, data []byte) {
+// BenchmarkAverageF64Samples44100 checks the performance of the
+// AverageF64Samples() function with 44100 samples
+func BenchmarkAverageF64Samples44100(b *testing.B) {
+	benchmarkAverageF64Samples(b, 44100)
+}
+
+// BenchmarkAverageF64Samples176400 checks the performance of the
+// AverageF64Samples() function with 176400 samples
+func BenchmarkAverageF64Samples176400(b *testing.B) {
+	benchmarkAverageF64Samples(b, 176400)
+}
+
+// benchmarkGenerate contains common logic for benchmarking Generate.  The
+// average time spent computing and drawing is reported alongside the usual
+// results, so that a regression can be attributed to either stage.
+func benchmarkGenerate(b *testing.B, data []byte, options ...OptionsFunc) {
+	b.ReportAllocs()
+
+	var total Timings
 	for i := 0; i < b.N; i++ {
-		Generate(bytes.NewReader(data))
+		_, t, _ := GenerateTimed(bytes.NewReader(data), options...)
+		total.Compute += t.Compute
+		total.Draw += t.Draw
 	}
+
+	b.ReportMetric(float64(total.Compute.Nanoseconds())/float64(b.N), "compute-ns/op")
+	b.ReportMetric(float64(total.Draw.Nanoseconds())/float64(b.N), "draw-ns/op")
 }
 
 // benchmarkWaveformCompute contains common logic for benchmarking Waveform.Compute
 func benchmarkWaveformCompute(b *testing.B, data []byte) {
+	b.ReportAllocs()
+
 	for i := 0; i < b.N; i++ {
 		w, err := New(bytes.NewReader(data))
 		if err != nil {
@@ -104,6 +202,8 @@ func benchmarkWaveformCompute(b *testing.B, data []byte) {
 
 // benchmarkWaveformDraw contains common logic for benchmarking Waveform.Draw
 func benchmarkWaveformDraw(b *testing.B, count int) {
+	b.ReportAllocs()
+
 	values := make([]float64, count)
 	for i := 0; i < b.N; i++ {
 		w, err := New(nil)
@@ -115,6 +215,42 @@ func benchmarkWaveformDraw(b *testing.B, count int) {
 	}
 }
 
+// benchmarkWaveformDrawInto contains common logic for benchmarking
+// Waveform.DrawInto
+func benchmarkWaveformDrawInto(b *testing.B, count int) {
+	values := make([]float64, count)
+	w, err := New(nil)
+	if err != nil {
+		panic(err)
+	}
+	dst := image.NewRGBA(w.Style().DrawBounds(values))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.DrawInto(dst, values); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// benchmarkAverageF64Samples contains common logic for benchmarking
+// AverageF64Samples
+func benchmarkAverageF64Samples(b *testing.B, count int) {
+	// Generate slice of samples
+	rand.Seed(time.Now().UnixNano())
+	var samples audio.Float64
+	for i := 0; i < count; i++ {
+		samples = append(samples, rand.Float64())
+	}
+
+	// Reset timer and start benchmark
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AverageF64Samples(samples)
+	}
+}
+
 // benchmarkRMSF64Samples contains common logic for benchmarking RMSF64Samples
 func benchmarkRMSF64Samples(b *testing.B, count int) {
 	// Generate slice of samples
@@ -6,6 +6,9 @@ import (
 	"image/color"
 	"image/png"
 	"os"
+	"time"
+
+	"github.com/mdlayher/waveform/synth"
 )
 
 // ExampleGenerate provides example usage of Generate, using a media file from the filesystem.
@@ -53,3 +56,24 @@ func ExampleGenerate() {
 	// encoded: 344 bytes
 	// resolution: (50,256)
 }
+
+// ExampleGenerate_synth provides example usage of Generate, using synthetic
+// speech-like audio generated by package synth, rather than a media file.
+func ExampleGenerate_synth() {
+	// Generate 10 seconds of speech-like audio, encoded as WAV
+	wav := synth.WAV(synth.Speech(), 10*time.Second, &synth.Options{Seed: 1})
+
+	// Compute 4 values per second, and draw each value 2 pixels wide
+	img, err := Generate(bytes.NewReader(wav),
+		Resolution(4),
+		Scale(2, 1),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("resolution: %s", img.Bounds().Max)
+
+	// Output:
+	// resolution: (80,128)
+}
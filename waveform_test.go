@@ -2,10 +2,19 @@ package waveform
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
 )
 
 var (
@@ -55,7 +64,6 @@ func TestWaveformComputeWAVOK(t *testing.T) {
 			0.7071166239921965,
 			0.7071165471800284,
 			0.7071166825227931,
-			0.7071166825227931,
 		},
 		nil,
 	)
@@ -105,11 +113,279 @@ func TestWaveformComputeMP3ErrFormat(t *testing.T) {
 	testWaveformCompute(t, bytes.NewReader(mp3File), ErrFormat, nil, nil)
 }
 
-// TestWaveformComputeOggVorbisErrFormat verifies that the Waveform.Compute method produces
+// TestWaveformComputeOggVorbisOK verifies that the Waveform.Compute method produces
+// appropriate computed samples and error for an input audio stream.
+// The input stream is in stereo Ogg Vorbis format, and no errors should occur.
+// Vorbis is a lossy format, so values are compared approximately.
+func TestWaveformComputeOggVorbisOK(t *testing.T) {
+	w, err := New(bytes.NewReader(oggVorbisFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatalf("unexpected Compute error: %v", err)
+	}
+
+	if len(values) != 5 {
+		t.Fatalf("unexpected number of values: %v != %v", len(values), 5)
+	}
+
+	for i, v := range values {
+		if math.Abs(v-0.7071) > 0.02 {
+			t.Fatalf("[%02d] unexpected value: %v", i, v)
+		}
+	}
+}
+
+// TestWaveformComputeOggVorbisErrInvalidData verifies that the Waveform.Compute method produces
+// appropriate computed samples and error for an input audio stream.
+// The input stream is in Ogg format, but contains invalid data.
+func TestWaveformComputeOggVorbisErrInvalidData(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader([]byte{'O', 'g', 'g', 'S'}), ErrInvalidData, nil, nil)
+}
+
+// TestWaveformComputeOggOpusErrFormat verifies that the Waveform.Compute method produces
 // appropriate computed samples and error for an input audio stream.
-// The input stream is in Ogg Vorbis format, and should produce an unsupported format error.
-func TestWaveformComputeOggVorbisErrFormat(t *testing.T) {
-	testWaveformCompute(t, bytes.NewReader(oggVorbisFile), ErrFormat, nil, nil)
+// The input stream is in Ogg Opus format, and should produce an unsupported format error.
+func TestWaveformComputeOggOpusErrFormat(t *testing.T) {
+	// First Ogg page of an Opus stream, containing the identification header
+	page := append([]byte("OggS\x00\x02"), make([]byte, 20)...)
+	page = append(page, 1, 19)
+	page = append(page, "OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00"...)
+
+	testWaveformCompute(t, bytes.NewReader(page), ErrFormat, nil, nil)
+}
+
+// TestWaveformComputeValueSetWAVCounts verifies that the Waveform.ComputeValueSet
+// method reports the number of samples read to compute each value.
+func TestWaveformComputeValueSetWAVCounts(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stereo, 44.1kHz audio is read once per second
+	const count = 44100 * 2
+
+	if len(vs.Counts) != len(vs.Values) {
+		t.Fatalf("unexpected Counts length: %v != %v", len(vs.Counts), len(vs.Values))
+	}
+	for i, c := range vs.Counts {
+		if c != count {
+			t.Fatalf("unexpected count at index %d: %v != %v", i, c, count)
+		}
+	}
+}
+
+// TestWaveformComputeSubSecondClips verifies that the Waveform.Compute method
+// produces a single value for audio streams shorter than one window, or
+// ErrTooShort if no value can be computed.
+func TestWaveformComputeSubSecondClips(t *testing.T) {
+	var tests = []struct {
+		description string
+		samples     int
+		options     []OptionsFunc
+		values      int
+		err         error
+	}{
+		{
+			description: "empty stream",
+			samples:     0,
+			err:         ErrTooShort,
+		},
+		{
+			description: "single sample",
+			samples:     1,
+			values:      1,
+		},
+		{
+			description: "quarter second",
+			samples:     2000,
+			values:      1,
+		},
+		{
+			description: "quarter second, padded",
+			samples:     2000,
+			options:     []OptionsFunc{PartialWindow(PartialWindowPad)},
+			values:      1,
+		},
+		{
+			description: "quarter second, dropped",
+			samples:     2000,
+			options:     []OptionsFunc{PartialWindow(PartialWindowDrop)},
+			err:         ErrTooShort,
+		},
+		{
+			description: "one and a quarter seconds, dropped",
+			samples:     10000,
+			options:     []OptionsFunc{PartialWindow(PartialWindowDrop)},
+			values:      1,
+		},
+	}
+
+	for _, test := range tests {
+		// Mono, 8kHz audio is read 8000 samples at a time
+		samples := make([]int16, test.samples)
+		for i := range samples {
+			samples[i] = 16384
+		}
+
+		w, err := New(bytes.NewReader(makeWAV(8000, 1, samples)), test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err != test.err {
+			t.Fatalf("%s: unexpected Compute error: %v != %v", test.description, err, test.err)
+		}
+		if len(values) != test.values {
+			t.Fatalf("%s: unexpected number of values: %v != %v", test.description, len(values), test.values)
+		}
+	}
+}
+
+// TestWaveformComputeValueSetFractionalDurations verifies that the
+// Waveform.ComputeValueSet method reports exact durations for each window when
+// the sample rate is not evenly divisible by the resolution.
+func TestWaveformComputeValueSetFractionalDurations(t *testing.T) {
+	// One second of mono, 8kHz audio, read 3 times per second
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, make([]int16, 8000))), Resolution(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := w.ComputeValueSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCounts := []int{2666, 2667, 2667}
+	wantDurations := []time.Duration{
+		333250 * time.Microsecond,
+		333375 * time.Microsecond,
+		333375 * time.Microsecond,
+	}
+
+	if len(vs.Counts) != len(wantCounts) || len(vs.Durations) != len(wantDurations) {
+		t.Fatalf("unexpected ValueSet lengths: %v, %v", len(vs.Counts), len(vs.Durations))
+	}
+
+	var total time.Duration
+	for i := range wantCounts {
+		if vs.Counts[i] != wantCounts[i] {
+			t.Fatalf("unexpected count at index %d: %v != %v", i, vs.Counts[i], wantCounts[i])
+		}
+		if vs.Durations[i] != wantDurations[i] {
+			t.Fatalf("unexpected duration at index %d: %v != %v", i, vs.Durations[i], wantDurations[i])
+		}
+
+		total += vs.Durations[i]
+	}
+
+	if total != time.Second {
+		t.Fatalf("unexpected total duration: %v != %v", total, time.Second)
+	}
+}
+
+// TestWaveformComputeResolutionTooHigh verifies that the Waveform.Compute
+// method returns an error if the resolution exceeds the stream's sample rate.
+func TestWaveformComputeResolutionTooHigh(t *testing.T) {
+	w, err := New(bytes.NewReader(makeWAV(8000, 1, make([]int16, 8000))), Resolution(8001))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != errResolutionTooHigh {
+		t.Fatalf("unexpected Compute error: %v != %v", err, errResolutionTooHigh)
+	}
+}
+
+// TestWaveformDrawOverrides verifies that options passed to Waveform.Draw apply
+// only to that call, and do not modify the receiving Waveform struct.
+func TestWaveformDrawOverrides(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.10}
+	red := color.RGBA{255, 0, 0, 255}
+
+	img := w.Draw(values, FGColorFunction(SolidColor(red)), Scale(5, 1))
+	if x := img.Bounds().Max.X; x != 10 {
+		t.Fatalf("unexpected override image width: %v != %v", x, 10)
+	}
+	if c := img.At(0, imgYDefault/2); !colorsEqual(c, red) {
+		t.Fatalf("unexpected override foreground: %v != %v", c, red)
+	}
+
+	// Receiver is unchanged
+	img = w.Draw(values)
+	if x := img.Bounds().Max.X; x != 2 {
+		t.Fatalf("unexpected image width: %v != %v", x, 2)
+	}
+	if c := img.At(0, imgYDefault/2); !colorsEqual(c, color.Black) {
+		t.Fatalf("unexpected foreground: %v != %v", c, color.Black)
+	}
+}
+
+// TestWaveformDrawCheckedOverrideInvalid verifies that Waveform.DrawChecked
+// returns an error for invalid overrides, including those which conflict with
+// options set on the receiving Waveform struct.
+func TestWaveformDrawCheckedOverrideInvalid(t *testing.T) {
+	w, err := New(nil, VectorRenderer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.DrawChecked([]float64{0.10}, Scale(0, 1)); err != errScaleXZero {
+		t.Fatalf("unexpected error: %v != %v", err, errScaleXZero)
+	}
+	if _, err := w.DrawChecked([]float64{0.10}, Sharpness(2)); !errors.Is(err, errSharpnessConflictsVector) {
+		t.Fatalf("unexpected error: %v != %v", err, errSharpnessConflictsVector)
+	}
+	if img := w.Draw([]float64{0.10}, Sharpness(2)); img != nil {
+		t.Fatal("unexpected non-nil image")
+	}
+
+	// Conflicting override is not retained by the receiver
+	if w.explicit["sharpness"] {
+		t.Fatal("override modified receiver")
+	}
+}
+
+// TestWaveformDrawOverridesConcurrent verifies that Waveform.Draw may be called
+// concurrently with different overrides.
+func TestWaveformDrawOverridesConcurrent(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.10, 0.20, 0.30}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			img := w.Draw(values, Scale(uint(i), 1), Sharpness(uint(i)))
+			if x := img.Bounds().Max.X; x != 3*i {
+				t.Errorf("unexpected image width: %v != %v", x, 3*i)
+			}
+		}(i)
+	}
+
+	wg.Wait()
 }
 
 // TestWaveformComputeSampleFuncFunctionNil verifies that the Waveform.Compute method returns an error
@@ -159,3 +435,88 @@ func testWaveformCompute(t *testing.T, r io.Reader, err error, values []float64,
 		}
 	}
 }
+
+// TestCheckConfig verifies that checkConfig rejects audio configurations
+// which cannot be used to compute values.
+func TestCheckConfig(t *testing.T) {
+	var tests = []struct {
+		config audio.Config
+		err    error
+	}{
+		{config: audio.Config{SampleRate: 44100, Channels: 2}},
+		{config: audio.Config{SampleRate: 0, Channels: 2}, err: ErrInvalidData},
+		{config: audio.Config{SampleRate: 44100, Channels: 0}, err: ErrInvalidData},
+		{config: audio.Config{SampleRate: -1, Channels: 1}, err: ErrInvalidData},
+		{config: audio.Config{SampleRate: 1 << 30, Channels: 1 << 16}, err: ErrInvalidData},
+	}
+
+	for i, tt := range tests {
+		if err := checkConfig(tt.config); err != tt.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, tt.err)
+		}
+	}
+}
+
+// TestSafeDecoderPanic verifies that safeDecoder recovers from a panic in the
+// underlying decoder, and returns a *DecoderPanicError which matches
+// ErrInvalidData and includes the panic message.
+func TestSafeDecoderPanic(t *testing.T) {
+	d := &safeDecoder{d: panicDecoder{}}
+
+	n, err := d.Read(make(audio.Float64, 16))
+	if n != 0 {
+		t.Fatalf("unexpected number of samples read: %v", n)
+	}
+
+	var perr *DecoderPanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if !errors.Is(err, ErrInvalidData) {
+		t.Fatalf("error does not match ErrInvalidData: %v", err)
+	}
+	if !strings.Contains(err.Error(), "index out of range") {
+		t.Fatalf("error does not contain panic message: %v", err)
+	}
+}
+
+// panicDecoder is an audio.Decoder which panics when read, as some decoders
+// do when given malformed input.
+type panicDecoder struct{}
+
+func (panicDecoder) Config() audio.Config {
+	return audio.Config{SampleRate: 44100, Channels: 1}
+}
+
+func (panicDecoder) Read(b audio.Slice) (int, error) {
+	_ = b.At(b.Len())
+	return 0, nil
+}
+
+// makeWAV is a test helper which encodes a canonical, 16-bit PCM WAV stream
+// from a slice of interleaved audio samples.
+func makeWAV(sampleRate int, channels int, samples []int16) []byte {
+	const bitDepth = 16
+	dataSize := len(samples) * bitDepth / 8
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{
+		uint32(16),
+		uint16(1),
+		uint16(channels),
+		uint32(sampleRate),
+		uint32(sampleRate * channels * bitDepth / 8),
+		uint16(channels * bitDepth / 8),
+		uint16(bitDepth),
+	} {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	_ = binary.Write(buf, binary.LittleEndian, samples)
+
+	return buf.Bytes()
+}
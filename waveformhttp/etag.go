@@ -0,0 +1,175 @@
+package waveformhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A RenderKeyFunc returns the content-addressed render key for an HTTP
+// request: a string which identifies both the content of the source audio and
+// every parameter which affects the rendered image, such as its size, style,
+// and output format.  Two requests with the same render key must produce
+// byte-for-byte identical responses.
+//
+// modTime is the time at which the source audio was last modified, or the
+// zero time if it is unknown.
+//
+// If ok is false, no render key is available for the request, and it is
+// handled without conditional request support.
+type RenderKeyFunc func(r *http.Request) (key string, modTime time.Time, ok bool)
+
+// ETag returns a strong entity tag for a render key, suitable for use in an
+// HTTP ETag header.
+func ETag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Conditional returns an http.Handler which adds ETag and Last-Modified
+// headers to successful responses from h, using the render key returned by
+// key, and which honors the If-None-Match and If-Modified-Since headers of GET
+// and HEAD requests.  Requests for a rendered waveform which the client
+// already has receive HTTP 304 Not Modified, without calling h.  Error
+// responses from h never carry these headers, so that they cannot be cached
+// in place of the rendered waveform.
+//
+// As with net/http, If-None-Match takes precedence over If-Modified-Since
+// when both are present.
+//
+// Conditional should wrap the handler returned by Limit, so that requests
+// which do not require a render do not count against quotas or wait for
+// other renders to complete.
+func Conditional(h http.Handler, key RenderKeyFunc) http.Handler {
+	return &conditionalHandler{
+		h:   h,
+		key: key,
+	}
+}
+
+// conditionalHandler is the http.Handler returned by Conditional.
+type conditionalHandler struct {
+	h   http.Handler
+	key RenderKeyFunc
+}
+
+// ServeHTTP implements http.Handler.
+func (ch *conditionalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		ch.h.ServeHTTP(w, r)
+		return
+	}
+
+	key, modTime, ok := ch.key(r)
+	if !ok {
+		ch.h.ServeHTTP(w, r)
+		return
+	}
+
+	etag := ETag(key)
+
+	// HTTP dates have a resolution of one second
+	var lastModified string
+	modTime = modTime.UTC().Truncate(time.Second)
+	if !isZeroTime(modTime) {
+		lastModified = modTime.Format(http.TimeFormat)
+	}
+
+	vw := &validatorWriter{
+		ResponseWriter: w,
+		etag:           etag,
+		lastModified:   lastModified,
+	}
+
+	if notModified(r, etag, modTime) {
+		vw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	ch.h.ServeHTTP(vw, r)
+
+	// As with net/http, a handler which writes nothing responds with
+	// HTTP 200 OK
+	if !vw.wroteHeader {
+		vw.WriteHeader(http.StatusOK)
+	}
+}
+
+// validatorWriter is an http.ResponseWriter which adds the ETag and
+// Last-Modified headers to successful and HTTP 304 Not Modified responses
+// only, so that errors such as those from Limit are never cached under the
+// entity tag of a rendered waveform.
+type validatorWriter struct {
+	http.ResponseWriter
+	etag         string
+	lastModified string
+	wroteHeader  bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (vw *validatorWriter) WriteHeader(code int) {
+	if !vw.wroteHeader && (code < 300 || code == http.StatusNotModified) {
+		vw.Header().Set("ETag", vw.etag)
+		if vw.lastModified != "" {
+			vw.Header().Set("Last-Modified", vw.lastModified)
+		}
+	}
+
+	vw.wroteHeader = true
+	vw.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (vw *validatorWriter) Write(b []byte) (int, error) {
+	if !vw.wroteHeader {
+		vw.WriteHeader(http.StatusOK)
+	}
+
+	return vw.ResponseWriter.Write(b)
+}
+
+// notModified reports whether the conditional headers of r indicate that the
+// client already has the representation identified by etag and modTime.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || isZeroTime(modTime) {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !modTime.After(t)
+}
+
+// etagMatch reports whether the list of entity tags in an If-None-Match
+// header matches etag.  If-None-Match uses the weak comparison function, so a
+// weak entity tag matches a strong one with the same opaque value.
+func etagMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isZeroTime reports whether t is the zero time or the Unix epoch, neither
+// of which is a meaningful modification time.
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
@@ -0,0 +1,216 @@
+package waveformhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestETag verifies that ETag produces stable, strong entity tags which
+// differ between render keys.
+func TestETag(t *testing.T) {
+	a, b := ETag("song.wav?width=800"), ETag("song.wav?width=400")
+
+	if a != ETag("song.wav?width=800") {
+		t.Fatalf("unstable entity tag: %q", a)
+	}
+	if a == b {
+		t.Fatalf("identical entity tags for different keys: %q", a)
+	}
+	if len(a) != 34 || a[0] != '"' || a[len(a)-1] != '"' {
+		t.Fatalf("malformed entity tag: %q", a)
+	}
+}
+
+// TestConditional verifies that Conditional sets caching headers and honors
+// conditional request headers.
+func TestConditional(t *testing.T) {
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	etag := ETag("key")
+
+	var tests = []struct {
+		name   string
+		method string
+		header http.Header
+		code   int
+	}{
+		{
+			name: "unconditional",
+			code: http.StatusOK,
+		},
+		{
+			name:   "If-None-Match match",
+			header: http.Header{"If-None-Match": {`"other", ` + etag}},
+			code:   http.StatusNotModified,
+		},
+		{
+			name:   "If-None-Match weak match",
+			header: http.Header{"If-None-Match": {"W/" + etag}},
+			code:   http.StatusNotModified,
+		},
+		{
+			name:   "If-None-Match wildcard",
+			header: http.Header{"If-None-Match": {"*"}},
+			code:   http.StatusNotModified,
+		},
+		{
+			name:   "If-None-Match mismatch",
+			header: http.Header{"If-None-Match": {`"other"`}},
+			code:   http.StatusOK,
+		},
+		{
+			name: "If-None-Match precedence",
+			header: http.Header{
+				"If-None-Match":     {`"other"`},
+				"If-Modified-Since": {modTime.Format(http.TimeFormat)},
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:   "If-Modified-Since not modified",
+			header: http.Header{"If-Modified-Since": {modTime.Add(time.Hour).Format(http.TimeFormat)}},
+			code:   http.StatusNotModified,
+		},
+		{
+			name:   "If-Modified-Since modified",
+			header: http.Header{"If-Modified-Since": {modTime.Add(-time.Hour).Format(http.TimeFormat)}},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "If-Modified-Since invalid",
+			header: http.Header{"If-Modified-Since": {"yesterday"}},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "HEAD",
+			method: http.MethodHead,
+			header: http.Header{"If-None-Match": {etag}},
+			code:   http.StatusNotModified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			h := Conditional(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					called = true
+				}),
+				func(_ *http.Request) (string, time.Time, bool) {
+					// Sub-second precision is discarded
+					return "key", modTime.Add(500 * time.Millisecond), true
+				},
+			)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			r := httptest.NewRequest(method, "/", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Fatalf("unexpected status code: %d != %d", w.Code, tt.code)
+			}
+			if want := tt.code == http.StatusOK; called != want {
+				t.Fatalf("unexpected handler call: %v != %v", called, want)
+			}
+
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("unexpected ETag: %q != %q", got, etag)
+			}
+			if got, want := w.Header().Get("Last-Modified"), modTime.Format(http.TimeFormat); got != want {
+				t.Fatalf("unexpected Last-Modified: %q != %q", got, want)
+			}
+		})
+	}
+}
+
+// TestConditionalPassthrough verifies that Conditional does not apply to
+// requests without a render key, or which are not GET or HEAD requests.
+func TestConditionalPassthrough(t *testing.T) {
+	var tests = []struct {
+		name   string
+		method string
+		ok     bool
+	}{
+		{name: "no key", method: http.MethodGet, ok: false},
+		{name: "POST", method: http.MethodPost, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Conditional(okHandler(), func(_ *http.Request) (string, time.Time, bool) {
+				return "key", time.Time{}, tt.ok
+			})
+
+			r := httptest.NewRequest(tt.method, "/", nil)
+			r.Header.Set("If-None-Match", "*")
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("unexpected status code: %d", w.Code)
+			}
+			if etag := w.Header().Get("ETag"); etag != "" {
+				t.Fatalf("unexpected ETag: %q", etag)
+			}
+		})
+	}
+}
+
+// TestConditionalError verifies that Conditional does not add validators to
+// error responses.
+func TestConditionalError(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusInternalServerError} {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			h := Conditional(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "error", code)
+			}), func(_ *http.Request) (string, time.Time, bool) {
+				return "key", time.Unix(1, 0), true
+			})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != code {
+				t.Fatalf("unexpected status code: %d != %d", w.Code, code)
+			}
+			if etag := w.Header().Get("ETag"); etag != "" {
+				t.Fatalf("unexpected ETag: %q", etag)
+			}
+			if lm := w.Header().Get("Last-Modified"); lm != "" {
+				t.Fatalf("unexpected Last-Modified: %q", lm)
+			}
+		})
+	}
+}
+
+// TestConditionalNoModTime verifies that If-Modified-Since is ignored when the
+// modification time of the source audio is unknown.
+func TestConditionalNoModTime(t *testing.T) {
+	h := Conditional(okHandler(), func(_ *http.Request) (string, time.Time, bool) {
+		return "key", time.Time{}, true
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != "" {
+		t.Fatalf("unexpected Last-Modified: %q", lm)
+	}
+}
@@ -0,0 +1,232 @@
+// Package waveformhttp provides HTTP building blocks for serving waveform
+// images rendered by package waveform.
+//
+// Rendering a waveform decodes an entire audio stream and is CPU-heavy, so
+// handlers which render waveforms on public endpoints should be protected
+// using Limit, which applies pluggable concurrency limits and per-key render
+// quotas to any http.Handler.  Conditional adds ETag and Last-Modified
+// headers derived from a render key, so that browsers and CDNs can cache
+// rendered waveforms and revalidate them without triggering another render.
+// Signer and RequireSignature restrict renders to URLs signed by the
+// application, so that clients cannot request arbitrary sizes or styles.
+package waveformhttp
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Limiter limits the number of renders which may run concurrently.
+//
+// Acquire blocks until a render may begin, or until ctx is canceled.  On
+// success, Acquire returns a function which must be called exactly once when
+// the render is complete.  On failure, Acquire returns an error and no render
+// may begin.
+//
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// A Quota limits the number of renders permitted for a single key, such as a
+// tenant, API token, or client address.
+//
+// Allow reports whether a render for key may begin.  If it may not, Allow
+// returns the amount of time after which the caller may retry, or zero if
+// the time is unknown.
+//
+// Implementations must be safe for concurrent use.
+type Quota interface {
+	Allow(key string) (ok bool, retryAfter time.Duration)
+}
+
+// A KeyFunc returns the quota key for an HTTP request.
+type KeyFunc func(r *http.Request) string
+
+// LimitConfig specifies the limits applied to a handler by Limit.
+type LimitConfig struct {
+	// Limiter, if not nil, limits the number of requests which are handled
+	// concurrently.  Requests which cannot acquire the Limiter before their
+	// context is canceled receive HTTP 503 Service Unavailable.
+	Limiter Limiter
+
+	// Quota, if not nil, limits the number of requests handled for each key.
+	// Requests which exceed their quota receive HTTP 429 Too Many Requests,
+	// with a Retry-After header if the Quota provides one.
+	Quota Quota
+
+	// Key returns the Quota key for each request.  If nil, RemoteHost is
+	// used.
+	Key KeyFunc
+}
+
+// Limit returns an http.Handler which applies the limits specified by cfg
+// before calling h.  If cfg is nil, or specifies no limits, h is returned.
+//
+// Quotas are checked before the Limiter is acquired, so requests which exceed
+// their quota never wait for a render to complete.
+func Limit(h http.Handler, cfg *LimitConfig) http.Handler {
+	if cfg == nil || (cfg.Limiter == nil && cfg.Quota == nil) {
+		return h
+	}
+
+	key := cfg.Key
+	if key == nil {
+		key = RemoteHost
+	}
+
+	return &limitHandler{
+		h:       h,
+		limiter: cfg.Limiter,
+		quota:   cfg.Quota,
+		key:     key,
+	}
+}
+
+// limitHandler is the http.Handler returned by Limit.
+type limitHandler struct {
+	h       http.Handler
+	limiter Limiter
+	quota   Quota
+	key     KeyFunc
+}
+
+// ServeHTTP implements http.Handler.
+func (lh *limitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if lh.quota != nil {
+		if ok, retry := lh.quota.Allow(lh.key(r)); !ok {
+			if retry > 0 {
+				// Retry-After is specified in whole seconds, so round up
+				secs := int64(math.Ceil(retry.Seconds()))
+				w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+			}
+
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	if lh.limiter != nil {
+		release, err := lh.limiter.Acquire(r.Context())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+	}
+
+	lh.h.ServeHTTP(w, r)
+}
+
+// RemoteHost is a KeyFunc which returns the host portion of the remote
+// address of an HTTP request.  It does not inspect proxy headers such as
+// X-Forwarded-For; applications behind a proxy should provide their own
+// KeyFunc.
+func RemoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// A Semaphore is a Limiter which permits a fixed number of renders to run
+// concurrently.
+type Semaphore struct {
+	c chan struct{}
+}
+
+// NewSemaphore creates a Semaphore which permits n concurrent renders.  If n
+// is less than 1, 1 is used.
+func NewSemaphore(n int) *Semaphore {
+	if n < 1 {
+		n = 1
+	}
+
+	return &Semaphore{c: make(chan struct{}, n)}
+}
+
+// Acquire implements Limiter.
+func (s *Semaphore) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case s.c <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-s.c })
+	}, nil
+}
+
+// A WindowQuota is a Quota which permits a fixed number of renders for each
+// key within a fixed window of time.  The window for a key begins with its
+// first render, and a new window begins once the previous one has elapsed.
+type WindowQuota struct {
+	n      int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*quotaWindow
+}
+
+// quotaWindow tracks the renders for a single key in a WindowQuota.
+type quotaWindow struct {
+	start time.Time
+	n     int
+}
+
+// NewWindowQuota creates a WindowQuota which permits n renders for each key
+// within each window of time.
+func NewWindowQuota(n int, window time.Duration) *WindowQuota {
+	return &WindowQuota{
+		n:       n,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*quotaWindow),
+	}
+}
+
+// Allow implements Quota.
+func (q *WindowQuota) Allow(key string) (bool, time.Duration) {
+	now := q.now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w, ok := q.windows[key]
+	if !ok || now.Sub(w.start) >= q.window {
+		// Discard expired windows so that keys which are no longer in use
+		// do not accumulate
+		if !ok {
+			q.prune(now)
+		}
+
+		w = &quotaWindow{start: now}
+		q.windows[key] = w
+	}
+
+	if w.n >= q.n {
+		return false, w.start.Add(q.window).Sub(now)
+	}
+
+	w.n++
+	return true, 0
+}
+
+// prune removes all expired windows from q.  q.mu must be held.
+func (q *WindowQuota) prune(now time.Time) {
+	for k, w := range q.windows {
+		if now.Sub(w.start) >= q.window {
+			delete(q.windows, k)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package waveformhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestLimitNoLimits verifies that Limit returns the input handler when no
+// limits are configured.
+func TestLimitNoLimits(t *testing.T) {
+	h := http.NewServeMux()
+
+	for _, cfg := range []*LimitConfig{nil, {}} {
+		if got := Limit(h, cfg); got != http.Handler(h) {
+			t.Fatalf("unexpected handler for %+v: %v", cfg, got)
+		}
+	}
+}
+
+// TestLimitQuota verifies that requests which exceed their quota receive
+// HTTP 429 with a Retry-After header, and that quotas are tracked per key.
+func TestLimitQuota(t *testing.T) {
+	q := NewWindowQuota(2, 1500*time.Millisecond)
+	now := time.Unix(0, 0)
+	q.now = func() time.Time { return now }
+
+	h := Limit(okHandler(), &LimitConfig{
+		Quota: q,
+		Key:   func(r *http.Request) string { return r.Header.Get("X-Tenant") },
+	})
+
+	var tests = []struct {
+		tenant string
+		code   int
+		retry  string
+	}{
+		{tenant: "a", code: http.StatusOK},
+		{tenant: "a", code: http.StatusOK},
+		{tenant: "a", code: http.StatusTooManyRequests, retry: "2"},
+		{tenant: "b", code: http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		code, retry := testLimitRequest(h, tt.tenant)
+		if code != tt.code || retry != tt.retry {
+			t.Fatalf("[%02d] unexpected response: %d, %q != %d, %q",
+				i, code, retry, tt.code, tt.retry)
+		}
+	}
+
+	// A new window begins once the previous window has elapsed
+	now = now.Add(1500 * time.Millisecond)
+	if code, _ := testLimitRequest(h, "a"); code != http.StatusOK {
+		t.Fatalf("unexpected status code after window: %d", code)
+	}
+}
+
+// TestLimitLimiter verifies that requests which cannot acquire the Limiter
+// receive HTTP 503, and that the Limiter is released after each request.
+func TestLimitLimiter(t *testing.T) {
+	s := NewSemaphore(1)
+
+	h := Limit(okHandler(), &LimitConfig{Limiter: s})
+
+	// The Limiter is released after each request
+	for i := 0; i < 3; i++ {
+		if code, _ := testLimitRequest(h, ""); code != http.StatusOK {
+			t.Fatalf("[%02d] unexpected status code: %d", i, code)
+		}
+	}
+
+	// Hold the only slot, so the next request waits until its context is
+	// canceled
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
+
+// TestSemaphoreReleaseOnce verifies that calling a Semaphore's release
+// function more than once does not release additional slots.
+func TestSemaphoreReleaseOnce(t *testing.T) {
+	s := NewSemaphore(1)
+
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release()
+
+	if _, err := s.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.Acquire(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+}
+
+// TestWindowQuotaPrune verifies that a WindowQuota discards expired windows.
+func TestWindowQuotaPrune(t *testing.T) {
+	q := NewWindowQuota(1, time.Second)
+	now := time.Unix(0, 0)
+	q.now = func() time.Time { return now }
+
+	q.Allow("a")
+	now = now.Add(time.Second)
+	q.Allow("b")
+
+	if _, ok := q.windows["a"]; ok || len(q.windows) != 1 {
+		t.Fatalf("unexpected windows: %v", q.windows)
+	}
+}
+
+// TestRemoteHost verifies that RemoteHost returns the host portion of a
+// request's remote address.
+func TestRemoteHost(t *testing.T) {
+	var tests = []struct {
+		addr string
+		want string
+	}{
+		{addr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{addr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{addr: "pipe", want: "pipe"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.addr
+
+		if got := RemoteHost(r); got != tt.want {
+			t.Fatalf("unexpected host for %q: %q != %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+// okHandler returns an http.Handler which always responds with HTTP 200.
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// testLimitRequest performs a request against h for the input tenant, and
+// returns the status code and Retry-After header of the response.
+func testLimitRequest(h http.Handler, tenant string) (int, string) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", tenant)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w.Code, w.Header().Get("Retry-After")
+}
@@ -0,0 +1,136 @@
+package waveformhttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// signatureParam is the query parameter which holds a URL's signature.
+	signatureParam = "sig"
+
+	// expiresParam is the query parameter which holds the time at which a
+	// signed URL expires, in seconds since the Unix epoch.
+	expiresParam = "expires"
+)
+
+var (
+	// ErrSignatureMissing is returned when a request URL has no signature.
+	ErrSignatureMissing = errors.New("waveformhttp: URL signature missing")
+
+	// ErrSignatureInvalid is returned when a request URL's signature does not
+	// match its path and query parameters.
+	ErrSignatureInvalid = errors.New("waveformhttp: URL signature invalid")
+
+	// ErrSignatureExpired is returned when a request URL's signature is valid,
+	// but its expiry time has passed.
+	ErrSignatureExpired = errors.New("waveformhttp: URL signature expired")
+)
+
+// A Signer signs and verifies URLs using HMAC-SHA256, so that only URLs
+// generated by an application, with the image sizes, styles, and sources it
+// approves, can trigger renders.
+//
+// A signature covers the URL's path and all of its query parameters, so a
+// client cannot alter any rendering parameter without invalidating it.
+type Signer struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewSigner creates a Signer which uses the input secret key.  The key should
+// be at least 32 bytes of random data, and must be kept private.
+func NewSigner(key []byte) *Signer {
+	return &Signer{
+		key: append([]byte(nil), key...),
+		now: time.Now,
+	}
+}
+
+// Sign returns a copy of u with a signature added to its query parameters.
+// If expires is not the zero time, the signature is only valid until that
+// time.  Any existing signature or expiry time in u is replaced.
+func (s *Signer) Sign(u *url.URL, expires time.Time) *url.URL {
+	q := u.Query()
+	q.Del(signatureParam)
+	q.Del(expiresParam)
+
+	if !expires.IsZero() {
+		q.Set(expiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+
+	q.Set(signatureParam, s.signature(u.Path, q))
+
+	su := *u
+	su.RawQuery = q.Encode()
+	return &su
+}
+
+// Verify verifies the signature and expiry time of u, returning
+// ErrSignatureMissing, ErrSignatureInvalid, or ErrSignatureExpired if u may
+// not trigger a render.
+func (s *Signer) Verify(u *url.URL) error {
+	q := u.Query()
+
+	sig := q.Get(signatureParam)
+	if sig == "" {
+		return ErrSignatureMissing
+	}
+	q.Del(signatureParam)
+
+	if !hmac.Equal([]byte(sig), []byte(s.signature(u.Path, q))) {
+		return ErrSignatureInvalid
+	}
+
+	// The expiry time is covered by the signature, so it is only checked
+	// once the signature is known to be valid
+	if exp := q.Get(expiresParam); exp != "" {
+		secs, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return ErrSignatureInvalid
+		}
+
+		if !s.now().Before(time.Unix(secs, 0)) {
+			return ErrSignatureExpired
+		}
+	}
+
+	return nil
+}
+
+// signature computes the signature of a URL path and its query parameters,
+// excluding any existing signature.
+func (s *Signer) signature(path string, q url.Values) string {
+	// url.Values.Encode sorts parameters by key, so the message is the same
+	// regardless of the order in which parameters appear in the URL
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write([]byte(path))
+	_, _ = mac.Write([]byte{'?'})
+	_, _ = mac.Write([]byte(q.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RequireSignature returns an http.Handler which verifies the URL of each
+// request using s before calling h.  Requests without a valid, unexpired
+// signature receive HTTP 403 Forbidden.
+//
+// RequireSignature should wrap the handlers returned by Conditional and
+// Limit, so that unsigned requests are rejected before any other work is
+// done.
+func RequireSignature(h http.Handler, s *Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Verify(r.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,150 @@
+package waveformhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestSignerVerify verifies that a Signer accepts URLs it signed, and rejects
+// URLs which are unsigned, altered, signed with another key, or expired.
+func TestSignerVerify(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	s := NewSigner([]byte("secret"))
+	s.now = func() time.Time { return now }
+
+	u := mustParseURL(t, "/waveform/song.wav?width=800&height=128")
+	signed := s.Sign(u, time.Time{})
+	expiring := s.Sign(u, now.Add(time.Minute))
+
+	var tests = []struct {
+		name string
+		u    *url.URL
+		err  error
+	}{
+		{name: "signed", u: signed},
+		{name: "expiring", u: expiring},
+		{name: "reordered", u: reorder(signed)},
+		{name: "unsigned", u: u, err: ErrSignatureMissing},
+		{name: "altered query", u: setQuery(signed, "width", "8000"), err: ErrSignatureInvalid},
+		{name: "added query", u: setQuery(signed, "style", "loud"), err: ErrSignatureInvalid},
+		{name: "altered expiry", u: setQuery(expiring, "expires", "99999"), err: ErrSignatureInvalid},
+		{name: "removed expiry", u: setQuery(expiring, "expires", ""), err: ErrSignatureInvalid},
+		{name: "altered path", u: setPath(signed, "/waveform/other.wav"), err: ErrSignatureInvalid},
+		{name: "other key", u: NewSigner([]byte("other")).Sign(u, time.Time{}), err: ErrSignatureInvalid},
+		{name: "expired", u: s.Sign(u, now), err: ErrSignatureExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Verify(tt.u); err != tt.err {
+				t.Fatalf("unexpected error for %s: %v != %v", tt.u, err, tt.err)
+			}
+		})
+	}
+
+	// The input URL is not modified by Sign
+	if u.RawQuery != "width=800&height=128" {
+		t.Fatalf("input URL modified: %s", u)
+	}
+}
+
+// TestSignerResign verifies that signing a signed URL replaces its existing
+// signature and expiry time.
+func TestSignerResign(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+
+	u := mustParseURL(t, "/waveform/song.wav?width=800")
+	signed := s.Sign(s.Sign(u, time.Now().Add(time.Hour)), time.Time{})
+
+	q := signed.Query()
+	if len(q["sig"]) != 1 || q.Get("expires") != "" {
+		t.Fatalf("unexpected query parameters: %v", q)
+	}
+	if err := s.Verify(signed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRequireSignature verifies that RequireSignature only calls its handler
+// for requests with a valid signature.
+func TestRequireSignature(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	h := RequireSignature(okHandler(), s)
+
+	u := mustParseURL(t, "/waveform/song.wav?width=800")
+
+	var tests = []struct {
+		name   string
+		target string
+		code   int
+	}{
+		{name: "signed", target: s.Sign(u, time.Time{}).String(), code: http.StatusOK},
+		{name: "unsigned", target: u.String(), code: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.code {
+				t.Fatalf("unexpected status code: %d != %d", w.Code, tt.code)
+			}
+		})
+	}
+}
+
+// mustParseURL parses a URL, failing the test if it is invalid.
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+// setQuery returns a copy of u with query parameter key set to value, or
+// removed if value is empty.
+func setQuery(u *url.URL, key, value string) *url.URL {
+	q := u.Query()
+	if value == "" {
+		q.Del(key)
+	} else {
+		q.Set(key, value)
+	}
+
+	cu := *u
+	cu.RawQuery = q.Encode()
+	return &cu
+}
+
+// setPath returns a copy of u with its path replaced.
+func setPath(u *url.URL, path string) *url.URL {
+	cu := *u
+	cu.Path = path
+	return &cu
+}
+
+// reorder returns a copy of u with the order of its query parameters
+// reversed.
+func reorder(u *url.URL) *url.URL {
+	q := u.Query()
+
+	var raw string
+	for k, vs := range q {
+		for _, v := range vs {
+			raw = url.QueryEscape(k) + "=" + url.QueryEscape(v) + "&" + raw
+		}
+	}
+
+	cu := *u
+	cu.RawQuery = raw[:len(raw)-1]
+	return &cu
+}
@@ -0,0 +1,1023 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"azul3d.org/engine/audio"
+)
+
+func init() {
+	// Register a WavPack decoder, since azul3d/engine/audio does not provide
+	// one
+	audio.RegisterFormat("wavpack", "wvpk", newWavPackDecoder)
+}
+
+const (
+	// wavpackHeaderSize is the size of the header of each WavPack block.
+	wavpackHeaderSize = 32
+
+	// maxWavPackBlockSize and maxWavPackBlockSamples are the maximum size
+	// and number of sample frames of a single WavPack block, and
+	// maxWavPackChannels is the maximum number of channels in a frame, which
+	// limit the memory allocated for malformed streams.
+	maxWavPackBlockSize    = 1 << 24
+	maxWavPackBlockSamples = 1 << 18
+	maxWavPackChannels     = 64
+
+	// maxWavPackTerms is the maximum number of decorrelation passes in a
+	// single WavPack block.
+	maxWavPackTerms = 16
+)
+
+// Flags which may be set in the header of a WavPack block.
+const (
+	wavpackBytesStored  = 0x3
+	wavpackMono         = 0x4
+	wavpackHybrid       = 0x8
+	wavpackJointStereo  = 0x10
+	wavpackFloat        = 0x80
+	wavpackInitialBlock = 0x800
+	wavpackFinalBlock   = 0x1000
+	wavpackShiftLSB     = 13
+	wavpackShiftMask    = 0x1f << wavpackShiftLSB
+	wavpackRateLSB      = 23
+	wavpackRateMask     = 0xf << wavpackRateLSB
+	wavpackFalseStereo  = 0x40000000
+	wavpackDSD          = 0x80000000
+
+	// wavpackMonoData is set when a block contains a single channel of audio,
+	// even if it is output as two identical channels
+	wavpackMonoData = wavpackMono | wavpackFalseStereo
+)
+
+// Identifiers of the metadata sub-blocks of a WavPack block.
+const (
+	wavpackIDMask       = 0x3f
+	wavpackIDOdd        = 0x40
+	wavpackIDLarge      = 0x80
+	wavpackIDTerms      = 0x2
+	wavpackIDWeights    = 0x3
+	wavpackIDSamples    = 0x4
+	wavpackIDEntropy    = 0x5
+	wavpackIDInt32Info  = 0x9
+	wavpackIDBitstream  = 0xa
+	wavpackIDSampleRate = 0x27
+)
+
+// wavpackSampleRates are the standard sample rates which may be indicated by
+// the flags of a WavPack block.  Any other sample rate is stored in a metadata
+// sub-block.
+var wavpackSampleRates = [...]int{
+	6000, 8000, 9600, 11025, 12000, 16000, 22050, 24000,
+	32000, 44100, 48000, 64000, 88200, 96000, 192000,
+}
+
+// wavpackExp2Table is used to compute the fractional part of 2 raised to a
+// fixed-point power, as stored for values in WavPack metadata.
+var wavpackExp2Table = [256]uint8{
+	0x00, 0x01, 0x01, 0x02, 0x03, 0x03, 0x04, 0x05, 0x06, 0x06, 0x07, 0x08, 0x08, 0x09, 0x0a, 0x0b,
+	0x0b, 0x0c, 0x0d, 0x0e, 0x0e, 0x0f, 0x10, 0x10, 0x11, 0x12, 0x13, 0x13, 0x14, 0x15, 0x16, 0x16,
+	0x17, 0x18, 0x19, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1d, 0x1e, 0x1f, 0x20, 0x20, 0x21, 0x22, 0x23,
+	0x24, 0x24, 0x25, 0x26, 0x27, 0x28, 0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2c, 0x2d, 0x2e, 0x2f, 0x30,
+	0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x3a, 0x3b, 0x3c, 0x3d,
+	0x3e, 0x3f, 0x40, 0x41, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x48, 0x49, 0x4a, 0x4b,
+	0x4c, 0x4d, 0x4e, 0x4f, 0x50, 0x51, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5a,
+	0x5b, 0x5c, 0x5d, 0x5e, 0x5e, 0x5f, 0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+	0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+	0x7a, 0x7b, 0x7c, 0x7d, 0x7e, 0x7f, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x87, 0x88, 0x89, 0x8a,
+	0x8b, 0x8c, 0x8d, 0x8e, 0x8f, 0x90, 0x91, 0x92, 0x93, 0x95, 0x96, 0x97, 0x98, 0x99, 0x9a, 0x9b,
+	0x9c, 0x9d, 0x9f, 0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa8, 0xa9, 0xaa, 0xab, 0xac, 0xad,
+	0xaf, 0xb0, 0xb1, 0xb2, 0xb3, 0xb4, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xbc, 0xbd, 0xbe, 0xbf, 0xc0,
+	0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc8, 0xc9, 0xca, 0xcb, 0xcd, 0xce, 0xcf, 0xd0, 0xd2, 0xd3, 0xd4,
+	0xd6, 0xd7, 0xd8, 0xd9, 0xdb, 0xdc, 0xdd, 0xde, 0xe0, 0xe1, 0xe2, 0xe4, 0xe5, 0xe6, 0xe8, 0xe9,
+	0xea, 0xec, 0xed, 0xee, 0xf0, 0xf1, 0xf2, 0xf4, 0xf5, 0xf6, 0xf8, 0xf9, 0xfa, 0xfc, 0xfd, 0xff,
+}
+
+// wavpackHeader is the header of a single WavPack block.
+type wavpackHeader struct {
+	size    int
+	samples int
+	flags   uint32
+}
+
+// wavpackDecoder is an audio.Decoder which decodes lossless WavPack audio,
+// such as a .wv file.
+//
+// Streams which use features that are not supported by this decoder, such as
+// hybrid (lossy) compression, floating point audio, or DSD audio, produce
+// audio.ErrFormat, so that they may be decoded by a FallbackDecoder.
+type wavpackDecoder struct {
+	r io.Reader
+
+	sampleRate int
+	channels   int
+
+	// header and data are reused to receive each block from the stream
+	header [wavpackHeaderSize]byte
+	data   []byte
+
+	// residuals and channel buffers used while decoding each block
+	buf    []int32
+	chans  [][]int32
+	scales []float64
+
+	// out holds decoded, interleaved samples which have not yet been read
+	out  []float64
+	outN int
+}
+
+// newWavPackDecoder creates an audio.Decoder from a WavPack stream.  The
+// first frame of the stream is decoded to determine its configuration.
+func newWavPackDecoder(r io.Reader) (audio.Decoder, error) {
+	d := &wavpackDecoder{r: r}
+	if err := d.decodeFrame(); err != nil {
+		if err == audio.EOS {
+			return nil, audio.ErrUnexpectedEOS
+		}
+
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the WavPack stream.
+func (d *wavpackDecoder) Config() audio.Config {
+	return audio.Config{
+		SampleRate: d.sampleRate,
+		Channels:   d.channels,
+	}
+}
+
+// Read decodes interleaved audio samples from the WavPack stream into b,
+// returning audio.EOS when the stream is exhausted.
+func (d *wavpackDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next frame once all samples from the last are read
+		if d.outN == len(d.out) {
+			if err := d.decodeFrame(); err != nil {
+				if err == audio.EOS && n > 0 {
+					return n, nil
+				}
+
+				return n, err
+			}
+		}
+
+		for ; n < b.Len() && d.outN < len(d.out); n++ {
+			b.Set(n, d.out[d.outN])
+			d.outN++
+		}
+	}
+
+	return n, nil
+}
+
+// decodeFrame reads and decodes the blocks of the next frame of the stream,
+// beginning with an initial block and ending with a final block.  Each block
+// contains one or two channels of the frame.
+func (d *wavpackDecoder) decodeFrame() error {
+	d.chans = d.chans[:0]
+	d.scales = d.scales[:0]
+
+	var (
+		samples    int
+		sampleRate int
+	)
+
+	for {
+		h, err := d.readBlock()
+		if err != nil {
+			if err == audio.EOS && len(d.chans) > 0 {
+				return audio.ErrUnexpectedEOS
+			}
+
+			return err
+		}
+
+		// Blocks without samples contain only metadata
+		if h.samples == 0 {
+			continue
+		}
+
+		initial := h.flags&wavpackInitialBlock != 0
+		if initial != (len(d.chans) == 0) {
+			return audio.ErrInvalidData
+		}
+		if initial {
+			samples = h.samples
+		} else if h.samples != samples {
+			return audio.ErrInvalidData
+		}
+
+		rate, err := d.decodeBlock(h)
+		if err != nil {
+			return err
+		}
+		if initial {
+			sampleRate = rate
+		}
+
+		if h.flags&wavpackFinalBlock != 0 {
+			break
+		}
+	}
+
+	// The configuration of the stream is determined by its first frame, and
+	// must not change
+	switch {
+	case d.channels == 0:
+		d.sampleRate, d.channels = sampleRate, len(d.chans)
+	case len(d.chans) != d.channels:
+		return audio.ErrInvalidData
+	}
+
+	// Interleave the channels of each block
+	d.out = d.out[:0]
+	d.outN = 0
+	for i := 0; i < samples; i++ {
+		for ch, c := range d.chans {
+			d.out = append(d.out, float64(c[i])/d.scales[ch])
+		}
+	}
+
+	return nil
+}
+
+// readBlock reads the header and data of the next block of the stream.  Data
+// which follows the final block, such as an APEv2 or ID3v1 tag, ends the
+// stream.
+func (d *wavpackDecoder) readBlock() (wavpackHeader, error) {
+	n, err := io.ReadFull(d.r, d.header[:])
+	switch {
+	case n == 0 && err == io.EOF:
+		return wavpackHeader{}, audio.EOS
+	case n >= 4 && string(d.header[0:4]) != "wvpk":
+		return wavpackHeader{}, audio.EOS
+	case err != nil:
+		return wavpackHeader{}, audio.ErrUnexpectedEOS
+	}
+
+	b := d.header[:]
+	size := binary.LittleEndian.Uint32(b[4:8])
+	version := binary.LittleEndian.Uint16(b[8:10])
+
+	h := wavpackHeader{
+		samples: int(binary.LittleEndian.Uint32(b[20:24])),
+		flags:   binary.LittleEndian.Uint32(b[24:28]),
+	}
+
+	// The block size excludes the identifier and size fields of the header
+	if size < wavpackHeaderSize-8 || size > maxWavPackBlockSize {
+		return wavpackHeader{}, audio.ErrInvalidData
+	}
+	h.size = int(size) - (wavpackHeaderSize - 8)
+
+	if version < 0x402 || version > 0x410 {
+		return wavpackHeader{}, audio.ErrFormat
+	}
+	if h.samples < 0 || h.samples > maxWavPackBlockSamples {
+		return wavpackHeader{}, audio.ErrInvalidData
+	}
+
+	if cap(d.data) < h.size {
+		d.data = make([]byte, h.size)
+	}
+	d.data = d.data[:h.size]
+
+	if _, err := io.ReadFull(d.r, d.data); err != nil {
+		return wavpackHeader{}, audio.ErrUnexpectedEOS
+	}
+
+	return h, nil
+}
+
+// wavpackDecorr is the state of a single decorrelation pass of a WavPack
+// block.  Passes with positive terms predict each sample from previous
+// samples of the same channel, and passes with negative terms predict each
+// sample from the other channel.
+type wavpackDecorr struct {
+	term             int
+	delta            int32
+	weightA, weightB int32
+	samplesA         [8]int32
+	samplesB         [8]int32
+}
+
+// wavpackBlock is the decoding state of a single WavPack block.
+type wavpackBlock struct {
+	flags      uint32
+	mono       bool
+	sampleRate int
+
+	terms     []wavpackDecorr
+	words     wavpackWords
+	bitstream []byte
+
+	// Transformation of integer samples, as described by int32 info
+	sentBits, shift uint
+	and, or         int32
+}
+
+// decodeBlock decodes the audio samples of a block into one or two channel
+// buffers, returning the sample rate of the block.
+func (d *wavpackDecoder) decodeBlock(h wavpackHeader) (int, error) {
+	if h.flags&(wavpackHybrid|wavpackFloat|wavpackDSD) != 0 {
+		return 0, audio.ErrFormat
+	}
+
+	blk := &wavpackBlock{
+		flags: h.flags,
+		mono:  h.flags&wavpackMonoData != 0,
+	}
+	if err := blk.parseMetadata(d.data); err != nil {
+		return 0, err
+	}
+	if blk.bitstream == nil {
+		return 0, audio.ErrInvalidData
+	}
+
+	// Determine the sample rate, which is only stored in metadata if it is
+	// not a standard rate
+	sampleRate := blk.sampleRate
+	if i := int(h.flags&wavpackRateMask) >> wavpackRateLSB; i < len(wavpackSampleRates) {
+		sampleRate = wavpackSampleRates[i]
+	}
+	if sampleRate <= 0 {
+		return 0, audio.ErrInvalidData
+	}
+
+	// Decode residuals, which are interleaved when a block contains two
+	// channels
+	n := h.samples
+	if !blk.mono {
+		n *= 2
+	}
+	if cap(d.buf) < n {
+		d.buf = make([]int32, n)
+	}
+	buf := d.buf[:n]
+
+	br := &wavpackBitReader{b: blk.bitstream}
+	for i := range buf {
+		ch := 0
+		if !blk.mono {
+			ch = i % 2
+		}
+
+		v, err := blk.words.value(br, ch)
+		if err != nil {
+			return 0, err
+		}
+		buf[i] = v
+	}
+
+	for i := range blk.terms {
+		if blk.mono {
+			blk.terms[i].decorrelateMono(buf)
+		} else {
+			blk.terms[i].decorrelateStereo(buf)
+		}
+	}
+
+	if !blk.mono && h.flags&wavpackJointStereo != 0 {
+		for i := 0; i < len(buf); i += 2 {
+			buf[i+1] -= buf[i] >> 1
+			buf[i] += buf[i+1]
+		}
+	}
+
+	// Restore bits which were removed from each sample before compression,
+	// and scale samples by the number of bytes stored per sample
+	shift := uint(h.flags&wavpackShiftMask) >> wavpackShiftLSB
+	for i, v := range buf {
+		v <<= blk.sentBits
+		bit := (v & blk.and) | blk.or
+		buf[i] = (((v + bit) << blk.shift) - bit) << shift
+	}
+	scale := float64(uint64(1) << (8*(h.flags&wavpackBytesStored+1) - 1))
+
+	// Store each channel, duplicating a single channel if the block is
+	// output as two identical channels
+	out := 2
+	if h.flags&wavpackMono != 0 {
+		out = 1
+	}
+	if len(d.chans)+out > maxWavPackChannels {
+		return 0, audio.ErrInvalidData
+	}
+
+	for ch := 0; ch < out; ch++ {
+		c := d.channel(h.samples)
+		for i := range c {
+			if blk.mono {
+				c[i] = buf[i]
+			} else {
+				c[i] = buf[2*i+ch]
+			}
+		}
+
+		d.scales = append(d.scales, scale)
+	}
+
+	return sampleRate, nil
+}
+
+// channel returns a buffer for the next channel of a frame, which holds the
+// input number of samples.
+func (d *wavpackDecoder) channel(samples int) []int32 {
+	n := len(d.chans)
+	if n < cap(d.chans) {
+		d.chans = d.chans[:n+1]
+	} else {
+		d.chans = append(d.chans, nil)
+	}
+
+	if cap(d.chans[n]) < samples {
+		d.chans[n] = make([]int32, samples)
+	}
+	d.chans[n] = d.chans[n][:samples]
+
+	return d.chans[n]
+}
+
+// parseMetadata parses the metadata sub-blocks of a WavPack block, which
+// contain the decoding parameters and compressed audio of the block.
+func (blk *wavpackBlock) parseMetadata(b []byte) error {
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return audio.ErrInvalidData
+		}
+
+		id := b[0]
+		size := int(b[1])
+		b = b[2:]
+		if id&wavpackIDLarge != 0 {
+			if len(b) < 2 {
+				return audio.ErrInvalidData
+			}
+			size |= int(b[0])<<8 | int(b[1])<<16
+			b = b[2:]
+		}
+
+		// Sizes are stored in 16-bit words, and sub-blocks of an odd size
+		// are padded
+		size *= 2
+		if len(b) < size {
+			return audio.ErrInvalidData
+		}
+		data := b[:size]
+		b = b[size:]
+		if id&wavpackIDOdd != 0 {
+			if size == 0 {
+				return audio.ErrInvalidData
+			}
+			data = data[:size-1]
+		}
+
+		var err error
+		switch id & wavpackIDMask {
+		case wavpackIDTerms:
+			err = blk.parseTerms(data)
+		case wavpackIDWeights:
+			err = blk.parseWeights(data)
+		case wavpackIDSamples:
+			err = blk.parseSamples(data)
+		case wavpackIDEntropy:
+			err = blk.parseEntropy(data)
+		case wavpackIDInt32Info:
+			err = blk.parseInt32Info(data)
+		case wavpackIDBitstream:
+			blk.bitstream = data
+		case wavpackIDSampleRate:
+			if len(data) < 3 {
+				return audio.ErrInvalidData
+			}
+			blk.sampleRate = int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseTerms parses the terms and deltas of each decorrelation pass.  Passes
+// are stored in reverse order.
+func (blk *wavpackBlock) parseTerms(b []byte) error {
+	if len(b) > maxWavPackTerms {
+		return audio.ErrInvalidData
+	}
+
+	blk.terms = make([]wavpackDecorr, len(b))
+	for i, v := range b {
+		p := &blk.terms[len(b)-1-i]
+		p.term = int(v&0x1f) - 5
+		p.delta = int32(v>>5) & 0x7
+
+		switch {
+		case p.term >= 1 && p.term <= 8, p.term == 17, p.term == 18:
+		case p.term >= -3 && p.term <= -1 && !blk.mono:
+		default:
+			return audio.ErrInvalidData
+		}
+	}
+
+	return nil
+}
+
+// parseWeights parses the initial weights of each decorrelation pass, which
+// are stored for the last pass first.
+func (blk *wavpackBlock) parseWeights(b []byte) error {
+	n := len(b)
+	if !blk.mono {
+		n /= 2
+	}
+	if n > len(blk.terms) {
+		return audio.ErrInvalidData
+	}
+
+	for i := 0; i < n; i++ {
+		p := &blk.terms[len(blk.terms)-1-i]
+		p.weightA = wavpackWeight(int8(b[0]))
+		b = b[1:]
+		if !blk.mono {
+			p.weightB = wavpackWeight(int8(b[0]))
+			b = b[1:]
+		}
+	}
+
+	return nil
+}
+
+// parseSamples parses the initial sample history of each decorrelation pass,
+// which is stored for the last pass first.
+func (blk *wavpackBlock) parseSamples(b []byte) error {
+	// next reads a single sample from the history
+	var short bool
+	next := func() int32 {
+		if len(b) < 2 {
+			short = true
+			return 0
+		}
+
+		v := wavpackExp2(int16(binary.LittleEndian.Uint16(b)))
+		b = b[2:]
+		return v
+	}
+
+	for i := len(blk.terms) - 1; i >= 0 && len(b) > 0; i-- {
+		p := &blk.terms[i]
+		switch {
+		case p.term > 8:
+			p.samplesA[0], p.samplesA[1] = next(), next()
+			if !blk.mono {
+				p.samplesB[0], p.samplesB[1] = next(), next()
+			}
+		case p.term < 0:
+			p.samplesA[0], p.samplesB[0] = next(), next()
+		default:
+			for j := 0; j < p.term; j++ {
+				p.samplesA[j] = next()
+				if !blk.mono {
+					p.samplesB[j] = next()
+				}
+			}
+		}
+	}
+
+	if short {
+		return audio.ErrInvalidData
+	}
+
+	return nil
+}
+
+// parseEntropy parses the initial medians used to decode residuals for each
+// channel.
+func (blk *wavpackBlock) parseEntropy(b []byte) error {
+	channels := 2
+	if blk.mono {
+		channels = 1
+	}
+	if len(b) != 6*channels {
+		return audio.ErrInvalidData
+	}
+
+	for ch := 0; ch < channels; ch++ {
+		for i := range blk.words.median[ch] {
+			blk.words.median[ch][i] = uint32(wavpackExp2(int16(binary.LittleEndian.Uint16(b))))
+			b = b[2:]
+		}
+	}
+
+	return nil
+}
+
+// parseInt32Info parses the transformation applied to integer samples before
+// compression, such as the removal of low bits which are always zero.
+func (blk *wavpackBlock) parseInt32Info(b []byte) error {
+	if len(b) < 4 {
+		return audio.ErrInvalidData
+	}
+
+	// Bits which are sent in a separate correction stream are not available,
+	// and are treated as zero
+	blk.sentBits = uint(b[0]) & 0x1f
+
+	switch zeros, ones, dups := b[1], b[2], b[3]; {
+	case zeros > 0:
+		blk.shift = uint(zeros) & 0x1f
+	case ones > 0:
+		blk.shift = uint(ones) & 0x1f
+		blk.and, blk.or = 1, 1
+	case dups > 0:
+		blk.shift = uint(dups) & 0x1f
+		blk.and = 1
+	}
+
+	return nil
+}
+
+// decorrelateMono applies a decorrelation pass to the residuals of a block
+// containing a single channel.
+func (p *wavpackDecorr) decorrelateMono(buf []int32) {
+	if p.term > 8 {
+		for i, v := range buf {
+			sam := p.extrapolate(&p.samplesA)
+			p.samplesA[0] = wavpackApplyWeight(p.weightA, sam) + v
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, sam, v)
+			buf[i] = p.samplesA[0]
+		}
+
+		return
+	}
+
+	m, k := 0, p.term&7
+	for i, v := range buf {
+		sam := p.samplesA[m]
+		p.samplesA[k] = wavpackApplyWeight(p.weightA, sam) + v
+		p.weightA = wavpackUpdateWeight(p.weightA, p.delta, sam, v)
+		buf[i] = p.samplesA[k]
+
+		m, k = (m+1)&7, (k+1)&7
+	}
+}
+
+// decorrelateStereo applies a decorrelation pass to the interleaved residuals
+// of a block containing two channels.
+func (p *wavpackDecorr) decorrelateStereo(buf []int32) {
+	switch {
+	case p.term > 8:
+		for i := 0; i < len(buf); i += 2 {
+			samA := p.extrapolate(&p.samplesA)
+			p.samplesA[0] = wavpackApplyWeight(p.weightA, samA) + buf[i]
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, samA, buf[i])
+			buf[i] = p.samplesA[0]
+
+			samB := p.extrapolate(&p.samplesB)
+			p.samplesB[0] = wavpackApplyWeight(p.weightB, samB) + buf[i+1]
+			p.weightB = wavpackUpdateWeight(p.weightB, p.delta, samB, buf[i+1])
+			buf[i+1] = p.samplesB[0]
+		}
+	case p.term > 0:
+		m, k := 0, p.term&7
+		for i := 0; i < len(buf); i += 2 {
+			samA, samB := p.samplesA[m], p.samplesB[m]
+
+			p.samplesA[k] = wavpackApplyWeight(p.weightA, samA) + buf[i]
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, samA, buf[i])
+			buf[i] = p.samplesA[k]
+
+			p.samplesB[k] = wavpackApplyWeight(p.weightB, samB) + buf[i+1]
+			p.weightB = wavpackUpdateWeight(p.weightB, p.delta, samB, buf[i+1])
+			buf[i+1] = p.samplesB[k]
+
+			m, k = (m+1)&7, (k+1)&7
+		}
+	case p.term == -1:
+		// The left channel is predicted from the previous right channel, and
+		// the right channel from the current left channel
+		for i := 0; i < len(buf); i += 2 {
+			l := wavpackApplyWeight(p.weightA, p.samplesA[0]) + buf[i]
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, p.samplesA[0], buf[i])
+			buf[i] = l
+
+			p.samplesA[0] = wavpackApplyWeight(p.weightB, l) + buf[i+1]
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, l, buf[i+1])
+			buf[i+1] = p.samplesA[0]
+		}
+	case p.term == -2:
+		// The right channel is predicted from the previous left channel, and
+		// the left channel from the current right channel
+		for i := 0; i < len(buf); i += 2 {
+			r := wavpackApplyWeight(p.weightB, p.samplesB[0]) + buf[i+1]
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, p.samplesB[0], buf[i+1])
+			buf[i+1] = r
+
+			p.samplesB[0] = wavpackApplyWeight(p.weightA, r) + buf[i]
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, r, buf[i])
+			buf[i] = p.samplesB[0]
+		}
+	case p.term == -3:
+		// Each channel is predicted from the previous sample of the other
+		for i := 0; i < len(buf); i += 2 {
+			l := wavpackApplyWeight(p.weightA, p.samplesA[0]) + buf[i]
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, p.samplesA[0], buf[i])
+
+			r := wavpackApplyWeight(p.weightB, p.samplesB[0]) + buf[i+1]
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, p.samplesB[0], buf[i+1])
+
+			buf[i], p.samplesB[0] = l, l
+			buf[i+1], p.samplesA[0] = r, r
+		}
+	}
+}
+
+// extrapolate predicts the next sample of a channel from its previous two
+// samples, for passes with terms 17 and 18, and shifts the sample history.
+func (p *wavpackDecorr) extrapolate(samples *[8]int32) int32 {
+	var sam int32
+	if p.term == 17 {
+		sam = 2*samples[0] - samples[1]
+	} else {
+		sam = (3*samples[0] - samples[1]) >> 1
+	}
+	samples[1] = samples[0]
+
+	return sam
+}
+
+// wavpackApplyWeight applies a fixed-point decorrelation weight to a sample.
+func wavpackApplyWeight(weight int32, sample int32) int32 {
+	return int32((int64(weight)*int64(sample) + 512) >> 10)
+}
+
+// wavpackUpdateWeight adapts a decorrelation weight toward the correlation of
+// a predicting sample and a residual.
+func wavpackUpdateWeight(weight int32, delta int32, source int32, result int32) int32 {
+	if source == 0 || result == 0 {
+		return weight
+	}
+	if (source ^ result) < 0 {
+		return weight - delta
+	}
+
+	return weight + delta
+}
+
+// wavpackUpdateWeightClip adapts a decorrelation weight in the same way as
+// wavpackUpdateWeight, but limits its magnitude to 1024.
+func wavpackUpdateWeightClip(weight int32, delta int32, source int32, result int32) int32 {
+	if source == 0 || result == 0 {
+		return weight
+	}
+	if (source ^ result) < 0 {
+		if weight -= delta; weight < -1024 {
+			weight = -1024
+		}
+		return weight
+	}
+
+	if weight += delta; weight > 1024 {
+		weight = 1024
+	}
+	return weight
+}
+
+// wavpackWeight restores a decorrelation weight from its stored 8-bit form.
+func wavpackWeight(v int8) int32 {
+	w := int32(v) * 8
+	if w > 0 {
+		w += (w + 64) >> 7
+	}
+
+	return w
+}
+
+// wavpackExp2 computes 2 raised to a signed fixed-point power with 8 fractional
+// bits, as stored for values in WavPack metadata.
+func wavpackExp2(log int16) int32 {
+	// Compute the magnitude, so that the most negative power does not overflow
+	l := int32(log)
+	neg := l < 0
+	if neg {
+		l = -l
+	}
+
+	v := int32(wavpackExp2Table[l&0xff]) | 0x100
+	if e := uint(l >> 8); e <= 9 {
+		v >>= 9 - e
+	} else {
+		v <<= (e - 9) & 0x1f
+	}
+
+	if neg {
+		return -v
+	}
+
+	return v
+}
+
+// wavpackWords is the state of the adaptive entropy decoder which decodes the
+// residuals of a WavPack block.  Residuals are coded relative to running
+// medians for each channel, with runs of zeros coded separately.
+type wavpackWords struct {
+	median [2][3]uint32
+
+	holdingZero bool
+	holdingOne  bool
+	zeros       uint32
+}
+
+// value decodes the next residual of the input channel.
+func (w *wavpackWords) value(br *wavpackBitReader, ch int) (int32, error) {
+	c := &w.median[ch]
+
+	// When both channels are quiet, runs of zeros are coded by their length
+	if !w.holdingZero && !w.holdingOne && w.median[0][0] < 2 && w.median[1][0] < 2 {
+		if w.zeros > 0 {
+			w.zeros--
+			if w.zeros > 0 {
+				return 0, nil
+			}
+		} else {
+			n, err := br.escape()
+			if err != nil {
+				return 0, err
+			}
+
+			w.zeros = n
+			if w.zeros > 0 {
+				w.median = [2][3]uint32{}
+				return 0, nil
+			}
+		}
+	}
+
+	// Determine the number of median steps in the residual.  Each count also
+	// codes whether the next residual is at least the first median, in which
+	// case the count is "held" and adjusted for the next residual.
+	var ones uint32
+	if w.holdingZero {
+		w.holdingZero = false
+	} else {
+		n := br.unary(17)
+		switch {
+		case n == 17:
+			return 0, audio.ErrInvalidData
+		case n == 16:
+			e, err := br.escape()
+			if err != nil {
+				return 0, err
+			}
+			n += e
+		}
+
+		if w.holdingOne {
+			ones = n>>1 + 1
+		} else {
+			ones = n >> 1
+		}
+		w.holdingOne = n&1 != 0
+		w.holdingZero = !w.holdingOne
+	}
+
+	// Determine the range of the residual from the medians, and adapt the
+	// medians toward its magnitude
+	var low, high uint32
+	switch {
+	case ones == 0:
+		high = wavpackMedian(c, 0) - 1
+		wavpackDecMedian(c, 0)
+	case ones == 1:
+		low = wavpackMedian(c, 0)
+		high = low + wavpackMedian(c, 1) - 1
+		wavpackIncMedian(c, 0)
+		wavpackDecMedian(c, 1)
+	case ones == 2:
+		low = wavpackMedian(c, 0) + wavpackMedian(c, 1)
+		high = low + wavpackMedian(c, 2) - 1
+		wavpackIncMedian(c, 0)
+		wavpackIncMedian(c, 1)
+		wavpackDecMedian(c, 2)
+	default:
+		low = wavpackMedian(c, 0) + wavpackMedian(c, 1) + (ones-2)*wavpackMedian(c, 2)
+		high = low + wavpackMedian(c, 2) - 1
+		wavpackIncMedian(c, 0)
+		wavpackIncMedian(c, 1)
+		wavpackIncMedian(c, 2)
+	}
+
+	low &= 0x7fffffff
+	high &= 0x7fffffff
+	if low > high {
+		return 0, audio.ErrInvalidData
+	}
+
+	v := low + br.code(high-low)
+	sign := br.bit()
+	if br.err {
+		return 0, audio.ErrInvalidData
+	}
+
+	if sign == 1 {
+		return ^int32(v), nil
+	}
+
+	return int32(v), nil
+}
+
+// wavpackMedian returns the step size of the input median.
+func wavpackMedian(c *[3]uint32, n uint) uint32 {
+	return c[n]>>4 + 1
+}
+
+// wavpackIncMedian and wavpackDecMedian adapt the input median after a
+// residual which is larger or smaller than it.
+func wavpackIncMedian(c *[3]uint32, n uint) {
+	div := uint32(128) >> n
+	c[n] += ((c[n] + div) / div) * 5
+}
+
+func wavpackDecMedian(c *[3]uint32, n uint) {
+	div := uint32(128) >> n
+	c[n] -= ((c[n] + div - 2) / div) * 2
+}
+
+// wavpackBitReader reads bits from a WavPack bitstream, least significant bit
+// first.  Reads beyond the end of the bitstream set err and return zero bits.
+type wavpackBitReader struct {
+	b   []byte
+	pos int
+	err bool
+}
+
+// bit reads a single bit.
+func (br *wavpackBitReader) bit() uint32 {
+	if br.pos >= 8*len(br.b) {
+		br.err = true
+		return 0
+	}
+
+	v := uint32(br.b[br.pos>>3]>>(uint(br.pos)&7)) & 1
+	br.pos++
+	return v
+}
+
+// read reads an n bit value, least significant bit first.
+func (br *wavpackBitReader) read(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		v |= br.bit() << uint(i)
+	}
+
+	return v
+}
+
+// unary counts consecutive one bits, up to limit, consuming the zero bit which
+// ends them if limit is not reached.
+func (br *wavpackBitReader) unary(limit uint32) uint32 {
+	var n uint32
+	for n < limit && br.bit() == 1 {
+		n++
+	}
+
+	return n
+}
+
+// escape reads a value which is coded as its number of significant bits in
+// unary, followed by all but the most significant bit.
+func (br *wavpackBitReader) escape() (uint32, error) {
+	n := br.unary(33)
+	if n == 33 || br.err {
+		return 0, audio.ErrInvalidData
+	}
+	if n < 2 {
+		return n, nil
+	}
+
+	return br.read(int(n-1)) | 1<<(n-1), nil
+}
+
+// code reads a value in the range [0, max], using fewer bits for smaller
+// values when the range is not a power of two.
+func (br *wavpackBitReader) code(max uint32) uint32 {
+	if max == 0 {
+		return 0
+	}
+
+	n := bits.Len32(max)
+	extras := uint32(1)<<uint(n) - max - 1
+
+	v := br.read(n - 1)
+	if v >= extras {
+		v = v<<1 - extras + br.bit()
+	}
+
+	return v
+}
@@ -0,0 +1,768 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestWavPackDecoder verifies that wavpackDecoder decodes WavPack streams
+// produced by a test encoder, with a variety of channel layouts and encoding
+// parameters.
+func TestWavPackDecoder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := func(n int, amp int32) []int32 {
+		out := make([]int32, n)
+		for i := range out {
+			out[i] = rng.Int31n(2*amp+1) - amp
+		}
+		return out
+	}
+
+	ramp := make([]int32, 300)
+	for i := range ramp {
+		ramp[i] = int32(i*200 - 30000)
+	}
+
+	// Mostly silent audio, which produces runs of zeros
+	quiet := make([]int32, 400)
+	for i := 100; i < 110; i++ {
+		quiet[i] = int32(i - 105)
+	}
+	quiet[399] = 7
+
+	stereo := wavpackTestEncoder{
+		terms: []wavpackTestTerm{
+			{term: 18, delta: 2, weightA: 48, weightB: -20, samplesA: []int16{1000, -900}, samplesB: []int16{-2400, 300}},
+			{term: 8, delta: 2, weightA: 30, weightB: 12},
+			{term: -1, delta: 3, weightA: -10, weightB: 90, samplesA: []int16{2000}, samplesB: []int16{-1500}},
+			{term: -2, delta: 3, weightA: 16, weightB: -16},
+			{term: -3, delta: 1, weightA: 100, weightB: -100, samplesA: []int16{700}, samplesB: []int16{-700}},
+			{term: 17, delta: 2, weightA: 120, weightB: 120},
+			{term: 2, delta: 2, weightA: 60, weightB: -4, samplesA: []int16{1500, 1600}, samplesB: []int16{-1500, -1600}},
+		},
+		joint:   true,
+		medians: [2][3]int16{{2000, 1800, 1700}, {2100, 1900, 1600}},
+	}
+
+	var tests = []struct {
+		name       string
+		sampleRate int
+		bytes      int
+		channels   [][]int32
+		perBlock   int
+		enc        wavpackTestEncoder
+	}{
+		{
+			name:       "mono 16-bit",
+			sampleRate: 44100,
+			bytes:      2,
+			channels:   [][]int32{ramp},
+			perBlock:   128,
+			enc: wavpackTestEncoder{
+				terms: []wavpackTestTerm{
+					{term: 17, delta: 2, weightA: 127, samplesA: []int16{2300, 2200}},
+					{term: 3, delta: 2, weightA: -128, samplesA: []int16{100, -100, 0}},
+					{term: 1, delta: 5, weightA: 1},
+				},
+				medians: [2][3]int16{{1500, 1500, 1500}},
+			},
+		},
+		{
+			name:       "stereo 16-bit",
+			sampleRate: 48000,
+			bytes:      2,
+			channels:   [][]int32{noise(500, 32767), noise(500, 1000)},
+			perBlock:   100,
+			enc:        stereo,
+		},
+		{
+			name:       "stereo 24-bit",
+			sampleRate: 96000,
+			bytes:      3,
+			channels:   [][]int32{noise(200, 1<<23-1), ramp[:200]},
+			perBlock:   1000,
+			enc:        stereo,
+		},
+		{
+			name:       "silence",
+			sampleRate: 8000,
+			bytes:      2,
+			channels:   [][]int32{quiet, make([]int32, len(quiet))},
+			perBlock:   150,
+			enc: wavpackTestEncoder{
+				terms: []wavpackTestTerm{{term: 1, delta: 2}},
+			},
+		},
+		{
+			name:       "shift",
+			sampleRate: 22050,
+			bytes:      2,
+			channels:   [][]int32{wavpackTestShift(noise(100, 2000), 4)},
+			perBlock:   100,
+			enc: wavpackTestEncoder{
+				terms:   []wavpackTestTerm{{term: 1, delta: 2, weightA: 20}},
+				medians: [2][3]int16{{1000, 1000, 1000}},
+				shift:   4,
+			},
+		},
+		{
+			name:       "custom sample rate",
+			sampleRate: 11111,
+			bytes:      2,
+			channels:   [][]int32{noise(50, 100)},
+			perBlock:   50,
+		},
+		{
+			name:       "multichannel",
+			sampleRate: 44100,
+			bytes:      2,
+			channels:   [][]int32{noise(300, 20000), noise(300, 20000), noise(300, 50)},
+			perBlock:   64,
+			enc: wavpackTestEncoder{
+				terms: []wavpackTestTerm{
+					{term: 18, delta: 2, weightA: 40, weightB: 40},
+					{term: 2, delta: 2, weightA: 20, weightB: 20},
+				},
+				joint:   true,
+				medians: [2][3]int16{{2000, 2000, 2000}, {2000, 2000, 2000}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := makeWavPack(tt.sampleRate, tt.bytes, tt.channels, tt.perBlock, tt.enc)
+
+			d, _, err := audio.NewDecoder(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if c := d.Config(); c.SampleRate != tt.sampleRate || c.Channels != len(tt.channels) {
+				t.Fatalf("unexpected config: %+v", c)
+			}
+
+			// Read in small slices to exercise reads which span frames
+			var got []float64
+			buf := make(audio.Float64, 7)
+			for {
+				n, err := d.Read(buf)
+				got = append(got, buf[:n]...)
+				if err == audio.EOS {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			scale := float64(uint64(1) << uint(8*tt.bytes-1))
+			var want []float64
+			for i := range tt.channels[0] {
+				for _, c := range tt.channels {
+					want = append(want, float64(c[i])/scale)
+				}
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("unexpected number of samples: %v != %v", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("[%04d] unexpected sample: %v != %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestWaveformComputeWavPackErrors verifies that malformed or unsupported
+// WavPack streams produce appropriate errors.
+func TestWaveformComputeWavPackErrors(t *testing.T) {
+	valid := makeWavPack(44100, 2, [][]int32{{1, 2, 3, 4}}, 4, wavpackTestEncoder{})
+
+	// Hybrid (lossy) streams are not supported
+	hybrid := append([]byte(nil), valid...)
+	hybrid[24] |= wavpackHybrid
+
+	// Unsupported version
+	version := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint16(version[8:10], 0x300)
+
+	// Block with an invalid decorrelation term
+	badTerm := makeWavPack(44100, 2, [][]int32{{1, 2, 3, 4}}, 4, wavpackTestEncoder{
+		terms: []wavpackTestTerm{{term: 9}},
+	})
+
+	// Mono block with a cross-channel decorrelation term
+	monoCross := makeWavPack(44100, 2, [][]int32{{1, 2, 3, 4}}, 4, wavpackTestEncoder{
+		terms: []wavpackTestTerm{{term: -1}},
+	})
+
+	// Stream which ends in the middle of a frame
+	frames := makeWavPack(44100, 2, [][]int32{{1, 2}, {3, 4}, {5, 6}}, 2, wavpackTestEncoder{})
+	first := int(binary.LittleEndian.Uint32(frames[4:8])) + 8
+
+	var tests = []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{name: "hybrid", b: hybrid, err: ErrFormat},
+		{name: "version", b: version, err: ErrFormat},
+		{name: "truncated header", b: valid[:20], err: ErrUnexpectedEOS},
+		{name: "truncated data", b: valid[:len(valid)-1], err: ErrUnexpectedEOS},
+		{name: "incomplete frame", b: frames[:first], err: ErrUnexpectedEOS},
+		{name: "invalid term", b: badTerm, err: ErrInvalidData},
+		{name: "mono cross-channel term", b: monoCross, err: ErrInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testWaveformCompute(t, bytes.NewReader(tt.b), tt.err, nil, nil)
+		})
+	}
+}
+
+// TestWavPackTrailingTag verifies that data following the final block of a
+// WavPack stream, such as an APEv2 tag, ends the stream.
+func TestWavPackTrailingTag(t *testing.T) {
+	b := makeWavPack(44100, 2, [][]int32{{1, 2, 3, 4}}, 2, wavpackTestEncoder{})
+	b = append(b, []byte("APETAGEX\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")...)
+
+	d, err := newWavPackDecoder(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := make(audio.Float64, 8)
+	if n, err := d.Read(buf); n != 4 || err != nil {
+		t.Fatalf("unexpected read: %v, %v", n, err)
+	}
+	if _, err := d.Read(buf); err != audio.EOS {
+		t.Fatalf("unexpected error: %v != %v", err, audio.EOS)
+	}
+}
+
+// TestWavPackExp2 verifies the computation of fixed-point powers of 2.
+func TestWavPackExp2(t *testing.T) {
+	var tests = []struct {
+		log  int16
+		want int32
+	}{
+		{log: 0, want: 0},
+		{log: 256, want: 1},
+		{log: 9 * 256, want: 256},
+		{log: -9 * 256, want: -256},
+		{log: 9*256 + 128, want: 256 + 106},
+		{log: 20 * 256, want: 1 << 19},
+	}
+
+	for _, tt := range tests {
+		if got := wavpackExp2(tt.log); got != tt.want {
+			t.Fatalf("unexpected value for %d: %d != %d", tt.log, got, tt.want)
+		}
+	}
+}
+
+// wavpackTestShift is a test helper which shifts each sample left by the input
+// number of bits.
+func wavpackTestShift(samples []int32, shift uint) []int32 {
+	for i := range samples {
+		samples[i] <<= shift
+	}
+
+	return samples
+}
+
+// wavpackTestTerm is a decorrelation pass used by wavpackTestEncoder.  Weights
+// are stored in their 8-bit form, and sample history in its logarithmic form.
+type wavpackTestTerm struct {
+	term               int
+	delta              int32
+	weightA, weightB   int8
+	samplesA, samplesB []int16
+}
+
+// wavpackTestEncoder describes the parameters used by makeWavPack to encode
+// each block of a WavPack stream.  Decorrelation passes are listed in the
+// order they are applied by the decoder.
+type wavpackTestEncoder struct {
+	terms   []wavpackTestTerm
+	joint   bool
+	medians [2][3]int16
+	shift   uint
+}
+
+// makeWavPack is a test helper which creates a lossless WavPack stream from
+// the samples of each channel.  Each frame contains the input number of
+// sample frames, in a stereo block for each pair of channels and a mono block
+// for any remaining channel.
+func makeWavPack(sampleRate int, bytesPerSample int, channels [][]int32, perBlock int, enc wavpackTestEncoder) []byte {
+	rate := len(wavpackSampleRates)
+	for i, r := range wavpackSampleRates {
+		if r == sampleRate {
+			rate = i
+		}
+	}
+
+	var out []byte
+	n := len(channels[0])
+	for start := 0; start < n; start += perBlock {
+		end := start + perBlock
+		if end > n {
+			end = n
+		}
+
+		for ch := 0; ch < len(channels); ch += 2 {
+			flags := uint32(bytesPerSample-1) | uint32(rate)<<wavpackRateLSB | uint32(enc.shift)<<wavpackShiftLSB
+			if ch == 0 {
+				flags |= wavpackInitialBlock
+			}
+			if ch+2 >= len(channels) {
+				flags |= wavpackFinalBlock
+			}
+
+			// Interleave the samples of a stereo block
+			var buf []int32
+			if ch+1 < len(channels) {
+				if enc.joint {
+					flags |= wavpackJointStereo
+				}
+				for i := start; i < end; i++ {
+					buf = append(buf, channels[ch][i]>>enc.shift, channels[ch+1][i]>>enc.shift)
+				}
+			} else {
+				flags |= wavpackMono
+				for i := start; i < end; i++ {
+					buf = append(buf, channels[ch][i]>>enc.shift)
+				}
+			}
+
+			data := enc.encodeBlock(buf, flags, sampleRate, rate == len(wavpackSampleRates))
+
+			header := make([]byte, wavpackHeaderSize)
+			copy(header, "wvpk")
+			binary.LittleEndian.PutUint32(header[4:8], uint32(wavpackHeaderSize-8+len(data)))
+			binary.LittleEndian.PutUint16(header[8:10], 0x410)
+			binary.LittleEndian.PutUint32(header[12:16], uint32(n))
+			binary.LittleEndian.PutUint32(header[16:20], uint32(start))
+			binary.LittleEndian.PutUint32(header[20:24], uint32(end-start))
+			binary.LittleEndian.PutUint32(header[24:28], flags)
+
+			out = append(out, header...)
+			out = append(out, data...)
+		}
+	}
+
+	return out
+}
+
+// encodeBlock encodes the metadata and bitstream of a single WavPack block.
+func (enc wavpackTestEncoder) encodeBlock(buf []int32, flags uint32, sampleRate int, customRate bool) []byte {
+	mono := flags&wavpackMono != 0
+
+	if !mono && flags&wavpackJointStereo != 0 {
+		for i := 0; i < len(buf); i += 2 {
+			l := buf[i] - buf[i+1]
+			buf[i], buf[i+1] = l, buf[i+1]+l>>1
+		}
+	}
+
+	// Restore the initial state of each pass from its stored form, and remove
+	// correlation by applying the inverse of each pass in reverse order
+	passes := make([]wavpackDecorr, len(enc.terms))
+	for i, tt := range enc.terms {
+		p := &passes[i]
+		p.term, p.delta = tt.term, tt.delta
+		p.weightA, p.weightB = wavpackWeight(tt.weightA), wavpackWeight(tt.weightB)
+		for j, v := range tt.samplesA {
+			p.samplesA[j] = wavpackExp2(v)
+		}
+		for j, v := range tt.samplesB {
+			p.samplesB[j] = wavpackExp2(v)
+		}
+	}
+	for i := len(passes) - 1; i >= 0; i-- {
+		if mono {
+			passes[i].correlateMono(buf)
+		} else {
+			passes[i].correlateStereo(buf)
+		}
+	}
+
+	var words wavpackWords
+	for ch := range words.median {
+		for i, v := range enc.medians[ch] {
+			words.median[ch][i] = uint32(wavpackExp2(v))
+		}
+	}
+	bw := &wavpackBitWriter{}
+	words.encode(bw, buf, mono)
+
+	// Write each metadata sub-block, with passes stored in reverse order
+	var terms, weights, samples []byte
+	for i := len(enc.terms) - 1; i >= 0; i-- {
+		tt := enc.terms[i]
+		terms = append(terms, byte(tt.term+5)&0x1f|byte(tt.delta)<<5)
+		weights = append(weights, byte(tt.weightA))
+		if !mono {
+			weights = append(weights, byte(tt.weightB))
+		}
+
+		samples = append(samples, tt.history(mono)...)
+	}
+
+	var entropy []byte
+	for ch := 0; ch < 2 && (ch == 0 || !mono); ch++ {
+		for _, v := range enc.medians[ch] {
+			entropy = append(entropy, wavpackTestUint16(v)...)
+		}
+	}
+
+	var data []byte
+	data = append(data, wavpackSubBlock(wavpackIDTerms, terms)...)
+	data = append(data, wavpackSubBlock(wavpackIDWeights, weights)...)
+	data = append(data, wavpackSubBlock(wavpackIDSamples, samples)...)
+	data = append(data, wavpackSubBlock(wavpackIDEntropy, entropy)...)
+	if customRate {
+		data = append(data, wavpackSubBlock(wavpackIDSampleRate, []byte{
+			byte(sampleRate), byte(sampleRate >> 8), byte(sampleRate >> 16),
+		})...)
+	}
+
+	return append(data, wavpackSubBlock(wavpackIDBitstream, bw.b)...)
+}
+
+// history encodes the initial sample history of a pass, in the order it is
+// read by parseSamples.  Samples which are not specified are zero.
+func (tt wavpackTestTerm) history(mono bool) []byte {
+	sample := func(s []int16, i int) []byte {
+		if i < len(s) {
+			return wavpackTestUint16(s[i])
+		}
+		return wavpackTestUint16(0)
+	}
+
+	var b []byte
+	switch {
+	case tt.term > 8:
+		b = append(b, sample(tt.samplesA, 0)...)
+		b = append(b, sample(tt.samplesA, 1)...)
+		if !mono {
+			b = append(b, sample(tt.samplesB, 0)...)
+			b = append(b, sample(tt.samplesB, 1)...)
+		}
+	case tt.term < 0:
+		b = append(b, sample(tt.samplesA, 0)...)
+		b = append(b, sample(tt.samplesB, 0)...)
+	default:
+		for i := 0; i < tt.term; i++ {
+			b = append(b, sample(tt.samplesA, i)...)
+			if !mono {
+				b = append(b, sample(tt.samplesB, i)...)
+			}
+		}
+	}
+
+	return b
+}
+
+// wavpackTestUint16 encodes a little-endian 16-bit integer.
+func wavpackTestUint16(v int16) []byte {
+	return []byte{byte(v), byte(uint16(v) >> 8)}
+}
+
+// wavpackSubBlock encodes a metadata sub-block, padding its data to a whole
+// number of 16-bit words.
+func wavpackSubBlock(id byte, data []byte) []byte {
+	if len(data)%2 == 1 {
+		id |= wavpackIDOdd
+		data = append(append([]byte(nil), data...), 0)
+	}
+
+	words := len(data) / 2
+	if words > 0xff {
+		id |= wavpackIDLarge
+		return append([]byte{id, byte(words), byte(words >> 8), byte(words >> 16)}, data...)
+	}
+
+	return append([]byte{id, byte(words)}, data...)
+}
+
+// correlateMono applies the inverse of decorrelateMono, replacing each sample
+// with its residual.
+func (p *wavpackDecorr) correlateMono(buf []int32) {
+	if p.term > 8 {
+		for i, v := range buf {
+			sam := p.extrapolate(&p.samplesA)
+			buf[i] = v - wavpackApplyWeight(p.weightA, sam)
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, sam, buf[i])
+			p.samplesA[0] = v
+		}
+
+		return
+	}
+
+	m, k := 0, p.term&7
+	for i, v := range buf {
+		sam := p.samplesA[m]
+		buf[i] = v - wavpackApplyWeight(p.weightA, sam)
+		p.weightA = wavpackUpdateWeight(p.weightA, p.delta, sam, buf[i])
+		p.samplesA[k] = v
+
+		m, k = (m+1)&7, (k+1)&7
+	}
+}
+
+// correlateStereo applies the inverse of decorrelateStereo, replacing each
+// sample with its residual.
+func (p *wavpackDecorr) correlateStereo(buf []int32) {
+	switch {
+	case p.term > 8:
+		for i := 0; i < len(buf); i += 2 {
+			l, r := buf[i], buf[i+1]
+
+			samA := p.extrapolate(&p.samplesA)
+			buf[i] = l - wavpackApplyWeight(p.weightA, samA)
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, samA, buf[i])
+			p.samplesA[0] = l
+
+			samB := p.extrapolate(&p.samplesB)
+			buf[i+1] = r - wavpackApplyWeight(p.weightB, samB)
+			p.weightB = wavpackUpdateWeight(p.weightB, p.delta, samB, buf[i+1])
+			p.samplesB[0] = r
+		}
+	case p.term > 0:
+		m, k := 0, p.term&7
+		for i := 0; i < len(buf); i += 2 {
+			l, r := buf[i], buf[i+1]
+			samA, samB := p.samplesA[m], p.samplesB[m]
+
+			buf[i] = l - wavpackApplyWeight(p.weightA, samA)
+			p.weightA = wavpackUpdateWeight(p.weightA, p.delta, samA, buf[i])
+			p.samplesA[k] = l
+
+			buf[i+1] = r - wavpackApplyWeight(p.weightB, samB)
+			p.weightB = wavpackUpdateWeight(p.weightB, p.delta, samB, buf[i+1])
+			p.samplesB[k] = r
+
+			m, k = (m+1)&7, (k+1)&7
+		}
+	case p.term == -1:
+		for i := 0; i < len(buf); i += 2 {
+			l, r := buf[i], buf[i+1]
+
+			buf[i] = l - wavpackApplyWeight(p.weightA, p.samplesA[0])
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, p.samplesA[0], buf[i])
+
+			buf[i+1] = r - wavpackApplyWeight(p.weightB, l)
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, l, buf[i+1])
+			p.samplesA[0] = r
+		}
+	case p.term == -2:
+		for i := 0; i < len(buf); i += 2 {
+			l, r := buf[i], buf[i+1]
+
+			buf[i+1] = r - wavpackApplyWeight(p.weightB, p.samplesB[0])
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, p.samplesB[0], buf[i+1])
+
+			buf[i] = l - wavpackApplyWeight(p.weightA, r)
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, r, buf[i])
+			p.samplesB[0] = l
+		}
+	case p.term == -3:
+		for i := 0; i < len(buf); i += 2 {
+			l, r := buf[i], buf[i+1]
+
+			buf[i] = l - wavpackApplyWeight(p.weightA, p.samplesA[0])
+			p.weightA = wavpackUpdateWeightClip(p.weightA, p.delta, p.samplesA[0], buf[i])
+
+			buf[i+1] = r - wavpackApplyWeight(p.weightB, p.samplesB[0])
+			p.weightB = wavpackUpdateWeightClip(p.weightB, p.delta, p.samplesB[0], buf[i+1])
+
+			p.samplesA[0], p.samplesB[0] = r, l
+		}
+	}
+}
+
+// encode codes residuals using the same adaptive state as value, so that
+// value decodes each residual in turn.
+func (w *wavpackWords) encode(bw *wavpackBitWriter, buf []int32, mono bool) {
+	channel := func(i int) int {
+		if mono {
+			return 0
+		}
+		return i % 2
+	}
+
+	for i := 0; i < len(buf); i++ {
+		if !w.holdingZero && !w.holdingOne && w.median[0][0] < 2 && w.median[1][0] < 2 {
+			if w.zeros > 0 {
+				w.zeros--
+				if w.zeros > 0 {
+					continue
+				}
+			} else {
+				var n uint32
+				for j := i; j < len(buf) && buf[j] == 0; j++ {
+					n++
+				}
+
+				bw.escape(n)
+				w.zeros = n
+				if n > 0 {
+					w.median = [2][3]uint32{}
+					continue
+				}
+			}
+		}
+
+		c := &w.median[channel(i)]
+		mag, sign := uint32(buf[i]), uint32(0)
+		if buf[i] < 0 {
+			mag, sign = uint32(^buf[i]), 1
+		}
+		ones, low, high := wavpackTestRange(c, mag)
+
+		if w.holdingZero {
+			if ones != 0 {
+				panic("residual cannot follow a held zero")
+			}
+			w.holdingZero = false
+		} else {
+			// Determine whether the next residual is at least its first
+			// median, once the medians are adapted to this residual
+			var next uint32
+			if i+1 < len(buf) {
+				after := w.median
+				wavpackTestAdapt(&after[channel(i)], ones)
+
+				n := buf[i+1]
+				if n < 0 {
+					n = ^n
+				}
+				if o, _, _ := wavpackTestRange(&after[channel(i+1)], uint32(n)); o > 0 {
+					next = 1
+				}
+			}
+
+			var held uint32
+			if w.holdingOne {
+				held = 1
+			}
+
+			n := 2*(ones-held) + next
+			if n < 16 {
+				bw.unary(n)
+			} else {
+				bw.unary(16)
+				bw.escape(n - 16)
+			}
+
+			w.holdingOne = next == 1
+			w.holdingZero = !w.holdingOne
+		}
+
+		wavpackTestAdapt(c, ones)
+		bw.code(mag-low, high-low)
+		bw.write(sign, 1)
+	}
+}
+
+// wavpackTestRange determines the number of median steps in a residual
+// magnitude, and the range of magnitudes it codes.
+func wavpackTestRange(c *[3]uint32, mag uint32) (uint32, uint32, uint32) {
+	m0, m1, m2 := wavpackMedian(c, 0), wavpackMedian(c, 1), wavpackMedian(c, 2)
+	switch {
+	case mag < m0:
+		return 0, 0, m0 - 1
+	case mag < m0+m1:
+		return 1, m0, m0 + m1 - 1
+	default:
+		ones := 2 + (mag-m0-m1)/m2
+		low := m0 + m1 + (ones-2)*m2
+		return ones, low, low + m2 - 1
+	}
+}
+
+// wavpackTestAdapt adapts medians after a residual with the input number of
+// median steps, in the same way as value.
+func wavpackTestAdapt(c *[3]uint32, ones uint32) {
+	switch {
+	case ones == 0:
+		wavpackDecMedian(c, 0)
+	case ones == 1:
+		wavpackIncMedian(c, 0)
+		wavpackDecMedian(c, 1)
+	case ones == 2:
+		wavpackIncMedian(c, 0)
+		wavpackIncMedian(c, 1)
+		wavpackDecMedian(c, 2)
+	default:
+		wavpackIncMedian(c, 0)
+		wavpackIncMedian(c, 1)
+		wavpackIncMedian(c, 2)
+	}
+}
+
+// wavpackBitWriter writes bits to a WavPack bitstream, least significant bit
+// first.
+type wavpackBitWriter struct {
+	b []byte
+	n int
+}
+
+// write writes the low n bits of v.
+func (bw *wavpackBitWriter) write(v uint32, n int) {
+	for i := 0; i < n; i++ {
+		if bw.n%8 == 0 {
+			bw.b = append(bw.b, 0)
+		}
+		bw.b[len(bw.b)-1] |= byte(v>>uint(i)&1) << uint(bw.n%8)
+		bw.n++
+	}
+}
+
+// unary writes n one bits, followed by a zero bit.
+func (bw *wavpackBitWriter) unary(n uint32) {
+	for i := uint32(0); i < n; i++ {
+		bw.write(1, 1)
+	}
+	bw.write(0, 1)
+}
+
+// escape writes a value as read by wavpackBitReader.escape.
+func (bw *wavpackBitWriter) escape(v uint32) {
+	if v < 2 {
+		bw.unary(v)
+		return
+	}
+
+	n := bits.Len32(v)
+	bw.unary(uint32(n))
+	bw.write(v, n-1)
+}
+
+// code writes a value in the range [0, max], as read by wavpackBitReader.code.
+func (bw *wavpackBitWriter) code(v uint32, max uint32) {
+	if max == 0 {
+		return
+	}
+
+	n := bits.Len32(max)
+	extras := uint32(1)<<uint(n) - max - 1
+	if v < extras {
+		bw.write(v, n-1)
+		return
+	}
+
+	v += extras
+	bw.write(v>>1, n-1)
+	bw.write(v&1, 1)
+}
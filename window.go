@@ -0,0 +1,170 @@
+package waveform
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// errResolutionTooHigh is returned when the resolution of a Waveform exceeds
+// the sample rate of an input audio stream, so that some windows would contain
+// no audio samples at all.
+var errResolutionTooHigh = errors.New("waveform: resolution exceeds audio sample rate")
+
+// PartialWindowPolicy is a policy which determines how a window of audio
+// samples, which is shorter than the window size determined by resolution,
+// is reduced to a computed value.  Partial windows typically occur only at
+// the end of an audio stream.
+type PartialWindowPolicy int
+
+const (
+	// PartialWindowTrim reduces only the audio samples which were actually
+	// read in a partial window.  This is the default behavior of the waveform
+	// package.
+	PartialWindowTrim PartialWindowPolicy = iota
+
+	// PartialWindowDrop discards partial windows, so that every computed value
+	// represents a full window of audio.
+	PartialWindowDrop
+
+	// PartialWindowPad pads partial windows with silence, so that every
+	// computed value is reduced from a full window of audio samples.
+	PartialWindowPad
+)
+
+// windowSize returns the number of interleaved audio samples in the window
+// with the input index, for a stream with the input configuration, read at the
+// input resolution.
+//
+// When the sample rate is not evenly divisible by the resolution, fractional
+// sample frames are accumulated across windows, so that window i always begins
+// at sample frame floor(i * SampleRate / resolution).  This keeps windows
+// aligned with time over long streams, rather than drifting by the fractional
+// remainder on every window.
+func windowSize(config audio.Config, resolution uint, window int) int {
+	rate := uint64(config.SampleRate)
+	start := uint64(window) * rate / uint64(resolution)
+	end := uint64(window+1) * rate / uint64(resolution)
+
+	return int(end-start) * config.Channels
+}
+
+// windowStart returns the time offset of the window with the input index, for
+// a stream with the input configuration, read at the input resolution.
+func windowStart(config audio.Config, resolution uint, window int) time.Duration {
+	if config.SampleRate <= 0 {
+		return 0
+	}
+
+	frames := uint64(window) * uint64(config.SampleRate) / uint64(resolution)
+	return time.Duration(frames * uint64(time.Second) / uint64(config.SampleRate))
+}
+
+// readWindow reads audio samples from an audio.Reader until the input slice
+// of samples is full, or an error occurs.  Decoders are permitted to return
+// fewer samples than requested, so multiple reads may be required to fill a
+// single window.  The number of samples read is returned, along with any
+// error, including end-of-stream.
+func readWindow(r audio.Reader, samples audio.Float64) (int, error) {
+	var n int
+	for n < len(samples) {
+		rn, err := r.Read(samples[n:])
+		n += rn
+		if err != nil {
+			return n, err
+		}
+
+		// Guard against decoders which never make progress
+		if rn == 0 {
+			return n, io.ErrNoProgress
+		}
+	}
+
+	return n, nil
+}
+
+// reduceWindow applies the SampleReduceFunc or ChunkReduceFunc of the
+// receiving Waveform struct to the window with the input index, of which n
+// samples were actually read.  If the window should not produce a value
+// according to the current PartialWindowPolicy, false is returned.
+func (w *Waveform) reduceWindow(config audio.Config, window int, samples audio.Float64, n int) (float64, bool) {
+	// Full windows are always reduced, and empty windows never are
+	if n == len(samples) {
+		return w.reduce(config, window, samples), true
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	switch w.partialWindow {
+	case PartialWindowDrop:
+		return 0, false
+	case PartialWindowPad:
+		// Replace any stale samples from the previous window with silence
+		for i := n; i < len(samples); i++ {
+			samples[i] = 0
+		}
+
+		return w.reduce(config, window, samples), true
+	default:
+		return w.reduce(config, window, samples[:n]), true
+	}
+}
+
+// reduceOrHold reduces a window of audio samples in the same way as
+// reduceWindow, storing its value in last, unless the window is skipped by
+// DownsampleAfter, in which case last is returned in place of its value.
+// Skipped windows produce a value under the same PartialWindowPolicy as
+// windows which are reduced.
+func (w *Waveform) reduceOrHold(config audio.Config, window int, samples audio.Float64, n int, last *float64) (float64, bool) {
+	if !w.skipWindow(config, window) {
+		value, ok := w.reduceWindow(config, window, samples, n)
+		if ok {
+			*last = value
+		}
+
+		return value, ok
+	}
+
+	if n == 0 || (n < len(samples) && w.partialWindow == PartialWindowDrop) {
+		return 0, false
+	}
+
+	return *last, true
+}
+
+// skipWindow reports whether the window with the input index is skipped by
+// DownsampleAfter, rather than reduced.  Windows which begin at or after the
+// downsampling duration are skipped, except for every downsampleStride'th
+// window, beginning with the first.
+func (w *Waveform) skipWindow(config audio.Config, window int) bool {
+	if w.downsampleStride <= 1 {
+		return false
+	}
+
+	// Find the first window which begins at or after the duration
+	start := int((uint64(w.downsampleAfter)*uint64(w.resolution) + uint64(time.Second) - 1) / uint64(time.Second))
+	if window < start {
+		return false
+	}
+
+	return (window-start)%int(w.downsampleStride) != 0
+}
+
+// reduce reduces the samples of the window with the input index to a single
+// value, using the ChunkReduceFunc of the receiving Waveform struct if one is
+// set, or its SampleReduceFunc otherwise.
+func (w *Waveform) reduce(config audio.Config, window int, samples audio.Float64) float64 {
+	if w.chunkFn == nil {
+		return w.sampleFn(samples)
+	}
+
+	return w.chunkFn(&Chunk{
+		Window:  window,
+		Start:   windowStart(config, w.resolution, window),
+		Samples: samples,
+		Config:  config,
+	})
+}
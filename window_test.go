@@ -0,0 +1,266 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestWindowSizeFractional verifies that windowSize accumulates fractional
+// sample frames, so that windows do not drift from time.
+func TestWindowSizeFractional(t *testing.T) {
+	config := audio.Config{
+		SampleRate: 10,
+		Channels:   2,
+	}
+
+	// 10 frames per second at 3 windows per second is 3.33 frames per window
+	want := []int{6, 6, 8, 6, 6, 8}
+	for i, w := range want {
+		if n := windowSize(config, 3, i); n != w {
+			t.Fatalf("unexpected window size at index %d: %v != %v", i, n, w)
+		}
+	}
+
+	// Many windows must add up to exactly the number of seconds elapsed
+	var total int
+	for i := 0; i < 3000; i++ {
+		total += windowSize(config, 3, i)
+	}
+	if total != 1000*10*2 {
+		t.Fatalf("unexpected total samples: %v != %v", total, 1000*10*2)
+	}
+}
+
+// TestReadWindowShortReads verifies that readWindow fills a window of samples
+// across multiple short reads from a decoder.
+func TestReadWindowShortReads(t *testing.T) {
+	r := &shortReader{
+		samples: audio.Float64{0.10, 0.20, 0.30, 0.40, 0.50},
+		max:     2,
+	}
+
+	samples := make(audio.Float64, 4)
+	n, err := readWindow(r, samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("unexpected read count: %v != %v", n, 4)
+	}
+
+	// Final window is partial, and ends the stream
+	n, err = readWindow(r, samples)
+	if err != audio.EOS {
+		t.Fatalf("unexpected error: %v != %v", err, audio.EOS)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected read count: %v != %v", n, 1)
+	}
+}
+
+// TestReadWindowNoProgress verifies that readWindow returns an error when a
+// decoder never returns any samples.
+func TestReadWindowNoProgress(t *testing.T) {
+	r := &shortReader{
+		samples: audio.Float64{0.10},
+		max:     0,
+	}
+
+	if _, err := readWindow(r, make(audio.Float64, 4)); err != io.ErrNoProgress {
+		t.Fatalf("unexpected error: %v != %v", err, io.ErrNoProgress)
+	}
+}
+
+// TestWaveformReduceWindowPolicies verifies that Waveform.reduceWindow applies
+// each PartialWindowPolicy correctly.
+func TestWaveformReduceWindowPolicies(t *testing.T) {
+	var tests = []struct {
+		policy PartialWindowPolicy
+		n      int
+		value  float64
+		ok     bool
+	}{
+		// Full windows are always reduced
+		{PartialWindowDrop, 4, 0.30, true},
+		// Empty windows are never reduced
+		{PartialWindowTrim, 0, 0, false},
+		// Partial windows use each policy
+		{PartialWindowTrim, 2, 0.15, true},
+		{PartialWindowDrop, 2, 0, false},
+		{PartialWindowPad, 2, 0.075, true},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, SampleFunction(meanSamples), PartialWindow(test.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Last two samples are stale, and should only be used in a full window
+		samples := audio.Float64{0.10, 0.20, 0.40, 0.50}
+		value, ok := w.reduceWindow(audio.Config{SampleRate: 4, Channels: 1}, 0, samples, test.n)
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v", i, ok, test.ok)
+		}
+		if !floatEqual(value, test.value) {
+			t.Fatalf("[%02d] unexpected value: %v != %v", i, value, test.value)
+		}
+	}
+}
+
+// meanSamples is a SampleReduceFunc which computes the mean of all input samples.
+func meanSamples(samples audio.Float64) float64 {
+	var sum float64
+	for i := range samples {
+		sum += samples.At(i)
+	}
+
+	return sum / float64(samples.Len())
+}
+
+// shortReader is an audio.Reader which returns at most max samples on each
+// call to Read.
+type shortReader struct {
+	samples audio.Float64
+	max     int
+}
+
+// Read implements audio.Reader.
+func (r *shortReader) Read(b audio.Slice) (int, error) {
+	if len(r.samples) == 0 && r.max > 0 {
+		return 0, audio.EOS
+	}
+
+	n := r.max
+	if n > b.Len() {
+		n = b.Len()
+	}
+	if n > len(r.samples) {
+		n = len(r.samples)
+	}
+
+	r.samples[:n].CopyTo(b)
+	r.samples = r.samples[n:]
+
+	if len(r.samples) == 0 {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
+
+// TestWaveformDownsampleAfter verifies that DownsampleAfter reduces only every
+// stride'th window once the input duration of audio has been read, and repeats
+// the last reduced value for skipped windows.
+func TestWaveformDownsampleAfter(t *testing.T) {
+	// Mono audio at 4Hz, read one sample frame per window, with a distinct
+	// level in each window
+	pcm := []int16{1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000}
+	wav := makeWAV(4, 1, pcm)
+
+	level := func(i int) float64 {
+		return float64(pcm[i]) / 32767
+	}
+
+	var tests = []struct {
+		after  time.Duration
+		stride uint
+		want   []int
+	}{
+		// A stride of 1 disables downsampling
+		{0, 1, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		// Downsample the entire stream
+		{0, 3, []int{0, 0, 0, 3, 3, 3, 6, 6}},
+		// Downsample after one second
+		{time.Second, 2, []int{0, 1, 2, 3, 4, 4, 6, 6}},
+		// Downsample from the first window which begins after the duration
+		{600 * time.Millisecond, 4, []int{0, 1, 2, 3, 3, 3, 3, 7}},
+	}
+
+	for i, test := range tests {
+		w, err := New(bytes.NewReader(wav), Resolution(4), DownsampleAfter(test.after, test.stride))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vs, err := w.ComputeValueSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []float64
+		for _, j := range test.want {
+			want = append(want, level(j))
+		}
+		if got, want := fmt.Sprint(vs.Values), fmt.Sprint(want); got != want {
+			t.Fatalf("[%02d] unexpected values:\n- want: %v\n-  got: %v", i, want, got)
+		}
+
+		// Values remain aligned with time
+		if got, want := fmt.Sprint(vs.Counts), "[1 1 1 1 1 1 1 1]"; got != want {
+			t.Fatalf("[%02d] unexpected counts: %v != %v", i, got, want)
+		}
+	}
+}
+
+// TestWaveformDownsampleAfterCalls verifies that DownsampleAfter bounds the
+// number of windows which are reduced, including with Overlap and
+// ComputeChannels.
+func TestWaveformDownsampleAfterCalls(t *testing.T) {
+	wav := makeWAV(4, 2, make([]int16, 2*16))
+
+	for _, options := range [][]OptionsFunc{
+		nil,
+		{Overlap(3)},
+	} {
+		var calls int
+		fn := func(c *Chunk) float64 {
+			calls++
+			return 0
+		}
+
+		options = append(options, Resolution(4), ChunkFunction(fn), DownsampleAfter(time.Second, 4))
+
+		w, err := New(bytes.NewReader(wav), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 16 || calls != 7 {
+			t.Fatalf("unexpected values and calls: %d, %d", len(values), calls)
+		}
+
+		calls = 0
+		w, err = New(bytes.NewReader(wav), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		channels, err := w.ComputeChannels()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(channels) != 2 || len(channels[1]) != 16 || calls != 14 {
+			t.Fatalf("unexpected channels and calls: %d, %d", len(channels), calls)
+		}
+	}
+}
+
+// TestOptionDownsampleAfterNegative verifies that DownsampleAfter does not
+// accept a negative duration.
+func TestOptionDownsampleAfterNegative(t *testing.T) {
+	testWaveformOptionFunc(t, DownsampleAfter(-1, 2), errDownsampleAfterNegative)
+}
+
+// TestOptionDownsampleAfterStrideZero verifies that DownsampleAfter does not
+// accept a stride of 0.
+func TestOptionDownsampleAfterStrideZero(t *testing.T) {
+	testWaveformOptionFunc(t, DownsampleAfter(0, 0), errDownsampleStrideZero)
+}
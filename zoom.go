@@ -0,0 +1,385 @@
+package waveform
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+var (
+	// errZoomLevelsEmpty is returned when no zoom levels are used in a call to
+	// NewZoomRenderer.
+	errZoomLevelsEmpty = errors.New("waveform: zoom renderer requires at least one level")
+
+	// errZoomLevelResolutionInvalid is returned when a ZoomLevel with a
+	// resolution which is not positive and finite is used in a call to
+	// NewZoomRenderer.
+	errZoomLevelResolutionInvalid = errors.New("waveform: zoom level resolution must be positive and finite")
+
+	// errZoomPixelsPerSecondInvalid is returned when a pixels per second target
+	// which is not positive and finite is used in a call to ZoomRenderer.Render.
+	errZoomPixelsPerSecondInvalid = errors.New("waveform: zoom pixels per second must be positive and finite")
+
+	// errZoomRangeInvalid is returned when a Range which does not overlap the
+	// audio is used in a call to ZoomRenderer.Render.
+	errZoomRangeInvalid = errors.New("waveform: zoom range does not overlap audio")
+
+	// errPyramidEmpty is returned when no resolutions are used in a call to
+	// ComputePyramid.
+	errPyramidEmpty = errors.New("waveform: pyramid requires at least one resolution")
+
+	// errPyramidResolutionZero is returned when a resolution of 0 is used in a
+	// call to ComputePyramid.
+	errPyramidResolutionZero = errors.New("waveform: pyramid resolution cannot be 0")
+
+	// errPyramidCheckpoint is returned when a pyramid is computed while
+	// checkpointing is enabled, because a Checkpoint contains only a single
+	// series of values.
+	errPyramidCheckpoint = &OptionsError{
+		Option: "checkpointEvery",
+		Reason: "checkpoints cannot be written while computing a pyramid",
+		Code:   CodeConflict,
+	}
+)
+
+// ZoomLevel is a single level of a multi-resolution peak pyramid: a slice of
+// computed values, along with the number of values which represent each second
+// of audio.
+type ZoomLevel struct {
+	// Resolution is the number of values per second of audio.
+	Resolution float64
+
+	// Values are the computed values for this level.
+	Values []float64
+}
+
+// Duration returns the duration of audio represented by a ZoomLevel.
+func (l ZoomLevel) Duration() time.Duration {
+	if l.Resolution <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(len(l.Values)) / l.Resolution * float64(time.Second))
+}
+
+// PeakPyramid builds a multi-resolution peak pyramid from a slice of values
+// computed at the input resolution, such as those returned by Compute.
+//
+// The first level contains the input values.  Each following level halves the
+// resolution of the level before it, taking the maximum of each pair of values
+// so that peaks are preserved.  At most levels levels are returned, and fewer
+// are returned if a level is reduced to a single value.
+func PeakPyramid(values []float64, resolution uint, levels int) []ZoomLevel {
+	if levels <= 0 {
+		return nil
+	}
+
+	pyramid := []ZoomLevel{{
+		Resolution: float64(resolution),
+		Values:     values,
+	}}
+
+	for len(pyramid) < levels {
+		prev := pyramid[len(pyramid)-1]
+		if len(prev.Values) <= 1 {
+			break
+		}
+
+		next := make([]float64, (len(prev.Values)+1)/2)
+		for i := range next {
+			next[i] = prev.Values[2*i]
+			if j := 2*i + 1; j < len(prev.Values) && prev.Values[j] > next[i] {
+				next[i] = prev.Values[j]
+			}
+		}
+
+		pyramid = append(pyramid, ZoomLevel{
+			Resolution: prev.Resolution / 2,
+			Values:     next,
+		})
+	}
+
+	return pyramid
+}
+
+// ComputePyramid computes a ZoomLevel at each of the input resolutions, in
+// values per second of audio, reading and decoding the audio stream only once.
+// Levels are returned in the order of the input resolutions, and are typically
+// rendered using a ZoomRenderer.
+//
+// Unlike PeakPyramid, which derives coarser levels from values which were
+// already computed, each level is computed from the audio samples themselves,
+// exactly as Compute would compute it using the Resolution option, so levels
+// may use any resolution, and values which are not peaks, such as those of
+// RMSF64Samples, remain accurate at every level.  All other options which
+// determine how values are computed apply to every level, and any Analyzers
+// receive the windows of the finest level.  ComputePyramid cannot be used with
+// CheckpointEvery.
+func (w *Waveform) ComputePyramid(resolutions ...uint) ([]ZoomLevel, error) {
+	if len(resolutions) == 0 {
+		return nil, errPyramidEmpty
+	}
+	if w.checkpointW != nil {
+		return nil, errPyramidCheckpoint
+	}
+
+	// The stream is read at the finest resolution, and every other level is
+	// computed from the samples of each window as they are read
+	finest := 0
+	for i, r := range resolutions {
+		if r == 0 {
+			return nil, errPyramidResolutionZero
+		}
+		if r > resolutions[finest] {
+			finest = i
+		}
+	}
+
+	cw := w.clone()
+	cw.resolution = resolutions[finest]
+	cw.valueFn = nil
+
+	levels := make([]*pyramidLevel, len(resolutions))
+	for i, r := range resolutions {
+		if i == finest {
+			continue
+		}
+
+		lw := cw.clone()
+		lw.resolution = r
+		levels[i] = &pyramidLevel{w: lw}
+
+		cw.levels = append(cw.levels, levels[i])
+	}
+
+	vs, err := cw.readAndComputeSamples(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ZoomLevel, len(resolutions))
+	for i, r := range resolutions {
+		values := vs.Values
+		if i != finest {
+			if values, err = levels[i].finish(); err != nil {
+				return nil, err
+			}
+		}
+
+		out[i] = ZoomLevel{
+			Resolution: float64(r),
+			Values:     values,
+		}
+	}
+
+	return out, nil
+}
+
+// pyramidLevel is an Analyzer which computes the values of a single level of a
+// pyramid at its own resolution, from the windows of audio samples read at the
+// finest resolution of the pyramid.
+type pyramidLevel struct {
+	w      *Waveform
+	config audio.Config
+
+	// buf holds the samples of the current window, of which n have been read
+	buf    audio.Float64
+	n      int
+	window int
+
+	// last is the value of the last window which was reduced, for use with
+	// DownsampleAfter
+	last float64
+
+	wd     *windower
+	values []float64
+}
+
+// Analyze adds a window of samples read at the finest resolution to the
+// pyramidLevel, reducing each of its own windows once it is complete.
+func (l *pyramidLevel) Analyze(c *Chunk) error {
+	if l.buf == nil {
+		l.config = c.Config
+		if l.w.overlap > 1 || l.w.windowFn != nil {
+			l.wd = l.w.newWindower(c.Config)
+		}
+	}
+
+	samples := c.Samples
+	for len(samples) > 0 {
+		size := windowSize(l.config, l.w.resolution, l.window)
+		if cap(l.buf) < size {
+			buf := make(audio.Float64, size)
+			copy(buf, l.buf[:l.n])
+			l.buf = buf
+		}
+		l.buf = l.buf[:size]
+
+		n := copy(l.buf[l.n:], samples)
+		samples = samples[n:]
+		l.n += n
+
+		if l.n < size {
+			break
+		}
+
+		if err := l.reduce(l.n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reduce reduces the current window, of which n samples were read.
+func (l *pyramidLevel) reduce(n int) error {
+	index := l.window
+	l.window++
+	l.n = 0
+
+	if l.wd != nil {
+		return l.wd.push(index, l.buf, n, l.emit)
+	}
+
+	if value, ok := l.w.reduceOrHold(l.config, index, l.buf, n, &l.last); ok {
+		return l.emit(value, n)
+	}
+
+	return nil
+}
+
+// finish reduces any partial window which remains once the end of the stream
+// is reached, and returns the values of the pyramidLevel.
+func (l *pyramidLevel) finish() ([]float64, error) {
+	if l.n > 0 {
+		if err := l.reduce(l.n); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.wd != nil {
+		if err := l.wd.flush(l.emit); err != nil {
+			return nil, err
+		}
+	}
+
+	return l.values, nil
+}
+
+// emit stores a value computed by the pyramidLevel.
+func (l *pyramidLevel) emit(value float64, _ int) error {
+	l.values = append(l.values, value)
+	return nil
+}
+
+// ZoomRenderer renders a waveform at any zoom level, given a pixels per second
+// target, by selecting the most appropriate level of a multi-resolution peak
+// pyramid.  This allows interactive viewers to render with consistent quality
+// at any zoom, without computing values from the audio again.
+type ZoomRenderer struct {
+	style  RenderStyle
+	levels []ZoomLevel
+}
+
+// NewZoomRenderer creates a new ZoomRenderer which draws using the input
+// RenderStyle, selecting from the input zoom levels, such as those returned
+// by PeakPyramid or Waveform.ComputePyramid.  Each level must have a resolution which is positive and
+// finite.
+func NewZoomRenderer(style RenderStyle, levels []ZoomLevel) (*ZoomRenderer, error) {
+	if len(levels) == 0 {
+		return nil, errZoomLevelsEmpty
+	}
+
+	for _, l := range levels {
+		if l.Resolution <= 0 || math.IsInf(l.Resolution, 0) || math.IsNaN(l.Resolution) {
+			return nil, errZoomLevelResolutionInvalid
+		}
+	}
+
+	// Time-based layout is handled by the ZoomRenderer itself
+	style.proportional = false
+	style.pixelsPerSecond = 0
+
+	// Sort levels from finest to coarsest, without modifying the input slice
+	sorted := make([]ZoomLevel, len(levels))
+	copy(sorted, levels)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return sorted[i].Resolution > sorted[j].Resolution
+	})
+
+	return &ZoomRenderer{
+		style:  style,
+		levels: sorted,
+	}, nil
+}
+
+// Level returns the ZoomLevel which is used to render at the input pixels
+// per second target.
+//
+// The coarsest level which still provides at least one value for each column
+// of the image is selected, so that no detail is lost, and as few values as
+// possible are reduced.  If no level is fine enough, the finest level is
+// selected, and its values are repeated.
+func (z *ZoomRenderer) Level(pixelsPerSecond float64) ZoomLevel {
+	columnsPerSecond := pixelsPerSecond / float64(z.style.scaleX)
+
+	level := z.levels[0]
+	for _, l := range z.levels[1:] {
+		if l.Resolution < columnsPerSecond {
+			break
+		}
+
+		level = l
+	}
+
+	return level
+}
+
+// Render creates a new image.Image of the input Range of audio, at the input
+// pixels per second target.  If the end of the Range is 0 or past the end of
+// the audio, the image extends to the end of the audio.
+//
+// The width of the image is the duration of the Range multiplied by the pixels
+// per second target, rounded up to a multiple of the X-axis scaling factor.
+func (z *ZoomRenderer) Render(pixelsPerSecond float64, r Range) (image.Image, error) {
+	if pixelsPerSecond <= 0 || math.IsInf(pixelsPerSecond, 0) || math.IsNaN(pixelsPerSecond) {
+		return nil, errZoomPixelsPerSecondInvalid
+	}
+
+	level := z.Level(pixelsPerSecond)
+
+	duration := level.Duration()
+	if r.End <= 0 || r.End > duration {
+		r.End = duration
+	}
+	if r.Start < 0 {
+		r.Start = 0
+	}
+	if r.Start >= r.End {
+		return nil, errZoomRangeInvalid
+	}
+
+	// Determine the values which cover the range, always covering at least
+	// one value
+	start := int(r.Start.Seconds() * level.Resolution)
+	end := int(math.Ceil(r.End.Seconds() * level.Resolution))
+	if end > len(level.Values) {
+		end = len(level.Values)
+	}
+	if end <= start {
+		end = start + 1
+	}
+
+	// Resample values to exactly the number of columns needed at this zoom
+	columnsPerSecond := pixelsPerSecond / float64(z.style.scaleX)
+	n := int(math.Ceil(r.Duration().Seconds()*columnsPerSecond - 1e-6))
+	if n < 1 {
+		n = 1
+	}
+
+	return z.style.DrawChecked(resampleMax(level.Values[start:end], n))
+}
@@ -0,0 +1,220 @@
+package waveform
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestPeakPyramid verifies that PeakPyramid halves the resolution of each
+// level, while preserving peaks.
+func TestPeakPyramid(t *testing.T) {
+	values := []float64{0.1, 0.5, 0.2, 0.3, 0.9}
+
+	pyramid := PeakPyramid(values, 8, 10)
+	if len(pyramid) != 4 {
+		t.Fatalf("unexpected number of levels: %d != %d", len(pyramid), 4)
+	}
+
+	want := []ZoomLevel{
+		{Resolution: 8, Values: values},
+		{Resolution: 4, Values: []float64{0.5, 0.3, 0.9}},
+		{Resolution: 2, Values: []float64{0.5, 0.9}},
+		{Resolution: 1, Values: []float64{0.9}},
+	}
+	if got, want := fmt.Sprint(pyramid), fmt.Sprint(want); got != want {
+		t.Fatalf("unexpected pyramid:\n- got:  %v\n- want: %v", got, want)
+	}
+
+	if l := PeakPyramid(values, 8, 2); len(l) != 2 {
+		t.Fatalf("unexpected number of levels: %d != %d", len(l), 2)
+	}
+	if l := PeakPyramid(values, 8, 0); l != nil {
+		t.Fatalf("unexpected levels: %v", l)
+	}
+}
+
+// TestWaveformComputePyramid verifies that each level computed by
+// ComputePyramid is identical to the values computed by Compute at the same
+// resolution.
+func TestWaveformComputePyramid(t *testing.T) {
+	// 2.5 seconds of stereo audio at 1000Hz, so that final windows are partial
+	// at most resolutions
+	samples := make([]int16, 2*2500)
+	for i := range samples {
+		samples[i] = int16((i*7919)%65536 - 32768)
+	}
+	wav := makeWAV(1000, 2, samples)
+
+	resolutions := []uint{3, 100, 7, 1}
+
+	var tests = [][]OptionsFunc{
+		nil,
+		{SampleFunction(RMSF64Samples), Downmix(DownmixAverage)},
+		{PartialWindow(PartialWindowPad)},
+		{PartialWindow(PartialWindowDrop)},
+		{Overlap(3), WindowFunction(HannWindow)},
+		{DownsampleAfter(time.Second, 2)},
+	}
+
+	for i, options := range tests {
+		w, err := New(bytes.NewReader(wav), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		levels, err := w.ComputePyramid(resolutions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(levels) != len(resolutions) {
+			t.Fatalf("[%02d] unexpected number of levels: %d", i, len(levels))
+		}
+
+		for j, r := range resolutions {
+			w, err := New(bytes.NewReader(wav), append(options, Resolution(r))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := w.Compute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if l := levels[j]; l.Resolution != float64(r) || fmt.Sprint(l.Values) != fmt.Sprint(want) {
+				t.Fatalf("[%02d] unexpected level at resolution %d:\n- want: %v\n-  got: %v", i, r, want, l.Values)
+			}
+		}
+	}
+}
+
+// TestWaveformComputePyramidErrors verifies that ComputePyramid rejects
+// invalid resolutions, and use with checkpoints.
+func TestWaveformComputePyramidErrors(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ComputePyramid(); err != errPyramidEmpty {
+		t.Fatalf("unexpected error: %v != %v", err, errPyramidEmpty)
+	}
+	if _, err := w.ComputePyramid(10, 0); err != errPyramidResolutionZero {
+		t.Fatalf("unexpected error: %v != %v", err, errPyramidResolutionZero)
+	}
+
+	w, err = New(bytes.NewReader(wavFile), CheckpointEvery(1, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ComputePyramid(10); err != errPyramidCheckpoint {
+		t.Fatalf("unexpected error: %v != %v", err, errPyramidCheckpoint)
+	}
+}
+
+// TestZoomRendererLevel verifies that ZoomRenderer.Level selects the coarsest
+// level which still provides a value for each column.
+func TestZoomRendererLevel(t *testing.T) {
+	style, err := NewStyle(Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Levels are deliberately out of order
+	z, err := NewZoomRenderer(style, []ZoomLevel{
+		{Resolution: 25},
+		{Resolution: 100},
+		{Resolution: 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		pixelsPerSecond float64
+		resolution      float64
+	}{
+		{pixelsPerSecond: 1000, resolution: 100},
+		{pixelsPerSecond: 200, resolution: 100},
+		{pixelsPerSecond: 150, resolution: 100},
+		{pixelsPerSecond: 100, resolution: 50},
+		{pixelsPerSecond: 60, resolution: 50},
+		{pixelsPerSecond: 50, resolution: 25},
+		{pixelsPerSecond: 1, resolution: 25},
+	}
+
+	for i, tt := range tests {
+		if r := z.Level(tt.pixelsPerSecond).Resolution; r != tt.resolution {
+			t.Fatalf("[%02d] unexpected resolution for %v pixels per second: %v != %v",
+				i, tt.pixelsPerSecond, r, tt.resolution)
+		}
+	}
+}
+
+// TestZoomRendererRender verifies that ZoomRenderer.Render produces images of
+// the correct width for a range of audio.
+func TestZoomRendererRender(t *testing.T) {
+	style, err := NewStyle(Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ten seconds of audio at 100 values per second
+	z, err := NewZoomRenderer(style, PeakPyramid(make([]float64, 1000), 100, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		pixelsPerSecond float64
+		r               Range
+		width           int
+	}{
+		{pixelsPerSecond: 200, width: 2000},
+		{pixelsPerSecond: 20, width: 200},
+		{pixelsPerSecond: 7, width: 70},
+		{pixelsPerSecond: 1000, r: Range{Start: time.Second, End: 2 * time.Second}, width: 1000},
+		{pixelsPerSecond: 40, r: Range{Start: 9 * time.Second, End: time.Minute}, width: 40},
+		{pixelsPerSecond: 1, r: Range{End: time.Second}, width: 2},
+	}
+
+	for i, tt := range tests {
+		img, err := z.Render(tt.pixelsPerSecond, tt.r)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected error: %v", i, err)
+		}
+
+		if w := img.Bounds().Dx(); w != tt.width {
+			t.Fatalf("[%02d] unexpected width: %d != %d", i, w, tt.width)
+		}
+	}
+}
+
+// TestZoomRendererErrors verifies that NewZoomRenderer and ZoomRenderer.Render
+// return errors for invalid input.
+func TestZoomRendererErrors(t *testing.T) {
+	style, err := NewStyle()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewZoomRenderer(style, nil); err != errZoomLevelsEmpty {
+		t.Fatalf("unexpected error: %v != %v", err, errZoomLevelsEmpty)
+	}
+	if _, err := NewZoomRenderer(style, []ZoomLevel{{Resolution: 0}}); err != errZoomLevelResolutionInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errZoomLevelResolutionInvalid)
+	}
+
+	z, err := NewZoomRenderer(style, PeakPyramid(make([]float64, 10), 1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := z.Render(0, Range{}); err != errZoomPixelsPerSecondInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errZoomPixelsPerSecondInvalid)
+	}
+	if _, err := z.Render(1, Range{Start: time.Minute}); err != errZoomRangeInvalid {
+		t.Fatalf("unexpected error: %v != %v", err, errZoomRangeInvalid)
+	}
+}